@@ -0,0 +1,186 @@
+// Package agent implements the helper the provider runs on hosts where parsing the output of
+// shell commands is too fragile. It reads one JSON Request per line from its standard input
+// and answers each with one JSON Response line. It only reads the hosts: files are still written
+// over SFTP or with shell commands and services queried with their manager, so a host where the
+// agent cannot run is managed the same way.
+package agent
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Flag is the name of the command line flag running the provider binary as the agent.
+const Flag = "agent"
+
+// The operations of the agent.
+const (
+	OpStat = "stat"
+	OpHash = "hash"
+)
+
+// Request is an operation asked to the agent.
+type Request struct {
+	Op   string `json:"op"`
+	Path string `json:"path,omitempty"`
+	// Algorithm is the checksum algorithm of hash, sha256 when empty.
+	Algorithm string `json:"algorithm,omitempty"`
+	// Follow makes stat describe the target of a symlink instead of the link itself.
+	Follow bool `json:"follow,omitempty"`
+}
+
+// Response is the outcome of a Request, Error is set when it failed.
+type Response struct {
+	Error    string `json:"error,omitempty"`
+	Exists   bool   `json:"exists,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Mode     string `json:"mode,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Inode    uint64 `json:"inode,omitempty"`
+	ModTime  int64  `json:"mod_time,omitempty"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// Serve answers the requests read from r on w until r is exhausted.
+func Serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+
+	encoder := json.NewEncoder(w)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var request Request
+		response := Response{}
+		if err := json.Unmarshal([]byte(line), &request); err != nil {
+			response.Error = fmt.Sprintf("invalid request: %s", err)
+		} else {
+			response = handle(request)
+		}
+
+		if err := encoder.Encode(response); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func handle(request Request) Response {
+	var response Response
+	var err error
+
+	switch request.Op {
+	case OpStat:
+		response, err = stat(request.Path, request.Follow)
+	case OpHash:
+		response, err = checksum(request.Path, request.Algorithm)
+	default:
+		err = fmt.Errorf("unsupported operation %q", request.Op)
+	}
+
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+
+	return response
+}
+
+// stat describes path, following symlinks when follow is set. Missing paths, and symlinks whose
+// target is missing when followed, are not an error.
+func stat(path string, follow bool) (Response, error) {
+	describe := os.Lstat
+	if follow {
+		describe = os.Stat
+	}
+
+	info, err := describe(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Response{}, nil
+	}
+	if err != nil {
+		return Response{}, err
+	}
+
+	fileType := "other"
+	switch {
+	case info.Mode().IsRegular():
+		fileType = "file"
+	case info.IsDir():
+		fileType = "directory"
+	case info.Mode()&fs.ModeSymlink != 0:
+		fileType = "symlink"
+	}
+
+	return Response{
+		Exists:  true,
+		Type:    fileType,
+		Mode:    fmt.Sprintf("%04o", info.Mode().Perm()),
+		Size:    info.Size(),
+		Inode:   inode(info),
+		ModTime: info.ModTime().Unix(),
+	}, nil
+}
+
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "", "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "blake2b":
+		return blake2b.New512(nil)
+	}
+
+	return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+}
+
+// checksum returns the hex encoded digest of the file at path, missing files are not an error.
+func checksum(path string, algorithm string) (Response, error) {
+	digest, err := newHash(algorithm)
+	if err != nil {
+		return Response{}, err
+	}
+
+	// As test -f, only regular files are hashed and symlinks are followed.
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.Mode().IsRegular()) {
+		return Response{}, nil
+	}
+	if err != nil {
+		return Response{}, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return Response{}, err
+	}
+	defer func() { _ = file.Close() }()
+
+	size, err := io.Copy(digest, file)
+	if err != nil {
+		return Response{}, err
+	}
+
+	return Response{Exists: true, Type: "file", Size: size, Checksum: hex.EncodeToString(digest.Sum(nil))}, nil
+}
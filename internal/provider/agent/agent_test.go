@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "app.conf")
+	missing := filepath.Join(dir, "missing")
+	if err := os.WriteFile(target, []byte("hello\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(target, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	link := filepath.Join(dir, "current.conf")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targetInode := inode(info)
+
+	requests := []Request{
+		{Op: OpStat, Path: target},
+		{Op: OpStat, Path: link},
+		{Op: OpStat, Path: link, Follow: true},
+		{Op: OpHash, Path: target},
+		{Op: OpStat, Path: missing},
+		{Op: OpHash, Path: missing},
+		{Op: OpHash, Path: target, Algorithm: "crc32"},
+		{Op: "reboot"},
+	}
+
+	var input bytes.Buffer
+	for _, request := range requests {
+		line, err := json.Marshal(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		input.Write(line)
+		input.WriteString("\n")
+	}
+	input.WriteString("not json\n")
+
+	var output bytes.Buffer
+	if err := Serve(&input, &output); err != nil {
+		t.Fatal(err)
+	}
+
+	var responses []Response
+	decoder := json.NewDecoder(&output)
+	for decoder.More() {
+		var response Response
+		if err := decoder.Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, response)
+	}
+
+	if len(responses) != len(requests)+1 {
+		t.Fatalf("got %d responses, want %d", len(responses), len(requests)+1)
+	}
+
+	for i, want := range []Response{
+		{Exists: true, Type: "file", Mode: "0640", Size: 6, Inode: targetInode, ModTime: info.ModTime().Unix()},
+		{Exists: true, Type: "symlink", Mode: "0777", Size: responses[1].Size, Inode: responses[1].Inode, ModTime: responses[1].ModTime},
+		{Exists: true, Type: "file", Mode: "0640", Size: 6, Inode: targetInode, ModTime: info.ModTime().Unix()},
+		{Exists: true, Type: "file", Size: 6, Checksum: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+		{},
+		{},
+	} {
+		if responses[i] != want {
+			t.Errorf("request %d: got %+v, want %+v", i, responses[i], want)
+		}
+	}
+
+	for i := len(requests) - 2; i < len(responses); i++ {
+		if responses[i].Error == "" {
+			t.Errorf("request %d: expected an error", i)
+		}
+	}
+
+	if !strings.HasPrefix(responses[len(responses)-1].Error, "invalid request") {
+		t.Errorf("got %q for an invalid request", responses[len(responses)-1].Error)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package agent
+
+import "io/fs"
+
+// inode returns 0, the inode number is only known on Unix hosts.
+func inode(fs.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package agent
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// inode returns the inode number of the file described by info.
+func inode(info fs.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return stat.Ino
+	}
+
+	return 0
+}
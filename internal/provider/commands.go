@@ -0,0 +1,110 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"remote-provider/internal/provider/servers"
+	"remote-provider/internal/provider/services"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// ExitCodeError represents an SSH command exit code error.
+type ExitCodeError struct {
+	code   int8
+	stderr string
+}
+
+func (e *ExitCodeError) Error() string {
+	return fmt.Sprintf("exit code %d: %s", e.code, e.stderr)
+}
+
+// commandTime formats a timestamp of a command as RFC 3339 in UTC, null when it is unknown.
+func commandTime(t time.Time) types.String {
+	if t.IsZero() {
+		return types.StringNull()
+	}
+
+	return types.StringValue(t.UTC().Format(time.RFC3339Nano))
+}
+
+// commandDuration returns how long command ran in milliseconds, null when it is unknown.
+func commandDuration(command *servers.ServerCommand) types.Int64 {
+	if command.StartedAt.IsZero() {
+		return types.Int64Null()
+	}
+
+	return types.Int64Value(command.Duration().Milliseconds())
+}
+
+// runCommand opens the connection to server if needed and executes command on it.
+// A non-zero exit code is returned as an *ExitCodeError alongside the command result, or as a
+// *services.PrivilegeError when sudo or doas refused to run the command.
+func runCommand(ctx context.Context, sshService *services.SSHService, server *servers.Server, command string) (*servers.ServerCommand, error) {
+	err := sshService.OpenConnection(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+
+	tflog.Debug(ctx, "executing remote command", map[string]any{"host": server.Name, "command": services.RedactFileContent(command)})
+
+	result, err := sshService.ExecuteCommand(ctx, command, server)
+
+	// A refused privilege escalation is reported with its fix instead of the garbled PTY output.
+	var privilegeErr *services.PrivilegeError
+	if errors.As(err, &privilegeErr) {
+		return result, err
+	}
+
+	if result != nil && result.ExitCode != 0 {
+		// The PTY merges stderr into stdout, so fall back to it for the message.
+		output := strings.TrimSpace(result.Stderr)
+		if output == "" {
+			output = strings.TrimSpace(result.Stdout)
+		}
+
+		return result, &ExitCodeError{code: result.ExitCode, stderr: output}
+	}
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+var fileModeRegexp = regexp.MustCompile(`^0?[0-7]{3}$`)
+
+// validFileMode reports whether mode is an octal permission string such as "0644".
+func validFileMode(mode string) bool {
+	return fileModeRegexp.MatchString(mode)
+}
+
+// privilegedCommand wraps command so the whole shell snippet runs as root on server.
+func privilegedCommand(server *servers.Server, command string) string {
+	return services.PrivilegedCommand(server, command)
+}
+
+// writeRemoteFile atomically replaces path on the remote host with content and the given octal mode.
+// An empty mode falls back to the provider default file mode.
+func writeRemoteFile(ctx context.Context, sshService *services.SSHService, server *servers.Server, path string, content []byte, mode string, privileged bool) error {
+	command := services.WriteFileCommand(path, content, sshService.FileMode(mode))
+
+	if privileged {
+		command = privilegedCommand(server, command)
+	}
+
+	start := time.Now()
+	_, err := runCommand(ctx, sshService, server, command)
+	sshService.Measure(ctx, "transfer", server, start, len(content), err)
+
+	return err
+}
@@ -0,0 +1,60 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+var (
+	_ function.Function = CronExpressionFunction{}
+)
+
+func NewCronExpressionFunction() function.Function {
+	return CronExpressionFunction{}
+}
+
+// CronExpressionFunction validates and normalizes a crontab schedule.
+type CronExpressionFunction struct{}
+
+func (r CronExpressionFunction) Metadata(_ context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "cron_expression"
+}
+
+func (r CronExpressionFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Validates and normalizes a cron expression",
+		MarkdownDescription: "Returns the five field crontab schedule `expression` in a canonical form, failing the plan " +
+			"when it is invalid instead of installing a broken crontab. Aliases such as `@daily` are expanded, month " +
+			"and day names become numbers and `*/1` becomes `*`. `@reboot` is returned as is.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "expression",
+				MarkdownDescription: "Cron expression, e.g. `*/15 * * * *`, `0 9 * * mon-fri` or `@hourly`",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (r CronExpressionFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var expression string
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &expression))
+
+	if resp.Error != nil {
+		return
+	}
+
+	normalized, err := services.NormalizeCron(expression)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, normalized))
+}
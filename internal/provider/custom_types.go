@@ -0,0 +1,315 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"remote-provider/internal/provider/services"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/attr/xattr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// The custom string types below validate the paths, modes and durations of every resource and
+// compare them semantically, so "0644" and "644" or "60s" and "1m" do not show as drift.
+var (
+	_ basetypes.StringTypable                    = RemotePathType{}
+	_ basetypes.StringValuableWithSemanticEquals = RemotePath{}
+	_ xattr.ValidateableAttribute                = RemotePath{}
+	_ basetypes.StringTypable                    = FileModeType{}
+	_ basetypes.StringValuableWithSemanticEquals = FileMode{}
+	_ xattr.ValidateableAttribute                = FileMode{}
+	_ basetypes.StringTypable                    = DurationType{}
+	_ basetypes.StringValuableWithSemanticEquals = Duration{}
+	_ xattr.ValidateableAttribute                = Duration{}
+)
+
+// customStringFromTerraform converts in to a string, then to the value of the custom type t.
+func customStringFromTerraform(ctx context.Context, t basetypes.StringTypable, in tftypes.Value) (attr.Value, error) {
+	value, err := basetypes.StringType{}.ValueFromTerraform(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	stringValue, ok := value.(basetypes.StringValue)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type of %T", value)
+	}
+
+	valuable, diags := t.ValueFromString(ctx, stringValue)
+	if diags.HasError() {
+		return nil, fmt.Errorf("unexpected error converting StringValue to %s: %v", t, diags)
+	}
+
+	return valuable, nil
+}
+
+// semanticEqualityError reports a value of an unexpected type given to a semantic equality check.
+func semanticEqualityError(expected attr.Value, got basetypes.StringValuable) diag.Diagnostics {
+	var diags diag.Diagnostics
+	diags.AddError(
+		"Semantic Equality Check Error",
+		fmt.Sprintf("An unexpected value type was received while performing semantic equality checks. "+
+			"Please report this to the provider developers.\n\nExpected Value Type: %T\nGot Value Type: %T", expected, got),
+	)
+
+	return diags
+}
+
+// RemotePathType is the type of the paths of files and directories on the hosts.
+type RemotePathType struct {
+	basetypes.StringType
+}
+
+func (t RemotePathType) String() string {
+	return "RemotePathType"
+}
+
+func (t RemotePathType) Equal(o attr.Type) bool {
+	other, ok := o.(RemotePathType)
+
+	return ok && t.StringType.Equal(other.StringType)
+}
+
+func (t RemotePathType) ValueType(ctx context.Context) attr.Value {
+	return RemotePath{}
+}
+
+func (t RemotePathType) ValueFromString(ctx context.Context, in basetypes.StringValue) (basetypes.StringValuable, diag.Diagnostics) {
+	return RemotePath{StringValue: in}, nil
+}
+
+func (t RemotePathType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	return customStringFromTerraform(ctx, t, in)
+}
+
+// RemotePath is a path on a host. Paths naming the same file once cleaned, e.g. "/etc/app/" and
+// "/etc//app", are semantically equal.
+type RemotePath struct {
+	basetypes.StringValue
+}
+
+func NewRemotePathValue(value string) RemotePath {
+	return RemotePath{StringValue: types.StringValue(value)}
+}
+
+func NewRemotePathNull() RemotePath {
+	return RemotePath{StringValue: types.StringNull()}
+}
+
+func NewRemotePathUnknown() RemotePath {
+	return RemotePath{StringValue: types.StringUnknown()}
+}
+
+func (v RemotePath) Type(ctx context.Context) attr.Type {
+	return RemotePathType{}
+}
+
+func (v RemotePath) Equal(o attr.Value) bool {
+	other, ok := o.(RemotePath)
+
+	return ok && v.StringValue.Equal(other.StringValue)
+}
+
+func (v RemotePath) StringSemanticEquals(ctx context.Context, newValuable basetypes.StringValuable) (bool, diag.Diagnostics) {
+	newValue, ok := newValuable.(RemotePath)
+	if !ok {
+		return false, semanticEqualityError(v, newValuable)
+	}
+
+	return path.Clean(v.ValueString()) == path.Clean(newValue.ValueString()), nil
+}
+
+func (v RemotePath) ValidateAttribute(ctx context.Context, req xattr.ValidateAttributeRequest, resp *xattr.ValidateAttributeResponse) {
+	if v.IsNull() || v.IsUnknown() {
+		return
+	}
+
+	// The output of the commands is read line by line, so a path cannot span lines.
+	if v.ValueString() == "" || strings.ContainsAny(v.ValueString(), "\x00\n\r") {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Remote Path",
+			fmt.Sprintf("Attribute %s must be a non-empty path on a single line, got: %q", req.Path, v.ValueString()),
+		)
+	}
+}
+
+// FileModeType is the type of the octal modes of files and directories.
+type FileModeType struct {
+	basetypes.StringType
+}
+
+func (t FileModeType) String() string {
+	return "FileModeType"
+}
+
+func (t FileModeType) Equal(o attr.Type) bool {
+	other, ok := o.(FileModeType)
+
+	return ok && t.StringType.Equal(other.StringType)
+}
+
+func (t FileModeType) ValueType(ctx context.Context) attr.Value {
+	return FileMode{}
+}
+
+func (t FileModeType) ValueFromString(ctx context.Context, in basetypes.StringValue) (basetypes.StringValuable, diag.Diagnostics) {
+	return FileMode{StringValue: in}, nil
+}
+
+func (t FileModeType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	return customStringFromTerraform(ctx, t, in)
+}
+
+// FileMode is an octal mode such as "0644". Modes with the same permissions are semantically
+// equal whatever their number of leading zeros, so a mode configured as the number 644 does not
+// drift from the "0644" read from the host.
+type FileMode struct {
+	basetypes.StringValue
+}
+
+func NewFileModeValue(value string) FileMode {
+	return FileMode{StringValue: types.StringValue(value)}
+}
+
+func NewFileModeNull() FileMode {
+	return FileMode{StringValue: types.StringNull()}
+}
+
+func NewFileModeUnknown() FileMode {
+	return FileMode{StringValue: types.StringUnknown()}
+}
+
+func (v FileMode) Type(ctx context.Context) attr.Type {
+	return FileModeType{}
+}
+
+func (v FileMode) Equal(o attr.Value) bool {
+	other, ok := o.(FileMode)
+
+	return ok && v.StringValue.Equal(other.StringValue)
+}
+
+func (v FileMode) StringSemanticEquals(ctx context.Context, newValuable basetypes.StringValuable) (bool, diag.Diagnostics) {
+	newValue, ok := newValuable.(FileMode)
+	if !ok {
+		return false, semanticEqualityError(v, newValuable)
+	}
+
+	prior, err := services.NormalizeMode(v.ValueString())
+	if err != nil {
+		return false, nil
+	}
+	current, err := services.NormalizeMode(newValue.ValueString())
+
+	return err == nil && prior == current, nil
+}
+
+func (v FileMode) ValidateAttribute(ctx context.Context, req xattr.ValidateAttributeRequest, resp *xattr.ValidateAttributeResponse) {
+	if v.IsNull() || v.IsUnknown() {
+		return
+	}
+
+	if !validFileMode(v.ValueString()) {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid File Mode",
+			fmt.Sprintf("Attribute %s must be an octal mode such as 0644, got: %s", req.Path, v.ValueString()),
+		)
+	}
+}
+
+// DurationType is the type of the Go durations such as "30s" or "10m".
+type DurationType struct {
+	basetypes.StringType
+}
+
+func (t DurationType) String() string {
+	return "DurationType"
+}
+
+func (t DurationType) Equal(o attr.Type) bool {
+	other, ok := o.(DurationType)
+
+	return ok && t.StringType.Equal(other.StringType)
+}
+
+func (t DurationType) ValueType(ctx context.Context) attr.Value {
+	return Duration{}
+}
+
+func (t DurationType) ValueFromString(ctx context.Context, in basetypes.StringValue) (basetypes.StringValuable, diag.Diagnostics) {
+	return Duration{StringValue: in}, nil
+}
+
+func (t DurationType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	return customStringFromTerraform(ctx, t, in)
+}
+
+// Duration is a positive Go duration. Durations of the same length, e.g. "60s" and "1m", are
+// semantically equal.
+type Duration struct {
+	basetypes.StringValue
+}
+
+func NewDurationValue(value string) Duration {
+	return Duration{StringValue: types.StringValue(value)}
+}
+
+func NewDurationNull() Duration {
+	return Duration{StringValue: types.StringNull()}
+}
+
+func NewDurationUnknown() Duration {
+	return Duration{StringValue: types.StringUnknown()}
+}
+
+func (v Duration) Type(ctx context.Context) attr.Type {
+	return DurationType{}
+}
+
+func (v Duration) Equal(o attr.Value) bool {
+	other, ok := o.(Duration)
+
+	return ok && v.StringValue.Equal(other.StringValue)
+}
+
+func (v Duration) StringSemanticEquals(ctx context.Context, newValuable basetypes.StringValuable) (bool, diag.Diagnostics) {
+	newValue, ok := newValuable.(Duration)
+	if !ok {
+		return false, semanticEqualityError(v, newValuable)
+	}
+
+	prior, err := time.ParseDuration(v.ValueString())
+	if err != nil {
+		return false, nil
+	}
+	current, err := time.ParseDuration(newValue.ValueString())
+
+	return err == nil && prior == current, nil
+}
+
+func (v Duration) ValidateAttribute(ctx context.Context, req xattr.ValidateAttributeRequest, resp *xattr.ValidateAttributeResponse) {
+	if v.IsNull() || v.IsUnknown() {
+		return
+	}
+
+	duration, err := time.ParseDuration(v.ValueString())
+	if err != nil || duration <= 0 {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Duration",
+			fmt.Sprintf("Attribute %s must be a positive duration such as 30s or 10m, got: %s", req.Path, v.ValueString()),
+		)
+	}
+}
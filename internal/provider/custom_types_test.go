@@ -0,0 +1,69 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr/xattr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+func TestCustomTypesSemanticEquals(t *testing.T) {
+	cases := map[string]struct {
+		prior    basetypes.StringValuableWithSemanticEquals
+		current  basetypes.StringValuable
+		expected bool
+	}{
+		"path trailing slash": {NewRemotePathValue("/etc/app/"), NewRemotePathValue("/etc/app"), true},
+		"path double slash":   {NewRemotePathValue("/etc//app"), NewRemotePathValue("/etc/app"), true},
+		"path different":      {NewRemotePathValue("/etc/app"), NewRemotePathValue("/etc/app2"), false},
+		"mode leading zero":   {NewFileModeValue("644"), NewFileModeValue("0644"), true},
+		"mode different":      {NewFileModeValue("0644"), NewFileModeValue("0640"), false},
+		"mode invalid":        {NewFileModeValue("rw"), NewFileModeValue("rw"), false},
+		"duration units":      {NewDurationValue("60s"), NewDurationValue("1m"), true},
+		"duration different":  {NewDurationValue("60s"), NewDurationValue("2m"), false},
+	}
+
+	for name, c := range cases {
+		equal, diags := c.prior.StringSemanticEquals(context.Background(), c.current)
+		if diags.HasError() {
+			t.Fatalf("%s: %v", name, diags)
+		}
+
+		if equal != c.expected {
+			t.Errorf("%s: expected %t, got %t", name, c.expected, equal)
+		}
+	}
+}
+
+func TestCustomTypesValidateAttribute(t *testing.T) {
+	cases := map[string]struct {
+		value   xattr.ValidateableAttribute
+		invalid bool
+	}{
+		"path":              {NewRemotePathValue("/etc/app.conf"), false},
+		"path empty":        {NewRemotePathValue(""), true},
+		"path newline":      {NewRemotePathValue("/etc/app\n.conf"), true},
+		"path null":         {NewRemotePathNull(), false},
+		"mode":              {NewFileModeValue("0640"), false},
+		"mode short":        {NewFileModeValue("640"), false},
+		"mode invalid":      {NewFileModeValue("0980"), true},
+		"mode unknown":      {NewFileModeUnknown(), false},
+		"duration":          {NewDurationValue("10m"), false},
+		"duration negative": {NewDurationValue("-1s"), true},
+		"duration invalid":  {NewDurationValue("ten minutes"), true},
+	}
+
+	for name, c := range cases {
+		resp := &xattr.ValidateAttributeResponse{}
+		c.value.ValidateAttribute(context.Background(), xattr.ValidateAttributeRequest{Path: path.Root("test")}, resp)
+
+		if resp.Diagnostics.HasError() != c.invalid {
+			t.Errorf("%s: expected invalid %t, got %v", name, c.invalid, resp.Diagnostics)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+var (
+	_ function.Function = FileModeOctalFunction{}
+)
+
+func NewFileModeOctalFunction() function.Function {
+	return FileModeOctalFunction{}
+}
+
+// FileModeOctalFunction converts a symbolic file mode to octal.
+type FileModeOctalFunction struct{}
+
+func (r FileModeOctalFunction) Metadata(_ context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "file_mode_octal"
+}
+
+func (r FileModeOctalFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Converts a symbolic file mode to octal",
+		MarkdownDescription: "Returns the four digit octal form of the absolute symbolic mode `symbolic`, e.g. `0640` for " +
+			"`u=rw,g=r,o=`, failing the plan when it is invalid. Classes may be combined (`ug=rw`) or be `a` for all of " +
+			"them, `s` sets the setuid or setgid bit and `t` the sticky bit. Classes not listed get no permissions.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "symbolic",
+				MarkdownDescription: "Absolute symbolic mode, e.g. `u=rw,g=r,o=`",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (r FileModeOctalFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var mode string
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &mode))
+
+	if resp.Error != nil {
+		return
+	}
+
+	converted, err := services.SymbolicToOctal(mode)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, converted))
+}
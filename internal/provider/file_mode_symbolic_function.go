@@ -0,0 +1,58 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+var (
+	_ function.Function = FileModeSymbolicFunction{}
+)
+
+func NewFileModeSymbolicFunction() function.Function {
+	return FileModeSymbolicFunction{}
+}
+
+// FileModeSymbolicFunction converts an octal file mode to its symbolic form.
+type FileModeSymbolicFunction struct{}
+
+func (r FileModeSymbolicFunction) Metadata(_ context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "file_mode_symbolic"
+}
+
+func (r FileModeSymbolicFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Converts an octal file mode to its symbolic form",
+		MarkdownDescription: "Returns the symbolic form of the octal mode `octal`, e.g. `u=rw,g=r,o=` for `0640`, failing the plan when it is invalid.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "octal",
+				MarkdownDescription: "Octal mode, e.g. `0640` or `755`",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (r FileModeSymbolicFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var mode string
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &mode))
+
+	if resp.Error != nil {
+		return
+	}
+
+	converted, err := services.OctalToSymbolic(mode)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, converted))
+}
@@ -4,105 +4,91 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"strings"
+	"path/filepath"
 )
 
+// directoryMode is the mode of the parent directories created for a file.
+const directoryMode os.FileMode = 0o755
+
 func FileExists(path string) bool {
 	_, err := os.Stat(path)
 	return !errors.Is(err, os.ErrNotExist)
 }
 
+// FileNotFoundError reports a local file that does not exist.
 type FileNotFoundError struct {
-	error
+	Path string
+	Err  error
 }
 
-func ReadFile(path string) ([]byte, error) {
-	if !FileExists(path) {
-		fmt.Printf("File %s not found\n", path)
-		return nil, FileNotFoundError{error: errors.New("FileNotFoundError")}
-	}
+func (e *FileNotFoundError) Error() string {
+	return fmt.Sprintf("file %s not found", e.Path)
+}
+
+func (e *FileNotFoundError) Unwrap() error {
+	return e.Err
+}
 
+func ReadFile(path string) ([]byte, error) {
 	file, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, &FileNotFoundError{Path: path, Err: err}
+	}
 	if err != nil {
-		fmt.Println(err.Error())
 		return nil, err
 	}
 
 	return file, nil
 }
 
+// DeleteFile removes path, a missing file is not an error.
 func DeleteFile(path string) error {
-	if !FileExists(path) {
-		fmt.Printf("File %s not found\n", path)
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
 		return nil
 	}
 
-	return os.Remove(path)
+	return err
+}
+
+func GetWorkingDirectory() (string, error) {
+	return os.Getwd()
 }
 
-func GetWorkingDirectory() string {
-	dir, err := os.Getwd()
+// CreateDirectory creates the directory path and its missing parents with mode perm. An existing
+// directory is left as is, but an existing file at path is an error.
+func CreateDirectory(path string, perm os.FileMode) error {
+	err := os.MkdirAll(filepath.Clean(path), perm)
 	if err != nil {
-		fmt.Println(err.Error())
-		panic(err)
+		return fmt.Errorf("creating directory %s: %w", path, err)
 	}
 
-	return dir
+	return nil
 }
 
-func CreatePath(path string) {
-	_, err := os.Stat(path)
-	if err == nil {
-		return
-	}
+// CreateFile creates the empty file path with mode perm, creating its missing parent
+// directories. An existing file is left untouched.
+func CreateFile(path string, perm os.FileMode) error {
+	path = filepath.Clean(path)
 
-	if !os.IsNotExist(err) {
-		fmt.Printf("Error accessing path %s: %s\n", path, err.Error())
-		panic(err)
+	err := CreateDirectory(filepath.Dir(path), directoryMode)
+	if err != nil {
+		return err
 	}
 
-	pathComponents := strings.Split(path, string(os.PathSeparator))
-	fmt.Printf("Path %s components: %v\n", path, pathComponents)
-
-	for i, component := range pathComponents {
-		currentPath := strings.Join(pathComponents[:i+1], string(os.PathSeparator))
-
-		if currentPath == "" {
-			currentPath = "/"
-		}
-
-		if FileExists(currentPath) {
-			fmt.Printf("Path %s already exists\n", currentPath)
-			continue
-		}
-		var err error
-		var file *os.File
-		isFile := strings.Contains(component, ".")
-
-		if isFile {
-			file, err = os.Create(currentPath)
-		} else {
-			err = os.Mkdir(currentPath, os.ModePerm)
-		}
-
-		if err != nil {
-			fmt.Printf("Failed to create path %s: %s\n", currentPath, err.Error())
-			panic(err)
-		}
-
-		if isFile {
-			file.Close()
-		}
-
-		fmt.Printf("Path %s created successfully\n", currentPath)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, perm)
+	if err != nil {
+		return fmt.Errorf("creating file %s: %w", path, err)
 	}
+
+	return file.Close()
 }
 
-func ListDirectory(path string) *[]os.DirEntry {
+func ListDirectory(path string) ([]os.DirEntry, error) {
 	files, err := os.ReadDir(path)
 	if err != nil {
-		fmt.Printf("Failed to list directory %s: %s\n", path, err.Error())
-		panic(err)
+		return nil, fmt.Errorf("listing directory %s: %w", path, err)
 	}
-	return &files
+
+	return files, nil
 }
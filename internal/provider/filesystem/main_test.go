@@ -0,0 +1,53 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreatePaths(t *testing.T) {
+	root := t.TempDir()
+
+	// Dotted directory names are directories when asked for one.
+	dir := filepath.Join(root, "conf.d", "v1.2")
+	if err := CreateDirectory(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Fatalf("expected %s to be a directory, got %v", dir, err)
+	}
+
+	// Files without an extension are files when asked for one.
+	file := filepath.Join(root, "bin", "tool")
+	if err := CreateFile(file, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(file); err != nil || !info.Mode().IsRegular() {
+		t.Fatalf("expected %s to be a file, got %v", file, err)
+	}
+
+	if err := CreateDirectory(file, 0o755); err == nil {
+		t.Fatal("expected an error creating a directory over a file")
+	}
+
+	entries, err := ListDirectory(root)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d, %v", len(entries), err)
+	}
+}
+
+func TestReadFileNotFound(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing")
+
+	_, err := ReadFile(path)
+
+	var notFound *FileNotFoundError
+	if !errors.As(err, &notFound) || notFound.Path != path || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	if err := DeleteFile(path); err != nil {
+		t.Fatalf("expected deleting a missing file to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"remote-provider/internal/provider/servers"
+	"remote-provider/internal/provider/services"
+
+	actionschema "github.com/hashicorp/terraform-plugin-framework/action/schema"
+	datasourceschema "github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	ephemeralschema "github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/objectplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// localHost is the host whose commands run on the machine running Terraform unless local is false.
+const localHost = "localhost"
+
+// webSocketURLRegexp matches the endpoints the SSH connection can be tunneled through.
+var webSocketURLRegexp = regexp.MustCompile(`^wss?://`)
+
+// HostConnectionModel describes the connection block attributes.
+type HostConnectionModel struct {
+	Host           types.String `tfsdk:"host"`
+	User           types.String `tfsdk:"user"`
+	PrivateKey     types.String `tfsdk:"private_key"`
+	Password       types.String `tfsdk:"password"`
+	SudoPassword   types.String `tfsdk:"sudo_password"`
+	TotpSecret     types.String `tfsdk:"totp_secret"`
+	BecomeMethod   types.String `tfsdk:"become_method"`
+	HostId         types.String `tfsdk:"host_id"`
+	ProxyCommand   types.String `tfsdk:"proxy_command"`
+	WebSocketURL   types.String `tfsdk:"websocket_url"`
+	ConsoleCommand types.String `tfsdk:"console_command"`
+	Local          types.Bool   `tfsdk:"local"`
+	Container      types.String `tfsdk:"container"`
+	ContainerTool  types.String `tfsdk:"container_tool"`
+}
+
+// hostConnectionSchema returns the host_connection attribute shared by every resource.
+func hostConnectionSchema() schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		Required:   true,
+		Attributes: hostConnectionAttributes(),
+	}
+}
+
+// hostConnectionsSchema returns a list of host connections for resources fanning out to many hosts.
+func hostConnectionsSchema() schema.ListNestedAttribute {
+	return schema.ListNestedAttribute{
+		Optional:            true,
+		MarkdownDescription: "Connections to every host of the group the resource applies to",
+		NestedObject: schema.NestedAttributeObject{
+			Attributes: hostConnectionAttributes(),
+		},
+	}
+}
+
+// hostConnectionAttribute describes an attribute of host_connection. The schemas of resources,
+// data sources, actions and ephemeral resources are all built from hostConnectionTable.
+type hostConnectionAttribute struct {
+	name        string
+	description string
+	required    bool
+	sensitive   bool
+	// boolean attributes are booleans instead of strings.
+	boolean    bool
+	validators []validator.String
+}
+
+// hostConnectionTable lists the attributes of host_connection.
+var hostConnectionTable = []hostConnectionAttribute{
+	{
+		name:        "host",
+		required:    true,
+		description: "Hostname or IP address of the remote host",
+	},
+	{
+		name:        "host_id",
+		description: "Stable identifier of the host, e.g. the ID of its VM, used instead of `host` in resource IDs. Resources keep their identity when only the address or credentials of a host with the same `host_id` change",
+	},
+	{
+		name:        "user",
+		description: "User name to access host. Defaults to the `REMOTE_HOST_USER` environment variable",
+	},
+	{
+		name:        "password",
+		sensitive:   true,
+		description: "Password to access host. Defaults to the `REMOTE_HOST_PASSWORD` environment variable",
+	},
+	{
+		name:        "sudo_password",
+		sensitive:   true,
+		description: "Password sudo or doas prompt for when privileged commands run on hosts without `NOPASSWD`, or the password of root with `su`, not used when `user` is `root`. Defaults to the `REMOTE_HOST_SUDO_PASSWORD` environment variable",
+	},
+	{
+		name:        "private_key",
+		description: "Private key path to access host. Defaults to the `REMOTE_HOST_PRIVATE_KEY` environment variable",
+	},
+	{
+		name:        "proxy_command",
+		description: "Local command whose standard input and output carry the SSH connection instead of TCP, like the OpenSSH `ProxyCommand`, e.g. `aws ssm start-session --target i-0abc --document-name AWS-StartSSHSession`. `%h`, `%p` and `%r` expand to the quoted host, port and user. Takes precedence over `websocket_url`",
+	},
+	{
+		name:        "websocket_url",
+		description: "`ws://` or `wss://` endpoint the SSH connection is tunneled through in binary WebSocket messages, for hosts whose port 22 is not reachable. `%h`, `%p` and `%r` expand to the escaped host, port and user, credentials in the URL are sent with basic auth",
+		validators:  []validator.String{stringMatches(webSocketURLRegexp, "must be a ws:// or wss:// URL")},
+	},
+	{
+		name:        "console_command",
+		description: "Local command attaching to the serial console of the host, e.g. `ipmitool -I lanplus -H bmc.example.com -U admin -E sol activate` or `virsh console metal-1`, used when its SSH server cannot be reached yet. The console logs in with `user` and `password` at a login prompt and runs the same commands through its shell. `%h`, `%p` and `%r` expand to the quoted host, port and user",
+	},
+	{
+		name:        "local",
+		boolean:     true,
+		description: "Run the commands and file operations of the host on the machine running Terraform instead of over SSH, e.g. to manage a CI runner with the same modules as remote hosts. `user` defaults to the local user and privileged commands read `sudo_password` from their standard input. Defaults to `true` when `host` is `localhost`",
+	},
+	{
+		name:        "container",
+		description: "Container on the host the commands and file operations run in as root, e.g. to manage the files and services of a long-lived container or LXC guest. A container name or ID for `docker`, `podman` and `lxc`, the PID of a process in the container for `nsenter`, the root directory for `chroot`, e.g. a mounted disk image customized into a golden image",
+	},
+	{
+		name:        "container_tool",
+		description: "Tool entering `container`, one of `docker`, `podman`, `lxc`, `nsenter` or `chroot`. Defaults to `docker`. `lxc`, `nsenter` and `chroot` need root on the host, they go through `become_method` without prompting when `user` is not `root`, which must be `sudo` or `doas` since `su` needs a terminal",
+		validators:  []validator.String{stringOneOf(services.ContainerTools...)},
+	},
+	{
+		name:        "become_method",
+		description: "Tool privileged commands run through, `sudo`, `doas` or `su`. Defaults to `sudo`, not used when `user` is `root`. `su` is for hosts where the user has no sudo rights but the password of root is known, set it in `sudo_password`",
+		validators:  []validator.String{stringOneOf(services.BecomeMethods...)},
+	},
+	{
+		name:        "totp_secret",
+		sensitive:   true,
+		description: "Base32 TOTP secret of the user, used to answer the one time password prompt of hosts enforcing PAM OTP on SSH. Defaults to the `REMOTE_HOST_TOTP_SECRET` environment variable",
+	},
+}
+
+// buildHostConnectionAttributes returns the attributes of hostConnectionTable built with the
+// string and boolean attribute types of a schema package.
+func buildHostConnectionAttributes[A any](stringAttribute func(hostConnectionAttribute) A, boolAttribute func(hostConnectionAttribute) A) map[string]A {
+	attributes := make(map[string]A, len(hostConnectionTable))
+	for _, attribute := range hostConnectionTable {
+		if attribute.boolean {
+			attributes[attribute.name] = boolAttribute(attribute)
+		} else {
+			attributes[attribute.name] = stringAttribute(attribute)
+		}
+	}
+
+	return attributes
+}
+
+func hostConnectionAttributes() map[string]schema.Attribute {
+	return buildHostConnectionAttributes(
+		func(attribute hostConnectionAttribute) schema.Attribute {
+			return schema.StringAttribute{
+				Required:            attribute.required,
+				Optional:            !attribute.required,
+				Sensitive:           attribute.sensitive,
+				MarkdownDescription: attribute.description,
+				Validators:          attribute.validators,
+			}
+		},
+		func(attribute hostConnectionAttribute) schema.Attribute {
+			return schema.BoolAttribute{Optional: true, MarkdownDescription: attribute.description}
+		},
+	)
+}
+
+// actionHostConnectionSchema returns the host_connection attribute of actions, whose schemas
+// use their own attribute types.
+func actionHostConnectionSchema() actionschema.SingleNestedAttribute {
+	return actionschema.SingleNestedAttribute{
+		Required: true,
+		Attributes: buildHostConnectionAttributes(
+			func(attribute hostConnectionAttribute) actionschema.Attribute {
+				return actionschema.StringAttribute{
+					Required:            attribute.required,
+					Optional:            !attribute.required,
+					Sensitive:           attribute.sensitive,
+					MarkdownDescription: attribute.description,
+					Validators:          attribute.validators,
+				}
+			},
+			func(attribute hostConnectionAttribute) actionschema.Attribute {
+				return actionschema.BoolAttribute{Optional: true, MarkdownDescription: attribute.description}
+			},
+		),
+	}
+}
+
+// dataSourceHostConnectionSchema returns the host_connection attribute of data sources.
+func dataSourceHostConnectionSchema() datasourceschema.SingleNestedAttribute {
+	return datasourceschema.SingleNestedAttribute{
+		Required: true,
+		Attributes: buildHostConnectionAttributes(
+			func(attribute hostConnectionAttribute) datasourceschema.Attribute {
+				return datasourceschema.StringAttribute{
+					Required:            attribute.required,
+					Optional:            !attribute.required,
+					Sensitive:           attribute.sensitive,
+					MarkdownDescription: attribute.description,
+					Validators:          attribute.validators,
+				}
+			},
+			func(attribute hostConnectionAttribute) datasourceschema.Attribute {
+				return datasourceschema.BoolAttribute{Optional: true, MarkdownDescription: attribute.description}
+			},
+		),
+	}
+}
+
+// ephemeralHostConnectionSchema returns the host_connection attribute of ephemeral resources.
+func ephemeralHostConnectionSchema() ephemeralschema.SingleNestedAttribute {
+	return ephemeralschema.SingleNestedAttribute{
+		Required: true,
+		Attributes: buildHostConnectionAttributes(
+			func(attribute hostConnectionAttribute) ephemeralschema.Attribute {
+				return ephemeralschema.StringAttribute{
+					Required:            attribute.required,
+					Optional:            !attribute.required,
+					Sensitive:           attribute.sensitive,
+					MarkdownDescription: attribute.description,
+					Validators:          attribute.validators,
+				}
+			},
+			func(attribute hostConnectionAttribute) ephemeralschema.Attribute {
+				return ephemeralschema.BoolAttribute{Optional: true, MarkdownDescription: attribute.description}
+			},
+		),
+	}
+}
+
+// hostConnectionRequiresReplace replaces the resource when its host connection changes, unless
+// the host keeps the same host_id: a recreated VM with a new address is still the same host.
+func hostConnectionRequiresReplace() planmodifier.Object {
+	return objectplanmodifier.RequiresReplaceIf(
+		func(ctx context.Context, req planmodifier.ObjectRequest, resp *objectplanmodifier.RequiresReplaceIfFuncResponse) {
+			stateID, planID := req.StateValue.Attributes()["host_id"], req.PlanValue.Attributes()["host_id"]
+			resp.RequiresReplace = stateID == nil || stateID.IsNull() || !stateID.Equal(planID)
+		},
+		"Requires replacement when the host connection changes, unless host_id is set and unchanged",
+		"Requires replacement when the host connection changes, unless `host_id` is set and unchanged",
+	)
+}
+
+// hostID returns the identifier of the host used in resource IDs: host_id when set, the
+// address otherwise.
+func (m *HostConnectionModel) hostID() string {
+	if !m.HostId.IsNull() && !m.HostId.IsUnknown() {
+		return m.HostId.ValueString()
+	}
+
+	return m.Host.ValueString()
+}
+
+// serverGroup builds a servers.ServerGroup out of connections, skipping duplicated hosts.
+func serverGroup(name string, connections []*HostConnectionModel) *servers.ServerGroup {
+	group := &servers.ServerGroup{Name: name}
+	seen := map[string]bool{}
+
+	for _, connection := range connections {
+		server := connection.server()
+		if seen[server.Name] {
+			continue
+		}
+
+		seen[server.Name] = true
+		group.Servers = append(group.Servers, server)
+	}
+
+	return group
+}
+
+// server builds the servers.Server used by the SSH service to reach the host. Unset
+// credentials default to the REMOTE_HOST_* environment variables so CI systems can inject them.
+func (m *HostConnectionModel) server() *servers.Server {
+	return &servers.Server{
+		Address:        m.Host.ValueString(),
+		PrivateKeyPath: envDefault(m.PrivateKey, "REMOTE_HOST_PRIVATE_KEY"),
+		User:           envDefault(m.User, "REMOTE_HOST_USER"),
+		Password:       envDefault(m.Password, "REMOTE_HOST_PASSWORD"),
+		SudoPassword:   envDefault(m.SudoPassword, "REMOTE_HOST_SUDO_PASSWORD"),
+		TOTPSecret:     envDefault(m.TotpSecret, "REMOTE_HOST_TOTP_SECRET"),
+		BecomeMethod:   m.BecomeMethod.ValueString(),
+		ProxyCommand:   m.ProxyCommand.ValueString(),
+		WebSocketURL:   m.WebSocketURL.ValueString(),
+		ConsoleCommand: m.ConsoleCommand.ValueString(),
+		Local:          m.Local.ValueBool() || (m.Local.IsNull() && m.Host.ValueString() == localHost),
+		Container:      m.Container.ValueString(),
+		ContainerTool:  m.ContainerTool.ValueString(),
+		Port:           22,
+		Name:           m.Host.ValueString(),
+	}
+}
+
+// envDefault returns value, or the content of the environment variable name when it is not set.
+func envDefault(value types.String, name string) string {
+	if value.IsNull() || value.IsUnknown() {
+		return os.Getenv(name)
+	}
+
+	return value.ValueString()
+}
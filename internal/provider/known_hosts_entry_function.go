@@ -0,0 +1,74 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+var (
+	_ function.Function = KnownHostsEntryFunction{}
+)
+
+func NewKnownHostsEntryFunction() function.Function {
+	return KnownHostsEntryFunction{}
+}
+
+// KnownHostsEntryFunction builds a known_hosts line for a host key.
+type KnownHostsEntryFunction struct{}
+
+func (r KnownHostsEntryFunction) Metadata(_ context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "known_hosts_entry"
+}
+
+func (r KnownHostsEntryFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Builds a known_hosts line",
+		MarkdownDescription: "Returns the OpenSSH `known_hosts` line trusting `public_key` for `host` on `port`, e.g. to " +
+			"bootstrap the trust of other tooling. Ports other than 22 use the `[host]:port` form. With `hashed`, the " +
+			"host is hashed with a random salt as `ssh-keygen -H` does, so the line changes on every evaluation.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "host",
+				MarkdownDescription: "Host name or address",
+			},
+			function.Int64Parameter{
+				Name:                "port",
+				MarkdownDescription: "SSH port of the host",
+			},
+			function.StringParameter{
+				Name:                "public_key",
+				MarkdownDescription: "Host public key in `authorized_keys` format, e.g. `ssh-ed25519 AAAA...`",
+			},
+			function.BoolParameter{
+				Name:                "hashed",
+				MarkdownDescription: "Whether to hash the host",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (r KnownHostsEntryFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var host, publicKey string
+	var port int64
+	var hashed bool
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &host, &port, &publicKey, &hashed))
+
+	if resp.Error != nil {
+		return
+	}
+
+	line, err := services.KnownHostsLine(host, port, publicKey, hashed)
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, line))
+}
@@ -5,20 +5,39 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"remote-provider/internal/provider/servers"
 	"remote-provider/internal/provider/services"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework/action"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 // Ensure RemoteHostProvider satisfies various provider interfaces.
 var _ provider.Provider = &RemoteHostProvider{}
 var _ provider.ProviderWithFunctions = &RemoteHostProvider{}
 var _ provider.ProviderWithEphemeralResources = &RemoteHostProvider{}
+var _ provider.ProviderWithActions = &RemoteHostProvider{}
+
+// configuredServices keeps track of the SSH services handed out by Configure so
+// Shutdown can clean up their remote workspaces once Terraform stops the provider.
+var configuredServices struct {
+	sync.Mutex
+	services []*services.SSHService
+}
 
 // RemoteHostProvider defines the provider implementation.
 type RemoteHostProvider struct {
@@ -29,7 +48,71 @@ type RemoteHostProvider struct {
 }
 
 // RemoteHostProviderModel describes the provider data model.
-type RemoteHostProviderModel struct{}
+type RemoteHostProviderModel struct {
+	MaxParallelHosts           types.Int64            `tfsdk:"max_parallel_hosts"`
+	MaxParallelSessionsPerHost types.Int64            `tfsdk:"max_parallel_sessions_per_host"`
+	SuppressBanners            types.Bool             `tfsdk:"suppress_banners"`
+	Umask                      types.String           `tfsdk:"umask"`
+	DefaultFileMode            types.String           `tfsdk:"default_file_mode"`
+	DefaultDirectoryMode       types.String           `tfsdk:"default_directory_mode"`
+	RetryableErrors            []RetryableErrorModel  `tfsdk:"retryable_errors"`
+	DebugSSH                   types.Bool             `tfsdk:"debug_ssh"`
+	DiscoverIdentities         types.Bool             `tfsdk:"discover_identities"`
+	CommandWrapper             *CommandWrapperModel   `tfsdk:"command_wrapper"`
+	SessionRecording           *SessionRecordingModel `tfsdk:"session_recording"`
+	FakeTransport              types.Bool             `tfsdk:"fake_transport"`
+	FakeResponses              []FakeResponseModel    `tfsdk:"fake_responses"`
+	ApplyHooks                 *ApplyHooksModel       `tfsdk:"apply_hooks"`
+	StateEncryptionKey         types.String           `tfsdk:"state_encryption_key"`
+	HostGroups                 types.Map              `tfsdk:"host_groups"`
+	Agent                      types.Bool             `tfsdk:"agent"`
+	AgentBinary                types.String           `tfsdk:"agent_binary"`
+}
+
+// HostGroupModel describes a group of hosts sharing variables.
+type HostGroupModel struct {
+	Hosts []types.String `tfsdk:"hosts"`
+	Vars  types.Map      `tfsdk:"vars"`
+}
+
+// RetryableErrorModel describes a command failure the provider retries.
+type RetryableErrorModel struct {
+	Pattern    types.String `tfsdk:"pattern"`
+	MaxRetries types.Int64  `tfsdk:"max_retries"`
+	Delay      types.String `tfsdk:"delay"`
+}
+
+// CommandWrapperModel describes how commands are wrapped for restricted accounts.
+type CommandWrapperModel struct {
+	Prefix types.String `tfsdk:"prefix"`
+	Suffix types.String `tfsdk:"suffix"`
+	Quote  types.Bool   `tfsdk:"quote"`
+}
+
+// SessionRecordingModel describes where and when privileged commands are recorded.
+type SessionRecordingModel struct {
+	Directory      types.String   `tfsdk:"directory"`
+	AllPrivileged  types.Bool     `tfsdk:"all_privileged_commands"`
+	RedactPatterns []types.String `tfsdk:"redact_patterns"`
+}
+
+// ApplyHooksModel describes the commands run around the changes made to every host.
+type ApplyHooksModel struct {
+	PreApplyCommand  types.String `tfsdk:"pre_apply_command"`
+	PostApplyCommand types.String `tfsdk:"post_apply_command"`
+	Privileged       types.Bool   `tfsdk:"privileged"`
+}
+
+// FakeResponseModel describes the answer of the fake transport to matching commands.
+type FakeResponseModel struct {
+	Pattern  types.String `tfsdk:"pattern"`
+	Stdout   types.String `tfsdk:"stdout"`
+	Stderr   types.String `tfsdk:"stderr"`
+	ExitCode types.Int64  `tfsdk:"exit_code"`
+}
+
+// fakeTransportEnv enables the fake transport without changing the provider configuration.
+const fakeTransportEnv = "REMOTE_HOST_FAKE_TRANSPORT"
 
 func (p *RemoteHostProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
 	resp.TypeName = "remote_host"
@@ -38,7 +121,206 @@ func (p *RemoteHostProvider) Metadata(ctx context.Context, req provider.Metadata
 
 func (p *RemoteHostProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		Attributes: map[string]schema.Attribute{},
+		MarkdownDescription: "Manages hosts over SSH. Connections are opened once per provider process and shared by " +
+			"the resources reaching a host with the same address, credentials, privilege escalation and transport. " +
+			"Terraform starts a new provider process for each phase of an operation, so every host is dialed once " +
+			"when planning and once more when applying",
+
+		Attributes: map[string]schema.Attribute{
+			"max_parallel_hosts": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "Maximum number of hosts worked on at the same time. Unlimited when unset",
+			},
+			"max_parallel_sessions_per_host": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "Maximum number of concurrent SSH sessions on a single host, keep it below the sshd `MaxSessions`/`MaxStartups` limits. Unlimited when unset",
+			},
+			"suppress_banners": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Delimit command output with sentinel lines so login banners, MOTDs and shell start-up output are stripped before parsing. Defaults to `true`",
+			},
+			"state_encryption_key": schema.StringAttribute{
+				Optional:            true,
+				Sensitive:           true,
+				MarkdownDescription: "Base64 encoded 32 byte key, e.g. the output of `openssl rand -base64 32`, encrypting with AES-256-GCM the content resources store in the state when their `state_content` is `encrypted`. Defaults to the `REMOTE_HOST_STATE_ENCRYPTION_KEY` environment variable. Losing the key only means the content is read again from the hosts",
+			},
+			"umask": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Octal umask applied to every command executed on the hosts, e.g. `0027`",
+			},
+			"default_file_mode": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Octal mode of files created by resources that do not set one explicitly. Defaults to `0644`",
+			},
+			"default_directory_mode": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Octal mode of directories created by resources that do not set one explicitly. Defaults to `0755`",
+			},
+			"debug_ssh": schema.BoolAttribute{
+				Optional: true,
+				MarkdownDescription: "Log the SSH handshake of every connection: offered key exchange, cipher and MAC algorithms, " +
+					"host key, server version and banner, and each authentication method attempted. " +
+					"Logged at the `DEBUG` level, e.g. with `TF_LOG_PROVIDER=DEBUG`. Defaults to `false`",
+			},
+			"command_wrapper": schema.SingleNestedAttribute{
+				Optional: true,
+				MarkdownDescription: "Wraps every command sent to the hosts, for appliance or bastion accounts with a restricted " +
+					"login shell (`rbash`) or an sshd `ForceCommand` wrapper, e.g. `{ prefix = \"sh -c \", quote = true }`",
+				Attributes: map[string]schema.Attribute{
+					"prefix": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "Text prepended to every command",
+					},
+					"suffix": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "Text appended to every command",
+					},
+					"quote": schema.BoolAttribute{
+						Optional:            true,
+						MarkdownDescription: "Whether to pass the command as a single single-quoted shell word between the prefix and the suffix. Defaults to `false`",
+					},
+				},
+			},
+			"session_recording": schema.SingleNestedAttribute{
+				Optional: true,
+				MarkdownDescription: "Records the output of privileged commands to asciinema v2 `.cast` files for compliance review. " +
+					"The passwords and TOTP secret of the hosts are redacted from the recordings, the file content written is replaced by " +
+					"`[CONTENT]` and the commands reading file content are not recorded",
+				Attributes: map[string]schema.Attribute{
+					"directory": schema.StringAttribute{
+						Required:            true,
+						MarkdownDescription: "Local directory receiving one file per recorded command, created with mode `0700`",
+					},
+					"all_privileged_commands": schema.BoolAttribute{
+						Optional:            true,
+						MarkdownDescription: "Whether to record every privileged command. Otherwise only resources setting `record_session` are recorded. Defaults to `true`",
+					},
+					"redact_patterns": schema.ListAttribute{
+						Optional:            true,
+						ElementType:         types.StringType,
+						MarkdownDescription: "Regular expressions whose matches are replaced by `[REDACTED]` in the recordings, e.g. `token=\\S+`",
+					},
+				},
+			},
+			"apply_hooks": schema.SingleNestedAttribute{
+				Optional: true,
+				MarkdownDescription: "Commands run on every host the provider changes during a run, e.g. to put it in maintenance " +
+					"mode or flush caches. Hosts that are only read, during plans or by data sources, are left alone",
+				Attributes: map[string]schema.Attribute{
+					"pre_apply_command": schema.StringAttribute{
+						Optional: true,
+						MarkdownDescription: "Command run once per host before the first create, update, delete or action on it. " +
+							"Operations on the host wait for it, and all of them fail when it fails",
+					},
+					"post_apply_command": schema.StringAttribute{
+						Optional: true,
+						MarkdownDescription: "Command run once per changed host after every operation, when Terraform stops the " +
+							"provider at the end of the run. Keep it short, Terraform only waits a few seconds for the provider to exit. " +
+							"Skipped on hosts whose `pre_apply_command` failed",
+					},
+					"privileged": schema.BoolAttribute{
+						Optional:            true,
+						MarkdownDescription: "Whether to run the hooks as root. Defaults to `false`",
+					},
+				},
+			},
+			"agent": schema.BoolAttribute{
+				Optional: true,
+				MarkdownDescription: "Upload a helper once per host to `~/.cache/remote-host` that hashes the files of the `checksum_of` " +
+					"attribute of `remote_exec` and reads the metadata of the `remote_file` resources whose content is read over SFTP or not " +
+					"kept, instead of parsing the output of shell commands. The helper is the provider binary itself, so it " +
+					"only runs on hosts of the same OS and architecture as the machine running Terraform unless `agent_binary` is set. " +
+					"Other hosts, containers and privileged reads of non-root users keep using shell commands. Defaults to `false`",
+			},
+			"agent_binary": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "Local path of the helper uploaded when `agent` is enabled, e.g. the provider binary built " +
+					"for the architecture of the hosts. Defaults to the running provider binary",
+			},
+			"fake_transport": schema.BoolAttribute{
+				Optional: true,
+				MarkdownDescription: "Answer commands from `fake_responses` instead of connecting to the hosts, so modules can be " +
+					"exercised with `terraform test` without real hosts. Also enabled by setting the `" + fakeTransportEnv + "` " +
+					"environment variable to `1`. Commands matching no response succeed without output, and the probes of the " +
+					"provider are answered as a Linux host with a `/tmp/remote-host.fake` workspace. Defaults to `false`",
+			},
+			"fake_responses": schema.ListNestedAttribute{
+				Optional:            true,
+				MarkdownDescription: "Answers of the fake transport, the first response whose pattern matches a command is used",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"pattern": schema.StringAttribute{
+							Required:            true,
+							MarkdownDescription: "Regular expression matched against the commands, e.g. `^systemctl is-active nginx`",
+						},
+						"stdout": schema.StringAttribute{
+							Optional:            true,
+							MarkdownDescription: "Standard output of matching commands",
+						},
+						"stderr": schema.StringAttribute{
+							Optional:            true,
+							MarkdownDescription: "Standard error of matching commands",
+						},
+						"exit_code": schema.Int64Attribute{
+							Optional:            true,
+							MarkdownDescription: "Exit code of matching commands, failing them when not zero. Defaults to `0`",
+						},
+					},
+				},
+			},
+			"discover_identities": schema.BoolAttribute{
+				Optional: true,
+				MarkdownDescription: "Offer the keys of the SSH agent (`SSH_AUTH_SOCK`) and the unencrypted default identity files " +
+					"(`~/.ssh/id_rsa`, `~/.ssh/id_ed25519`, ...) to hosts configured without `password` nor `private_key`, as " +
+					"OpenSSH does. Defaults to `true`",
+			},
+			"host_groups": schema.MapNestedAttribute{
+				Optional: true,
+				MarkdownDescription: "Groups of hosts keyed by name, whose variables commands reference with `{{ var.name }}`, " +
+					"e.g. in the `command` of `remote_host_exec`, or read with the `remote_host_vars` data source for templates. " +
+					"The variables of the `" + services.AllHostsGroup + "` group apply to every host, the ones of the other groups " +
+					"listing a host override them in group name order",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"hosts": schema.ListAttribute{
+							Optional:            true,
+							ElementType:         types.StringType,
+							MarkdownDescription: "Hosts of the group, as set in the `host` of their `host_connection`",
+						},
+						"vars": schema.MapAttribute{
+							Optional:            true,
+							ElementType:         types.StringType,
+							MarkdownDescription: "Variables of the hosts of the group",
+						},
+					},
+				},
+			},
+			"retryable_errors": schema.ListNestedAttribute{
+				Optional: true,
+				MarkdownDescription: "Failures retried for every command executed by the provider, e.g. " +
+					"`{ pattern = \"Could not get lock /var/lib/dpkg/lock\" }` to wait for unattended upgrades. " +
+					"Only use patterns of failures that happen before the command changes anything",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"pattern": schema.StringAttribute{
+							Required:            true,
+							MarkdownDescription: "Regular expression matched against the output of failed commands",
+						},
+						"max_retries": schema.Int64Attribute{
+							Optional:            true,
+							MarkdownDescription: "Maximum number of retries for failures matching the pattern. Defaults to `3`",
+						},
+						"delay": schema.StringAttribute{
+							Optional:            true,
+							MarkdownDescription: "Duration to wait before retrying, e.g. `30s`. Defaults to `10s`",
+							Validators: []validator.String{
+								durationValidator{},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 }
 
@@ -51,33 +333,254 @@ func (p *RemoteHostProvider) Configure(ctx context.Context, req provider.Configu
 		return
 	}
 
-	sshService := &services.SSHService{}
+	for attribute, value := range map[string]types.String{
+		"umask":                  data.Umask,
+		"default_file_mode":      data.DefaultFileMode,
+		"default_directory_mode": data.DefaultDirectoryMode,
+	} {
+		if !value.IsNull() && !validFileMode(value.ValueString()) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attribute),
+				"Invalid Permission Policy",
+				fmt.Sprintf("Expected an octal mode such as 0644, got: %s", value.ValueString()),
+			)
+		}
+	}
+
+	var retryPolicies []services.RetryPolicy
+	for i, retryable := range data.RetryableErrors {
+		pattern, err := regexp.Compile(retryable.Pattern.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("retryable_errors").AtListIndex(i).AtName("pattern"),
+				"Invalid Retry Pattern",
+				fmt.Sprintf("Unable to compile the regular expression, got error: %s", err),
+			)
+			continue
+		}
+
+		policy := services.RetryPolicy{Pattern: pattern, MaxRetries: 3, Delay: 10 * time.Second}
+		if !retryable.MaxRetries.IsNull() {
+			policy.MaxRetries = int(retryable.MaxRetries.ValueInt64())
+		}
+		if !retryable.Delay.IsNull() {
+			policy.Delay, _ = time.ParseDuration(retryable.Delay.ValueString())
+		}
+
+		retryPolicies = append(retryPolicies, policy)
+	}
+
+	var sessionRecording *services.SessionRecording
+	if data.SessionRecording != nil {
+		sessionRecording = &services.SessionRecording{
+			Directory: data.SessionRecording.Directory.ValueString(),
+			Always:    data.SessionRecording.AllPrivileged.IsNull() || data.SessionRecording.AllPrivileged.ValueBool(),
+		}
+
+		for i, value := range data.SessionRecording.RedactPatterns {
+			pattern, err := regexp.Compile(value.ValueString())
+			if err != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("session_recording").AtName("redact_patterns").AtListIndex(i),
+					"Invalid Redaction Pattern",
+					fmt.Sprintf("Unable to compile the regular expression, got error: %s", err),
+				)
+				continue
+			}
+
+			sessionRecording.Redact = append(sessionRecording.Redact, pattern)
+		}
+	}
+
+	var fakeTransport *services.FakeTransport
+	if data.FakeTransport.ValueBool() || os.Getenv(fakeTransportEnv) == "1" {
+		fakeTransport = &services.FakeTransport{}
+		for i, response := range data.FakeResponses {
+			pattern, err := regexp.Compile(response.Pattern.ValueString())
+			if err != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("fake_responses").AtListIndex(i).AtName("pattern"),
+					"Invalid Fake Response Pattern",
+					fmt.Sprintf("Unable to compile the regular expression, got error: %s", err),
+				)
+				continue
+			}
+
+			fakeTransport.Responses = append(fakeTransport.Responses, services.FakeResponse{
+				Pattern:  pattern,
+				Stdout:   response.Stdout.ValueString(),
+				Stderr:   response.Stderr.ValueString(),
+				ExitCode: int(response.ExitCode.ValueInt64()),
+			})
+		}
+	}
+
+	groups := map[string]HostGroupModel{}
+	if !data.HostGroups.IsNull() && !data.HostGroups.IsUnknown() {
+		resp.Diagnostics.Append(data.HostGroups.ElementsAs(ctx, &groups, false)...)
+	}
+
+	var hostGroups []*servers.ServerGroup
+	for name, group := range groups {
+		vars := map[string]string{}
+		if !group.Vars.IsNull() && !group.Vars.IsUnknown() {
+			resp.Diagnostics.Append(group.Vars.ElementsAs(ctx, &vars, false)...)
+		}
+
+		serverGroup := &servers.ServerGroup{Name: name, Args: map[string]any{}}
+		for key, value := range vars {
+			serverGroup.Args[key] = value
+		}
+		for _, host := range group.Hosts {
+			serverGroup.Servers = append(serverGroup.Servers, &servers.Server{Name: host.ValueString(), Address: host.ValueString()})
+		}
+
+		hostGroups = append(hostGroups, serverGroup)
+	}
+
+	var stateCipher *services.StateCipher
+	if key := envDefault(data.StateEncryptionKey, "REMOTE_HOST_STATE_ENCRYPTION_KEY"); key != "" {
+		var err error
+		stateCipher, err = services.NewStateCipher(key)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("state_encryption_key"),
+				"Invalid State Encryption Key",
+				fmt.Sprintf("Unable to use the key, got error: %s", err),
+			)
+		}
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var commandWrapper *services.CommandWrapper
+	if data.CommandWrapper != nil {
+		commandWrapper = &services.CommandWrapper{
+			Prefix: data.CommandWrapper.Prefix.ValueString(),
+			Suffix: data.CommandWrapper.Suffix.ValueString(),
+			Quote:  data.CommandWrapper.Quote.ValueBool(),
+		}
+	}
+
+	var applyHooks *services.ApplyHooks
+	if data.ApplyHooks != nil {
+		applyHooks = &services.ApplyHooks{
+			PreApply:   data.ApplyHooks.PreApplyCommand.ValueString(),
+			PostApply:  data.ApplyHooks.PostApplyCommand.ValueString(),
+			Privileged: data.ApplyHooks.Privileged.ValueBool(),
+		}
+	}
+
+	sshService := &services.SSHService{
+		MaxParallelHosts:           int(data.MaxParallelHosts.ValueInt64()),
+		MaxParallelSessionsPerHost: int(data.MaxParallelSessionsPerHost.ValueInt64()),
+		OutputFraming:              data.SuppressBanners.IsNull() || data.SuppressBanners.ValueBool(),
+		Umask:                      data.Umask.ValueString(),
+		DefaultFileMode:            data.DefaultFileMode.ValueString(),
+		DefaultDirectoryMode:       data.DefaultDirectoryMode.ValueString(),
+		RetryPolicies:              retryPolicies,
+		DebugSSH:                   data.DebugSSH.ValueBool(),
+		DiscoverIdentities:         data.DiscoverIdentities.IsNull() || data.DiscoverIdentities.ValueBool(),
+		Recording:                  sessionRecording,
+		Wrapper:                    commandWrapper,
+		Fake:                       fakeTransport,
+		Hooks:                      applyHooks,
+		StateCipher:                stateCipher,
+		HostGroups:                 hostGroups,
+		Agent:                      data.Agent.ValueBool(),
+		AgentBinary:                data.AgentBinary.ValueString(),
+	}
+
+	configuredServices.Lock()
+	configuredServices.services = append(configuredServices.services, sshService)
+	configuredServices.Unlock()
 
 	resp.DataSourceData = sshService
 	resp.ResourceData = sshService
+	resp.ActionData = sshService
+	resp.EphemeralResourceData = sshService
 }
 
 func (p *RemoteHostProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewRemoteFileResource,
+		NewRemoteNodeExporterResource,
+		NewRemoteExecResource,
+		NewRemoteMaintenanceWindowResource,
+		NewRemoteUserSSHAccessResource,
+		NewRemoteFileSetResource,
+		NewRemoteDirectoryResource,
+		NewRemoteArtifactResource,
+		NewRemotePackageUpgradeResource,
+		NewRemoteUnattendedUpgradesResource,
+		NewRemoteSnapResource,
+		NewRemoteFlatpakResource,
+		NewRemoteHomebrewPackageResource,
+		NewRemoteHomebrewTapResource,
+		NewRemoteBinaryReleaseResource,
+		NewRemoteContainerRuntimeResource,
+		NewRemoteWireGuardPeerResource,
+		NewRemoteCertificateResource,
+		NewRemoteKVFactResource,
+		NewRemoteJSONFileResource,
+		NewRemoteYAMLFileResource,
+		NewLegacyRemoteFileResource,
+		NewLegacyRemoteNodeExporterResource,
+		NewLegacyRemoteExecResource,
+		NewLegacyRemoteMaintenanceWindowResource,
+		NewLegacyRemoteUserSSHAccessResource,
+	}
+}
+
+func (p *RemoteHostProvider) Actions(ctx context.Context) []func() action.Action {
+	return []func() action.Action{
+		NewRemoteRestartServiceAction,
+		NewRemoteRunScriptAction,
+		NewRemoteRebootAction,
 	}
 }
 
 func (p *RemoteHostProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
 	return []func() ephemeral.EphemeralResource{
 		NewExampleEphemeralResource,
+		NewRemoteCommandEphemeralResource,
 	}
 }
 
 func (p *RemoteHostProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		NewExampleDataSource,
+		NewRemoteValidationDataSource,
+		NewLegacyRemoteValidationDataSource,
+		NewRemoteDisksDataSource,
+		NewRemotePartitionsDataSource,
+		NewRemoteOpenPortsDataSource,
+		NewRemoteMACStatusDataSource,
+		NewRemoteUptimeDataSource,
+		NewRemoteCommandHistoryDataSource,
+		NewRemoteWaitForFileDataSource,
+		NewRemoteChecksumDataSource,
+		NewRemoteFactsDataSource,
+		NewRemoteIdentityDataSource,
+		NewRemoteComplianceDataSource,
+		NewRemoteVarsDataSource,
+		NewRemoteFleetSummaryDataSource,
+		NewRemoteUserKeysDataSource,
+		NewRemoteLoginsDataSource,
 	}
 }
 
 func (p *RemoteHostProvider) Functions(ctx context.Context) []func() function.Function {
 	return []func() function.Function{
 		NewExampleFunction,
+		NewRemoteFileContentFunction,
+		NewCronExpressionFunction,
+		NewUnitFileFunction,
+		NewFileModeOctalFunction,
+		NewFileModeSymbolicFunction,
+		NewKnownHostsEntryFunction,
 	}
 }
 
@@ -88,3 +591,18 @@ func New(version string) func() provider.Provider {
 		}
 	}
 }
+
+// Shutdown removes the remote workspaces and closes the SSH connections opened by
+// every configured provider instance. It is called once the provider server stops.
+func Shutdown() error {
+	configuredServices.Lock()
+	defer configuredServices.Unlock()
+
+	var errs []error
+	for _, sshService := range configuredServices.services {
+		errs = append(errs, sshService.Close())
+	}
+	configuredServices.services = nil
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,386 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"remote-provider/internal/provider/services"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteArtifactResource{}
+var _ resource.ResourceWithModifyPlan = &RemoteArtifactResource{}
+
+func NewRemoteArtifactResource() resource.Resource {
+	return &RemoteArtifactResource{}
+}
+
+// RemoteArtifactResource uploads a large local file, such as a disk image or an installer, to a host.
+type RemoteArtifactResource struct {
+	sshService *services.SSHService
+}
+
+// RemoteArtifactResourceModel describes the resource data model.
+type RemoteArtifactResourceModel struct {
+	Id             types.String         `tfsdk:"id"`
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Source         types.String         `tfsdk:"source"`
+	Destination    RemotePath           `tfsdk:"destination"`
+	Mode           FileMode             `tfsdk:"mode"`
+	Privileged     types.Bool           `tfsdk:"privileged"`
+	Sparse         types.Bool           `tfsdk:"sparse"`
+	Checksum       types.String         `tfsdk:"checksum"`
+	Timeouts       *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemoteArtifactResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_artifact"
+}
+
+func (r *RemoteArtifactResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Large local file, such as a VM image or an installer, streamed to a host. The content never " +
+			"lives in the state: the upload is verified and tracked with its sha256 checksum",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"source": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Path of the local file to upload",
+			},
+			"destination": schema.StringAttribute{
+				CustomType:          RemotePathType{},
+				Required:            true,
+				MarkdownDescription: "Absolute path of the file on the host",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"mode": schema.StringAttribute{
+				CustomType:          FileModeType{},
+				Optional:            true,
+				MarkdownDescription: "Octal mode of the file, e.g. `0640`. Defaults to the provider `default_file_mode`",
+			},
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to install the file as root",
+				Default:             booldefault.StaticBool(false),
+			},
+			"sparse": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to leave blocks of zeros as holes in the file on hosts whose `dd` supports `conv=sparse`",
+				Default:             booldefault.StaticBool(true),
+			},
+			"checksum": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Hex encoded sha256 digest of the file on the host. A different digest of `source` plans a new upload",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Path of the file on the host",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *RemoteArtifactResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+// sourceChecksum returns the sha256 digest of the local file at source, streaming it so large
+// images are never held in memory.
+func sourceChecksum(source string) (string, error) {
+	file, err := os.Open(source)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	digest, err := services.NewHash("sha256")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(digest, file)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// ModifyPlan plans the upload of a source whose checksum differs from the file on the host.
+func (r *RemoteArtifactResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan RemoteArtifactResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+
+	if resp.Diagnostics.HasError() || plan.Source.IsUnknown() {
+		return
+	}
+
+	checksum, err := sourceChecksum(plan.Source.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("source"), "Invalid Source", fmt.Sprintf("Unable to read %s, got error: %s", plan.Source.ValueString(), err))
+		return
+	}
+
+	plan.Checksum = types.StringValue(checksum)
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+// upload streams the source of data to a private file on the host, verifies its checksum and
+// moves it to the destination.
+func (r *RemoteArtifactResource) upload(ctx context.Context, data *RemoteArtifactResourceModel) error {
+	server := data.HostConnection.server()
+	err := r.sshService.OpenConnection(ctx, server)
+	if err != nil {
+		return err
+	}
+
+	workspace, err := r.sshService.Workspace(ctx, server)
+	if err != nil {
+		return err
+	}
+
+	id := make([]byte, 8)
+	_, err = rand.Read(id)
+	if err != nil {
+		return err
+	}
+	tmpPath := workspace + "/artifact-" + hex.EncodeToString(id)
+
+	file, err := os.Open(data.Source.ValueString())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	digest, err := services.NewHash("sha256")
+	if err != nil {
+		return err
+	}
+
+	// The checksum is computed while streaming, so it matches the bytes actually sent.
+	result, err := r.sshService.Upload(ctx, server, services.UploadCommand(tmpPath, data.Sparse.ValueBool()), io.TeeReader(file, digest))
+	if err != nil {
+		if result != nil && strings.TrimSpace(result.Stderr) != "" {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(result.Stderr))
+		}
+		return err
+	}
+	checksum := hex.EncodeToString(digest.Sum(nil))
+	if !data.Checksum.IsUnknown() && !data.Checksum.IsNull() && data.Checksum.ValueString() != checksum {
+		return fmt.Errorf("%s changed since the plan", data.Source.ValueString())
+	}
+
+	quotedTmpPath := services.ShellQuote(tmpPath)
+	checksumCommand, err := services.RemoteChecksumCommand("sha256", tmpPath)
+	if err != nil {
+		return err
+	}
+
+	script := []string{
+		fmt.Sprintf(`checksum=$(%s)`, checksumCommand),
+		fmt.Sprintf(`if [ "$checksum" != - ] && [ "$checksum" != %s ]; then rm -f %s; echo "checksum mismatch: got $checksum" >&2; exit 1; fi`, checksum, quotedTmpPath),
+		fmt.Sprintf("chmod %s %s", r.sshService.FileMode(data.Mode.ValueString()), quotedTmpPath),
+	}
+	if data.Privileged.ValueBool() {
+		script = append(script, "chown 0:0 "+quotedTmpPath)
+	}
+	script = append(script, fmt.Sprintf("mv -f %s %s", quotedTmpPath, services.ShellQuote(data.Destination.ValueString())))
+
+	command := strings.Join(script, " && ")
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	_, err = runCommand(ctx, r.sshService, server, command)
+	if err != nil {
+		return err
+	}
+
+	data.Id = types.StringValue(data.Destination.ValueString())
+	data.Checksum = types.StringValue(checksum)
+
+	return nil
+}
+
+func (r *RemoteArtifactResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteArtifactResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	err := r.upload(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to upload %s, got error: %s", data.Source.ValueString(), err))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteArtifactResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteArtifactResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	command := services.ChecksumFilesCommand([]string{data.Destination.ValueString()})
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(data.HostConnection.server(), command)
+	}
+
+	result, err := runCommand(ctx, r.sshService, data.HostConnection.server(), command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the checksum of %s, got error: %s", data.Destination.ValueString(), err))
+		return
+	}
+
+	lines, err := services.ParseFileLines(result.Stdout, 1)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the checksum of %s, got error: %s", data.Destination.ValueString(), err))
+		return
+	}
+
+	switch lines[0] {
+	case "missing":
+		resp.State.RemoveResource(ctx)
+		return
+	case "-":
+		// Hosts without sha256sum keep the checksum of the last upload.
+	default:
+		data.Checksum = types.StringValue(lines[0])
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteArtifactResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state RemoteArtifactResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	// Only a different content needs a new upload, mode changes are applied in place.
+	if data.Checksum.Equal(state.Checksum) {
+		command := fmt.Sprintf("chmod %s %s", r.sshService.FileMode(data.Mode.ValueString()), services.ShellQuote(data.Destination.ValueString()))
+		if data.Privileged.ValueBool() {
+			command = privilegedCommand(data.HostConnection.server(), command)
+		}
+
+		_, err := runCommand(ctx, r.sshService, data.HostConnection.server(), command)
+		if err != nil {
+			resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to update %s, got error: %s", data.Destination.ValueString(), err))
+			return
+		}
+
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	err := r.upload(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to upload %s, got error: %s", data.Source.ValueString(), err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteArtifactResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteArtifactResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	command := "rm -f -- " + services.ShellQuote(data.Destination.ValueString())
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(data.HostConnection.server(), command)
+	}
+
+	_, err := runCommand(ctx, r.sshService, data.HostConnection.server(), command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to remove %s, got error: %s", data.Destination.ValueString(), err))
+		return
+	}
+}
@@ -0,0 +1,476 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"remote-provider/internal/provider/services"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteBinaryReleaseResource{}
+var _ resource.ResourceWithModifyPlan = &RemoteBinaryReleaseResource{}
+
+// sha256Regexp matches a hex encoded sha256 digest.
+var sha256Regexp = regexp.MustCompile(`^[0-9A-Fa-f]{64}$`)
+
+func NewRemoteBinaryReleaseResource() resource.Resource {
+	return &RemoteBinaryReleaseResource{}
+}
+
+// RemoteBinaryReleaseResource installs a single-binary tool from a GitHub release.
+type RemoteBinaryReleaseResource struct {
+	sshService *services.SSHService
+}
+
+// RemoteBinaryReleaseResourceModel describes the resource data model.
+type RemoteBinaryReleaseResourceModel struct {
+	Id               types.String         `tfsdk:"id"`
+	HostConnection   *HostConnectionModel `tfsdk:"host_connection"`
+	Repository       types.String         `tfsdk:"repository"`
+	Version          types.String         `tfsdk:"version"`
+	Asset            types.String         `tfsdk:"asset"`
+	Binary           types.String         `tfsdk:"binary"`
+	SHA256           types.String         `tfsdk:"sha256"`
+	ChecksumsAsset   types.String         `tfsdk:"checksums_asset"`
+	TargetDir        types.String         `tfsdk:"target_dir"`
+	Mode             FileMode             `tfsdk:"mode"`
+	Privileged       types.Bool           `tfsdk:"privileged"`
+	GitHubAPIURL     types.String         `tfsdk:"github_api_url"`
+	GitHubToken      types.String         `tfsdk:"github_token"`
+	InstalledVersion types.String         `tfsdk:"installed_version"`
+	AssetURL         types.String         `tfsdk:"asset_url"`
+	Checksum         types.String         `tfsdk:"checksum"`
+	Timeouts         *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemoteBinaryReleaseResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_binary_release"
+}
+
+func (r *RemoteBinaryReleaseResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Installs a single-binary tool, such as helm or vector, from a GitHub release. The release matching " +
+			"`version` is resolved by the provider on every plan, so a new matching release plans an upgrade. The host " +
+			"downloads the asset with curl or wget, verifies it, extracts `binary` from `.tar.gz`, `.tar.xz`, `.tar.bz2` " +
+			"or `.zip` archives and installs it in `target_dir`. A binary changed on the host is planned for reinstallation.",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"repository": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "GitHub repository publishing the releases as `<owner>/<repository>`, e.g. `helm/helm`",
+				Validators: []validator.String{
+					stringMatches(services.GitHubRepositoryRegexp, "must be a repository such as helm/helm"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"version": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Version constraint of the release, e.g. `~> 3.14` or `>= 0.38, < 1.0`, or `latest`. Drafts and prereleases are never selected. Defaults to `latest`",
+				Default:             stringdefault.StaticString("latest"),
+			},
+			"asset": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Glob matching the name of exactly one asset of the release, in which `{version}` stands for the version without its `v` prefix, e.g. `helm-v{version}-linux-amd64.tar.gz`",
+			},
+			"binary": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Name of the binary in the asset, and of the installed file. Defaults to the name of the repository",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"sha256": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Expected hex encoded sha256 digest of the asset. Only fits a `version` pinned to a single release",
+				Validators: []validator.String{
+					stringMatches(sha256Regexp, "must be a hex encoded sha256 digest"),
+				},
+			},
+			"checksums_asset": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Glob matching the asset listing the sha256 digests of the other assets in the `sha256sum` format, e.g. `checksums.txt` or `vector-{version}-SHA256SUMS`. Ignored when `sha256` is set",
+			},
+			"target_dir": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Directory the binary is installed in. Defaults to `/usr/local/bin`",
+				Default:             stringdefault.StaticString("/usr/local/bin"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"mode": schema.StringAttribute{
+				CustomType:          FileModeType{},
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Octal mode of the binary. Defaults to `0755`",
+				Default:             stringdefault.StaticString("0755"),
+			},
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to install the binary as root. Defaults to `true`",
+				Default:             booldefault.StaticBool(true),
+			},
+			"github_api_url": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "API the releases are listed from, e.g. `https://github.example.com/api/v3` for GitHub Enterprise. Defaults to `" + services.GitHubAPIURL + "`",
+				Default:             stringdefault.StaticString(services.GitHubAPIURL),
+			},
+			"github_token": schema.StringAttribute{
+				Optional:            true,
+				Sensitive:           true,
+				MarkdownDescription: "Token listing the releases of private repositories and raising the API rate limit. Defaults to the `GITHUB_TOKEN` environment variable. Assets of private repositories cannot be downloaded by the host",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Path of the binary on the host",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"installed_version": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Tag of the installed release",
+			},
+			"asset_url": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Download URL of the installed asset",
+			},
+			"checksum": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Hex encoded sha256 digest of the installed binary",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *RemoteBinaryReleaseResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+// defaultBinary names the binary after the repository when it is not set.
+func (data *RemoteBinaryReleaseResourceModel) defaultBinary() {
+	if data.Binary.IsUnknown() || data.Binary.IsNull() {
+		data.Binary = types.StringValue(path.Base(data.Repository.ValueString()))
+	}
+}
+
+// target returns the path of the binary on the host.
+func (data *RemoteBinaryReleaseResourceModel) target() string {
+	return path.Join(data.TargetDir.ValueString(), data.Binary.ValueString())
+}
+
+// resolveBinaryRelease returns the release matching the version constraint of data along with its asset and
+// the URL of its checksums asset, empty when the asset is verified against sha256 or not at all.
+func resolveBinaryRelease(ctx context.Context, data *RemoteBinaryReleaseResourceModel) (*services.GitHubRelease, *services.GitHubReleaseAsset, string, error) {
+	token := data.GitHubToken.ValueString()
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+
+	releases, err := services.ListGitHubReleases(ctx, http.DefaultClient, data.GitHubAPIURL.ValueString(), data.Repository.ValueString(), token)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	release, err := services.SelectGitHubRelease(releases, data.Version.ValueString())
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	asset, err := release.Asset(data.Asset.ValueString())
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	checksumsURL := ""
+	if data.SHA256.IsNull() && !data.ChecksumsAsset.IsNull() {
+		checksums, err := release.Asset(data.ChecksumsAsset.ValueString())
+		if err != nil {
+			return nil, nil, "", err
+		}
+		checksumsURL = checksums.URL
+	}
+
+	return release, asset, checksumsURL, nil
+}
+
+// ModifyPlan resolves the release matching the version constraint, planning an upgrade when it
+// differs from the installed one.
+func (r *RemoteBinaryReleaseResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan RemoteBinaryReleaseResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for _, value := range []types.String{plan.Repository, plan.Version, plan.Asset, plan.SHA256, plan.ChecksumsAsset, plan.GitHubAPIURL, plan.GitHubToken} {
+		if value.IsUnknown() {
+			return
+		}
+	}
+
+	plan.defaultBinary()
+
+	release, asset, _, err := resolveBinaryRelease(ctx, &plan)
+	if err != nil {
+		resp.Diagnostics.AddError("Release Error", fmt.Sprintf("Unable to resolve a release of %s, got error: %s", plan.Repository.ValueString(), err))
+		return
+	}
+
+	plan.InstalledVersion = types.StringValue(release.TagName)
+	if !plan.AssetURL.IsNull() && plan.AssetURL.ValueString() != asset.URL {
+		plan.Checksum = types.StringUnknown()
+	}
+	plan.AssetURL = types.StringValue(asset.URL)
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+// install downloads the planned release asset on the host and installs its binary.
+func (r *RemoteBinaryReleaseResource) install(ctx context.Context, data *RemoteBinaryReleaseResourceModel) error {
+	data.defaultBinary()
+
+	release, asset, checksumsURL, err := resolveBinaryRelease(ctx, data)
+	if err != nil {
+		return err
+	}
+	if !data.AssetURL.IsUnknown() && data.AssetURL.ValueString() != asset.URL {
+		return fmt.Errorf("release %s was published since the plan", release.TagName)
+	}
+
+	server := data.HostConnection.server()
+	tools := []string{"tar", "awk"}
+	if data.Privileged.ValueBool() {
+		tools = append(tools, services.PrivilegeTool)
+	}
+
+	err = r.sshService.Preflight(ctx, server, tools)
+	if err != nil {
+		return err
+	}
+
+	command := services.BinaryReleaseInstallCommand(asset, strings.ToLower(data.SHA256.ValueString()), checksumsURL, data.Binary.ValueString(), data.target(), data.Mode.ValueString())
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	_, err = runCommand(ctx, r.sshService, server, command)
+	if err != nil {
+		return err
+	}
+
+	checksum, err := r.checksum(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	data.Id = types.StringValue(data.target())
+	data.InstalledVersion = types.StringValue(release.TagName)
+	data.AssetURL = types.StringValue(asset.URL)
+	data.Checksum = types.StringValue(checksum)
+
+	return nil
+}
+
+// checksum returns the sha256 digest of the installed binary, "-" on hosts without a sha256
+// tool or "missing" when it does not exist.
+func (r *RemoteBinaryReleaseResource) checksum(ctx context.Context, data *RemoteBinaryReleaseResourceModel) (string, error) {
+	server := data.HostConnection.server()
+
+	command := services.ChecksumFilesCommand([]string{data.target()})
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	result, err := runCommand(ctx, r.sshService, server, command)
+	if err != nil {
+		return "", err
+	}
+
+	lines, err := services.ParseFileLines(result.Stdout, 1)
+	if err != nil {
+		return "", err
+	}
+
+	return lines[0], nil
+}
+
+func (r *RemoteBinaryReleaseResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteBinaryReleaseResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	err := r.install(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to install %s from %s, got error: %s", data.Binary.ValueString(), data.Repository.ValueString(), err))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteBinaryReleaseResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteBinaryReleaseResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	checksum, err := r.checksum(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the checksum of %s, got error: %s", data.target(), err))
+		return
+	}
+
+	switch checksum {
+	case "missing":
+		resp.State.RemoveResource(ctx)
+		return
+	case "-", data.Checksum.ValueString():
+		// Hosts without sha256sum keep the checksum of the installation.
+	default:
+		// The binary no longer comes from the release, it is installed again.
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteBinaryReleaseResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state RemoteBinaryReleaseResourceModel
+
+	// Read Terraform plan and prior state data into the models
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	// Only a different asset needs a new installation, mode changes are applied in place.
+	if data.AssetURL.Equal(state.AssetURL) {
+		command := fmt.Sprintf("chmod %s %s", data.Mode.ValueString(), services.ShellQuote(data.target()))
+		if data.Privileged.ValueBool() {
+			command = privilegedCommand(data.HostConnection.server(), command)
+		}
+
+		_, err := runCommand(ctx, r.sshService, data.HostConnection.server(), command)
+		if err != nil {
+			resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to update %s, got error: %s", data.target(), err))
+			return
+		}
+
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	err := r.install(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to upgrade %s from %s, got error: %s", data.Binary.ValueString(), data.Repository.ValueString(), err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteBinaryReleaseResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteBinaryReleaseResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	command := "rm -f -- " + services.ShellQuote(data.target())
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(data.HostConnection.server(), command)
+	}
+
+	_, err := runCommand(ctx, r.sshService, data.HostConnection.server(), command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to remove %s, got error: %s", data.target(), err))
+		return
+	}
+}
@@ -0,0 +1,514 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteCertificateResource{}
+var _ resource.ResourceWithValidateConfig = &RemoteCertificateResource{}
+var _ resource.ResourceWithModifyPlan = &RemoteCertificateResource{}
+
+func NewRemoteCertificateResource() resource.Resource {
+	return &RemoteCertificateResource{}
+}
+
+// RemoteCertificateResource installs a TLS certificate on a host, either supplied by the
+// configuration or obtained with certbot.
+type RemoteCertificateResource struct {
+	sshService *services.SSHService
+}
+
+// RemoteCertificateResourceModel describes the resource data model.
+type RemoteCertificateResourceModel struct {
+	Id                types.String         `tfsdk:"id"`
+	HostConnection    *HostConnectionModel `tfsdk:"host_connection"`
+	CertificatePEM    types.String         `tfsdk:"certificate_pem"`
+	PrivateKeyPEM     types.String         `tfsdk:"private_key_pem"`
+	ChainPEM          types.String         `tfsdk:"chain_pem"`
+	Certbot           *RemoteCertbotModel  `tfsdk:"certbot"`
+	CertificatePath   types.String         `tfsdk:"certificate_path"`
+	KeyPath           types.String         `tfsdk:"key_path"`
+	ChainPath         types.String         `tfsdk:"chain_path"`
+	FullChainPath     types.String         `tfsdk:"fullchain_path"`
+	Owner             types.String         `tfsdk:"owner"`
+	Group             types.String         `tfsdk:"group"`
+	ReloadCommand     types.String         `tfsdk:"reload_command"`
+	RenewBefore       Duration             `tfsdk:"renew_before"`
+	NotBefore         types.String         `tfsdk:"not_before"`
+	NotAfter          types.String         `tfsdk:"not_after"`
+	Serial            types.String         `tfsdk:"serial"`
+	FingerprintSHA256 types.String         `tfsdk:"fingerprint_sha256"`
+	Timeouts          *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+// RemoteCertbotModel describes the certificate certbot obtains.
+type RemoteCertbotModel struct {
+	Domains  []types.String `tfsdk:"domains"`
+	Email    types.String   `tfsdk:"email"`
+	Webroot  types.String   `tfsdk:"webroot"`
+	Staging  types.Bool     `tfsdk:"staging"`
+	CertName types.String   `tfsdk:"cert_name"`
+}
+
+func (r *RemoteCertificateResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_certificate"
+}
+
+func (r *RemoteCertificateResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	pathAttribute := func(description string, required bool) schema.StringAttribute {
+		return schema.StringAttribute{
+			Required:            required,
+			Optional:            !required,
+			MarkdownDescription: description,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.RequiresReplace(),
+			},
+		}
+	}
+
+	computed := func(description string) schema.StringAttribute {
+		return schema.StringAttribute{
+			Computed:            true,
+			MarkdownDescription: description,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		}
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Installs a TLS certificate and its private key on a host, either supplied with `certificate_pem` " +
+			"and `private_key_pem`, e.g. from the `acme` provider, or obtained on the host with certbot. `reload_command` runs " +
+			"whenever the files change, e.g. to reload a web server. In certbot mode, a deploy hook under " +
+			"`/etc/letsencrypt/renewal-hooks/deploy` installs the renewed files and runs the reload command, so the renewals " +
+			"of the certbot timer are deployed too. Once `not_after` is less than `renew_before` away, the plan forces a renewal.",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"certificate_pem": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "PEM encoded certificate to install. Conflicts with `certbot`",
+			},
+			"private_key_pem": schema.StringAttribute{
+				Optional:            true,
+				Sensitive:           true,
+				MarkdownDescription: "PEM encoded private key of the certificate, required with `certificate_pem`",
+			},
+			"chain_pem": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "PEM encoded intermediate certificates, written to `chain_path` and appended to the certificate in `fullchain_path`",
+			},
+			"certbot": schema.SingleNestedAttribute{
+				Optional:            true,
+				MarkdownDescription: "Obtains the certificate with certbot on the host instead, which must be installed. Conflicts with `certificate_pem`",
+				Attributes: map[string]schema.Attribute{
+					"domains": schema.ListAttribute{
+						Required:            true,
+						ElementType:         types.StringType,
+						MarkdownDescription: "Domains of the certificate, the first one being its subject",
+					},
+					"email": schema.StringAttribute{
+						Required:            true,
+						MarkdownDescription: "Email address of the ACME account, receiving the expiry notices",
+					},
+					"webroot": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "Document root of a running web server the HTTP challenges are served from. certbot serves them itself on port 80 when unset",
+					},
+					"staging": schema.BoolAttribute{
+						Optional:            true,
+						MarkdownDescription: "Uses the Let's Encrypt staging environment, whose certificates are not trusted",
+					},
+					"cert_name": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "Name of the certbot lineage under `" + services.CertbotLiveDir + "`. Defaults to the first domain",
+						Validators: []validator.String{
+							stringMatches(services.CertbotNameRegexp, "must be a valid certbot certificate name"),
+						},
+					},
+				},
+			},
+			"certificate_path": pathAttribute("Path the certificate is installed at", true),
+			"key_path":         pathAttribute("Path the private key is installed at, readable by its owner only", true),
+			"chain_path":       pathAttribute("Path the intermediate certificates are installed at", false),
+			"fullchain_path":   pathAttribute("Path the certificate followed by the intermediate certificates is installed at", false),
+			"owner": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Owner of the installed files. Defaults to the connecting user, or root with privilege escalation",
+			},
+			"group": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Group of the installed files",
+			},
+			"reload_command": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Command run after the files are installed, e.g. `systemctl reload nginx`",
+			},
+			"renew_before": schema.StringAttribute{
+				CustomType:          DurationType{},
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "How long before `not_after` a certbot certificate is renewed. Defaults to `720h`",
+				Default:             stringdefault.StaticString("720h"),
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier of the certificate, made of the host and the certificate path",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"not_before":         computed("RFC 3339 time the certificate is valid from"),
+			"not_after":          computed("RFC 3339 time the certificate expires at"),
+			"serial":             computed("Hexadecimal serial number of the certificate"),
+			"fingerprint_sha256": computed("Hexadecimal SHA-256 fingerprint of the certificate"),
+		},
+	}
+}
+
+func (r *RemoteCertificateResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+func (r *RemoteCertificateResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data RemoteCertificateResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	supplied := !data.CertificatePEM.IsNull()
+	if supplied == (data.Certbot != nil) {
+		resp.Diagnostics.AddAttributeError(path.Root("certificate_pem"), "Invalid Certificate Source", "exactly one of certificate_pem or certbot must be set")
+		return
+	}
+
+	if supplied && data.PrivateKeyPEM.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("private_key_pem"), "Invalid Certificate Source", "private_key_pem is required with certificate_pem")
+	}
+	if !supplied && (!data.PrivateKeyPEM.IsNull() || !data.ChainPEM.IsNull()) {
+		resp.Diagnostics.AddAttributeError(path.Root("certbot"), "Invalid Certificate Source", "private_key_pem and chain_pem cannot be set with certbot")
+	}
+	if supplied && !data.ChainPath.IsNull() && data.ChainPEM.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("chain_path"), "Invalid Certificate Source", "chain_path requires chain_pem")
+	}
+	if data.Certbot != nil && len(data.Certbot.Domains) == 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("certbot").AtName("domains"), "Invalid Certificate Source", "at least one domain is required")
+	}
+}
+
+// setInfo stores the description of the installed certificate in data.
+func (data *RemoteCertificateResourceModel) setInfo(info services.CertificateInfo) {
+	data.NotBefore = types.StringValue(info.NotBefore.UTC().Format(time.RFC3339))
+	data.NotAfter = types.StringValue(info.NotAfter.UTC().Format(time.RFC3339))
+	data.Serial = types.StringValue(info.Serial)
+	data.FingerprintSHA256 = types.StringValue(info.FingerprintSHA256)
+}
+
+// renewalDue reports whether the certificate of data expires within renew_before.
+func (data *RemoteCertificateResourceModel) renewalDue() bool {
+	notAfter, err := time.Parse(time.RFC3339, data.NotAfter.ValueString())
+	if err != nil {
+		return false
+	}
+
+	renewBefore, err := time.ParseDuration(data.RenewBefore.ValueString())
+	if err != nil {
+		return false
+	}
+
+	return time.Now().Add(renewBefore).After(notAfter)
+}
+
+// ModifyPlan plans the description of a supplied certificate, and a renewal of a certbot one
+// once it is due.
+func (r *RemoteCertificateResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data RemoteCertificateResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Certbot == nil {
+		if data.CertificatePEM.IsUnknown() || data.CertificatePEM.IsNull() {
+			return
+		}
+
+		info, err := services.ParseCertificatePEM([]byte(data.CertificatePEM.ValueString()))
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("certificate_pem"), "Invalid Certificate", err.Error())
+			return
+		}
+		data.setInfo(info)
+	} else if !req.State.Raw.IsNull() && data.renewalDue() {
+		data.NotBefore = types.StringUnknown()
+		data.NotAfter = types.StringUnknown()
+		data.Serial = types.StringUnknown()
+		data.FingerprintSHA256 = types.StringUnknown()
+	} else {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &data)...)
+}
+
+// files returns the places data installs the certificate at.
+func (data *RemoteCertificateResourceModel) files() services.CertificateFiles {
+	return services.CertificateFiles{
+		Certificate:   data.CertificatePath.ValueString(),
+		Key:           data.KeyPath.ValueString(),
+		Chain:         data.ChainPath.ValueString(),
+		FullChain:     data.FullChainPath.ValueString(),
+		Owner:         data.Owner.ValueString(),
+		Group:         data.Group.ValueString(),
+		ReloadCommand: data.ReloadCommand.ValueString(),
+	}
+}
+
+// certbotRequest returns the certbot request of data.
+func (data *RemoteCertificateResourceModel) certbotRequest(force bool) services.CertbotRequest {
+	request := services.CertbotRequest{
+		Name:    data.Certbot.CertName.ValueString(),
+		Email:   data.Certbot.Email.ValueString(),
+		Webroot: data.Certbot.Webroot.ValueString(),
+		Staging: data.Certbot.Staging.ValueBool(),
+		Force:   force,
+	}
+	for _, domain := range data.Certbot.Domains {
+		request.Domains = append(request.Domains, domain.ValueString())
+	}
+	if request.Name == "" && len(request.Domains) > 0 {
+		request.Name = request.Domains[0]
+	}
+
+	return request
+}
+
+// install installs the certificate of data, obtaining it with certbot first in certbot mode,
+// and stores the description of the installed certificate in data.
+func (r *RemoteCertificateResource) install(ctx context.Context, data *RemoteCertificateResourceModel, renew bool) error {
+	server := data.HostConnection.server()
+
+	command := data.files().InstallCommand(data.CertificatePEM.ValueString(), data.PrivateKeyPEM.ValueString(), data.ChainPEM.ValueString())
+	if data.Certbot != nil {
+		err := r.sshService.Preflight(ctx, server, []string{services.PrivilegeTool, "certbot", "install"})
+		if err != nil {
+			return err
+		}
+
+		command = services.CertbotCommand(data.certbotRequest(renew), data.files())
+	}
+
+	_, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, command))
+	if err != nil {
+		return err
+	}
+
+	exists, err := r.refresh(ctx, data)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%s was not installed", data.CertificatePath.ValueString())
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.HostConnection.hostID(), data.CertificatePath.ValueString()))
+
+	return nil
+}
+
+// refresh reads the certificate installed on the host into data, and reports whether it exists.
+func (r *RemoteCertificateResource) refresh(ctx context.Context, data *RemoteCertificateResourceModel) (bool, error) {
+	server := data.HostConnection.server()
+	certificatePath := data.CertificatePath.ValueString()
+
+	command := fmt.Sprintf("if [ -f %s ]; then %s; else echo missing; fi",
+		services.ShellQuote(certificatePath), services.ReadFilesCommand([]string{certificatePath}))
+
+	result, err := runCommand(services.WithFileContent(ctx), r.sshService, server, privilegedCommand(server, command))
+	if err != nil {
+		return false, err
+	}
+
+	lines, err := services.ParseFileLines(result.Stdout, 1)
+	if err != nil {
+		return false, err
+	}
+	if lines[0] == "missing" {
+		return false, nil
+	}
+
+	content, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return false, fmt.Errorf("unable to decode the content of %s: %w", certificatePath, err)
+	}
+
+	info, err := services.ParseCertificatePEM(content)
+	if err != nil {
+		return false, fmt.Errorf("unable to parse %s: %w", certificatePath, err)
+	}
+	data.setInfo(info)
+
+	return true, nil
+}
+
+func (r *RemoteCertificateResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteCertificateResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	err := r.install(ctx, &data, false)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to install certificate %s, got error: %s", data.CertificatePath.ValueString(), err))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteCertificateResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteCertificateResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	exists, err := r.refresh(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read certificate %s, got error: %s", data.CertificatePath.ValueString(), err))
+		return
+	}
+
+	if !exists {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteCertificateResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state RemoteCertificateResourceModel
+
+	// Read Terraform plan and prior state data into the models
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	server := data.HostConnection.server()
+
+	// A lineage left behind by a switch to supplied certificates or another name would keep
+	// deploying its renewals over the new files.
+	if state.Certbot != nil && (data.Certbot == nil || data.certbotRequest(false).Name != state.certbotRequest(false).Name) {
+		_, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, services.CertbotDeleteCommand(state.certbotRequest(false).Name)))
+		if err != nil {
+			resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to delete certbot certificate %s, got error: %s", state.certbotRequest(false).Name, err))
+			return
+		}
+	}
+
+	err := r.install(ctx, &data, state.Certbot != nil && data.Certbot != nil && state.renewalDue())
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to install certificate %s, got error: %s", data.CertificatePath.ValueString(), err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteCertificateResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteCertificateResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	server := data.HostConnection.server()
+
+	command := data.files().RemoveCommand()
+	if data.Certbot != nil {
+		command += " && " + services.CertbotDeleteCommand(data.certbotRequest(false).Name)
+	}
+
+	_, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, command))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to remove certificate %s, got error: %s", data.CertificatePath.ValueString(), err))
+		return
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteChecksumDataSource{}
+
+func NewRemoteChecksumDataSource() datasource.DataSource {
+	return &RemoteChecksumDataSource{}
+}
+
+// RemoteChecksumDataSource reports the checksums and sizes of files on a host without managing them.
+type RemoteChecksumDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemoteChecksumDataSourceModel describes the data source data model.
+type RemoteChecksumDataSourceModel struct {
+	HostConnection *HostConnectionModel               `tfsdk:"host_connection"`
+	Path           RemotePath                         `tfsdk:"path"`
+	Algorithm      types.String                       `tfsdk:"algorithm"`
+	Privileged     types.Bool                         `tfsdk:"privileged"`
+	Files          map[string]RemoteChecksumFileModel `tfsdk:"files"`
+}
+
+// RemoteChecksumFileModel describes a file matched by the data source.
+type RemoteChecksumFileModel struct {
+	Checksum types.String `tfsdk:"checksum"`
+	Size     types.Int64  `tfsdk:"size"`
+}
+
+func (d *RemoteChecksumDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_checksum"
+}
+
+func (d *RemoteChecksumDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Checksums and sizes of files on a host, e.g. to compare a deployed artifact against an " +
+			"expected digest without managing the file",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": dataSourceHostConnectionSchema(),
+			"path": schema.StringAttribute{
+				CustomType:          RemotePathType{},
+				Required:            true,
+				MarkdownDescription: "Path of the file, or a glob matching several files, e.g. `/opt/app/lib/*.jar`",
+			},
+			"algorithm": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Checksum algorithm, one of `md5`, `sha1`, `sha256`, `sha512` or `blake2b`. Defaults to `sha256`",
+				Validators: []validator.String{
+					stringOneOf(services.ChecksumAlgorithms()...),
+				},
+			},
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to read the files as root",
+			},
+			"files": schema.MapNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "Regular files matching `path` keyed by path, empty when nothing matches",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"checksum": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Hex encoded digest of the file",
+						},
+						"size": schema.Int64Attribute{
+							Computed:            true,
+							MarkdownDescription: "Size of the file in bytes",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *RemoteChecksumDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+func (d *RemoteChecksumDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteChecksumDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.HostConnection.server()
+
+	algorithm := "sha256"
+	if !data.Algorithm.IsNull() {
+		algorithm = data.Algorithm.ValueString()
+	}
+
+	command, err := services.ChecksumGlobCommand(algorithm, data.Path.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Internal Error", fmt.Sprintf("Unable to build the checksum command, got error: %s", err))
+		return
+	}
+
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	result, err := runCommand(ctx, d.sshService, server, command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to compute the checksums, got error: %s", err))
+		return
+	}
+
+	checksums, err := services.ParseChecksums(result.Stdout)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to compute the checksums, got error: %s", err))
+		return
+	}
+
+	data.Files = make(map[string]RemoteChecksumFileModel, len(checksums))
+	for _, checksum := range checksums {
+		data.Files[checksum.Path] = RemoteChecksumFileModel{
+			Checksum: types.StringValue(checksum.Checksum),
+			Size:     types.Int64Value(checksum.Size),
+		}
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
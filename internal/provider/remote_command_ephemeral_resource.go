@@ -0,0 +1,224 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"remote-provider/internal/provider/servers"
+	"remote-provider/internal/provider/services"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ ephemeral.EphemeralResource = &RemoteCommandEphemeralResource{}
+var _ ephemeral.EphemeralResourceWithConfigure = &RemoteCommandEphemeralResource{}
+var _ ephemeral.EphemeralResourceWithClose = &RemoteCommandEphemeralResource{}
+
+// remoteCommandCloseKey is the private data key holding what Close needs to run the close command.
+const remoteCommandCloseKey = "close"
+
+func NewRemoteCommandEphemeralResource() ephemeral.EphemeralResource {
+	return &RemoteCommandEphemeralResource{}
+}
+
+// RemoteCommandEphemeralResource runs a command when Terraform opens it and another one when
+// it is closed at the end of the run, e.g. to toggle a maintenance mode around an apply.
+type RemoteCommandEphemeralResource struct {
+	sshService *services.SSHService
+}
+
+// RemoteCommandEphemeralResourceModel describes the ephemeral resource data model.
+type RemoteCommandEphemeralResourceModel struct {
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	OpenCommand    types.String         `tfsdk:"open_command"`
+	CloseCommand   types.String         `tfsdk:"close_command"`
+	Privileged     types.Bool           `tfsdk:"privileged"`
+	Stdout         types.String         `tfsdk:"stdout"`
+	DurationMs     types.Int64          `tfsdk:"duration_ms"`
+}
+
+// remoteCommandClose is stored in the private data of the resource between Open and Close.
+type remoteCommandClose struct {
+	Address        string `json:"address"`
+	Port           uint16 `json:"port"`
+	User           string `json:"user"`
+	Password       string `json:"password"`
+	PrivateKeyPath string `json:"private_key_path"`
+	SudoPassword   string `json:"sudo_password"`
+	TOTPSecret     string `json:"totp_secret"`
+	BecomeMethod   string `json:"become_method"`
+	ProxyCommand   string `json:"proxy_command"`
+	WebSocketURL   string `json:"websocket_url"`
+	ConsoleCommand string `json:"console_command"`
+	Local          bool   `json:"local"`
+	Container      string `json:"container"`
+	ContainerTool  string `json:"container_tool"`
+	Command        string `json:"command"`
+	Privileged     bool   `json:"privileged"`
+}
+
+func (c *remoteCommandClose) server() *servers.Server {
+	return &servers.Server{
+		Address:        c.Address,
+		PrivateKeyPath: c.PrivateKeyPath,
+		User:           c.User,
+		Password:       c.Password,
+		SudoPassword:   c.SudoPassword,
+		TOTPSecret:     c.TOTPSecret,
+		BecomeMethod:   c.BecomeMethod,
+		ProxyCommand:   c.ProxyCommand,
+		WebSocketURL:   c.WebSocketURL,
+		ConsoleCommand: c.ConsoleCommand,
+		Local:          c.Local,
+		Container:      c.Container,
+		ContainerTool:  c.ContainerTool,
+		Port:           c.Port,
+		Name:           c.Address,
+	}
+}
+
+func (r *RemoteCommandEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_command"
+}
+
+func (r *RemoteCommandEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Runs `open_command` on a host when Terraform opens the resource and `close_command` once it is " +
+			"done with it at the end of the run, e.g. to enable a maintenance mode while the host is changed",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": ephemeralHostConnectionSchema(),
+			"open_command": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Command run when the resource is opened, its output is exposed as `stdout`",
+			},
+			"close_command": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Command run when the resource is closed, even if the run failed after opening it",
+			},
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to run the commands as root",
+			},
+			"stdout": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Standard output of `open_command`",
+			},
+			"duration_ms": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "How long `open_command` ran in milliseconds",
+			},
+		},
+	}
+}
+
+func (r *RemoteCommandEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Ephemeral Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+func (r *RemoteCommandEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	var data RemoteCommandEphemeralResourceModel
+
+	// Read Terraform config data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.HostConnection.server()
+
+	command := data.OpenCommand.ValueString()
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	result, err := runCommand(ctx, r.sshService, server, command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to run the open command on %s, got error: %s", server.Name, err))
+		return
+	}
+
+	if !data.CloseCommand.IsNull() {
+		closeData, err := json.Marshal(remoteCommandClose{
+			Address:        server.Address,
+			Port:           server.Port,
+			User:           server.User,
+			Password:       server.Password,
+			PrivateKeyPath: server.PrivateKeyPath,
+			SudoPassword:   server.SudoPassword,
+			TOTPSecret:     server.TOTPSecret,
+			BecomeMethod:   server.BecomeMethod,
+			ProxyCommand:   server.ProxyCommand,
+			WebSocketURL:   server.WebSocketURL,
+			ConsoleCommand: server.ConsoleCommand,
+			Local:          server.Local,
+			Container:      server.Container,
+			ContainerTool:  server.ContainerTool,
+			Command:        data.CloseCommand.ValueString(),
+			Privileged:     data.Privileged.ValueBool(),
+		})
+		if err != nil {
+			resp.Diagnostics.AddError("Internal Error", fmt.Sprintf("Unable to store the close command, got error: %s", err))
+			return
+		}
+
+		resp.Diagnostics.Append(resp.Private.SetKey(ctx, remoteCommandCloseKey, closeData)...)
+	}
+
+	data.Stdout = types.StringValue(strings.TrimSpace(result.Stdout))
+	data.DurationMs = commandDuration(result)
+
+	// Save data into ephemeral result data
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+}
+
+func (r *RemoteCommandEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	closeData, diags := req.Private.GetKey(ctx, remoteCommandCloseKey)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() || closeData == nil {
+		return
+	}
+
+	var closing remoteCommandClose
+	err := json.Unmarshal(closeData, &closing)
+	if err != nil {
+		resp.Diagnostics.AddError("Internal Error", fmt.Sprintf("Unable to read the close command, got error: %s", err))
+		return
+	}
+
+	server := closing.server()
+
+	command := closing.Command
+	if closing.Privileged {
+		command = privilegedCommand(server, command)
+	}
+
+	_, err = runCommand(ctx, r.sshService, server, command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to run the close command on %s, got error: %s", closing.Address, err))
+		return
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteCommandHistoryDataSource{}
+
+func NewRemoteCommandHistoryDataSource() datasource.DataSource {
+	return &RemoteCommandHistoryDataSource{}
+}
+
+// RemoteCommandHistoryDataSource exposes the last commands the provider executed on a host.
+type RemoteCommandHistoryDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemoteCommandHistoryDataSourceModel describes the data source data model.
+type RemoteCommandHistoryDataSourceModel struct {
+	Host     types.String                `tfsdk:"host"`
+	Limit    types.Int64                 `tfsdk:"limit"`
+	Commands []RemoteHistoryCommandModel `tfsdk:"commands"`
+}
+
+// RemoteHistoryCommandModel describes a command executed on the host.
+type RemoteHistoryCommandModel struct {
+	Command    types.String `tfsdk:"command"`
+	ExitCode   types.Int64  `tfsdk:"exit_code"`
+	Stderr     types.String `tfsdk:"stderr"`
+	StartedAt  types.String `tfsdk:"started_at"`
+	FinishedAt types.String `tfsdk:"finished_at"`
+	DurationMs types.Int64  `tfsdk:"duration_ms"`
+}
+
+func (d *RemoteCommandHistoryDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_command_history"
+}
+
+func (d *RemoteCommandHistoryDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Last commands the provider executed on a host during the current Terraform operation, to " +
+			"troubleshoot applies. Only commands executed before the data source is read are listed, so make it " +
+			"`depends_on` the resources of interest",
+
+		Attributes: map[string]schema.Attribute{
+			"host": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Hostname or IP address of the host, as set in `host_connection`",
+			},
+			"limit": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "Maximum number of commands listed. Defaults to `20`, at most 100 commands are kept per host",
+				Validators: []validator.Int64{
+					int64AtLeast(1),
+				},
+			},
+			"commands": schema.ListNestedAttribute{
+				Computed:            true,
+				Sensitive:           true,
+				MarkdownDescription: "Executed commands, oldest first. Sensitive as commands may embed file contents or secrets",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"command": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Command as built by the provider, before the umask, output framing and command wrapper are applied",
+						},
+						"exit_code": schema.Int64Attribute{
+							Computed:            true,
+							MarkdownDescription: "Exit code of the command",
+						},
+						"stderr": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Standard error of the command",
+						},
+						"started_at": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "RFC 3339 timestamp of when the command started",
+						},
+						"finished_at": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "RFC 3339 timestamp of when the command exited",
+						},
+						"duration_ms": schema.Int64Attribute{
+							Computed:            true,
+							MarkdownDescription: "How long the command ran in milliseconds, use it to spot slow provisioning steps",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *RemoteCommandHistoryDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+func (d *RemoteCommandHistoryDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteCommandHistoryDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	limit := 20
+	if !data.Limit.IsNull() {
+		limit = int(data.Limit.ValueInt64())
+	}
+
+	history := d.sshService.History(data.Host.ValueString(), limit)
+	data.Commands = make([]RemoteHistoryCommandModel, 0, len(history))
+	for _, command := range history {
+		data.Commands = append(data.Commands, RemoteHistoryCommandModel{
+			Command:    types.StringValue(command.Command),
+			ExitCode:   types.Int64Value(int64(command.ExitCode)),
+			Stderr:     types.StringValue(strings.ToValidUTF8(command.Stderr, "\uFFFD")),
+			StartedAt:  commandTime(command.StartedAt),
+			FinishedAt: commandTime(command.FinishedAt),
+			DurationMs: commandDuration(command),
+		})
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,352 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteComplianceDataSource{}
+
+func NewRemoteComplianceDataSource() datasource.DataSource {
+	return &RemoteComplianceDataSource{}
+}
+
+// RemoteComplianceDataSource evaluates declared files, packages and services against a host
+// without managing them.
+type RemoteComplianceDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemoteComplianceDataSourceModel describes the data source data model.
+type RemoteComplianceDataSourceModel struct {
+	HostConnection *HostConnectionModel           `tfsdk:"host_connection"`
+	Privileged     types.Bool                     `tfsdk:"privileged"`
+	FailOnDrift    types.Bool                     `tfsdk:"fail_on_drift"`
+	Files          []RemoteComplianceFileModel    `tfsdk:"files"`
+	Packages       []RemoteCompliancePackageModel `tfsdk:"packages"`
+	Services       []RemoteComplianceServiceModel `tfsdk:"services"`
+	Commands       []RemoteComplianceCommandModel `tfsdk:"commands"`
+	Checks         []RemoteComplianceCheckModel   `tfsdk:"checks"`
+	Passed         types.Bool                     `tfsdk:"passed"`
+	FailedCount    types.Int64                    `tfsdk:"failed_count"`
+}
+
+// RemoteComplianceFileModel describes the expected state of a file.
+type RemoteComplianceFileModel struct {
+	Path    RemotePath   `tfsdk:"path"`
+	Present types.Bool   `tfsdk:"present"`
+	SHA256  types.String `tfsdk:"sha256"`
+	Mode    FileMode     `tfsdk:"mode"`
+	Owner   types.String `tfsdk:"owner"`
+	Group   types.String `tfsdk:"group"`
+}
+
+// RemoteCompliancePackageModel describes the expected state of a package.
+type RemoteCompliancePackageModel struct {
+	Name      types.String `tfsdk:"name"`
+	Installed types.Bool   `tfsdk:"installed"`
+	Version   types.String `tfsdk:"version"`
+}
+
+// RemoteComplianceServiceModel describes the expected state of a service.
+type RemoteComplianceServiceModel struct {
+	Name    types.String `tfsdk:"name"`
+	Active  types.Bool   `tfsdk:"active"`
+	Enabled types.Bool   `tfsdk:"enabled"`
+}
+
+// RemoteComplianceCommandModel describes an assertion command.
+type RemoteComplianceCommandModel struct {
+	Name    types.String `tfsdk:"name"`
+	Command types.String `tfsdk:"command"`
+}
+
+// RemoteComplianceCheckModel describes the result of a check.
+type RemoteComplianceCheckModel struct {
+	Kind    types.String `tfsdk:"kind"`
+	Name    types.String `tfsdk:"name"`
+	Passed  types.Bool   `tfsdk:"passed"`
+	Message types.String `tfsdk:"message"`
+}
+
+func (d *RemoteComplianceDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_compliance"
+}
+
+func (d *RemoteComplianceDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Read-only compliance scan: evaluates declared files, packages, services and assertion commands " +
+			"against a host in a single command and reports pass or fail per check without changing anything, e.g. to use " +
+			"Terraform as a drift auditor with `check` blocks on `passed`",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": dataSourceHostConnectionSchema(),
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to run the scan as root, e.g. to inspect files only readable by root",
+			},
+			"fail_on_drift": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether failed checks fail the read with an error listing them, instead of only being reported",
+			},
+			"files": schema.ListNestedAttribute{
+				Optional:            true,
+				MarkdownDescription: "Files to check, only the attributes set are compared",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"path": schema.StringAttribute{
+							CustomType:          RemotePathType{},
+							Required:            true,
+							MarkdownDescription: "Path of the file",
+						},
+						"present": schema.BoolAttribute{
+							Optional:            true,
+							MarkdownDescription: "Whether the file must exist. Defaults to `true`, `false` expects it to be absent",
+						},
+						"sha256": schema.StringAttribute{
+							Optional:            true,
+							MarkdownDescription: "Expected hex encoded SHA-256 digest of the content, e.g. `sha256(local.config)`",
+							Validators: []validator.String{
+								stringMatches(sha256Regexp, "must be a hex encoded SHA-256 digest"),
+							},
+						},
+						"mode": schema.StringAttribute{
+							CustomType:          FileModeType{},
+							Optional:            true,
+							MarkdownDescription: "Expected octal mode, e.g. `0640`",
+						},
+						"owner": schema.StringAttribute{
+							Optional:            true,
+							MarkdownDescription: "Expected owner name",
+						},
+						"group": schema.StringAttribute{
+							Optional:            true,
+							MarkdownDescription: "Expected group name",
+						},
+					},
+				},
+			},
+			"packages": schema.ListNestedAttribute{
+				Optional:            true,
+				MarkdownDescription: "Packages to check with dpkg or rpm",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Required:            true,
+							MarkdownDescription: "Name of the package",
+						},
+						"installed": schema.BoolAttribute{
+							Optional:            true,
+							MarkdownDescription: "Whether the package must be installed. Defaults to `true`",
+						},
+						"version": schema.StringAttribute{
+							Optional:            true,
+							MarkdownDescription: "Expected version, matching the installed versions equal to it or starting with it and a separator, so `1.24` matches `1.24.3-1`",
+						},
+					},
+				},
+			},
+			"services": schema.ListNestedAttribute{
+				Optional:            true,
+				MarkdownDescription: "systemd services to check",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Required:            true,
+							MarkdownDescription: "Name of the unit, e.g. `nginx.service`",
+						},
+						"active": schema.BoolAttribute{
+							Optional:            true,
+							MarkdownDescription: "Whether the service must be running. Not checked when unset",
+						},
+						"enabled": schema.BoolAttribute{
+							Optional:            true,
+							MarkdownDescription: "Whether the service must start at boot. Not checked when unset",
+						},
+					},
+				},
+			},
+			"commands": schema.ListNestedAttribute{
+				Optional:            true,
+				MarkdownDescription: "Assertion commands, passing when they exit with status 0, e.g. `sshd -T | grep -qx 'permitrootlogin no'`",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Required:            true,
+							MarkdownDescription: "Name of the check in the results",
+						},
+						"command": schema.StringAttribute{
+							Required:            true,
+							MarkdownDescription: "Shell command asserting the condition",
+						},
+					},
+				},
+			},
+			"checks": schema.ListNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "Results of the checks: files, then packages, services and commands, in the order they are declared",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"kind": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Kind of the check: `file`, `package`, `service` or `command`",
+						},
+						"name": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Path of the file, or name of the package, service or command",
+						},
+						"passed": schema.BoolAttribute{
+							Computed:            true,
+							MarkdownDescription: "Whether the host matches the check",
+						},
+						"message": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Description of the drift, empty when the check passed",
+						},
+					},
+				},
+			},
+			"passed": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether every check passed",
+			},
+			"failed_count": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "Number of failed checks",
+			},
+		},
+	}
+}
+
+func (d *RemoteComplianceDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+// scan returns the checks declared by data.
+func (data *RemoteComplianceDataSourceModel) scan() services.ComplianceScan {
+	var scan services.ComplianceScan
+
+	for _, file := range data.Files {
+		scan.Files = append(scan.Files, services.ComplianceFile{
+			Path:   file.Path.ValueString(),
+			Absent: !file.Present.IsNull() && !file.Present.ValueBool(),
+			SHA256: file.SHA256.ValueString(),
+			Mode:   file.Mode.ValueString(),
+			Owner:  file.Owner.ValueString(),
+			Group:  file.Group.ValueString(),
+		})
+	}
+
+	for _, pkg := range data.Packages {
+		scan.Packages = append(scan.Packages, services.CompliancePackage{
+			Name:    pkg.Name.ValueString(),
+			Absent:  !pkg.Installed.IsNull() && !pkg.Installed.ValueBool(),
+			Version: pkg.Version.ValueString(),
+		})
+	}
+
+	for _, service := range data.Services {
+		check := services.ComplianceService{Name: service.Name.ValueString()}
+		if !service.Active.IsNull() {
+			check.Active = service.Active.ValueBoolPointer()
+		}
+		if !service.Enabled.IsNull() {
+			check.Enabled = service.Enabled.ValueBoolPointer()
+		}
+		scan.Services = append(scan.Services, check)
+	}
+
+	for _, command := range data.Commands {
+		scan.Assertions = append(scan.Assertions, services.ComplianceAssertion{
+			Name:    command.Name.ValueString(),
+			Command: command.Command.ValueString(),
+		})
+	}
+
+	return scan
+}
+
+func (d *RemoteComplianceDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteComplianceDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.HostConnection.server()
+	scan := data.scan()
+
+	command := scan.Command()
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	result, err := runCommand(ctx, d.sshService, server, command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to run the compliance scan, got error: %s", err))
+		return
+	}
+
+	results, err := scan.Evaluate(result.Stdout)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to evaluate the compliance scan, got error: %s", err))
+		return
+	}
+
+	var failures []string
+	data.Checks = make([]RemoteComplianceCheckModel, 0, len(results))
+	for _, check := range results {
+		data.Checks = append(data.Checks, RemoteComplianceCheckModel{
+			Kind:    types.StringValue(check.Kind),
+			Name:    types.StringValue(check.Name),
+			Passed:  types.BoolValue(check.Passed),
+			Message: types.StringValue(check.Message),
+		})
+		if !check.Passed {
+			failures = append(failures, fmt.Sprintf("%s %s %s", check.Kind, check.Name, check.Message))
+		}
+	}
+	data.Passed = types.BoolValue(len(failures) == 0)
+	data.FailedCount = types.Int64Value(int64(len(failures)))
+
+	if data.FailOnDrift.ValueBool() && len(failures) > 0 {
+		resp.Diagnostics.AddError(
+			"Compliance Drift",
+			fmt.Sprintf("%d checks failed on %s:\n  - %s", len(failures), data.HostConnection.Host.ValueString(), strings.Join(failures, "\n  - ")),
+		)
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,394 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteContainerRuntimeResource{}
+var _ resource.ResourceWithValidateConfig = &RemoteContainerRuntimeResource{}
+
+func NewRemoteContainerRuntimeResource() resource.Resource {
+	return &RemoteContainerRuntimeResource{}
+}
+
+// RemoteContainerRuntimeResource installs and configures Docker or containerd.
+type RemoteContainerRuntimeResource struct {
+	sshService *services.SSHService
+}
+
+// RemoteContainerRuntimeResourceModel describes the resource data model.
+type RemoteContainerRuntimeResourceModel struct {
+	Id             types.String         `tfsdk:"id"`
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Runtime        types.String         `tfsdk:"runtime"`
+	RepositoryURL  types.String         `tfsdk:"repository_url"`
+	Config         types.String         `tfsdk:"config"`
+	Users          []types.String       `tfsdk:"users"`
+	Version        types.String         `tfsdk:"version"`
+	ServiceActive  types.Bool           `tfsdk:"service_active"`
+	Timeouts       *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemoteContainerRuntimeResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_container_runtime"
+}
+
+func (r *RemoteContainerRuntimeResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Bootstraps a container runtime on a Debian, Ubuntu, Fedora or Red Hat host: adds the Docker package " +
+			"repository, installs Docker or containerd from it, writes its configuration and runs its systemd service. " +
+			"The runtime restarts when its configuration changes. Destroying the resource removes the packages but keeps " +
+			"the images, containers and volumes.",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"runtime": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Runtime to install: `docker` for Docker Engine with the buildx and compose plugins, or `containerd`",
+				Validators: []validator.String{
+					stringOneOf(services.ContainerRuntimes...),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"repository_url": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Docker package repository, or a mirror of it. Defaults to `" + services.DockerRepositoryURL + "`",
+				Default:             stringdefault.StaticString(services.DockerRepositoryURL),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"config": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "Content of `/etc/docker/daemon.json` for docker, e.g. with `jsonencode`, or of " +
+					"`/etc/containerd/config.toml` for containerd. It is validated by the runtime before replacing the " +
+					"previous one. When unset, the daemon.json of docker is left alone and containerd gets its default " +
+					"configuration with the systemd cgroup driver",
+			},
+			"users": schema.ListAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Users added to the `docker` group so they can use Docker without privileges. Only used by docker",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier of the runtime, made of the host and the runtime",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"version": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Version reported by the runtime, e.g. `Docker version 27.3.1, build ce12230`",
+			},
+			"service_active": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether the service was active when last read",
+			},
+		},
+	}
+}
+
+func (r *RemoteContainerRuntimeResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+func (r *RemoteContainerRuntimeResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data RemoteContainerRuntimeResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() || data.Runtime.IsUnknown() || data.Config.IsUnknown() || data.Config.IsNull() {
+		return
+	}
+
+	err := services.ValidateContainerRuntimeConfig(data.Runtime.ValueString(), data.Config.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("config"), "Invalid Configuration", err.Error())
+	}
+}
+
+// configureCommand returns a command validating and installing the configuration of data, or
+// the default containerd one.
+func (data *RemoteContainerRuntimeResourceModel) configureCommand() string {
+	runtime := data.Runtime.ValueString()
+	if data.Config.IsNull() {
+		if runtime == "containerd" {
+			return services.ContainerdDefaultConfigCommand
+		}
+		return ""
+	}
+
+	configPath := services.ContainerRuntimeConfigPath(runtime)
+	newPath := configPath + ".remote-host.new"
+
+	validate := fmt.Sprintf("dockerd --validate --config-file=%s >/dev/null", services.ShellQuote(newPath))
+	if runtime == "containerd" {
+		validate = fmt.Sprintf("containerd --config %s config dump >/dev/null", services.ShellQuote(newPath))
+	}
+
+	return fmt.Sprintf(
+		"mkdir -p \"$(dirname %s)\" && %s && { %s || { rm -f %s; echo 'the configuration was rejected' >&2; exit 1; }; } && mv -f %s %s",
+		services.ShellQuote(configPath),
+		services.WriteFileCommand(newPath, []byte(data.Config.ValueString()), "0644"),
+		validate,
+		services.ShellQuote(newPath),
+		services.ShellQuote(newPath),
+		services.ShellQuote(configPath),
+	)
+}
+
+// usersCommand returns a command adding the users of data to the docker group and removing the
+// ones only in previous.
+func (data *RemoteContainerRuntimeResourceModel) usersCommand(previous []types.String) string {
+	if data.Runtime.ValueString() != "docker" {
+		return ""
+	}
+
+	var commands []string
+	for _, user := range data.Users {
+		commands = append(commands, "usermod -aG docker "+services.ShellQuote(user.ValueString()))
+	}
+	for _, user := range previous {
+		if !slices.Contains(data.Users, user) {
+			commands = append(commands, fmt.Sprintf("{ gpasswd -d %s docker >/dev/null 2>&1 || true; }", services.ShellQuote(user.ValueString())))
+		}
+	}
+	if len(commands) == 0 {
+		return ""
+	}
+
+	return "groupadd -f docker && " + strings.Join(commands, " && ")
+}
+
+// apply installs the runtime when install is set, then brings its configuration, the docker
+// group and the service to the plan. prior is the state before an update, nil on creation.
+func (r *RemoteContainerRuntimeResource) apply(ctx context.Context, data *RemoteContainerRuntimeResourceModel, prior *RemoteContainerRuntimeResourceModel) error {
+	server := data.HostConnection.server()
+	runtime := data.Runtime.ValueString()
+
+	script := []string{}
+	restart := false
+	var previousUsers []types.String
+
+	if prior == nil {
+		err := r.sshService.Preflight(ctx, server, []string{services.PrivilegeTool, "curl", "systemctl"})
+		if err != nil {
+			return err
+		}
+
+		script = append(script, services.ContainerRuntimeInstallCommand(runtime, data.RepositoryURL.ValueString()))
+		if command := data.configureCommand(); command != "" {
+			script = append(script, command)
+			restart = true
+		}
+	} else {
+		previousUsers = prior.Users
+		if !data.Config.Equal(prior.Config) && !data.Config.IsNull() {
+			script = append(script, data.configureCommand())
+			restart = true
+		}
+	}
+
+	if command := data.usersCommand(previousUsers); command != "" {
+		script = append(script, command)
+	}
+
+	script = append(script, "systemctl enable --now "+runtime)
+	if restart {
+		script = append(script, "systemctl restart "+runtime)
+	}
+
+	command := strings.Join(script, "\n") + "\n"
+	if len(script) > 1 {
+		command = "set -e\n" + command
+	}
+
+	_, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, command))
+	if err != nil {
+		return err
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.HostConnection.hostID(), runtime))
+
+	_, err = r.refresh(ctx, data)
+	return err
+}
+
+// refresh reads the runtime back from the host and reports whether it is still installed.
+func (r *RemoteContainerRuntimeResource) refresh(ctx context.Context, data *RemoteContainerRuntimeResourceModel) (bool, error) {
+	runtime := data.Runtime.ValueString()
+
+	result, err := runCommand(services.WithFileContent(ctx), r.sshService, data.HostConnection.server(), services.ContainerRuntimeStatusCommand(runtime))
+	if err != nil {
+		return false, err
+	}
+
+	status, err := services.ParseContainerRuntimeStatus(result.Stdout)
+	if err != nil || !status.Installed {
+		return false, err
+	}
+
+	if !data.Config.IsNull() {
+		switch {
+		case status.Config == nil:
+			data.Config = types.StringNull()
+		case !services.ContainerRuntimeConfigEqual(runtime, data.Config.ValueString(), *status.Config):
+			data.Config = types.StringValue(*status.Config)
+		}
+	}
+
+	if runtime == "docker" && data.Users != nil {
+		var users []types.String
+		for _, user := range data.Users {
+			if slices.Contains(status.Members, user.ValueString()) {
+				users = append(users, user)
+			}
+		}
+		data.Users = users
+	}
+
+	data.Version = types.StringValue(status.Version)
+	data.ServiceActive = types.BoolValue(status.Active)
+
+	return true, nil
+}
+
+func (r *RemoteContainerRuntimeResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteContainerRuntimeResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	err := r.apply(ctx, &data, nil)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to install %s, got error: %s", data.Runtime.ValueString(), err))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteContainerRuntimeResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteContainerRuntimeResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	exists, err := r.refresh(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read %s, got error: %s", data.Runtime.ValueString(), err))
+		return
+	}
+
+	if !exists {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteContainerRuntimeResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state RemoteContainerRuntimeResourceModel
+
+	// Read Terraform plan and prior state data into the models
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	err := r.apply(ctx, &data, &state)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to configure %s, got error: %s", data.Runtime.ValueString(), err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteContainerRuntimeResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteContainerRuntimeResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	server := data.HostConnection.server()
+
+	_, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, services.ContainerRuntimeRemoveCommand(data.Runtime.ValueString())))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to remove %s, got error: %s", data.Runtime.ValueString(), err))
+		return
+	}
+}
@@ -0,0 +1,383 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteDirectoryResource{}
+var _ resource.ResourceWithModifyPlan = &RemoteDirectoryResource{}
+
+func NewRemoteDirectoryResource() resource.Resource {
+	return &RemoteDirectoryResource{}
+}
+
+// RemoteDirectoryResource manages a directory and optionally the permissions of its contents.
+type RemoteDirectoryResource struct {
+	sshService *services.SSHService
+}
+
+// RemoteDirectoryResourceModel describes the resource data model.
+type RemoteDirectoryResourceModel struct {
+	Id                 types.String         `tfsdk:"id"`
+	HostConnection     *HostConnectionModel `tfsdk:"host_connection"`
+	Path               RemotePath           `tfsdk:"path"`
+	Mode               FileMode             `tfsdk:"mode"`
+	FileMode           FileMode             `tfsdk:"file_mode"`
+	Owner              types.String         `tfsdk:"owner"`
+	Group              types.String         `tfsdk:"group"`
+	Privileged         types.Bool           `tfsdk:"privileged"`
+	RecursePermissions types.Bool           `tfsdk:"recurse_permissions"`
+	MaxDepth           types.Int64          `tfsdk:"max_depth"`
+	Exclude            types.List           `tfsdk:"exclude"`
+	DriftedPaths       types.List           `tfsdk:"drifted_paths"`
+	Timeouts           *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemoteDirectoryResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_directory"
+}
+
+func (r *RemoteDirectoryResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Directory on a host. With `recurse_permissions` its mode and ownership are also applied to " +
+			"its existing contents, within `max_depth` and outside of the `exclude` patterns",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"path": schema.StringAttribute{
+				CustomType:          RemotePathType{},
+				Required:            true,
+				MarkdownDescription: "Absolute path of the directory",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"mode": schema.StringAttribute{
+				CustomType:          FileModeType{},
+				Optional:            true,
+				MarkdownDescription: "Octal mode of the directory and of the directories it contains, e.g. `0750`. Defaults to the provider `default_directory_mode`",
+			},
+			"file_mode": schema.StringAttribute{
+				CustomType:          FileModeType{},
+				Optional:            true,
+				MarkdownDescription: "Octal mode of the regular files the directory contains when `recurse_permissions` is set. Their mode is left unchanged when unset",
+			},
+			"owner": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "User owning the directory",
+			},
+			"group": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Group owning the directory",
+			},
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to manage the directory as root",
+				Default:             booldefault.StaticBool(false),
+			},
+			"recurse_permissions": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to apply the mode and ownership to the existing contents of the directory",
+				Default:             booldefault.StaticBool(false),
+			},
+			"max_depth": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "Number of levels below the directory `recurse_permissions` descends into. Unlimited when unset",
+				Validators: []validator.Int64{
+					int64AtLeast(1),
+				},
+			},
+			"exclude": schema.ListAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				MarkdownDescription: "Glob patterns of entries `recurse_permissions` skips along with their contents. Patterns " +
+					"with a slash match the path relative to the directory, e.g. `data/cache`, others match entry names, e.g. `*.key`",
+			},
+			"drifted_paths": schema.ListAttribute{
+				Computed:    true,
+				ElementType: types.StringType,
+				MarkdownDescription: "Paths whose mode or ownership differ from the configuration, listed in the plan as the " +
+					"paths the apply changes. Always empty after an apply",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Path of the directory",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *RemoteDirectoryResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+// scope returns the entries of the directory its permissions apply to.
+func (data *RemoteDirectoryResourceModel) scope(ctx context.Context) (services.DirectoryScope, error) {
+	scope := services.DirectoryScope{
+		Recurse:  data.RecursePermissions.ValueBool(),
+		MaxDepth: data.MaxDepth.ValueInt64(),
+	}
+
+	if !data.Exclude.IsNull() && !data.Exclude.IsUnknown() {
+		diags := data.Exclude.ElementsAs(ctx, &scope.Exclude, false)
+		if diags.HasError() {
+			return scope, fmt.Errorf("unable to read the exclude patterns")
+		}
+	}
+
+	return scope, nil
+}
+
+// permissions returns the permissions configured in data.
+func (data *RemoteDirectoryResourceModel) permissions(sshService *services.SSHService) services.DirectoryPermissions {
+	permissions := services.DirectoryPermissions{
+		Mode:  sshService.DirectoryMode(data.Mode.ValueString()),
+		Owner: data.Owner.ValueString(),
+		Group: data.Group.ValueString(),
+	}
+	if data.RecursePermissions.ValueBool() {
+		permissions.FileMode = data.FileMode.ValueString()
+	}
+
+	return permissions
+}
+
+// command wraps script so it runs as root when the directory is privileged.
+func (data *RemoteDirectoryResourceModel) command(script string) string {
+	if data.Privileged.ValueBool() {
+		return privilegedCommand(data.HostConnection.server(), script)
+	}
+
+	return script
+}
+
+// driftedPaths returns the paths whose permissions differ from data, and whether the directory exists.
+func (r *RemoteDirectoryResource) driftedPaths(ctx context.Context, data *RemoteDirectoryResourceModel) ([]string, bool, error) {
+	scope, err := data.scope(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	quotedPath := services.ShellQuote(data.Path.ValueString())
+	script := fmt.Sprintf(
+		"if [ -d %s ]; then %s; else echo %s; fi",
+		quotedPath, services.DriftedPathsCommand(data.Path.ValueString(), scope, data.permissions(r.sshService)), fileMissingLine,
+	)
+
+	result, err := runCommand(ctx, r.sshService, data.HostConnection.server(), data.command(script))
+	if err != nil {
+		return nil, false, err
+	}
+
+	paths := services.ParsePaths(result.Stdout)
+	if len(paths) == 1 && strings.TrimSpace(paths[0]) == fileMissingLine {
+		return nil, false, nil
+	}
+
+	return paths, true, nil
+}
+
+// apply creates the directory of data when missing and applies its permissions.
+func (r *RemoteDirectoryResource) apply(ctx context.Context, data *RemoteDirectoryResourceModel) error {
+	scope, err := data.scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	script := fmt.Sprintf(
+		"mkdir -p -- %s && %s",
+		services.ShellQuote(data.Path.ValueString()), services.ApplyPermissionsCommand(data.Path.ValueString(), scope, data.permissions(r.sshService)),
+	)
+
+	_, err = runCommand(ctx, r.sshService, data.HostConnection.server(), data.command(script))
+	if err != nil {
+		return err
+	}
+
+	data.Id = types.StringValue(data.Path.ValueString())
+	data.DriftedPaths = types.ListValueMust(types.StringType, nil)
+
+	return nil
+}
+
+// ModifyPlan lists in the plan output the paths whose permissions the apply changes.
+func (r *RemoteDirectoryResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.sshService == nil {
+		return
+	}
+
+	var plan RemoteDirectoryResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.DriftedPaths = types.ListValueMust(types.StringType, nil)
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+
+	if req.Plan.Raw.Equal(req.State.Raw) || plan.HostConnection == nil || plan.HostConnection.Host.IsUnknown() ||
+		plan.Path.IsUnknown() || plan.Mode.IsUnknown() || plan.FileMode.IsUnknown() || plan.Owner.IsUnknown() ||
+		plan.Group.IsUnknown() || plan.MaxDepth.IsUnknown() || plan.Exclude.IsUnknown() {
+		return
+	}
+
+	// The configuration changes, dry run the new permissions so the plan shows what they affect.
+	paths, exists, err := r.driftedPaths(ctx, &plan)
+	if err != nil {
+		tflog.Warn(ctx, "unable to list the paths affected by the directory permissions", map[string]any{"path": plan.Path.ValueString(), "error": err.Error()})
+		return
+	}
+
+	if exists && len(paths) > 0 {
+		resp.Diagnostics.AddWarning(
+			"Directory Permissions Changes",
+			fmt.Sprintf("Applying the permissions of %s changes %d paths:\n%s", plan.Path.ValueString(), len(paths), strings.Join(paths, "\n")),
+		)
+	}
+}
+
+func (r *RemoteDirectoryResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteDirectoryResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	err := r.apply(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to create the directory, got error: %s", err))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteDirectoryResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteDirectoryResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	paths, exists, err := r.driftedPaths(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the directory, got error: %s", err))
+		return
+	}
+
+	if !exists {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	var diags diag.Diagnostics
+	data.DriftedPaths, diags = types.ListValueFrom(ctx, types.StringType, paths)
+	resp.Diagnostics.Append(diags...)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteDirectoryResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data RemoteDirectoryResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	err := r.apply(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to update the directory, got error: %s", err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Delete removes the directory only when it is empty, its contents are not managed by the resource.
+func (r *RemoteDirectoryResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteDirectoryResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	quotedPath := services.ShellQuote(data.Path.ValueString())
+	_, err := runCommand(ctx, r.sshService, data.HostConnection.server(), data.command(fmt.Sprintf("[ ! -d %s ] || rmdir -- %s", quotedPath, quotedPath)))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to remove the directory, got error: %s", err))
+		return
+	}
+}
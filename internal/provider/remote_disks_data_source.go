@@ -0,0 +1,173 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteDisksDataSource{}
+
+func NewRemoteDisksDataSource() datasource.DataSource {
+	return &RemoteDisksDataSource{}
+}
+
+// RemoteDisksDataSource lists the disks of a host.
+type RemoteDisksDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemoteDisksDataSourceModel describes the data source data model.
+type RemoteDisksDataSourceModel struct {
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Disks          []RemoteDiskModel    `tfsdk:"disks"`
+}
+
+// RemoteDiskModel describes a disk of the host.
+type RemoteDiskModel struct {
+	Name   types.String `tfsdk:"name"`
+	Path   types.String `tfsdk:"path"`
+	Size   types.Int64  `tfsdk:"size"`
+	Serial types.String `tfsdk:"serial"`
+	WWN    types.String `tfsdk:"wwn"`
+	Model  types.String `tfsdk:"model"`
+	FSType types.String `tfsdk:"fstype"`
+}
+
+func (d *RemoteDisksDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_disks"
+}
+
+func (d *RemoteDisksDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Disks of a Linux host as reported by `lsblk`, so storage resources can select them by serial " +
+			"number or WWN instead of `/dev` names that change across reboots",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": dataSourceHostConnectionSchema(),
+			"disks": schema.ListNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "Disks of the host",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Kernel name of the disk, e.g. `nvme0n1`",
+						},
+						"path": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Device path of the disk, e.g. `/dev/nvme0n1`",
+						},
+						"size": schema.Int64Attribute{
+							Computed:            true,
+							MarkdownDescription: "Size of the disk in bytes",
+						},
+						"serial": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Serial number of the disk",
+						},
+						"wwn": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "World Wide Name of the disk",
+						},
+						"model": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Model of the disk",
+						},
+						"fstype": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Filesystem or signature found on the whole disk, empty when it is partitioned or blank",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *RemoteDisksDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+// readBlockDevices lists the block devices of the host of connection.
+func readBlockDevices(ctx context.Context, sshService *services.SSHService, connection *HostConnectionModel) ([]services.BlockDevice, error) {
+	server := connection.server()
+
+	err := sshService.OpenConnection(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sshService.Preflight(ctx, server, []string{"lsblk"})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := runCommand(ctx, sshService, server, services.BlockDevicesCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	return services.ParseBlockDevices(result.Stdout)
+}
+
+func (d *RemoteDisksDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteDisksDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	devices, err := readBlockDevices(ctx, d.sshService, data.HostConnection)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to list the disks, got error: %s", err))
+		return
+	}
+
+	data.Disks = []RemoteDiskModel{}
+	for _, device := range devices {
+		if device.Type != "disk" {
+			continue
+		}
+
+		data.Disks = append(data.Disks, RemoteDiskModel{
+			Name:   types.StringValue(device.Name),
+			Path:   types.StringValue(device.Path),
+			Size:   types.Int64Value(device.Size),
+			Serial: types.StringValue(device.Serial),
+			WWN:    types.StringValue(device.WWN),
+			Model:  types.StringValue(device.Model),
+			FSType: types.StringValue(device.FSType),
+		})
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
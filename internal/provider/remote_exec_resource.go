@@ -0,0 +1,714 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"remote-provider/internal/provider/servers"
+	"remote-provider/internal/provider/services"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteExecResource{}
+var _ resource.ResourceWithMoveState = &RemoteExecResource{}
+var _ resource.ResourceWithValidateConfig = &RemoteExecResource{}
+var _ resource.ResourceWithModifyPlan = &RemoteExecResource{}
+
+// remoteExecChangedKey is the private state key set by Read when files of checksum_of changed.
+const remoteExecChangedKey = "checksum_of_changed"
+
+var remoteExecResultAttrTypes = map[string]attr.Type{
+	"status":        types.StringType,
+	"exit_code":     types.Int64Type,
+	"stdout":        types.StringType,
+	"stdout_base64": types.StringType,
+	"stderr":        types.StringType,
+	"started_at":    types.StringType,
+	"finished_at":   types.StringType,
+	"duration_ms":   types.Int64Type,
+}
+
+func NewRemoteExecResource() resource.Resource {
+	return &RemoteExecResource{}
+}
+
+// NewLegacyRemoteExecResource registers the resource under its deprecated unprefixed name.
+func NewLegacyRemoteExecResource() resource.Resource {
+	return &RemoteExecResource{legacyTypeName: "remote_exec"}
+}
+
+// RemoteExecResource runs a command once on one host or fans it out to a group of hosts.
+type RemoteExecResource struct {
+	sshService     *services.SSHService
+	legacyTypeName string
+}
+
+// RemoteExecResourceModel describes the resource data model.
+type RemoteExecResourceModel struct {
+	Id               types.String           `tfsdk:"id"`
+	HostConnection   *HostConnectionModel   `tfsdk:"host_connection"`
+	HostConnections  []*HostConnectionModel `tfsdk:"host_connections"`
+	DelegateTo       *HostConnectionModel   `tfsdk:"delegate_to"`
+	Command          types.String           `tfsdk:"command"`
+	Privileged       types.Bool             `tfsdk:"privileged"`
+	FailOnError      types.Bool             `tfsdk:"fail_on_error"`
+	RecordSession    types.Bool             `tfsdk:"record_session"`
+	CollectFiles     []types.String         `tfsdk:"collect_files"`
+	CollectDirectory types.String           `tfsdk:"collect_directory"`
+	CollectedFiles   types.Map              `tfsdk:"collected_files"`
+	Triggers         types.Map              `tfsdk:"triggers"`
+	Creates          types.String           `tfsdk:"creates"`
+	ChecksumOf       []types.String         `tfsdk:"checksum_of"`
+	Checksums        types.Map              `tfsdk:"checksums"`
+	Results          types.Map              `tfsdk:"results"`
+	Timeouts         *TimeoutsModel         `tfsdk:"timeouts"`
+}
+
+func (r *RemoteExecResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_exec"
+	if r.legacyTypeName != "" {
+		resp.TypeName = r.legacyTypeName
+	}
+}
+
+func (r *RemoteExecResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.Required = false
+	hostConnection.Optional = true
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	delegateTo := hostConnectionSchema()
+	delegateTo.Required = false
+	delegateTo.Optional = true
+	delegateTo.MarkdownDescription = "Host the command actually runs on, once per target host, e.g. to run `pg_basebackup` on " +
+		"the primary for each replica. The target is described to the command by the `REMOTE_HOST_TARGET` (address) and " +
+		"`REMOTE_HOST_TARGET_USER` environment variables, and the results stay keyed by target host"
+	delegateTo.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	hostConnections := hostConnectionsSchema()
+	hostConnections.PlanModifiers = []planmodifier.List{
+		listplanmodifier.RequiresReplace(),
+	}
+
+	resp.Schema = schema.Schema{
+		DeprecationMessage: legacyDeprecationMessage(r.legacyTypeName, "remote_host_exec"),
+
+		MarkdownDescription: "Runs a command on a single host (`host_connection`) or on every host of a group (`host_connections`). " +
+			"The command runs again whenever `command`, `triggers`, the hosts or the files of `checksum_of` change.",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":         timeoutsSchema(),
+			"host_connection":  hostConnection,
+			"host_connections": hostConnections,
+			"delegate_to":      delegateTo,
+			"command": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Shell command to execute, `{{ var.name }}` is replaced by the `name` variable the host inherits from the `host_groups` of the provider",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to run the command as root",
+				Default:             booldefault.StaticBool(false),
+			},
+			"fail_on_error": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether a failure on any host fails the apply. When disabled failures are only reported in `results`",
+				Default:             booldefault.StaticBool(true),
+			},
+			"record_session": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to record the output of the privileged command to the provider `session_recording` directory. Defaults to the provider setting",
+			},
+			"collect_files": schema.ListAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				MarkdownDescription: "Shell glob patterns of files downloaded from every host after the command succeeded, " +
+					"e.g. `[\"/etc/kubernetes/admin.conf\", \"/var/tmp/reports/*.json\"]`. Patterns matching no file are skipped",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"collect_directory": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Local directory the collected files are also written to, as `<directory>/<host>/<remote path>`",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"collected_files": schema.MapAttribute{
+				Computed:            true,
+				Sensitive:           true,
+				ElementType:         types.MapType{ElemType: types.StringType},
+				MarkdownDescription: "Base64 encoded content of the collected files keyed by host, then by remote path, e.g. `base64decode(remote_host_exec.init.collected_files[\"10.0.0.1\"][\"/etc/join-token\"])`. Sensitive, as collected files usually hold secrets such as join tokens",
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"triggers": schema.MapAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Arbitrary values that cause the command to run again when changed",
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"creates": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "Path on the host whose existence marks the command as already applied, e.g. the file it generates. " +
+					"The command does not run on hosts where it exists and their result has the `skipped` status",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"checksum_of": schema.ListAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				MarkdownDescription: "Paths of files on the host the command depends on, e.g. the configuration it applies. " +
+					"The command runs again when the SHA-256 checksum of any of them changes",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"checksums": schema.MapAttribute{
+				Computed:            true,
+				ElementType:         types.MapType{ElemType: types.StringType},
+				MarkdownDescription: "SHA-256 checksums of the files of `checksum_of` when the command ran keyed by host, then by path. Missing files have the `missing` checksum",
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Random identifier of the execution",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"results": schema.MapNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "Outcome of the command keyed by host",
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.UseStateForUnknown(),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"status": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "`ok` when the command succeeded, `skipped` when the path of `creates` exists, `failed` otherwise",
+						},
+						"exit_code": schema.Int64Attribute{
+							Computed:            true,
+							MarkdownDescription: "Exit code of the command",
+						},
+						"stdout": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Standard output of the command, null when it is not valid UTF-8",
+						},
+						"stdout_base64": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Standard output of the command encoded as base64, use it for binary output",
+						},
+						"stderr": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Standard error of the command, or the connection error",
+						},
+						"started_at": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "RFC 3339 timestamp of when the command started, null when it did not run",
+						},
+						"finished_at": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "RFC 3339 timestamp of when the command exited, null when it did not run",
+						},
+						"duration_ms": schema.Int64Attribute{
+							Computed:            true,
+							MarkdownDescription: "How long the command ran in milliseconds, null when it did not run",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *RemoteExecResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var hostConnection types.Object
+	var hostConnections types.List
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("host_connection"), &hostConnection)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("host_connections"), &hostConnections)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if hostConnection.IsNull() == hostConnections.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("host_connections"),
+			"Invalid Host Configuration",
+			"Exactly one of host_connection or host_connections must be configured.",
+		)
+	}
+}
+
+func (r *RemoteExecResource) MoveState(ctx context.Context) []resource.StateMover {
+	return renamedStateMovers[RemoteExecResourceModel](ctx, &RemoteExecResource{}, "remote_exec")
+}
+
+func (r *RemoteExecResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+func (data *RemoteExecResourceModel) connections() []*HostConnectionModel {
+	if data.HostConnection != nil {
+		return []*HostConnectionModel{data.HostConnection}
+	}
+
+	return data.HostConnections
+}
+
+// execute fans the command out to every host and stores the per host results in data.
+func (r *RemoteExecResource) execute(ctx context.Context, data *RemoteExecResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if !data.RecordSession.IsNull() {
+		ctx = services.WithSessionRecording(ctx, data.RecordSession.ValueBool())
+	}
+
+	group := serverGroup(data.Id.ValueString(), data.connections())
+	results := map[string]attr.Value{}
+	var succeeded []*servers.Server
+
+	command := data.Command.ValueString()
+	if !data.Creates.IsNull() {
+		command = services.CreatesCommand(data.Creates.ValueString(), command)
+	}
+
+	var groupResults []services.GroupResult
+	if data.DelegateTo != nil {
+		delegate := data.DelegateTo.server()
+		groupResults = r.preflightDelegate(ctx, data, delegate, group)
+		groupResults = append(groupResults, r.sshService.ExecuteDelegatedCommand(ctx, command, data.Privileged.ValueBool(), delegate, group)...)
+	} else {
+		groupResults = r.preflight(ctx, data, group)
+		groupResults = append(groupResults, r.sshService.ExecuteGroupCommand(ctx, command, data.Privileged.ValueBool(), group)...)
+	}
+
+	for _, result := range groupResults {
+		values := map[string]attr.Value{
+			"status":        types.StringValue("ok"),
+			"exit_code":     types.Int64Value(0),
+			"stdout":        types.StringValue(""),
+			"stdout_base64": types.StringValue(""),
+			"stderr":        types.StringValue(""),
+			"started_at":    types.StringNull(),
+			"finished_at":   types.StringNull(),
+			"duration_ms":   types.Int64Null(),
+		}
+
+		if result.Command != nil {
+			values["exit_code"] = types.Int64Value(int64(result.Command.ExitCode))
+			values["stdout_base64"] = types.StringValue(result.Command.StdoutBase64())
+			values["stderr"] = types.StringValue(strings.ToValidUTF8(result.Command.Stderr, "\uFFFD"))
+			values["started_at"] = commandTime(result.Command.StartedAt)
+			values["finished_at"] = commandTime(result.Command.FinishedAt)
+			values["duration_ms"] = commandDuration(result.Command)
+
+			if result.Command.StdoutIsUTF8() {
+				values["stdout"] = types.StringValue(result.Command.Stdout)
+			} else {
+				values["stdout"] = types.StringNull()
+			}
+
+			if result.Err == nil && !data.Creates.IsNull() && services.CreatesSkipped(result.Command.Stdout) {
+				values["status"] = types.StringValue("skipped")
+				values["stdout"] = types.StringValue("")
+				values["stdout_base64"] = types.StringValue("")
+			}
+		}
+
+		if result.Err != nil {
+			values["status"] = types.StringValue("failed")
+
+			if result.Command == nil {
+				values["stderr"] = types.StringValue(result.Err.Error())
+			}
+
+			tflog.Warn(ctx, "remote command failed", map[string]any{"host": result.Server.Name, "error": result.Err.Error()})
+
+			if data.FailOnError.ValueBool() {
+				diags.AddError("Command Error", fmt.Sprintf("Command failed on host %s: %s", result.Server.Name, result.Err))
+			}
+		}
+
+		if result.Err == nil {
+			succeeded = append(succeeded, result.Server)
+		}
+
+		value, objectDiags := types.ObjectValue(remoteExecResultAttrTypes, values)
+		diags.Append(objectDiags...)
+		results[result.Server.Name] = value
+	}
+
+	resultsValue, mapDiags := types.MapValue(types.ObjectType{AttrTypes: remoteExecResultAttrTypes}, results)
+	diags.Append(mapDiags...)
+	data.Results = resultsValue
+
+	checksums, checksumDiags := r.checksums(ctx, data, succeeded)
+	diags.Append(checksumDiags...)
+	data.Checksums = checksums
+
+	// Delegated commands leave their files on the delegate.
+	if data.DelegateTo != nil && len(succeeded) > 0 {
+		succeeded = []*servers.Server{data.DelegateTo.server()}
+	}
+
+	diags.Append(r.collect(ctx, data, succeeded)...)
+
+	return diags
+}
+
+// collect downloads the files matching collect_files from hosts into collected_files, and
+// into collect_directory when set.
+func (r *RemoteExecResource) collect(ctx context.Context, data *RemoteExecResourceModel, hosts []*servers.Server) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	collected := map[string]attr.Value{}
+	if len(data.CollectFiles) > 0 {
+		globs := make([]string, 0, len(data.CollectFiles))
+		for _, glob := range data.CollectFiles {
+			globs = append(globs, glob.ValueString())
+		}
+
+		for _, server := range hosts {
+			command := services.CollectFilesCommand(globs)
+			if data.Privileged.ValueBool() {
+				command = privilegedCommand(server, command)
+			}
+
+			result, err := runCommand(services.WithFileContent(ctx), r.sshService, server, command)
+			if err != nil {
+				diags.AddError("Collect Error", fmt.Sprintf("Unable to collect the files of host %s, got error: %s", server.Name, err))
+				continue
+			}
+
+			files, err := services.ParseCollectedFiles(result.Stdout)
+			if err != nil {
+				diags.AddError("Collect Error", fmt.Sprintf("Unable to collect the files of host %s, got error: %s", server.Name, err))
+				continue
+			}
+
+			contents := map[string]attr.Value{}
+			for remotePath, content := range files {
+				contents[remotePath] = types.StringValue(base64.StdEncoding.EncodeToString(content))
+
+				if data.CollectDirectory.IsNull() {
+					continue
+				}
+
+				localPath := filepath.Join(data.CollectDirectory.ValueString(), server.Name, filepath.Clean("/"+remotePath))
+				err := os.MkdirAll(filepath.Dir(localPath), 0o700)
+				if err == nil {
+					err = os.WriteFile(localPath, content, 0o600)
+				}
+				if err != nil {
+					diags.AddError("Collect Error", fmt.Sprintf("Unable to write %s, got error: %s", localPath, err))
+				}
+			}
+
+			value, mapDiags := types.MapValue(types.StringType, contents)
+			diags.Append(mapDiags...)
+			collected[server.Name] = value
+		}
+	}
+
+	collectedValue, mapDiags := types.MapValue(types.MapType{ElemType: types.StringType}, collected)
+	diags.Append(mapDiags...)
+	data.CollectedFiles = collectedValue
+
+	return diags
+}
+
+// checksums returns the checksums of the checksum_of files of hosts keyed by host, then by path.
+// The files of delegated commands are read on the delegate.
+func (r *RemoteExecResource) checksums(ctx context.Context, data *RemoteExecResourceModel, hosts []*servers.Server) (types.Map, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	checksums := map[string]attr.Value{}
+	if len(data.ChecksumOf) > 0 {
+		paths := make([]string, 0, len(data.ChecksumOf))
+		for _, checksumPath := range data.ChecksumOf {
+			paths = append(paths, checksumPath.ValueString())
+		}
+
+		for _, server := range hosts {
+			runner := server
+			if data.DelegateTo != nil {
+				runner = data.DelegateTo.server()
+			}
+
+			lines, err := r.sshService.FileChecksums(ctx, runner, paths, data.Privileged.ValueBool())
+			if err != nil {
+				diags.AddError("SSH Error", fmt.Sprintf("Unable to compute the checksums of host %s, got error: %s", server.Name, err))
+				continue
+			}
+
+			pathChecksums := map[string]string{}
+			for i, checksumPath := range paths {
+				pathChecksums[checksumPath] = lines[i]
+			}
+
+			value, mapDiags := types.MapValueFrom(ctx, types.StringType, pathChecksums)
+			diags.Append(mapDiags...)
+			checksums[server.Name] = value
+		}
+	}
+
+	checksumsValue, mapDiags := types.MapValue(types.MapType{ElemType: types.StringType}, checksums)
+	diags.Append(mapDiags...)
+
+	return checksumsValue, diags
+}
+
+// preflight removes from group the hosts lacking the tools needed to run the command and
+// returns them as failed results. Unreachable hosts are left to fail during the execution.
+func (r *RemoteExecResource) preflight(ctx context.Context, data *RemoteExecResourceModel, group *servers.ServerGroup) []services.GroupResult {
+	if !data.Privileged.ValueBool() {
+		return nil
+	}
+
+	var failed []services.GroupResult
+	var ready []*servers.Server
+	for _, server := range group.Servers {
+		err := r.sshService.Preflight(ctx, server, []string{services.PrivilegeTool})
+
+		var missing *services.MissingToolsError
+		if errors.As(err, &missing) {
+			failed = append(failed, services.GroupResult{Server: server, Err: err})
+			continue
+		}
+
+		ready = append(ready, server)
+	}
+
+	group.Servers = ready
+
+	return failed
+}
+
+// preflightDelegate checks that the delegate can run privileged commands, otherwise every host
+// of group is returned as failed and removed from it.
+func (r *RemoteExecResource) preflightDelegate(ctx context.Context, data *RemoteExecResourceModel, delegate *servers.Server, group *servers.ServerGroup) []services.GroupResult {
+	if !data.Privileged.ValueBool() {
+		return nil
+	}
+
+	err := r.sshService.Preflight(ctx, delegate, []string{services.PrivilegeTool})
+
+	var missing *services.MissingToolsError
+	if !errors.As(err, &missing) {
+		return nil
+	}
+
+	failed := make([]services.GroupResult, 0, len(group.Servers))
+	for _, server := range group.Servers {
+		failed = append(failed, services.GroupResult{Server: server, Err: fmt.Errorf("delegate %s: %w", delegate.Name, err)})
+	}
+	group.Servers = nil
+
+	return failed
+}
+
+func (r *RemoteExecResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteExecResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	id := make([]byte, 8)
+	_, err := rand.Read(id)
+	if err != nil {
+		resp.Diagnostics.AddError("ID Error", fmt.Sprintf("Unable to generate an identifier, got error: %s", err))
+		return
+	}
+
+	data.Id = types.StringValue(hex.EncodeToString(id))
+
+	resp.Diagnostics.Append(r.execute(ctx, &data)...)
+
+	// Save data into Terraform state, failed executions get tainted with their results
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteExecResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteExecResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	// Command executions cannot be read back from the hosts, the state is kept as is and the
+	// next plan runs the command again when the files of checksum_of changed since.
+	changed, diags := r.checksumsChanged(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var flag []byte
+	if changed {
+		flag = []byte("true")
+	}
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, remoteExecChangedKey, flag)...)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// checksumsChanged returns whether the checksums of the files of checksum_of differ from the
+// ones recorded when the command ran.
+func (r *RemoteExecResource) checksumsChanged(ctx context.Context, data *RemoteExecResourceModel) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if len(data.ChecksumOf) == 0 || data.Checksums.IsNull() || data.Checksums.IsUnknown() {
+		return false, diags
+	}
+
+	var recorded map[string]map[string]string
+	diags.Append(data.Checksums.ElementsAs(ctx, &recorded, false)...)
+
+	var hosts []*servers.Server
+	for _, connection := range data.connections() {
+		server := connection.server()
+		if _, ok := recorded[server.Name]; ok {
+			hosts = append(hosts, server)
+		}
+	}
+
+	current, checksumDiags := r.checksums(ctx, data, hosts)
+	diags.Append(checksumDiags...)
+
+	if diags.HasError() {
+		return false, diags
+	}
+
+	var checksums map[string]map[string]string
+	diags.Append(current.ElementsAs(ctx, &checksums, false)...)
+
+	for host, paths := range recorded {
+		if !maps.Equal(paths, checksums[host]) {
+			tflog.Info(ctx, "files of checksum_of changed", map[string]any{"host": host})
+			return true, diags
+		}
+	}
+
+	return false, diags
+}
+
+// ModifyPlan runs the command again when Read found that the files of checksum_of changed.
+func (r *RemoteExecResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || req.State.Raw.IsNull() {
+		return
+	}
+
+	flag, diags := req.Private.GetKey(ctx, remoteExecChangedKey)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() || string(flag) != "true" {
+		return
+	}
+
+	var plan RemoteExecResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.Id = types.StringUnknown()
+	plan.Results = types.MapUnknown(types.ObjectType{AttrTypes: remoteExecResultAttrTypes})
+	plan.CollectedFiles = types.MapUnknown(types.MapType{ElemType: types.StringType})
+	plan.Checksums = types.MapUnknown(types.MapType{ElemType: types.StringType})
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+	resp.RequiresReplace = append(resp.RequiresReplace, path.Root("checksum_of"))
+}
+
+func (r *RemoteExecResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data RemoteExecResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Only attributes that do not require a new execution can change in place.
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteExecResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+}
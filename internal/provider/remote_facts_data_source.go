@@ -0,0 +1,104 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteFactsDataSource{}
+
+func NewRemoteFactsDataSource() datasource.DataSource {
+	return &RemoteFactsDataSource{}
+}
+
+// RemoteFactsDataSource reads the custom facts persisted on a host.
+type RemoteFactsDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemoteFactsDataSourceModel describes the data source data model.
+type RemoteFactsDataSourceModel struct {
+	HostConnection *HostConnectionModel         `tfsdk:"host_connection"`
+	Directory      types.String                 `tfsdk:"directory"`
+	Facts          map[string]map[string]string `tfsdk:"facts"`
+}
+
+func (d *RemoteFactsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_facts"
+}
+
+func (d *RemoteFactsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Custom facts persisted on a host, e.g. by `remote_host_kv_fact` resources of another " +
+			"configuration, to coordinate several runs",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": dataSourceHostConnectionSchema(),
+			"directory": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Directory of the fact files. Defaults to `" + services.FactsDir + "`",
+			},
+			"facts": schema.MapAttribute{
+				Computed:            true,
+				ElementType:         types.MapType{ElemType: types.StringType},
+				MarkdownDescription: "Facts keyed by group, then by name, e.g. `data.remote_host_facts.db.facts[\"app\"][\"version\"]`. Files that are not JSON objects are skipped",
+			},
+		},
+	}
+}
+
+func (d *RemoteFactsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+func (d *RemoteFactsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteFactsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	directory := services.FactsDir
+	if !data.Directory.IsNull() {
+		directory = data.Directory.ValueString()
+	}
+
+	result, err := runCommand(services.WithFileContent(ctx), d.sshService, data.HostConnection.server(), services.ReadFactsCommand(directory))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the facts of %s, got error: %s", directory, err))
+		return
+	}
+
+	data.Facts = services.ParseFactFiles(result.Stdout)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,257 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"remote-provider/internal/provider/servers"
+	"remote-provider/internal/provider/services"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// fileCommand runs command on the host of the file, holding the lock file when one is set and
+// through sudo when the resource is privileged.
+func fileCommand(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource, command string) (*servers.ServerCommand, error) {
+	server := data.HostConnection.server()
+	if !data.LockFile.IsNull() {
+		command = services.LockedCommand(data.LockFile.ValueString(), data.lockTimeout(), command)
+	}
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	result, err := runCommand(ctx, r.sshService, server, command)
+
+	var exitErr *ExitCodeError
+	if errors.As(err, &exitErr) && exitErr.code == services.LockConflictExitCode && !data.LockFile.IsNull() {
+		return result, fmt.Errorf("%s is still locked after %s by another process", data.LockFile.ValueString(), data.lockTimeout())
+	}
+
+	return result, err
+}
+
+// lockTimeout returns how long commands wait for the lock file of the resource.
+func (data *RemoteFileResourceModel) lockTimeout() time.Duration {
+	timeout, err := time.ParseDuration(data.LockTimeout.ValueString())
+	if err != nil {
+		return time.Minute
+	}
+
+	return timeout
+}
+
+func readFlags(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource) (services.FileFlags, error) {
+	result, err := fileCommand(ctx, data, r, services.GetFlagsCommand(data.Path.ValueString()))
+	if err != nil {
+		return services.FileFlags{}, err
+	}
+
+	return services.ParseLsattr(result.Stdout)
+}
+
+// applyAttributes writes the file with write when set, then applies the changes of its
+// ownership, its ACL, extended attributes and inode flags. An immutable or append-only file would
+// reject the changes, so those flags are lifted first and reapplied afterwards, keeping the
+// current value of the flags the configuration does not manage.
+func applyAttributes(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource, write func() error, ownership services.FileOwnership, removedXattrs []string) error {
+	managed := !data.Immutable.IsNull() || !data.AppendOnly.IsNull()
+
+	// A file that is written may not exist yet.
+	current, err := readFlags(ctx, data, r)
+	if err != nil && managed && write == nil {
+		return err
+	}
+
+	lifted := err == nil && (current.Immutable || current.AppendOnly)
+	if lifted {
+		_, err = fileCommand(ctx, data, r, services.SetFlagsCommand(data.Path.ValueString(), services.FileFlags{}))
+		if err != nil {
+			return err
+		}
+	}
+
+	if write != nil {
+		err = write()
+		if err != nil {
+			return err
+		}
+	}
+
+	// chmod resets the ACL mask, so the ACL is applied afterwards.
+	command := services.ChangeOwnershipCommand(data.Path.ValueString(), ownership, data.FollowSymlinks.ValueBool())
+	if command != "" {
+		_, err = fileCommand(ctx, data, r, command)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = applyACL(ctx, data, r)
+	if err != nil {
+		return err
+	}
+
+	err = applyXattrs(ctx, data, r, removedXattrs)
+	if err != nil {
+		return err
+	}
+
+	desired := current
+	if !data.Immutable.IsNull() && !data.Immutable.IsUnknown() {
+		desired.Immutable = data.Immutable.ValueBool()
+	}
+	if !data.AppendOnly.IsNull() && !data.AppendOnly.IsUnknown() {
+		desired.AppendOnly = data.AppendOnly.ValueBool()
+	}
+
+	if !lifted && desired == current {
+		return nil
+	}
+
+	if lifted && desired == (services.FileFlags{}) {
+		return nil
+	}
+
+	_, err = fileCommand(ctx, data, r, services.SetFlagsCommand(data.Path.ValueString(), desired))
+
+	return err
+}
+
+func applyXattrs(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource, removed []string) error {
+	xattrs := map[string]string{}
+	if !data.Xattrs.IsNull() && !data.Xattrs.IsUnknown() {
+		diags := data.Xattrs.ElementsAs(ctx, &xattrs, false)
+		if diags.HasError() {
+			return fmt.Errorf("unable to read the configured extended attributes of %s", data.Path.ValueString())
+		}
+	}
+
+	if len(xattrs) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	_, err := fileCommand(ctx, data, r, services.SetXattrsCommand(data.Path.ValueString(), xattrs, removed, data.FollowSymlinks.ValueBool()))
+
+	return err
+}
+
+// removedXattrs returns the extended attributes managed in state that are no longer configured.
+func removedXattrs(state types.Map, plan types.Map) []string {
+	var removed []string
+	for name := range state.Elements() {
+		if _, ok := plan.Elements()[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+
+	return removed
+}
+
+// ownershipChanges returns the known mode, owner and group of data that differ from the ones of
+// state, all of them when state is nil.
+func (data *RemoteFileResourceModel) ownershipChanges(state *RemoteFileResourceModel) services.FileOwnership {
+	var previous services.FileOwnership
+	if state != nil {
+		previous = services.FileOwnership{Mode: state.Mode.ValueString(), Owner: state.Owner.ValueString(), Group: state.Group.ValueString()}
+	}
+
+	var changes services.FileOwnership
+	mode, err := services.NormalizeMode(data.Mode.ValueString())
+	if previousMode, _ := services.NormalizeMode(previous.Mode); err == nil && mode != previousMode {
+		changes.Mode = mode
+	}
+	if owner := data.Owner.ValueString(); owner != previous.Owner {
+		changes.Owner = owner
+	}
+	if group := data.Group.ValueString(); group != previous.Group {
+		changes.Group = group
+	}
+
+	return changes
+}
+
+// readOwnership refreshes the mode, owner and group of the file.
+func readOwnership(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource) error {
+	platform, err := r.sshService.DetectPlatform(ctx, data.HostConnection.server())
+	if err != nil {
+		return err
+	}
+
+	command := platform.OwnershipCommand(services.ShellQuote(data.Path.ValueString()), data.FollowSymlinks.ValueBool())
+	result, err := fileCommand(ctx, data, r, command)
+	if err != nil {
+		return err
+	}
+
+	ownership, err := services.ParseOwnership(result.Stdout)
+	if err != nil {
+		return err
+	}
+
+	data.Mode = NewFileModeValue(ownership.Mode)
+	data.Owner = types.StringValue(ownership.Owner)
+	data.Group = types.StringValue(ownership.Group)
+
+	return nil
+}
+
+// readAttributes refreshes the ownership, the managed extended attributes and the inode flags
+// of the file. Attributes the configuration does not manage are ignored so they do not show as
+// drift.
+func readAttributes(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource) error {
+	err := readOwnership(ctx, data, r)
+	if err != nil {
+		return err
+	}
+
+	if !data.Immutable.IsNull() || !data.AppendOnly.IsNull() {
+		flags, err := readFlags(ctx, data, r)
+		if err != nil {
+			return err
+		}
+
+		if !data.Immutable.IsNull() {
+			data.Immutable = types.BoolValue(flags.Immutable)
+		}
+		if !data.AppendOnly.IsNull() {
+			data.AppendOnly = types.BoolValue(flags.AppendOnly)
+		}
+	}
+
+	if data.Xattrs.IsNull() || data.Xattrs.IsUnknown() {
+		return nil
+	}
+
+	result, err := fileCommand(ctx, data, r, services.GetXattrsCommand(data.Path.ValueString(), data.FollowSymlinks.ValueBool()))
+	if err != nil {
+		return err
+	}
+
+	remote, err := services.ParseGetfattr(result.Stdout)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]attr.Value{}
+	for name := range maps.Keys(data.Xattrs.Elements()) {
+		if value, ok := remote[name]; ok {
+			values[name] = types.StringValue(value)
+		}
+	}
+
+	xattrs, diags := types.MapValue(types.StringType, values)
+	if diags.HasError() {
+		return fmt.Errorf("unable to store the extended attributes of %s", data.Path.ValueString())
+	}
+
+	data.Xattrs = xattrs
+
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// remoteFileContentMaxBytes bounds the content returned by the function, larger files
+// belong in a remote_host_file resource.
+const remoteFileContentMaxBytes = 1024 * 1024
+
+var (
+	_ function.Function = RemoteFileContentFunction{}
+)
+
+func NewRemoteFileContentFunction() function.Function {
+	return RemoteFileContentFunction{}
+}
+
+// RemoteFileContentFunction reads a small file from a remote host.
+type RemoteFileContentFunction struct{}
+
+func (r RemoteFileContentFunction) Metadata(_ context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "remote_file_content"
+}
+
+func (r RemoteFileContentFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Reads a small file from a remote host",
+		MarkdownDescription: "Connects to the host and returns the content of the file at `path`, up to 1 MiB. " +
+			"Functions have no state: the file is read again every time Terraform evaluates the expression, " +
+			"during both plan and apply, so a file changing in between fails the apply with an inconsistent plan. " +
+			"Use the `remote_host_file` resource to track a file across runs. " +
+			"The provider configuration does not apply to the connection opened by the function.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "host",
+				MarkdownDescription: "Host address",
+			},
+			function.StringParameter{
+				Name:                "user",
+				MarkdownDescription: "User name to access the host",
+			},
+			function.StringParameter{
+				Name:                "private_key",
+				MarkdownDescription: "Path to the private key used to authenticate, or null to use the `REMOTE_HOST_PRIVATE_KEY` environment variable",
+				AllowNullValue:      true,
+			},
+			function.StringParameter{
+				Name:                "password",
+				MarkdownDescription: "Password used to authenticate, or null to use the `REMOTE_HOST_PASSWORD` environment variable",
+				AllowNullValue:      true,
+			},
+			function.StringParameter{
+				Name:                "path",
+				MarkdownDescription: "Path to the file on the remote host",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (r RemoteFileContentFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var connection HostConnectionModel
+	var path string
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &connection.Host, &connection.User, &connection.PrivateKey, &connection.Password, &path))
+
+	if resp.Error != nil {
+		return
+	}
+
+	// Functions are not configured by the provider, so each call uses its own connection.
+	sshService := &services.SSHService{OutputFraming: true}
+	defer sshService.Close()
+
+	command := fmt.Sprintf("head -c %d -- %s", remoteFileContentMaxBytes+1, services.ShellQuote(path))
+	result, err := runCommand(ctx, sshService, connection.server(), command)
+	if err != nil {
+		resp.Error = function.NewFuncError(fmt.Sprintf("Unable to read %s on %s, got error: %s", path, connection.Host.ValueString(), err))
+		return
+	}
+
+	if len(result.Stdout) > remoteFileContentMaxBytes {
+		resp.Error = function.NewFuncError(fmt.Sprintf("File %s on %s is larger than %d bytes, use a remote_host_file resource instead", path, connection.Host.ValueString(), remoteFileContentMaxBytes))
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, result.Stdout))
+}
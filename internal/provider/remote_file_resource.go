@@ -4,201 +4,1279 @@
 package provider
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"remote-provider/internal/provider/servers"
 	"remote-provider/internal/provider/services"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/setplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &RemoteFileResource{}
+var _ resource.ResourceWithMoveState = &RemoteFileResource{}
 var _ resource.ResourceWithImportState = &RemoteFileResource{}
+var _ resource.ResourceWithValidateConfig = &RemoteFileResource{}
+var _ resource.ResourceWithModifyPlan = &RemoteFileResource{}
+var _ resource.ResourceWithUpgradeState = &RemoteFileResource{}
+
+// stateContentModes lists how the content of a file is stored in the state, the first one is
+// the default.
+var stateContentModes = []string{"plain", "encrypted", "checksum"}
+
+// fileIdentities lists the strategies deriving the ID of a file, the first one is the default.
+var fileIdentities = []string{"path", "checksum", "inode"}
 
 func NewRemoteFileResource() resource.Resource {
 	return &RemoteFileResource{}
 }
 
+// NewLegacyRemoteFileResource registers the resource under its deprecated unprefixed name.
+func NewLegacyRemoteFileResource() resource.Resource {
+	return &RemoteFileResource{legacyTypeName: "remote_file"}
+}
+
 // RemoteFileResource defines the resource implementation.
 type RemoteFileResource struct {
-	sshService *services.SSHService
+	sshService     *services.SSHService
+	legacyTypeName string
+}
+
+// RemoteFileResourceModel describes the resource data model.
+type RemoteFileResourceModel struct {
+	Id                       types.String         `tfsdk:"id"`
+	HostConnection           *HostConnectionModel `tfsdk:"host_connection"`
+	Path                     RemotePath           `tfsdk:"path"`
+	Content                  types.String         `tfsdk:"content"`
+	ContentBase64            types.String         `tfsdk:"content_base64"`
+	ContentWO                types.String         `tfsdk:"content_wo"`
+	ContentWOVersion         types.Int64          `tfsdk:"content_wo_version"`
+	Source                   types.String         `tfsdk:"source"`
+	SourceChecksum           types.String         `tfsdk:"source_checksum"`
+	Privileged               types.Bool           `tfsdk:"privileged"`
+	Sensitive                types.Bool           `tfsdk:"sensitive"`
+	SensitiveContent         types.String         `tfsdk:"sensitive_content"`
+	EnforceSecurePermissions types.Bool           `tfsdk:"enforce_secure_permissions"`
+	StateContent             types.String         `tfsdk:"state_content"`
+	ChecksumAlgorithm        types.String         `tfsdk:"checksum_algorithm"`
+	Checksum                 types.String         `tfsdk:"checksum"`
+	Acl                      types.Set            `tfsdk:"acl"`
+	Xattrs                   types.Map            `tfsdk:"xattrs"`
+	Immutable                types.Bool           `tfsdk:"immutable"`
+	AppendOnly               types.Bool           `tfsdk:"append_only"`
+	Mode                     FileMode             `tfsdk:"mode"`
+	Owner                    types.String         `tfsdk:"owner"`
+	Group                    types.String         `tfsdk:"group"`
+	FollowSymlinks           types.Bool           `tfsdk:"follow_symlinks"`
+	IsSymlink                types.Bool           `tfsdk:"is_symlink"`
+	ContentCommand           types.String         `tfsdk:"content_command"`
+	MaxAge                   Duration             `tfsdk:"max_age"`
+	LockFile                 RemotePath           `tfsdk:"lock_file"`
+	LockTimeout              Duration             `tfsdk:"lock_timeout"`
+	OnlyIf                   types.String         `tfsdk:"only_if"`
+	Unless                   types.String         `tfsdk:"unless"`
+	Identity                 types.String         `tfsdk:"identity"`
+	Mtime                    types.String         `tfsdk:"mtime"`
+	Timeouts                 *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemoteFileResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_file"
+	if r.legacyTypeName != "" {
+		resp.TypeName = r.legacyTypeName
+	}
+}
+
+func (r *RemoteFileResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		DeprecationMessage: legacyDeprecationMessage(r.legacyTypeName, "remote_host_file"),
+		Version:            2,
+
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "A file at a remote host, read as it is, written with `content` or uploaded from `source`. Destroying the resource leaves the file on the host",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnectionSchema(),
+			"path": schema.StringAttribute{
+				CustomType:          RemotePathType{},
+				Required:            true,
+				MarkdownDescription: "Path to the file on the remote host",
+			},
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to run the command as root",
+				Default:             booldefault.StaticBool(false),
+			},
+			"follow_symlinks": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether a symlink at `path` is dereferenced. When enabled the inode, content and checksum are the ones of the link target, otherwise the inode is the one of the link, the content is the link target path and extended attributes are managed on the link itself. ACL entries and inode flags always apply to the link target",
+				Default:             booldefault.StaticBool(true),
+			},
+			"is_symlink": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether `path` is a symlink",
+			},
+			"sensitive": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether the file holds a secret, checked by `enforce_secure_permissions`. `content` is always sensitive, so this no longer decides which attribute holds it",
+				Default:             booldefault.StaticBool(false),
+			},
+			"enforce_secure_permissions": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to fail the apply when a `sensitive` file can be read by its group, by others or through a named ACL entry, so secrets pushed to the host are not exposed by a lax umask or mode. Only applies when `sensitive` is enabled",
+			},
+			"state_content": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				MarkdownDescription: "How the file content is stored in the state: `plain`, `encrypted` with the provider " +
+					"`state_encryption_key` so only the machine running Terraform can read it, or `checksum` to keep " +
+					"`content` empty and only track `checksum`. Defaults to `plain`. Only applies to content read back from the " +
+					"host: the configured `content`, `sensitive_content` and `content_base64` are stored as they are in the " +
+					"state, set secrets in `content_wo` to keep them out of it",
+				Default: stringdefault.StaticString(stateContentModes[0]),
+				Validators: []validator.String{
+					stringOneOf(stateContentModes...),
+				},
+			},
+			"identity": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				MarkdownDescription: "How `id` is derived: `path` from the host and the path, `checksum` from the host and the " +
+					"checksum of the content, or `inode` from the host and the inode, which changes whenever the file is " +
+					"atomically replaced. Defaults to `path`",
+				Default: stringdefault.StaticString(fileIdentities[0]),
+				Validators: []validator.String{
+					stringOneOf(fileIdentities...),
+				},
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier of the file, see `identity`",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"content": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				MarkdownDescription: "File content, see `state_content` for how it is stored. When set the file is written with it " +
+					"and written again whenever it drifts. Existing files are rewritten in place, keeping their owner, mode, ACL " +
+					"and inode, missing ones are created with mode `0600` when `sensitive`, the provider `default_file_mode` " +
+					"otherwise. When unset the content is read from the host. Always sensitive since whether a file holds a " +
+					"secret is not known from its schema, wrap it in `nonsensitive()` to show it in plans and outputs",
+				Sensitive: true,
+			},
+			"content_base64": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "Base64 encoded content of binary files such as keystores or small archives, instead of setting " +
+					"`content`. The decoded bytes are uploaded as they are, existing files are rewritten in place and missing ones " +
+					"created as with `content`. Binary content is not read back into `content`, the file is written again when " +
+					"its `checksum` drifts",
+				Sensitive: true,
+			},
+			"content_wo": schema.StringAttribute{
+				Optional:  true,
+				Sensitive: true,
+				WriteOnly: true,
+				MarkdownDescription: "Content written to the file that is never stored in the plan or the state, e.g. a secret from an " +
+					"ephemeral resource, instead of setting `content`. Requires `content_wo_version`. Existing files are rewritten " +
+					"in place and missing ones created as with `content`. Only the `checksum` of the file is read back, the file " +
+					"is written again when it differs from the checksum of `content_wo` or when `content_wo_version` changes",
+			},
+			"content_wo_version": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "Version of `content_wo`, changing it writes the file again",
+			},
+			"sensitive_content": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Same value as `content`, setting it writes the file as setting `content` does",
+				DeprecationMessage:  "Use content instead, which is now sensitive. sensitive_content will be removed in the next major version.",
+				Sensitive:           true,
+			},
+			"source": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "Path of a local file on the machine running Terraform uploaded to `path`, instead of setting " +
+					"`content`. It is uploaded again when its content changes or when the file is missing on the host, existing " +
+					"files are rewritten in place and missing ones created as with `content`. Its content is read back into " +
+					"`content`, use `state_content` to keep large files out of the state",
+			},
+			"source_checksum": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Hex encoded sha256 digest of `source` when it was last uploaded",
+			},
+			"checksum_algorithm": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Algorithm used for `checksum`, one of `md5`, `sha1`, `sha256`, `sha512` or `blake2b`",
+				Default:             stringdefault.StaticString("sha256"),
+				Validators: []validator.String{
+					stringOneOf(services.ChecksumAlgorithms()...),
+				},
+			},
+			"checksum": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Hex encoded digest of the file content computed with `checksum_algorithm`",
+			},
+			"mode": schema.StringAttribute{
+				CustomType:          FileModeType{},
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Octal mode of the file, e.g. `0640`, applied with `chmod`. Read back from the host when unset",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"owner": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Name of the user owning the file, applied with `chown`. Read back from the host when unset",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"group": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Name of the group owning the file, applied with `chown`. Read back from the host when unset",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"acl": schema.SetAttribute{
+				Optional:            true,
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Named POSIX ACL entries of the file such as `user:alice:rw-` or `default:group:ops:r-x`, managed with `setfacl`. Owner, group, other and mask entries follow the file mode. Read back from the host when unset",
+				Validators: []validator.Set{
+					aclEntriesValidator{},
+				},
+				PlanModifiers: []planmodifier.Set{
+					setplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"xattrs": schema.MapAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Extended attributes of the file, e.g. `user.owner`. Only the attributes listed here are managed, removing one from the map removes it from the file",
+			},
+			"immutable": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether the file has the `chattr +i` immutable flag. The flag is lifted while the provider changes the file and applied again afterwards",
+			},
+			"append_only": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether the file has the `chattr +a` append-only flag",
+			},
+			"content_command": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Command generating the file on the host, e.g. `openssl dhparam -out /etc/ssl/dhparam.pem 2048`. It runs when the file is missing, older than `max_age` or when the command changes. Only the checksum of generated files is stored, `content` stays empty so large artifacts do not live in the state",
+			},
+			"max_age": schema.StringAttribute{
+				CustomType:          DurationType{},
+				Optional:            true,
+				MarkdownDescription: "Maximum age of the file, e.g. `720h`. The resource is planned for update once `mtime` is older, so periodically renewed files such as certificates or CRLs show up in plans",
+			},
+			"lock_file": schema.StringAttribute{
+				CustomType: RemotePathType{},
+				Optional:   true,
+				MarkdownDescription: "Path of a `flock` lock file on the host held exclusively around every command of the resource, " +
+					"so concurrent Terraform runs or a configuration management agent locking the same file do not interleave " +
+					"their changes, e.g. `/run/lock/nginx.conf.lock`. Requires `flock` from util-linux",
+			},
+			"lock_timeout": schema.StringAttribute{
+				CustomType:          DurationType{},
+				Optional:            true,
+				MarkdownDescription: "How long to wait for the lock of `lock_file`, e.g. `5m`. Defaults to `60s`",
+			},
+			"mtime": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "RFC 3339 modification time of the file",
+			},
+			"only_if": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "Command run on the host before changing the file, the file is only generated and its " +
+					"attributes only applied when it succeeds. While it fails the file is left alone and not refreshed, " +
+					"e.g. `test ! -e /etc/appliance-managed`",
+			},
+			"unless": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "Command run on the host before changing the file, the file is left alone and not " +
+					"refreshed while it succeeds, e.g. `grep -q 'managed by appliance' /etc/app.conf`",
+			},
+		},
+	}
+}
+
+// fileID returns the ID of a file according to the identity strategy.
+func fileID(identity string, hostID string, filePath string, checksum string, inode string) string {
+	switch identity {
+	case "checksum":
+		return hostID + "-" + checksum
+	case "inode":
+		return hostID + "-" + inode
+	default:
+		return hostID + ":" + filePath
+	}
+}
+
+// UpgradeState moves the states of the previous schema versions to the current one: version 0
+// files, identified by their inode, get the path identity and version 1 files get their
+// content in the single content attribute. The prior state is handled as JSON so the schema
+// of every version does not need to be kept.
+func (r *RemoteFileResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: fileStateUpgrader(upgradeFileStateV0, upgradeFileStateV1),
+		1: fileStateUpgrader(upgradeFileStateV1),
+	}
+}
+
+// fileStateUpgrader returns an upgrader applying steps in order to the JSON state.
+func fileStateUpgrader(steps ...func([]byte) ([]byte, error)) resource.StateUpgrader {
+	return resource.StateUpgrader{
+		StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+			upgraded := req.RawState.JSON
+			for _, step := range steps {
+				var err error
+				upgraded, err = step(upgraded)
+				if err != nil {
+					resp.Diagnostics.AddError("State Upgrade Error", fmt.Sprintf("Unable to upgrade the state of the file, got error: %s", err))
+					return
+				}
+			}
+
+			resp.DynamicValue = &tfprotov6.DynamicValue{JSON: upgraded}
+		},
+	}
+}
+
+// upgradeFileStateV0 sets the path identity and the matching ID on a version 0 state.
+func upgradeFileStateV0(raw []byte) ([]byte, error) {
+	var state map[string]any
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, err
+	}
+
+	filePath, _ := state["path"].(string)
+	connection, _ := state["host_connection"].(map[string]any)
+	hostID, _ := connection["host_id"].(string)
+	if hostID == "" {
+		hostID, _ = connection["host"].(string)
+	}
+
+	state["identity"] = fileIdentities[0]
+	state["id"] = fileID(fileIdentities[0], hostID, filePath, "", "")
+
+	return json.Marshal(state)
+}
+
+// upgradeFileStateV1 moves the content of sensitive files from sensitive_content to content,
+// which holds the content of every file since version 2.
+func upgradeFileStateV1(raw []byte) ([]byte, error) {
+	var state map[string]any
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, err
+	}
+
+	if sensitive, _ := state["sensitive"].(bool); sensitive {
+		state["content"] = state["sensitive_content"]
+	}
+	state["sensitive_content"] = state["content"]
+	if state["state_content"] == nil {
+		state["state_content"] = stateContentModes[0]
+	}
+
+	return json.Marshal(state)
+}
+
+func (r *RemoteFileResource) MoveState(ctx context.Context) []resource.StateMover {
+	return renamedStateMovers[RemoteFileResourceModel](ctx, &RemoteFileResource{}, "remote_file")
+}
+
+// ModifyPlan digests the local source of the file, then plans an update of files older than
+// max_age, that must be generated again, whose written content drifted or whose source changed.
+// The read back content and modification time are then unknown until the apply.
+func (r *RemoteFileResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan, state, config RemoteFileResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	switch {
+	case plan.Source.IsNull():
+		plan.SourceChecksum = types.StringNull()
+	case !plan.Source.IsUnknown():
+		checksum, err := sourceChecksum(plan.Source.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("source"), "Invalid Source", fmt.Sprintf("Unable to read %s, got error: %s", plan.Source.ValueString(), err))
+			return
+		}
+		plan.SourceChecksum = types.StringValue(checksum)
+	}
+
+	if req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+		return
+	}
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// A missing file has an empty checksum, it is written again even when the content is empty.
+	content, managed := config.managedContent()
+	rewrite := managed && (state.Checksum.ValueString() == "" || content != state.Content.ValueString())
+	rewrite = rewrite || !plan.Source.IsNull() && (state.Checksum.ValueString() == "" || !plan.SourceChecksum.Equal(state.SourceChecksum))
+	// Binary and write-only content is not kept in the state, its drift shows in the checksum of
+	// the file.
+	if name, uploaded, ok := config.checksumOnlyContent(); ok {
+		checksum, err := services.Checksum(state.ChecksumAlgorithm.ValueString(), uploaded)
+		if err != nil {
+			resp.Diagnostics.AddError("Checksum Error", fmt.Sprintf("Unable to compute the checksum of %s, got error: %s", name, err))
+			return
+		}
+		rewrite = rewrite || state.Checksum.ValueString() != checksum
+	}
+	rewrite = rewrite || !plan.ContentWOVersion.Equal(state.ContentWOVersion)
+
+	if !expired(plan.MaxAge, state.Mtime) && !needsGeneration(&plan, &state) && !rewrite {
+		resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+		return
+	}
+
+	tflog.Info(ctx, "file must be renewed", map[string]any{"path": plan.Path.ValueString(), "mtime": state.Mtime.ValueString()})
+
+	plan.Mtime = types.StringUnknown()
+	if config.Content.IsNull() {
+		plan.Content = types.StringUnknown()
+	}
+	if config.SensitiveContent.IsNull() {
+		plan.SensitiveContent = types.StringUnknown()
+	}
+	plan.Checksum = types.StringUnknown()
+	// Generating the file again may change the ownership the configuration does not manage.
+	if config.Mode.IsNull() {
+		plan.Mode = NewFileModeUnknown()
+	}
+	if config.Owner.IsNull() {
+		plan.Owner = types.StringUnknown()
+	}
+	if config.Group.IsNull() {
+		plan.Group = types.StringUnknown()
+	}
+	if plan.Identity.ValueString() == "checksum" {
+		plan.Id = types.StringUnknown()
+	}
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
 }
 
-// HostConnectionModel describes the connection block attributes
-type HostConnectionModel struct {
-	Host       types.String `tfsdk:"host"`
-	User       types.String `tfsdk:"user"`
-	PrivateKey types.String `tfsdk:"private_key"`
-	Password   types.String `tfsdk:"password"`
-}
+func (r *RemoteFileResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data RemoteFileResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.Content.IsNull() && !data.SensitiveContent.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("sensitive_content"), "Conflicting Content", "Only one of content or sensitive_content can be set.")
+		return
+	}
+
+	if !data.Source.IsNull() && (!data.Content.IsNull() || !data.SensitiveContent.IsNull()) {
+		resp.Diagnostics.AddAttributeError(path.Root("source"), "Conflicting Content", "Only one of content, sensitive_content or source can be set.")
+		return
+	}
+
+	if !data.ContentBase64.IsNull() && (!data.Content.IsNull() || !data.SensitiveContent.IsNull() || !data.Source.IsNull()) {
+		resp.Diagnostics.AddAttributeError(path.Root("content_base64"), "Conflicting Content", "Only one of content, sensitive_content, content_base64 or source can be set.")
+		return
+	}
+
+	if !data.ContentWO.IsNull() && (!data.Content.IsNull() || !data.SensitiveContent.IsNull() || !data.Source.IsNull() || !data.ContentBase64.IsNull()) {
+		resp.Diagnostics.AddAttributeError(path.Root("content_wo"), "Conflicting Content", "Only one of content, sensitive_content, content_base64, content_wo or source can be set.")
+		return
+	}
+
+	if data.ContentWO.IsNull() != data.ContentWOVersion.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("content_wo_version"), "Missing Content Version", "content_wo and content_wo_version must be set together.")
+		return
+	}
+
+	if !data.ContentBase64.IsNull() && !data.ContentBase64.IsUnknown() {
+		_, err := base64.StdEncoding.DecodeString(data.ContentBase64.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("content_base64"), "Invalid Content", fmt.Sprintf("Unable to decode content_base64, got error: %s", err))
+			return
+		}
+	}
+
+	attribute := path.Root("content")
+	switch {
+	case !data.SensitiveContent.IsNull():
+		attribute = path.Root("sensitive_content")
+	case !data.Source.IsNull():
+		attribute = path.Root("source")
+	case !data.ContentBase64.IsNull():
+		attribute = path.Root("content_base64")
+	case !data.ContentWO.IsNull():
+		attribute = path.Root("content_wo")
+	case data.Content.IsNull():
+		return
+	}
+
+	if !data.ContentCommand.IsNull() {
+		resp.Diagnostics.AddAttributeError(attribute, "Conflicting Content", "The content of a file cannot be set together with content_command.")
+	}
+	if data.Source.IsNull() && !data.checksumOnly() && !data.StateContent.IsNull() && !data.StateContent.IsUnknown() && data.StateContent.ValueString() != stateContentModes[0] {
+		resp.Diagnostics.AddAttributeError(attribute, "Conflicting Content", "The configured content is stored in the state as is, state_content must be plain. Set content_wo instead to keep the content out of the state.")
+	}
+	if !data.FollowSymlinks.IsNull() && !data.FollowSymlinks.IsUnknown() && !data.FollowSymlinks.ValueBool() {
+		resp.Diagnostics.AddAttributeError(attribute, "Conflicting Content", "The content is written to the target of symlinks, follow_symlinks must be enabled.")
+	}
+}
+
+func (r *RemoteFileResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+// requiredTools lists the tools the host needs for the attributes configured in data.
+func (data *RemoteFileResourceModel) requiredTools(platform services.Platform) []string {
+	tools := append(platform.InodeTools(), "cat")
+	if data.Privileged.ValueBool() {
+		tools = append(tools, services.PrivilegeTool)
+	}
+	if !data.FollowSymlinks.ValueBool() {
+		tools = append(tools, "readlink")
+	}
+	if !data.Acl.IsNull() && !data.Acl.IsUnknown() {
+		tools = append(tools, "getfacl", "setfacl")
+	}
+	if !data.Xattrs.IsNull() {
+		tools = append(tools, "getfattr", "setfattr")
+	}
+	if !data.Immutable.IsNull() || !data.AppendOnly.IsNull() {
+		tools = append(tools, "lsattr", "chattr")
+	}
+	if data.generated() || data.checksumOnly() {
+		tools = append(tools, services.ChecksumTool(data.ChecksumAlgorithm.ValueString()))
+	}
+	if !data.LockFile.IsNull() {
+		tools = append(tools, "flock")
+	}
+
+	return tools
+}
+
+// preflightFile checks that the host of the file provides the tools the resource needs.
+func preflightFile(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource) error {
+	server := data.HostConnection.server()
+
+	platform, err := r.sshService.DetectPlatform(ctx, server)
+	if err != nil {
+		return err
+	}
+
+	return r.sshService.Preflight(ctx, server, data.requiredTools(platform))
+}
+
+// fileManagedKey is the private state key marking files whose content is written by the
+// resource, so Read plans writing a missing file again instead of failing.
+const fileManagedKey = "managed_content"
+
+// managedContent returns the content written to the file, configured with content or
+// sensitive_content. The values of data must come from the configuration or the plan.
+func (data *RemoteFileResourceModel) managedContent() (string, bool) {
+	for _, value := range []types.String{data.Content, data.SensitiveContent} {
+		if !value.IsNull() && !value.IsUnknown() {
+			return value.ValueString(), true
+		}
+	}
+
+	return "", false
+}
+
+// checksumOnlyContent returns the name of the attribute and the content of files written with
+// content_base64, decoded, or content_wo. The values of data must come from the configuration,
+// the only one holding content_wo.
+func (data *RemoteFileResourceModel) checksumOnlyContent() (string, []byte, bool) {
+	if !data.ContentWO.IsNull() && !data.ContentWO.IsUnknown() {
+		return "content_wo", []byte(data.ContentWO.ValueString()), true
+	}
+	if data.ContentBase64.IsNull() || data.ContentBase64.IsUnknown() {
+		return "", nil, false
+	}
+
+	content, err := base64.StdEncoding.DecodeString(data.ContentBase64.ValueString())
+
+	return "content_base64", content, err == nil
+}
+
+// checksumOnly reports whether the file is written with content_base64 or content_wo, only its
+// checksum is then read back. content_wo_version marks the files written with content_wo in the
+// plan and the state, which never hold content_wo.
+func (data *RemoteFileResourceModel) checksumOnly() bool {
+	return !data.ContentBase64.IsNull() || !data.ContentWOVersion.IsNull()
+}
+
+// uploaded reports whether the content of the file is uploaded from source, content_base64 or
+// content_wo.
+func (data *RemoteFileResourceModel) uploaded() bool {
+	return !data.Source.IsNull() || data.checksumOnly()
+}
+
+// managedFlag returns the value of fileManagedKey, removing the key from files that are only read.
+func managedFlag(managed bool) []byte {
+	if !managed {
+		return nil
+	}
+
+	return []byte("true")
+}
+
+// keepManagedContent stores content in both content attributes of data, so the state matches
+// the configuration of a file its guards did not let write.
+func keepManagedContent(data *RemoteFileResourceModel, content string) {
+	data.Content = types.StringValue(content)
+	data.SensitiveContent = data.Content
+}
+
+// createMode returns the mode of the file when it is created by writing its content or source.
+func (data *RemoteFileResourceModel) createMode(r *RemoteFileResource) string {
+	if data.Mode.ValueString() == "" && data.Sensitive.ValueBool() {
+		return "0600"
+	}
+
+	return r.sshService.FileMode(data.Mode.ValueString())
+}
+
+// fileWrite returns the step writing the configured content or uploading the binary or write-only
+// content or the source of data, nil when the file is only read. The source is only uploaded
+// again when it changed since the upload recorded in prior or when the file went missing.
+// content_wo is cleared from data, so it never reaches the state.
+func fileWrite(ctx context.Context, data *RemoteFileResourceModel, prior *RemoteFileResourceModel, r *RemoteFileResource) func() error {
+	name, uploaded, checksumOnly := data.checksumOnlyContent()
+	data.ContentWO = types.StringNull()
+
+	if content, managed := data.managedContent(); managed {
+		return func() error { return writeContent(ctx, data, r, content) }
+	}
+
+	if checksumOnly {
+		return func() error {
+			_, err := uploadFile(ctx, data, r, name, bytes.NewReader(uploaded), "")
+			return err
+		}
+	}
+
+	if data.Source.IsNull() {
+		return nil
+	}
+
+	if prior != nil && prior.Checksum.ValueString() != "" && prior.SourceChecksum.Equal(data.SourceChecksum) {
+		return nil
+	}
+
+	return func() error { return uploadSource(ctx, data, r) }
+}
+
+// writeContent writes the managed content of data to the file, uploaded over SFTP when the host
+// supports it, inlined in the command otherwise.
+func writeContent(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource, content string) error {
+	server := data.HostConnection.server()
+	err := r.sshService.OpenConnection(ctx, server)
+	if err != nil {
+		return err
+	}
+
+	if r.sshService.SFTPAvailable(ctx, server) {
+		_, err = uploadFile(ctx, data, r, "content", strings.NewReader(content), "")
+		return err
+	}
+
+	_, err = fileCommand(ctx, data, r, services.RewriteFileCommand(data.Path.ValueString(), []byte(content), data.createMode(r)))
+
+	return err
+}
+
+// uploadFile copies content to the workspace of the connection user over SFTP, or over the
+// standard input of a command on hosts without it, so its bytes reach the host unchanged. It
+// then installs it at the path of the file. The content named name is not installed when its
+// sha256 digest differs from expected, unless expected is empty. It returns the digest of the
+// uploaded content.
+func uploadFile(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource, name string, content io.Reader, expected string) (string, error) {
+	server := data.HostConnection.server()
+	err := r.sshService.OpenConnection(ctx, server)
+	if err != nil {
+		return "", err
+	}
+
+	workspace, err := r.sshService.Workspace(ctx, server)
+	if err != nil {
+		return "", err
+	}
+
+	id := make([]byte, 8)
+	_, err = rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+	tmpPath := workspace + "/file-" + hex.EncodeToString(id)
+
+	digest, err := services.NewHash("sha256")
+	if err != nil {
+		return "", err
+	}
+
+	reader := io.TeeReader(content, digest)
+	err = r.sshService.UploadSFTP(ctx, server, tmpPath, reader)
+	if errors.Is(err, services.ErrSFTPUnavailable) {
+		var result *servers.ServerCommand
+		result, err = r.sshService.Upload(ctx, server, services.UploadCommand(tmpPath, false), reader)
+		if err != nil && result != nil && strings.TrimSpace(result.Stderr) != "" {
+			err = fmt.Errorf("%w: %s", err, strings.TrimSpace(result.Stderr))
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	checksum := hex.EncodeToString(digest.Sum(nil))
+	if expected != "" && checksum != expected {
+		return "", fmt.Errorf("%s changed since the plan", name)
+	}
+
+	_, err = fileCommand(ctx, data, r, services.InstallFileCommand(tmpPath, data.Path.ValueString(), data.createMode(r), data.Privileged.ValueBool()))
+
+	return checksum, err
+}
+
+// uploadSource uploads the local source of data, checking it did not change since the plan.
+func uploadSource(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource) error {
+	file, err := os.Open(data.Source.ValueString())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	checksum, err := uploadFile(ctx, data, r, data.Source.ValueString(), file, data.SourceChecksum.ValueString())
+	if err != nil {
+		return err
+	}
+	data.SourceChecksum = types.StringValue(checksum)
+
+	return nil
+}
+
+// fileMissingLine is printed instead of the file information when a generated file is missing.
+const fileMissingLine = "remote-host-file-missing"
+
+// errFileMissing is returned by getFile when a generated file does not exist.
+var errFileMissing = errors.New("file does not exist")
+
+// generated reports whether the file is generated on the host by content_command.
+func (data *RemoteFileResourceModel) generated() bool {
+	return !data.ContentCommand.IsNull() && !data.ContentCommand.IsUnknown()
+}
+
+// expired reports whether a file modified at mtime is older than maxAge.
+func expired(maxAge Duration, mtime types.String) bool {
+	if maxAge.IsNull() || maxAge.IsUnknown() || mtime.IsNull() || mtime.IsUnknown() {
+		return false
+	}
+
+	age, err := time.ParseDuration(maxAge.ValueString())
+	if err != nil {
+		return false
+	}
+
+	modified, err := time.Parse(time.RFC3339, mtime.ValueString())
+	if err != nil {
+		return false
+	}
+
+	return time.Since(modified) > age
+}
+
+// needsGeneration reports whether the content command of plan must run given the prior state:
+// the file is missing, expired or the command changed.
+func needsGeneration(plan *RemoteFileResourceModel, state *RemoteFileResourceModel) bool {
+	if !plan.generated() {
+		return false
+	}
+
+	return state.Checksum.ValueString() == "" ||
+		expired(plan.MaxAge, state.Mtime) ||
+		!plan.ContentCommand.Equal(state.ContentCommand)
+}
+
+// guarded reports whether data has an only_if or unless guard.
+func (data *RemoteFileResourceModel) guarded() bool {
+	return !data.OnlyIf.IsNull() || !data.Unless.IsNull()
+}
+
+// guardsAllow runs the only_if and unless guards of data on the host and reports whether the
+// file may be changed.
+func guardsAllow(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource) (bool, error) {
+	if !data.guarded() {
+		return true, nil
+	}
+
+	result, err := fileCommand(ctx, data, r, services.GuardCommand(data.OnlyIf.ValueString(), data.Unless.ValueString()))
+	if err != nil {
+		return false, err
+	}
+
+	allowed, err := services.ParseGuard(result.Stdout)
+	if err == nil && !allowed {
+		tflog.Info(ctx, "file left alone by its guards", map[string]any{"path": data.Path.ValueString()})
+	}
+
+	return allowed, err
+}
+
+// readGuarded fills the computed attributes of a file its guards did not let change, keeping
+// the configured attributes as planned. A missing generated file gets empty values, the empty
+// checksum plans its generation again.
+func readGuarded(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource) error {
+	err := getFile(data, r, ctx)
+	if errors.Is(err, errFileMissing) {
+		data.Id = types.StringValue(fileID(data.Identity.ValueString(), data.HostConnection.hostID(), data.Path.ValueString(), "", ""))
+		data.Checksum = types.StringValue("")
+		data.IsSymlink = types.BoolValue(false)
+		data.Mtime = types.StringValue("")
+		data.Content = types.StringValue("")
+		data.SensitiveContent = data.Content
+		if data.Acl.IsUnknown() {
+			data.Acl = types.SetNull(types.StringType)
+		}
+		if data.Mode.IsUnknown() {
+			data.Mode = NewFileModeValue("")
+		}
+		for _, value := range []*types.String{&data.Owner, &data.Group} {
+			if value.IsUnknown() {
+				*value = types.StringValue("")
+			}
+		}
+
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if data.Mode.IsUnknown() || data.Owner.IsUnknown() || data.Group.IsUnknown() {
+		err = readOwnership(ctx, data, r)
+		if err != nil {
+			return err
+		}
+	}
+
+	if data.Acl.IsUnknown() {
+		return readACL(ctx, data, r)
+	}
+
+	return nil
+}
+
+// generate runs the content command of data, only when the file does not exist unless force is set.
+func generate(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource, force bool) error {
+	command := data.ContentCommand.ValueString()
+	if !force {
+		quotedPath := services.ShellQuote(data.Path.ValueString())
+		command = fmt.Sprintf("if [ ! -e %s ]; then\n%s\nfi", quotedPath, command)
+	}
+
+	_, err := fileCommand(ctx, data, r, command)
 
-// ExitCodeError represents an SSH command exit code error
-type ExitCodeError struct {
-	code   int8
-	stderr string
+	return err
 }
 
-func (e *ExitCodeError) Error() string {
-	return fmt.Sprintf("exit code %d: %s", e.code, e.stderr)
+// remoteFileStat is the metadata and content of a file read by getFile.
+type remoteFileStat struct {
+	inode     string
+	mtime     int64
+	isSymlink bool
+	// checksum is "-" when the host could not compute it.
+	checksum string
+	content  string
 }
 
-// RemoteFileResourceModel describes the resource data model.
-type RemoteFileResourceModel struct {
-	Id               types.String         `tfsdk:"id"`
-	HostConnection   *HostConnectionModel `tfsdk:"host_connection"`
-	Path             types.String         `tfsdk:"path"`
-	Content          types.String         `tfsdk:"content"`
-	Privileged       types.Bool           `tfsdk:"privileged"`
-	Sensitive        types.Bool           `tfsdk:"sensitive"`
-	SensitiveContent types.String         `tfsdk:"sensitive_content"`
-}
+func getFile(data *RemoteFileResourceModel, r *RemoteFileResource, ctx context.Context) error {
+	server := data.HostConnection.server()
+	err := r.sshService.OpenConnection(ctx, server)
+	if err != nil {
+		return err
+	}
 
-func (r *RemoteFileResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
-	resp.TypeName = "remote_file"
-}
+	// Followed files are read over SFTP when the host supports it, so their content comes back
+	// unchanged whatever bytes or lines it holds, and is hashed locally.
+	var sftpContent []byte
+	readOverSFTP := false
+	if data.FollowSymlinks.ValueBool() && !data.generated() && !data.checksumOnly() {
+		sftpContent, err = r.sshService.ReadFileSFTP(ctx, server, data.Path.ValueString(), data.Privileged.ValueBool())
+		readOverSFTP = err == nil
+		if err != nil && !errors.Is(err, services.ErrSFTPUnavailable) {
+			tflog.Debug(ctx, "unable to read the file over SFTP, reading it with cat", map[string]any{"path": data.Path.ValueString(), "error": err.Error()})
+		}
+	}
 
-func (r *RemoteFileResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
-	resp.Schema = schema.Schema{
-		// This description is used by the documentation generator and the language server.
-		MarkdownDescription: "An existent file at a remote host",
+	// Without content to read with a command, the metadata is read with the agent when the
+	// host runs it, batched with the other files of the host.
+	var stat remoteFileStat
+	readWithAgent := false
+	if data.FollowSymlinks.ValueBool() && (readOverSFTP || data.generated() || data.checksumOnly()) {
+		stat, err = statFileWithAgent(ctx, data, r, server, readOverSFTP)
+		readWithAgent = err == nil
+		if err != nil && !errors.Is(err, services.ErrAgentUnavailable) {
+			return err
+		}
+	}
+	if !readWithAgent {
+		stat, err = statFileWithCommand(ctx, data, r, server, readOverSFTP)
+		if err != nil {
+			return err
+		}
+	}
 
-		Attributes: map[string]schema.Attribute{
-			"host_connection": schema.SingleNestedAttribute{
-				Required: true,
-				Attributes: map[string]schema.Attribute{
-					"host": schema.StringAttribute{
-						Required:            true,
-						MarkdownDescription: "Hostname or IP address of the remote host",
-					},
-					"user": schema.StringAttribute{
-						Required:            true,
-						MarkdownDescription: "User nae to access host",
-					},
-					"password": schema.StringAttribute{
-						Optional:            true,
-						MarkdownDescription: "Password to access host",
-					},
-					"private_key": schema.StringAttribute{
-						Optional:            true,
-						MarkdownDescription: "Private key path to access host",
-					},
-				},
-			},
-			"path": schema.StringAttribute{
-				Required:            true,
-				MarkdownDescription: "Path to the file on the remote host",
-			},
-			"privileged": schema.BoolAttribute{
-				Optional:            true,
-				Computed:            true,
-				MarkdownDescription: "Whether to run the command as root",
-				Default:             booldefault.StaticBool(false),
-			},
-			"sensitive": schema.BoolAttribute{
-				Optional:            true,
-				Computed:            true,
-				MarkdownDescription: "Whether to mark the content attribute as sensitive",
-				Default:             booldefault.StaticBool(false),
-			},
-			"id": schema.StringAttribute{
-				Computed:            true,
-				MarkdownDescription: "Example identifier",
-				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.UseStateForUnknown(),
-				},
-			},
-			"content": schema.StringAttribute{
-				Computed:            true,
-				MarkdownDescription: "File content",
-			},
-			"sensitive_content": schema.StringAttribute{
-				Computed:            true,
-				MarkdownDescription: "File content marked as sensitive",
-				Sensitive:           true,
-			},
-		},
+	checksum, content := stat.checksum, stat.content
+	if readOverSFTP {
+		content = string(sftpContent)
 	}
-}
 
-func (r *RemoteFileResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
-		return
+	// Hosts without the matching tool print "-", hash the content read back instead.
+	if checksum == "-" && data.generated() {
+		return fmt.Errorf("unable to compute the %s checksum of the generated file %s", data.ChecksumAlgorithm.ValueString(), data.Path.ValueString())
+	}
+	if checksum == "-" && data.checksumOnly() {
+		return fmt.Errorf("unable to compute the %s checksum of %s, whose content is not read back", data.ChecksumAlgorithm.ValueString(), data.Path.ValueString())
+	}
+	if checksum == "-" {
+		checksum, err = services.Checksum(data.ChecksumAlgorithm.ValueString(), []byte(content))
+		if err != nil {
+			return err
+		}
 	}
 
-	sshService, ok := req.ProviderData.(*services.SSHService)
+	previous := data.Content.ValueString()
 
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
-		)
+	data.Id = types.StringValue(fileID(data.Identity.ValueString(), data.HostConnection.hostID(), data.Path.ValueString(), checksum, stat.inode))
+	data.Checksum = types.StringValue(checksum)
+	data.IsSymlink = types.BoolValue(stat.isSymlink)
+	if data.generated() || data.checksumOnly() {
+		content = ""
+	}
+	content, err = stateContent(data, r, previous, content)
+	if err != nil {
+		return err
+	}
+	data.Mtime = types.StringValue(time.Unix(stat.mtime, 0).UTC().Format(time.RFC3339))
+	data.Content = types.StringValue(content)
+	data.SensitiveContent = data.Content
 
-		return
+	return nil
+}
+
+// statFileWithAgent reads the metadata of the followed file of data with the agent, and its
+// checksum unless its content was read over SFTP. services.ErrAgentUnavailable is returned when
+// the host does not run the agent.
+func statFileWithAgent(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource, server *servers.Server, readOverSFTP bool) (remoteFileStat, error) {
+	algorithm := data.ChecksumAlgorithm.ValueString()
+	if readOverSFTP {
+		algorithm = ""
 	}
 
-	r.sshService = sshService
+	info, err := r.sshService.StatFile(ctx, server, data.Path.ValueString(), true, algorithm, data.Privileged.ValueBool())
+	if err != nil {
+		return remoteFileStat{}, err
+	}
+	if !info.Exists && data.generated() {
+		return remoteFileStat{}, errFileMissing
+	}
+	if !info.Exists {
+		return remoteFileStat{}, fmt.Errorf("%s does not exist", data.Path.ValueString())
+	}
+
+	checksum := info.Checksum
+	if checksum == "" {
+		checksum = "-"
+	}
+
+	return remoteFileStat{inode: info.Inode, mtime: info.ModTime, isSymlink: info.IsSymlink, checksum: checksum}, nil
 }
 
-func getFile(data *RemoteFileResourceModel, r *RemoteFileResource, ctx context.Context) error {
-	server := &servers.Server{
-		Address:        data.HostConnection.Host.ValueString(),
-		PrivateKeyPath: data.HostConnection.PrivateKey.ValueString(),
-		User:           data.HostConnection.User.ValueString(),
-		Port:           22,
-		Name:           data.HostConnection.Host.ValueString(),
+// statFileWithCommand reads the metadata and content of the file of data with a shell command,
+// batched with the ones of the other files of the host. The content is not read again when it
+// was read over SFTP.
+func statFileWithCommand(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource, server *servers.Server, readOverSFTP bool) (remoteFileStat, error) {
+	platform, err := r.sshService.DetectPlatform(ctx, server)
+	if err != nil {
+		return remoteFileStat{}, err
 	}
-	err := r.sshService.OpenConnection(server)
+
+	quotedPath := services.ShellQuote(data.Path.ValueString())
+	follow := data.FollowSymlinks.ValueBool()
+
+	checksumCmd, err := services.RemoteChecksumCommand(data.ChecksumAlgorithm.ValueString(), data.Path.ValueString())
 	if err != nil {
-		return err
+		return remoteFileStat{}, err
+	}
+
+	// Get the file inode, its modification time, whether the path is a symlink, the digest
+	// computed on the host when its tooling supports the algorithm and the content. A symlink
+	// that is not followed has the link target as content. The content of generated and binary
+	// files is not read, only their checksum is kept.
+	contentCmd := fmt.Sprintf("cat -- %s", quotedPath)
+	if !follow {
+		checksumCmd = fmt.Sprintf("if [ -L %s ]; then echo -; else %s; fi", quotedPath, checksumCmd)
+		contentCmd = fmt.Sprintf("if [ -L %s ]; then printf '%%s' \"$(readlink -- %s)\"; else %s; fi", quotedPath, quotedPath, contentCmd)
+	}
+	if data.generated() || data.checksumOnly() {
+		contentCmd = "true"
+	}
+	if readOverSFTP {
+		checksumCmd = "echo -"
+		contentCmd = "true"
 	}
 
-	// Get file inode and content using stat and cat commands
-	sudoText := ""
+	combinedCmd := fmt.Sprintf(
+		"%s; %s; if [ -L %s ]; then echo symlink; else echo file; fi; %s; %s",
+		platform.InodeCommand(quotedPath, follow), platform.MtimeCommand(quotedPath, follow), quotedPath, checksumCmd, contentCmd,
+	)
+	if data.generated() {
+		// Generated files may legitimately be missing, they are then generated again.
+		combinedCmd = fmt.Sprintf("if [ ! -e %s ] && [ ! -L %s ]; then echo %s; exit 0; fi; %s", quotedPath, quotedPath, fileMissingLine, combinedCmd)
+	}
 	if data.Privileged.ValueBool() {
-		sudoText = "sudo "
+		combinedCmd = privilegedCommand(server, combinedCmd)
 	}
 
-	combinedCmd := fmt.Sprintf("%sstat -c '%%i' %s; %scat %s", sudoText, data.Path.ValueString(), sudoText, data.Path.ValueString())
 	var command *servers.ServerCommand
-	command, err = r.sshService.ExecuteCommand(combinedCmd, server)
+	// Files of the same host are refreshed concurrently, their commands are coalesced into one.
+	// It prints the content of the files, so it is never recorded.
+	command, err = r.sshService.ExecuteBatched(services.WithFileContent(ctx), combinedCmd, server)
 	if err != nil {
-		return err
+		return remoteFileStat{}, err
 	}
 
 	if command.ExitCode != 0 {
-		return &ExitCodeError{code: command.ExitCode, stderr: command.Stderr}
+		return remoteFileStat{}, &ExitCodeError{code: command.ExitCode, stderr: command.Stderr}
 	}
 
 	outputs := strings.Split(command.Stdout, "\n")
 
-	tflog.Warn(ctx, fmt.Sprintf("outputs: %+v", command.Stdout))
-	inode := strings.TrimSpace(outputs[1])
-	content := strings.Join(outputs[2:], "\n")
+	// Without output framing banners or echoed input may precede the stat output.
+	inodeLine := slices.IndexFunc(outputs, isInode)
+	if inodeLine < 0 && data.generated() && slices.ContainsFunc(outputs, func(line string) bool {
+		return strings.TrimSpace(line) == fileMissingLine
+	}) {
+		return remoteFileStat{}, errFileMissing
+	}
+	if inodeLine < 0 || inodeLine+3 >= len(outputs) {
+		return remoteFileStat{}, fmt.Errorf("unable to find the inode of %s in the command output", data.Path.ValueString())
+	}
+
+	mtime, err := strconv.ParseInt(strings.TrimSpace(outputs[inodeLine+1]), 10, 64)
+	if err != nil {
+		return remoteFileStat{}, fmt.Errorf("unable to parse the modification time of %s: %w", data.Path.ValueString(), err)
+	}
+
+	return remoteFileStat{
+		inode:     strings.TrimSpace(outputs[inodeLine]),
+		mtime:     mtime,
+		isSymlink: strings.TrimSpace(outputs[inodeLine+2]) == "symlink",
+		checksum:  strings.TrimSpace(outputs[inodeLine+3]),
+		content:   strings.Join(outputs[inodeLine+4:], "\n"),
+	}, nil
+}
+
+// stateContent returns the value stored in the state for content according to state_content.
+// Encrypted content keeps the previous value when it decrypts to the same content, so a
+// refresh of an unchanged file leaves the state as is.
+func stateContent(data *RemoteFileResourceModel, r *RemoteFileResource, previous string, content string) (string, error) {
+	switch data.StateContent.ValueString() {
+	case "checksum":
+		return "", nil
+	case "encrypted":
+		if r.sshService.StateCipher == nil {
+			return "", errors.New("state_content = \"encrypted\" requires the state_encryption_key of the provider")
+		}
+		if content == "" {
+			return "", nil
+		}
+
+		return r.sshService.StateCipher.SealStable(previous, content)
+	default:
+		return content, nil
+	}
+}
+
+// applyACL replaces the named ACL entries of the file with the configured ones.
+func applyACL(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource) error {
+	if data.Acl.IsNull() || data.Acl.IsUnknown() {
+		return nil
+	}
+
+	var entries []string
+	diags := data.Acl.ElementsAs(ctx, &entries, false)
+	if diags.HasError() {
+		return fmt.Errorf("unable to read the configured ACL of %s", data.Path.ValueString())
+	}
+
+	normalized, err := services.NormalizeACL(entries)
+	if err != nil {
+		return err
+	}
+
+	_, err = fileCommand(ctx, data, r, services.SetACLCommand(data.Path.ValueString(), normalized))
+
+	return err
+}
+
+// readACL stores the named ACL entries of the file in data. The configured spelling of the
+// entries is kept when it normalizes to what the host reports so only real drift shows in plans.
+func readACL(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource) error {
+	managed := !data.Acl.IsNull() && !data.Acl.IsUnknown()
+
+	result, err := fileCommand(ctx, data, r, services.GetACLCommand(data.Path.ValueString()))
+	if err != nil {
+		// Hosts without the acl tools are fine as long as no entries are managed.
+		if !managed {
+			data.Acl = types.SetNull(types.StringType)
+			return nil
+		}
+
+		return err
+	}
+
+	remote := services.ParseGetfacl(result.Stdout)
+
+	if managed {
+		var entries []string
+		diags := data.Acl.ElementsAs(ctx, &entries, false)
+		if diags.HasError() {
+			return fmt.Errorf("unable to read the configured ACL of %s", data.Path.ValueString())
+		}
+
+		normalized, err := services.NormalizeACL(entries)
+		if err == nil && slices.Equal(normalized, remote) {
+			return nil
+		}
+	}
+
+	values := make([]attr.Value, 0, len(remote))
+	for _, entry := range remote {
+		values = append(values, types.StringValue(entry))
+	}
+
+	acl, diags := types.SetValue(types.StringType, values)
+	if diags.HasError() {
+		return fmt.Errorf("unable to store the ACL of %s", data.Path.ValueString())
+	}
+
+	data.Acl = acl
+
+	return nil
+}
+
+// checkSecurePermissions fails when enforce_secure_permissions is set and the sensitive file
+// can be read by anyone besides its owner. data must hold the ACL read back from the host.
+func checkSecurePermissions(ctx context.Context, data *RemoteFileResourceModel, r *RemoteFileResource) error {
+	if !data.EnforceSecurePermissions.ValueBool() || !data.Sensitive.ValueBool() {
+		return nil
+	}
+
+	result, err := fileCommand(ctx, data, r, services.PermissionsCommand(data.Path.ValueString()))
+	if err != nil {
+		return err
+	}
 
-	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.HostConnection.Host.ValueString(), inode))
-	data.Content = types.StringValue("")
-	data.SensitiveContent = types.StringValue("")
+	var acl []string
+	if !data.Acl.IsNull() && !data.Acl.IsUnknown() {
+		diags := data.Acl.ElementsAs(ctx, &acl, false)
+		if diags.HasError() {
+			return fmt.Errorf("unable to read the ACL of %s", data.Path.ValueString())
+		}
+	}
 
-	if data.Sensitive.ValueBool() {
-		data.SensitiveContent = types.StringValue(content)
-	} else {
-		data.Content = types.StringValue(content)
+	readers, err := services.ReadableBeyondOwner(result.Stdout, acl)
+	if err != nil {
+		return err
+	}
+	if len(readers) > 0 {
+		return fmt.Errorf("%s can be read by %s, restrict it to its owner, e.g. with mode 0600, or disable enforce_secure_permissions", data.Path.ValueString(), strings.Join(readers, ", "))
 	}
 
 	return nil
 }
 
+func isInode(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return false
+	}
+
+	_, err := strconv.ParseUint(line, 10, 64)
+
+	return err == nil
+}
+
 func (r *RemoteFileResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var data RemoteFileResourceModel
 
@@ -209,6 +1287,11 @@ func (r *RemoteFileResource) Create(ctx context.Context, req resource.CreateRequ
 		return
 	}
 
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	content, managed := data.managedContent()
+
 	// If applicable, this is a great opportunity to initialize any necessary
 	// provider client data and make a call using it.
 	// httpResp, err := r.client.Do(httpReq)
@@ -217,12 +1300,76 @@ func (r *RemoteFileResource) Create(ctx context.Context, req resource.CreateRequ
 	//     return
 	// }
 
-	err := getFile(&data, r, ctx)
+	err := preflightFile(ctx, &data, r)
+	if err != nil {
+		resp.Diagnostics.AddError("Preflight Error", fmt.Sprintf("Unable to manage %s, got error: %s", data.Path.ValueString(), err))
+		return
+	}
+
+	allowed, err := guardsAllow(ctx, &data, r)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to run the only_if and unless guards, got error: %s", err))
+		return
+	}
+	if !allowed {
+		err = readGuarded(ctx, &data, r)
+		if err != nil {
+			resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to execute commands, got error: %s", err))
+			return
+		}
+		if managed {
+			keepManagedContent(&data, content)
+		}
+
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	if data.generated() {
+		err = generate(ctx, &data, r, false)
+		if err != nil {
+			resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to generate the file, got error: %s", err))
+			return
+		}
+	}
+
+	// content_wo is only in the configuration.
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("content_wo"), &data.ContentWO)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err = applyAttributes(ctx, &data, r, fileWrite(ctx, &data, nil, r), data.ownershipChanges(nil), nil)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to write the file or set its attributes, got error: %s", err))
+		return
+	}
+
+	err = getFile(&data, r, ctx)
 	if err != nil {
 		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to execute commands, got error: %s", err))
 		return
 	}
 
+	err = readACL(ctx, &data, r)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the file ACL, got error: %s", err))
+		return
+	}
+
+	err = readAttributes(ctx, &data, r)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the file attributes, got error: %s", err))
+		return
+	}
+
+	err = checkSecurePermissions(ctx, &data, r)
+	if err != nil {
+		resp.Diagnostics.AddError("Insecure Permissions", fmt.Sprintf("Unable to accept the permissions of the sensitive file, got error: %s", err))
+		return
+	}
+
 	var exitErr *ExitCodeError
 	if errors.As(err, &exitErr) {
 		resp.Diagnostics.AddError("Command Error", fmt.Sprintf("Unable to get file info: %s", exitErr.Error()))
@@ -233,6 +1380,8 @@ func (r *RemoteFileResource) Create(ctx context.Context, req resource.CreateRequ
 	// Documentation: https://terraform.io/plugin/log
 	tflog.Trace(ctx, "created a resource")
 
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, fileManagedKey, managedFlag(managed || data.uploaded()))...)
+
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -247,6 +1396,9 @@ func (r *RemoteFileResource) Read(ctx context.Context, req resource.ReadRequest,
 		return
 	}
 
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
 	// If applicable, this is a great opportunity to initialize any necessary
 	// provider client data and make a call using it.
 	// httpResp, err := r.client.Do(httpReq)
@@ -255,12 +1407,70 @@ func (r *RemoteFileResource) Read(ctx context.Context, req resource.ReadRequest,
 	//     return
 	// }
 
-	err := getFile(&data, r, ctx)
+	err := preflightFile(ctx, &data, r)
+	if err != nil {
+		resp.Diagnostics.AddError("Preflight Error", fmt.Sprintf("Unable to manage %s, got error: %s", data.Path.ValueString(), err))
+		return
+	}
+
+	// A file the guards do not let change keeps its state, so it shows no drift.
+	allowed, err := guardsAllow(ctx, &data, r)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to run the only_if and unless guards, got error: %s", err))
+		return
+	}
+	if !allowed {
+		return
+	}
+
+	managed, diags := req.Private.GetKey(ctx, fileManagedKey)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// A managed file that was removed from the host is written again.
+	if string(managed) == "true" {
+		quotedPath := services.ShellQuote(data.Path.ValueString())
+		result, err := fileCommand(ctx, &data, r, fmt.Sprintf("if [ ! -e %s ] && [ ! -L %s ]; then echo %s; fi", quotedPath, quotedPath, fileMissingLine))
+		if err != nil {
+			resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to execute commands, got error: %s", err))
+			return
+		}
+
+		if strings.Contains(result.Stdout, fileMissingLine) {
+			data.Checksum = types.StringValue("")
+			keepManagedContent(&data, "")
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+			return
+		}
+	}
+
+	err = getFile(&data, r, ctx)
+	if errors.Is(err, errFileMissing) {
+		// The empty checksum plans the generation of the file again.
+		data.Checksum = types.StringValue("")
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
 	if err != nil {
 		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to execute commands, got error: %s", err))
 		return
 	}
 
+	err = readACL(ctx, &data, r)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the file ACL, got error: %s", err))
+		return
+	}
+
+	err = readAttributes(ctx, &data, r)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the file attributes, got error: %s", err))
+		return
+	}
+
 	var exitErr *ExitCodeError
 	if errors.As(err, &exitErr) {
 		resp.Diagnostics.AddError("Command Error", fmt.Sprintf("Unable to get file info: %s", exitErr.Error()))
@@ -281,6 +1491,11 @@ func (r *RemoteFileResource) Update(ctx context.Context, req resource.UpdateRequ
 		return
 	}
 
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	content, managed := data.managedContent()
+
 	// If applicable, this is a great opportunity to initialize any necessary
 	// provider client data and make a call using it.
 	// httpResp, err := r.client.Do(httpReq)
@@ -289,6 +1504,85 @@ func (r *RemoteFileResource) Update(ctx context.Context, req resource.UpdateRequ
 	//     return
 	// }
 
+	err := preflightFile(ctx, &data, r)
+	if err != nil {
+		resp.Diagnostics.AddError("Preflight Error", fmt.Sprintf("Unable to manage %s, got error: %s", data.Path.ValueString(), err))
+		return
+	}
+
+	var state RemoteFileResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	allowed, err := guardsAllow(ctx, &data, r)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to run the only_if and unless guards, got error: %s", err))
+		return
+	}
+	if !allowed {
+		err = readGuarded(ctx, &data, r)
+		if err != nil {
+			resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to execute commands, got error: %s", err))
+			return
+		}
+		if managed {
+			keepManagedContent(&data, content)
+		}
+
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	if data.generated() {
+		err = generate(ctx, &data, r, needsGeneration(&data, &state))
+		if err != nil {
+			resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to generate the file, got error: %s", err))
+			return
+		}
+	}
+
+	// content_wo is only in the configuration.
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("content_wo"), &data.ContentWO)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err = applyAttributes(ctx, &data, r, fileWrite(ctx, &data, &state, r), data.ownershipChanges(&state), removedXattrs(state.Xattrs, data.Xattrs))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to write the file or set its attributes, got error: %s", err))
+		return
+	}
+
+	err = getFile(&data, r, ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to execute commands, got error: %s", err))
+		return
+	}
+
+	err = readACL(ctx, &data, r)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the file ACL, got error: %s", err))
+		return
+	}
+
+	err = readAttributes(ctx, &data, r)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the file attributes, got error: %s", err))
+		return
+	}
+
+	err = checkSecurePermissions(ctx, &data, r)
+	if err != nil {
+		resp.Diagnostics.AddError("Insecure Permissions", fmt.Sprintf("Unable to accept the permissions of the sensitive file, got error: %s", err))
+		return
+	}
+
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, fileManagedKey, managedFlag(managed || data.uploaded()))...)
+
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -0,0 +1,669 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"remote-provider/internal/provider/servers"
+	"remote-provider/internal/provider/services"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteFileSetResource{}
+var _ resource.ResourceWithValidateConfig = &RemoteFileSetResource{}
+
+func NewRemoteFileSetResource() resource.Resource {
+	return &RemoteFileSetResource{}
+}
+
+// RemoteFileSetResource writes many files on one or many hosts with a single command per host
+// and operation.
+type RemoteFileSetResource struct {
+	sshService *services.SSHService
+}
+
+// RemoteFileSetResourceModel describes the resource data model.
+type RemoteFileSetResourceModel struct {
+	Id              types.String           `tfsdk:"id"`
+	HostConnection  *HostConnectionModel   `tfsdk:"host_connection"`
+	HostConnections []*HostConnectionModel `tfsdk:"host_connections"`
+	Files           types.Map              `tfsdk:"files"`
+	Mode            FileMode               `tfsdk:"mode"`
+	Privileged      types.Bool             `tfsdk:"privileged"`
+	TrailingNewline types.Bool             `tfsdk:"ensure_trailing_newline"`
+	Whitespace      types.Bool             `tfsdk:"normalize_whitespace"`
+	Checksums       types.Map              `tfsdk:"checksums"`
+	HostChecksums   types.Map              `tfsdk:"host_checksums"`
+	HostStatus      types.Map              `tfsdk:"host_status"`
+	Timeouts        *TimeoutsModel         `tfsdk:"timeouts"`
+}
+
+func (r *RemoteFileSetResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_file_set"
+}
+
+func (r *RemoteFileSetResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.Required = false
+	hostConnection.Optional = true
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	hostConnections := hostConnectionsSchema()
+	hostConnections.MarkdownDescription = "Connections to every host the same files are replicated on, e.g. the nodes of a " +
+		"cluster sharing certificates. Files are removed from the hosts dropped from the list"
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Files written on a host from a map of path to content. Every file is written, checked and " +
+			"removed with a single command, which is much faster than one `remote_host_file` per file for configurations " +
+			"templating many small files. The files are written on a single host (`host_connection`) or replicated on " +
+			"every host of a group (`host_connections`)",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":         timeoutsSchema(),
+			"host_connection":  hostConnection,
+			"host_connections": hostConnections,
+			"files": schema.MapAttribute{
+				Required:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Content of the files keyed by their absolute path. Removing a path from the map deletes the file",
+			},
+			"mode": schema.StringAttribute{
+				CustomType:          FileModeType{},
+				Optional:            true,
+				MarkdownDescription: "Octal mode of the files, e.g. `0640`. Defaults to the provider `default_file_mode`",
+			},
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to write the files as root",
+				Default:             booldefault.StaticBool(false),
+			},
+			"ensure_trailing_newline": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to append a newline to the files not ending with one before they are written and compared, so heredocs and templates trimming the last newline do not show as drift",
+			},
+			"normalize_whitespace": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to convert CRLF line endings to LF and strip the spaces and tabs ending lines before the files are written and compared, so changes made by editors do not trigger endless updates",
+			},
+			"checksums": schema.MapAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Hex encoded sha256 digest of the files keyed by their path",
+			},
+			"host_checksums": schema.MapAttribute{
+				Computed:            true,
+				ElementType:         types.MapType{ElemType: types.StringType},
+				MarkdownDescription: "Hex encoded sha256 digest of the files found on each host keyed by host, then by path. Missing files are left out",
+			},
+			"host_status": schema.MapAttribute{
+				Computed:    true,
+				ElementType: types.StringType,
+				MarkdownDescription: "Replication status keyed by host: `in_sync`, `drifted` when a file is missing or differs, " +
+					"`unverified` when the host has no sha256 tool or `unreachable`",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Comma separated hosts the files are written on",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *RemoteFileSetResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+// files returns the configured content of the files keyed by path.
+func (data *RemoteFileSetResourceModel) files(ctx context.Context) (map[string]string, error) {
+	files := map[string]string{}
+	if data.Files.IsNull() || data.Files.IsUnknown() {
+		return files, nil
+	}
+
+	diags := data.Files.ElementsAs(ctx, &files, false)
+	if diags.HasError() {
+		return nil, fmt.Errorf("unable to read the configured files")
+	}
+
+	return files, nil
+}
+
+// normalization returns the rewrites applied to the content of the files before they are
+// written and compared.
+func (data *RemoteFileSetResourceModel) normalization() services.ContentNormalization {
+	return services.ContentNormalization{
+		TrailingNewline: data.TrailingNewline.ValueBool(),
+		Whitespace:      data.Whitespace.ValueBool(),
+	}
+}
+
+// fileSetStatuses are the replication statuses of a host.
+const (
+	fileSetInSync      = "in_sync"
+	fileSetDrifted     = "drifted"
+	fileSetUnverified  = "unverified"
+	fileSetUnreachable = "unreachable"
+)
+
+func (r *RemoteFileSetResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var hostConnection types.Object
+	var hostConnections types.List
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("host_connection"), &hostConnection)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("host_connections"), &hostConnections)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if hostConnection.IsNull() == hostConnections.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("host_connections"),
+			"Invalid Host Configuration",
+			"Exactly one of host_connection or host_connections must be configured.",
+		)
+	}
+}
+
+// connections returns the connections to every host the files are written on.
+func (data *RemoteFileSetResourceModel) connections() []*HostConnectionModel {
+	if data.HostConnection != nil {
+		return []*HostConnectionModel{data.HostConnection}
+	}
+
+	return data.HostConnections
+}
+
+// group returns the hosts the files are written on.
+func (data *RemoteFileSetResourceModel) group() *servers.ServerGroup {
+	return serverGroup(data.Id.ValueString(), data.connections())
+}
+
+// hostIDs returns the comma separated identifiers of the hosts, used as ID of the set.
+func (data *RemoteFileSetResourceModel) hostIDs() string {
+	var ids []string
+	for _, connection := range data.connections() {
+		if !slices.Contains(ids, connection.hostID()) {
+			ids = append(ids, connection.hostID())
+		}
+	}
+
+	return strings.Join(ids, ",")
+}
+
+// command wraps script so it runs as root on server when the set is privileged.
+func (data *RemoteFileSetResourceModel) command(server *servers.Server, script string) string {
+	if data.Privileged.ValueBool() {
+		return privilegedCommand(server, script)
+	}
+
+	return script
+}
+
+// groupError joins the failures of results with the output of the failed commands.
+func groupError(results []services.GroupResult) error {
+	var errs []error
+	for _, result := range results {
+		if result.Err == nil {
+			continue
+		}
+
+		output := ""
+		if result.Command != nil {
+			output = strings.TrimSpace(result.Command.Stderr)
+			if output == "" {
+				output = strings.TrimSpace(result.Command.Stdout)
+			}
+		}
+
+		if output != "" {
+			errs = append(errs, fmt.Errorf("%s: %w: %s", result.Server.Name, result.Err, output))
+		} else {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Server.Name, result.Err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// store saves files and the checksums of their normalized content in data.
+func (data *RemoteFileSetResourceModel) store(files map[string]string) error {
+	contents := make(map[string]attr.Value, len(files))
+	checksums := make(map[string]attr.Value, len(files))
+	for filePath, content := range files {
+		checksum, err := services.Checksum("sha256", []byte(data.normalization().Normalize(content)))
+		if err != nil {
+			return err
+		}
+
+		contents[filePath] = types.StringValue(content)
+		checksums[filePath] = types.StringValue(checksum)
+	}
+
+	value, diags := types.MapValue(types.StringType, contents)
+	if diags.HasError() {
+		return fmt.Errorf("unable to store the files")
+	}
+	data.Files = value
+
+	value, diags = types.MapValue(types.StringType, checksums)
+	if diags.HasError() {
+		return fmt.Errorf("unable to store the file checksums")
+	}
+	data.Checksums = value
+
+	return nil
+}
+
+// storeHosts saves the checksums found on every host and their replication status in data.
+func (data *RemoteFileSetResourceModel) storeHosts(checksums map[string]map[string]string, status map[string]string) error {
+	hostChecksums := make(map[string]attr.Value, len(checksums))
+	for host, files := range checksums {
+		values := make(map[string]attr.Value, len(files))
+		for filePath, checksum := range files {
+			values[filePath] = types.StringValue(checksum)
+		}
+
+		value, diags := types.MapValue(types.StringType, values)
+		if diags.HasError() {
+			return fmt.Errorf("unable to store the file checksums of %s", host)
+		}
+		hostChecksums[host] = value
+	}
+
+	value, diags := types.MapValue(types.MapType{ElemType: types.StringType}, hostChecksums)
+	if diags.HasError() {
+		return fmt.Errorf("unable to store the file checksums of the hosts")
+	}
+	data.HostChecksums = value
+
+	statusValues := make(map[string]attr.Value, len(status))
+	for host, hostStatus := range status {
+		statusValues[host] = types.StringValue(hostStatus)
+	}
+
+	value, diags = types.MapValue(types.StringType, statusValues)
+	if diags.HasError() {
+		return fmt.Errorf("unable to store the status of the hosts")
+	}
+	data.HostStatus = value
+
+	return nil
+}
+
+// apply writes every file of data and removes the removed paths with a single command per host.
+func (r *RemoteFileSetResource) apply(ctx context.Context, data *RemoteFileSetResourceModel, removed []string) error {
+	files, err := data.files(ctx)
+	if err != nil {
+		return err
+	}
+
+	mode := r.sshService.FileMode(data.Mode.ValueString())
+	normalization := data.normalization()
+	script := []string{"set -e"}
+	size := 0
+	for _, filePath := range slices.Sorted(maps.Keys(files)) {
+		content := normalization.Normalize(files[filePath])
+		script = append(script, services.WriteFileCommand(filePath, []byte(content), mode))
+		size += len(content)
+	}
+	for _, filePath := range removed {
+		script = append(script, "rm -f -- "+services.ShellQuote(filePath))
+	}
+
+	group := data.group()
+	start := time.Now()
+	results := r.sshService.ExecuteGroupCommand(ctx, strings.Join(script, "\n"), data.Privileged.ValueBool(), group)
+	for _, result := range results {
+		r.sshService.Measure(ctx, "transfer", result.Server, start, size, result.Err)
+	}
+	if err := groupError(results); err != nil {
+		return err
+	}
+
+	data.Id = types.StringValue(data.hostIDs())
+
+	err = data.store(files)
+	if err != nil {
+		return err
+	}
+
+	checksums := map[string]map[string]string{}
+	status := map[string]string{}
+	for _, server := range group.Servers {
+		checksums[server.Name] = map[string]string{}
+		for filePath, content := range files {
+			checksums[server.Name][filePath], err = services.Checksum("sha256", []byte(normalization.Normalize(content)))
+			if err != nil {
+				return err
+			}
+		}
+		status[server.Name] = fileSetInSync
+	}
+
+	return data.storeHosts(checksums, status)
+}
+
+// remove deletes every file of data from the hosts of group.
+func (r *RemoteFileSetResource) remove(ctx context.Context, data *RemoteFileSetResourceModel, group *servers.ServerGroup) error {
+	files, err := data.files(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 || len(group.Servers) == 0 {
+		return nil
+	}
+
+	var quoted []string
+	for _, filePath := range slices.Sorted(maps.Keys(files)) {
+		quoted = append(quoted, services.ShellQuote(filePath))
+	}
+
+	return groupError(r.sshService.ExecuteGroupCommand(ctx, "rm -f -- "+strings.Join(quoted, " "), data.Privileged.ValueBool(), group))
+}
+
+// refresh compares the files on the hosts with the state using their checksums. On a single
+// host only the content of the files that changed is read back and missing files are dropped
+// from the state. With many hosts the content cannot be read back from one of them, so files
+// missing or differing on any reachable host are dropped from the state to be written again.
+func (r *RemoteFileSetResource) refresh(ctx context.Context, data *RemoteFileSetResourceModel) error {
+	files, err := data.files(ctx)
+	if err != nil {
+		return err
+	}
+
+	paths := slices.Sorted(maps.Keys(files))
+	expected := make(map[string]string, len(paths))
+	for _, filePath := range paths {
+		expected[filePath], err = services.Checksum("sha256", []byte(data.normalization().Normalize(files[filePath])))
+		if err != nil {
+			return err
+		}
+	}
+
+	group := data.group()
+	single := data.HostConnection != nil
+
+	var results []services.GroupResult
+	if len(paths) > 0 {
+		results = r.sshService.ExecuteGroupCommand(ctx, services.ChecksumFilesCommand(paths), data.Privileged.ValueBool(), group)
+	} else {
+		for _, server := range group.Servers {
+			results = append(results, services.GroupResult{Server: server})
+		}
+	}
+
+	checksums := map[string]map[string]string{}
+	status := map[string]string{}
+	drifted := map[string]bool{}
+	unverified := map[string]bool{}
+	missing := map[string]bool{}
+	for _, result := range results {
+		host := result.Server.Name
+		if result.Err != nil {
+			if single {
+				return groupError(results)
+			}
+
+			status[host] = fileSetUnreachable
+			continue
+		}
+
+		lines := make([]string, len(paths))
+		if len(paths) > 0 {
+			lines, err = services.ParseFileLines(result.Command.Stdout, len(paths))
+			if err != nil {
+				return fmt.Errorf("%s: %w", host, err)
+			}
+		}
+
+		checksums[host] = map[string]string{}
+		status[host] = fileSetInSync
+		for i, filePath := range paths {
+			switch lines[i] {
+			case "missing":
+				missing[filePath] = true
+				status[host] = fileSetDrifted
+			case "-":
+				// Hosts without sha256sum print "-", the single host has its files read back.
+				unverified[filePath] = true
+				if status[host] == fileSetInSync {
+					status[host] = fileSetUnverified
+				}
+			default:
+				checksums[host][filePath] = lines[i]
+				if lines[i] != expected[filePath] {
+					drifted[filePath] = true
+					status[host] = fileSetDrifted
+				}
+			}
+		}
+	}
+
+	for filePath := range missing {
+		delete(files, filePath)
+	}
+
+	if single {
+		maps.Copy(drifted, unverified)
+		err = r.readBack(ctx, data, group.Servers[0], files, drifted)
+		if err != nil {
+			return err
+		}
+	} else {
+		for filePath := range drifted {
+			delete(files, filePath)
+		}
+	}
+
+	err = data.store(files)
+	if err != nil {
+		return err
+	}
+
+	return data.storeHosts(checksums, status)
+}
+
+// readBack reads the content of the changed files of the single host of data into files.
+func (r *RemoteFileSetResource) readBack(ctx context.Context, data *RemoteFileSetResourceModel, server *servers.Server, files map[string]string, changed map[string]bool) error {
+	var paths []string
+	for _, filePath := range slices.Sorted(maps.Keys(changed)) {
+		if _, ok := files[filePath]; ok {
+			paths = append(paths, filePath)
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	result, err := runCommand(services.WithFileContent(ctx), r.sshService, server, data.command(server, services.ReadFilesCommand(paths)))
+	if err != nil {
+		return err
+	}
+
+	contents, err := services.ParseFileLines(result.Stdout, len(paths))
+	if err != nil {
+		return err
+	}
+
+	normalization := data.normalization()
+
+	for i, filePath := range paths {
+		content, err := base64.StdEncoding.DecodeString(contents[i])
+		if err != nil {
+			return fmt.Errorf("unable to decode the content of %s: %w", filePath, err)
+		}
+
+		// Content differing only by what the normalization rewrites keeps the configured value.
+		if normalization.Normalize(string(content)) == normalization.Normalize(files[filePath]) {
+			continue
+		}
+
+		files[filePath] = string(content)
+	}
+
+	return nil
+}
+
+func (r *RemoteFileSetResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteFileSetResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	err := r.apply(ctx, &data, nil)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to write the files, got error: %s", err))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteFileSetResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteFileSetResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	err := r.refresh(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the files, got error: %s", err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteFileSetResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state RemoteFileSetResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	planned, err := data.files(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to update the files, got error: %s", err))
+		return
+	}
+
+	previous, err := state.files(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to update the files, got error: %s", err))
+		return
+	}
+
+	var removed []string
+	for _, filePath := range slices.Sorted(maps.Keys(previous)) {
+		if _, ok := planned[filePath]; !ok {
+			removed = append(removed, filePath)
+		}
+	}
+
+	err = r.apply(ctx, &data, removed)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to update the files, got error: %s", err))
+		return
+	}
+
+	// Hosts dropped from the group no longer get the files.
+	kept := data.group()
+	dropped := state.group()
+	dropped.Servers = slices.DeleteFunc(dropped.Servers, func(server *servers.Server) bool {
+		return slices.ContainsFunc(kept.Servers, func(keptServer *servers.Server) bool { return keptServer.Name == server.Name })
+	})
+
+	err = r.remove(ctx, &state, dropped)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to remove the files from the dropped hosts, got error: %s", err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteFileSetResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteFileSetResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	err := r.remove(ctx, &data, data.group())
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to remove the files, got error: %s", err))
+		return
+	}
+}
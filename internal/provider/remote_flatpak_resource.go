@@ -0,0 +1,270 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteFlatpakResource{}
+
+func NewRemoteFlatpakResource() resource.Resource {
+	return &RemoteFlatpakResource{}
+}
+
+// RemoteFlatpakResource installs a flatpak application or runtime system-wide.
+type RemoteFlatpakResource struct {
+	sshService *services.SSHService
+}
+
+// RemoteFlatpakResourceModel describes the resource data model.
+type RemoteFlatpakResourceModel struct {
+	Id             types.String         `tfsdk:"id"`
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Name           types.String         `tfsdk:"name"`
+	Remote         types.String         `tfsdk:"remote"`
+	RemoteURL      types.String         `tfsdk:"remote_url"`
+	Branch         types.String         `tfsdk:"branch"`
+	Version        types.String         `tfsdk:"version"`
+	Timeouts       *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemoteFlatpakResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_flatpak"
+}
+
+func (r *RemoteFlatpakResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Installs a flatpak application or runtime for every user of a host with `flatpak install --system`. " +
+			"The remote is added first when `remote_url` is set. Destroying the resource uninstalls it.",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Identifier of the application or runtime, e.g. `org.mozilla.firefox`",
+				Validators: []validator.String{
+					stringMatches(services.FlatpakRefRegexp, "must be a reverse DNS identifier such as org.mozilla.firefox"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"remote": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Remote the application is installed from. Defaults to `flathub`",
+				Default:             stringdefault.StaticString("flathub"),
+				Validators: []validator.String{
+					stringMatches(services.FlatpakRemoteRegexp, "must be a remote name"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"remote_url": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "URL of a `.flatpakrepo` file adding `remote` when the host does not have it yet, e.g. `https://dl.flathub.org/repo/flathub.flatpakrepo`",
+			},
+			"branch": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Branch of the application, e.g. `stable` or `beta`. Defaults to the default branch of the remote",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier of the installation, made of the host and the application",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"version": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Version of the installed application, empty for the runtimes without one",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *RemoteFlatpakResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+// refresh reads the installation back from the host and reports whether it still exists.
+func (r *RemoteFlatpakResource) refresh(ctx context.Context, data *RemoteFlatpakResourceModel) (bool, error) {
+	result, err := runCommand(ctx, r.sshService, data.HostConnection.server(), services.FlatpakListCommand)
+	if err != nil {
+		return false, err
+	}
+
+	flatpak := services.ParseFlatpakList(result.Stdout, data.Name.ValueString(), data.Branch.ValueString())
+	if flatpak == nil {
+		return false, nil
+	}
+
+	data.Remote = types.StringValue(flatpak.Remote)
+	data.Branch = types.StringValue(flatpak.Branch)
+	data.Version = types.StringValue(flatpak.Version)
+
+	return true, nil
+}
+
+func (r *RemoteFlatpakResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteFlatpakResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	server := data.HostConnection.server()
+
+	err := r.sshService.Preflight(ctx, server, []string{services.PrivilegeTool, "flatpak"})
+	if err != nil {
+		resp.Diagnostics.AddError("Preflight Error", fmt.Sprintf("Unable to install %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+
+	// The branch stays unknown until the remote picked its default one.
+	branch := ""
+	if !data.Branch.IsUnknown() {
+		branch = data.Branch.ValueString()
+	}
+	data.Branch = types.StringValue(branch)
+
+	command := services.FlatpakInstallCommand(data.Name.ValueString(), branch, data.Remote.ValueString(), data.RemoteURL.ValueString())
+	_, err = runCommand(ctx, r.sshService, server, privilegedCommand(server, command))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to install %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-flatpak-%s", data.HostConnection.hostID(), data.Name.ValueString()))
+
+	exists, err := r.refresh(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+	if !exists {
+		resp.Diagnostics.AddError("Command Error", fmt.Sprintf("%s is not installed after its installation", data.Name.ValueString()))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteFlatpakResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteFlatpakResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	exists, err := r.refresh(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+
+	if !exists {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteFlatpakResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data RemoteFlatpakResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The installation is replaced when it changes, only remote_url and the timeouts change in
+	// place and remote_url is only used to add a missing remote.
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteFlatpakResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteFlatpakResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	server := data.HostConnection.server()
+
+	command := services.FlatpakRemoveCommand(data.Name.ValueString(), data.Branch.ValueString())
+	_, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, command))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to uninstall %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+}
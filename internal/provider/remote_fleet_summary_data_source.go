@@ -0,0 +1,151 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"slices"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteFleetSummaryDataSource{}
+
+func NewRemoteFleetSummaryDataSource() datasource.DataSource {
+	return &RemoteFleetSummaryDataSource{}
+}
+
+// RemoteFleetSummaryDataSource summarizes per host the resources the provider worked on during the run.
+type RemoteFleetSummaryDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemoteFleetSummaryDataSourceModel describes the data source data model.
+type RemoteFleetSummaryDataSourceModel struct {
+	Hosts        map[string]RemoteHostActivityModel `tfsdk:"hosts"`
+	ChangedHosts []types.String                     `tfsdk:"changed_hosts"`
+	Resources    types.Int64                        `tfsdk:"resources"`
+}
+
+// RemoteHostActivityModel describes the resource operations that reached a host.
+type RemoteHostActivityModel struct {
+	Resources types.Int64 `tfsdk:"resources"`
+	Reads     types.Int64 `tfsdk:"reads"`
+	Creates   types.Int64 `tfsdk:"creates"`
+	Updates   types.Int64 `tfsdk:"updates"`
+	Deletes   types.Int64 `tfsdk:"deletes"`
+	Changed   types.Bool  `tfsdk:"changed"`
+}
+
+func (d *RemoteFleetSummaryDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_fleet_summary"
+}
+
+func (d *RemoteFleetSummaryDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	count := func(description string) schema.Int64Attribute {
+		return schema.Int64Attribute{
+			Computed:            true,
+			MarkdownDescription: description,
+		}
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Summary per host of the resources the provider worked on so far during the run, to review " +
+			"applies spanning many hosts. Set `depends_on` to the resources of the hosts: Terraform then reads the summary " +
+			"during the apply, after their changes, when any of them changes. During a plan it counts the resources " +
+			"refreshed on every host. Resources are counted once they ran a command on a host",
+
+		Attributes: map[string]schema.Attribute{
+			"hosts": schema.MapNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "Activity keyed by host name",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"resources": count("Number of resources that worked on the host"),
+						"reads":     count("Number of resources refreshed from the host"),
+						"creates":   count("Number of resources created on the host"),
+						"updates":   count("Number of resources updated on the host"),
+						"deletes":   count("Number of resources deleted from the host"),
+						"changed": schema.BoolAttribute{
+							Computed:            true,
+							MarkdownDescription: "Whether a resource created, updated or deleted something on the host",
+						},
+					},
+				},
+			},
+			"changed_hosts": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Names of the changed hosts, sorted",
+			},
+			"resources": count("Number of resources that worked on the hosts, resources spanning several hosts are counted on each of them"),
+		},
+	}
+}
+
+func (d *RemoteFleetSummaryDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+func (d *RemoteFleetSummaryDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteFleetSummaryDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Hosts = map[string]RemoteHostActivityModel{}
+	data.ChangedHosts = []types.String{}
+
+	var resources int64
+	var changed []string
+	for host, activity := range d.sshService.Activity() {
+		data.Hosts[host] = RemoteHostActivityModel{
+			Resources: types.Int64Value(int64(activity.Resources)),
+			Reads:     types.Int64Value(int64(activity.Reads)),
+			Creates:   types.Int64Value(int64(activity.Creates)),
+			Updates:   types.Int64Value(int64(activity.Updates)),
+			Deletes:   types.Int64Value(int64(activity.Deletes)),
+			Changed:   types.BoolValue(activity.Changed()),
+		}
+
+		resources += int64(activity.Resources)
+		if activity.Changed() {
+			changed = append(changed, host)
+		}
+	}
+
+	slices.Sort(changed)
+	for _, host := range changed {
+		data.ChangedHosts = append(data.ChangedHosts, types.StringValue(host))
+	}
+	data.Resources = types.Int64Value(resources)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
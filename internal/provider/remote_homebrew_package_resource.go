@@ -0,0 +1,256 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteHomebrewPackageResource{}
+
+func NewRemoteHomebrewPackageResource() resource.Resource {
+	return &RemoteHomebrewPackageResource{}
+}
+
+// RemoteHomebrewPackageResource installs a Homebrew formula or cask on macOS or Linux.
+type RemoteHomebrewPackageResource struct {
+	sshService *services.SSHService
+}
+
+// RemoteHomebrewPackageResourceModel describes the resource data model.
+type RemoteHomebrewPackageResourceModel struct {
+	Id             types.String         `tfsdk:"id"`
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Name           types.String         `tfsdk:"name"`
+	Cask           types.Bool           `tfsdk:"cask"`
+	Triggers       types.Map            `tfsdk:"triggers"`
+	Version        types.String         `tfsdk:"version"`
+	Timeouts       *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemoteHomebrewPackageResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_homebrew_package"
+}
+
+func (r *RemoteHomebrewPackageResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Installs a Homebrew formula or cask on a macOS or Linux host. Homebrew refuses to run as root, so " +
+			"the package is installed as the user of `host_connection`, which must own the Homebrew prefix. brew is found " +
+			"in `/opt/homebrew`, `/usr/local` or `/home/linuxbrew/.linuxbrew` when it is not on the `PATH`. Taps other " +
+			"than GitHub ones are added with `remote_host_homebrew_tap`.",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Name of the formula or cask, optionally qualified by its tap, e.g. `jq` or `hashicorp/tap/terraform`. brew taps the repository of a qualified name by itself",
+				Validators: []validator.String{
+					stringMatches(services.HomebrewPackageRegexp, "must be a formula or cask name such as jq or hashicorp/tap/terraform"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"cask": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether `name` is a cask, i.e. a macOS application, rather than a formula. Defaults to `false`",
+				Default:             booldefault.StaticBool(false),
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"triggers": schema.MapAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Arbitrary values that upgrade the package to its latest version when changed",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier of the package, made of the host and its name",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"version": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Installed version of the package, the first one listed by brew when several are kept",
+			},
+		},
+	}
+}
+
+func (r *RemoteHomebrewPackageResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+// install installs the package, or upgrades it when upgrade is set.
+func (r *RemoteHomebrewPackageResource) install(ctx context.Context, data *RemoteHomebrewPackageResourceModel, upgrade bool) error {
+	command := services.HomebrewInstallCommand(data.Name.ValueString(), data.Cask.ValueBool(), upgrade)
+	_, err := runCommand(ctx, r.sshService, data.HostConnection.server(), command)
+	if err != nil {
+		return err
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-homebrew-%s", data.HostConnection.hostID(), data.Name.ValueString()))
+
+	exists, err := r.refresh(ctx, data)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%s is not installed after its installation", data.Name.ValueString())
+	}
+
+	return nil
+}
+
+// refresh reads the installed version back from the host and reports whether the package is
+// still installed.
+func (r *RemoteHomebrewPackageResource) refresh(ctx context.Context, data *RemoteHomebrewPackageResourceModel) (bool, error) {
+	command := services.HomebrewVersionsCommand(data.Name.ValueString(), data.Cask.ValueBool())
+	result, err := runCommand(ctx, r.sshService, data.HostConnection.server(), command)
+	if err != nil {
+		return false, err
+	}
+
+	versions, err := services.ParseHomebrewVersions(result.Stdout, data.Name.ValueString())
+	if err != nil || versions == nil {
+		return false, err
+	}
+
+	data.Version = types.StringValue(versions[0])
+
+	return true, nil
+}
+
+func (r *RemoteHomebrewPackageResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteHomebrewPackageResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	err := r.install(ctx, &data, false)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to install %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteHomebrewPackageResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteHomebrewPackageResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	exists, err := r.refresh(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+
+	if !exists {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteHomebrewPackageResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state RemoteHomebrewPackageResourceModel
+
+	// Read Terraform plan and prior state data into the models
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	err := r.install(ctx, &data, !data.Triggers.Equal(state.Triggers))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to upgrade %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteHomebrewPackageResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteHomebrewPackageResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	command := services.HomebrewUninstallCommand(data.Name.ValueString(), data.Cask.ValueBool())
+	_, err := runCommand(ctx, r.sshService, data.HostConnection.server(), command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to uninstall %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteHomebrewTapResource{}
+
+func NewRemoteHomebrewTapResource() resource.Resource {
+	return &RemoteHomebrewTapResource{}
+}
+
+// RemoteHomebrewTapResource adds a third-party Homebrew repository.
+type RemoteHomebrewTapResource struct {
+	sshService *services.SSHService
+}
+
+// RemoteHomebrewTapResourceModel describes the resource data model.
+type RemoteHomebrewTapResourceModel struct {
+	Id             types.String         `tfsdk:"id"`
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Name           types.String         `tfsdk:"name"`
+	URL            types.String         `tfsdk:"url"`
+	Timeouts       *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemoteHomebrewTapResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_homebrew_tap"
+}
+
+func (r *RemoteHomebrewTapResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Adds a Homebrew tap, a third-party repository of formulae and casks, with `brew tap` as the user " +
+			"of `host_connection`. Destroying the resource removes the tap, which fails while packages of the tap are installed.",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Name of the tap as `<user>/<repository>`, e.g. `hashicorp/tap`",
+				Validators: []validator.String{
+					stringMatches(services.HomebrewTapRegexp, "must be a tap name such as hashicorp/tap"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"url": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Git URL the tap is cloned from. Defaults to the `https://github.com/<user>/homebrew-<repository>` repository",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier of the tap, made of the host and its name",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *RemoteHomebrewTapResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+func (r *RemoteHomebrewTapResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteHomebrewTapResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	command := services.HomebrewTapCommand(data.Name.ValueString(), data.URL.ValueString())
+	_, err := runCommand(ctx, r.sshService, data.HostConnection.server(), command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to tap %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-homebrew-tap-%s", data.HostConnection.hostID(), data.Name.ValueString()))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteHomebrewTapResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteHomebrewTapResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	result, err := runCommand(ctx, r.sshService, data.HostConnection.server(), services.HomebrewTapsCommand)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to list the taps, got error: %s", err))
+		return
+	}
+
+	if !services.HasHomebrewTap(result.Stdout, data.Name.ValueString()) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteHomebrewTapResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data RemoteHomebrewTapResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Every tap attribute requires a new tap, only the timeouts change in place.
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteHomebrewTapResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteHomebrewTapResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	_, err := runCommand(ctx, r.sshService, data.HostConnection.server(), services.HomebrewUntapCommand(data.Name.ValueString()))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to untap %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteIdentityDataSource{}
+
+func NewRemoteIdentityDataSource() datasource.DataSource {
+	return &RemoteIdentityDataSource{}
+}
+
+// RemoteIdentityDataSource reports the machine ID and hardware identifiers of a host.
+type RemoteIdentityDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemoteIdentityDataSourceModel describes the data source data model.
+type RemoteIdentityDataSourceModel struct {
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Privileged     types.Bool           `tfsdk:"privileged"`
+	MachineID      types.String         `tfsdk:"machine_id"`
+	ProductUUID    types.String         `tfsdk:"product_uuid"`
+	ProductSerial  types.String         `tfsdk:"product_serial"`
+	BoardSerial    types.String         `tfsdk:"board_serial"`
+	Identifier     types.String         `tfsdk:"identifier"`
+}
+
+func (d *RemoteIdentityDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_identity"
+}
+
+func (d *RemoteIdentityDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Machine ID and hardware identifiers of a host, e.g. to derive stable per-host names or " +
+			"license keys. Placeholder values left by firmware vendors, such as `To Be Filled By O.E.M.`, are reported as null",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": dataSourceHostConnectionSchema(),
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to read the identifiers as root, which the DMI tables of Linux hosts require",
+			},
+			"machine_id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Machine ID from `/etc/machine-id`, generated when the system is installed",
+			},
+			"product_uuid": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Lower cased system UUID of the DMI tables, or the platform UUID on macOS",
+			},
+			"product_serial": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Serial number of the system",
+			},
+			"board_serial": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Serial number of the motherboard",
+			},
+			"identifier": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Most specific identifier found: `product_uuid`, which survives reinstalls, then `machine_id`, then `board_serial`",
+			},
+		},
+	}
+}
+
+func (d *RemoteIdentityDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+func (d *RemoteIdentityDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteIdentityDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.HostConnection.server()
+
+	command := services.IdentityCommand
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	result, err := runCommand(ctx, d.sshService, server, command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the host identity, got error: %s", err))
+		return
+	}
+
+	identity := services.ParseHostIdentity(result.Stdout)
+	if identity.Identifier() == "" {
+		resp.Diagnostics.AddError("SSH Error", "Unable to read the host identity, got error: no machine ID or hardware identifier found")
+		return
+	}
+
+	data.MachineID = optionalString(identity.MachineID)
+	data.ProductUUID = optionalString(identity.ProductUUID)
+	data.ProductSerial = optionalString(identity.ProductSerial)
+	data.BoardSerial = optionalString(identity.BoardSerial)
+	data.Identifier = types.StringValue(identity.Identifier())
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
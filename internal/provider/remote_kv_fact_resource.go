@@ -0,0 +1,277 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteKVFactResource{}
+
+func NewRemoteKVFactResource() resource.Resource {
+	return &RemoteKVFactResource{}
+}
+
+// RemoteKVFactResource persists a group of custom key/value facts on a host.
+type RemoteKVFactResource struct {
+	sshService *services.SSHService
+}
+
+// RemoteKVFactResourceModel describes the resource data model.
+type RemoteKVFactResourceModel struct {
+	Id             types.String         `tfsdk:"id"`
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Name           types.String         `tfsdk:"name"`
+	Directory      types.String         `tfsdk:"directory"`
+	Facts          types.Map            `tfsdk:"facts"`
+	Timeouts       *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemoteKVFactResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_kv_fact"
+}
+
+func (r *RemoteKVFactResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Persists a group of custom key/value facts on a host as a JSON file, `<directory>/<name>.fact`, " +
+			"read back by the `remote_host_facts` data source, e.g. to record what a previous run deployed. The files " +
+			"follow the `facts.d` layout of Ansible, so `directory = \"/etc/ansible/facts.d\"` shares them with its local facts.",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Name of the fact group, the name of its file without the `.fact` extension",
+				Validators: []validator.String{
+					stringMatches(services.FactNameRegexp, "must only contain letters, digits, dots, dashes and underscores"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"directory": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Directory of the fact files. Defaults to `" + services.FactsDir + "`",
+				Default:             stringdefault.StaticString(services.FactsDir),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"facts": schema.MapAttribute{
+				Required:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Facts of the group keyed by name",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier of the fact group, made of the host and the path of its file",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *RemoteKVFactResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+func (data *RemoteKVFactResourceModel) path() string {
+	return services.FactPath(data.Directory.ValueString(), data.Name.ValueString())
+}
+
+// write writes the facts of data to their file.
+func (r *RemoteKVFactResource) write(ctx context.Context, data *RemoteKVFactResourceModel) error {
+	facts := map[string]string{}
+	diags := data.Facts.ElementsAs(ctx, &facts, false)
+	if diags.HasError() {
+		return fmt.Errorf("unable to read the facts: %v", diags)
+	}
+
+	server := data.HostConnection.server()
+	command := fmt.Sprintf("mkdir -p %s && %s",
+		services.ShellQuote(data.Directory.ValueString()),
+		services.WriteFileCommand(data.path(), services.RenderFacts(facts), "0644"))
+
+	_, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, command))
+	if err != nil {
+		return err
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.HostConnection.hostID(), data.path()))
+
+	return nil
+}
+
+// refresh reads the facts of the file into data, and reports whether it exists.
+func (r *RemoteKVFactResource) refresh(ctx context.Context, data *RemoteKVFactResourceModel) (bool, error) {
+	server := data.HostConnection.server()
+	factPath := data.path()
+
+	command := fmt.Sprintf("if [ -f %s ]; then %s; else echo missing; fi",
+		services.ShellQuote(factPath), services.ReadFilesCommand([]string{factPath}))
+
+	result, err := runCommand(services.WithFileContent(ctx), r.sshService, server, command)
+	if err != nil {
+		return false, err
+	}
+
+	lines, err := services.ParseFileLines(result.Stdout, 1)
+	if err != nil {
+		return false, err
+	}
+	if lines[0] == "missing" {
+		return false, nil
+	}
+
+	content, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return false, fmt.Errorf("unable to decode the content of %s: %w", factPath, err)
+	}
+
+	facts, err := services.ParseFacts(content)
+	if err != nil {
+		return false, fmt.Errorf("unable to parse %s: %w", factPath, err)
+	}
+
+	value, diags := types.MapValueFrom(ctx, types.StringType, facts)
+	if diags.HasError() {
+		return false, fmt.Errorf("unable to store the facts: %v", diags)
+	}
+	data.Facts = value
+
+	return true, nil
+}
+
+func (r *RemoteKVFactResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteKVFactResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	err := r.write(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to write facts %s, got error: %s", data.path(), err))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteKVFactResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteKVFactResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	exists, err := r.refresh(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read facts %s, got error: %s", data.path(), err))
+		return
+	}
+
+	if !exists {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteKVFactResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data RemoteKVFactResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	err := r.write(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to write facts %s, got error: %s", data.path(), err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteKVFactResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteKVFactResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	server := data.HostConnection.server()
+
+	_, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, "rm -f -- "+services.ShellQuote(data.path())))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to remove facts %s, got error: %s", data.path(), err))
+		return
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteLoginsDataSource{}
+
+func NewRemoteLoginsDataSource() datasource.DataSource {
+	return &RemoteLoginsDataSource{}
+}
+
+// RemoteLoginsDataSource exposes the login records and failed login attempts of a host.
+type RemoteLoginsDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemoteLoginsDataSourceModel describes the data source data model.
+type RemoteLoginsDataSourceModel struct {
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Limit          types.Int64          `tfsdk:"limit"`
+	FailedSince    Duration             `tfsdk:"failed_since"`
+	Privileged     types.Bool           `tfsdk:"privileged"`
+	RecentLogins   []RemoteLoginModel   `tfsdk:"recent_logins"`
+	LastLogins     []RemoteLoginModel   `tfsdk:"last_logins"`
+	FailedTotal    types.Int64          `tfsdk:"failed_total"`
+	FailedByUser   types.Map            `tfsdk:"failed_by_user"`
+	FailedByHost   types.Map            `tfsdk:"failed_by_host"`
+}
+
+// RemoteLoginModel describes a login of a user.
+type RemoteLoginModel struct {
+	User     types.String `tfsdk:"user"`
+	Terminal types.String `tfsdk:"terminal"`
+	Host     types.String `tfsdk:"host"`
+	Time     types.String `tfsdk:"time"`
+}
+
+func (d *RemoteLoginsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_logins"
+}
+
+// loginSchema describes a login listed by the data source.
+func loginSchema(description string) schema.ListNestedAttribute {
+	return schema.ListNestedAttribute{
+		Computed:            true,
+		MarkdownDescription: description,
+		NestedObject: schema.NestedAttributeObject{
+			Attributes: map[string]schema.Attribute{
+				"user": schema.StringAttribute{
+					Computed:            true,
+					MarkdownDescription: "User that logged in",
+				},
+				"terminal": schema.StringAttribute{
+					Computed:            true,
+					MarkdownDescription: "Terminal of the session, e.g. `pts/0`",
+				},
+				"host": schema.StringAttribute{
+					Computed:            true,
+					MarkdownDescription: "Remote host the user logged in from, empty for local logins",
+				},
+				"time": schema.StringAttribute{
+					Computed:            true,
+					MarkdownDescription: "RFC 3339 timestamp of the login",
+				},
+			},
+		},
+	}
+}
+
+func (d *RemoteLoginsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Login records of a host read with `last`, `lastlog` and `lastb`, so security modules can " +
+			"alert on unexpected access during scheduled runs. Records the host does not keep, e.g. without wtmp or " +
+			"lastlog, are empty",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": dataSourceHostConnectionSchema(),
+			"limit": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "Maximum number of recent logins listed. Defaults to `50`",
+				Validators: []validator.Int64{
+					int64AtLeast(1),
+				},
+			},
+			"failed_since": schema.StringAttribute{
+				CustomType:          DurationType{},
+				Optional:            true,
+				MarkdownDescription: "Only count the failed attempts of this last duration, e.g. `24h`. Defaults to every attempt btmp keeps",
+			},
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to read the records as root, which `lastb` needs to read the failed attempts",
+			},
+			"recent_logins": loginSchema("Most recent logins recorded in wtmp, most recent first. Sessions still open are included"),
+			"last_logins":   loginSchema("Last login of every user that ever logged in, as recorded in lastlog, most recent first"),
+			"failed_total": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "Number of failed login attempts",
+			},
+			"failed_by_user": schema.MapAttribute{
+				ElementType:         types.Int64Type,
+				Computed:            true,
+				MarkdownDescription: "Number of failed login attempts by user name",
+			},
+			"failed_by_host": schema.MapAttribute{
+				ElementType:         types.Int64Type,
+				Computed:            true,
+				MarkdownDescription: "Number of failed login attempts by remote host, local attempts are counted under an empty key",
+			},
+		},
+	}
+}
+
+func (d *RemoteLoginsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+// loginModels converts logins to their model.
+func loginModels(logins []services.Login) []RemoteLoginModel {
+	models := []RemoteLoginModel{}
+	for _, login := range logins {
+		models = append(models, RemoteLoginModel{
+			User:     types.StringValue(login.User),
+			Terminal: types.StringValue(login.Terminal),
+			Host:     types.StringValue(login.Host),
+			Time:     types.StringValue(login.Time.UTC().Format(time.RFC3339)),
+		})
+	}
+
+	return models
+}
+
+func (d *RemoteLoginsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteLoginsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.HostConnection.server()
+
+	err := d.sshService.OpenConnection(ctx, server)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to connect to %s, got error: %s", server.Name, err))
+		return
+	}
+
+	limit := int64(50)
+	if !data.Limit.IsNull() {
+		limit = data.Limit.ValueInt64()
+	}
+
+	command := services.LoginsCommand(limit)
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	result, err := runCommand(ctx, d.sshService, server, command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the login records, got error: %s", err))
+		return
+	}
+
+	records := services.ParseLogins(result.Stdout)
+	services.SortLogins(records.Last)
+
+	var since time.Time
+	if !data.FailedSince.IsNull() {
+		duration, _ := time.ParseDuration(data.FailedSince.ValueString())
+		since = time.Now().Add(-duration)
+	}
+	byUser, byHost := records.FailedCounts(since)
+
+	total := int64(0)
+	for _, count := range byUser {
+		total += count
+	}
+
+	data.RecentLogins = loginModels(records.Recent)
+	data.LastLogins = loginModels(records.Last)
+	data.FailedTotal = types.Int64Value(total)
+
+	failedByUser, diags := types.MapValueFrom(ctx, types.Int64Type, byUser)
+	resp.Diagnostics.Append(diags...)
+	failedByHost, diags := types.MapValueFrom(ctx, types.Int64Type, byHost)
+	resp.Diagnostics.Append(diags...)
+	data.FailedByUser = failedByUser
+	data.FailedByHost = failedByHost
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
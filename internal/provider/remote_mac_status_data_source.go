@@ -0,0 +1,131 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteMACStatusDataSource{}
+
+func NewRemoteMACStatusDataSource() datasource.DataSource {
+	return &RemoteMACStatusDataSource{}
+}
+
+// RemoteMACStatusDataSource reports the mandatory access control system in effect on a host.
+type RemoteMACStatusDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemoteMACStatusDataSourceModel describes the data source data model.
+type RemoteMACStatusDataSourceModel struct {
+	HostConnection   *HostConnectionModel `tfsdk:"host_connection"`
+	Privileged       types.Bool           `tfsdk:"privileged"`
+	Active           types.String         `tfsdk:"active"`
+	SELinuxMode      types.String         `tfsdk:"selinux_mode"`
+	SELinuxPolicy    types.String         `tfsdk:"selinux_policy"`
+	AppArmorEnabled  types.Bool           `tfsdk:"apparmor_enabled"`
+	AppArmorProfiles map[string]string    `tfsdk:"apparmor_profiles"`
+}
+
+func (d *RemoteMACStatusDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_mac_status"
+}
+
+func (d *RemoteMACStatusDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Mandatory access control system in effect on a Linux host: the SELinux mode or the loaded " +
+			"AppArmor profiles, so security-sensitive modules can enforce preconditions",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": dataSourceHostConnectionSchema(),
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to read the status as root, which is needed to list the AppArmor profiles",
+			},
+			"active": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "System in effect: `selinux`, `apparmor` or `none`",
+			},
+			"selinux_mode": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "SELinux mode: `enforcing`, `permissive` or `disabled`, empty when SELinux is not installed",
+			},
+			"selinux_policy": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "SELinux policy configured in `/etc/selinux/config`, e.g. `targeted`",
+			},
+			"apparmor_enabled": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether the AppArmor module is enabled in the kernel",
+			},
+			"apparmor_profiles": schema.MapAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Mode of the loaded AppArmor profiles keyed by profile name, e.g. `enforce` or `complain`. Empty unless `privileged` is set",
+			},
+		},
+	}
+}
+
+func (d *RemoteMACStatusDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+func (d *RemoteMACStatusDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteMACStatusDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.HostConnection.server()
+
+	command := services.MACStatusCommand
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	result, err := runCommand(ctx, d.sshService, server, command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the access control status, got error: %s", err))
+		return
+	}
+
+	status := services.ParseMACStatus(result.Stdout)
+	data.Active = types.StringValue(status.Active())
+	data.SELinuxMode = types.StringValue(status.SELinuxMode)
+	data.SELinuxPolicy = types.StringValue(status.SELinuxPolicy)
+	data.AppArmorEnabled = types.BoolValue(status.AppArmorEnabled)
+	data.AppArmorProfiles = status.AppArmorProfiles
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,319 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteMaintenanceWindowResource{}
+var _ resource.ResourceWithMoveState = &RemoteMaintenanceWindowResource{}
+var _ resource.ResourceWithValidateConfig = &RemoteMaintenanceWindowResource{}
+
+// maintenanceWindowTools are the tools the host needs to schedule jobs.
+var maintenanceWindowTools = []string{services.PrivilegeTool, "at", "atq", "atrm"}
+
+func NewRemoteMaintenanceWindowResource() resource.Resource {
+	return &RemoteMaintenanceWindowResource{}
+}
+
+// NewLegacyRemoteMaintenanceWindowResource registers the resource under its deprecated unprefixed name.
+func NewLegacyRemoteMaintenanceWindowResource() resource.Resource {
+	return &RemoteMaintenanceWindowResource{legacyTypeName: "remote_maintenance_window"}
+}
+
+// RemoteMaintenanceWindowResource schedules a shutdown or a maintenance command with at(1).
+type RemoteMaintenanceWindowResource struct {
+	sshService     *services.SSHService
+	legacyTypeName string
+}
+
+// RemoteMaintenanceWindowResourceModel describes the resource data model.
+type RemoteMaintenanceWindowResourceModel struct {
+	Id             types.String         `tfsdk:"id"`
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	At             types.String         `tfsdk:"at"`
+	Command        types.String         `tfsdk:"command"`
+	Reboot         types.Bool           `tfsdk:"reboot"`
+	ScheduledAt    types.String         `tfsdk:"scheduled_at"`
+	Pending        types.Bool           `tfsdk:"pending"`
+	Timeouts       *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemoteMaintenanceWindowResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_maintenance_window"
+	if r.legacyTypeName != "" {
+		resp.TypeName = r.legacyTypeName
+	}
+}
+
+func (r *RemoteMaintenanceWindowResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	resp.Schema = schema.Schema{
+		DeprecationMessage: legacyDeprecationMessage(r.legacyTypeName, "remote_host_maintenance_window"),
+
+		MarkdownDescription: "Schedules a shutdown, a reboot or a maintenance command on a host with `at`. " +
+			"Destroying the resource cancels the job when it did not run yet, changing it schedules a new one.",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"at": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "When to run the job, as an `at` time specification such as `23:00`, `now + 2 hours` or `02:00 tomorrow`",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"command": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Maintenance command to run as root. The host is powered off, or rebooted with `reboot`, when unset",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"reboot": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to reboot instead of powering off the host, only used without `command`",
+				Default:             booldefault.StaticBool(false),
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Number of the `at` job",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"scheduled_at": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Time the job is scheduled at as reported by the host",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"pending": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether the job is still queued. It becomes false once the job ran or was removed outside of Terraform",
+			},
+		},
+	}
+}
+
+func (r *RemoteMaintenanceWindowResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var command types.String
+	var reboot types.Bool
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("command"), &command)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("reboot"), &reboot)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !command.IsNull() && reboot.ValueBool() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("reboot"),
+			"Invalid Maintenance Configuration",
+			"reboot cannot be enabled together with command, reboot from the command instead.",
+		)
+	}
+}
+
+func (r *RemoteMaintenanceWindowResource) MoveState(ctx context.Context) []resource.StateMover {
+	return renamedStateMovers[RemoteMaintenanceWindowResourceModel](ctx, &RemoteMaintenanceWindowResource{}, "remote_maintenance_window")
+}
+
+func (r *RemoteMaintenanceWindowResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+// job returns the command queued with at.
+func (data *RemoteMaintenanceWindowResourceModel) job() string {
+	if !data.Command.IsNull() {
+		return data.Command.ValueString()
+	}
+
+	if data.Reboot.ValueBool() {
+		return "shutdown -r now"
+	}
+
+	return "shutdown -h now"
+}
+
+// refresh updates whether the job is still queued on the host.
+func (r *RemoteMaintenanceWindowResource) refresh(ctx context.Context, data *RemoteMaintenanceWindowResourceModel) error {
+	server := data.HostConnection.server()
+
+	result, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, "atq"))
+	if err != nil {
+		return err
+	}
+
+	data.Pending = types.BoolValue(services.AtqHasJob(result.Stdout, data.Id.ValueString()))
+
+	return nil
+}
+
+func (r *RemoteMaintenanceWindowResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteMaintenanceWindowResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	server := data.HostConnection.server()
+
+	err := r.sshService.Preflight(ctx, server, maintenanceWindowTools)
+	if err != nil {
+		resp.Diagnostics.AddError("Preflight Error", fmt.Sprintf("Unable to schedule the maintenance window, got error: %s", err))
+		return
+	}
+
+	result, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, services.ScheduleCommand(data.job(), data.At.ValueString())))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to schedule the maintenance window, got error: %s", err))
+		return
+	}
+
+	job, scheduledAt, err := services.ParseAtJob(result.Stdout)
+	if err != nil {
+		resp.Diagnostics.AddError("Command Error", fmt.Sprintf("Unable to schedule the maintenance window, got error: %s", err))
+		return
+	}
+
+	data.Id = types.StringValue(job)
+	data.ScheduledAt = types.StringValue(scheduledAt)
+	data.Pending = types.BoolValue(true)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteMaintenanceWindowResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteMaintenanceWindowResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	// A job that already ran stays in state so it is not scheduled again on the next apply.
+	// Hosts that were powered off by the job cannot be reached, the state is kept as is then.
+	err := r.refresh(ctx, &data)
+
+	var exitErr *ExitCodeError
+	if err != nil && !errors.As(err, &exitErr) {
+		resp.Diagnostics.AddWarning("Host Unreachable", fmt.Sprintf("Unable to reach the host of maintenance window %s, keeping its last known state: %s", data.Id.ValueString(), err))
+	} else if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the maintenance window, got error: %s", err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteMaintenanceWindowResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data RemoteMaintenanceWindowResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	// Every job attribute requires a replacement, only the timeouts change in place.
+	err := r.refresh(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the maintenance window, got error: %s", err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteMaintenanceWindowResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteMaintenanceWindowResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	// Jobs that already ran are gone from the queue, which is fine.
+	command := fmt.Sprintf("atrm %s 2>/dev/null || true", services.ShellQuote(data.Id.ValueString()))
+
+	server := data.HostConnection.server()
+
+	_, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, command))
+
+	var exitErr *ExitCodeError
+	if err != nil && !errors.As(err, &exitErr) && !data.Pending.ValueBool() {
+		// The job ran and powered the host off, there is nothing left to cancel.
+		return
+	}
+
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to cancel the maintenance window, got error: %s", err))
+		return
+	}
+}
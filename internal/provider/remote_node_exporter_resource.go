@@ -0,0 +1,409 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"remote-provider/internal/provider/services"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteNodeExporterResource{}
+var _ resource.ResourceWithMoveState = &RemoteNodeExporterResource{}
+
+var nodeExporterVersionRegexp = regexp.MustCompile(`node_exporter, version (\S+)`)
+
+// nodeExporterTools are the tools the host needs to install the exporter, next to the
+// ones managing daemons on its platform.
+var nodeExporterTools = []string{services.PrivilegeTool, "mktemp", "curl|wget", "awk", "sha256sum|shasum", "tar", "install", "base64"}
+
+func NewRemoteNodeExporterResource() resource.Resource {
+	return &RemoteNodeExporterResource{}
+}
+
+// NewLegacyRemoteNodeExporterResource registers the resource under its deprecated unprefixed name.
+func NewLegacyRemoteNodeExporterResource() resource.Resource {
+	return &RemoteNodeExporterResource{legacyTypeName: "remote_node_exporter"}
+}
+
+// RemoteNodeExporterResource installs the Prometheus node exporter as a systemd or launchd service.
+type RemoteNodeExporterResource struct {
+	sshService     *services.SSHService
+	legacyTypeName string
+}
+
+// RemoteNodeExporterResourceModel describes the resource data model.
+type RemoteNodeExporterResourceModel struct {
+	Id             types.String         `tfsdk:"id"`
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Version        types.String         `tfsdk:"version"`
+	Arch           types.String         `tfsdk:"arch"`
+	User           types.String         `tfsdk:"user"`
+	InstallDir     types.String         `tfsdk:"install_dir"`
+	ListenAddress  types.String         `tfsdk:"listen_address"`
+	ExtraArgs      []types.String       `tfsdk:"extra_args"`
+	ServiceActive  types.Bool           `tfsdk:"service_active"`
+	Timeouts       *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemoteNodeExporterResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_node_exporter"
+	if r.legacyTypeName != "" {
+		resp.TypeName = r.legacyTypeName
+	}
+}
+
+func (r *RemoteNodeExporterResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		DeprecationMessage: legacyDeprecationMessage(r.legacyTypeName, "remote_host_node_exporter"),
+
+		MarkdownDescription: "Installs the Prometheus node exporter from its GitHub release, verified against the `sha256sums.txt` of the release, creates its system user and runs it as a systemd service, or a launchd daemon on macOS",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnectionSchema(),
+			"version": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Node exporter release to install, without the `v` prefix (e.g. `1.8.2`)",
+			},
+			"arch": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Release architecture to download, e.g. `amd64` or `arm64` for Apple silicon",
+				Default:             stringdefault.StaticString("amd64"),
+			},
+			"user": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "System user the service runs as",
+				Default:             stringdefault.StaticString("node_exporter"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"install_dir": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Directory the binary is installed into",
+				Default:             stringdefault.StaticString("/usr/local/bin"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"listen_address": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Address passed to `--web.listen-address`",
+				Default:             stringdefault.StaticString(":9100"),
+			},
+			"extra_args": schema.ListAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Additional command line flags for the exporter",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier of the installation",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"service_active": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether the service was active when last read",
+			},
+		},
+	}
+}
+
+func (r *RemoteNodeExporterResource) MoveState(ctx context.Context) []resource.StateMover {
+	return renamedStateMovers[RemoteNodeExporterResourceModel](ctx, &RemoteNodeExporterResource{}, "remote_node_exporter")
+}
+
+func (r *RemoteNodeExporterResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+func (data *RemoteNodeExporterResourceModel) binaryPath() string {
+	return path.Join(data.InstallDir.ValueString(), "node_exporter")
+}
+
+// downloadScript fetches the release tarball into dir, which is a private workspace, verifies it
+// against the sha256sums.txt published with the release and unpacks it.
+func (data *RemoteNodeExporterResourceModel) downloadScript(dir string, platform services.Platform) string {
+	releaseURL := fmt.Sprintf("https://github.com/prometheus/node_exporter/releases/download/v%s", data.Version.ValueString())
+	name := data.release(platform) + ".tar.gz"
+	archive := services.ShellQuote(dir + "/release.tar.gz")
+	sums := services.ShellQuote(dir + "/sha256sums.txt")
+
+	fetch := func(output string, url string) string {
+		return fmt.Sprintf(`if command -v curl >/dev/null 2>&1; then curl -fsSL -o %[1]s %[2]s; else wget -q -O %[1]s %[2]s; fi`, output, services.ShellQuote(url))
+	}
+
+	return strings.Join([]string{
+		"set -e",
+		fetch(archive, releaseURL+"/"+name),
+		fetch(sums, releaseURL+"/sha256sums.txt"),
+		fmt.Sprintf(`expected=$(awk -v name=%s '$2 == name || $2 == "*" name { print $1 }' %s)`, services.ShellQuote(name), sums),
+		fmt.Sprintf(`actual=$({ sha256sum 2>/dev/null < %[1]s || shasum -a 256 < %[1]s; } | cut -d ' ' -f 1)`, archive),
+		fmt.Sprintf(`if [ -z "$expected" ] || [ "$expected" != "$actual" ]; then echo "sha256 of %s is $actual, sha256sums.txt lists '$expected'" >&2; exit 1; fi`, name),
+		fmt.Sprintf(`tar -xzf %s -C %s`, archive, services.ShellQuote(dir)),
+	}, "\n")
+}
+
+// installScript installs the unpacked binary from dir and makes sure the service user exists.
+func (data *RemoteNodeExporterResourceModel) installScript(dir string, directoryMode string, platform services.Platform) string {
+	return strings.Join([]string{
+		"set -e",
+		fmt.Sprintf(`mkdir -p -m %s %s`, directoryMode, services.ShellQuote(data.InstallDir.ValueString())),
+		fmt.Sprintf(`install -m 0755 %s %s`, services.ShellQuote(dir+"/"+data.release(platform)+"/node_exporter"), services.ShellQuote(data.binaryPath())),
+		platform.CreateSystemUserCommand(data.User.ValueString()),
+	}, "\n")
+}
+
+func (data *RemoteNodeExporterResourceModel) release(platform services.Platform) string {
+	return fmt.Sprintf("node_exporter-%s.%s-%s", data.Version.ValueString(), platform.OS, data.Arch.ValueString())
+}
+
+func (data *RemoteNodeExporterResourceModel) daemon() services.Daemon {
+	command := []string{data.binaryPath(), "--web.listen-address=" + data.ListenAddress.ValueString()}
+	for _, arg := range data.ExtraArgs {
+		command = append(command, arg.ValueString())
+	}
+
+	return services.Daemon{
+		Name:        "node_exporter",
+		Description: "Prometheus Node Exporter",
+		User:        data.User.ValueString(),
+		Command:     command,
+	}
+}
+
+func (r *RemoteNodeExporterResource) install(ctx context.Context, data *RemoteNodeExporterResourceModel) error {
+	server := data.HostConnection.server()
+
+	platform, err := r.sshService.DetectPlatform(ctx, server)
+	if err != nil {
+		return err
+	}
+
+	err = r.sshService.Preflight(ctx, server, append(platform.DaemonTools(), nodeExporterTools...))
+	if err != nil {
+		return err
+	}
+
+	workspace, err := r.sshService.Workspace(ctx, server)
+	if err != nil {
+		return err
+	}
+
+	_, err = runCommand(ctx, r.sshService, server, data.downloadScript(workspace, platform))
+	if err != nil {
+		return fmt.Errorf("downloading release: %w", err)
+	}
+
+	_, err = runCommand(ctx, r.sshService, server, privilegedCommand(server, data.installScript(workspace, r.sshService.DirectoryMode(""), platform)))
+	if err != nil {
+		return fmt.Errorf("installing release: %w", err)
+	}
+
+	daemon := data.daemon()
+	definition, err := platform.DaemonDefinition(daemon)
+	if err != nil {
+		return fmt.Errorf("writing service definition: %w", err)
+	}
+
+	err = writeRemoteFile(ctx, r.sshService, server, platform.DaemonDefinitionPath(daemon), []byte(definition), "", true)
+	if err != nil {
+		return fmt.Errorf("writing service definition: %w", err)
+	}
+
+	_, err = runCommand(ctx, r.sshService, server, privilegedCommand(server, platform.StartDaemonCommand(daemon)))
+	if err != nil {
+		return fmt.Errorf("starting service: %w", err)
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-node_exporter", data.HostConnection.hostID()))
+	data.ServiceActive = types.BoolValue(true)
+
+	return nil
+}
+
+// refresh reads the installed version and service state, reporting false when the exporter is not installed.
+func (r *RemoteNodeExporterResource) refresh(ctx context.Context, data *RemoteNodeExporterResourceModel) (bool, error) {
+	server := data.HostConnection.server()
+
+	platform, err := r.sshService.DetectPlatform(ctx, server)
+	if err != nil {
+		return false, err
+	}
+
+	command, err := runCommand(ctx, r.sshService, server, fmt.Sprintf("%s --version 2>&1", services.ShellQuote(data.binaryPath())))
+
+	var exitErr *ExitCodeError
+	if errors.As(err, &exitErr) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	match := nodeExporterVersionRegexp.FindStringSubmatch(command.Stdout)
+	if match == nil {
+		return false, fmt.Errorf("unexpected version output: %s", command.Stdout)
+	}
+
+	data.Version = types.StringValue(match[1])
+
+	// The check exits non-zero for inactive services, which only matters for the computed flag.
+	_, err = runCommand(ctx, r.sshService, server, platform.DaemonActiveCommand(data.daemon()))
+	if err != nil && !errors.As(err, &exitErr) {
+		return false, err
+	}
+
+	data.ServiceActive = types.BoolValue(err == nil)
+
+	return true, nil
+}
+
+func (r *RemoteNodeExporterResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteNodeExporterResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	err := r.install(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to install node exporter, got error: %s", err))
+		return
+	}
+
+	tflog.Trace(ctx, "installed node exporter")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteNodeExporterResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteNodeExporterResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	installed, err := r.refresh(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read node exporter, got error: %s", err))
+		return
+	}
+
+	if !installed {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteNodeExporterResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data RemoteNodeExporterResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	err := r.install(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to update node exporter, got error: %s", err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteNodeExporterResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteNodeExporterResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	server := data.HostConnection.server()
+
+	platform, err := r.sshService.DetectPlatform(ctx, server)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to remove node exporter, got error: %s", err))
+		return
+	}
+
+	daemon := data.daemon()
+	command := strings.Join([]string{
+		platform.StopDaemonCommand(daemon),
+		fmt.Sprintf("rm -f %s %s", services.ShellQuote(platform.DaemonDefinitionPath(daemon)), services.ShellQuote(data.binaryPath())),
+		platform.DeleteUserCommand(data.User.ValueString()),
+	}, "\n")
+
+	_, err = runCommand(ctx, r.sshService, server, privilegedCommand(server, command))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to remove node exporter, got error: %s", err))
+		return
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"slices"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteOpenPortsDataSource{}
+
+func NewRemoteOpenPortsDataSource() datasource.DataSource {
+	return &RemoteOpenPortsDataSource{}
+}
+
+// RemoteOpenPortsDataSource lists the listening sockets of a host.
+type RemoteOpenPortsDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemoteOpenPortsDataSourceModel describes the data source data model.
+type RemoteOpenPortsDataSourceModel struct {
+	HostConnection *HostConnectionModel  `tfsdk:"host_connection"`
+	Privileged     types.Bool            `tfsdk:"privileged"`
+	Sockets        []RemoteOpenPortModel `tfsdk:"sockets"`
+	TCPPorts       []types.Int64         `tfsdk:"tcp_ports"`
+	UDPPorts       []types.Int64         `tfsdk:"udp_ports"`
+}
+
+// RemoteOpenPortModel describes a listening socket of the host.
+type RemoteOpenPortModel struct {
+	Protocol types.String `tfsdk:"protocol"`
+	Address  types.String `tfsdk:"address"`
+	Port     types.Int64  `tfsdk:"port"`
+	Process  types.String `tfsdk:"process"`
+	PID      types.Int64  `tfsdk:"pid"`
+}
+
+func (d *RemoteOpenPortsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_open_ports"
+}
+
+func (d *RemoteOpenPortsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Listening TCP and UDP sockets of a Linux host as reported by `ss`, e.g. to assert with a " +
+			"precondition that port 443 is free before installing a new proxy",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": dataSourceHostConnectionSchema(),
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to list the sockets as root, which is needed to know the process of sockets owned by other users",
+			},
+			"sockets": schema.ListNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "Listening sockets of the host",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"protocol": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "`tcp` or `udp`",
+						},
+						"address": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Local address the socket is bound to, `0.0.0.0` or `::` for every address",
+						},
+						"port": schema.Int64Attribute{
+							Computed:            true,
+							MarkdownDescription: "Local port of the socket",
+						},
+						"process": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Name of the process owning the socket, empty when it is not visible to the user",
+						},
+						"pid": schema.Int64Attribute{
+							Computed:            true,
+							MarkdownDescription: "Process ID of the process owning the socket, 0 when it is not visible to the user",
+						},
+					},
+				},
+			},
+			"tcp_ports": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.Int64Type,
+				MarkdownDescription: "Sorted distinct TCP ports listened on",
+			},
+			"udp_ports": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.Int64Type,
+				MarkdownDescription: "Sorted distinct UDP ports listened on",
+			},
+		},
+	}
+}
+
+func (d *RemoteOpenPortsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+// listenedPorts returns the sorted distinct ports of the sockets of protocol.
+func listenedPorts(sockets []services.ListeningSocket, protocol string) []types.Int64 {
+	seen := map[int64]bool{}
+	var numbers []int64
+	for _, socket := range sockets {
+		if socket.Protocol == protocol && !seen[socket.Port] {
+			seen[socket.Port] = true
+			numbers = append(numbers, socket.Port)
+		}
+	}
+	slices.Sort(numbers)
+
+	values := make([]types.Int64, 0, len(numbers))
+	for _, number := range numbers {
+		values = append(values, types.Int64Value(number))
+	}
+
+	return values
+}
+
+func (d *RemoteOpenPortsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteOpenPortsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.HostConnection.server()
+
+	err := d.sshService.OpenConnection(ctx, server)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to connect, got error: %s", err))
+		return
+	}
+
+	err = d.sshService.Preflight(ctx, server, []string{"ss"})
+	if err != nil {
+		resp.Diagnostics.AddError("Preflight Error", fmt.Sprintf("Unable to list the open ports, got error: %s", err))
+		return
+	}
+
+	command := services.ListeningSocketsCommand
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	result, err := runCommand(ctx, d.sshService, server, command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to list the open ports, got error: %s", err))
+		return
+	}
+
+	sockets, err := services.ParseListeningSockets(result.Stdout)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to parse the open ports, got error: %s", err))
+		return
+	}
+
+	data.Sockets = make([]RemoteOpenPortModel, 0, len(sockets))
+	for _, socket := range sockets {
+		data.Sockets = append(data.Sockets, RemoteOpenPortModel{
+			Protocol: types.StringValue(socket.Protocol),
+			Address:  types.StringValue(socket.Address),
+			Port:     types.Int64Value(socket.Port),
+			Process:  types.StringValue(socket.Process),
+			PID:      types.Int64Value(socket.PID),
+		})
+	}
+	data.TCPPorts = listenedPorts(sockets, "tcp")
+	data.UDPPorts = listenedPorts(sockets, "udp")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
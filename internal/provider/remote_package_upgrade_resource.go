@@ -0,0 +1,288 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemotePackageUpgradeResource{}
+
+func NewRemotePackageUpgradeResource() resource.Resource {
+	return &RemotePackageUpgradeResource{}
+}
+
+// RemotePackageUpgradeResource applies the pending security or full upgrades of a host.
+type RemotePackageUpgradeResource struct {
+	sshService *services.SSHService
+}
+
+// RemotePackageUpgradeResourceModel describes the resource data model.
+type RemotePackageUpgradeResourceModel struct {
+	Id               types.String         `tfsdk:"id"`
+	HostConnection   *HostConnectionModel `tfsdk:"host_connection"`
+	Scope            types.String         `tfsdk:"scope"`
+	Exclude          []types.String       `tfsdk:"exclude"`
+	Triggers         types.Map            `tfsdk:"triggers"`
+	UpgradedPackages types.Map            `tfsdk:"upgraded_packages"`
+	UpgradedAt       types.String         `tfsdk:"upgraded_at"`
+	RebootRequired   types.Bool           `tfsdk:"reboot_required"`
+	RebootPackages   types.List           `tfsdk:"reboot_packages"`
+	Timeouts         *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemotePackageUpgradeResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_package_upgrade"
+}
+
+func (r *RemotePackageUpgradeResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Applies the pending security or full upgrades of a host with apt, dnf or yum. The upgrade runs " +
+			"again whenever `scope`, `exclude` or `triggers` change, e.g. with a `time_rotating` value as a weekly " +
+			"patching schedule. `reboot_required` tells whether the host must be rebooted afterwards, e.g. with the " +
+			"`remote_host_reboot` action. Destroying the resource does not downgrade anything.",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"scope": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Updates to apply: `security` for security fixes only or `full` for every pending update. Defaults to `security`",
+				Default:             stringdefault.StaticString("security"),
+				Validators: []validator.String{
+					stringOneOf(services.UpgradeScopes...),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"exclude": schema.ListAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Names of the packages held back, e.g. `[\"docker-ce\"]`",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"triggers": schema.MapAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Arbitrary values that cause the upgrade to run again when changed",
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier of the upgrade, made of the host and the time it ran",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"upgraded_packages": schema.MapAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "New version of the packages upgraded or installed by the upgrade keyed by name",
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"upgraded_at": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "RFC 3339 time the upgrade ran",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"reboot_required": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether the host waits for a reboot, from `/var/run/reboot-required` on Debian and `needs-restarting -r` on Red Hat hosts. Refreshed on every read, so it clears once the host rebooted",
+			},
+			"reboot_packages": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Packages asking for the reboot, from `/var/run/reboot-required.pkgs` on Debian hosts",
+			},
+		},
+	}
+}
+
+func (r *RemotePackageUpgradeResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+// installedPackages returns the version of every package installed on the host keyed by name.
+func (r *RemotePackageUpgradeResource) installedPackages(ctx context.Context, data *RemotePackageUpgradeResourceModel) (map[string]string, error) {
+	result, err := runCommand(ctx, r.sshService, data.HostConnection.server(), services.InstalledPackagesCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	return services.ParseInstalledPackages(result.Stdout), nil
+}
+
+// refreshReboot updates whether the host waits for a reboot.
+func (r *RemotePackageUpgradeResource) refreshReboot(ctx context.Context, data *RemotePackageUpgradeResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	result, err := runCommand(ctx, r.sshService, data.HostConnection.server(), services.UptimeCommand)
+	if err != nil {
+		diags.AddError("SSH Error", fmt.Sprintf("Unable to read whether the host must reboot, got error: %s", err))
+		return diags
+	}
+
+	uptime, err := services.ParseUptime(result.Stdout)
+	if err != nil {
+		diags.AddError("SSH Error", fmt.Sprintf("Unable to read whether the host must reboot, got error: %s", err))
+		return diags
+	}
+
+	data.RebootRequired = types.BoolValue(uptime.RebootRequired)
+	data.RebootPackages, diags = types.ListValueFrom(ctx, types.StringType, uptime.RebootPackages)
+
+	return diags
+}
+
+func (r *RemotePackageUpgradeResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemotePackageUpgradeResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	server := data.HostConnection.server()
+
+	before, err := r.installedPackages(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to list the installed packages, got error: %s", err))
+		return
+	}
+
+	exclude := make([]string, 0, len(data.Exclude))
+	for _, name := range data.Exclude {
+		exclude = append(exclude, name.ValueString())
+	}
+
+	upgradedAt := time.Now().UTC()
+	_, err = runCommand(ctx, r.sshService, server, privilegedCommand(server, services.UpgradeCommand(data.Scope.ValueString(), exclude)))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to upgrade the packages, got error: %s", err))
+		return
+	}
+
+	after, err := r.installedPackages(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to list the upgraded packages, got error: %s", err))
+		return
+	}
+
+	var diags diag.Diagnostics
+	data.UpgradedPackages, diags = types.MapValueFrom(ctx, types.StringType, services.ChangedPackages(before, after))
+	resp.Diagnostics.Append(diags...)
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-%d", data.HostConnection.hostID(), upgradedAt.Unix()))
+	data.UpgradedAt = types.StringValue(upgradedAt.Truncate(time.Second).Format(time.RFC3339))
+
+	resp.Diagnostics.Append(r.refreshReboot(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemotePackageUpgradeResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemotePackageUpgradeResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	resp.Diagnostics.Append(r.refreshReboot(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemotePackageUpgradeResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data RemotePackageUpgradeResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	// Every upgrade attribute requires a new upgrade, only the timeouts change in place.
+	resp.Diagnostics.Append(r.refreshReboot(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemotePackageUpgradeResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+}
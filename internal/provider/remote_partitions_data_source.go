@@ -0,0 +1,168 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemotePartitionsDataSource{}
+
+func NewRemotePartitionsDataSource() datasource.DataSource {
+	return &RemotePartitionsDataSource{}
+}
+
+// RemotePartitionsDataSource lists the partitions of a host.
+type RemotePartitionsDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemotePartitionsDataSourceModel describes the data source data model.
+type RemotePartitionsDataSourceModel struct {
+	HostConnection *HostConnectionModel   `tfsdk:"host_connection"`
+	Disk           types.String           `tfsdk:"disk"`
+	Partitions     []RemotePartitionModel `tfsdk:"partitions"`
+}
+
+// RemotePartitionModel describes a partition of the host.
+type RemotePartitionModel struct {
+	Name       types.String `tfsdk:"name"`
+	Path       types.String `tfsdk:"path"`
+	Disk       types.String `tfsdk:"disk"`
+	Size       types.Int64  `tfsdk:"size"`
+	FSType     types.String `tfsdk:"fstype"`
+	Label      types.String `tfsdk:"label"`
+	UUID       types.String `tfsdk:"uuid"`
+	PartUUID   types.String `tfsdk:"partuuid"`
+	Mountpoint types.String `tfsdk:"mountpoint"`
+}
+
+func (d *RemotePartitionsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_partitions"
+}
+
+func (d *RemotePartitionsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Partitions of a Linux host as reported by `lsblk`, with their filesystem UUIDs and partition " +
+			"UUIDs to reference them by stable identifiers",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": dataSourceHostConnectionSchema(),
+			"disk": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Kernel name of the disk to list the partitions of, e.g. `nvme0n1`. All partitions are listed when unset",
+			},
+			"partitions": schema.ListNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "Partitions of the host",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Kernel name of the partition, e.g. `nvme0n1p1`",
+						},
+						"path": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Device path of the partition",
+						},
+						"disk": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Kernel name of the disk holding the partition",
+						},
+						"size": schema.Int64Attribute{
+							Computed:            true,
+							MarkdownDescription: "Size of the partition in bytes",
+						},
+						"fstype": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Filesystem of the partition, empty when it has none",
+						},
+						"label": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Filesystem label",
+						},
+						"uuid": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Filesystem UUID",
+						},
+						"partuuid": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Partition UUID of the partition table entry",
+						},
+						"mountpoint": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Where the partition is mounted, empty when it is not",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *RemotePartitionsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+func (d *RemotePartitionsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemotePartitionsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	devices, err := readBlockDevices(ctx, d.sshService, data.HostConnection)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to list the partitions, got error: %s", err))
+		return
+	}
+
+	data.Partitions = []RemotePartitionModel{}
+	for _, device := range devices {
+		if device.Type != "part" || (!data.Disk.IsNull() && device.Parent != data.Disk.ValueString()) {
+			continue
+		}
+
+		data.Partitions = append(data.Partitions, RemotePartitionModel{
+			Name:       types.StringValue(device.Name),
+			Path:       types.StringValue(device.Path),
+			Disk:       types.StringValue(device.Parent),
+			Size:       types.Int64Value(device.Size),
+			FSType:     types.StringValue(device.FSType),
+			Label:      types.StringValue(device.Label),
+			UUID:       types.StringValue(device.UUID),
+			PartUUID:   types.StringValue(device.PartUUID),
+			Mountpoint: types.StringValue(device.Mountpoint),
+		})
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
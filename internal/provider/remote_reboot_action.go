@@ -0,0 +1,146 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/action"
+	"github.com/hashicorp/terraform-plugin-framework/action/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ action.Action = &RemoteRebootAction{}
+var _ action.ActionWithConfigure = &RemoteRebootAction{}
+
+// rebootPollInterval is the delay between reconnection attempts while a host reboots.
+const rebootPollInterval = 5 * time.Second
+
+// bootIDCommand prints a value that changes on every boot, on Linux and macOS.
+const bootIDCommand = "cat /proc/sys/kernel/random/boot_id 2>/dev/null || sysctl -n kern.boottime"
+
+func NewRemoteRebootAction() action.Action {
+	return &RemoteRebootAction{}
+}
+
+// RemoteRebootAction reboots a host and optionally waits until it accepts connections again.
+type RemoteRebootAction struct {
+	sshService *services.SSHService
+}
+
+// RemoteRebootActionModel describes the action data model.
+type RemoteRebootActionModel struct {
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Wait           types.Bool           `tfsdk:"wait"`
+	Timeout        types.String         `tfsdk:"timeout"`
+}
+
+func (a *RemoteRebootAction) Metadata(ctx context.Context, req action.MetadataRequest, resp *action.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_reboot"
+}
+
+func (a *RemoteRebootAction) Schema(ctx context.Context, req action.SchemaRequest, resp *action.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Reboots a host",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": actionHostConnectionSchema(),
+			"wait": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to wait until the host accepts SSH connections again. Defaults to `true`",
+			},
+			"timeout": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Maximum duration to wait for the host, e.g. `15m`. Defaults to `10m`",
+				Validators: []validator.String{
+					durationValidator{},
+				},
+			},
+		},
+	}
+}
+
+func (a *RemoteRebootAction) Configure(ctx context.Context, req action.ConfigureRequest, resp *action.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Action Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	a.sshService = sshService
+}
+
+func (a *RemoteRebootAction) Invoke(ctx context.Context, req action.InvokeRequest, resp *action.InvokeResponse) {
+	var data RemoteRebootActionModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Actions change the host, so their commands run after the pre-apply hook of the provider.
+	ctx = services.WithApply(ctx)
+	server := data.HostConnection.server()
+
+	bootID, err := runCommand(ctx, a.sshService, server, bootIDCommand)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the boot identifier of %s, got error: %s", server.Name, err))
+		return
+	}
+
+	// The reboot is delayed in the background so the command returns before sshd goes away.
+	_, err = runCommand(ctx, a.sshService, server, privilegedCommand(server, "nohup sh -c 'sleep 2; shutdown -r now' >/dev/null 2>&1 &"))
+	if err != nil {
+		resp.Diagnostics.AddError("Command Error", fmt.Sprintf("Unable to reboot %s, got error: %s", server.Name, err))
+		return
+	}
+
+	err = a.sshService.DropConnection(server)
+	if err != nil {
+		resp.Diagnostics.AddWarning("SSH Error", fmt.Sprintf("Unable to close the connection to %s: %s", server.Name, err))
+	}
+
+	if !data.Wait.IsNull() && !data.Wait.ValueBool() {
+		return
+	}
+
+	resp.SendProgress(action.InvokeProgressEvent{Message: fmt.Sprintf("Waiting for %s to come back", server.Name)})
+
+	ctx, cancel := withTimeout(ctx, data.Timeout, 10*time.Minute)
+	defer cancel()
+
+	// The host is back once it reports a new boot identifier, the old sshd may still answer
+	// for a moment after the reboot was requested.
+	for {
+		err = services.SleepContext(ctx, rebootPollInterval)
+		if err != nil {
+			resp.Diagnostics.AddError("Timeout Error", fmt.Sprintf("%s did not come back after the reboot: %s", server.Name, err))
+			return
+		}
+
+		current, err := runCommand(ctx, a.sshService, server, bootIDCommand)
+		if err == nil && current.Stdout != bootID.Stdout {
+			return
+		}
+
+		_ = a.sshService.DropConnection(server)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/action"
+	"github.com/hashicorp/terraform-plugin-framework/action/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ action.Action = &RemoteRestartServiceAction{}
+var _ action.ActionWithConfigure = &RemoteRestartServiceAction{}
+
+func NewRemoteRestartServiceAction() action.Action {
+	return &RemoteRestartServiceAction{}
+}
+
+// RemoteRestartServiceAction restarts a service on a host.
+type RemoteRestartServiceAction struct {
+	sshService *services.SSHService
+}
+
+// RemoteRestartServiceActionModel describes the action data model.
+type RemoteRestartServiceActionModel struct {
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Service        types.String         `tfsdk:"service"`
+}
+
+func (a *RemoteRestartServiceAction) Metadata(ctx context.Context, req action.MetadataRequest, resp *action.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_restart_service"
+}
+
+func (a *RemoteRestartServiceAction) Schema(ctx context.Context, req action.SchemaRequest, resp *action.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Restarts a systemd unit, an OpenRC service on BusyBox hosts or a launchd daemon on macOS",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": actionHostConnectionSchema(),
+			"service": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Name of the service, the launchd label on macOS",
+			},
+		},
+	}
+}
+
+func (a *RemoteRestartServiceAction) Configure(ctx context.Context, req action.ConfigureRequest, resp *action.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Action Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	a.sshService = sshService
+}
+
+func (a *RemoteRestartServiceAction) Invoke(ctx context.Context, req action.InvokeRequest, resp *action.InvokeResponse) {
+	var data RemoteRestartServiceActionModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Actions change the host, so their commands run after the pre-apply hook of the provider.
+	ctx = services.WithApply(ctx)
+	server := data.HostConnection.server()
+
+	platform, err := a.sshService.DetectPlatform(ctx, server)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to restart %s, got error: %s", data.Service.ValueString(), err))
+		return
+	}
+
+	_, err = runCommand(ctx, a.sshService, server, privilegedCommand(server, platform.RestartServiceCommand(data.Service.ValueString())))
+	if err != nil {
+		resp.Diagnostics.AddError("Command Error", fmt.Sprintf("Unable to restart %s, got error: %s", data.Service.ValueString(), err))
+		return
+	}
+}
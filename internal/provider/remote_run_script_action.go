@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/action"
+	"github.com/hashicorp/terraform-plugin-framework/action/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ action.Action = &RemoteRunScriptAction{}
+var _ action.ActionWithConfigure = &RemoteRunScriptAction{}
+
+func NewRemoteRunScriptAction() action.Action {
+	return &RemoteRunScriptAction{}
+}
+
+// RemoteRunScriptAction runs a shell script on a host.
+type RemoteRunScriptAction struct {
+	sshService *services.SSHService
+}
+
+// RemoteRunScriptActionModel describes the action data model.
+type RemoteRunScriptActionModel struct {
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Script         types.String         `tfsdk:"script"`
+	Privileged     types.Bool           `tfsdk:"privileged"`
+}
+
+func (a *RemoteRunScriptAction) Metadata(ctx context.Context, req action.MetadataRequest, resp *action.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_run_script"
+}
+
+func (a *RemoteRunScriptAction) Schema(ctx context.Context, req action.SchemaRequest, resp *action.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Runs a shell script on a host, failing when it exits non-zero",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": actionHostConnectionSchema(),
+			"script": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Shell script to run with `sh`",
+			},
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to run the script as root",
+			},
+		},
+	}
+}
+
+func (a *RemoteRunScriptAction) Configure(ctx context.Context, req action.ConfigureRequest, resp *action.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Action Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	a.sshService = sshService
+}
+
+func (a *RemoteRunScriptAction) Invoke(ctx context.Context, req action.InvokeRequest, resp *action.InvokeResponse) {
+	var data RemoteRunScriptActionModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Actions change the host, so their commands run after the pre-apply hook of the provider.
+	ctx = services.WithApply(ctx)
+	server := data.HostConnection.server()
+
+	command := "sh -c " + services.ShellQuote(data.Script.ValueString())
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	result, err := runCommand(ctx, a.sshService, server, command)
+	if err != nil {
+		resp.Diagnostics.AddError("Command Error", fmt.Sprintf("Script failed on %s, got error: %s", data.HostConnection.Host.ValueString(), err))
+		return
+	}
+
+	if output := strings.TrimSpace(result.Stdout); output != "" {
+		resp.SendProgress(action.InvokeProgressEvent{Message: output})
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteSnapResource{}
+
+func NewRemoteSnapResource() resource.Resource {
+	return &RemoteSnapResource{}
+}
+
+// RemoteSnapResource installs a snap from the snap store.
+type RemoteSnapResource struct {
+	sshService *services.SSHService
+}
+
+// RemoteSnapResourceModel describes the resource data model.
+type RemoteSnapResourceModel struct {
+	Id             types.String         `tfsdk:"id"`
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Name           types.String         `tfsdk:"name"`
+	Channel        types.String         `tfsdk:"channel"`
+	Classic        types.Bool           `tfsdk:"classic"`
+	Version        types.String         `tfsdk:"version"`
+	Revision       types.String         `tfsdk:"revision"`
+	Timeouts       *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemoteSnapResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_snap"
+}
+
+func (r *RemoteSnapResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Installs a snap from the snap store with `snap install`. Changing `channel` switches the snap " +
+			"to the new channel with `snap refresh`. Destroying the resource removes the snap without keeping a snapshot of its data.",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Name of the snap, e.g. `lxd`",
+				Validators: []validator.String{
+					stringMatches(services.SnapNameRegexp, "must be a snap name made of lowercase letters, digits and hyphens"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"channel": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Channel the snap follows as `[<track>/]<risk>[/<branch>]`, e.g. `5.21/stable` or `edge`. Follows `latest/stable` when unset",
+				Validators: []validator.String{
+					stringMatches(services.SnapChannelRegexp, "must be a channel such as 5.21/stable"),
+				},
+			},
+			"classic": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether the snap is installed with classic confinement, giving it full access to the host as the `aws-cli` or `certbot` snaps need. Defaults to `false`",
+				Default:             booldefault.StaticBool(false),
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier of the snap, made of the host and its name",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"version": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Version of the installed snap",
+			},
+			"revision": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Store revision of the installed snap",
+			},
+		},
+	}
+}
+
+func (r *RemoteSnapResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+// install installs the snap, or switches it to the planned channel.
+func (r *RemoteSnapResource) install(ctx context.Context, data *RemoteSnapResourceModel) error {
+	server := data.HostConnection.server()
+
+	err := r.sshService.Preflight(ctx, server, []string{services.PrivilegeTool, "snap"})
+	if err != nil {
+		return err
+	}
+
+	command := services.SnapInstallCommand(data.Name.ValueString(), data.Channel.ValueString(), data.Classic.ValueBool())
+	_, err = runCommand(ctx, r.sshService, server, privilegedCommand(server, command))
+	if err != nil {
+		return err
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-snap-%s", data.HostConnection.hostID(), data.Name.ValueString()))
+
+	exists, err := r.refresh(ctx, data)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("snap %s is not installed after its installation", data.Name.ValueString())
+	}
+
+	return nil
+}
+
+// refresh reads the snap back from the host and reports whether it is still installed.
+func (r *RemoteSnapResource) refresh(ctx context.Context, data *RemoteSnapResourceModel) (bool, error) {
+	result, err := runCommand(ctx, r.sshService, data.HostConnection.server(), services.SnapListCommand(data.Name.ValueString()))
+	if err != nil {
+		return false, err
+	}
+
+	snap, err := services.ParseSnapList(result.Stdout, data.Name.ValueString())
+	if err != nil || snap == nil {
+		return false, err
+	}
+
+	// snapd reports the full channel, an equivalent short one in the configuration is kept.
+	planned := services.NormalizeSnapChannel(data.Channel.ValueString())
+	if data.Channel.IsNull() {
+		planned = "latest/stable"
+	}
+	if snap.Channel != planned {
+		data.Channel = optionalString(snap.Channel)
+	}
+
+	data.Classic = types.BoolValue(snap.Classic)
+	data.Version = types.StringValue(snap.Version)
+	data.Revision = types.StringValue(snap.Revision)
+
+	return true, nil
+}
+
+func (r *RemoteSnapResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteSnapResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	err := r.install(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to install snap %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteSnapResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteSnapResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	exists, err := r.refresh(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read snap %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+
+	if !exists {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteSnapResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data RemoteSnapResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	err := r.install(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to refresh snap %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteSnapResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteSnapResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	server := data.HostConnection.server()
+
+	_, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, services.SnapRemoveCommand(data.Name.ValueString())))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to remove snap %s, got error: %s", data.Name.ValueString(), err))
+		return
+	}
+}
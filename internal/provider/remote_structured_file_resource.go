@@ -0,0 +1,410 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteStructuredFileResource{}
+var _ resource.ResourceWithModifyPlan = &RemoteStructuredFileResource{}
+
+func NewRemoteJSONFileResource() resource.Resource {
+	return &RemoteStructuredFileResource{format: "json"}
+}
+
+func NewRemoteYAMLFileResource() resource.Resource {
+	return &RemoteStructuredFileResource{format: "yaml"}
+}
+
+// RemoteStructuredFileResource writes a Terraform value as a JSON or YAML document on a host.
+type RemoteStructuredFileResource struct {
+	sshService *services.SSHService
+	format     string
+}
+
+// RemoteStructuredFileResourceModel describes the resource data model.
+type RemoteStructuredFileResourceModel struct {
+	Id             types.String         `tfsdk:"id"`
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Path           RemotePath           `tfsdk:"path"`
+	Data           types.Dynamic        `tfsdk:"data"`
+	Mode           FileMode             `tfsdk:"mode"`
+	Privileged     types.Bool           `tfsdk:"privileged"`
+	Content        types.String         `tfsdk:"content"`
+	Timeouts       *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+// errUnknownValue is returned when a value cannot be converted before it is known.
+var errUnknownValue = errors.New("value is not known yet")
+
+func (r *RemoteStructuredFileResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_" + r.format + "_file"
+}
+
+func (r *RemoteStructuredFileResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	format := strings.ToUpper(r.format)
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "A " + format + " file on a host written from `data`, with keys sorted and two space indentation. " +
+			"The file is compared with `data` by value rather than by text, so reordered keys, reformatting or comments " +
+			"added on the host do not show as drift, only changed values do.",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"path": schema.StringAttribute{
+				CustomType:          RemotePathType{},
+				Required:            true,
+				MarkdownDescription: "Path to the file on the remote host",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"data": schema.DynamicAttribute{
+				Required:            true,
+				MarkdownDescription: "Value written to the file, e.g. `{ server = { port = 8080, hosts = [\"a\", \"b\"] } }`. Objects and maps become " + format + " objects, lists, tuples and sets become arrays",
+			},
+			"mode": schema.StringAttribute{
+				CustomType:          FileModeType{},
+				Optional:            true,
+				MarkdownDescription: "Octal mode of the file, e.g. `0640`. Defaults to the provider `default_file_mode`",
+			},
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to write the file as root",
+				Default:             booldefault.StaticBool(false),
+			},
+			"content": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Rendered " + format + " document. It holds the file found on the host when its values differ from `data`",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier of the file, made of the host and the path",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *RemoteStructuredFileResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+// ModifyPlan renders data into content, so changed values show in the plan as a diff of the
+// document.
+func (r *RemoteStructuredFileResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan RemoteStructuredFileResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rendered, err := r.render(&plan)
+	switch {
+	case errors.Is(err, errUnknownValue):
+		plan.Content = types.StringUnknown()
+	case err != nil:
+		resp.Diagnostics.AddAttributeError(path.Root("data"), "Invalid Data", fmt.Sprintf("Unable to render the %s document, got error: %s", r.format, err))
+		return
+	default:
+		plan.Content = types.StringValue(rendered)
+	}
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+// render returns the document written for the data of model.
+func (r *RemoteStructuredFileResource) render(data *RemoteStructuredFileResourceModel) (string, error) {
+	value, err := structuredValue(data.Data)
+	if err != nil {
+		return "", err
+	}
+
+	return services.RenderStructured(r.format, value)
+}
+
+// structuredValue converts a Terraform value to a structured document value.
+func structuredValue(value attr.Value) (any, error) {
+	if value == nil || value.IsNull() {
+		return nil, nil
+	}
+	if value.IsUnknown() {
+		return nil, errUnknownValue
+	}
+
+	var elements []attr.Value
+	switch value := value.(type) {
+	case types.Dynamic:
+		if value.IsUnderlyingValueUnknown() {
+			return nil, errUnknownValue
+		}
+
+		return structuredValue(value.UnderlyingValue())
+	case types.String:
+		return value.ValueString(), nil
+	case types.Bool:
+		return value.ValueBool(), nil
+	case types.Number:
+		number := value.ValueBigFloat()
+		if number.IsInt() {
+			integer, _ := number.Int(nil)
+			return json.Number(integer.String()), nil
+		}
+
+		return json.Number(number.Text('g', -1)), nil
+	case types.Tuple:
+		elements = value.Elements()
+	case types.List:
+		elements = value.Elements()
+	case types.Set:
+		elements = value.Elements()
+	default:
+		attributes, ok := objectElements(value)
+		if !ok {
+			return nil, fmt.Errorf("unsupported value of type %s", value.Type(context.Background()))
+		}
+
+		object := make(map[string]any, len(attributes))
+		for name, attribute := range attributes {
+			converted, err := structuredValue(attribute)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			object[name] = converted
+		}
+
+		return object, nil
+	}
+
+	items := make([]any, 0, len(elements))
+	for i, element := range elements {
+		converted, err := structuredValue(element)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		items = append(items, converted)
+	}
+
+	return items, nil
+}
+
+// write renders data and writes it to the file.
+func (r *RemoteStructuredFileResource) write(ctx context.Context, data *RemoteStructuredFileResourceModel) error {
+	rendered, err := r.render(data)
+	if err != nil {
+		return err
+	}
+
+	server := data.HostConnection.server()
+	command := services.WriteFileCommand(data.Path.ValueString(), []byte(rendered), r.sshService.FileMode(data.Mode.ValueString()))
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	_, err = runCommand(ctx, r.sshService, server, command)
+	if err != nil {
+		return err
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s:%s", data.HostConnection.hostID(), data.Path.ValueString()))
+	data.Content = types.StringValue(rendered)
+
+	return nil
+}
+
+// refresh compares the file with data by value, and reports whether it exists. content keeps
+// the rendering of data while the values match and holds the file found on the host otherwise,
+// which plans writing it again.
+func (r *RemoteStructuredFileResource) refresh(ctx context.Context, data *RemoteStructuredFileResourceModel) (bool, error) {
+	server := data.HostConnection.server()
+	filePath := data.Path.ValueString()
+
+	command := fmt.Sprintf("if [ -f %s ]; then %s; else echo missing; fi",
+		services.ShellQuote(filePath), services.ReadFilesCommand([]string{filePath}))
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	result, err := runCommand(services.WithFileContent(ctx), r.sshService, server, command)
+	if err != nil {
+		return false, err
+	}
+
+	lines, err := services.ParseFileLines(result.Stdout, 1)
+	if err != nil {
+		return false, err
+	}
+	if lines[0] == "missing" {
+		return false, nil
+	}
+
+	content, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return false, fmt.Errorf("unable to decode the content of %s: %w", filePath, err)
+	}
+
+	rendered, err := r.render(data)
+	if err != nil {
+		return false, err
+	}
+
+	// Unreadable files are treated as drift and written again.
+	remote, err := services.ParseStructured(r.format, string(content))
+	expected, _ := structuredValue(data.Data)
+	if err == nil && services.StructuredEqual(expected, remote) {
+		data.Content = types.StringValue(rendered)
+	} else {
+		data.Content = types.StringValue(string(content))
+	}
+
+	return true, nil
+}
+
+func (r *RemoteStructuredFileResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteStructuredFileResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	err := r.write(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to write %s, got error: %s", data.Path.ValueString(), err))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteStructuredFileResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteStructuredFileResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	exists, err := r.refresh(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read %s, got error: %s", data.Path.ValueString(), err))
+		return
+	}
+
+	if !exists {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteStructuredFileResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data RemoteStructuredFileResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	err := r.write(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to write %s, got error: %s", data.Path.ValueString(), err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteStructuredFileResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteStructuredFileResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	server := data.HostConnection.server()
+	command := "rm -f -- " + services.ShellQuote(data.Path.ValueString())
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	_, err := runCommand(ctx, r.sshService, server, command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to remove %s, got error: %s", data.Path.ValueString(), err))
+		return
+	}
+}
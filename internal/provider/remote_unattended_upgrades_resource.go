@@ -0,0 +1,394 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteUnattendedUpgradesResource{}
+
+func NewRemoteUnattendedUpgradesResource() resource.Resource {
+	return &RemoteUnattendedUpgradesResource{}
+}
+
+// RemoteUnattendedUpgradesResource configures the automatic upgrades of a host with
+// unattended-upgrades on Debian hosts and dnf-automatic on Red Hat hosts.
+type RemoteUnattendedUpgradesResource struct {
+	sshService *services.SSHService
+}
+
+// RemoteUnattendedUpgradesResourceModel describes the resource data model.
+type RemoteUnattendedUpgradesResourceModel struct {
+	Id              types.String         `tfsdk:"id"`
+	HostConnection  *HostConnectionModel `tfsdk:"host_connection"`
+	SecurityOnly    types.Bool           `tfsdk:"security_only"`
+	Origins         []types.String       `tfsdk:"origins"`
+	Blacklist       []types.String       `tfsdk:"blacklist"`
+	AutomaticReboot types.Bool           `tfsdk:"automatic_reboot"`
+	RebootTime      types.String         `tfsdk:"reboot_time"`
+	Mail            types.String         `tfsdk:"mail"`
+	PackageManager  types.String         `tfsdk:"package_manager"`
+	Path            types.String         `tfsdk:"path"`
+	Timeouts        *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemoteUnattendedUpgradesResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_unattended_upgrades"
+}
+
+func (r *RemoteUnattendedUpgradesResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Configures the automatic upgrades of a host: `unattended-upgrades` on Debian and Ubuntu hosts, " +
+			"written to `" + services.AptUnattendedUpgradesPath + "`, and `dnf-automatic` on Red Hat hosts, written to `" +
+			services.DnfAutomaticPath + "` with its timer enabled. The tool must be installed. The configuration is " +
+			"validated on the host before it replaces the previous one.",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"security_only": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether only security updates are applied. Not used by `unattended-upgrades` when `origins` is set. Defaults to `true`",
+				Default:             booldefault.StaticBool(true),
+			},
+			"origins": schema.ListAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "`unattended-upgrades` origin patterns replacing the distribution ones, e.g. `[\"origin=${distro_id},codename=${distro_codename}-security\", \"origin=Docker\"]`. Ignored by `dnf-automatic`",
+			},
+			"blacklist": schema.ListAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Packages never upgraded automatically. `unattended-upgrades` reads them as regular expressions, `dnf-automatic` as globs",
+			},
+			"automatic_reboot": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to reboot the host when an upgrade requires it. Defaults to `false`",
+				Default:             booldefault.StaticBool(false),
+			},
+			"reboot_time": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Time of day of the automatic reboots as `HH:MM`, e.g. `02:00`. The host reboots right after the upgrade when unset",
+				Validators: []validator.String{
+					stringMatches(services.RebootTimeRegexp, "must be a time of day such as 02:00"),
+				},
+			},
+			"mail": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Address the upgrade reports are mailed to through the local mail transfer agent",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier of the configuration, made of the host",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"package_manager": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Package manager of the host: `apt` or `dnf`",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"path": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Path of the configuration file written on the host",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *RemoteUnattendedUpgradesResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+// config returns the configuration described by data.
+func (data *RemoteUnattendedUpgradesResourceModel) config() services.UnattendedUpgrades {
+	config := services.UnattendedUpgrades{
+		SecurityOnly:    data.SecurityOnly.ValueBool(),
+		AutomaticReboot: data.AutomaticReboot.ValueBool(),
+		RebootTime:      data.RebootTime.ValueString(),
+		Mail:            data.Mail.ValueString(),
+	}
+	for _, origin := range data.Origins {
+		config.Origins = append(config.Origins, origin.ValueString())
+	}
+	for _, name := range data.Blacklist {
+		config.Blacklist = append(config.Blacklist, name.ValueString())
+	}
+
+	return config
+}
+
+// setConfig stores the settings of config found on a host with manager in data.
+func (data *RemoteUnattendedUpgradesResourceModel) setConfig(config services.UnattendedUpgrades, manager string) {
+	if manager == "dnf" || len(config.Origins) == 0 {
+		data.SecurityOnly = types.BoolValue(config.SecurityOnly)
+	}
+	if manager == "apt" {
+		data.Origins = nil
+		for _, origin := range config.Origins {
+			data.Origins = append(data.Origins, types.StringValue(origin))
+		}
+	}
+
+	data.Blacklist = nil
+	for _, name := range config.Blacklist {
+		data.Blacklist = append(data.Blacklist, types.StringValue(name))
+	}
+
+	data.AutomaticReboot = types.BoolValue(config.AutomaticReboot)
+	data.RebootTime = optionalString(config.RebootTime)
+	data.Mail = optionalString(config.Mail)
+}
+
+// optionalString returns value, or null when it is empty.
+func optionalString(value string) types.String {
+	if value == "" {
+		return types.StringNull()
+	}
+
+	return types.StringValue(value)
+}
+
+// apply writes the configuration to the host, replacing the previous one only once the tool
+// accepted it.
+func (r *RemoteUnattendedUpgradesResource) apply(ctx context.Context, data *RemoteUnattendedUpgradesResourceModel) error {
+	server := data.HostConnection.server()
+
+	result, err := runCommand(ctx, r.sshService, server, services.UnattendedUpgradesManagerCommand)
+	if err != nil {
+		return err
+	}
+
+	manager := strings.TrimSpace(result.Stdout)
+	if i := strings.LastIndex(manager, "\n"); i >= 0 {
+		manager = strings.TrimSpace(manager[i+1:])
+	}
+
+	var path, content string
+	var tools []string
+	switch manager {
+	case "apt":
+		path = services.AptUnattendedUpgradesPath
+		content, err = data.config().RenderApt()
+		tools = []string{services.PrivilegeTool, "unattended-upgrade", "apt-config"}
+	case "dnf":
+		path = services.DnfAutomaticPath
+		content, err = data.config().RenderDnf()
+		tools = []string{services.PrivilegeTool, "dnf-automatic", "python3", "systemctl"}
+	default:
+		return fmt.Errorf("the host has neither apt nor dnf")
+	}
+	if err != nil {
+		return err
+	}
+
+	err = r.sshService.Preflight(ctx, server, tools)
+	if err != nil {
+		return err
+	}
+
+	newPath := path + ".remote-host.new"
+	command := fmt.Sprintf(
+		"%s && { %s || { rm -f %s; echo 'the configuration was rejected' >&2; exit 1; }; } && mv -f %s %s",
+		services.WriteFileCommand(newPath, []byte(content), "0644"),
+		services.ValidateConfigCommand(manager, newPath),
+		services.ShellQuote(newPath),
+		services.ShellQuote(newPath),
+		services.ShellQuote(path),
+	)
+	if manager == "dnf" {
+		command += " && systemctl enable --now " + services.DnfAutomaticTimer
+	}
+
+	_, err = runCommand(ctx, r.sshService, server, privilegedCommand(server, command))
+	if err != nil {
+		return err
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-unattended-upgrades", data.HostConnection.hostID()))
+	data.PackageManager = types.StringValue(manager)
+	data.Path = types.StringValue(path)
+
+	return nil
+}
+
+// refresh reads the configuration back from the host and reports whether it still exists.
+func (r *RemoteUnattendedUpgradesResource) refresh(ctx context.Context, data *RemoteUnattendedUpgradesResourceModel) (bool, error) {
+	server := data.HostConnection.server()
+	path := services.ShellQuote(data.Path.ValueString())
+
+	result, err := runCommand(services.WithFileContent(ctx), r.sshService, server, fmt.Sprintf("if [ -f %s ]; then echo present; cat %s; else echo missing; fi", path, path))
+	if err != nil {
+		return false, err
+	}
+
+	lines := strings.Split(strings.ReplaceAll(result.Stdout, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case "missing":
+			return false, nil
+		case "present":
+			content := strings.Join(lines[i+1:], "\n")
+
+			manager := data.PackageManager.ValueString()
+			found := services.ParseAptUnattendedUpgrades(content)
+			if manager == "dnf" {
+				found = services.ParseDnfAutomatic(content)
+			}
+
+			if !found.Equal(data.config().Effective(manager)) {
+				data.setConfig(found, manager)
+			}
+
+			return true, nil
+		}
+	}
+
+	return false, fmt.Errorf("unexpected output: %s", result.Stdout)
+}
+
+func (r *RemoteUnattendedUpgradesResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteUnattendedUpgradesResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	err := r.apply(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to configure the automatic upgrades, got error: %s", err))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteUnattendedUpgradesResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteUnattendedUpgradesResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	exists, err := r.refresh(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the automatic upgrades configuration, got error: %s", err))
+		return
+	}
+
+	if !exists {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteUnattendedUpgradesResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data RemoteUnattendedUpgradesResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	err := r.apply(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to configure the automatic upgrades, got error: %s", err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteUnattendedUpgradesResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteUnattendedUpgradesResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	// The Debian package defaults apply again once the override is gone. dnf-automatic has no
+	// defaults to go back to, its timer is stopped instead.
+	command := "rm -f " + services.ShellQuote(data.Path.ValueString())
+	if data.PackageManager.ValueString() == "dnf" {
+		command = "systemctl disable --now " + services.DnfAutomaticTimer
+	}
+
+	server := data.HostConnection.server()
+
+	_, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, command))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to remove the automatic upgrades configuration, got error: %s", err))
+		return
+	}
+}
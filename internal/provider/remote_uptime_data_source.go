@@ -0,0 +1,152 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteUptimeDataSource{}
+
+func NewRemoteUptimeDataSource() datasource.DataSource {
+	return &RemoteUptimeDataSource{}
+}
+
+// RemoteUptimeDataSource reports the uptime, load and pending reboot of a host.
+type RemoteUptimeDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemoteUptimeDataSourceModel describes the data source data model.
+type RemoteUptimeDataSourceModel struct {
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Privileged     types.Bool           `tfsdk:"privileged"`
+	UptimeSeconds  types.Int64          `tfsdk:"uptime_seconds"`
+	BootTime       types.String         `tfsdk:"boot_time"`
+	Load1          types.Float64        `tfsdk:"load_1"`
+	Load5          types.Float64        `tfsdk:"load_5"`
+	Load15         types.Float64        `tfsdk:"load_15"`
+	RebootRequired types.Bool           `tfsdk:"reboot_required"`
+	RebootPackages []types.String       `tfsdk:"reboot_packages"`
+}
+
+func (d *RemoteUptimeDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_uptime"
+}
+
+func (d *RemoteUptimeDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Uptime, load averages and pending reboot indicators of a host, e.g. to only create a " +
+			"`remote_host_reboot` action when `reboot_required` is set",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": dataSourceHostConnectionSchema(),
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to run the checks as root, which some versions of `needs-restarting` require",
+			},
+			"uptime_seconds": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "Seconds since the host booted",
+			},
+			"boot_time": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "RFC 3339 time the host booted, derived from the uptime",
+			},
+			"load_1": schema.Float64Attribute{
+				Computed:            true,
+				MarkdownDescription: "Load average over the last minute",
+			},
+			"load_5": schema.Float64Attribute{
+				Computed:            true,
+				MarkdownDescription: "Load average over the last 5 minutes",
+			},
+			"load_15": schema.Float64Attribute{
+				Computed:            true,
+				MarkdownDescription: "Load average over the last 15 minutes",
+			},
+			"reboot_required": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether the host waits for a reboot, from `/var/run/reboot-required` on Debian and `needs-restarting -r` on Red Hat hosts",
+			},
+			"reboot_packages": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Packages asking for the reboot, from `/var/run/reboot-required.pkgs` on Debian hosts",
+			},
+		},
+	}
+}
+
+func (d *RemoteUptimeDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+func (d *RemoteUptimeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteUptimeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.HostConnection.server()
+
+	command := services.UptimeCommand
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	result, err := runCommand(ctx, d.sshService, server, command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the uptime, got error: %s", err))
+		return
+	}
+
+	uptime, err := services.ParseUptime(result.Stdout)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to parse the uptime, got error: %s", err))
+		return
+	}
+
+	data.UptimeSeconds = types.Int64Value(uptime.Seconds)
+	data.BootTime = types.StringValue(time.Now().Add(-time.Duration(uptime.Seconds) * time.Second).UTC().Truncate(time.Second).Format(time.RFC3339))
+	data.Load1 = types.Float64Value(uptime.Load[0])
+	data.Load5 = types.Float64Value(uptime.Load[1])
+	data.Load15 = types.Float64Value(uptime.Load[2])
+	data.RebootRequired = types.BoolValue(uptime.RebootRequired)
+	data.RebootPackages = make([]types.String, 0, len(uptime.RebootPackages))
+	for _, pkg := range uptime.RebootPackages {
+		data.RebootPackages = append(data.RebootPackages, types.StringValue(pkg))
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
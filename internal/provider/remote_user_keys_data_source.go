@@ -0,0 +1,209 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteUserKeysDataSource{}
+
+func NewRemoteUserKeysDataSource() datasource.DataSource {
+	return &RemoteUserKeysDataSource{}
+}
+
+// RemoteUserKeysDataSource lists the authorized keys of a user of a host.
+type RemoteUserKeysDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemoteUserKeysDataSourceModel describes the data source data model.
+type RemoteUserKeysDataSourceModel struct {
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	User           types.String         `tfsdk:"user"`
+	Files          []types.String       `tfsdk:"files"`
+	Privileged     types.Bool           `tfsdk:"privileged"`
+	Keys           []RemoteUserKeyModel `tfsdk:"keys"`
+	InvalidLines   []types.String       `tfsdk:"invalid_lines"`
+}
+
+// RemoteUserKeyModel describes an entry of an authorized_keys file.
+type RemoteUserKeyModel struct {
+	File        types.String   `tfsdk:"file"`
+	Line        types.Int64    `tfsdk:"line"`
+	Type        types.String   `tfsdk:"type"`
+	PublicKey   types.String   `tfsdk:"public_key"`
+	Comment     types.String   `tfsdk:"comment"`
+	Options     []types.String `tfsdk:"options"`
+	Fingerprint types.String   `tfsdk:"fingerprint"`
+}
+
+func (d *RemoteUserKeysDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_user_keys"
+}
+
+func (d *RemoteUserKeysDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Entries of the authorized_keys files of a user, with their options and fingerprints, so " +
+			"access reviews can reconcile who can log in to which host",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": dataSourceHostConnectionSchema(),
+			"user": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "User whose authorized keys are read",
+			},
+			"files": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				MarkdownDescription: "Authorized keys files to read, as in the `AuthorizedKeysFile` option of sshd: relative " +
+					"to the home directory of the user, with the `%h`, `%u` and `%%` tokens expanded. Defaults to " +
+					"`.ssh/authorized_keys` and `.ssh/authorized_keys2`. Missing files are skipped",
+			},
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to read the files as root, for users other than the connection user",
+			},
+			"keys": schema.ListNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "Keys of the files, in the order of the files then of their lines",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"file": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Path of the file holding the key",
+						},
+						"line": schema.Int64Attribute{
+							Computed:            true,
+							MarkdownDescription: "Line of the key in the file, starting at 1",
+						},
+						"type": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Type of the key, e.g. `ssh-ed25519`",
+						},
+						"public_key": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Public key in authorized_keys format, without its options and comment",
+						},
+						"comment": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Comment of the key, usually naming its owner",
+						},
+						"options": schema.ListAttribute{
+							ElementType:         types.StringType,
+							Computed:            true,
+							MarkdownDescription: "Options restricting the key, e.g. `from=\"10.0.0.0/8\"` or `no-pty`",
+						},
+						"fingerprint": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "SHA256 fingerprint of the key as printed by `ssh-keygen -l`",
+						},
+					},
+				},
+			},
+			"invalid_lines": schema.ListAttribute{
+				ElementType:         types.StringType,
+				Computed:            true,
+				MarkdownDescription: "Lines that are not valid keys, as `file:line`. sshd ignores them",
+			},
+		},
+	}
+}
+
+func (d *RemoteUserKeysDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+func (d *RemoteUserKeysDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteUserKeysDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.HostConnection.server()
+
+	platform, err := d.sshService.DetectPlatform(ctx, server)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to detect the platform, got error: %s", err))
+		return
+	}
+
+	files := services.DefaultAuthorizedKeysFiles
+	if data.Files != nil {
+		files = make([]string, 0, len(data.Files))
+		for _, file := range data.Files {
+			files = append(files, file.ValueString())
+		}
+	}
+
+	command := platform.AuthorizedKeysCommand(data.User.ValueString(), files)
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	result, err := runCommand(services.WithFileContent(ctx), d.sshService, server, command)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the authorized keys, got error: %s", err))
+		return
+	}
+
+	keys, invalid, err := services.ParseAuthorizedKeys(result.Stdout)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read the authorized keys, got error: %s", err))
+		return
+	}
+
+	data.Keys = []RemoteUserKeyModel{}
+	for _, key := range keys {
+		options := []types.String{}
+		for _, option := range key.Options {
+			options = append(options, types.StringValue(option))
+		}
+
+		data.Keys = append(data.Keys, RemoteUserKeyModel{
+			File:        types.StringValue(key.File),
+			Line:        types.Int64Value(int64(key.Line)),
+			Type:        types.StringValue(key.Type),
+			PublicKey:   types.StringValue(key.PublicKey),
+			Comment:     types.StringValue(key.Comment),
+			Options:     options,
+			Fingerprint: types.StringValue(key.Fingerprint),
+		})
+	}
+
+	data.InvalidLines = []types.String{}
+	for _, line := range invalid {
+		data.InvalidLines = append(data.InvalidLines, types.StringValue(line))
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
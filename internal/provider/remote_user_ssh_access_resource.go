@@ -0,0 +1,396 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"remote-provider/internal/provider/services"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteUserSSHAccessResource{}
+var _ resource.ResourceWithMoveState = &RemoteUserSSHAccessResource{}
+
+// userNameRegexp matches portable user names, which are also safe to use unquoted in paths.
+var userNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_.-]{0,31}$`)
+
+// sudoersSeparator separates the authorized keys from the sudoers entry in the read output.
+const sudoersSeparator = "--- remote-host sudoers ---"
+
+func NewRemoteUserSSHAccessResource() resource.Resource {
+	return &RemoteUserSSHAccessResource{}
+}
+
+// NewLegacyRemoteUserSSHAccessResource registers the resource under its deprecated unprefixed name.
+func NewLegacyRemoteUserSSHAccessResource() resource.Resource {
+	return &RemoteUserSSHAccessResource{legacyTypeName: "remote_user_ssh_access"}
+}
+
+// RemoteUserSSHAccessResource grants a user SSH access to a host, creating the user when requested.
+type RemoteUserSSHAccessResource struct {
+	sshService     *services.SSHService
+	legacyTypeName string
+}
+
+// RemoteUserSSHAccessResourceModel describes the resource data model.
+type RemoteUserSSHAccessResourceModel struct {
+	Id             types.String         `tfsdk:"id"`
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	User           types.String         `tfsdk:"user"`
+	CreateUser     types.Bool           `tfsdk:"create_user"`
+	AuthorizedKeys []types.String       `tfsdk:"authorized_keys"`
+	SudoersRule    types.String         `tfsdk:"sudoers_rule"`
+	Timeouts       *TimeoutsModel       `tfsdk:"timeouts"`
+}
+
+func (r *RemoteUserSSHAccessResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_user_ssh_access"
+	if r.legacyTypeName != "" {
+		resp.TypeName = r.legacyTypeName
+	}
+}
+
+func (r *RemoteUserSSHAccessResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	resp.Schema = schema.Schema{
+		DeprecationMessage: legacyDeprecationMessage(r.legacyTypeName, "remote_host_user_ssh_access"),
+
+		MarkdownDescription: "Bootstraps SSH access for a user: creates the user when requested, its `.ssh` directory, " +
+			"its `authorized_keys` file and an optional sudoers entry",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"user": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Name of the user",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringMatches(userNameRegexp, "must be a lower case user name of at most 32 characters"),
+				},
+			},
+			"create_user": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to create the user when it does not exist. The user is deleted with the resource when enabled",
+				Default:             booldefault.StaticBool(false),
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"authorized_keys": schema.ListAttribute{
+				Required:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Public keys written to the `authorized_keys` file of the user, replacing its content",
+			},
+			"sudoers_rule": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Sudoers rule granted to the user, e.g. `ALL=(ALL) NOPASSWD: ALL`. It is checked with `visudo` before being installed",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Host and user the access is granted on",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *RemoteUserSSHAccessResource) MoveState(ctx context.Context) []resource.StateMover {
+	return renamedStateMovers[RemoteUserSSHAccessResourceModel](ctx, &RemoteUserSSHAccessResource{}, "remote_user_ssh_access")
+}
+
+func (r *RemoteUserSSHAccessResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+// sudoersPath returns the drop-in file of the user, sudo ignores drop-ins whose name contains a dot.
+func (data *RemoteUserSSHAccessResourceModel) sudoersPath() string {
+	return "/etc/sudoers.d/remote-host-" + strings.ReplaceAll(data.User.ValueString(), ".", "_")
+}
+
+func (data *RemoteUserSSHAccessResourceModel) authorizedKeys() string {
+	var keys []string
+	for _, key := range data.AuthorizedKeys {
+		keys = append(keys, strings.TrimSpace(key.ValueString()))
+	}
+
+	return strings.Join(keys, "\n") + "\n"
+}
+
+// applyScript creates the user if requested, then replaces its authorized keys and sudoers entry.
+func (data *RemoteUserSSHAccessResourceModel) applyScript(platform services.Platform) string {
+	user := services.ShellQuote(data.User.ValueString())
+	sudoers := services.ShellQuote(data.sudoersPath())
+	sudoersTmp := services.ShellQuote(data.sudoersPath() + ".remote-host.tmp")
+
+	script := []string{"set -e"}
+	if data.CreateUser.ValueBool() {
+		script = append(script, platform.CreateLoginUserCommand(data.User.ValueString()))
+	}
+
+	script = append(script,
+		fmt.Sprintf(`home=$(%s)`, platform.HomeDirectoryCommand(data.User.ValueString())),
+		fmt.Sprintf(`[ -n "$home" ] || { echo "user %s has no home directory" >&2; exit 1; }`, data.User.ValueString()),
+		fmt.Sprintf(`group=$(id -gn %s)`, user),
+		fmt.Sprintf(`install -d -m 700 -o %s -g "$group" "$home/.ssh"`, user),
+		fmt.Sprintf(`printf '%%s' %s | base64 -d > "$home/.ssh/authorized_keys.remote-host.tmp"`, services.ShellQuote(base64.StdEncoding.EncodeToString([]byte(data.authorizedKeys())))),
+		fmt.Sprintf(`chown %s:"$group" "$home/.ssh/authorized_keys.remote-host.tmp"`, user),
+		`chmod 600 "$home/.ssh/authorized_keys.remote-host.tmp"`,
+		`mv -f "$home/.ssh/authorized_keys.remote-host.tmp" "$home/.ssh/authorized_keys"`,
+	)
+
+	if data.SudoersRule.IsNull() {
+		script = append(script, fmt.Sprintf("rm -f %s", sudoers))
+	} else {
+		script = append(script,
+			fmt.Sprintf(`printf '%%s %%s\n' %s %s > %s`, user, services.ShellQuote(data.SudoersRule.ValueString()), sudoersTmp),
+			fmt.Sprintf(`chmod 0440 %s`, sudoersTmp),
+			fmt.Sprintf(`visudo -cf %[1]s >/dev/null || { rm -f %[1]s; echo "invalid sudoers rule" >&2; exit 1; }`, sudoersTmp),
+			fmt.Sprintf(`mv -f %s %s`, sudoersTmp, sudoers),
+		)
+	}
+
+	return strings.Join(script, "\n")
+}
+
+func (r *RemoteUserSSHAccessResource) apply(ctx context.Context, data *RemoteUserSSHAccessResourceModel) error {
+	server := data.HostConnection.server()
+
+	platform, err := r.sshService.DetectPlatform(ctx, server)
+	if err != nil {
+		return err
+	}
+
+	tools := []string{services.PrivilegeTool, "install", "base64"}
+	if !data.SudoersRule.IsNull() {
+		tools = append(tools, "visudo")
+	}
+
+	err = r.sshService.Preflight(ctx, server, tools)
+	if err != nil {
+		return err
+	}
+
+	_, err = runCommand(ctx, r.sshService, server, privilegedCommand(server, data.applyScript(platform)))
+	if err != nil {
+		return err
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.HostConnection.hostID(), data.User.ValueString()))
+
+	return nil
+}
+
+// refresh reads the authorized keys and sudoers entry back, reporting false when the user is gone.
+func (r *RemoteUserSSHAccessResource) refresh(ctx context.Context, data *RemoteUserSSHAccessResourceModel) (bool, error) {
+	server := data.HostConnection.server()
+
+	platform, err := r.sshService.DetectPlatform(ctx, server)
+	if err != nil {
+		return false, err
+	}
+
+	script := strings.Join([]string{
+		fmt.Sprintf(`id -u %s >/dev/null 2>&1 || { echo missing; exit 0; }`, services.ShellQuote(data.User.ValueString())),
+		"echo present",
+		fmt.Sprintf(`home=$(%s)`, platform.HomeDirectoryCommand(data.User.ValueString())),
+		`cat "$home/.ssh/authorized_keys" 2>/dev/null || true`,
+		fmt.Sprintf("echo %s", services.ShellQuote(sudoersSeparator)),
+		fmt.Sprintf("cat %s 2>/dev/null || true", services.ShellQuote(data.sudoersPath())),
+	}, "\n")
+
+	result, err := runCommand(services.WithFileContent(ctx), r.sshService, server, privilegedCommand(server, script))
+	if err != nil {
+		return false, err
+	}
+
+	lines := strings.Split(result.Stdout, "\n")
+	start := -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case "missing":
+			return false, nil
+		case "present":
+			start = i + 1
+		}
+
+		if start >= 0 {
+			break
+		}
+	}
+
+	if start < 0 {
+		return false, fmt.Errorf("unexpected output: %s", result.Stdout)
+	}
+
+	keys, sudoers, _ := strings.Cut(strings.Join(lines[start:], "\n"), sudoersSeparator)
+
+	data.AuthorizedKeys = nil
+	for _, key := range strings.Split(keys, "\n") {
+		key = strings.TrimSpace(key)
+		if key != "" && !strings.HasPrefix(key, "#") {
+			data.AuthorizedKeys = append(data.AuthorizedKeys, types.StringValue(key))
+		}
+	}
+
+	data.SudoersRule = types.StringNull()
+	for _, line := range strings.Split(sudoers, "\n") {
+		rule, found := strings.CutPrefix(strings.TrimSpace(line), data.User.ValueString()+" ")
+		if found {
+			data.SudoersRule = types.StringValue(rule)
+		}
+	}
+
+	return true, nil
+}
+
+func (r *RemoteUserSSHAccessResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteUserSSHAccessResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	err := r.apply(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to grant SSH access to %s, got error: %s", data.User.ValueString(), err))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteUserSSHAccessResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteUserSSHAccessResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	exists, err := r.refresh(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read SSH access of %s, got error: %s", data.User.ValueString(), err))
+		return
+	}
+
+	if !exists {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteUserSSHAccessResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data RemoteUserSSHAccessResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	err := r.apply(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to update SSH access of %s, got error: %s", data.User.ValueString(), err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteUserSSHAccessResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteUserSSHAccessResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	server := data.HostConnection.server()
+
+	platform, err := r.sshService.DetectPlatform(ctx, server)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to revoke SSH access of %s, got error: %s", data.User.ValueString(), err))
+		return
+	}
+
+	script := []string{fmt.Sprintf("rm -f %s", services.ShellQuote(data.sudoersPath()))}
+	if data.CreateUser.ValueBool() {
+		script = append(script, platform.DeleteUserCommand(data.User.ValueString()))
+	} else {
+		script = append(script,
+			fmt.Sprintf(`home=$(%s)`, platform.HomeDirectoryCommand(data.User.ValueString())),
+			`[ -z "$home" ] || rm -f "$home/.ssh/authorized_keys"`,
+		)
+	}
+
+	_, err = runCommand(ctx, r.sshService, server, privilegedCommand(server, strings.Join(script, "\n")))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to revoke SSH access of %s, got error: %s", data.User.ValueString(), err))
+		return
+	}
+}
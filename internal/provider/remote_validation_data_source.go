@@ -0,0 +1,140 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteValidationDataSource{}
+
+func NewRemoteValidationDataSource() datasource.DataSource {
+	return &RemoteValidationDataSource{}
+}
+
+// NewLegacyRemoteValidationDataSource registers the data source under its deprecated unprefixed name.
+func NewLegacyRemoteValidationDataSource() datasource.DataSource {
+	return &RemoteValidationDataSource{legacyTypeName: "remote_validation"}
+}
+
+// RemoteValidationDataSource asserts a condition on a host by running a command.
+type RemoteValidationDataSource struct {
+	sshService     *services.SSHService
+	legacyTypeName string
+}
+
+// RemoteValidationDataSourceModel describes the data source data model.
+type RemoteValidationDataSourceModel struct {
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Command        types.String         `tfsdk:"command"`
+	Privileged     types.Bool           `tfsdk:"privileged"`
+	ErrorMessage   types.String         `tfsdk:"error_message"`
+	Stdout         types.String         `tfsdk:"stdout"`
+}
+
+func (d *RemoteValidationDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_validation"
+	if d.legacyTypeName != "" {
+		resp.TypeName = d.legacyTypeName
+	}
+}
+
+func (d *RemoteValidationDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		DeprecationMessage: legacyDeprecationMessage(d.legacyTypeName, "remote_host_validation"),
+
+		MarkdownDescription: "Runs an assertion command on a host and fails the plan with `error_message` when it exits non-zero, " +
+			"e.g. `test \"$(uname -r | cut -d. -f1-2)\" = 5.15` or `cryptsetup status root`",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": dataSourceHostConnectionSchema(),
+			"command": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Shell command asserting the condition, a zero exit code means it holds",
+			},
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to run the command as root",
+			},
+			"error_message": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Message of the error reported when the assertion fails",
+			},
+			"stdout": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Standard output of the command when the assertion holds",
+			},
+		},
+	}
+}
+
+func (d *RemoteValidationDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+func (d *RemoteValidationDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteValidationDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.HostConnection.server()
+
+	command := data.Command.ValueString()
+	if data.Privileged.ValueBool() {
+		command = privilegedCommand(server, command)
+	}
+
+	result, err := runCommand(ctx, d.sshService, server, command)
+
+	var exitErr *ExitCodeError
+	if errors.As(err, &exitErr) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("command"),
+			"Validation Failed",
+			fmt.Sprintf("%s\n\nThe assertion failed on %s with %s", data.ErrorMessage.ValueString(), data.HostConnection.Host.ValueString(), exitErr),
+		)
+		return
+	}
+
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to run the assertion, got error: %s", err))
+		return
+	}
+
+	data.Stdout = types.StringValue(strings.TrimSpace(result.Stdout))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
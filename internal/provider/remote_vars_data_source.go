@@ -0,0 +1,94 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/servers"
+	"remote-provider/internal/provider/services"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteVarsDataSource{}
+
+func NewRemoteVarsDataSource() datasource.DataSource {
+	return &RemoteVarsDataSource{}
+}
+
+// RemoteVarsDataSource exposes the variables a host inherits from the host groups of the provider.
+type RemoteVarsDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemoteVarsDataSourceModel describes the data source data model.
+type RemoteVarsDataSourceModel struct {
+	Host types.String `tfsdk:"host"`
+	Vars types.Map    `tfsdk:"vars"`
+}
+
+func (d *RemoteVarsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_vars"
+}
+
+func (d *RemoteVarsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Variables a host inherits from the `host_groups` of the provider, e.g. to render them in " +
+			"`templatefile` for the content of a `remote_host_file`",
+
+		Attributes: map[string]schema.Attribute{
+			"host": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Hostname or IP address of the host, as set in `host_connection`",
+			},
+			"vars": schema.MapAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Variables of the host, the ones of its groups overriding the ones of the `all` group",
+			},
+		},
+	}
+}
+
+func (d *RemoteVarsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+func (d *RemoteVarsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteVarsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	vars, diags := types.MapValueFrom(ctx, types.StringType, d.sshService.HostVars(&servers.Server{Name: data.Host.ValueString()}))
+	resp.Diagnostics.Append(diags...)
+	data.Vars = vars
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
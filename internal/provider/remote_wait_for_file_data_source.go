@@ -0,0 +1,143 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RemoteWaitForFileDataSource{}
+
+func NewRemoteWaitForFileDataSource() datasource.DataSource {
+	return &RemoteWaitForFileDataSource{}
+}
+
+// RemoteWaitForFileDataSource blocks until a file appears or disappears on a host.
+type RemoteWaitForFileDataSource struct {
+	sshService *services.SSHService
+}
+
+// RemoteWaitForFileDataSourceModel describes the data source data model.
+type RemoteWaitForFileDataSourceModel struct {
+	HostConnection *HostConnectionModel `tfsdk:"host_connection"`
+	Path           RemotePath           `tfsdk:"path"`
+	State          types.String         `tfsdk:"state"`
+	Timeout        Duration             `tfsdk:"timeout"`
+	Interval       Duration             `tfsdk:"interval"`
+	Privileged     types.Bool           `tfsdk:"privileged"`
+	WaitedSeconds  types.Int64          `tfsdk:"waited_seconds"`
+}
+
+func (d *RemoteWaitForFileDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_wait_for_file"
+}
+
+func (d *RemoteWaitForFileDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Blocks until a file appears or disappears on a host, e.g. to wait for the readiness markers " +
+			"dropped by cloud-init or a license activation before configuring the host further",
+
+		Attributes: map[string]schema.Attribute{
+			"host_connection": dataSourceHostConnectionSchema(),
+			"path": schema.StringAttribute{
+				CustomType:          RemotePathType{},
+				Required:            true,
+				MarkdownDescription: "Path of the file to wait for, e.g. `/var/lib/cloud/instance/boot-finished`",
+			},
+			"state": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "State to wait for: `present` until the file exists or `absent` until it is removed. Defaults to `present`",
+				Validators: []validator.String{
+					stringOneOf("present", "absent"),
+				},
+			},
+			"timeout": schema.StringAttribute{
+				CustomType:          DurationType{},
+				Optional:            true,
+				MarkdownDescription: "Maximum duration to wait, e.g. `15m`. Defaults to `5m`",
+			},
+			"interval": schema.StringAttribute{
+				CustomType:          DurationType{},
+				Optional:            true,
+				MarkdownDescription: "Duration between two checks. Defaults to `5s`",
+			},
+			"privileged": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to check the file as root, for paths the connection user cannot read",
+			},
+			"waited_seconds": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "Seconds waited until the file reached the expected state",
+			},
+		},
+	}
+}
+
+func (d *RemoteWaitForFileDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.sshService = sshService
+}
+
+func (d *RemoteWaitForFileDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RemoteWaitForFileDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	server := data.HostConnection.server()
+
+	err := d.sshService.OpenConnection(ctx, server)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to connect to %s, got error: %s", server.Name, err))
+		return
+	}
+
+	interval := 5 * time.Second
+	if !data.Interval.IsNull() {
+		interval, _ = time.ParseDuration(data.Interval.ValueString())
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeout.StringValue, 5*time.Minute)
+	defer cancel()
+
+	present := data.State.ValueString() != "absent"
+	waited, err := d.sshService.WaitForFile(ctx, server, data.Path.ValueString(), present, data.Privileged.ValueBool(), interval)
+	if err != nil {
+		resp.Diagnostics.AddError("Timeout Error", fmt.Sprintf("Unable to wait for %s on %s, got error: %s", data.Path.ValueString(), server.Name, err))
+		return
+	}
+
+	data.WaitedSeconds = types.Int64Value(int64(waited.Seconds()))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,434 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"remote-provider/internal/provider/services"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &RemoteWireGuardPeerResource{}
+var _ resource.ResourceWithModifyPlan = &RemoteWireGuardPeerResource{}
+
+func NewRemoteWireGuardPeerResource() resource.Resource {
+	return &RemoteWireGuardPeerResource{}
+}
+
+// RemoteWireGuardPeerResource configures a WireGuard interface of a host and its peers.
+type RemoteWireGuardPeerResource struct {
+	sshService *services.SSHService
+}
+
+// RemoteWireGuardPeerResourceModel describes the resource data model.
+type RemoteWireGuardPeerResourceModel struct {
+	Id                  types.String                    `tfsdk:"id"`
+	HostConnection      *HostConnectionModel            `tfsdk:"host_connection"`
+	Interface           types.String                    `tfsdk:"interface"`
+	Addresses           []types.String                  `tfsdk:"addresses"`
+	ListenPort          types.Int64                     `tfsdk:"listen_port"`
+	PrivateKeyWO        types.String                    `tfsdk:"private_key_wo"`
+	PrivateKeyWOVersion types.Int64                     `tfsdk:"private_key_wo_version"`
+	Peers               []RemoteWireGuardPeerEntryModel `tfsdk:"peers"`
+	PublicKey           types.String                    `tfsdk:"public_key"`
+	Timeouts            *TimeoutsModel                  `tfsdk:"timeouts"`
+}
+
+// RemoteWireGuardPeerEntryModel describes a peer of the interface.
+type RemoteWireGuardPeerEntryModel struct {
+	PublicKey           types.String   `tfsdk:"public_key"`
+	AllowedIPs          []types.String `tfsdk:"allowed_ips"`
+	Endpoint            types.String   `tfsdk:"endpoint"`
+	PersistentKeepalive types.Int64    `tfsdk:"persistent_keepalive"`
+}
+
+func (r *RemoteWireGuardPeerResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_wireguard_peer"
+}
+
+func (r *RemoteWireGuardPeerResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostConnection := hostConnectionSchema()
+	hostConnection.PlanModifiers = []planmodifier.Object{
+		hostConnectionRequiresReplace(),
+	}
+
+	keyValidator := stringMatches(services.WireGuardKeyRegexp, "must be a base64 encoded WireGuard key")
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Makes a host a WireGuard peer: writes the wg-quick configuration of an interface under `" +
+			services.WireGuardDir + "` and applies it with `wg syncconf` when the interface is up, so peer changes do not " +
+			"drop the tunnel, or starts the `wg-quick@` service otherwise. Changing `addresses` only takes effect once the " +
+			"service restarts. The private key is generated on the host unless `private_key_wo` is set, and it is never " +
+			"stored in the state. The `wg` and `wg-quick` tools must be installed.",
+
+		Attributes: map[string]schema.Attribute{
+			"timeouts":        timeoutsSchema(),
+			"host_connection": hostConnection,
+			"interface": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Name of the interface. Defaults to `wg0`",
+				Default:             stringdefault.StaticString("wg0"),
+				Validators: []validator.String{
+					stringMatches(services.WireGuardInterfaceRegexp, "must be an interface name of at most 15 characters"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"addresses": schema.ListAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Addresses of the interface in CIDR notation, e.g. `[\"10.8.0.1/24\"]`",
+			},
+			"listen_port": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "UDP port the interface listens on. A random port is used when unset",
+			},
+			"private_key_wo": schema.StringAttribute{
+				Optional:            true,
+				Sensitive:           true,
+				WriteOnly:           true,
+				MarkdownDescription: "Private key of the interface, e.g. from an ephemeral resource. It is only sent to the host on creation and when `private_key_wo_version` changes",
+				Validators: []validator.String{
+					keyValidator,
+				},
+			},
+			"private_key_wo_version": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "Version of the private key. Changing it installs `private_key_wo` again, or generates a new key on the host when it is not set",
+			},
+			"peers": schema.ListNestedAttribute{
+				Optional:            true,
+				MarkdownDescription: "Peers of the interface",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"public_key": schema.StringAttribute{
+							Required:            true,
+							MarkdownDescription: "Public key of the peer",
+							Validators: []validator.String{
+								keyValidator,
+							},
+						},
+						"allowed_ips": schema.ListAttribute{
+							Required:            true,
+							ElementType:         types.StringType,
+							MarkdownDescription: "Addresses routed to the peer and accepted from it in CIDR notation",
+						},
+						"endpoint": schema.StringAttribute{
+							Optional:            true,
+							MarkdownDescription: "`host:port` the peer is reached at. Peers without one must connect first",
+						},
+						"persistent_keepalive": schema.Int64Attribute{
+							Optional:            true,
+							MarkdownDescription: "Interval in seconds of the keepalive packets keeping a NAT mapping open, e.g. `25`",
+						},
+					},
+				},
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier of the interface, made of the host and the interface name",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"public_key": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Public key of the interface, to be configured on the other peers",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *RemoteWireGuardPeerResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	sshService, ok := req.ProviderData.(*services.SSHService)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *services.SSHService, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.sshService = sshService
+}
+
+// ModifyPlan plans a new public key when the private key is rotated.
+func (r *RemoteWireGuardPeerResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || req.State.Raw.IsNull() {
+		return
+	}
+
+	var planned, prior types.Int64
+
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("private_key_wo_version"), &planned)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("private_key_wo_version"), &prior)...)
+
+	if resp.Diagnostics.HasError() || planned.Equal(prior) {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("public_key"), types.StringUnknown())...)
+}
+
+// config returns the configuration described by data.
+func (data *RemoteWireGuardPeerResourceModel) config() services.WireGuardConfig {
+	config := services.WireGuardConfig{ListenPort: data.ListenPort.ValueInt64()}
+	for _, address := range data.Addresses {
+		config.Addresses = append(config.Addresses, address.ValueString())
+	}
+
+	for _, entry := range data.Peers {
+		peer := services.WireGuardPeer{
+			PublicKey:           entry.PublicKey.ValueString(),
+			Endpoint:            entry.Endpoint.ValueString(),
+			PersistentKeepalive: entry.PersistentKeepalive.ValueInt64(),
+		}
+		for _, allowed := range entry.AllowedIPs {
+			peer.AllowedIPs = append(peer.AllowedIPs, allowed.ValueString())
+		}
+		config.Peers = append(config.Peers, peer)
+	}
+
+	return config
+}
+
+// setConfig stores the settings of config found on the host in data.
+func (data *RemoteWireGuardPeerResourceModel) setConfig(config services.WireGuardConfig) {
+	data.Addresses = nil
+	for _, address := range config.Addresses {
+		data.Addresses = append(data.Addresses, types.StringValue(address))
+	}
+
+	data.ListenPort = types.Int64Null()
+	if config.ListenPort != 0 {
+		data.ListenPort = types.Int64Value(config.ListenPort)
+	}
+
+	data.Peers = nil
+	for _, peer := range config.Peers {
+		entry := RemoteWireGuardPeerEntryModel{
+			PublicKey:           types.StringValue(peer.PublicKey),
+			Endpoint:            optionalString(peer.Endpoint),
+			PersistentKeepalive: types.Int64Null(),
+		}
+		for _, allowed := range peer.AllowedIPs {
+			entry.AllowedIPs = append(entry.AllowedIPs, types.StringValue(allowed))
+		}
+		if peer.PersistentKeepalive != 0 {
+			entry.PersistentKeepalive = types.Int64Value(peer.PersistentKeepalive)
+		}
+		data.Peers = append(data.Peers, entry)
+	}
+}
+
+// uploadKey copies key to a private file of the workspace of the host and returns its path, so
+// the key never shows up in a command line.
+func (r *RemoteWireGuardPeerResource) uploadKey(ctx context.Context, data *RemoteWireGuardPeerResourceModel, key string) (string, error) {
+	server := data.HostConnection.server()
+	err := r.sshService.OpenConnection(ctx, server)
+	if err != nil {
+		return "", err
+	}
+
+	workspace, err := r.sshService.Workspace(ctx, server)
+	if err != nil {
+		return "", err
+	}
+
+	id := make([]byte, 8)
+	_, err = rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+	keyPath := workspace + "/wireguard-" + hex.EncodeToString(id)
+
+	_, err = r.sshService.Upload(ctx, server, services.UploadCommand(keyPath, false), strings.NewReader(key+"\n"))
+	if err != nil {
+		return "", err
+	}
+
+	return keyPath, nil
+}
+
+// apply writes the configuration of the interface and applies it. The private key is installed
+// from privateKey when it is set, and generated when rotate is set or the interface has none.
+func (r *RemoteWireGuardPeerResource) apply(ctx context.Context, data *RemoteWireGuardPeerResourceModel, privateKey types.String, rotate bool) error {
+	server := data.HostConnection.server()
+
+	err := r.sshService.Preflight(ctx, server, []string{services.PrivilegeTool, "wg", "wg-quick", "systemctl"})
+	if err != nil {
+		return err
+	}
+
+	body, err := data.config().Render()
+	if err != nil {
+		return err
+	}
+
+	keyPath := ""
+	if !privateKey.IsNull() && (rotate || data.PublicKey.IsUnknown()) {
+		keyPath, err = r.uploadKey(ctx, data, privateKey.ValueString())
+		if err != nil {
+			return err
+		}
+	}
+
+	command := services.WireGuardApplyCommand(data.Interface.ValueString(), body, keyPath, rotate)
+	result, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, command))
+	if err != nil {
+		return err
+	}
+
+	status, err := services.ParseWireGuardStatus(result.Stdout)
+	if err != nil {
+		return err
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s-%s", data.HostConnection.hostID(), data.Interface.ValueString()))
+	data.PublicKey = types.StringValue(status.PublicKey)
+
+	return nil
+}
+
+func (r *RemoteWireGuardPeerResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data RemoteWireGuardPeerResourceModel
+	var privateKey types.String
+
+	// Read Terraform plan data into the model, the write-only key is only in the configuration
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("private_key_wo"), &privateKey)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.create(ctx)
+	defer cancel()
+
+	err := r.apply(ctx, &data, privateKey, false)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to configure WireGuard interface %s, got error: %s", data.Interface.ValueString(), err))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteWireGuardPeerResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data RemoteWireGuardPeerResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.read(ctx)
+	defer cancel()
+
+	server := data.HostConnection.server()
+
+	result, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, services.WireGuardStatusCommand(data.Interface.ValueString())))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read WireGuard interface %s, got error: %s", data.Interface.ValueString(), err))
+		return
+	}
+
+	status, err := services.ParseWireGuardStatus(result.Stdout)
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to read WireGuard interface %s, got error: %s", data.Interface.ValueString(), err))
+		return
+	}
+
+	if status == nil {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	rendered, _ := data.config().Render()
+	found, _ := status.Config.Render()
+	if rendered != found {
+		data.setConfig(status.Config)
+	}
+	data.PublicKey = types.StringValue(status.PublicKey)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteWireGuardPeerResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state RemoteWireGuardPeerResourceModel
+	var privateKey types.String
+
+	// Read Terraform plan and prior state data into the models, the write-only key is only in
+	// the configuration
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("private_key_wo"), &privateKey)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.update(ctx)
+	defer cancel()
+
+	err := r.apply(ctx, &data, privateKey, !data.PrivateKeyWOVersion.Equal(state.PrivateKeyWOVersion))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to configure WireGuard interface %s, got error: %s", data.Interface.ValueString(), err))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RemoteWireGuardPeerResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data RemoteWireGuardPeerResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := data.Timeouts.delete(ctx)
+	defer cancel()
+
+	server := data.HostConnection.server()
+
+	_, err := runCommand(ctx, r.sshService, server, privilegedCommand(server, services.WireGuardRemoveCommand(data.Interface.ValueString())))
+	if err != nil {
+		resp.Diagnostics.AddError("SSH Error", fmt.Sprintf("Unable to remove WireGuard interface %s, got error: %s", data.Interface.ValueString(), err))
+		return
+	}
+}
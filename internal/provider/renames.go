@@ -0,0 +1,53 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+)
+
+// Resources and data sources were first registered without the provider type name prefix,
+// e.g. "remote_file" instead of "remote_host_file". The unprefixed names stay registered as
+// deprecated aliases and their states can be moved with a `moved` block.
+
+// legacyDeprecationMessage returns the deprecation message of a legacy type name, or an
+// empty string for the prefixed one.
+func legacyDeprecationMessage(legacyTypeName string, typeName string) string {
+	if legacyTypeName == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%[1]s is deprecated, use %[2]s instead. Existing resources can be migrated without changes with a moved block from %[1]s to %[2]s.", legacyTypeName, typeName)
+}
+
+// renamedStateMovers moves the state of a resource declared with its legacy type name to the
+// prefixed one. Both share the same schema, so the state is copied through the model type T.
+func renamedStateMovers[T any](ctx context.Context, r resource.Resource, legacyTypeName string) []resource.StateMover {
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+
+	return []resource.StateMover{
+		{
+			SourceSchema: &schemaResp.Schema,
+			StateMover: func(ctx context.Context, req resource.MoveStateRequest, resp *resource.MoveStateResponse) {
+				if req.SourceTypeName != legacyTypeName {
+					return
+				}
+
+				var data T
+
+				resp.Diagnostics.Append(req.SourceState.Get(ctx, &data)...)
+
+				if resp.Diagnostics.HasError() {
+					return
+				}
+
+				resp.Diagnostics.Append(resp.TargetState.Set(ctx, &data)...)
+			},
+		},
+	}
+}
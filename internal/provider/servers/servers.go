@@ -1,8 +1,12 @@
 package servers
 
 import (
+	"encoding/base64"
 	"fmt"
 	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 type Server struct {
@@ -12,16 +16,42 @@ type Server struct {
 	User           string
 	Password       string
 	PrivateKeyPath string
+	TOTPSecret     string
+	BecomeMethod   string
 	SudoPassword   string
+	ProxyCommand   string
+	WebSocketURL   string
+	ConsoleCommand string
+	Local          bool
+	Container      string
+	ContainerTool  string
 	Args           map[string]any
 	Err            error
-	History        []*ServerCommand
+
+	historyMutex sync.Mutex
+	history      []*ServerCommand
 }
 
 func (s *Server) GetFullAddress() string {
 	return fmt.Sprintf("%s:%s", s.Address, strconv.Itoa(int(s.Port)))
 }
 
+// AppendHistory records command as executed on the server, it is safe for concurrent use.
+func (s *Server) AppendHistory(command *ServerCommand) {
+	s.historyMutex.Lock()
+	defer s.historyMutex.Unlock()
+
+	s.history = append(s.history, command)
+}
+
+// GetHistory returns a copy of the commands executed on the server, oldest first.
+func (s *Server) GetHistory() []*ServerCommand {
+	s.historyMutex.Lock()
+	defer s.historyMutex.Unlock()
+
+	return append([]*ServerCommand(nil), s.history...)
+}
+
 type ServerGroup struct {
 	Name    string
 	Servers []*Server
@@ -34,4 +64,27 @@ type ServerCommand struct {
 	Stdout   string
 	Stderr   string
 	ExitCode int8
+
+	// StartedAt and FinishedAt bound the execution of the command on the host.
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Duration returns how long the command ran, zero when its timing is unknown.
+func (c *ServerCommand) Duration() time.Duration {
+	if c.StartedAt.IsZero() || c.FinishedAt.Before(c.StartedAt) {
+		return 0
+	}
+
+	return c.FinishedAt.Sub(c.StartedAt)
+}
+
+// StdoutIsUTF8 reports whether Stdout can be stored as a string value in Terraform state.
+func (c *ServerCommand) StdoutIsUTF8() bool {
+	return utf8.ValidString(c.Stdout)
+}
+
+// StdoutBase64 returns Stdout encoded as standard base64, which round-trips binary output.
+func (c *ServerCommand) StdoutBase64() string {
+	return base64.StdEncoding.EncodeToString([]byte(c.Stdout))
 }
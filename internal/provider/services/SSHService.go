@@ -2,141 +2,317 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"regexp"
 	"remote-provider/internal/provider/filesystem"
 	"remote-provider/internal/provider/servers"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"golang.org/x/crypto/ssh"
 )
 
 type SSHService struct {
-	connections []SSHConnection
+	// MaxParallelHosts bounds how many hosts are worked on at the same time, 0 means unlimited.
+	MaxParallelHosts int
+	// MaxParallelSessionsPerHost bounds concurrent sessions on a single connection, 0 means unlimited.
+	MaxParallelSessionsPerHost int
+	// OutputFraming surrounds commands with sentinel lines so banners printed by the
+	// remote shell are stripped from their output.
+	OutputFraming bool
+	// Umask is applied to every command when set, e.g. "0027".
+	Umask string
+	// DefaultFileMode and DefaultDirectoryMode are used by resources creating files or
+	// directories without an explicit mode.
+	DefaultFileMode      string
+	DefaultDirectoryMode string
+	// RetryPolicies lists the failures worth retrying, checked against the command output.
+	RetryPolicies []RetryPolicy
+	// DebugSSH logs the handshake of every connection: offered algorithms, host key,
+	// server banner and authentication attempts.
+	DebugSSH bool
+	// Recording stores the output of privileged commands to local files when set.
+	Recording *SessionRecording
+	// Wrapper rewrites every command for hosts with restricted shells when set.
+	Wrapper *CommandWrapper
+	// DiscoverIdentities offers the keys of the local SSH agent and the default identity files
+	// to hosts configured without password nor private key, as OpenSSH does.
+	DiscoverIdentities bool
+	// Fake answers every command from a table instead of connecting to the hosts when set.
+	Fake *FakeTransport
+	// Hooks run before and after the changes made to every host when set.
+	Hooks *ApplyHooks
+	// StateCipher encrypts the content resources store in the state when they ask for it.
+	StateCipher *StateCipher
+	// HostGroups hold the variables commands reference with "{{ var.name }}", see HostVars.
+	HostGroups []*servers.ServerGroup
+	// Agent runs the requests of AgentRequests with a helper uploaded once per host, see agent.go.
+	Agent bool
+	// AgentBinary is the local path of the helper, the provider executable when empty.
+	AgentBinary string
+
+	mutex       sync.Mutex
+	connections []*SSHConnection
+	workspaces  map[string]string
+	tools       map[string]map[string]bool
+	platforms   map[string]Platform
+	history     map[string][]*servers.ServerCommand
+	applied     map[string]*applyHookState
+	hosts       hostLimiter
+	batcher     commandBatcher
+	agents      map[string]string
+	agentCalls  commandBatcher
+	operations  map[string]map[*operation]bool
+	agentDigest string
+	sftp        sftpAvailability
 }
 
-func createSSHClient(host *servers.Server) (*ssh.Client, error) {
+// createSSHClient connects to host. Without password nor private key, the keys of the local
+// SSH agent and the default identity files are offered when discover is set.
+func createSSHClient(ctx context.Context, host *servers.Server, debug bool, discover bool) (*ssh.Client, error) {
+	logger := handshakeLogger{ctx: ctx, host: host.Name, enabled: debug}
+
 	conf := &ssh.ClientConfig{
 		User:            host.User,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: logger.hostKeyCallback(ssh.InsecureIgnoreHostKey()),
+		BannerCallback:  logger.bannerCallback(),
 		Auth:            []ssh.AuthMethod{},
 		Timeout:         10 * time.Second,
 	}
 
 	if len(host.Password) > 0 {
-		conf.Auth = append(conf.Auth, ssh.Password(host.Password))
+		conf.Auth = append(conf.Auth, logger.passwordAuth(host.Password))
 	}
 
 	if len(host.PrivateKeyPath) > 0 {
+		// A configured key that cannot be used fails even when a password is set, so a typo
+		// in the path does not silently fall back to another authentication method.
 		keyFile, err := filesystem.ReadFile(host.PrivateKeyPath)
-		if err != nil && host.Password == "" {
-			fmt.Println(err.Error())
-			return nil, err
+		if err != nil {
+			return nil, &CredentialError{Host: host.Name, Path: host.PrivateKeyPath, Err: err}
 		}
 
-		var signer ssh.Signer
-
-		signer, err = ssh.ParsePrivateKey(keyFile)
+		signer, err := ssh.ParsePrivateKey(keyFile)
 		if err != nil {
-			fmt.Println(err.Error())
-			return nil, err
+			return nil, &CredentialError{Host: host.Name, Path: host.PrivateKeyPath, Err: err}
+		}
+
+		conf.Auth = append(conf.Auth, logger.publicKeyAuth(signer))
+	}
+
+	if host.Password == "" && host.PrivateKeyPath == "" && discover {
+		signers, agentConn := discoverSigners(defaultSSHDir(), logger)
+		if agentConn != nil {
+			defer agentConn.Close()
 		}
+		if len(signers) > 0 {
+			conf.Auth = append(conf.Auth, logger.publicKeyAuth(signers...))
+		}
+	}
 
-		conf.Auth = append(conf.Auth, ssh.PublicKeys(signer))
+	// Hosts enforcing PAM one time passwords ask for the code with keyboard-interactive.
+	if len(host.TOTPSecret) > 0 {
+		conf.Auth = append(conf.Auth, keyboardInteractive(host.Password, host.TOTPSecret, logger))
 	}
 
-	client, err := ssh.Dial("tcp", host.GetFullAddress(), conf)
+	logger.offered(conf)
+
+	// Dial through the context so a deadline also bounds the TCP connect and the handshake.
+	// The connection goes through the proxy set in ALL_PROXY unless NO_PROXY matches the host,
+	// or through the proxy command or WebSocket tunnel of the host.
+	dialer := net.Dialer{Timeout: conf.Timeout}
+	conn, err := dialHost(ctx, &dialer, host)
 	if err != nil {
-		fmt.Println(err.Error())
-		return nil, err
+		logger.log("ssh dial failed", map[string]any{"address": host.GetFullAddress(), "error": err.Error()})
+		return nil, &DialError{Host: host.Name, Address: host.GetFullAddress(), Err: err}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
 	}
 
-	return client, nil
+	clientConn, channels, requests, err := ssh.NewClientConn(conn, host.GetFullAddress(), conf)
+	if err != nil {
+		conn.Close()
+		logger.log("ssh handshake failed", map[string]any{"error": err.Error()})
+		return nil, &DialError{Host: host.Name, Address: host.GetFullAddress(), Err: err}
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	logger.log("ssh handshake completed", map[string]any{"server_version": string(clientConn.ServerVersion())})
+
+	return ssh.NewClient(clientConn, channels, requests), nil
 }
 
-func NewSSHService(hosts []*servers.Server) *SSHService {
-	var connections []SSHConnection
+// NewSSHService connects to every host of hosts. Hosts that cannot be connected to are left
+// out of the returned service and their errors are joined in the returned error.
+func NewSSHService(hosts []*servers.Server) (*SSHService, error) {
+	var errs []error
+	var connections []*SSHConnection
 	for _, host := range hosts {
-		var foundHost *servers.Server
-		for _, connection := range connections {
-			if connection.host.Name == host.Name {
-				foundHost = connection.host
-				break
-			}
-		}
-		if foundHost != nil {
+		key := connectionKey(host)
+		if slices.ContainsFunc(connections, func(connection *SSHConnection) bool { return connection.key == key }) {
 			continue
 		}
 
-		client, err := createSSHClient(host)
+		client, err := createSSHClient(context.Background(), host, false, true)
 		if err != nil {
-			fmt.Println(err.Error())
+			errs = append(errs, err)
 			continue
 		}
-		connection := SSHConnection{
+		connections = append(connections, &SSHConnection{
 			host:   host,
+			key:    key,
 			client: client,
-		}
-		connections = append(connections, connection)
+		})
 	}
 
 	return &SSHService{
 		connections: connections,
+	}, errors.Join(errs...)
+}
+
+// findConnection returns the connection opened for host, see connectionKey. Connections are
+// never modified once added, so the pointer stays valid for the commands running on it even
+// when the connection is dropped concurrently.
+func (service *SSHService) findConnection(host *servers.Server) *SSHConnection {
+	key := connectionKey(host)
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	return service.connectionByKey(key)
+}
+
+// connectionByKey returns the connection whose connectionKey is key, the mutex must be held.
+func (service *SSHService) connectionByKey(key string) *SSHConnection {
+	for _, connection := range service.connections {
+		if connection.key == key {
+			return connection
+		}
 	}
+
+	return nil
 }
 
-func (service *SSHService) OpenConnection(host *servers.Server) error {
-	var foundHost *servers.Server
-	if service.connections == nil {
-		service.connections = []SSHConnection{}
+func (service *SSHService) OpenConnection(ctx context.Context, host *servers.Server) error {
+	if service.findConnection(host) != nil {
+		return nil
 	}
-	for _, connection := range service.connections {
-		if connection.host.Name == host.Name {
-			foundHost = connection.host
-			break
+
+	if host.Local && host.User == "" {
+		name, err := localUser()
+		if err != nil {
+			return err
 		}
+		host.User = name
+	}
+
+	if host.User == "" {
+		return fmt.Errorf("no user to connect to %s as", host.Name)
+	}
+
+	if err := checkContainerBecome(host); err != nil {
+		return err
 	}
-	if foundHost != nil {
+
+	if service.Fake != nil {
+		key := connectionKey(host)
+
+		service.mutex.Lock()
+		defer service.mutex.Unlock()
+
+		if service.connectionByKey(key) == nil {
+			service.connections = append(service.connections, &SSHConnection{host: host, key: key})
+		}
 		return nil
 	}
 
-	client, err := createSSHClient(host)
+	if host.Local {
+		return service.openLocal(host)
+	}
+
+	// Dial outside of the lock so connections to different hosts are opened in parallel.
+	service.hosts.acquire(host.Name, service.MaxParallelHosts)
+	start := time.Now()
+	client, err := createSSHClient(ctx, host, service.DebugSSH, service.DiscoverIdentities)
+	service.Measure(ctx, "dial", host, start, 0, err)
+	service.hosts.release(host.Name, service.MaxParallelHosts)
 	if err != nil {
-		return err
+		return service.openConsole(ctx, host, err)
 	}
-	connection := SSHConnection{
-		host:   host,
-		client: client,
+
+	key := connectionKey(host)
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	if service.connectionByKey(key) != nil {
+		// Another resource opened the same connection concurrently.
+		return client.Close()
 	}
-	service.connections = append(service.connections, connection)
+
+	service.connections = append(service.connections, &SSHConnection{
+		host:     host,
+		key:      key,
+		client:   client,
+		sessions: newSessionSlots(service.MaxParallelSessionsPerHost),
+	})
 	return nil
 }
 
-func (service *SSHService) spawnSession(connection *SSHConnection) (*ssh.Session, error) {
+func (service *SSHService) spawnSession(ctx context.Context, connection *SSHConnection) (*ssh.Session, error) {
 	var err error
 
 	var session *ssh.Session
 	session, err = connection.client.NewSession()
 	if err != nil {
-		fmt.Println(err.Error())
+		tflog.Debug(ctx, "unable to open an ssh session", map[string]any{"host": connection.host.Name, "error": err.Error()})
 		return nil, err
 	}
 
 	return session, nil
 }
 
-func extractSudoPasswordFromOutput(stdout *bytes.Buffer, password *string) {
+// doasPromptRegexp matches the password prompt of doas, e.g. "doas (alice@host) password:".
+var doasPromptRegexp = regexp.MustCompile(`doas \([^)]*\) password:`)
+
+// suPromptRegexp matches the password prompt of su at the start of a line.
+var suPromptRegexp = regexp.MustCompile(`(?m)^Password: ?`)
+
+// becomePrompt reports whether output shows the password prompt of a become method. The
+// "Password:" prompt of su is only looked for on hosts using su, as commands print it too.
+func becomePrompt(host *servers.Server, output string) bool {
+	return strings.Contains(output, "[sudo] password for") || doasPromptRegexp.MatchString(output) ||
+		(becomeMethod(host) == "su" && suPromptRegexp.MatchString(output))
+}
+
+func extractSudoPasswordFromOutput(stdout *bytes.Buffer, host *servers.Server) {
+	password := &host.SudoPassword
 	commandOutput := strings.Split(stdout.String(), "\n")
-	if strings.Contains(stdout.String(), "[sudo] password for") {
+	if becomePrompt(host, stdout.String()) {
 		var filteredOutput []string
 		for _, line := range commandOutput {
-			if !strings.Contains(line, *password) && !strings.Contains(line, "[sudo] password for") {
+			// su reads the password without printing a new line, the output follows its prompt.
+			if becomeMethod(host) == "su" {
+				line = suPromptRegexp.ReplaceAllString(line, "")
+				if strings.TrimSpace(line) == "" {
+					continue
+				}
+			}
+			if (*password == "" || !strings.Contains(line, *password)) && !becomePrompt(host, line) {
 				filteredOutput = append(filteredOutput, line)
 			}
 		}
 		commandOutput = filteredOutput
-	} else {
+	} else if *password != "" {
 		if len(commandOutput) > 0 && strings.Contains(commandOutput[0], *password) {
 			commandOutput = commandOutput[1:]
 		}
@@ -145,54 +321,203 @@ func extractSudoPasswordFromOutput(stdout *bytes.Buffer, password *string) {
 	stdout.WriteString(strings.Join(commandOutput, "\n"))
 }
 
-func (service *SSHService) ExecuteCommand(command string, server *servers.Server) (*servers.ServerCommand, error) {
-	var connection *SSHConnection
-	for _, conn := range service.connections {
-		if conn.host.Name == server.Name {
-			connection = &conn
-			break
+// ExecuteCommand runs command on server, retrying it while a failure matches one of the
+// service retry policies.
+func (service *SSHService) ExecuteCommand(ctx context.Context, command string, server *servers.Server) (*servers.ServerCommand, error) {
+	service.recordOperation(ctx, server)
+	if err := service.beginApply(ctx, server); err != nil {
+		return nil, err
+	}
+
+	attempts := make([]int, len(service.RetryPolicies))
+
+	for {
+		serverCommand, err := service.executeCommand(ctx, command, server)
+		if err == nil || serverCommand == nil || serverCommand.ExitCode == 0 {
+			return serverCommand, err
+		}
+
+		// The PTY merges stderr into stdout, so both are matched.
+		policy := matchRetryPolicy(service.RetryPolicies, serverCommand.Stdout+"\n"+serverCommand.Stderr, attempts)
+		if policy < 0 {
+			return serverCommand, err
+		}
+
+		attempts[policy]++
+		tflog.Warn(ctx, "retrying remote command", map[string]any{
+			"host":    server.Name,
+			"pattern": service.RetryPolicies[policy].Pattern.String(),
+			"attempt": attempts[policy],
+		})
+
+		if SleepContext(ctx, service.RetryPolicies[policy].Delay) != nil {
+			return serverCommand, err
 		}
 	}
+}
 
+func (service *SSHService) executeCommand(ctx context.Context, command string, server *servers.Server) (*servers.ServerCommand, error) {
+	connection := service.findConnection(server)
 	if connection == nil {
 		return nil, fmt.Errorf("no connection found for server %s", server.Name)
 	}
 
-	session, err := service.spawnSession(connection)
+	if service.Fake != nil {
+		serverCommand, err := service.Fake.execute(command)
+		service.recordHistory(server, serverCommand)
+		if privilegeErr := privilegeError(server, serverCommand); privilegeErr != nil {
+			return serverCommand, privilegeErr
+		}
+		return serverCommand, err
+	}
+
+	if connection.local {
+		return service.executeLocalCommand(ctx, server, command)
+	}
+
+	if connection.console != nil {
+		return service.executeConsoleCommand(ctx, connection, server, command, nil)
+	}
+
+	service.hosts.acquire(server.Name, service.MaxParallelHosts)
+	defer service.hosts.release(server.Name, service.MaxParallelHosts)
+
+	if connection.sessions != nil {
+		select {
+		case connection.sessions <- struct{}{}:
+			defer func() { <-connection.sessions }()
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for a session on %s: %w", server.Name, ctx.Err())
+		}
+	}
+
+	start := time.Now()
+	session, err := service.spawnSession(ctx, connection)
+	service.Measure(ctx, "session", server, start, 0, err)
 	if err != nil {
 		return nil, err
 	}
 
 	defer func(session *ssh.Session) {
 		err := session.Close()
-		if err != nil && err.Error() != "EOF" {
-			fmt.Println(err.Error())
+		if err != nil && !errors.Is(err, io.EOF) {
+			tflog.Debug(ctx, "unable to close the ssh session", map[string]any{"host": server.Name, "error": err.Error()})
 		}
 	}(session)
 
 	var stdout, stderr bytes.Buffer
 	session.Stdout = &stdout
 	session.Stderr = &stderr
-	session.Stdin = strings.NewReader(connection.host.SudoPassword + "\n")
 
-	err = session.RequestPty("xterm", 40, 80, ssh.TerminalModes{ssh.ECHO: 1, ssh.TTY_OP_ISPEED: 14400, ssh.TTY_OP_OSPEED: 14400})
+	// Root sessions never go through sudo, so they need neither its password nor a PTY.
+	if !runsAsRoot(connection.host) {
+		session.Stdin = strings.NewReader(connection.host.SudoPassword + "\n")
+
+		err = session.RequestPty("xterm", 40, 80, ssh.TerminalModes{ssh.ECHO: 1, ssh.TTY_OP_ISPEED: 14400, ssh.TTY_OP_OSPEED: 14400})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	remoteCommand := containerCommand(server, service.withUmask(command))
+	var frame *outputFrame
+	if service.OutputFraming {
+		frame, err = newOutputFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		remoteCommand = frame.wrap(remoteCommand)
+	}
+
+	var recorder *sessionRecorder
+	if service.recordsSession(ctx, server, command) {
+		recorder = newSessionRecorder()
+		session.Stdout = io.MultiWriter(&stdout, recorder)
+		session.Stderr = io.MultiWriter(&stderr, recorder)
+	}
+
+	mark, err := newCommandMark()
 	if err != nil {
 		return nil, err
 	}
 
-	err = session.Run(command)
-	extractSudoPasswordFromOutput(&stdout, &connection.host.SudoPassword)
+	start = time.Now()
+	err = runSession(ctx, session, service.Wrapper.Wrap(mark.wrap(remoteCommand)), service.interrupter(connection, mark))
+	finish := time.Now()
+	service.Measure(ctx, "command", server, start, stdout.Len()+stderr.Len(), err)
+
+	if recorder != nil {
+		name, saveErr := recorder.save(service.Recording, connection.host, command)
+		if saveErr != nil {
+			tflog.Warn(ctx, "unable to save the session recording", map[string]any{"host": server.Name, "error": saveErr.Error()})
+		} else {
+			tflog.Info(ctx, "session recorded", map[string]any{"host": server.Name, "file": name})
+		}
+	}
+
+	if frame != nil {
+		framed := frame.extract(stdout.String())
+		stdout.Reset()
+		stdout.WriteString(framed)
+	}
+	extractSudoPasswordFromOutput(&stdout, connection.host)
 
 	serverCommand := &servers.ServerCommand{
-		Command:  command,
-		Stdout:   stdout.String(),
-		Stderr:   stderr.String(),
-		ExitCode: extractExitCode(err),
+		Command:    command,
+		Stdout:     stdout.String(),
+		Stderr:     stderr.String(),
+		ExitCode:   extractExitCode(err),
+		StartedAt:  start,
+		FinishedAt: finish,
+	}
+	service.recordHistory(server, serverCommand)
+	if privilegeErr := privilegeError(server, serverCommand); privilegeErr != nil {
+		return serverCommand, privilegeErr
 	}
-	server.History = append(server.History, serverCommand)
 	return serverCommand, err
 }
 
+// runSession runs command on session. When ctx is done before the command exits, the
+// cancelSignals are sent in turn to the session and through interrupt when set, then the
+// session is killed.
+func runSession(ctx context.Context, session *ssh.Session, command string, interrupt func(ssh.Signal)) error {
+	err := session.Start(command)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	for _, signal := range cancelSignals {
+		_ = session.Signal(signal)
+		if interrupt != nil {
+			// The interrupting session may hang as well when the host stopped responding.
+			go interrupt(signal)
+		}
+
+		select {
+		case <-done:
+			return fmt.Errorf("command timed out: %w", ctx.Err())
+		case <-time.After(cancelGracePeriod):
+		}
+	}
+
+	_ = session.Signal(ssh.SIGKILL)
+	_ = session.Close()
+
+	return fmt.Errorf("command timed out: %w", ctx.Err())
+}
+
 func extractExitCode(err error) int8 {
 	var exitErr *ssh.ExitError
 	if errors.As(err, &exitErr) {
@@ -202,10 +527,45 @@ func extractExitCode(err error) int8 {
 }
 
 func (service *SSHService) CloseConnection(connection *SSHConnection) error {
-	err := connection.client.Close()
-	return err
+	if connection.console != nil {
+		return connection.console.Close()
+	}
+
+	// Connections of the fake transport have no client.
+	if connection.client == nil {
+		return nil
+	}
+
+	return connection.client.Close()
 }
 
-func (service *SSHService) GetConnections() *[]SSHConnection {
-	return &service.connections
+// DropConnection closes the connections to the host of server and forgets everything cached
+// about it, e.g. after a reboot, so the next commands dial it again.
+func (service *SSHService) DropConnection(server *servers.Server) error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	var errs []error
+	service.connections = slices.DeleteFunc(service.connections, func(connection *SSHConnection) bool {
+		if connection.host.Name != server.Name {
+			return false
+		}
+
+		delete(service.workspaces, connection.key)
+		delete(service.tools, connection.key)
+		delete(service.platforms, connection.key)
+		errs = append(errs, service.CloseConnection(connection))
+		return true
+	})
+
+	return errors.Join(errs...)
+}
+
+// GetConnections returns the open connections. The slice is a copy, so it can be ranged over
+// while other resources open or drop connections.
+func (service *SSHService) GetConnections() []*SSHConnection {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	return slices.Clone(service.connections)
 }
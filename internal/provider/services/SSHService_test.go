@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/servers"
+	"sync"
+	"testing"
+)
+
+func TestFindConnectionConcurrent(t *testing.T) {
+	service := &SSHService{Fake: &FakeTransport{}}
+	ctx := context.Background()
+
+	hosts := make([]*servers.Server, 8)
+	for i := range hosts {
+		hosts[i] = &servers.Server{Name: fmt.Sprintf("web-%d", i), Address: "192.0.2.10", Port: 22, User: "root"}
+	}
+
+	// Commands on every host run while the other hosts are dropped and connected again, each
+	// must keep running against its own host.
+	var wg sync.WaitGroup
+	errs := make(chan error, len(hosts)*2)
+	for _, host := range hosts {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if err := service.OpenConnection(ctx, host); err != nil {
+					errs <- err
+					return
+				}
+				if connection := service.findConnection(host); connection != nil && connection.host != host {
+					errs <- fmt.Errorf("connection of %s found for %s", connection.host.Name, host.Name)
+					return
+				}
+				if _, err := service.ExecuteCommand(ctx, "hostname", host); err != nil && service.findConnection(host) != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if err := service.DropConnection(host); err != nil {
+					errs <- err
+					return
+				}
+				_ = service.GetConnections()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	for _, connection := range service.GetConnections() {
+		if service.findConnection(connection.host) != connection {
+			t.Errorf("expected a stable connection for %s", connection.host.Name)
+		}
+	}
+}
+
+func TestDropConnectionKeepsOthers(t *testing.T) {
+	service := &SSHService{Fake: &FakeTransport{}}
+	ctx := context.Background()
+
+	first := &servers.Server{Name: "web-1", Address: "192.0.2.10", Port: 22, User: "root"}
+	second := &servers.Server{Name: "web-2", Address: "192.0.2.11", Port: 22, User: "root"}
+	for _, host := range []*servers.Server{first, second} {
+		if err := service.OpenConnection(ctx, host); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	connection := service.findConnection(second)
+	if err := service.DropConnection(first); err != nil {
+		t.Fatal(err)
+	}
+
+	// The connection found before the drop is still the one of its host.
+	if connection.host != second || service.findConnection(second) != connection {
+		t.Errorf("expected the connection of %s to survive the drop of %s", second.Name, first.Name)
+	}
+	if service.findConnection(first) != nil {
+		t.Errorf("expected the connection of %s to be dropped", first.Name)
+	}
+}
+
+func TestConnectionsKeyedByCredentials(t *testing.T) {
+	service := &SSHService{Fake: &FakeTransport{}}
+	ctx := context.Background()
+
+	deploy := &servers.Server{Name: "web-1", Address: "192.0.2.10", Port: 22, User: "deploy", SudoPassword: "first"}
+	hosts := []*servers.Server{
+		deploy,
+		{Name: "web-1", Address: "192.0.2.10", Port: 22, User: "admin", SudoPassword: "first"},
+		{Name: "web-1", Address: "192.0.2.10", Port: 22, User: "deploy", SudoPassword: "second"},
+		{Name: "web-1", Address: "192.0.2.10", Port: 22, User: "deploy", SudoPassword: "first", BecomeMethod: "doas"},
+		{Name: "web-1", Address: "192.0.2.10", Port: 22, User: "deploy", SudoPassword: "first", PrivateKeyPath: "/keys/deploy"},
+		{Name: "web-1", Address: "192.0.2.10", Port: 22, User: "deploy", SudoPassword: "first", ProxyCommand: "ssh -W %h:%p bastion"},
+		{Name: "web-1", Address: "192.0.2.10", Port: 22, User: "deploy", SudoPassword: "first", Container: "app"},
+	}
+	for _, host := range hosts {
+		if err := service.OpenConnection(ctx, host); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if connections := service.GetConnections(); len(connections) != len(hosts) {
+		t.Fatalf("expected a connection per set of credentials, got %d", len(connections))
+	}
+	for _, host := range hosts {
+		if connection := service.findConnection(host); connection == nil || connection.host != host {
+			t.Errorf("expected the connection of %+v to be its own", host)
+		}
+	}
+
+	same := &servers.Server{Name: "web-1", Address: "192.0.2.10", Port: 22, User: "deploy", SudoPassword: "first"}
+	if service.findConnection(same) == nil || service.findConnection(same).host != deploy {
+		t.Error("expected the same credentials to share a connection")
+	}
+
+	if err := service.DropConnection(deploy); err != nil {
+		t.Fatal(err)
+	}
+	if connections := service.GetConnections(); len(connections) != 0 {
+		t.Errorf("expected every connection of the host to be dropped, got %d", len(connections))
+	}
+}
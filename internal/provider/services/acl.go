@@ -0,0 +1,109 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var aclTags = map[string]string{
+	"u":     "user",
+	"user":  "user",
+	"g":     "group",
+	"group": "group",
+}
+
+// NormalizeACLEntry returns entry in the form printed by getfacl, e.g. "u:alice:rw" becomes
+// "user:alice:rw-". Only named user and group entries, optionally default ones, are managed:
+// the owner, group, other and mask entries follow the file mode.
+func NormalizeACLEntry(entry string) (string, error) {
+	fields := strings.Split(strings.TrimSpace(entry), ":")
+
+	prefix := ""
+	if len(fields) == 4 && (fields[0] == "d" || fields[0] == "default") {
+		prefix = "default:"
+		fields = fields[1:]
+	}
+
+	if len(fields) != 3 {
+		return "", fmt.Errorf("invalid ACL entry %q, expected [default:]user|group:name:perms", entry)
+	}
+
+	tag, ok := aclTags[fields[0]]
+	if !ok || fields[1] == "" {
+		return "", fmt.Errorf("invalid ACL entry %q, only named user and group entries are supported", entry)
+	}
+
+	perms := []byte("---")
+	for _, perm := range fields[2] {
+		switch perm {
+		case 'r':
+			perms[0] = 'r'
+		case 'w':
+			perms[1] = 'w'
+		case 'x', 'X':
+			perms[2] = 'x'
+		case '-':
+		default:
+			return "", fmt.Errorf("invalid ACL entry %q, permissions must be made of r, w, x and -", entry)
+		}
+	}
+
+	return fmt.Sprintf("%s%s:%s:%s", prefix, tag, fields[1], perms), nil
+}
+
+// NormalizeACL normalizes every entry and returns them sorted without duplicates.
+func NormalizeACL(entries []string) ([]string, error) {
+	normalized := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		value, err := NormalizeACLEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		normalized = append(normalized, value)
+	}
+
+	slices.Sort(normalized)
+
+	return slices.Compact(normalized), nil
+}
+
+// ParseGetfacl extracts the named entries from getfacl output, ignoring comments,
+// effective rights and the entries derived from the file mode.
+func ParseGetfacl(output string) []string {
+	var entries []string
+	for _, line := range strings.Split(output, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		entry, err := NormalizeACLEntry(line)
+		if err != nil {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	slices.Sort(entries)
+
+	return slices.Compact(entries)
+}
+
+// GetACLCommand returns a command printing the ACL of path.
+func GetACLCommand(path string) string {
+	return fmt.Sprintf("getfacl -p -E -- %s", ShellQuote(path))
+}
+
+// SetACLCommand returns a command replacing the extended ACL entries of path with entries.
+func SetACLCommand(path string, entries []string) string {
+	command := fmt.Sprintf("setfacl -b -- %s", ShellQuote(path))
+	if len(entries) > 0 {
+		command += fmt.Sprintf(" && setfacl -m %s -- %s", ShellQuote(strings.Join(entries, ",")), ShellQuote(path))
+	}
+
+	return command
+}
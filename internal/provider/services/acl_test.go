@@ -0,0 +1,44 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeACL(t *testing.T) {
+	actual, err := NormalizeACL([]string{"u:alice:rw", "group:ops:xr", "d:g:ops:r", "user:alice:rw-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"default:group:ops:r--", "group:ops:r-x", "user:alice:rw-"}
+	if !slices.Equal(actual, expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+
+	for _, entry := range []string{"other::r--", "mask::rwx", "user::rw-", "user:alice:rwz", "alice:rw"} {
+		if _, err := NormalizeACL([]string{entry}); err == nil {
+			t.Errorf("expected an error for %q", entry)
+		}
+	}
+}
+
+func TestParseGetfacl(t *testing.T) {
+	output := "# file: /srv/shared\n" +
+		"# owner: root\n" +
+		"# group: root\n" +
+		"user::rwx\n" +
+		"user:alice:rwx\t#effective:r-x\n" +
+		"group::r-x\n" +
+		"group:ops:r-x\n" +
+		"mask::r-x\n" +
+		"other::r-x\n" +
+		"default:user::rwx\n" +
+		"default:group:ops:rwx\n"
+
+	expected := []string{"default:group:ops:rwx", "group:ops:r-x", "user:alice:rwx"}
+	actual := ParseGetfacl(output)
+	if !slices.Equal(actual, expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"remote-provider/internal/provider/agent"
+	"remote-provider/internal/provider/servers"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// ErrAgentUnavailable is returned by AgentRequests for hosts the agent cannot run on, callers
+// fall back to shell commands.
+var ErrAgentUnavailable = errors.New("the agent is not available on this host")
+
+// agentMachines maps the architectures of Go to the machine names printed by uname -m.
+var agentMachines = map[string][]string{
+	"amd64": {"x86_64", "amd64"},
+	"arm64": {"aarch64", "arm64"},
+	"386":   {"i386", "i686"},
+	"arm":   {"armv6l", "armv7l"},
+}
+
+// agentBinary returns the local path of the agent binary and its sha256 digest, hashed once
+// per provider run.
+func (service *SSHService) agentBinary() (string, string, error) {
+	binary := service.AgentBinary
+	if binary == "" {
+		executable, err := os.Executable()
+		if err != nil {
+			return "", "", fmt.Errorf("locating the provider executable: %w", err)
+		}
+		binary = executable
+	}
+
+	service.mutex.Lock()
+	digest := service.agentDigest
+	service.mutex.Unlock()
+
+	if digest != "" {
+		return binary, digest, nil
+	}
+
+	file, err := os.Open(binary)
+	if err != nil {
+		return "", "", err
+	}
+	defer func() { _ = file.Close() }()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", "", err
+	}
+	digest = hex.EncodeToString(hash.Sum(nil))
+
+	service.mutex.Lock()
+	service.agentDigest = digest
+	service.mutex.Unlock()
+
+	return binary, digest, nil
+}
+
+// agentProbeCommand prints the kernel and machine of the host, the cache directory the agent
+// is uploaded to, and whether name is already there from a previous run.
+func agentProbeCommand(name string) string {
+	return fmt.Sprintf(
+		`dir="${XDG_CACHE_HOME:-$HOME/.cache}/remote-host"; echo "machine=$(uname -sm)"; mkdir -p "$dir" && cd "$dir" && echo "dir=$(pwd)" && if [ -x %s ]; then echo present; fi`,
+		ShellQuote(name),
+	)
+}
+
+// parseAgentProbe returns the kernel and machine names, the cache directory and whether the
+// agent is present from the output of an agentProbeCommand.
+func parseAgentProbe(output string) (string, string, string, bool) {
+	var system, machine, dir string
+	var present bool
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "machine="):
+			fields := strings.Fields(strings.TrimPrefix(line, "machine="))
+			if len(fields) == 2 {
+				system, machine = strings.ToLower(fields[0]), fields[1]
+			}
+		case strings.HasPrefix(line, "dir="):
+			dir = strings.TrimPrefix(line, "dir=")
+		case line == "present":
+			present = true
+		}
+	}
+
+	return system, machine, dir, present
+}
+
+// agentPath returns the path of the agent on server, uploading it to the cache directory of
+// the user when missing. Hosts the agent cannot run on are remembered for the provider run.
+func (service *SSHService) agentPath(ctx context.Context, server *servers.Server) (string, error) {
+	// Commands of containers do not see the files of the host.
+	if !service.Agent || service.Fake != nil || server.Container != "" {
+		return "", ErrAgentUnavailable
+	}
+
+	// The agent is installed in the cache directory of the connecting user.
+	key := connectionKey(server)
+
+	service.mutex.Lock()
+	path, ok := service.agents[key]
+	service.mutex.Unlock()
+
+	if ok {
+		if path == "" {
+			return "", ErrAgentUnavailable
+		}
+		return path, nil
+	}
+
+	path, err := service.installAgent(ctx, server)
+	if err != nil && !errors.Is(err, ErrAgentUnavailable) {
+		return "", err
+	}
+
+	service.mutex.Lock()
+	if service.agents == nil {
+		service.agents = map[string]string{}
+	}
+	service.agents[key] = path
+	service.mutex.Unlock()
+
+	return path, err
+}
+
+func (service *SSHService) installAgent(ctx context.Context, server *servers.Server) (string, error) {
+	err := service.OpenConnection(ctx, server)
+	if err != nil {
+		return "", err
+	}
+
+	connection := service.findConnection(server)
+	if connection == nil {
+		return "", fmt.Errorf("no connection found for server %s", server.Name)
+	}
+	if connection.console != nil {
+		return "", ErrAgentUnavailable
+	}
+
+	binary, digest, err := service.agentBinary()
+	if err != nil {
+		return "", err
+	}
+
+	// Local hosts run the binary in place.
+	if connection.local {
+		return binary, nil
+	}
+
+	name := "agent-" + digest[:16]
+	result, err := service.ExecuteCommand(ctx, agentProbeCommand(name), server)
+	if err != nil {
+		return "", fmt.Errorf("probing the agent on %s: %w", server.Name, err)
+	}
+
+	system, machine, dir, present := parseAgentProbe(result.Stdout)
+	if dir == "" {
+		return "", fmt.Errorf("probing the agent on %s: no cache directory", server.Name)
+	}
+
+	// Only the provider executable is checked, a configured binary is trusted to match.
+	if service.AgentBinary == "" && (system != runtime.GOOS || !slices.Contains(agentMachines[runtime.GOARCH], machine)) {
+		tflog.Debug(ctx, "the agent cannot run on the host", map[string]any{"host": server.Name, "system": system, "machine": machine})
+		return "", ErrAgentUnavailable
+	}
+
+	path := dir + "/" + name
+	if present {
+		return path, nil
+	}
+
+	file, err := os.Open(binary)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+
+	// Concurrent first requests may upload it at the same time, each to its own temporary file.
+	tmp := ShellQuote(path) + `".$$.tmp"`
+	_, err = service.upload(ctx, server, fmt.Sprintf("cat > %s && chmod 700 %s && mv -f %s %s", tmp, tmp, tmp, ShellQuote(path)), file)
+	if err != nil {
+		return "", fmt.Errorf("uploading the agent to %s: %w", server.Name, err)
+	}
+
+	tflog.Info(ctx, "agent uploaded", map[string]any{"host": server.Name, "path": path})
+
+	return path, nil
+}
+
+// AgentRequests runs requests with the agent of server as the connecting user and returns
+// their responses in order. ErrAgentUnavailable is returned when the agent is disabled or
+// cannot run on the host.
+func (service *SSHService) AgentRequests(ctx context.Context, server *servers.Server, requests []agent.Request) ([]agent.Response, error) {
+	service.recordOperation(ctx, server)
+
+	path, err := service.agentPath(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+
+	var input bytes.Buffer
+	encoder := json.NewEncoder(&input)
+	for _, request := range requests {
+		if err := encoder.Encode(request); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := service.upload(ctx, server, ShellQuote(path)+" -"+agent.Flag, &input)
+	if err != nil {
+		return nil, fmt.Errorf("running the agent on %s: %w", server.Name, err)
+	}
+
+	responses := make([]agent.Response, 0, len(requests))
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "{") {
+			continue
+		}
+
+		var response agent.Response
+		if err := json.Unmarshal([]byte(line), &response); err != nil {
+			return nil, fmt.Errorf("running the agent on %s: %w", server.Name, err)
+		}
+		responses = append(responses, response)
+	}
+
+	if len(responses) != len(requests) {
+		return nil, fmt.Errorf("running the agent on %s: expected %d responses, got %d", server.Name, len(requests), len(responses))
+	}
+
+	return responses, nil
+}
+
+// FileChecksums returns the sha256 digest of every path on server, "missing" for missing files.
+// An error is returned when the host cannot compute them, so an unknown digest is never compared
+// as a checksum. The agent is used when available, privileged reads only when the host logs in
+// as root.
+func (service *SSHService) FileChecksums(ctx context.Context, server *servers.Server, paths []string, privileged bool) ([]string, error) {
+	if !privileged || runsAsRoot(server) {
+		requests := make([]agent.Request, 0, len(paths))
+		for _, path := range paths {
+			requests = append(requests, agent.Request{Op: agent.OpHash, Path: path, Algorithm: "sha256"})
+		}
+
+		responses, err := service.AgentBatched(ctx, server, requests)
+		if err == nil {
+			checksums := make([]string, 0, len(responses))
+			for i, response := range responses {
+				switch {
+				case response.Error != "":
+					return nil, fmt.Errorf("hashing %s on %s: %s", paths[i], server.Name, response.Error)
+				case !response.Exists:
+					checksums = append(checksums, "missing")
+				default:
+					checksums = append(checksums, response.Checksum)
+				}
+			}
+
+			return checksums, nil
+		}
+		if !errors.Is(err, ErrAgentUnavailable) {
+			return nil, err
+		}
+	}
+
+	err := service.OpenConnection(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+
+	command := ChecksumFilesCommand(paths)
+	if privileged {
+		command = PrivilegedCommand(server, command)
+	}
+
+	result, err := service.ExecuteCommand(ctx, command, server)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums, err := ParseFileLines(result.Stdout, len(paths))
+	if err != nil {
+		return nil, err
+	}
+
+	// The host prints "-" without sha256sum.
+	for i, checksum := range checksums {
+		if checksum == "-" {
+			return nil, fmt.Errorf("unable to compute the sha256 checksum of %s on %s, install sha256sum or enable the agent", paths[i], server.Name)
+		}
+	}
+
+	return checksums, nil
+}
+
+// FileInfo is the metadata of a file on a host read by StatFile.
+type FileInfo struct {
+	Exists    bool
+	IsSymlink bool
+	// Inode and ModTime describe the target of a symlink when it is followed.
+	Inode   string
+	ModTime int64
+	// Checksum is the digest of the target of a symlink, empty when not asked for or when the
+	// path is not a regular file.
+	Checksum string
+}
+
+// StatFile reads the metadata of path on server with the agent, following symlinks when follow
+// is set, and its digest with algorithm when not empty. The requests are batched with the ones
+// of the other files of the host. ErrAgentUnavailable is returned when the agent cannot be
+// used, privileged reads only run when the host logs in as root.
+func (service *SSHService) StatFile(ctx context.Context, server *servers.Server, path string, follow bool, algorithm string, privileged bool) (FileInfo, error) {
+	if privileged && !runsAsRoot(server) {
+		return FileInfo{}, ErrAgentUnavailable
+	}
+
+	requests := []agent.Request{{Op: agent.OpStat, Path: path}, {Op: agent.OpStat, Path: path, Follow: follow}}
+	if algorithm != "" {
+		requests = append(requests, agent.Request{Op: agent.OpHash, Path: path, Algorithm: algorithm})
+	}
+
+	responses, err := service.AgentBatched(ctx, server, requests)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	for _, response := range responses {
+		if response.Error != "" {
+			return FileInfo{}, fmt.Errorf("reading the metadata of %s on %s: %s", path, server.Name, response.Error)
+		}
+	}
+
+	link, target := responses[0], responses[1]
+	if !link.Exists {
+		return FileInfo{}, nil
+	}
+	if !target.Exists {
+		return FileInfo{}, fmt.Errorf("the target of the symlink %s on %s does not exist", path, server.Name)
+	}
+	if target.Inode == 0 {
+		// Agents built for hosts without inode numbers cannot identify the file.
+		return FileInfo{}, ErrAgentUnavailable
+	}
+
+	info := FileInfo{
+		Exists:    true,
+		IsSymlink: link.Type == "symlink",
+		Inode:     strconv.FormatUint(target.Inode, 10),
+		ModTime:   target.ModTime,
+	}
+	if algorithm != "" {
+		info.Checksum = responses[2].Checksum
+	}
+
+	return info, nil
+}
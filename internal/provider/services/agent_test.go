@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"remote-provider/internal/provider/agent"
+	"remote-provider/internal/provider/servers"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testAgentEnv makes the test binary serve agent requests, as the provider binary does.
+const testAgentEnv = "REMOTE_HOST_TEST_AGENT"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testAgentEnv) == "1" {
+		if err := agent.Serve(os.Stdin, os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestParseAgentProbe(t *testing.T) {
+	system, machine, dir, present := parseAgentProbe("Welcome\r\nmachine=Linux x86_64\r\ndir=/home/deploy/.cache/remote-host\r\npresent\r\n")
+	if system != "linux" || machine != "x86_64" || dir != "/home/deploy/.cache/remote-host" || !present {
+		t.Errorf("got %q, %q, %q, %t", system, machine, dir, present)
+	}
+
+	if _, _, _, present := parseAgentProbe("machine=Linux x86_64\ndir=/root/.cache/remote-host\n"); present {
+		t.Error("expected the agent to be missing")
+	}
+}
+
+func TestFileChecksums(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(testAgentEnv, "1")
+
+	dir := t.TempDir()
+	present := filepath.Join(dir, "app.conf")
+	if err := os.WriteFile(present, []byte("hello\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{present, filepath.Join(dir, "missing")}
+	want := []string{"5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03", "missing"}
+
+	for _, enabled := range []bool{true, false} {
+		server := &servers.Server{Name: "localhost", Address: "localhost", Port: 22, Local: true}
+		service := &SSHService{Agent: enabled, AgentBinary: executable}
+
+		checksums, err := service.FileChecksums(context.Background(), server, paths, false)
+		if err != nil || !slices.Equal(checksums, want) {
+			t.Errorf("agent %t: got %v, %v, want %v", enabled, checksums, err, want)
+		}
+
+		_, err = service.AgentRequests(context.Background(), server, nil)
+		if enabled == (err != nil) {
+			t.Errorf("agent %t: got error %v", enabled, err)
+		}
+	}
+}
+
+func TestFileChecksumsWithoutTool(t *testing.T) {
+	server := &servers.Server{Name: "alpine", User: "root"}
+	service := &SSHService{Fake: &FakeTransport{Responses: []FakeResponse{
+		{Pattern: regexp.MustCompile(`sha256sum`), Stdout: "-\nmissing\n"},
+	}}}
+
+	_, err := service.FileChecksums(context.Background(), server, []string{"/etc/app.conf", "/etc/missing"}, false)
+	if err == nil || !strings.Contains(err.Error(), "/etc/app.conf") {
+		t.Errorf("expected an error for the file the host could not hash, got %v", err)
+	}
+}
+
+func TestAgentBatched(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(testAgentEnv, "1")
+
+	server := &servers.Server{Name: "localhost", Address: "localhost", Port: 22, Local: true}
+	service := &SSHService{Agent: true, AgentBinary: executable}
+	dir := t.TempDir()
+
+	// Digesting the agent binary outlasts the batch window, it is done before the requests race.
+	if _, err := service.agentPath(context.Background(), server); err != nil {
+		t.Fatal(err)
+	}
+
+	var wait sync.WaitGroup
+	sizes := make([]int64, 5)
+	errs := make([]error, 5)
+	for i := range sizes {
+		if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(i)), make([]byte, i), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := range sizes {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+
+			path := filepath.Join(dir, strconv.Itoa(i))
+			responses, err := service.AgentBatched(context.Background(), server, []agent.Request{{Op: agent.OpStat, Path: path}})
+			if err == nil {
+				sizes[i] = responses[0].Size
+			}
+			errs[i] = err
+		}()
+	}
+	wait.Wait()
+
+	for i := range sizes {
+		if errs[i] != nil || sizes[i] != int64(i) {
+			t.Errorf("request %d: got size %d, %v", i, sizes[i], errs[i])
+		}
+	}
+
+	if history := service.History(server.Name, 0); len(history) != 1 {
+		t.Errorf("expected the requests to share one agent run, got %d commands", len(history))
+	}
+}
+
+func TestStatFile(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(testAgentEnv, "1")
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "app.conf")
+	if err := os.WriteFile(target, []byte("hello\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "current.conf")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &servers.Server{Name: "localhost", Address: "localhost", Port: 22, Local: true, User: "deploy"}
+	service := &SSHService{Agent: true, AgentBinary: executable}
+	ctx := context.Background()
+
+	stat, err := service.StatFile(ctx, server, target, true, "", false)
+	if err != nil || !stat.Exists || stat.IsSymlink || stat.Inode == "" || stat.Inode == "0" || stat.Checksum != "" {
+		t.Fatalf("got %+v, %v for the target", stat, err)
+	}
+
+	followed, err := service.StatFile(ctx, server, link, true, "sha256", false)
+	want := FileInfo{Exists: true, IsSymlink: true, Inode: stat.Inode, ModTime: info.ModTime().Unix(), Checksum: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"}
+	if err != nil || followed != want {
+		t.Errorf("got %+v, %v, want %+v", followed, err, want)
+	}
+	if stat, err := service.StatFile(ctx, server, filepath.Join(dir, "missing"), true, "sha256", false); err != nil || stat.Exists {
+		t.Errorf("got %+v, %v for a missing file", stat, err)
+	}
+	if _, err := service.StatFile(ctx, server, target, true, "", true); !errors.Is(err, ErrAgentUnavailable) {
+		t.Errorf("expected privileged reads of %s to need root, got %v", server.User, err)
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var atJobRegexp = regexp.MustCompile(`job (\d+) at (.+)`)
+
+// ScheduleCommand returns a command queueing command with at(1) for timespec, e.g. "now + 1 hour".
+func ScheduleCommand(command string, timespec string) string {
+	var words []string
+	for _, word := range strings.Fields(timespec) {
+		words = append(words, ShellQuote(word))
+	}
+
+	return fmt.Sprintf("printf '%%s\\n' %s | at %s 2>&1", ShellQuote(command), strings.Join(words, " "))
+}
+
+// ParseAtJob extracts the job number and the scheduled time from the output of at.
+func ParseAtJob(output string) (string, string, error) {
+	match := atJobRegexp.FindStringSubmatch(output)
+	if match == nil {
+		return "", "", fmt.Errorf("unable to find the job in the at output %q", strings.TrimSpace(output))
+	}
+
+	return match[1], strings.TrimSpace(match[2]), nil
+}
+
+// AtqHasJob reports whether the atq output lists job as pending.
+func AtqHasJob(output string, job string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == job {
+			return true
+		}
+	}
+
+	return false
+}
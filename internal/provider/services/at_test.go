@@ -0,0 +1,32 @@
+package services
+
+import "testing"
+
+func TestParseAtJob(t *testing.T) {
+	output := "warning: commands will be executed using /bin/sh\r\njob 12 at Sat Oct 17 23:00:00 2026\r\n"
+
+	job, when, err := ParseAtJob(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job != "12" || when != "Sat Oct 17 23:00:00 2026" {
+		t.Fatalf("unexpected job %q at %q", job, when)
+	}
+
+	if _, _, err := ParseAtJob("syntax error. Last token seen: x"); err == nil {
+		t.Fatalf("expected an error for a rejected timespec")
+	}
+}
+
+func TestAtqHasJob(t *testing.T) {
+	output := "12\tSat Oct 17 23:00:00 2026 a root\n120\tSun Oct 18 01:00:00 2026 a root\n"
+
+	if !AtqHasJob(output, "12") {
+		t.Errorf("expected job 12 to be pending")
+	}
+
+	if AtqHasJob(output, "1") {
+		t.Errorf("expected job 1 not to be pending")
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FileFlags holds the inode flags managed through chattr.
+type FileFlags struct {
+	Immutable  bool
+	AppendOnly bool
+}
+
+// GetFlagsCommand returns a command printing the inode flags of path.
+func GetFlagsCommand(path string) string {
+	return fmt.Sprintf("lsattr -d -- %s", ShellQuote(path))
+}
+
+// ParseLsattr reads the immutable and append-only flags from lsattr -d output.
+func ParseLsattr(output string) (FileFlags, error) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.Trim(fields[0], "-abcdeijmsutACDEFINPSTVx") != "" {
+			continue
+		}
+
+		return FileFlags{
+			Immutable:  strings.Contains(fields[0], "i"),
+			AppendOnly: strings.Contains(fields[0], "a"),
+		}, nil
+	}
+
+	return FileFlags{}, fmt.Errorf("unable to parse lsattr output %q", output)
+}
+
+// SetFlagsCommand returns a command setting the immutable and append-only flags of path to flags.
+func SetFlagsCommand(path string, flags FileFlags) string {
+	modes := []string{"-i", "-a"}
+	if flags.Immutable {
+		modes[0] = "+i"
+	}
+	if flags.AppendOnly {
+		modes[1] = "+a"
+	}
+
+	return fmt.Sprintf("chattr %s -- %s", strings.Join(modes, " "), ShellQuote(path))
+}
+
+// GetXattrsCommand returns a command dumping the extended attributes of path with base64 values.
+// Symlinks are dereferenced when follow is set, otherwise the attributes of the link are read.
+func GetXattrsCommand(path string, follow bool) string {
+	return fmt.Sprintf("getfattr %s--absolute-names -d -m - -e base64 -- %s", noDereferenceFlag(follow), ShellQuote(path))
+}
+
+func noDereferenceFlag(follow bool) string {
+	if follow {
+		return ""
+	}
+
+	return "-h "
+}
+
+// ParseGetfattr decodes getfattr -d output into a map of attribute names and values.
+func ParseGetfattr(output string) (map[string]string, error) {
+	xattrs := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, found := strings.Cut(line, "=")
+		if !found {
+			xattrs[name] = ""
+			continue
+		}
+
+		decoded, err := decodeXattrValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode extended attribute %s: %w", name, err)
+		}
+
+		xattrs[name] = decoded
+	}
+
+	return xattrs, nil
+}
+
+func decodeXattrValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "0s"):
+		decoded, err := base64.StdEncoding.DecodeString(value[2:])
+		return string(decoded), err
+	case strings.HasPrefix(value, "\""):
+		return strconv.Unquote(value)
+	}
+
+	return value, nil
+}
+
+// SetXattrsCommand returns a command setting xattrs on path and removing the attributes in removed.
+func SetXattrsCommand(path string, xattrs map[string]string, removed []string, follow bool) string {
+	var commands []string
+	for _, name := range slices.Sorted(maps.Keys(xattrs)) {
+		commands = append(commands, fmt.Sprintf("setfattr %s-n %s -v %s -- %s",
+			noDereferenceFlag(follow),
+			ShellQuote(name),
+			ShellQuote("0s"+base64.StdEncoding.EncodeToString([]byte(xattrs[name]))),
+			ShellQuote(path),
+		))
+	}
+
+	for _, name := range removed {
+		commands = append(commands, fmt.Sprintf("setfattr %s-x %s -- %s", noDereferenceFlag(follow), ShellQuote(name), ShellQuote(path)))
+	}
+
+	return strings.Join(commands, " && ")
+}
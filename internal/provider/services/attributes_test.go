@@ -0,0 +1,55 @@
+package services
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseLsattr(t *testing.T) {
+	cases := map[string]FileFlags{
+		"--------------e------- /etc/hosts":          {},
+		"----i---------e------- /etc/hosts":          {Immutable: true},
+		"-----a--------e------- /var/log/audit.log":  {AppendOnly: true},
+		"banner\n----ia--------e------- /etc/passwd": {Immutable: true, AppendOnly: true},
+	}
+
+	for output, expected := range cases {
+		actual, err := ParseLsattr(output)
+		if err != nil {
+			t.Fatalf("%q: %s", output, err)
+		}
+
+		if actual != expected {
+			t.Errorf("%q: expected %+v, got %+v", output, expected, actual)
+		}
+	}
+
+	if _, err := ParseLsattr("lsattr: Operation not supported"); err == nil {
+		t.Fatalf("expected an error for unsupported filesystems")
+	}
+}
+
+func TestParseGetfattr(t *testing.T) {
+	output := "# file: /srv/data\n" +
+		"user.owner=0sYWxpY2U=\n" +
+		"user.quoted=\"a b\"\n" +
+		"user.empty\n"
+
+	actual, err := ParseGetfattr(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"user.owner": "alice", "user.quoted": "a b", "user.empty": ""}
+	if !maps.Equal(actual, expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestSetFlagsCommand(t *testing.T) {
+	actual := SetFlagsCommand("/etc/hosts", FileFlags{Immutable: true})
+	expected := "chattr +i -a -- '/etc/hosts'"
+	if actual != expected {
+		t.Fatalf("expected %s, got %s", expected, actual)
+	}
+}
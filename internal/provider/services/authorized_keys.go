@@ -0,0 +1,132 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultAuthorizedKeysFiles are the files sshd reads the authorized keys of a user from when
+// AuthorizedKeysFile is not set.
+var DefaultAuthorizedKeysFiles = []string{".ssh/authorized_keys", ".ssh/authorized_keys2"}
+
+// authorizedKeysMarker prefixes the lines of AuthorizedKeysCommand holding the content of a file.
+const authorizedKeysMarker = "remote-host-authorized-keys"
+
+// AuthorizedKey is an entry of an authorized_keys file.
+type AuthorizedKey struct {
+	File string
+	// Line is the line number of the entry in File, starting at 1.
+	Line    int
+	Type    string
+	Options []string
+	Comment string
+	// PublicKey is the key in authorized_keys format, without options nor comment.
+	PublicKey string
+	// Fingerprint is the SHA256 fingerprint of the key as printed by ssh-keygen -l.
+	Fingerprint string
+}
+
+// authorizedKeysPath returns the shell word of file for user, expanding the %h, %u and %% tokens
+// as sshd does. Relative files are relative to the home directory held by $home.
+func authorizedKeysPath(file string, user string) string {
+	word := ""
+	if !strings.HasPrefix(file, "/") && !strings.HasPrefix(file, "%h") {
+		word = `"$home"/`
+	}
+
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			word += ShellQuote(literal.String())
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(file); i++ {
+		if file[i] != '%' || i+1 == len(file) {
+			literal.WriteByte(file[i])
+			continue
+		}
+
+		i++
+		switch file[i] {
+		case 'h':
+			flush()
+			word += `"$home"`
+		case 'u':
+			literal.WriteString(user)
+		default:
+			literal.WriteByte(file[i])
+		}
+	}
+	flush()
+
+	return word
+}
+
+// AuthorizedKeysCommand returns a command printing the base64 encoded content of the files of
+// user that exist, read back by ParseAuthorizedKeys. It fails when the user does not exist.
+func (p Platform) AuthorizedKeysCommand(user string, files []string) string {
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, authorizedKeysPath(file, user))
+	}
+
+	return strings.Join([]string{
+		fmt.Sprintf(`id -u %s >/dev/null 2>&1 || { echo "no such user: "%s >&2; exit 1; }`, ShellQuote(user), ShellQuote(user)),
+		fmt.Sprintf(`home=$(%s)`, p.HomeDirectoryCommand(user)),
+		fmt.Sprintf(`for path in %s; do if [ -f "$path" ]; then printf '%s\t%%s\t' "$path"; base64 < "$path" | tr -d '\n'; echo; fi; done`,
+			strings.Join(paths, " "), authorizedKeysMarker),
+	}, "\n")
+}
+
+// ParseAuthorizedKeys reads the output of AuthorizedKeysCommand. Entries that are not valid keys
+// are returned as "file:line" in invalid, sshd ignores them as well. Comments and blank lines are
+// skipped.
+func ParseAuthorizedKeys(output string) (keys []AuthorizedKey, invalid []string, err error) {
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		rest, found := strings.CutPrefix(line, authorizedKeysMarker+"\t")
+		if !found {
+			continue
+		}
+
+		separator := strings.LastIndex(rest, "\t")
+		if separator < 0 {
+			return nil, nil, fmt.Errorf("unexpected authorized keys output %q", line)
+		}
+
+		file := rest[:separator]
+		content, err := base64.StdEncoding.DecodeString(strings.TrimSpace(rest[separator+1:]))
+		if err != nil {
+			return nil, nil, fmt.Errorf("decoding %s: %w", file, err)
+		}
+
+		for number, entry := range strings.Split(string(content), "\n") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" || strings.HasPrefix(entry, "#") {
+				continue
+			}
+
+			key, comment, options, _, err := ssh.ParseAuthorizedKey([]byte(entry))
+			if err != nil {
+				invalid = append(invalid, fmt.Sprintf("%s:%d", file, number+1))
+				continue
+			}
+
+			keys = append(keys, AuthorizedKey{
+				File:        file,
+				Line:        number + 1,
+				Type:        key.Type(),
+				Options:     options,
+				Comment:     comment,
+				PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+				Fingerprint: ssh.FingerprintSHA256(key),
+			})
+		}
+	}
+
+	return keys, invalid, nil
+}
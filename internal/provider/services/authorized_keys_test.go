@@ -0,0 +1,124 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func testAuthorizedKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func TestAuthorizedKeysPath(t *testing.T) {
+	cases := map[string]string{
+		".ssh/authorized_keys":         `"$home"/'.ssh/authorized_keys'`,
+		"%h/.ssh/keys":                 `"$home"'/.ssh/keys'`,
+		"/etc/ssh/keys/%u":             `'/etc/ssh/keys/alice'`,
+		"/etc/ssh/100%%/%u.pub":        `'/etc/ssh/100%/alice.pub'`,
+		"/etc/ssh/it's%":               `'/etc/ssh/it'"'"'s%'`,
+		"%h":                           `"$home"`,
+		"keys/%u/%h-not-at-the-start%": `"$home"/'keys/alice/'"$home"'-not-at-the-start%'`,
+	}
+
+	for file, expected := range cases {
+		if actual := authorizedKeysPath(file, "alice"); actual != expected {
+			t.Errorf("%q: expected %s, got %s", file, expected, actual)
+		}
+	}
+}
+
+func TestAuthorizedKeysCommand(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+	if _, err := exec.LookPath("base64"); err != nil {
+		t.Skip("base64 is not installed")
+	}
+
+	key := testAuthorizedKey(t)
+	authorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+
+	dir := t.TempDir()
+	content := strings.Join([]string{
+		"# deploy keys",
+		"",
+		`from="10.0.0.0/8",no-pty ` + authorized + " deploy@ci",
+		"not a key",
+		authorized,
+	}, "\n")
+	if err := os.WriteFile(filepath.Join(dir, "keys"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	command := Platform{OS: "linux"}.AuthorizedKeysCommand(current.Username, []string{
+		filepath.Join(dir, "keys"),
+		filepath.Join(dir, "missing"),
+	})
+	output, err := exec.Command("sh", "-c", command).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, invalid, err := ParseAuthorizedKeys("Welcome!\n" + string(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %+v", keys)
+	}
+
+	first := keys[0]
+	if first.File != filepath.Join(dir, "keys") || first.Line != 3 || first.Type != ssh.KeyAlgoED25519 ||
+		first.Comment != "deploy@ci" || strings.Join(first.Options, ",") != `from="10.0.0.0/8",no-pty` ||
+		first.PublicKey != authorized || first.Fingerprint != ssh.FingerprintSHA256(key) {
+		t.Errorf("unexpected key %+v", first)
+	}
+
+	if keys[1].Line != 5 || keys[1].Comment != "" || len(keys[1].Options) != 0 {
+		t.Errorf("unexpected key %+v", keys[1])
+	}
+
+	if len(invalid) != 1 || invalid[0] != filepath.Join(dir, "keys")+":4" {
+		t.Errorf("unexpected invalid lines %v", invalid)
+	}
+}
+
+func TestAuthorizedKeysCommandUnknownUser(t *testing.T) {
+	command := Platform{OS: "linux"}.AuthorizedKeysCommand("no-such-user-for-tests", DefaultAuthorizedKeysFiles)
+	if err := exec.Command("sh", "-c", command).Run(); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+}
+
+func TestParseAuthorizedKeysInvalidOutput(t *testing.T) {
+	for _, output := range []string{
+		authorizedKeysMarker + "\tno-content",
+		authorizedKeysMarker + "\t/root/.ssh/authorized_keys\tnot base64!",
+	} {
+		if _, _, err := ParseAuthorizedKeys(output); err == nil {
+			t.Errorf("%q: expected an error", output)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"remote-provider/internal/provider/agent"
+	"remote-provider/internal/provider/servers"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// batchWindow is how long the first command of a batch waits for others to join it.
+	batchWindow = 20 * time.Millisecond
+	// maxBatchSize bounds the number of commands, and so the output size, of a batch.
+	maxBatchSize = 64
+)
+
+type batchedCommand struct {
+	command string
+	// requests replace command in the batches of agent requests, see AgentBatched.
+	requests []agent.Request
+	done     chan batchedResult
+}
+
+type batchedResult struct {
+	command   *servers.ServerCommand
+	responses []agent.Response
+	err       error
+}
+
+// commandBatch collects the commands issued for a host during the batch window.
+type commandBatch struct {
+	server *servers.Server
+	// key is the connectionKey of server.
+	key      string
+	ctx      context.Context
+	deadline time.Time
+	commands []*batchedCommand
+	timer    *time.Timer
+}
+
+// commandBatcher coalesces read-only commands issued concurrently on the same connection, e.g.
+// by the Read of every file managed on a host during a refresh, into a single remote command.
+type commandBatcher struct {
+	mutex   sync.Mutex
+	batches map[string]*commandBatch
+}
+
+// ExecuteBatched runs command on server like ExecuteCommand, but together with the other
+// commands batched on the same connection within a few milliseconds. Batched commands run one
+// after the other in subshells, so they must not depend on each other. A non-zero exit code
+// is only reported in the ExitCode of the result.
+func (service *SSHService) ExecuteBatched(ctx context.Context, command string, server *servers.Server) (*servers.ServerCommand, error) {
+	service.recordOperation(ctx, server)
+	if err := service.beginApply(ctx, server); err != nil {
+		return nil, err
+	}
+
+	request := &batchedCommand{command: command, done: make(chan batchedResult, 1)}
+	service.batcher.add(ctx, server, request, service.runBatch)
+
+	select {
+	case result := <-request.done:
+		return result.command, result.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a batched command on %s: %w", server.Name, ctx.Err())
+	}
+}
+
+// AgentBatched runs requests with the agent of server like AgentRequests, but together with the
+// requests batched on the same connection within a few milliseconds, so the Read of every resource
+// on a host shares a single round trip.
+func (service *SSHService) AgentBatched(ctx context.Context, server *servers.Server, requests []agent.Request) ([]agent.Response, error) {
+	service.recordOperation(ctx, server)
+
+	// Unavailable agents are known before waiting for a batch.
+	if _, err := service.agentPath(ctx, server); err != nil {
+		return nil, err
+	}
+
+	request := &batchedCommand{requests: requests, done: make(chan batchedResult, 1)}
+	service.agentCalls.add(ctx, server, request, service.runAgentBatch)
+
+	select {
+	case result := <-request.done:
+		return result.responses, result.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for batched agent requests on %s: %w", server.Name, ctx.Err())
+	}
+}
+
+func (batcher *commandBatcher) add(ctx context.Context, server *servers.Server, request *batchedCommand, run func(*commandBatch)) {
+	batcher.mutex.Lock()
+	defer batcher.mutex.Unlock()
+
+	if batcher.batches == nil {
+		batcher.batches = map[string]*commandBatch{}
+	}
+
+	// Only the commands sharing a connection, and so its credentials, are batched together.
+	key := connectionKey(server)
+	batch := batcher.batches[key]
+	if batch == nil {
+		// The batch outlives the caller that opened it, it ends with the last deadline of its commands.
+		batch = &commandBatch{server: server, key: key, ctx: context.WithoutCancel(ctx)}
+		batcher.batches[key] = batch
+		batch.timer = time.AfterFunc(batchWindow, func() {
+			batcher.flush(batch, run)
+		})
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && deadline.After(batch.deadline) {
+		batch.deadline = deadline
+	}
+	batch.commands = append(batch.commands, request)
+
+	if len(batch.commands) >= maxBatchSize && batch.timer.Stop() {
+		delete(batcher.batches, key)
+		go run(batch)
+	}
+}
+
+func (batcher *commandBatcher) flush(batch *commandBatch, run func(*commandBatch)) {
+	batcher.mutex.Lock()
+	if batcher.batches[batch.key] == batch {
+		delete(batcher.batches, batch.key)
+	}
+	batcher.mutex.Unlock()
+
+	run(batch)
+}
+
+// runBatch executes the commands of batch as one remote command and hands every caller its result.
+func (service *SSHService) runBatch(batch *commandBatch) {
+	ctx := batch.ctx
+	if !batch.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, batch.deadline)
+		defer cancel()
+	}
+
+	if len(batch.commands) == 1 {
+		result, err := service.ExecuteCommand(ctx, batch.commands[0].command, batch.server)
+		batch.commands[0].done <- batchedResult{command: result, err: err}
+		return
+	}
+
+	commands := make([]string, len(batch.commands))
+	for i, request := range batch.commands {
+		commands[i] = request.command
+	}
+
+	results, err := service.executeBatch(ctx, commands, batch.server)
+	for i, request := range batch.commands {
+		if err != nil {
+			request.done <- batchedResult{err: err}
+			continue
+		}
+
+		request.done <- batchedResult{command: results[i]}
+	}
+}
+
+// runAgentBatch sends the requests of batch to the agent at once and hands every caller its
+// responses.
+func (service *SSHService) runAgentBatch(batch *commandBatch) {
+	ctx := batch.ctx
+	if !batch.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, batch.deadline)
+		defer cancel()
+	}
+
+	var requests []agent.Request
+	for _, request := range batch.commands {
+		requests = append(requests, request.requests...)
+	}
+
+	responses, err := service.AgentRequests(ctx, batch.server, requests)
+	for _, request := range batch.commands {
+		if err != nil {
+			request.done <- batchedResult{err: err}
+			continue
+		}
+
+		request.done <- batchedResult{responses: responses[:len(request.requests)]}
+		responses = responses[len(request.requests):]
+	}
+}
+
+func (service *SSHService) executeBatch(ctx context.Context, commands []string, server *servers.Server) ([]*servers.ServerCommand, error) {
+	token := make([]byte, 8)
+	_, err := rand.Read(token)
+	if err != nil {
+		return nil, err
+	}
+	marker := "REMOTE-HOST-BATCH-" + hex.EncodeToString(token)
+
+	result, err := service.ExecuteCommand(ctx, batchScript(marker, commands), server)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := splitBatchOutput(marker, result.Stdout, commands)
+	if err != nil {
+		return nil, err
+	}
+
+	// The commands of a batch are not timed separately, they share the timing of the batch.
+	for _, command := range results {
+		command.StartedAt = result.StartedAt
+		command.FinishedAt = result.FinishedAt
+	}
+
+	return results, nil
+}
+
+// batchScript runs every command in a subshell and prints its exit code in a marker line
+// after its output.
+func batchScript(marker string, commands []string) string {
+	script := []string{fmt.Sprintf("printf '\\n%%s\\n' %s", marker)}
+	for i, command := range commands {
+		script = append(script, fmt.Sprintf("(\n%s\n); printf '\\n%%s %d %%d\\n' %s $?", command, i, marker))
+	}
+
+	return strings.Join(script, "\n")
+}
+
+// splitBatchOutput splits the output of a batchScript into the result of every command.
+func splitBatchOutput(marker string, output string, commands []string) ([]*servers.ServerCommand, error) {
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+
+	begin := strings.Index(output, marker+"\n")
+	if begin < 0 {
+		return nil, fmt.Errorf("unable to find the batch output")
+	}
+	output = output[begin+len(marker)+1:]
+
+	results := make([]*servers.ServerCommand, len(commands))
+	for i, command := range commands {
+		prefix := fmt.Sprintf("\n%s %d ", marker, i)
+		end := strings.Index(output, prefix)
+		if end < 0 {
+			return nil, fmt.Errorf("unable to find the output of batched command %d", i)
+		}
+
+		stdout := output[:end]
+		output = output[end+len(prefix):]
+
+		status, rest, _ := strings.Cut(output, "\n")
+		exitCode, err := strconv.Atoi(strings.TrimSpace(status))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the exit code of batched command %d: %w", i, err)
+		}
+		output = rest
+
+		results[i] = &servers.ServerCommand{Command: command, Stdout: stdout, ExitCode: int8(exitCode)}
+	}
+
+	return results, nil
+}
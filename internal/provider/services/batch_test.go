@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"remote-provider/internal/provider/servers"
+	"testing"
+)
+
+func TestSplitBatchOutput(t *testing.T) {
+	marker := "REMOTE-HOST-BATCH-0011223344556677"
+	commands := []string{"cat /etc/hostname", "cat /missing", "printf 'a\\n\\nb'"}
+
+	output := "Last login: Sat Oct 17 10:00:00 2026\r\n" +
+		"\r\n" + marker + "\r\n" +
+		"web-1\r\n" +
+		"\r\n" + marker + " 0 0\r\n" +
+		"cat: /missing: No such file or directory\r\n" +
+		"\r\n" + marker + " 1 1\r\n" +
+		"a\r\n\r\nb" +
+		"\r\n" + marker + " 2 0\r\n"
+
+	results, err := splitBatchOutput(marker, output, commands)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		stdout   string
+		exitCode int8
+	}{
+		{"web-1\n", 0},
+		{"cat: /missing: No such file or directory\n", 1},
+		{"a\n\nb", 0},
+	}
+	for i, result := range results {
+		if result.Stdout != want[i].stdout || result.ExitCode != want[i].exitCode || result.Command != commands[i] {
+			t.Errorf("command %d: unexpected result %+v", i, result)
+		}
+	}
+
+	if _, err := splitBatchOutput(marker, output[:len(output)-len(marker)-10], commands); err == nil {
+		t.Fatal("expected an error for truncated output")
+	}
+}
+
+func TestBatchScript(t *testing.T) {
+	script := batchScript("M", []string{"true", "exit 3"})
+
+	want := `printf '\n%s\n' M
+(
+true
+); printf '\n%s 0 %d\n' M $?
+(
+exit 3
+); printf '\n%s 1 %d\n' M $?`
+	if script != want {
+		t.Fatalf("unexpected script:\n%s", script)
+	}
+}
+
+func TestCommandBatcherCoalesces(t *testing.T) {
+	var batcher commandBatcher
+	server := &servers.Server{Name: "host"}
+
+	batches := make(chan *commandBatch, 2)
+	run := func(batch *commandBatch) { batches <- batch }
+
+	for _, command := range []string{"a", "b", "c"} {
+		batcher.add(context.Background(), server, &batchedCommand{command: command}, run)
+	}
+
+	batch := <-batches
+	if len(batch.commands) != 3 {
+		t.Fatalf("expected the 3 commands in one batch, got %d", len(batch.commands))
+	}
+
+	batcher.add(context.Background(), server, &batchedCommand{command: "d"}, run)
+	if batch := <-batches; len(batch.commands) != 1 {
+		t.Fatalf("expected a new batch after the flush, got %d commands", len(batch.commands))
+	}
+}
+
+func TestCommandBatcherSeparatesCredentials(t *testing.T) {
+	var batcher commandBatcher
+	deploy := &servers.Server{Name: "host", User: "deploy", SudoPassword: "first"}
+	admin := &servers.Server{Name: "host", User: "admin", SudoPassword: "second"}
+
+	batches := make(chan *commandBatch, 2)
+	run := func(batch *commandBatch) { batches <- batch }
+
+	batcher.add(context.Background(), deploy, &batchedCommand{command: "a"}, run)
+	batcher.add(context.Background(), admin, &batchedCommand{command: "b"}, run)
+	batcher.add(context.Background(), &servers.Server{Name: "host", User: "deploy", SudoPassword: "first"}, &batchedCommand{command: "c"}, run)
+
+	for range 2 {
+		batch := <-batches
+		switch batch.server {
+		case deploy:
+			if len(batch.commands) != 2 {
+				t.Errorf("expected the 2 commands of deploy in one batch, got %d", len(batch.commands))
+			}
+		case admin:
+			if len(batch.commands) != 1 || batch.commands[0].command != "b" {
+				t.Errorf("expected the command of admin alone in its batch, got %d", len(batch.commands))
+			}
+		default:
+			t.Errorf("unexpected batch server %+v", batch.server)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BlockDevicesCommand lists the block devices of a host as lsblk JSON, one flat entry per device.
+const BlockDevicesCommand = "lsblk --json --list --bytes --output NAME,PATH,PKNAME,TYPE,SIZE,FSTYPE,LABEL,UUID,PARTUUID,SERIAL,WWN,MODEL,MOUNTPOINT"
+
+// BlockDevice is a disk, partition or other block device reported by lsblk.
+type BlockDevice struct {
+	Name       string
+	Path       string
+	Parent     string
+	Type       string
+	Size       int64
+	FSType     string
+	Label      string
+	UUID       string
+	PartUUID   string
+	Serial     string
+	WWN        string
+	Model      string
+	Mountpoint string
+}
+
+// lsblkDevice is a device of the lsblk JSON output. Columns are null when unset, and older
+// versions of lsblk print sizes as strings.
+type lsblkDevice struct {
+	Name       *string         `json:"name"`
+	Path       *string         `json:"path"`
+	PKName     *string         `json:"pkname"`
+	Type       *string         `json:"type"`
+	Size       json.RawMessage `json:"size"`
+	FSType     *string         `json:"fstype"`
+	Label      *string         `json:"label"`
+	UUID       *string         `json:"uuid"`
+	PartUUID   *string         `json:"partuuid"`
+	Serial     *string         `json:"serial"`
+	WWN        *string         `json:"wwn"`
+	Model      *string         `json:"model"`
+	Mountpoint *string         `json:"mountpoint"`
+}
+
+// ParseBlockDevices parses the output of BlockDevicesCommand. Lines printed before the JSON
+// document, e.g. a login banner, are ignored.
+func ParseBlockDevices(output string) ([]BlockDevice, error) {
+	start := strings.Index(output, "{")
+	if start < 0 {
+		return nil, fmt.Errorf("no lsblk JSON output")
+	}
+
+	var document struct {
+		BlockDevices []lsblkDevice `json:"blockdevices"`
+	}
+	err := json.Unmarshal([]byte(output[start:]), &document)
+	if err != nil {
+		return nil, fmt.Errorf("parsing lsblk output: %w", err)
+	}
+
+	devices := make([]BlockDevice, 0, len(document.BlockDevices))
+	for _, device := range document.BlockDevices {
+		size, err := parseLsblkSize(device.Size)
+		if err != nil {
+			return nil, fmt.Errorf("parsing the size of %s: %w", stringValue(device.Name), err)
+		}
+
+		devices = append(devices, BlockDevice{
+			Name:       stringValue(device.Name),
+			Path:       stringValue(device.Path),
+			Parent:     stringValue(device.PKName),
+			Type:       stringValue(device.Type),
+			Size:       size,
+			FSType:     stringValue(device.FSType),
+			Label:      stringValue(device.Label),
+			UUID:       stringValue(device.UUID),
+			PartUUID:   stringValue(device.PartUUID),
+			Serial:     strings.TrimSpace(stringValue(device.Serial)),
+			WWN:        stringValue(device.WWN),
+			Model:      strings.TrimSpace(stringValue(device.Model)),
+			Mountpoint: stringValue(device.Mountpoint),
+		})
+	}
+
+	return devices, nil
+}
+
+// parseLsblkSize parses a size printed either as a number or as a string of digits.
+func parseLsblkSize(raw json.RawMessage) (int64, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return strconv.ParseInt(text, 10, 64)
+	}
+
+	return strconv.ParseInt(string(raw), 10, 64)
+}
+
+func stringValue(pointer *string) string {
+	if pointer == nil {
+		return ""
+	}
+
+	return *pointer
+}
@@ -0,0 +1,36 @@
+package services
+
+import "testing"
+
+func TestParseBlockDevices(t *testing.T) {
+	output := "Welcome\n" + `{
+   "blockdevices": [
+      {"name":"nvme0n1", "path":"/dev/nvme0n1", "pkname":null, "type":"disk", "size":512110190592, "fstype":null, "label":null, "uuid":null, "partuuid":null, "serial":"S4EWNX0R123456  ", "wwn":"eui.002538b", "model":"Samsung SSD 970 ", "mountpoint":null},
+      {"name":"nvme0n1p1", "path":"/dev/nvme0n1p1", "pkname":"nvme0n1", "type":"part", "size":"536870912", "fstype":"vfat", "label":"EFI", "uuid":"A1B2-C3D4", "partuuid":"0b1c", "serial":null, "wwn":"eui.002538b", "model":null, "mountpoint":"/boot/efi"}
+   ]
+}`
+
+	devices, err := ParseBlockDevices(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(devices) != 2 {
+		t.Fatalf("expected 2 devices, got %d", len(devices))
+	}
+
+	disk := devices[0]
+	if disk.Type != "disk" || disk.Size != 512110190592 || disk.Serial != "S4EWNX0R123456" || disk.Model != "Samsung SSD 970" || disk.Parent != "" {
+		t.Errorf("unexpected disk %+v", disk)
+	}
+
+	// Older lsblk versions print sizes as strings.
+	partition := devices[1]
+	if partition.Parent != "nvme0n1" || partition.Size != 536870912 || partition.FSType != "vfat" || partition.Mountpoint != "/boot/efi" {
+		t.Errorf("unexpected partition %+v", partition)
+	}
+
+	if _, err := ParseBlockDevices("lsblk: command not found"); err == nil {
+		t.Fatal("expected an error without JSON output")
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// cancelGracePeriod is how long a canceled command is given to exit after each signal before
+// the next one, then its session is closed.
+const cancelGracePeriod = 5 * time.Second
+
+// cancelSignals are sent in turn to the commands whose context is canceled, e.g. when
+// Terraform is interrupted, so they can clean up before their session is closed.
+var cancelSignals = []ssh.Signal{ssh.SIGINT, ssh.SIGTERM}
+
+// commandMark tags the remote processes of a command so they can be signaled from another
+// session. The signals of the SSH protocol only reach the shell of the session, when the
+// server supports them at all, not the processes it started.
+type commandMark struct {
+	token string
+}
+
+func newCommandMark() (*commandMark, error) {
+	token := make([]byte, 8)
+	_, err := rand.Read(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &commandMark{token: "REMOTE-HOST-CMD-" + hex.EncodeToString(token)}, nil
+}
+
+// wrap prefixes command with a no-op carrying the token, so it shows in the arguments of the
+// shell running it.
+func (mark *commandMark) wrap(command string) string {
+	return fmt.Sprintf(": %s; %s", mark.token, command)
+}
+
+// signalCommand returns a command sending signal to the process groups of the shells running
+// the marked command. The pattern is bracketed so it does not match the shell running it.
+// Processes of another user, e.g. started with sudo, only get the signals sudo relays.
+func (mark *commandMark) signalCommand(signal ssh.Signal) string {
+	pattern := "[" + mark.token[:1] + "]" + mark.token[1:]
+
+	return fmt.Sprintf(
+		`for pid in $(pgrep -f %s 2>/dev/null); do pgid=$(ps -o pgid= -p "$pid" 2>/dev/null | tr -d ' '); kill -s %s -- "-${pgid:-$pid}" 2>/dev/null || kill -s %s "$pid" 2>/dev/null; done; true`,
+		ShellQuote(pattern), signal, signal,
+	)
+}
+
+// interrupter returns a function sending a signal to the processes of the command marked with
+// mark through a new session of connection, without waiting for a free session slot.
+func (service *SSHService) interrupter(connection *SSHConnection, mark *commandMark) func(ssh.Signal) {
+	return func(signal ssh.Signal) {
+		session, err := connection.client.NewSession()
+		if err != nil {
+			return
+		}
+		defer func() { _ = session.Close() }()
+
+		_ = session.Run(service.Wrapper.Wrap(mark.signalCommand(signal)))
+	}
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestCommandMarkSignalCommand(t *testing.T) {
+	for _, tool := range []string{"setsid", "pgrep", "ps"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not installed", tool)
+		}
+	}
+
+	mark, err := newCommandMark()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// setsid gives the command its own process group, as sshd does for its sessions.
+	command := exec.Command("setsid", "sh", "-c", mark.wrap("sleep 30; echo finished"))
+	if err := command.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- command.Wait()
+	}()
+
+	// Give the shell time to start its child.
+	time.Sleep(200 * time.Millisecond)
+
+	if err := exec.Command("sh", "-c", mark.signalCommand(ssh.SIGTERM)).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected the command to be terminated")
+		}
+	case <-time.After(10 * time.Second):
+		_ = command.Process.Kill()
+		t.Fatal("the command was not terminated")
+	}
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// CertbotLiveDir holds the current certificates of the certbot lineages.
+const CertbotLiveDir = "/etc/letsencrypt/live"
+
+// certbotDeployHookDir holds the hooks certbot runs after every renewal.
+const certbotDeployHookDir = "/etc/letsencrypt/renewal-hooks/deploy"
+
+// CertbotNameRegexp matches the names of certbot lineages.
+var CertbotNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// CertificateInfo describes the leaf certificate of a PEM bundle.
+type CertificateInfo struct {
+	NotBefore         time.Time
+	NotAfter          time.Time
+	Serial            string
+	FingerprintSHA256 string
+}
+
+// ParseCertificatePEM returns the description of the first certificate of data.
+func ParseCertificatePEM(data []byte) (CertificateInfo, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return CertificateInfo{}, errors.New("no PEM encoded certificate found")
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return CertificateInfo{}, err
+		}
+
+		fingerprint := sha256.Sum256(block.Bytes)
+		return CertificateInfo{
+			NotBefore:         certificate.NotBefore,
+			NotAfter:          certificate.NotAfter,
+			Serial:            certificate.SerialNumber.Text(16),
+			FingerprintSHA256: hex.EncodeToString(fingerprint[:]),
+		}, nil
+	}
+}
+
+// CertificateFiles are the places a certificate is installed at on a host.
+type CertificateFiles struct {
+	Certificate string
+	Key         string
+	// Chain and FullChain are only installed when set.
+	Chain     string
+	FullChain string
+	// Owner and Group own the files when set.
+	Owner string
+	Group string
+	// ReloadCommand runs once the files are installed, e.g. to reload a web server.
+	ReloadCommand string
+}
+
+// installCommand returns a command installing the file at source to target, private files
+// being readable by their owner only.
+func (f CertificateFiles) installCommand(source string, target string, private bool) string {
+	mode := "0644"
+	if private {
+		mode = "0600"
+	}
+
+	owner := ""
+	if f.Owner != "" {
+		owner += " -o " + ShellQuote(f.Owner)
+	}
+	if f.Group != "" {
+		owner += " -g " + ShellQuote(f.Group)
+	}
+
+	return fmt.Sprintf(`mkdir -p "$(dirname %[3]s)" && install -m %[1]s%[2]s %[4]s %[3]s.new && mv -f %[3]s.new %[3]s`,
+		mode, owner, ShellQuote(target), source)
+}
+
+// DeployHook returns a certbot deploy hook installing the files of the lineage name when
+// certbot renews it, and running the reload command.
+func (f CertificateFiles) DeployHook(name string) string {
+	lines := []string{
+		"#!/bin/sh",
+		"# " + managedHeader,
+		"set -e",
+		fmt.Sprintf(`[ "$RENEWED_LINEAGE" = %s ] || exit 0`, ShellQuote(CertbotLiveDir+"/"+name)),
+		f.installCommand(`"$RENEWED_LINEAGE/cert.pem"`, f.Certificate, false),
+		f.installCommand(`"$RENEWED_LINEAGE/privkey.pem"`, f.Key, true),
+	}
+	if f.Chain != "" {
+		lines = append(lines, f.installCommand(`"$RENEWED_LINEAGE/chain.pem"`, f.Chain, false))
+	}
+	if f.FullChain != "" {
+		lines = append(lines, f.installCommand(`"$RENEWED_LINEAGE/fullchain.pem"`, f.FullChain, false))
+	}
+	if f.ReloadCommand != "" {
+		lines = append(lines, f.ReloadCommand)
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// InstallCommand returns a command writing the PEM encoded certificate, key and chain to the
+// files and running the reload command.
+func (f CertificateFiles) InstallCommand(certificate string, key string, chain string) string {
+	type file struct {
+		path    string
+		content string
+		mode    string
+	}
+	files := []file{{f.Certificate, certificate, "0644"}, {f.Key, key, "0600"}}
+	if f.Chain != "" {
+		files = append(files, file{f.Chain, chain, "0644"})
+	}
+	if f.FullChain != "" {
+		files = append(files, file{f.FullChain, strings.TrimRight(certificate, "\n") + "\n" + chain, "0644"})
+	}
+
+	owner := f.Owner
+	if f.Group != "" {
+		owner += ":" + f.Group
+	}
+
+	lines := []string{"set -e"}
+	for _, file := range files {
+		lines = append(lines,
+			fmt.Sprintf(`mkdir -p "$(dirname %s)"`, ShellQuote(file.path)),
+			WriteFileCommand(file.path, []byte(file.content), file.mode),
+		)
+		if owner != "" {
+			lines = append(lines, fmt.Sprintf("chown %s %s", ShellQuote(owner), ShellQuote(file.path)))
+		}
+	}
+	if f.ReloadCommand != "" {
+		lines = append(lines, f.ReloadCommand)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// RemoveCommand returns a command removing the installed files.
+func (f CertificateFiles) RemoveCommand() string {
+	paths := []string{ShellQuote(f.Certificate), ShellQuote(f.Key)}
+	for _, path := range []string{f.Chain, f.FullChain} {
+		if path != "" {
+			paths = append(paths, ShellQuote(path))
+		}
+	}
+
+	return "rm -f -- " + strings.Join(paths, " ")
+}
+
+// CertbotDeployHookPath returns the path of the deploy hook of the lineage name.
+func CertbotDeployHookPath(name string) string {
+	return certbotDeployHookDir + "/remote-host-" + name + ".sh"
+}
+
+// CertbotRequest describes the certificate certbot obtains for a lineage.
+type CertbotRequest struct {
+	Name    string
+	Domains []string
+	Email   string
+	// Webroot is the document root the HTTP challenges are served from, certbot answers
+	// them with its own server when it is empty.
+	Webroot string
+	Staging bool
+	// Force renews the certificate even when it is far from its expiry.
+	Force bool
+}
+
+// CertbotCommand returns a command installing the deploy hook of files, obtaining or renewing
+// the certificate of request with certbot and running the hook once.
+func CertbotCommand(request CertbotRequest, files CertificateFiles) string {
+	hookPath := CertbotDeployHookPath(request.Name)
+
+	arguments := []string{
+		"certbot certonly --non-interactive --agree-tos --keep-until-expiring",
+		"--cert-name " + ShellQuote(request.Name),
+		"-m " + ShellQuote(request.Email),
+	}
+	for _, domain := range request.Domains {
+		arguments = append(arguments, "-d "+ShellQuote(domain))
+	}
+	if request.Webroot != "" {
+		arguments = append(arguments, "--webroot -w "+ShellQuote(request.Webroot))
+	} else {
+		arguments = append(arguments, "--standalone")
+	}
+	if request.Staging {
+		arguments = append(arguments, "--staging")
+	}
+	if request.Force {
+		arguments = append(arguments, "--force-renewal")
+	}
+
+	return strings.Join([]string{
+		"set -e",
+		"mkdir -p " + ShellQuote(certbotDeployHookDir),
+		WriteFileCommand(hookPath, []byte(files.DeployHook(request.Name)), "0755"),
+		strings.Join(arguments, " "),
+		fmt.Sprintf("RENEWED_LINEAGE=%s sh %s", ShellQuote(CertbotLiveDir+"/"+request.Name), ShellQuote(hookPath)),
+	}, "\n")
+}
+
+// CertbotDeleteCommand returns a command deleting the lineage name and its deploy hook.
+func CertbotDeleteCommand(name string) string {
+	return fmt.Sprintf("rm -f %s && certbot delete --non-interactive --cert-name %s",
+		ShellQuote(CertbotDeployHookPath(name)), ShellQuote(name))
+}
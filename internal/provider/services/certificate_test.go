@@ -0,0 +1,143 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCertificatePEM(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notAfter := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(0x2a),
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("ignored")})
+	bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+
+	info, err := ParseCertificatePEM(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.NotAfter.Equal(notAfter) || info.Serial != "2a" || len(info.FingerprintSHA256) != 64 {
+		t.Errorf("unexpected certificate %+v", info)
+	}
+
+	if _, err := ParseCertificatePEM([]byte("not a certificate")); err == nil {
+		t.Error("expected an error without a certificate")
+	}
+}
+
+func TestCertbotDeployHook(t *testing.T) {
+	dir := t.TempDir()
+	lineage := filepath.Join(dir, "live", "www")
+	if err := os.MkdirAll(lineage, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"cert.pem", "privkey.pem", "chain.pem", "fullchain.pem"} {
+		if err := os.WriteFile(filepath.Join(lineage, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := CertificateFiles{
+		Certificate:   filepath.Join(dir, "nginx", "www.crt"),
+		Key:           filepath.Join(dir, "nginx", "private", "www.key"),
+		FullChain:     filepath.Join(dir, "nginx", "www-fullchain.crt"),
+		ReloadCommand: "touch " + filepath.Join(dir, "reloaded"),
+	}
+	hook := files.DeployHook("www")
+
+	// Renewals of other lineages are ignored.
+	command := exec.Command("sh", "-c", hook)
+	command.Env = append(os.Environ(), "RENEWED_LINEAGE="+filepath.Join(dir, "live", "api"))
+	if output, err := command.CombinedOutput(); err != nil {
+		t.Fatalf("%s: %s", err, output)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "reloaded")); err == nil {
+		t.Fatal("expected the hook to ignore another lineage")
+	}
+
+	// The hook only matches lineages under the certbot live directory.
+	hook = strings.ReplaceAll(hook, CertbotLiveDir, filepath.Join(dir, "live"))
+	command = exec.Command("sh", "-c", hook)
+	command.Env = append(os.Environ(), "RENEWED_LINEAGE="+lineage)
+	if output, err := command.CombinedOutput(); err != nil {
+		t.Fatalf("%s: %s", err, output)
+	}
+
+	for path, want := range map[string]string{files.Certificate: "cert.pem", files.Key: "privkey.pem", files.FullChain: "fullchain.pem"} {
+		content, err := os.ReadFile(path)
+		if err != nil || string(content) != want {
+			t.Errorf("expected %s to hold %s, got %q, %v", path, want, content, err)
+		}
+	}
+	if info, err := os.Stat(files.Key); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected a private key file, got %v, %v", info, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "reloaded")); err != nil {
+		t.Error("expected the reload command to run")
+	}
+}
+
+func TestCertificateInstallCommand(t *testing.T) {
+	dir := t.TempDir()
+	files := CertificateFiles{
+		Certificate: filepath.Join(dir, "tls", "www.crt"),
+		Key:         filepath.Join(dir, "tls", "www.key"),
+		FullChain:   filepath.Join(dir, "tls", "www-fullchain.crt"),
+	}
+
+	output, err := exec.Command("sh", "-c", files.InstallCommand("CERT\n", "KEY\n", "CHAIN\n")).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %s", err, output)
+	}
+
+	for path, want := range map[string]string{files.Certificate: "CERT\n", files.Key: "KEY\n", files.FullChain: "CERT\nCHAIN\n"} {
+		content, err := os.ReadFile(path)
+		if err != nil || string(content) != want {
+			t.Errorf("expected %s to hold %q, got %q, %v", path, want, content, err)
+		}
+	}
+
+	if output, err := exec.Command("sh", "-c", files.RemoveCommand()).CombinedOutput(); err != nil {
+		t.Fatalf("%s: %s", err, output)
+	}
+	if _, err := os.Stat(files.Key); !os.IsNotExist(err) {
+		t.Errorf("expected the key to be removed, got %v", err)
+	}
+}
+
+func TestCertbotCommand(t *testing.T) {
+	request := CertbotRequest{Name: "www", Domains: []string{"example.com", "www.example.com"}, Email: "ops@example.com", Webroot: "/var/www/html"}
+	for _, command := range []string{
+		CertbotCommand(request, CertificateFiles{Certificate: "/etc/nginx/www.crt", Key: "/etc/nginx/www.key"}),
+		CertbotDeleteCommand("www"),
+	} {
+		if output, err := exec.Command("sh", "-n", "-c", command).CombinedOutput(); err != nil {
+			t.Errorf("invalid command %s: %s", command, output)
+		}
+	}
+}
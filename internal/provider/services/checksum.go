@@ -0,0 +1,136 @@
+package services
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// checksumTools maps the supported algorithms to the coreutils tool computing them on a host.
+var checksumTools = map[string]string{
+	"md5":     "md5sum",
+	"sha1":    "sha1sum",
+	"sha256":  "sha256sum",
+	"sha512":  "sha512sum",
+	"blake2b": "b2sum",
+}
+
+// ChecksumAlgorithms returns the supported checksum algorithms sorted by name.
+func ChecksumAlgorithms() []string {
+	algorithms := make([]string, 0, len(checksumTools))
+	for algorithm := range checksumTools {
+		algorithms = append(algorithms, algorithm)
+	}
+	slices.Sort(algorithms)
+
+	return algorithms
+}
+
+// ChecksumTool returns the tool computing algorithm on a host.
+func ChecksumTool(algorithm string) string {
+	return checksumTools[algorithm]
+}
+
+// NewHash returns a hash.Hash for algorithm, blake2b being BLAKE2b-512 as printed by b2sum.
+func NewHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "blake2b":
+		return blake2b.New512(nil)
+	}
+
+	return nil, fmt.Errorf("unsupported checksum algorithm %q, expected one of %s", algorithm, strings.Join(ChecksumAlgorithms(), ", "))
+}
+
+// Checksum returns the hex encoded digest of data.
+func Checksum(algorithm string, data []byte) (string, error) {
+	digest, err := NewHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	digest.Write(data)
+
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// RemoteChecksumCommand returns a command printing the digest of path, or "-" when the
+// host lacks the tool so callers can fall back to hashing the content themselves.
+func RemoteChecksumCommand(algorithm string, path string) (string, error) {
+	tool, ok := checksumTools[algorithm]
+	if !ok {
+		return "", fmt.Errorf("unsupported checksum algorithm %q, expected one of %s", algorithm, strings.Join(ChecksumAlgorithms(), ", "))
+	}
+
+	return hashCommand(tool, ShellQuote(path)), nil
+}
+
+// hashCommand returns a command printing the digest computed by tool of the quoted path, or "-"
+// when the tool is missing. The content is hashed from the standard input, so the tool does not
+// escape odd names, and the backslash coreutils prints before the digest of escaped names is
+// stripped all the same.
+func hashCommand(tool string, quotedPath string) string {
+	return fmt.Sprintf("{ %s 2>/dev/null < %s || echo -; } | cut -d ' ' -f 1 | sed 's/^\\\\//'", tool, quotedPath)
+}
+
+// FileChecksum is the digest and size of a file on a host.
+type FileChecksum struct {
+	Path     string
+	Checksum string
+	Size     int64
+}
+
+// ChecksumGlobCommand returns a command printing a "<size>\t<digest>\t<path>" line for every
+// regular file matching pattern. The digest is "-" when the host lacks the tool.
+func ChecksumGlobCommand(algorithm string, pattern string) (string, error) {
+	tool, ok := checksumTools[algorithm]
+	if !ok {
+		return "", fmt.Errorf("unsupported checksum algorithm %q, expected one of %s", algorithm, strings.Join(ChecksumAlgorithms(), ", "))
+	}
+
+	return fmt.Sprintf(
+		"for f in %s; do if [ -f \"$f\" ]; then printf '%%s\\t%%s\\t%%s\\n' \"$(wc -c < \"$f\" | tr -d ' ')\" \"$(%s)\" \"$f\"; fi; done",
+		GlobQuote(pattern), hashCommand(tool, `"$f"`),
+	), nil
+}
+
+// ParseChecksums parses the output of a ChecksumGlobCommand. Lines of another shape, e.g. a
+// login banner, are ignored.
+func ParseChecksums(output string) ([]FileChecksum, error) {
+	var checksums []FileChecksum
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if fields[1] == "-" {
+			return nil, fmt.Errorf("the host cannot compute the checksum of %s", fields[2])
+		}
+
+		checksums = append(checksums, FileChecksum{Path: fields[2], Checksum: fields[1], Size: size})
+	}
+
+	return checksums, nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	cases := map[string]string{
+		"md5":     "5d41402abc4b2a76b9719d911017c592",
+		"sha1":    "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d",
+		"sha256":  "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		"sha512":  "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043",
+		"blake2b": "e4cfa39a3d37be31c59609e807970799caa68a19bfaa15135f165085e01d41a65ba1e1b146aeb6bd0092b49eac214c103ccfa3a365954bbbe52f74a2b3620c94",
+	}
+
+	for algorithm, expected := range cases {
+		actual, err := Checksum(algorithm, []byte("hello"))
+		if err != nil {
+			t.Fatalf("%s: %s", algorithm, err)
+		}
+
+		if actual != expected {
+			t.Errorf("%s: expected %s, got %s", algorithm, expected, actual)
+		}
+	}
+
+	_, err := Checksum("crc32", []byte("hello"))
+	if err == nil {
+		t.Fatalf("expected an error for an unsupported algorithm")
+	}
+}
+
+func TestChecksumGlob(t *testing.T) {
+	if _, err := exec.LookPath("sha256sum"); err != nil {
+		t.Skip("sha256sum is not installed")
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"app 1.jar": "hello", "app-2.jar": "", "notes.txt": "skipped"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	command, err := ChecksumGlobCommand("sha256", filepath.Join(dir, "*.jar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := exec.Command("sh", "-c", "echo 'Welcome!'; "+command).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checksums, err := ParseChecksums(string(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []FileChecksum{
+		{Path: filepath.Join(dir, "app 1.jar"), Checksum: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", Size: 5},
+		{Path: filepath.Join(dir, "app-2.jar"), Checksum: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", Size: 0},
+	}
+	if len(checksums) != len(want) {
+		t.Fatalf("expected %d checksums, got %+v", len(want), checksums)
+	}
+	for i := range want {
+		if checksums[i] != want[i] {
+			t.Errorf("expected %+v, got %+v", want[i], checksums[i])
+		}
+	}
+
+	if _, err := ParseChecksums("3\t-\t/opt/app.jar\n"); err == nil {
+		t.Errorf("expected an error when the host lacks the tool")
+	}
+}
+
+func TestRemoteChecksumCommandOddNames(t *testing.T) {
+	if _, err := exec.LookPath("sha256sum"); err != nil {
+		t.Skip("sha256sum is not installed")
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{`back\slash.conf`, "new\nline.conf", "-dash.conf"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("hello"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		command, err := RemoteChecksumCommand("sha256", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		output, err := exec.Command("sh", "-c", command).CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(output)); got != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+			t.Errorf("%q: got %q", name, got)
+		}
+	}
+
+	command, _ := RemoteChecksumCommand("sha256", filepath.Join(dir, "missing"))
+	if output, err := exec.Command("sh", "-c", command).CombinedOutput(); err != nil || strings.TrimSpace(string(output)) != "-" {
+		t.Errorf("expected - for a missing file, got %q, %v", output, err)
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// GlobQuote quotes pattern for the shell while leaving its wildcards (*, ? and bracket
+// expressions) unquoted, so the shell expands them but no other character is interpreted.
+func GlobQuote(pattern string) string {
+	var quoted, literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			quoted.WriteString(ShellQuote(literal.String()))
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*', '?':
+			flush()
+			quoted.WriteByte(pattern[i])
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				literal.WriteByte('[')
+				continue
+			}
+
+			class := pattern[i : i+end+2]
+			if strings.ContainsAny(class, "'\"\\`$;&|<> \t\n") {
+				literal.WriteString(class)
+			} else {
+				flush()
+				quoted.WriteString(class)
+			}
+			i += end + 1
+		default:
+			literal.WriteByte(pattern[i])
+		}
+	}
+	flush()
+
+	return quoted.String()
+}
+
+// CollectFilesCommand returns a command printing a "<path>\t<base64 content>" line for every
+// regular file matching globs. Patterns matching nothing are skipped.
+func CollectFilesCommand(globs []string) string {
+	patterns := make([]string, 0, len(globs))
+	for _, glob := range globs {
+		patterns = append(patterns, GlobQuote(glob))
+	}
+
+	return fmt.Sprintf(
+		"for f in %s; do if [ -f \"$f\" ]; then printf '%%s\\t' \"$f\"; base64 < \"$f\" | tr -d '\\n'; echo; fi; done",
+		strings.Join(patterns, " "),
+	)
+}
+
+// ParseCollectedFiles returns the content of the files printed by a CollectFilesCommand keyed
+// by path. Lines printed before them, e.g. a login banner, are ignored.
+func ParseCollectedFiles(output string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		separator := strings.LastIndexByte(line, '\t')
+		if separator <= 0 {
+			continue
+		}
+
+		path := line[:separator]
+		content, err := base64.StdEncoding.DecodeString(strings.TrimSpace(line[separator+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode the content of %s: %w", path, err)
+		}
+
+		files[path] = content
+	}
+
+	return files, nil
+}
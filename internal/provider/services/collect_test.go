@@ -0,0 +1,55 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestGlobQuote(t *testing.T) {
+	tests := map[string]string{
+		"/etc/kubernetes/admin.conf": `'/etc/kubernetes/admin.conf'`,
+		"/var/log/*.log":             `'/var/log/'*'.log'`,
+		"/tmp/report-?.[ch]":         `'/tmp/report-'?'.'[ch]`,
+		"/tmp/it's here/*":           `'/tmp/it'"'"'s here/'*`,
+		"/tmp/$(reboot)[x;y]":        `'/tmp/$(reboot)[x;y]'`,
+		"/tmp/[unterminated":         `'/tmp/[unterminated'`,
+	}
+
+	for pattern, want := range tests {
+		if got := GlobQuote(pattern); got != want {
+			t.Errorf("GlobQuote(%q) = %s, want %s", pattern, got, want)
+		}
+	}
+}
+
+func TestCollectFiles(t *testing.T) {
+	if _, err := exec.LookPath("base64"); err != nil {
+		t.Skip("base64 is not installed")
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"join token": "abc\n", "report.txt": "ok", "other.log": "skipped"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "directory.txt"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := exec.Command("sh", "-c", "echo banner; "+CollectFilesCommand([]string{dir + "/join*", dir + "/*.txt", dir + "/missing-*"})).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ParseCollectedFiles(string(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 || string(files[dir+"/join token"]) != "abc\n" || string(files[dir+"/report.txt"]) != "ok" {
+		t.Fatalf("unexpected files %q", files)
+	}
+}
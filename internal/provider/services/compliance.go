@@ -0,0 +1,289 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ComplianceFile declares the expected state of a file. Empty fields are not checked.
+type ComplianceFile struct {
+	Path string
+	// Absent expects the file not to exist, the other fields are then ignored.
+	Absent bool
+	// SHA256 is the hex encoded digest of the content of a regular file.
+	SHA256 string
+	// Mode is an octal mode such as "0644".
+	Mode  string
+	Owner string
+	Group string
+}
+
+// CompliancePackage declares the expected state of a package.
+type CompliancePackage struct {
+	Name   string
+	Absent bool
+	// Version matches the installed versions equal to it or starting with it followed by a
+	// separator, so "1.24" matches "1.24.3-1" but not "1.240".
+	Version string
+}
+
+// ComplianceService declares the expected state of a systemd service. Nil fields are not checked.
+type ComplianceService struct {
+	Name    string
+	Active  *bool
+	Enabled *bool
+}
+
+// ComplianceAssertion is a command that must exit with status 0.
+type ComplianceAssertion struct {
+	Name    string
+	Command string
+}
+
+// ComplianceScan is a set of checks evaluated against a host without changing it.
+type ComplianceScan struct {
+	Files      []ComplianceFile
+	Packages   []CompliancePackage
+	Services   []ComplianceService
+	Assertions []ComplianceAssertion
+}
+
+// ComplianceResult is the outcome of a check of a scan.
+type ComplianceResult struct {
+	// Kind is "file", "package", "service" or "command".
+	Kind string
+	Name string
+	// Passed tells whether the host matches the check, Message describes the drift otherwise.
+	Passed  bool
+	Message string
+}
+
+// compliancePackagePrelude defines rh_pkg, printing the installed version of a package with
+// dpkg or rpm, or nothing when it is not installed.
+const compliancePackagePrelude = `rh_pkg() { if command -v dpkg-query >/dev/null 2>&1; then ` +
+	`v=$(dpkg-query -W -f='${db:Status-Status} ${Version}' "$1" 2>/dev/null); case "$v" in "installed "*) echo "${v#installed }";; esac; ` +
+	`elif command -v rpm >/dev/null 2>&1; then rpm -q --qf '%{VERSION}-%{RELEASE} ' "$1" 2>/dev/null | grep -v 'not installed'; fi; }`
+
+// Command returns a command printing one tab separated line per check of the scan, read back by
+// Evaluate. Assertions run in subshells, so an exit in one of them does not end the scan.
+func (s ComplianceScan) Command() string {
+	lines := []string{compliancePackagePrelude}
+
+	for i, file := range s.Files {
+		path := ShellQuote(file.Path)
+		checksum, _ := RemoteChecksumCommand("sha256", file.Path)
+		lines = append(lines, fmt.Sprintf(
+			`if [ -e %[1]s ] || [ -L %[1]s ]; then printf 'file\t%[2]d\tpresent\t%%s\t%%s\n' "$(ls -ld -- %[1]s | awk '{print $1 "\t" $3 "\t" $4}')" `+
+				`"$(if [ -f %[1]s ]; then %[3]s; else echo -; fi)"; else printf 'file\t%[2]d\tabsent\n'; fi`,
+			path, i, checksum,
+		))
+	}
+
+	for i, pkg := range s.Packages {
+		lines = append(lines, fmt.Sprintf(`printf 'package\t%d\t%%s\n' "$(rh_pkg %s)"`, i, ShellQuote(pkg.Name)))
+	}
+
+	for i, service := range s.Services {
+		name := ShellQuote(service.Name)
+		lines = append(lines, fmt.Sprintf(
+			`printf 'service\t%d\t%%s\t%%s\n' "$(systemctl is-active %s 2>/dev/null)" "$(systemctl is-enabled %s 2>/dev/null)"`,
+			i, name, name,
+		))
+	}
+
+	for i, assertion := range s.Assertions {
+		lines = append(lines, fmt.Sprintf(
+			"if ( %s\n) </dev/null >/dev/null 2>&1; then printf 'command\\t%d\\tpass\\n'; else printf 'command\\t%d\\tfail\\t%%s\\n' \"$?\"; fi",
+			assertion.Command, i, i,
+		))
+	}
+
+	lines = append(lines, "true")
+
+	return strings.Join(lines, "\n")
+}
+
+// Evaluate compares the output of Command to the checks of the scan and returns their results in
+// the order of the scan: files, packages, services, then assertions.
+func (s ComplianceScan) Evaluate(output string) ([]ComplianceResult, error) {
+	found := map[string][]string{}
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(fields) < 3 {
+			continue
+		}
+		switch fields[0] {
+		case "file", "package", "service", "command":
+			found[fields[0]+"\t"+fields[1]] = fields[2:]
+		}
+	}
+
+	lookup := func(kind string, index int) ([]string, error) {
+		fields, ok := found[kind+"\t"+strconv.Itoa(index)]
+		if !ok {
+			return nil, fmt.Errorf("no result for %s check %d in the output", kind, index)
+		}
+		return fields, nil
+	}
+
+	var results []ComplianceResult
+
+	for i, file := range s.Files {
+		fields, err := lookup("file", i)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, file.evaluate(fields))
+	}
+
+	for i, pkg := range s.Packages {
+		fields, err := lookup("package", i)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, pkg.evaluate(strings.TrimSpace(fields[0])))
+	}
+
+	for i, service := range s.Services {
+		fields, err := lookup("service", i)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, "")
+		results = append(results, service.evaluate(strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1])))
+	}
+
+	for i, assertion := range s.Assertions {
+		fields, err := lookup("command", i)
+		if err != nil {
+			return nil, err
+		}
+		result := ComplianceResult{Kind: "command", Name: assertion.Name, Passed: fields[0] == "pass"}
+		if !result.Passed && len(fields) > 1 {
+			result.Message = "exited with status " + fields[1]
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// evaluate compares the "present <permissions> <owner> <group> <digest>" or "absent" fields
+// printed for the file.
+func (f ComplianceFile) evaluate(fields []string) ComplianceResult {
+	result := ComplianceResult{Kind: "file", Name: f.Path}
+
+	var drifts []string
+	switch {
+	case fields[0] == "absent" && !f.Absent:
+		drifts = append(drifts, "does not exist")
+	case fields[0] != "absent" && f.Absent:
+		drifts = append(drifts, "exists")
+	case fields[0] != "absent" && len(fields) < 5:
+		drifts = append(drifts, "cannot be inspected")
+	case fields[0] != "absent":
+		if f.Mode != "" {
+			mode, err := permissionsToOctal(fields[1])
+			want, _ := strconv.ParseUint(f.Mode, 8, 64)
+			if err != nil {
+				mode = fields[1]
+			}
+			if mode != fmt.Sprintf("%04o", want) {
+				drifts = append(drifts, fmt.Sprintf("mode is %s, expected %04o", mode, want))
+			}
+		}
+		if f.Owner != "" && fields[2] != f.Owner {
+			drifts = append(drifts, fmt.Sprintf("owner is %s, expected %s", fields[2], f.Owner))
+		}
+		if f.Group != "" && fields[3] != f.Group {
+			drifts = append(drifts, fmt.Sprintf("group is %s, expected %s", fields[3], f.Group))
+		}
+		if f.SHA256 != "" && !strings.EqualFold(fields[4], f.SHA256) {
+			drifts = append(drifts, "content differs")
+		}
+	}
+
+	result.Passed = len(drifts) == 0
+	result.Message = strings.Join(drifts, ", ")
+
+	return result
+}
+
+// evaluate compares the installed versions of the package, separated by spaces.
+func (p CompliancePackage) evaluate(installed string) ComplianceResult {
+	result := ComplianceResult{Kind: "package", Name: p.Name}
+
+	switch {
+	case installed == "" && !p.Absent:
+		result.Message = "is not installed"
+	case installed != "" && p.Absent:
+		result.Message = "is installed at " + installed
+	case p.Version != "":
+		matched := false
+		for _, version := range strings.Fields(installed) {
+			rest, ok := strings.CutPrefix(version, p.Version)
+			matched = matched || ok && (rest == "" || !strings.ContainsAny(rest[:1], "0123456789"))
+		}
+		if !matched {
+			result.Message = fmt.Sprintf("is installed at %s, expected %s", installed, p.Version)
+		}
+	}
+
+	result.Passed = result.Message == ""
+
+	return result
+}
+
+// evaluate compares the states printed by systemctl is-active and is-enabled.
+func (s ComplianceService) evaluate(active string, enabled string) ComplianceResult {
+	result := ComplianceResult{Kind: "service", Name: s.Name}
+
+	var drifts []string
+	if s.Active != nil && (active == "active") != *s.Active {
+		drifts = append(drifts, "is "+orUnknown(active))
+	}
+	if s.Enabled != nil && strings.HasPrefix(enabled, "enabled") != *s.Enabled {
+		drifts = append(drifts, "is "+orUnknown(enabled))
+	}
+
+	result.Passed = len(drifts) == 0
+	result.Message = strings.Join(drifts, ", ")
+
+	return result
+}
+
+func orUnknown(state string) string {
+	if state == "" {
+		return "unknown"
+	}
+
+	return state
+}
+
+// permissionsToOctal converts the permissions printed by ls -l, such as "-rwsr-x---", to a
+// four digit octal mode.
+func permissionsToOctal(permissions string) (string, error) {
+	if len(permissions) < 10 {
+		return "", fmt.Errorf("unexpected permissions %q", permissions)
+	}
+
+	clauses := make([]string, 0, 3)
+	for i, class := range []string{"u", "g", "o"} {
+		bits := permissions[1+3*i : 4+3*i]
+		clause := class + "="
+		for _, bit := range bits {
+			switch bit {
+			case 'r', 'w', 'x':
+				clause += string(bit)
+			case 's', 't':
+				clause += "x" + string(bit)
+			case 'S', 'T':
+				clause += strings.ToLower(string(bit))
+			}
+		}
+		clauses = append(clauses, clause)
+	}
+
+	return SymbolicToOctal(strings.Join(clauses, ","))
+}
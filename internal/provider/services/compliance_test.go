@@ -0,0 +1,113 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"testing"
+)
+
+func TestComplianceScan(t *testing.T) {
+	dir := t.TempDir()
+	present := filepath.Join(dir, "app's.conf")
+	if err := os.WriteFile(present, []byte("hello\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(present, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yes := true
+	scan := ComplianceScan{
+		Files: []ComplianceFile{
+			{Path: present, Mode: "640", Owner: current.Username, SHA256: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+			{Path: present, Mode: "0600", SHA256: "00"},
+			{Path: filepath.Join(dir, "missing"), Absent: true},
+			{Path: filepath.Join(dir, "missing")},
+		},
+		Packages: []CompliancePackage{{Name: "remote-host-missing-package", Absent: true}},
+		Services: []ComplianceService{{Name: "remote-host-missing.service", Active: &yes}},
+		Assertions: []ComplianceAssertion{
+			{Name: "true", Command: "true"},
+			{Name: "exit", Command: "exit 3"},
+		},
+	}
+
+	output, err := exec.Command("sh", "-c", scan.Command()).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := scan.Evaluate("Welcome!\n" + string(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ComplianceResult{
+		{Kind: "file", Name: present, Passed: true},
+		{Kind: "file", Name: present, Message: "mode is 0640, expected 0600, content differs"},
+		{Kind: "file", Name: filepath.Join(dir, "missing"), Passed: true},
+		{Kind: "file", Name: filepath.Join(dir, "missing"), Message: "does not exist"},
+		{Kind: "package", Name: "remote-host-missing-package", Passed: true},
+		{Kind: "service", Name: "remote-host-missing.service", Message: "is " + orUnknown(string(mustSystemctl("is-active", "remote-host-missing.service")))},
+		{Kind: "command", Name: "true", Passed: true},
+		{Kind: "command", Name: "exit", Message: "exited with status 3"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("expected %d results, got %+v", len(want), results)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("result %d: expected %+v, got %+v", i, want[i], results[i])
+		}
+	}
+
+	if _, err := scan.Evaluate("file\t0\tabsent\n"); err == nil {
+		t.Error("expected an error for a truncated output")
+	}
+}
+
+// mustSystemctl returns the trimmed output of systemctl, empty on hosts without it.
+func mustSystemctl(args ...string) []byte {
+	output, _ := exec.Command("systemctl", args...).Output()
+	for len(output) > 0 && output[len(output)-1] == '\n' {
+		output = output[:len(output)-1]
+	}
+
+	return output
+}
+
+func TestCompliancePackageVersion(t *testing.T) {
+	for installed, want := range map[string]bool{
+		"1.24.3-1":         true,
+		"1.24":             true,
+		"1.240":            false,
+		"1.23.9 1.24.0-1":  true,
+		"":                 false,
+		"2:1.24.0-1ubuntu": false,
+	} {
+		result := CompliancePackage{Name: "go", Version: "1.24"}.evaluate(installed)
+		if result.Passed != want {
+			t.Errorf("version %q: expected passed=%t, got %+v", installed, want, result)
+		}
+	}
+}
+
+func TestPermissionsToOctal(t *testing.T) {
+	for permissions, want := range map[string]string{
+		"-rw-r--r--":  "0644",
+		"drwxrwxrwt":  "1777",
+		"-rwsr-x---.": "4750",
+		"-rwSr-----+": "4640",
+	} {
+		if got, err := permissionsToOctal(permissions); err != nil || got != want {
+			t.Errorf("permissionsToOctal(%q) = %q, %v, want %q", permissions, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package services
+
+import "fmt"
+
+// CredentialError reports a credential of a host that cannot be used, e.g. a private key
+// file that is missing or cannot be parsed.
+type CredentialError struct {
+	Host string
+	Path string
+	Err  error
+}
+
+func (e *CredentialError) Error() string {
+	return fmt.Sprintf("unable to use the private key %s to connect to %s: %s", e.Path, e.Host, e.Err)
+}
+
+func (e *CredentialError) Unwrap() error {
+	return e.Err
+}
+
+// DialError reports a host that could not be reached or rejected the SSH handshake.
+type DialError struct {
+	Host    string
+	Address string
+	Err     error
+}
+
+func (e *DialError) Error() string {
+	return fmt.Sprintf("unable to connect to %s at %s: %s", e.Host, e.Address, e.Err)
+}
+
+func (e *DialError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"remote-provider/internal/provider/servers"
+	"testing"
+)
+
+func TestCreateSSHClientCredentialErrors(t *testing.T) {
+	invalidKey := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(invalidKey, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, path := range map[string]string{
+		"missing": filepath.Join(t.TempDir(), "missing"),
+		"invalid": invalidKey,
+	} {
+		t.Run(name, func(t *testing.T) {
+			// The password must not hide the unusable key.
+			host := &servers.Server{Name: "web-1", Address: "127.0.0.1", Port: 22, User: "deploy", Password: "secret", PrivateKeyPath: path}
+
+			_, err := createSSHClient(context.Background(), host, false, true)
+
+			var credentialErr *CredentialError
+			if !errors.As(err, &credentialErr) || credentialErr.Path != path {
+				t.Fatalf("expected a credential error for %s, got %v", path, err)
+			}
+		})
+	}
+}
+
+func TestNewSSHServiceJoinsDialErrors(t *testing.T) {
+	// Nothing listens on the TCP port 1 of the loopback interface.
+	service, err := NewSSHService([]*servers.Server{
+		{Name: "web-1", Address: "127.0.0.1", Port: 1, User: "deploy", Password: "secret"},
+	})
+
+	var dialErr *DialError
+	if !errors.As(err, &dialErr) || dialErr.Host != "web-1" {
+		t.Fatalf("expected a dial error for web-1, got %v", err)
+	}
+	if len(service.GetConnections()) != 0 {
+		t.Fatal("expected no connection")
+	}
+}
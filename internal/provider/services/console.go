@@ -0,0 +1,345 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"remote-provider/internal/provider/servers"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// consoleLoginTimeout bounds the login on a console when the context has no deadline.
+const consoleLoginTimeout = time.Minute
+
+// consoleIdle is how long a console may stay silent before it is prompted with an empty line.
+const consoleIdle = time.Second
+
+var (
+	// consoleLoginRegexp matches the login prompt of a getty.
+	consoleLoginRegexp = regexp.MustCompile(`(?i)login:\s*$`)
+	// consolePasswordRegexp matches the password prompt following the login.
+	consolePasswordRegexp = regexp.MustCompile(`(?i)password:\s*$`)
+	// consoleShellRegexp matches the prompt of a shell ready for commands.
+	consoleShellRegexp = regexp.MustCompile(`[$#>]\s*$`)
+	// consoleEndRegexp matches the line ending the output of a command and carrying its status.
+	consoleEndRegexp = regexp.MustCompile(`__RH_END_([0-9a-f]+) (\d+)`)
+)
+
+// serialConsole runs commands through the shell of a serial console, e.g. reached with
+// `ipmitool sol activate` or `virsh console`, for hosts whose SSH server is not up yet. The
+// console is a single terminal, so commands run one at a time.
+type serialConsole struct {
+	conn   net.Conn
+	output chan []byte
+	stop   chan struct{}
+
+	mutex   sync.Mutex
+	pending bytes.Buffer
+	closed  bool
+}
+
+// openSerialConsole starts the console command of host and logs in with its user and password
+// when the console shows a login prompt.
+func openSerialConsole(ctx context.Context, host *servers.Server) (*serialConsole, error) {
+	conn, err := dialProxyCommand(expandHostTokens(host.ConsoleCommand, host, ShellQuote))
+	if err != nil {
+		return nil, err
+	}
+
+	console := &serialConsole{conn: conn, output: make(chan []byte, 64), stop: make(chan struct{})}
+	go console.read()
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, consoleLoginTimeout)
+		defer cancel()
+	}
+
+	if err := console.login(ctx, host); err != nil {
+		console.Close()
+		return nil, fmt.Errorf("logging in on the console of %s: %w", host.Name, err)
+	}
+
+	// Commands must not be echoed back, consoles reached through a pipe have no terminal.
+	if _, _, err := console.execute(ctx, host, "stty -echo 2>/dev/null; true", nil); err != nil {
+		console.Close()
+		return nil, fmt.Errorf("preparing the console of %s: %w", host.Name, err)
+	}
+
+	return console, nil
+}
+
+// read forwards the console output until it closes.
+func (console *serialConsole) read() {
+	defer close(console.output)
+
+	for {
+		buffer := make([]byte, 4096)
+		n, err := console.conn.Read(buffer)
+		if n > 0 {
+			select {
+			case console.output <- buffer[:n]:
+			case <-console.stop:
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// wait appends the console output to pending until done reports the exchange is over.
+func (console *serialConsole) wait(ctx context.Context, done func(screen string) (bool, error)) error {
+	for {
+		finished, err := done(console.pending.String())
+		if finished || err != nil {
+			return err
+		}
+
+		select {
+		case chunk, ok := <-console.output:
+			if !ok {
+				return errors.New("the console closed")
+			}
+			console.pending.Write(chunk)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (console *serialConsole) send(line string) error {
+	_, err := console.conn.Write([]byte(line + "\n"))
+	return err
+}
+
+// login answers the login and password prompts until a shell prompt shows up. When the console
+// stays silent, an empty line is sent so a getty or a logged in shell prints its prompt again.
+func (console *serialConsole) login(ctx context.Context, host *servers.Server) error {
+	select {
+	case chunk, ok := <-console.output:
+		if !ok {
+			return errors.New("the console closed")
+		}
+		console.pending.Write(chunk)
+	case <-time.After(consoleIdle):
+		if err := console.send(""); err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return console.wait(ctx, func(screen string) (bool, error) {
+		var err error
+		switch {
+		case consoleLoginRegexp.MatchString(screen):
+			console.pending.Reset()
+			err = console.send(host.User)
+		case consolePasswordRegexp.MatchString(screen):
+			console.pending.Reset()
+			err = console.send(host.Password)
+		case consoleShellRegexp.MatchString(screen):
+			console.pending.Reset()
+			return true, nil
+		}
+
+		return false, err
+	})
+}
+
+// consoleHeredoc returns the lines of a heredoc feeding the base64 encoding of data, short
+// enough for the line buffer of any terminal.
+func consoleHeredoc(delimiter string, data []byte) string {
+	var lines strings.Builder
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		lines.WriteString(encoded[:76] + "\n")
+		encoded = encoded[76:]
+	}
+	if encoded != "" {
+		lines.WriteString(encoded + "\n")
+	}
+	lines.WriteString(delimiter)
+
+	return lines.String()
+}
+
+// consoleScript returns the input running command with stdin on the console. The command and
+// its input travel base64 encoded in heredocs, so neither their length nor their quoting
+// matter, and the markers around the output are printed in two parts so their echo never
+// matches them.
+func consoleScript(nonce string, command string, stdin []byte) string {
+	script := fmt.Sprintf("__rh_script=$(base64 -d <<'__RH_SCRIPT_%s'\n%s\n)\n", nonce, consoleHeredoc("__RH_SCRIPT_"+nonce, []byte(command)))
+
+	if stdin == nil {
+		return script + fmt.Sprintf(
+			"{ printf '%%s_%%s\\n' __RH_BEGIN %[1]s; sh -c \"$__rh_script\" </dev/null 2>&1; printf '\\n%%s_%%s %%s\\n' __RH_END %[1]s \"$?\"; }",
+			nonce,
+		)
+	}
+
+	return script + fmt.Sprintf(
+		"{ printf '%%s_%%s\\n' __RH_BEGIN %[1]s; base64 -d <<'__RH_STDIN_%[1]s' | sh -c \"$__rh_script\" 2>&1; printf '\\n%%s_%%s %%s\\n' __RH_END %[1]s \"$?\"; }\n%[2]s",
+		nonce, consoleHeredoc("__RH_STDIN_"+nonce, stdin),
+	)
+}
+
+// execute runs command on the console and returns its merged output and exit code, answering
+// the password prompt of the become method with the sudo password of host.
+func (console *serialConsole) execute(ctx context.Context, host *servers.Server, command string, stdin []byte) (string, int8, error) {
+	console.mutex.Lock()
+	defer console.mutex.Unlock()
+
+	if console.closed {
+		return "", 0, errors.New("the console closed")
+	}
+
+	nonceBytes := make([]byte, 8)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return "", 0, err
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+
+	command = consoleScript(nonce, command, stdin)
+
+	console.pending.Reset()
+	if err := console.send(command); err != nil {
+		return "", 0, err
+	}
+
+	begin := "__RH_BEGIN_" + nonce
+	answered := false
+	var output string
+	var exitCode int8
+	err := console.wait(ctx, func(screen string) (bool, error) {
+		screen = strings.ReplaceAll(screen, "\r\n", "\n")
+
+		start := strings.Index(screen, begin+"\n")
+		if start < 0 {
+			return false, nil
+		}
+		screen = screen[start+len(begin)+1:]
+
+		if !answered && host.SudoPassword != "" && becomePrompt(host, screen) {
+			answered = true
+			if err := console.send(host.SudoPassword); err != nil {
+				return false, err
+			}
+		}
+
+		match := consoleEndRegexp.FindStringSubmatchIndex(screen)
+		if match == nil || screen[match[2]:match[3]] != nonce {
+			return false, nil
+		}
+
+		status, err := strconv.Atoi(screen[match[4]:match[5]])
+		if err != nil {
+			return false, err
+		}
+
+		output = strings.TrimSuffix(screen[:match[0]], "\n")
+		exitCode = int8(status)
+		return true, nil
+	})
+	if err != nil {
+		// The shell state is unknown after an interrupted command, later commands must fail.
+		_ = console.shutdown()
+		return "", 0, err
+	}
+
+	return output, exitCode, nil
+}
+
+// Close logs out of the console and stops its command.
+func (console *serialConsole) Close() error {
+	console.mutex.Lock()
+	defer console.mutex.Unlock()
+
+	if console.closed {
+		return nil
+	}
+	_ = console.send("exit")
+
+	return console.shutdown()
+}
+
+// shutdown stops the console command and its reader.
+func (console *serialConsole) shutdown() error {
+	console.closed = true
+	close(console.stop)
+
+	return console.conn.Close()
+}
+
+// executeConsoleCommand runs command through the serial console of connection.
+func (service *SSHService) executeConsoleCommand(ctx context.Context, connection *SSHConnection, server *servers.Server, command string, stdin []byte) (*servers.ServerCommand, error) {
+	start := time.Now()
+	output, exitCode, err := connection.console.execute(ctx, connection.host, service.Wrapper.Wrap(containerCommand(server, service.withUmask(command))), stdin)
+	finish := time.Now()
+	service.Measure(ctx, "command", server, start, len(output), err)
+	if err != nil {
+		return nil, fmt.Errorf("running a command on the console of %s: %w", server.Name, err)
+	}
+
+	stdout := bytes.NewBufferString(output)
+	extractSudoPasswordFromOutput(stdout, connection.host)
+
+	serverCommand := &servers.ServerCommand{
+		Command:    command,
+		Stdout:     stdout.String(),
+		ExitCode:   exitCode,
+		StartedAt:  start,
+		FinishedAt: finish,
+	}
+	service.recordHistory(server, serverCommand)
+
+	if privilegeErr := privilegeError(server, serverCommand); privilegeErr != nil {
+		return serverCommand, privilegeErr
+	}
+	if exitCode != 0 {
+		return serverCommand, fmt.Errorf("console command exited with status %d", exitCode)
+	}
+
+	return serverCommand, nil
+}
+
+// openConsole falls back to the serial console of host when dialing its SSH server failed
+// with dialErr, e.g. while a bare-metal host is still being provisioned.
+func (service *SSHService) openConsole(ctx context.Context, host *servers.Server, dialErr error) error {
+	var unreachable *DialError
+	if host.ConsoleCommand == "" || !errors.As(dialErr, &unreachable) {
+		return dialErr
+	}
+
+	tflog.Warn(ctx, "SSH is not reachable, falling back to the serial console", map[string]any{"host": host.Name, "error": dialErr.Error()})
+
+	console, err := openSerialConsole(ctx, host)
+	if err != nil {
+		return errors.Join(dialErr, err)
+	}
+
+	key := connectionKey(host)
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	if service.connectionByKey(key) != nil {
+		return console.Close()
+	}
+
+	service.connections = append(service.connections, &SSHConnection{host: host, key: key, console: console})
+	return nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"remote-provider/internal/provider/servers"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSerialConsoleFallback(t *testing.T) {
+	// The console asks for a login like a getty, again after an empty one, then hands over to a shell. Port 1 is closed,
+	// so dialing SSH fails and the service falls back to the console.
+	server := &servers.Server{
+		Name:     "metal-1",
+		Address:  "127.0.0.1",
+		Port:     1,
+		User:     "admin",
+		Password: "secret",
+		ConsoleCommand: `while [ -z "$user" ]; do printf 'metal-1 login: '; read user; done; printf 'Password: '; read password; ` +
+			`[ "$user" = admin ] && [ "$password" = secret ] || exit 1; printf 'admin@metal-1:~$ '; exec sh`,
+	}
+
+	service := &SSHService{}
+	defer service.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := service.OpenConnection(ctx, server); err != nil {
+		t.Fatal(err)
+	}
+	if connection := service.findConnection(server); connection == nil || connection.console == nil {
+		t.Fatal("expected a console connection")
+	}
+
+	result, err := service.ExecuteCommand(ctx, "echo 'hello world'; echo oops >&2; exit 3", server)
+	if err == nil || result.ExitCode != 3 || result.Stdout != "hello world\noops\n" {
+		t.Errorf("unexpected result %+v: %v", result, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "it's here")
+	content := bytes.Repeat([]byte("binary\x00content\n"), 1000)
+	if _, err := service.Upload(ctx, server, "cat > "+ShellQuote(path), bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	if written, err := os.ReadFile(path); err != nil || !bytes.Equal(written, content) {
+		t.Errorf("unexpected uploaded content of %d bytes: %v", len(written), err)
+	}
+
+	intruder := &servers.Server{Name: "metal-2", Address: "127.0.0.1", Port: 1, User: "admin", Password: "wrong", ConsoleCommand: server.ConsoleCommand}
+	err = service.OpenConnection(ctx, intruder)
+	if err == nil || !strings.Contains(err.Error(), "console") {
+		t.Errorf("expected the console login to fail, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"fmt"
+	"remote-provider/internal/provider/servers"
+)
+
+// ContainerTools are the tools commands can be run inside a container of the host with, the
+// first one is the default. chroot runs them in a directory tree, e.g. a mounted disk image.
+var ContainerTools = []string{"docker", "podman", "lxc", "nsenter", "chroot"}
+
+// containerTool returns the tool entering the container of server.
+func containerTool(server *servers.Server) string {
+	if server.ContainerTool == "" {
+		return ContainerTools[0]
+	}
+
+	return server.ContainerTool
+}
+
+// containerCommand wraps command so it runs as root inside the container of server, reading the
+// standard input of the session. lxc-attach, nsenter and chroot need root on the host, they go through
+// the become method of server without prompting when it does not log in as root, see
+// checkContainerBecome. Commands of servers without container are returned as is.
+func containerCommand(server *servers.Server, command string) string {
+	if server.Container == "" {
+		return command
+	}
+
+	container := ShellQuote(server.Container)
+	shell := "sh -c " + ShellQuote(command)
+
+	var entered string
+	switch containerTool(server) {
+	case "podman":
+		return "podman exec -i -u root " + container + " " + shell
+	case "lxc":
+		entered = "lxc-attach -n " + container + " -- " + shell
+	case "nsenter":
+		// The container is the PID of a process whose namespaces are entered.
+		entered = "nsenter -t " + container + " -a " + shell
+	case "chroot":
+		// The container is the root directory, the paths of the commands are relative to it.
+		entered = "chroot " + container + " " + shell
+	default:
+		return "docker exec -i -u root " + container + " " + shell
+	}
+
+	if !containerBecomes(server) {
+		return entered
+	}
+
+	return becomeMethod(server) + " -n " + entered
+}
+
+// containerBecomes reports whether the tool entering the container of server needs root on the
+// host and gets it through the become method of server.
+func containerBecomes(server *servers.Server) bool {
+	switch containerTool(server) {
+	case "lxc", "nsenter", "chroot":
+		return server.Container != "" && server.User != "root"
+	}
+
+	return false
+}
+
+// checkContainerBecome returns an error when the container of server can only be entered through
+// su, which has no non-interactive mode and needs a PTY the container commands do not get.
+func checkContainerBecome(server *servers.Server) error {
+	if containerBecomes(server) && becomeMethod(server) == "su" {
+		return fmt.Errorf("%s cannot enter container %s of %s through su, which needs a terminal: connect as root or set become_method to sudo or doas",
+			containerTool(server), server.Container, server.Name)
+	}
+
+	return nil
+}
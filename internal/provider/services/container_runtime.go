@@ -0,0 +1,174 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ContainerRuntimes are the runtimes a host can be bootstrapped with.
+var ContainerRuntimes = []string{"docker", "containerd"}
+
+// DockerRepositoryURL is the package repository of Docker, which also ships containerd.
+const DockerRepositoryURL = "https://download.docker.com"
+
+// containerRuntimePackages are the packages installed from the Docker repository for a runtime.
+var containerRuntimePackages = map[string]string{
+	"docker":     "docker-ce docker-ce-cli containerd.io docker-buildx-plugin docker-compose-plugin",
+	"containerd": "containerd.io",
+}
+
+// ContainerRuntimeConfigPath returns the configuration file of runtime.
+func ContainerRuntimeConfigPath(runtime string) string {
+	if runtime == "containerd" {
+		return "/etc/containerd/config.toml"
+	}
+
+	return "/etc/docker/daemon.json"
+}
+
+// ContainerRuntimeInstallCommand returns a command adding the Docker repository at
+// repositoryURL to the apt or dnf sources of the host and installing the packages of runtime.
+func ContainerRuntimeInstallCommand(runtime string, repositoryURL string) string {
+	repositoryURL = strings.TrimSuffix(repositoryURL, "/")
+	url := ShellQuote(repositoryURL)
+	packages := containerRuntimePackages[runtime]
+
+	return strings.Join([]string{
+		`. /etc/os-release || exit 1`,
+		`if command -v apt-get >/dev/null 2>&1; then`,
+		`export DEBIAN_FRONTEND=noninteractive`,
+		`apt-get update -q >/dev/null && apt-get install -y -q ca-certificates curl >/dev/null || exit 1`,
+		`install -m 0755 -d /etc/apt/keyrings || exit 1`,
+		fmt.Sprintf(`curl -fsSL %s/linux/"$ID"/gpg -o /etc/apt/keyrings/docker.asc && chmod a+r /etc/apt/keyrings/docker.asc || exit 1`, url),
+		fmt.Sprintf(`echo "deb [arch=$(dpkg --print-architecture) signed-by=/etc/apt/keyrings/docker.asc] %s/linux/$ID ${UBUNTU_CODENAME:-$VERSION_CODENAME} stable" > /etc/apt/sources.list.d/docker.list || exit 1`, repositoryURL),
+		`apt-get update -q >/dev/null && apt-get install -y -q ` + packages,
+		`elif command -v dnf >/dev/null 2>&1; then`,
+		`case "$ID" in fedora) distro=fedora ;; rhel) distro=rhel ;; *) distro=centos ;; esac`,
+		fmt.Sprintf(`curl -fsSL %s/linux/"$distro"/docker-ce.repo -o /etc/yum.repos.d/docker-ce.repo || exit 1`, url),
+		// The repository file points to download.docker.com, mirrors serve the same layout.
+		fmt.Sprintf(`sed -i "s|%s|%s|g" /etc/yum.repos.d/docker-ce.repo || exit 1`, DockerRepositoryURL, repositoryURL),
+		`dnf -y -q install ` + packages,
+		`else echo 'no supported package manager, expected apt or dnf' >&2; exit 1; fi`,
+	}, "\n")
+}
+
+// ContainerRuntimeRemoveCommand returns a command removing the packages of runtime and the
+// Docker repository. Images, containers and volumes are left in /var/lib.
+func ContainerRuntimeRemoveCommand(runtime string) string {
+	packages := containerRuntimePackages[runtime]
+
+	return fmt.Sprintf(`systemctl disable --now %[1]s 2>/dev/null; `+
+		`if command -v apt-get >/dev/null 2>&1; then DEBIAN_FRONTEND=noninteractive apt-get remove -y -q %[2]s && rm -f /etc/apt/sources.list.d/docker.list /etc/apt/keyrings/docker.asc; `+
+		`else dnf -y -q remove %[2]s && rm -f /etc/yum.repos.d/docker-ce.repo; fi`,
+		runtime, packages)
+}
+
+// ContainerdDefaultConfigCommand writes the default configuration of containerd with the
+// systemd cgroup driver, which kubelet expects on systemd hosts.
+const ContainerdDefaultConfigCommand = `mkdir -p /etc/containerd && containerd config default | ` +
+	`sed 's/SystemdCgroup = false/SystemdCgroup = true/' > /etc/containerd/config.toml`
+
+// ValidateContainerRuntimeConfig rejects a docker daemon.json that is not a JSON object. The
+// containerd TOML is left to containerd.
+func ValidateContainerRuntimeConfig(runtime string, config string) error {
+	if runtime != "docker" {
+		return nil
+	}
+
+	var object map[string]any
+	if err := json.Unmarshal([]byte(config), &object); err != nil {
+		return fmt.Errorf("daemon.json must be a JSON object: %w", err)
+	}
+
+	return nil
+}
+
+// ContainerRuntimeConfigEqual reports whether both configurations have the same settings,
+// ignoring the formatting of daemon.json.
+func ContainerRuntimeConfigEqual(runtime string, a string, b string) bool {
+	if a == b {
+		return true
+	}
+	if runtime != "docker" {
+		return strings.TrimSpace(a) == strings.TrimSpace(b)
+	}
+
+	var objectA, objectB any
+	if json.Unmarshal([]byte(a), &objectA) != nil || json.Unmarshal([]byte(b), &objectB) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(objectA, objectB)
+}
+
+// ContainerRuntimeStatus is the state of a runtime read back from a host.
+type ContainerRuntimeStatus struct {
+	Installed bool
+	Active    bool
+	Version   string
+	// Config is the content of the configuration file, nil when it does not exist.
+	Config *string
+	// Members are the users of the docker group.
+	Members []string
+}
+
+// ContainerRuntimeStatusCommand prints the state of runtime as key=value lines.
+func ContainerRuntimeStatusCommand(runtime string) string {
+	configPath := ShellQuote(ContainerRuntimeConfigPath(runtime))
+
+	return fmt.Sprintf(`if command -v %[1]s >/dev/null 2>&1; then echo "version=$(%[1]s --version)"; else echo version=missing; fi; `+
+		`echo "active=$(systemctl is-active %[1]s 2>/dev/null)"; `+
+		`if [ -f %[2]s ]; then echo "config=$(base64 < %[2]s | tr -d '\n')"; else echo config=missing; fi; `+
+		`echo "members=$(getent group docker | cut -d: -f4)"`,
+		runtime, configPath)
+}
+
+// ParseContainerRuntimeStatus parses the output of ContainerRuntimeStatusCommand. Lines of
+// another shape, e.g. a login banner, are ignored.
+func ParseContainerRuntimeStatus(output string) (ContainerRuntimeStatus, error) {
+	var status ContainerRuntimeStatus
+	found := map[string]bool{}
+
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		found[key] = true
+
+		switch key {
+		case "version":
+			if value != "missing" {
+				status.Installed = true
+				status.Version = value
+			}
+		case "active":
+			status.Active = value == "active"
+		case "config":
+			if value != "missing" {
+				content, err := base64.StdEncoding.DecodeString(value)
+				if err != nil {
+					return status, fmt.Errorf("decoding the configuration: %w", err)
+				}
+				config := string(content)
+				status.Config = &config
+			}
+		case "members":
+			for _, member := range strings.Split(value, ",") {
+				if member != "" {
+					status.Members = append(status.Members, member)
+				}
+			}
+		}
+	}
+
+	if !found["version"] || !found["active"] || !found["config"] || !found["members"] {
+		return status, errors.New("unexpected output: " + output)
+	}
+
+	return status, nil
+}
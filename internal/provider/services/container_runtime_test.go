@@ -0,0 +1,61 @@
+package services
+
+import (
+	"os/exec"
+	"slices"
+	"testing"
+)
+
+func TestContainerRuntimeCommands(t *testing.T) {
+	for _, runtime := range ContainerRuntimes {
+		for _, command := range []string{
+			ContainerRuntimeInstallCommand(runtime, "https://mirror.example.com/docker/"),
+			ContainerRuntimeRemoveCommand(runtime),
+			ContainerRuntimeStatusCommand(runtime),
+			ContainerdDefaultConfigCommand,
+		} {
+			if output, err := exec.Command("sh", "-n", "-c", command).CombinedOutput(); err != nil {
+				t.Errorf("invalid command %s: %s", command, output)
+			}
+		}
+	}
+}
+
+func TestParseContainerRuntimeStatus(t *testing.T) {
+	output := "Welcome!\r\nversion=Docker version 27.3.1, build ce12230\r\nactive=active\r\nconfig=eyJsb2ctZHJpdmVyIjoibG9jYWwifQ==\r\nmembers=deploy,ci\r\n"
+
+	status, err := ParseContainerRuntimeStatus(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Installed || !status.Active || status.Version != "Docker version 27.3.1, build ce12230" {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if status.Config == nil || *status.Config != `{"log-driver":"local"}` {
+		t.Errorf("unexpected configuration %v", status.Config)
+	}
+	if !slices.Equal(status.Members, []string{"deploy", "ci"}) {
+		t.Errorf("unexpected members %v", status.Members)
+	}
+
+	status, err = ParseContainerRuntimeStatus("version=missing\nactive=inactive\nconfig=missing\nmembers=\n")
+	if err != nil || status.Installed || status.Active || status.Config != nil || status.Members != nil {
+		t.Errorf("expected a missing runtime, got %+v, %v", status, err)
+	}
+
+	if _, err := ParseContainerRuntimeStatus("sudo: a password is required\n"); err == nil {
+		t.Error("expected an error for an unexpected output")
+	}
+}
+
+func TestContainerRuntimeConfig(t *testing.T) {
+	if err := ValidateContainerRuntimeConfig("docker", `["log-driver"]`); err == nil {
+		t.Error("expected a JSON array to be rejected")
+	}
+	if !ContainerRuntimeConfigEqual("docker", `{"log-driver": "local", "live-restore": true}`, "{\n  \"live-restore\": true,\n  \"log-driver\": \"local\"\n}\n") {
+		t.Error("expected the same settings to be equal")
+	}
+	if ContainerRuntimeConfigEqual("docker", `{"log-driver": "local"}`, `{"log-driver": "json-file"}`) {
+		t.Error("expected different settings to differ")
+	}
+}
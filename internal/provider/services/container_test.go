@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"remote-provider/internal/provider/servers"
+	"strings"
+	"testing"
+)
+
+func TestContainerCommand(t *testing.T) {
+	for _, test := range []struct {
+		server *servers.Server
+		want   string
+	}{
+		{&servers.Server{User: "deploy"}, "id -u"},
+		{&servers.Server{User: "deploy", Container: "web"}, "docker exec -i -u root 'web' sh -c 'id -u'"},
+		{&servers.Server{User: "deploy", Container: "web", ContainerTool: "podman"}, "podman exec -i -u root 'web' sh -c 'id -u'"},
+		{&servers.Server{User: "deploy", Container: "guest", ContainerTool: "lxc"}, "sudo -n lxc-attach -n 'guest' -- sh -c 'id -u'"},
+		{&servers.Server{User: "deploy", Container: "1234", ContainerTool: "nsenter", BecomeMethod: "doas"}, "doas -n nsenter -t '1234' -a sh -c 'id -u'"},
+		{&servers.Server{User: "root", Container: "1234", ContainerTool: "nsenter"}, "nsenter -t '1234' -a sh -c 'id -u'"},
+		{&servers.Server{User: "deploy", Container: "/mnt/image", ContainerTool: "chroot"}, "sudo -n chroot '/mnt/image' sh -c 'id -u'"},
+	} {
+		if got := containerCommand(test.server, "id -u"); got != test.want {
+			t.Errorf("containerCommand(%q) = %q, want %q", test.server.Container, got, test.want)
+		}
+	}
+
+	// su cannot enter a container without a terminal, the connection is refused before any command.
+	legacy := &servers.Server{Name: "host", User: "deploy", BecomeMethod: "su", Container: "guest", ContainerTool: "lxc"}
+	service := &SSHService{Fake: &FakeTransport{}}
+	if err := service.OpenConnection(context.Background(), legacy); err == nil || !strings.Contains(err.Error(), "through su") {
+		t.Errorf("expected su to be rejected for lxc containers, got %v", err)
+	}
+	legacy.ContainerTool = "docker"
+	if err := service.OpenConnection(context.Background(), legacy); err != nil {
+		t.Errorf("expected docker containers to be entered without su, got %v", err)
+	}
+
+	// Commands run as root in the container, so privileged commands are not escalated again.
+	server := &servers.Server{User: "deploy", Container: "web"}
+	if got := PrivilegedCommand(server, "id -u"); got != "id -u" {
+		t.Errorf("unexpected privileged command %q in a container", got)
+	}
+}
+
+func TestContainerTransport(t *testing.T) {
+	// A docker stand-in dropping "exec -i -u root <container>" and running the rest locally.
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte("#!/bin/sh\nshift 5\nexec \"$@\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	server := &servers.Server{Name: "localhost", Address: "localhost", Port: 22, Local: true, Container: "web"}
+	service := &SSHService{}
+	defer service.Close()
+	ctx := context.Background()
+
+	if err := service.OpenConnection(ctx, server); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "index.html")
+	if _, err := service.Upload(ctx, server, UploadCommand(path, false), strings.NewReader("<h1>hello</h1>\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := service.ExecuteCommand(ctx, "cat "+ShellQuote(path), server)
+	if err != nil || result.Stdout != "<h1>hello</h1>\n" {
+		t.Fatalf("unexpected result %+v, %v", result, err)
+	}
+}
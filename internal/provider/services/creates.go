@@ -0,0 +1,23 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// createsSkip is printed by a CreatesCommand that did not run its command.
+const createsSkip = "remote-host-creates-skip"
+
+// CreatesCommand returns a command running command unless creates exists, in which case it
+// only prints a marker read back by CreatesSkipped.
+func CreatesCommand(creates string, command string) string {
+	return fmt.Sprintf("if [ -e %s ]; then echo %s; exit 0; fi\n%s", ShellQuote(creates), createsSkip, command)
+}
+
+// CreatesSkipped returns whether the output of a CreatesCommand tells that its command did not
+// run. Lines printed before the marker, e.g. a login banner, are ignored.
+func CreatesSkipped(output string) bool {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(output, "\r\n", "\n")), "\n")
+
+	return strings.TrimSpace(lines[len(lines)-1]) == createsSkip
+}
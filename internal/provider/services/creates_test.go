@@ -0,0 +1,36 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreatesCommand(t *testing.T) {
+	dir := t.TempDir()
+	creates := filepath.Join(dir, "it's created")
+
+	for _, test := range []struct {
+		exists bool
+		want   bool
+	}{
+		{false, false},
+		{true, true},
+	} {
+		if test.exists {
+			if err := os.WriteFile(creates, nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		output, err := exec.Command("sh", "-c", CreatesCommand(creates, "echo applied")).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if skipped := CreatesSkipped(string(output)); skipped != test.want {
+			t.Errorf("exists %t: got skipped %t with output %q, want %t", test.exists, skipped, output, test.want)
+		}
+	}
+}
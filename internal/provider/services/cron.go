@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// cronAliases maps the cron shorthands to their five field expression.
+var cronAliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField describes the values allowed in a field of a cron expression.
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names []string
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// Both 0 and 7 are Sunday.
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// NormalizeCron validates a crontab schedule and returns it in a canonical form: aliases such
+// as @daily are expanded, month and day names become numbers, */1 becomes * and fields are
+// separated by single spaces. @reboot has no equivalent and is returned as is.
+func NormalizeCron(expression string) (string, error) {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(expression, "@") {
+		alias := strings.ToLower(expression)
+		if alias == "@reboot" {
+			return alias, nil
+		}
+
+		normalized, ok := cronAliases[alias]
+		if !ok {
+			return "", fmt.Errorf("unknown cron alias %q", expression)
+		}
+
+		return normalized, nil
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return "", fmt.Errorf("cron expression %q must have %d fields, got %d", expression, len(cronFields), len(fields))
+	}
+
+	for i, field := range fields {
+		normalized, err := cronFields[i].normalize(strings.ToLower(field))
+		if err != nil {
+			return "", fmt.Errorf("invalid %s %q in cron expression %q: %w", cronFields[i].name, field, expression, err)
+		}
+		fields[i] = normalized
+	}
+
+	return strings.Join(fields, " "), nil
+}
+
+// normalize validates a field made of comma separated items such as "*", "5", "1-5", "*/15" or "mon-fri/2".
+func (f cronField) normalize(field string) (string, error) {
+	items := strings.Split(field, ",")
+	for i, item := range items {
+		values, step, hasStep := strings.Cut(item, "/")
+		if hasStep {
+			number, err := strconv.Atoi(step)
+			if err != nil || number < 1 || number > f.max {
+				return "", fmt.Errorf("step %q must be between 1 and %d", step, f.max)
+			}
+			step = strconv.Itoa(number)
+		}
+
+		if values != "*" {
+			low, high, isRange := strings.Cut(values, "-")
+
+			lowValue, err := f.value(low)
+			if err != nil {
+				return "", err
+			}
+			values = strconv.Itoa(lowValue)
+
+			if isRange {
+				highValue, err := f.value(high)
+				if err != nil {
+					return "", err
+				}
+				if highValue < lowValue {
+					return "", fmt.Errorf("range %q is reversed", item)
+				}
+				values += "-" + strconv.Itoa(highValue)
+			} else if hasStep {
+				return "", fmt.Errorf("step of %q needs a range or *", item)
+			}
+		}
+
+		switch {
+		case !hasStep:
+			items[i] = values
+		case values == "*" && step == "1":
+			items[i] = "*"
+		default:
+			items[i] = values + "/" + step
+		}
+	}
+
+	if len(items) > 1 && strings.Contains(","+strings.Join(items, ",")+",", ",*,") {
+		return "", fmt.Errorf("* cannot be combined with other values")
+	}
+
+	return strings.Join(items, ","), nil
+}
+
+// value parses a number or a name of the field, names being numbered from the field minimum.
+func (f cronField) value(text string) (int, error) {
+	for i, name := range f.names {
+		if text == name {
+			return f.min + i, nil
+		}
+	}
+
+	number, err := strconv.Atoi(text)
+	if err != nil || number < f.min || number > f.max {
+		return 0, fmt.Errorf("%q must be between %d and %d", text, f.min, f.max)
+	}
+
+	return number, nil
+}
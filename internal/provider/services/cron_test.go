@@ -0,0 +1,46 @@
+package services
+
+import "testing"
+
+func TestNormalizeCron(t *testing.T) {
+	valid := map[string]string{
+		"@daily":                "0 0 * * *",
+		"@Weekly":               "0 0 * * 0",
+		"@reboot":               "@reboot",
+		" */15  *  * * * ":      "*/15 * * * *",
+		"*/1 2 * * *":           "* 2 * * *",
+		"0 9 * jan-mar MON-FRI": "0 9 * 1-3 1-5",
+		"0,30 8-18/2 1 * 7":     "0,30 8-18/2 1 * 7",
+		"05 04 * * sun":         "5 4 * * 0",
+	}
+	for expression, want := range valid {
+		got, err := NormalizeCron(expression)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", expression, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%q: got %q, want %q", expression, got, want)
+		}
+	}
+
+	invalid := []string{
+		"@sometimes",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"5/10 * * * *",
+		"*,5 * * * *",
+		"* * * foo *",
+	}
+	for _, expression := range invalid {
+		if got, err := NormalizeCron(expression); err == nil {
+			t.Errorf("%q: expected an error, got %q", expression, got)
+		}
+	}
+}
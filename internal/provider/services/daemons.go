@@ -0,0 +1,158 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Daemon describes a long running service installed by a resource.
+type Daemon struct {
+	Name        string
+	Description string
+	User        string
+	Command     []string
+}
+
+// launchdLabel returns the launchd label of the daemon.
+func (d Daemon) launchdLabel() string {
+	return "com.remote-host." + d.Name
+}
+
+// DaemonDefinitionPath returns where the systemd unit or launchd property list of d is installed.
+func (p Platform) DaemonDefinitionPath(d Daemon) string {
+	if p.OS == "darwin" {
+		return fmt.Sprintf("/Library/LaunchDaemons/%s.plist", d.launchdLabel())
+	}
+
+	return fmt.Sprintf("/etc/systemd/system/%s.service", d.Name)
+}
+
+// systemdArgumentEscaper escapes the characters of an argument systemd would otherwise interpret
+// in a quoted word of a command line, including its specifiers and variables.
+var systemdArgumentEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "%", "%%", "$", "$$")
+
+// systemdArgument quotes argument for a command line of a systemd unit, see the "Command lines"
+// section of systemd.service(5), so it is passed as a single argument as is.
+func systemdArgument(argument string) string {
+	return `"` + systemdArgumentEscaper.Replace(argument) + `"`
+}
+
+// systemdValue returns value for a single line setting of a systemd unit. name is the setting,
+// used in the error returned when value spans several lines and would inject other settings.
+func systemdValue(name string, value string) (string, error) {
+	if strings.ContainsAny(value, "\r\n") {
+		return "", fmt.Errorf("%s of the service must be a single line, got %q", name, value)
+	}
+
+	return strings.ReplaceAll(value, "%", "%%"), nil
+}
+
+// DaemonDefinition returns the systemd unit, or the launchd property list on macOS, running d.
+func (p Platform) DaemonDefinition(d Daemon) (string, error) {
+	if p.OS == "darwin" {
+		var arguments []string
+		for _, argument := range d.Command {
+			arguments = append(arguments, fmt.Sprintf("\t\t<string>%s</string>", html.EscapeString(argument)))
+		}
+
+		return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>UserName</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+</dict>
+</plist>
+`, d.launchdLabel(), html.EscapeString(d.User), strings.Join(arguments, "\n")), nil
+	}
+
+	description, err := systemdValue("Description", d.Description)
+	if err != nil {
+		return "", err
+	}
+	user, err := systemdValue("User", d.User)
+	if err != nil {
+		return "", err
+	}
+
+	var arguments []string
+	for _, argument := range d.Command {
+		arguments = append(arguments, systemdArgument(argument))
+	}
+
+	return fmt.Sprintf(`[Unit]
+Description=%s
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+User=%s
+Type=simple
+ExecStart=%s
+Restart=on-failure
+
+[Install]
+WantedBy=multi-user.target
+`, description, user, strings.Join(arguments, " ")), nil
+}
+
+// StartDaemonCommand returns a command (re)loading the definition of d and starting it at boot.
+func (p Platform) StartDaemonCommand(d Daemon) string {
+	if p.OS == "darwin" {
+		return fmt.Sprintf("launchctl bootout system/%[1]s 2>/dev/null; launchctl bootstrap system %[2]s && launchctl enable system/%[1]s",
+			d.launchdLabel(), ShellQuote(p.DaemonDefinitionPath(d)))
+	}
+
+	return fmt.Sprintf("systemctl daemon-reload && systemctl enable %[1]s && systemctl restart %[1]s", ShellQuote(d.Name))
+}
+
+// StopDaemonCommand returns a command stopping d and removing it from the boot sequence.
+func (p Platform) StopDaemonCommand(d Daemon) string {
+	if p.OS == "darwin" {
+		return fmt.Sprintf("launchctl bootout system/%s || true", d.launchdLabel())
+	}
+
+	return fmt.Sprintf("systemctl disable --now %s || true", ShellQuote(d.Name))
+}
+
+// DaemonActiveCommand returns a command exiting zero when d is running.
+func (p Platform) DaemonActiveCommand(d Daemon) string {
+	if p.OS == "darwin" {
+		return fmt.Sprintf("launchctl print system/%s | grep -q 'state = running'", d.launchdLabel())
+	}
+
+	return fmt.Sprintf("systemctl is-active --quiet %s", ShellQuote(d.Name))
+}
+
+// DaemonTools returns the tools needed to manage daemons.
+func (p Platform) DaemonTools() []string {
+	if p.OS == "darwin" {
+		return []string{"launchctl", "dscl"}
+	}
+
+	return []string{"systemctl", "useradd|adduser"}
+}
+
+// RestartServiceCommand returns a command restarting an existing service, name being a
+// systemd unit, an OpenRC service on BusyBox hosts or a launchd label on macOS.
+func (p Platform) RestartServiceCommand(name string) string {
+	switch {
+	case p.OS == "darwin":
+		return fmt.Sprintf("launchctl kickstart -k %s", ShellQuote("system/"+name))
+	case p.BusyBox:
+		return fmt.Sprintf("rc-service %s restart", ShellQuote(name))
+	}
+
+	return fmt.Sprintf("systemctl restart %s", ShellQuote(name))
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DirectoryScope selects the entries of a directory its permissions apply to.
+type DirectoryScope struct {
+	// Recurse applies the permissions to the contents of the directory, not only to itself.
+	Recurse bool
+	// MaxDepth limits the recursion to that many levels below the directory, 0 means no limit.
+	MaxDepth int64
+	// Exclude lists glob patterns of entries skipped along with their contents. Patterns with
+	// a slash match the path relative to the directory, others match the entry name.
+	Exclude []string
+}
+
+// DirectoryPermissions are the permissions of a directory and of the entries of its scope.
+// Empty fields are left unchanged.
+type DirectoryPermissions struct {
+	Mode     string
+	FileMode string
+	Owner    string
+	Group    string
+}
+
+// find returns the beginning of a find command walking the scope of directory, excluded
+// entries being pruned.
+func (scope DirectoryScope) find(directory string) string {
+	command := "find " + ShellQuote(directory)
+	switch {
+	case !scope.Recurse:
+		command += " -maxdepth 0"
+	case scope.MaxDepth > 0:
+		command += fmt.Sprintf(" -maxdepth %d", scope.MaxDepth)
+	}
+
+	if !scope.Recurse || len(scope.Exclude) == 0 {
+		return command
+	}
+
+	var tests []string
+	for _, pattern := range scope.Exclude {
+		if strings.Contains(pattern, "/") {
+			tests = append(tests, "-path "+ShellQuote(strings.TrimSuffix(directory, "/")+"/"+strings.TrimPrefix(pattern, "/")))
+		} else {
+			tests = append(tests, "-name "+ShellQuote(pattern))
+		}
+	}
+
+	return command + ` \( ` + strings.Join(tests, " -o ") + ` \) -prune -o`
+}
+
+// ownership returns the chown argument and find test of the owner and group of permissions.
+func (permissions DirectoryPermissions) ownership() (string, string) {
+	var owner string
+	var tests []string
+	if permissions.Owner != "" {
+		owner = permissions.Owner
+		tests = append(tests, "! -user "+ShellQuote(permissions.Owner))
+	}
+	if permissions.Group != "" {
+		owner += ":" + permissions.Group
+		tests = append(tests, "! -group "+ShellQuote(permissions.Group))
+	}
+
+	return ShellQuote(owner), strings.Join(tests, " -o ")
+}
+
+// DriftedPathsCommand returns a command printing the paths in the scope of directory whose
+// permissions differ from permissions, one per line.
+func DriftedPathsCommand(directory string, scope DirectoryScope, permissions DirectoryPermissions) string {
+	var tests []string
+	if permissions.Mode != "" {
+		tests = append(tests, "-type d ! -perm "+permissions.Mode)
+	}
+	if permissions.FileMode != "" {
+		tests = append(tests, "-type f ! -perm "+permissions.FileMode)
+	}
+	if _, ownership := permissions.ownership(); ownership != "" {
+		tests = append(tests, ownership)
+	}
+
+	if len(tests) == 0 {
+		return "true"
+	}
+
+	return fmt.Sprintf(`%s \( %s \) -print`, scope.find(directory), strings.Join(tests, ` -o `))
+}
+
+// ApplyPermissionsCommand returns a command applying permissions to the entries in the scope of
+// directory that differ from them.
+func ApplyPermissionsCommand(directory string, scope DirectoryScope, permissions DirectoryPermissions) string {
+	commands := []string{"true"}
+
+	if owner, ownership := permissions.ownership(); ownership != "" {
+		commands = append(commands, fmt.Sprintf(`%s \( %s \) -exec chown -h %s {} +`, scope.find(directory), ownership, owner))
+	}
+	if permissions.Mode != "" {
+		commands = append(commands, fmt.Sprintf("%s -type d ! -perm %s -exec chmod %s {} +", scope.find(directory), permissions.Mode, permissions.Mode))
+	}
+	if permissions.FileMode != "" {
+		commands = append(commands, fmt.Sprintf("%s -type f ! -perm %s -exec chmod %s {} +", scope.find(directory), permissions.FileMode, permissions.FileMode))
+	}
+
+	return strings.Join(commands, " && ")
+}
+
+// ParsePaths returns the non-empty lines of output of a DriftedPathsCommand.
+func ParsePaths(output string) []string {
+	paths := []string{}
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		if line != "" {
+			paths = append(paths, line)
+		}
+	}
+
+	return paths
+}
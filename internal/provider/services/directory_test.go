@@ -0,0 +1,65 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDirectoryPermissionsCommands(t *testing.T) {
+	directory := t.TempDir()
+	for _, path := range []string{"a/b/c", "cache/x", "keep"} {
+		if err := os.MkdirAll(filepath.Join(directory, path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(directory, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(command string) []string {
+		t.Helper()
+
+		output, err := exec.Command("sh", "-c", command).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %v: %s", command, err, output)
+		}
+
+		paths := ParsePaths(string(output))
+		slices.Sort(paths)
+
+		return paths
+	}
+
+	scope := DirectoryScope{Recurse: true, MaxDepth: 2, Exclude: []string{"cache", "/keep"}}
+	permissions := DirectoryPermissions{Mode: "0755"}
+
+	drifted := run(DriftedPathsCommand(directory, scope, permissions))
+	want := []string{directory, filepath.Join(directory, "a"), filepath.Join(directory, "a/b")}
+	if !slices.Equal(drifted, want) {
+		t.Fatalf("unexpected drifted paths %q, want %q", drifted, want)
+	}
+
+	run(ApplyPermissionsCommand(directory, scope, permissions))
+	if drifted := run(DriftedPathsCommand(directory, scope, permissions)); len(drifted) != 0 {
+		t.Fatalf("unexpected drifted paths %q after apply", drifted)
+	}
+
+	// Entries beyond max_depth or excluded are left untouched.
+	for _, path := range []string{"a/b/c", "cache", "keep"} {
+		info, err := os.Stat(filepath.Join(directory, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o700 {
+			t.Errorf("%s mode changed to %o", path, info.Mode().Perm())
+		}
+	}
+
+	// Without recursion only the directory itself is considered.
+	if drifted := run(DriftedPathsCommand(directory, DirectoryScope{}, DirectoryPermissions{Mode: "0750"})); !slices.Equal(drifted, []string{directory}) {
+		t.Fatalf("unexpected drifted paths %q without recursion", drifted)
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FactsDir holds the custom facts of a host, one JSON file per fact group as in the facts.d
+// directories of Ansible, which can read them too when pointed at it.
+const FactsDir = "/etc/remote-host/facts.d"
+
+// factSuffix ends the names of fact files.
+const factSuffix = ".fact"
+
+// FactNameRegexp matches the names of fact groups.
+var FactNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// FactPath returns the path of the file of the fact group name in dir.
+func FactPath(dir string, name string) string {
+	return strings.TrimRight(dir, "/") + "/" + name + factSuffix
+}
+
+// RenderFacts returns the JSON file content of facts, with sorted keys.
+func RenderFacts(facts map[string]string) []byte {
+	content, _ := json.MarshalIndent(facts, "", "  ")
+	return append(content, '\n')
+}
+
+// ParseFacts parses the JSON object of a fact file. Values that are not strings, e.g. written
+// by another tool, are kept as their JSON text.
+func ParseFacts(content []byte) (map[string]string, error) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("facts must be a JSON object: %w", err)
+	}
+
+	facts := make(map[string]string, len(values))
+	for key, raw := range values {
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			value = string(raw)
+		}
+		facts[key] = value
+	}
+
+	return facts, nil
+}
+
+// ReadFactsCommand returns a command printing a "<name> <base64 content>" line for every fact
+// file of dir, and nothing when dir does not exist.
+func ReadFactsCommand(dir string) string {
+	return fmt.Sprintf(`for f in %s/*%s; do [ -f "$f" ] || continue; `+
+		`printf '%%s ' "$(basename "$f" %s)"; base64 < "$f" | tr -d '\n'; echo; done`,
+		ShellQuote(strings.TrimRight(dir, "/")), factSuffix, factSuffix)
+}
+
+// ParseFactFiles parses the output of ReadFactsCommand into the facts of every group keyed by
+// name. Lines of another shape, e.g. a login banner, and files that are not JSON objects are
+// ignored.
+func ParseFactFiles(output string) map[string]map[string]string {
+	groups := map[string]map[string]string{}
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		name, encoded, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok || !FactNameRegexp.MatchString(name) {
+			continue
+		}
+
+		content, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+
+		facts, err := ParseFacts(content)
+		if err != nil {
+			continue
+		}
+		groups[name] = facts
+	}
+
+	return groups
+}
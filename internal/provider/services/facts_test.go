@@ -0,0 +1,66 @@
+package services
+
+import (
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFacts(t *testing.T) {
+	facts := map[string]string{"role": "db", "quote": `"primary"`}
+	got, err := ParseFacts(RenderFacts(facts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(got, facts) {
+		t.Errorf("expected %v, got %v", facts, got)
+	}
+
+	got, err = ParseFacts([]byte(`{"replicas": 3, "tags": ["a"], "name": "x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"replicas": "3", "tags": `["a"]`, "name": "x"}
+	if !maps.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if _, err := ParseFacts([]byte("[section]\nkey=value\n")); err == nil {
+		t.Error("expected an error for a file that is not JSON")
+	}
+}
+
+func TestReadFactsCommand(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.fact":     string(RenderFacts(map[string]string{"version": "1.2.3"})),
+		"legacy.fact":  "[section]\nkey=value\n",
+		"ignored.json": `{"a": "b"}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output, err := exec.Command("sh", "-c", ReadFactsCommand(dir+"/")).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups := ParseFactFiles("Welcome!\n" + string(output))
+	if len(groups) != 1 || groups["app"]["version"] != "1.2.3" {
+		t.Errorf("expected the app facts only, got %v", groups)
+	}
+
+	output, err = exec.Command("sh", "-c", ReadFactsCommand(filepath.Join(dir, "missing"))).Output()
+	if err != nil || len(output) != 0 {
+		t.Errorf("expected no output for a missing directory, got %q, %v", output, err)
+	}
+
+	if got := FactPath("/etc/facts.d/", "app"); got != "/etc/facts.d/app.fact" {
+		t.Errorf("unexpected fact path %s", got)
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"remote-provider/internal/provider/servers"
+	"time"
+)
+
+// FakeTransport answers commands from a table of responses instead of connecting to hosts,
+// so modules can be exercised with terraform test without real hosts.
+type FakeTransport struct {
+	// Responses are matched in order against each command, the first match answers it.
+	// Commands matching none succeed without output.
+	Responses []FakeResponse
+}
+
+// FakeResponse is the result of the commands matching Pattern.
+type FakeResponse struct {
+	Pattern  *regexp.Regexp
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// fakeDefaults answer the probes every resource relies on after the configured responses:
+// a Linux host with GNU tools, every tool present and a private workspace.
+var fakeDefaults = []FakeResponse{
+	{Pattern: regexp.MustCompile(`^` + regexp.QuoteMeta(detectPlatformCommand) + `$`), Stdout: "Linux\n/usr/bin/stat\n"},
+	{Pattern: regexp.MustCompile(`mktemp -d "\$\{TMPDIR:-/tmp\}/remote-host\.`), Stdout: "/tmp/remote-host.fake\n"},
+}
+
+// execute returns the response to command.
+func (fake *FakeTransport) execute(command string) (*servers.ServerCommand, error) {
+	start := time.Now()
+	response := FakeResponse{}
+	for _, candidate := range append(fake.Responses[:len(fake.Responses):len(fake.Responses)], fakeDefaults...) {
+		if candidate.Pattern.MatchString(command) {
+			response = candidate
+			break
+		}
+	}
+
+	serverCommand := &servers.ServerCommand{
+		Command:    command,
+		Stdout:     response.Stdout,
+		Stderr:     response.Stderr,
+		ExitCode:   int8(response.ExitCode),
+		StartedAt:  start,
+		FinishedAt: time.Now(),
+	}
+
+	if response.ExitCode != 0 {
+		return serverCommand, fmt.Errorf("fake command exited with status %d", response.ExitCode)
+	}
+
+	return serverCommand, nil
+}
+
+// upload discards content and answers command.
+func (fake *FakeTransport) upload(command string, content io.Reader) (*servers.ServerCommand, error) {
+	_, err := io.Copy(io.Discard, content)
+	if err != nil {
+		return nil, err
+	}
+
+	return fake.execute(command)
+}
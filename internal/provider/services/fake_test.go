@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"remote-provider/internal/provider/servers"
+	"strings"
+	"testing"
+)
+
+func TestFakeTransport(t *testing.T) {
+	service := &SSHService{
+		OutputFraming: true,
+		Fake: &FakeTransport{Responses: []FakeResponse{
+			{Pattern: regexp.MustCompile(`^systemctl is-active nginx$`), Stdout: "inactive\n", ExitCode: 3},
+			{Pattern: regexp.MustCompile(`^cat /etc/hostname$`), Stdout: "web-1\n"},
+		}},
+	}
+	defer service.Close()
+
+	server := &servers.Server{Name: "web-1", Address: "192.0.2.10", Port: 22, User: "deploy"}
+	ctx := context.Background()
+
+	if err := service.OpenConnection(ctx, server); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := service.ExecuteCommand(ctx, "cat /etc/hostname", server)
+	if err != nil || result.Stdout != "web-1\n" {
+		t.Fatalf("unexpected result %+v, %v", result, err)
+	}
+	if result.StartedAt.IsZero() || result.FinishedAt.Before(result.StartedAt) {
+		t.Errorf("expected the command to be timed, got %v to %v", result.StartedAt, result.FinishedAt)
+	}
+	if (&servers.ServerCommand{}).Duration() != 0 {
+		t.Error("expected no duration for an untimed command")
+	}
+
+	result, err = service.ExecuteCommand(ctx, "systemctl is-active nginx", server)
+	if err == nil || result.ExitCode != 3 || result.Stdout != "inactive\n" {
+		t.Fatalf("unexpected failing result %+v, %v", result, err)
+	}
+
+	// The probes of the provider are answered by default, other commands succeed silently.
+	platform, err := service.DetectPlatform(ctx, server)
+	if err != nil || platform.OS != "linux" || platform.BusyBox {
+		t.Fatalf("unexpected platform %+v, %v", platform, err)
+	}
+	if err := service.Preflight(ctx, server, []string{"sha256sum", "curl|wget"}); err != nil {
+		t.Fatal(err)
+	}
+	workspace, err := service.Workspace(ctx, server)
+	if err != nil || workspace != "/tmp/remote-host.fake" {
+		t.Fatalf("unexpected workspace %q, %v", workspace, err)
+	}
+
+	if _, err := service.Upload(ctx, server, "cat > /tmp/x", strings.NewReader("content")); err != nil {
+		t.Fatal(err)
+	}
+	history := server.GetHistory()
+	if last := history[len(history)-1]; last.Command != "cat > /tmp/x" {
+		t.Fatalf("unexpected last command %q", last.Command)
+	}
+
+	if err := service.DropConnection(server); err != nil {
+		t.Fatal(err)
+	}
+}
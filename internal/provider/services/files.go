@@ -0,0 +1,124 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// WriteFileCommand returns a command atomically replacing path with content and the given octal mode.
+// The temporary file is removed when the command is interrupted.
+func WriteFileCommand(path string, content []byte, mode string) string {
+	tmpPath := ShellQuote(path + ".remote-host.tmp")
+
+	return fmt.Sprintf(
+		"trap %s INT TERM HUP; printf '%%s' %s | base64 -d > %s && chmod %s %s && mv -f %s %s",
+		ShellQuote("rm -f "+tmpPath+"; exit 130"),
+		ShellQuote(base64.StdEncoding.EncodeToString(content)),
+		tmpPath,
+		mode,
+		tmpPath,
+		tmpPath,
+		ShellQuote(path),
+	)
+}
+
+// RewriteFileCommand returns a command replacing the content of path in place, keeping the owner,
+// mode, ACL and inode of an existing file, or atomically creating it with the given octal mode.
+func RewriteFileCommand(path string, content []byte, mode string) string {
+	tmpPath := path + ".remote-host.tmp"
+
+	return fmt.Sprintf(
+		"trap %s INT TERM HUP; printf '%%s' %s | base64 -d > %s && %s",
+		ShellQuote("rm -f "+ShellQuote(tmpPath)+"; exit 130"),
+		ShellQuote(base64.StdEncoding.EncodeToString(content)),
+		ShellQuote(tmpPath),
+		InstallFileCommand(tmpPath, path, mode, false),
+	)
+}
+
+// InstallFileCommand returns a command moving the content of tmpPath to path as RewriteFileCommand
+// does. A created file gets the given octal mode, and is owned by root with root when tmpPath was
+// written by another user.
+func InstallFileCommand(tmpPath string, path string, mode string, root bool) string {
+	quotedTmpPath := ShellQuote(tmpPath)
+	quotedPath := ShellQuote(path)
+
+	create := fmt.Sprintf("chmod %s %s", mode, quotedTmpPath)
+	if root {
+		create += " && chown 0:0 " + quotedTmpPath
+	}
+
+	return fmt.Sprintf(
+		"if [ -f %s ]; then cat %s > %s && rm -f %s; else %s && mv -f %s %s; fi",
+		quotedPath, quotedTmpPath, quotedPath, quotedTmpPath, create, quotedTmpPath, quotedPath,
+	)
+}
+
+// ChecksumFilesCommand returns a command printing one line per path: the sha256 digest of the
+// file, "-" when the host has no sha256 tool or "missing" when the file does not exist.
+func ChecksumFilesCommand(paths []string) string {
+	var commands []string
+	for _, path := range paths {
+		checksum, _ := RemoteChecksumCommand("sha256", path)
+		commands = append(commands, fmt.Sprintf("if [ -f %s ]; then %s; else echo missing; fi", ShellQuote(path), checksum))
+	}
+
+	return strings.Join(commands, "; ")
+}
+
+// ReadFilesCommand returns a command printing the base64 encoded content of every path on its own line.
+func ReadFilesCommand(paths []string) string {
+	var commands []string
+	for _, path := range paths {
+		commands = append(commands, fmt.Sprintf("{ base64 < %s | tr -d '\\n'; echo; }", ShellQuote(path)))
+	}
+
+	return strings.Join(commands, "; ")
+}
+
+// ParseFileLines returns the last count lines of output, one per path of a ChecksumFilesCommand
+// or a ReadFilesCommand. Lines printed before them, e.g. a login banner, are ignored.
+func ParseFileLines(output string, count int) ([]string, error) {
+	if count == 0 {
+		return nil, nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(strings.ReplaceAll(output, "\r\n", "\n"), "\n"), "\n")
+	if len(lines) < count {
+		return nil, fmt.Errorf("expected %d lines of output, got %d", count, len(lines))
+	}
+
+	lines = lines[len(lines)-count:]
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+
+	return lines, nil
+}
+
+// ContentNormalization lists the rewrites applied to file content before it is written and
+// compared, so whitespace changes made by editors or heredocs do not show as drift.
+type ContentNormalization struct {
+	// TrailingNewline appends a newline to non-empty content not ending with one.
+	TrailingNewline bool
+	// Whitespace converts CRLF line endings to LF and strips the spaces and tabs ending lines.
+	Whitespace bool
+}
+
+// Normalize returns content rewritten according to normalization.
+func (normalization ContentNormalization) Normalize(content string) string {
+	if normalization.Whitespace {
+		lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(line, " \t\r")
+		}
+		content = strings.Join(lines, "\n")
+	}
+
+	if normalization.TrailingNewline && content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+
+	return content
+}
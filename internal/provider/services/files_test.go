@@ -0,0 +1,112 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseFileLines(t *testing.T) {
+	output := "Welcome to host\r\n" +
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\r\n" +
+		"missing\r\n"
+
+	lines, err := ParseFileLines(output, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", "missing"}
+	if !slices.Equal(lines, want) {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+
+	// Empty files read back as empty lines.
+	lines, err = ParseFileLines("aGk=\n\n", 2)
+	if err != nil || !slices.Equal(lines, []string{"aGk=", ""}) {
+		t.Fatalf("unexpected lines %q, %v", lines, err)
+	}
+
+	if _, err := ParseFileLines("missing\n", 3); err == nil {
+		t.Fatal("expected an error for truncated output")
+	}
+}
+
+func TestContentNormalization(t *testing.T) {
+	for _, test := range []struct {
+		normalization ContentNormalization
+		content       string
+		want          string
+	}{
+		{ContentNormalization{}, "a  \r\nb", "a  \r\nb"},
+		{ContentNormalization{TrailingNewline: true}, "a\nb", "a\nb\n"},
+		{ContentNormalization{TrailingNewline: true}, "a\nb\n", "a\nb\n"},
+		{ContentNormalization{TrailingNewline: true}, "", ""},
+		{ContentNormalization{Whitespace: true}, "key = value \t\r\n\r\n  indented\t", "key = value\n\n  indented"},
+		{ContentNormalization{TrailingNewline: true, Whitespace: true}, "a \r\nb\t", "a\nb\n"},
+		{ContentNormalization{TrailingNewline: true, Whitespace: true}, "a\n  \n", "a\n\n"},
+	} {
+		if got := test.normalization.Normalize(test.content); got != test.want {
+			t.Errorf("%+v.Normalize(%q) = %q, want %q", test.normalization, test.content, got, test.want)
+		}
+	}
+}
+
+func TestRewriteFileCommand(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.conf")
+	created := filepath.Join(dir, "created.conf")
+	if err := os.WriteFile(existing, []byte("old\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Stat(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{existing, created} {
+		if output, err := exec.Command("sh", "-c", RewriteFileCommand(path, []byte("new\n"), "0600")).CombinedOutput(); err != nil {
+			t.Fatalf("%s: %v: %s", path, err, output)
+		}
+		if content, err := os.ReadFile(path); err != nil || string(content) != "new\n" {
+			t.Errorf("%s: got %q, %v", path, content, err)
+		}
+	}
+
+	after, err := os.Stat(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Mode().Perm() != 0o640 || !os.SameFile(before, after) {
+		t.Errorf("expected the existing file to keep its mode and inode, got %v", after.Mode())
+	}
+
+	if info, err := os.Stat(created); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected the created file to get the mode, got %v, %v", info, err)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("expected the temporary files to be removed, got %d entries", len(entries))
+	}
+}
+
+func TestInstallFileCommand(t *testing.T) {
+	cases := map[string]struct {
+		root     bool
+		expected string
+	}{
+		"user": {false, "if [ -f '/etc/app.conf' ]; then cat '/tmp/w/file' > '/etc/app.conf' && rm -f '/tmp/w/file'; " +
+			"else chmod 0644 '/tmp/w/file' && mv -f '/tmp/w/file' '/etc/app.conf'; fi"},
+		"root": {true, "if [ -f '/etc/app.conf' ]; then cat '/tmp/w/file' > '/etc/app.conf' && rm -f '/tmp/w/file'; " +
+			"else chmod 0644 '/tmp/w/file' && chown 0:0 '/tmp/w/file' && mv -f '/tmp/w/file' '/etc/app.conf'; fi"},
+	}
+
+	for name, c := range cases {
+		actual := InstallFileCommand("/tmp/w/file", "/etc/app.conf", "0644", c.root)
+		if actual != c.expected {
+			t.Errorf("%s: expected %s, got %s", name, c.expected, actual)
+		}
+	}
+}
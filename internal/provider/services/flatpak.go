@@ -0,0 +1,71 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// FlatpakRefRegexp matches the reverse DNS identifiers of flatpak applications and runtimes.
+	FlatpakRefRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*(\.[A-Za-z_][A-Za-z0-9_-]*)+$`)
+	// FlatpakRemoteRegexp matches the names of flatpak remotes.
+	FlatpakRemoteRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// Flatpak is a flatpak application or runtime installed system-wide on a host.
+type Flatpak struct {
+	Name    string
+	Branch  string
+	Remote  string
+	Version string
+}
+
+// flatpakRef returns the identifier of name on branch understood by the flatpak commands.
+func flatpakRef(name string, branch string) string {
+	if branch == "" {
+		return ShellQuote(name)
+	}
+
+	return ShellQuote(name + "//" + branch)
+}
+
+// FlatpakListCommand prints a tab separated line for every system-wide installation.
+const FlatpakListCommand = "flatpak list --system --columns=application,branch,origin,version"
+
+// ParseFlatpakList returns the installation of name on branch from the output of
+// FlatpakListCommand, on any branch when branch is empty. It returns nil when name is not
+// installed.
+func ParseFlatpakList(output string, name string, branch string) *Flatpak {
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 || fields[0] != name || (branch != "" && fields[1] != branch) {
+			continue
+		}
+
+		flatpak := &Flatpak{Name: name, Branch: fields[1], Remote: fields[2]}
+		if len(fields) > 3 {
+			flatpak.Version = strings.TrimSpace(fields[3])
+		}
+
+		return flatpak
+	}
+
+	return nil
+}
+
+// FlatpakInstallCommand returns a command installing name on branch from remote, adding remote
+// from url first when url is set.
+func FlatpakInstallCommand(name string, branch string, remote string, url string) string {
+	command := ""
+	if url != "" {
+		command = fmt.Sprintf("flatpak remote-add --system --if-not-exists %s %s && ", ShellQuote(remote), ShellQuote(url))
+	}
+
+	return command + fmt.Sprintf("flatpak install --system -y --noninteractive %s %s", ShellQuote(remote), flatpakRef(name, branch))
+}
+
+// FlatpakRemoveCommand returns a command uninstalling name on branch.
+func FlatpakRemoveCommand(name string, branch string) string {
+	return fmt.Sprintf("flatpak uninstall --system -y --noninteractive %s", flatpakRef(name, branch))
+}
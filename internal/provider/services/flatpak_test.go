@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestParseFlatpakList(t *testing.T) {
+	output := "org.freedesktop.Platform\t23.08\tflathub\t23.08.20\r\norg.mozilla.firefox\tstable\tflathub\t125.0.3\r\n"
+
+	want := Flatpak{Name: "org.mozilla.firefox", Branch: "stable", Remote: "flathub", Version: "125.0.3"}
+	if flatpak := ParseFlatpakList(output, "org.mozilla.firefox", ""); flatpak == nil || *flatpak != want {
+		t.Errorf("expected %+v, got %+v", want, flatpak)
+	}
+	if flatpak := ParseFlatpakList(output, "org.mozilla.firefox", "beta"); flatpak != nil {
+		t.Errorf("expected no installation on the beta branch, got %+v", flatpak)
+	}
+}
+
+func TestFlatpakInstallCommand(t *testing.T) {
+	tests := []struct {
+		branch string
+		url    string
+		want   string
+	}{
+		{want: "flatpak install --system -y --noninteractive 'flathub' 'org.mozilla.firefox'"},
+		{
+			branch: "beta",
+			url:    "https://dl.flathub.org/beta-repo/flathub-beta.flatpakrepo",
+			want:   "flatpak remote-add --system --if-not-exists 'flathub' 'https://dl.flathub.org/beta-repo/flathub-beta.flatpakrepo' && flatpak install --system -y --noninteractive 'flathub' 'org.mozilla.firefox//beta'",
+		},
+	}
+
+	for _, tt := range tests {
+		if command := FlatpakInstallCommand("org.mozilla.firefox", tt.branch, "flathub", tt.url); command != tt.want {
+			t.Errorf("expected %s, got %s", tt.want, command)
+		}
+	}
+}
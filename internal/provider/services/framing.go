@@ -0,0 +1,59 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// outputFrame delimits the output of a command with random sentinel lines so
+// login banners, MOTDs and shell start-up noise printed into the PTY can be
+// told apart from what the command itself wrote.
+type outputFrame struct {
+	begin string
+	end   string
+}
+
+func newOutputFrame() (*outputFrame, error) {
+	token := make([]byte, 8)
+	_, err := rand.Read(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &outputFrame{
+		begin: "REMOTE-HOST-BEGIN-" + hex.EncodeToString(token),
+		end:   "REMOTE-HOST-END-" + hex.EncodeToString(token),
+	}, nil
+}
+
+// wrap surrounds command with the sentinel lines, preserving its exit code.
+func (frame *outputFrame) wrap(command string) string {
+	return fmt.Sprintf(
+		"printf '\\n%%s\\n' %s; {\n%s\n}; __remote_host_status=$?; printf '\\n%%s\\n' %s; exit $__remote_host_status",
+		frame.begin,
+		command,
+		frame.end,
+	)
+}
+
+// extract returns what was printed between the sentinel lines with PTY line endings normalized.
+// The output is returned untouched when the begin sentinel is missing, and up to the end
+// of the output when the command exited before the end sentinel was printed.
+func (frame *outputFrame) extract(output string) string {
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+
+	begin := strings.Index(output, frame.begin+"\n")
+	if begin < 0 {
+		return output
+	}
+	output = output[begin+len(frame.begin)+1:]
+
+	end := strings.LastIndex(output, "\n"+frame.end)
+	if end >= 0 {
+		output = output[:end]
+	}
+
+	return output
+}
@@ -0,0 +1,38 @@
+package services
+
+import "testing"
+
+func TestOutputFrameExtract(t *testing.T) {
+	frame := &outputFrame{begin: "BEGIN", end: "END"}
+
+	cases := map[string]struct {
+		output   string
+		expected string
+	}{
+		"banner": {
+			output:   "Welcome to host\r\nLast login: today\r\n\r\nBEGIN\r\n1234\r\ncontent\r\n\r\nEND\r\n",
+			expected: "1234\ncontent\n",
+		},
+		"no trailing newline": {
+			output:   "\nBEGIN\nvalue\nEND\n",
+			expected: "value",
+		},
+		"exit before end": {
+			output:   "noise\nBEGIN\npartial\n",
+			expected: "partial\n",
+		},
+		"not framed": {
+			output:   "plain\r\noutput",
+			expected: "plain\noutput",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			actual := frame.extract(c.output)
+			if actual != c.expected {
+				t.Fatalf("expected %q, got %q", c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/servers"
+	"sync"
+)
+
+// GroupResult holds the outcome of a group command on a single server.
+type GroupResult struct {
+	Server  *servers.Server
+	Command *servers.ServerCommand
+	Err     error
+}
+
+// ExecuteGroupCommand runs command on every server of group concurrently, as root when
+// privileged. Results are returned in the same order as group.Servers.
+func (service *SSHService) ExecuteGroupCommand(ctx context.Context, command string, privileged bool, group *servers.ServerGroup) []GroupResult {
+	results := make([]GroupResult, len(group.Servers))
+
+	var wg sync.WaitGroup
+	for i, server := range group.Servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			results[i] = GroupResult{Server: server}
+
+			err := service.OpenConnection(ctx, server)
+			if err != nil {
+				results[i].Err = err
+				return
+			}
+
+			serverCommand, err := service.ExpandHostVars(command, server)
+			if err != nil {
+				results[i].Err = err
+				return
+			}
+
+			if privileged {
+				serverCommand = PrivilegedCommand(server, serverCommand)
+			}
+
+			results[i].Command, results[i].Err = service.ExecuteCommand(ctx, serverCommand, server)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// DelegatedCommand prefixes command with the REMOTE_HOST_TARGET and REMOTE_HOST_TARGET_USER
+// variables describing target, for commands running on another host on behalf of target.
+func DelegatedCommand(target *servers.Server, command string) string {
+	return "REMOTE_HOST_TARGET=" + ShellQuote(target.Address) + " REMOTE_HOST_TARGET_USER=" + ShellQuote(target.User) +
+		"; export REMOTE_HOST_TARGET REMOTE_HOST_TARGET_USER\n" + command
+}
+
+// ExecuteDelegatedCommand runs command on delegate once for every server of group, with the
+// target server described by DelegatedCommand, as root when privileged. Results are returned
+// for the target servers in the same order as group.Servers.
+func (service *SSHService) ExecuteDelegatedCommand(ctx context.Context, command string, privileged bool, delegate *servers.Server, group *servers.ServerGroup) []GroupResult {
+	results := make([]GroupResult, len(group.Servers))
+
+	err := service.OpenConnection(ctx, delegate)
+	if err != nil {
+		for i, server := range group.Servers {
+			results[i] = GroupResult{Server: server, Err: fmt.Errorf("delegate %s: %w", delegate.Name, err)}
+		}
+
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i, server := range group.Servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			results[i] = GroupResult{Server: server}
+
+			// The variables are the ones of the target, the command runs on its behalf.
+			targetCommand, err := service.ExpandHostVars(command, server)
+			if err != nil {
+				results[i].Err = err
+				return
+			}
+
+			delegateCommand := DelegatedCommand(server, targetCommand)
+			if privileged {
+				delegateCommand = PrivilegedCommand(delegate, delegateCommand)
+			}
+
+			results[i].Command, results[i].Err = service.ExecuteCommand(ctx, delegateCommand, delegate)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
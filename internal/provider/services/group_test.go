@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"remote-provider/internal/provider/servers"
+	"strings"
+	"testing"
+)
+
+func TestExecuteDelegatedCommand(t *testing.T) {
+	service := &SSHService{Fake: &FakeTransport{Responses: []FakeResponse{
+		{Pattern: regexp.MustCompile(`REMOTE_HOST_TARGET='10\.0\.0\.3'`), Stderr: "replica unreachable", ExitCode: 1},
+	}}}
+	defer service.Close()
+
+	delegate := &servers.Server{Name: "primary", Address: "10.0.0.1", Port: 22, User: "postgres"}
+	group := &servers.ServerGroup{Servers: []*servers.Server{
+		{Name: "replica-1", Address: "10.0.0.2", Port: 22, User: "admin"},
+		{Name: "replica-2", Address: "10.0.0.3", Port: 22, User: "admin"},
+	}}
+
+	results := service.ExecuteDelegatedCommand(context.Background(), "pg_basebackup -h \"$REMOTE_HOST_TARGET\"", false, delegate, group)
+
+	if results[0].Server.Name != "replica-1" || results[0].Err != nil {
+		t.Errorf("unexpected result for replica-1: %+v", results[0])
+	}
+	if results[1].Server.Name != "replica-2" || results[1].Err == nil || results[1].Command.Stderr != "replica unreachable" {
+		t.Errorf("unexpected result for replica-2: %+v", results[1])
+	}
+
+	// Every command ran on the delegate.
+	history := delegate.GetHistory()
+	if len(history) != 2 || len(group.Servers[0].GetHistory()) != 0 {
+		t.Fatalf("expected both commands on the delegate, got %d", len(history))
+	}
+	for _, command := range history {
+		if !strings.HasPrefix(command.Command, "REMOTE_HOST_TARGET='10.0.0.") || !strings.HasSuffix(command.Command, "\npg_basebackup -h \"$REMOTE_HOST_TARGET\"") {
+			t.Errorf("unexpected delegated command %q", command.Command)
+		}
+	}
+}
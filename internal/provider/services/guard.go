@@ -0,0 +1,41 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// guardRun and guardSkip are printed by GuardCommand.
+const (
+	guardRun  = "remote-host-guard-run"
+	guardSkip = "remote-host-guard-skip"
+)
+
+// GuardCommand returns a command telling whether the changes guarded by onlyIf and unless must
+// be made: onlyIf must succeed and unless must fail, empty guards are ignored. The output of the
+// guards is discarded, read the verdict with ParseGuard.
+func GuardCommand(onlyIf string, unless string) string {
+	conditions := []string{"true"}
+	if onlyIf != "" {
+		conditions = append(conditions, fmt.Sprintf("(\n%s\n) >/dev/null 2>&1", onlyIf))
+	}
+	if unless != "" {
+		conditions = append(conditions, fmt.Sprintf("! (\n%s\n) >/dev/null 2>&1", unless))
+	}
+
+	return fmt.Sprintf("if %s; then echo %s; else echo %s; fi", strings.Join(conditions, " && "), guardRun, guardSkip)
+}
+
+// ParseGuard returns whether the output of a GuardCommand allows the guarded changes.
+func ParseGuard(output string) (bool, error) {
+	for _, line := range strings.Split(output, "\n") {
+		switch strings.TrimSpace(line) {
+		case guardRun:
+			return true, nil
+		case guardSkip:
+			return false, nil
+		}
+	}
+
+	return false, fmt.Errorf("unable to find the verdict of the guards in the command output")
+}
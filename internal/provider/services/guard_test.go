@@ -0,0 +1,36 @@
+package services
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestGuardCommand(t *testing.T) {
+	for _, test := range []struct {
+		onlyIf string
+		unless string
+		want   bool
+	}{
+		{"", "", true},
+		{"true", "", true},
+		{"test -e /nonexistent", "", false},
+		{"", "echo managed; exit 0", false},
+		{"", "grep -q appliance /nonexistent", true},
+		{"true", "false", true},
+		{"true", "true", false},
+	} {
+		output, err := exec.Command("sh", "-c", GuardCommand(test.onlyIf, test.unless)).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		run, err := ParseGuard(string(output))
+		if err != nil || run != test.want {
+			t.Errorf("only_if %q, unless %q: got %t, %v, want %t", test.onlyIf, test.unless, run, err, test.want)
+		}
+	}
+
+	if _, err := ParseGuard("motd\n"); err == nil {
+		t.Error("expected an error without verdict")
+	}
+}
@@ -0,0 +1,39 @@
+package services
+
+import "remote-provider/internal/provider/servers"
+
+// historySize bounds how many commands are kept per host by the SSH service.
+const historySize = 100
+
+// recordHistory records command in the history of server and in the bounded history the
+// service keeps per host across resources.
+func (service *SSHService) recordHistory(server *servers.Server, command *servers.ServerCommand) {
+	server.AppendHistory(command)
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	if service.history == nil {
+		service.history = map[string][]*servers.ServerCommand{}
+	}
+
+	history := append(service.history[server.Name], command)
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+	service.history[server.Name] = history
+}
+
+// History returns up to the last limit commands executed on host by the service, oldest first.
+// A limit of 0 returns every command kept.
+func (service *SSHService) History(host string, limit int) []*servers.ServerCommand {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	history := service.history[host]
+	if limit > 0 && len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+
+	return append([]*servers.ServerCommand(nil), history...)
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"fmt"
+	"remote-provider/internal/provider/servers"
+	"sync"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	service := &SSHService{}
+	server := &servers.Server{Name: "web-1"}
+
+	var wg sync.WaitGroup
+	for i := range historySize + 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			service.recordHistory(server, &servers.ServerCommand{Command: fmt.Sprintf("echo %d", i)})
+		}()
+	}
+	wg.Wait()
+
+	if got := len(server.GetHistory()); got != historySize+20 {
+		t.Errorf("expected %d commands in the server history, got %d", historySize+20, got)
+	}
+	if got := len(service.History("web-1", 0)); got != historySize {
+		t.Errorf("expected the service history bounded to %d commands, got %d", historySize, got)
+	}
+
+	last := service.History("web-1", 3)
+	if len(last) != 3 || last[2] != server.GetHistory()[historySize+19] {
+		t.Errorf("expected the 3 last commands, got %d", len(last))
+	}
+	if got := service.History("web-2", 3); len(got) != 0 {
+		t.Errorf("expected no history for an unknown host, got %d commands", len(got))
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// HomebrewTapRegexp matches the <user>/<repository> names of the taps.
+	HomebrewTapRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+	// HomebrewPackageRegexp matches the names of formulae and casks, optionally qualified by
+	// their tap.
+	HomebrewPackageRegexp = regexp.MustCompile(`^([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+/)?[A-Za-z0-9_.+@-]+$`)
+)
+
+// brewPrelude finds brew even when the non-login shell of the connection lacks the PATH set up by
+// the shell profile: in /opt/homebrew on Apple Silicon, /usr/local on Intel Macs and
+// /home/linuxbrew for Linuxbrew. Homebrew refuses to run as root, so it runs as the connection
+// user, and it must not ask questions nor update itself on every command.
+const brewPrelude = `brew=$(command -v brew || for p in /opt/homebrew/bin/brew /usr/local/bin/brew /home/linuxbrew/.linuxbrew/bin/brew; do ` +
+	`[ -x "$p" ] && echo "$p" && break; done); ` +
+	`[ -n "$brew" ] || { echo 'brew is not installed' >&2; exit 127; }; ` +
+	`export NONINTERACTIVE=1 HOMEBREW_NO_AUTO_UPDATE=1 HOMEBREW_NO_ENV_HINTS=1 HOMEBREW_NO_INSTALL_CLEANUP=1; `
+
+// brewKind returns the brew option selecting casks instead of formulae.
+func brewKind(cask bool) string {
+	if cask {
+		return " --cask"
+	}
+
+	return " --formula"
+}
+
+// HomebrewVersionsCommand prints the installed versions of name, or missing when it is not
+// installed.
+func HomebrewVersionsCommand(name string, cask bool) string {
+	return brewPrelude + fmt.Sprintf(`"$brew" list --versions%s %s || echo missing`, brewKind(cask), ShellQuote(name))
+}
+
+// ParseHomebrewVersions returns the versions of name from the output of HomebrewVersionsCommand,
+// nil when it is not installed.
+func ParseHomebrewVersions(output string, name string) ([]string, error) {
+	// brew lists tap qualified packages by their short name.
+	short := path.Base(name)
+
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 1 && fields[0] == "missing" {
+			return nil, nil
+		}
+		if len(fields) >= 2 && fields[0] == short {
+			return fields[1:], nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected brew list output: %s", output)
+}
+
+// HomebrewInstallCommand returns a command installing name, or upgrading it when upgrade is set
+// and it is already installed. brew taps the repository of a tap qualified name by itself.
+func HomebrewInstallCommand(name string, cask bool, upgrade bool) string {
+	kind := brewKind(cask)
+	command := fmt.Sprintf(`"$brew" list%s %s >/dev/null 2>&1 || "$brew" install%s %s`, kind, ShellQuote(name), kind, ShellQuote(name))
+	if upgrade {
+		command = fmt.Sprintf(`if "$brew" list%s %s >/dev/null 2>&1; then "$brew" upgrade%s %s; else "$brew" install%s %s; fi`,
+			kind, ShellQuote(name), kind, ShellQuote(name), kind, ShellQuote(name))
+	}
+
+	return brewPrelude + command
+}
+
+// HomebrewUninstallCommand returns a command uninstalling name.
+func HomebrewUninstallCommand(name string, cask bool) string {
+	return brewPrelude + fmt.Sprintf(`"$brew" uninstall%s %s`, brewKind(cask), ShellQuote(name))
+}
+
+// HomebrewTapsCommand lists the taps of the host.
+const HomebrewTapsCommand = brewPrelude + `"$brew" tap`
+
+// HasHomebrewTap reports whether the output of HomebrewTapsCommand lists name. brew lowercases
+// the names of the taps.
+func HasHomebrewTap(output string, name string) bool {
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		if strings.EqualFold(strings.TrimSpace(line), name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HomebrewTapCommand returns a command adding the tap name, cloned from url when set instead of
+// the homebrew-<repository> GitHub repository.
+func HomebrewTapCommand(name string, url string) string {
+	command := brewPrelude + `"$brew" tap ` + ShellQuote(name)
+	if url != "" {
+		command += " " + ShellQuote(url)
+	}
+
+	return command
+}
+
+// HomebrewUntapCommand returns a command removing the tap name.
+func HomebrewUntapCommand(name string) string {
+	return brewPrelude + `"$brew" untap ` + ShellQuote(name)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseHomebrewVersions(t *testing.T) {
+	tests := []struct {
+		output string
+		name   string
+		want   []string
+	}{
+		{output: "Welcome!\r\njq 1.7.1\r\n", name: "jq", want: []string{"1.7.1"}},
+		{output: "terraform 1.5.7 1.5.6\n", name: "hashicorp/tap/terraform", want: []string{"1.5.7", "1.5.6"}},
+		{output: "missing\n", name: "jq"},
+	}
+
+	for _, tt := range tests {
+		versions, err := ParseHomebrewVersions(tt.output, tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(versions, tt.want) {
+			t.Errorf("expected %v, got %v", tt.want, versions)
+		}
+	}
+
+	if _, err := ParseHomebrewVersions("Error: Running Homebrew as root is extremely dangerous\n", "jq"); err == nil {
+		t.Error("expected an error for an unexpected output")
+	}
+}
+
+func TestHasHomebrewTap(t *testing.T) {
+	output := "hashicorp/tap\nhomebrew/bundle\n"
+	if !HasHomebrewTap(output, "HashiCorp/tap") || HasHomebrewTap(output, "hashicorp/tap-extra") {
+		t.Errorf("unexpected taps matched in %q", output)
+	}
+}
+
+func TestHomebrewCommands(t *testing.T) {
+	dir := t.TempDir()
+	brew := filepath.Join(dir, "brew")
+	if err := os.WriteFile(brew, []byte("#!/bin/sh\n[ \"$1\" = list ] && exit 1\necho \"$@\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{command: HomebrewInstallCommand("jq", false, false), want: "install --formula jq\n"},
+		{command: HomebrewInstallCommand("firefox", true, true), want: "install --cask firefox\n"},
+		{command: HomebrewTapCommand("acme/tools", "https://git.example.com/acme/homebrew-tools.git"), want: "tap acme/tools https://git.example.com/acme/homebrew-tools.git\n"},
+	}
+
+	for _, tt := range tests {
+		command := exec.Command("sh", "-c", tt.command)
+		command.Env = append(os.Environ(), "PATH="+dir+":/usr/bin:/bin")
+		output, err := command.CombinedOutput()
+		if err != nil || string(output) != tt.want {
+			t.Errorf("%s: expected %q, got %q, %v", tt.command, tt.want, output, err)
+		}
+	}
+}
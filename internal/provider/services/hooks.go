@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"remote-provider/internal/provider/servers"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// ApplyHooks are commands run on every host the provider changes during a run, e.g. to enable
+// a maintenance mode before the changes and disable it afterwards.
+type ApplyHooks struct {
+	// PreApply runs once per host, before the first command of an operation marked by WithApply.
+	PreApply string
+	// PostApply runs once per host on which PreApply succeeded, when the service is closed.
+	PostApply string
+	// Privileged runs the hooks as root.
+	Privileged bool
+}
+
+// applyHookState is the outcome of the pre-apply hook of a host, known once done is closed.
+type applyHookState struct {
+	server *servers.Server
+	done   chan struct{}
+	err    error
+}
+
+type applyContextKey struct{}
+
+// WithApply marks ctx as belonging to an operation changing hosts, such as a resource create,
+// update or delete, or an action. Its commands run after the pre-apply hook of their host.
+func WithApply(ctx context.Context) context.Context {
+	return context.WithValue(ctx, applyContextKey{}, true)
+}
+
+// isApply reports whether ctx was marked by WithApply.
+func isApply(ctx context.Context) bool {
+	applying, _ := ctx.Value(applyContextKey{}).(bool)
+	return applying
+}
+
+// hookCommand returns the command running hook on server.
+func (service *SSHService) hookCommand(server *servers.Server, hook string) string {
+	if service.Hooks.Privileged {
+		return PrivilegedCommand(server, hook)
+	}
+
+	return hook
+}
+
+// beginApply runs the pre-apply hook of server before the first command of an apply operation
+// on it. Concurrent operations wait for the hook, and all of them fail when it failed, so no
+// change reaches a host whose maintenance mode could not be enabled.
+func (service *SSHService) beginApply(ctx context.Context, server *servers.Server) error {
+	if !isApply(ctx) {
+		return nil
+	}
+
+	service.mutex.Lock()
+	state, ok := service.applied[server.Name]
+	if !ok {
+		if service.applied == nil {
+			service.applied = map[string]*applyHookState{}
+		}
+		state = &applyHookState{server: server, done: make(chan struct{})}
+		service.applied[server.Name] = state
+	}
+	service.mutex.Unlock()
+
+	if ok {
+		select {
+		case <-state.done:
+			return state.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if service.Hooks != nil && service.Hooks.PreApply != "" {
+		tflog.Info(ctx, "running the pre-apply hook", map[string]any{"host": server.Name})
+
+		_, err := service.executeCommand(ctx, service.hookCommand(server, service.Hooks.PreApply), server)
+		if err != nil {
+			state.err = fmt.Errorf("running the pre-apply hook on %s: %w", server.Name, err)
+		}
+	}
+	close(state.done)
+
+	return state.err
+}
+
+// endApply runs the post-apply hook on the hosts whose pre-apply hook succeeded.
+func (service *SSHService) endApply() error {
+	if service.Hooks == nil || service.Hooks.PostApply == "" {
+		return nil
+	}
+
+	service.mutex.Lock()
+	applied := service.applied
+	service.applied = nil
+	service.mutex.Unlock()
+
+	var errs []error
+	for name, state := range applied {
+		<-state.done
+
+		connection := service.findConnection(state.server)
+		if state.err != nil || connection == nil {
+			continue
+		}
+
+		_, err := service.executeCommand(context.Background(), service.hookCommand(connection.host, service.Hooks.PostApply), connection.host)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("running the post-apply hook on %s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"remote-provider/internal/provider/servers"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestApplyHooks(t *testing.T) {
+	service := &SSHService{
+		Fake:  &FakeTransport{},
+		Hooks: &ApplyHooks{PreApply: "maintenance on", PostApply: "maintenance off"},
+	}
+
+	server := &servers.Server{Name: "web-1", Address: "192.0.2.10", Port: 22, User: "root"}
+	ctx := context.Background()
+
+	if err := service.OpenConnection(ctx, server); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reads do not run the hooks, changes run the pre-apply hook once before their commands.
+	for _, step := range []struct {
+		ctx     context.Context
+		command string
+	}{
+		{ctx, "cat /etc/hostname"},
+		{WithApply(ctx), "touch /etc/a"},
+		{WithApply(ctx), "touch /etc/b"},
+	} {
+		if _, err := service.ExecuteCommand(step.ctx, step.command, server); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := service.Upload(WithApply(ctx), server, "cat > /etc/c", strings.NewReader("c")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var commands []string
+	for _, command := range server.GetHistory() {
+		commands = append(commands, command.Command)
+	}
+	want := []string{"cat /etc/hostname", "maintenance on", "touch /etc/a", "touch /etc/b", "cat > /etc/c", "maintenance off"}
+	if !slices.Equal(commands, want) {
+		t.Errorf("expected commands %q, got %q", want, commands)
+	}
+}
+
+func TestApplyHooksFailure(t *testing.T) {
+	service := &SSHService{
+		Fake: &FakeTransport{Responses: []FakeResponse{
+			{Pattern: regexp.MustCompile(`^maintenance on$`), Stderr: "busy\n", ExitCode: 1},
+		}},
+		Hooks: &ApplyHooks{PreApply: "maintenance on", PostApply: "maintenance off"},
+	}
+
+	server := &servers.Server{Name: "web-1", Address: "192.0.2.10", Port: 22, User: "root"}
+	ctx := WithApply(context.Background())
+
+	if err := service.OpenConnection(ctx, server); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if _, err := service.ExecuteCommand(ctx, "touch /etc/a", server); err == nil || !strings.Contains(err.Error(), "pre-apply hook") {
+			t.Fatalf("expected the pre-apply hook to fail the command, got %v", err)
+		}
+	}
+
+	if err := service.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	history := server.GetHistory()
+	if len(history) != 1 || history[0].Command != "maintenance on" {
+		t.Errorf("expected only the failed pre-apply hook to run, got %+v", history)
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// defaultIdentityFiles are the identity files OpenSSH tries by default, in its order.
+var defaultIdentityFiles = []string{"id_rsa", "id_ecdsa", "id_ecdsa_sk", "id_ed25519", "id_ed25519_sk", "id_xmss", "id_dsa"}
+
+// discoverSigners returns the keys OpenSSH would offer without configuration: the keys of the
+// agent listening on SSH_AUTH_SOCK, then the unencrypted default identity files of sshDir.
+// The returned agent connection must be closed once the handshake is done, it is nil when no
+// agent is used.
+func discoverSigners(sshDir string, logger handshakeLogger) ([]ssh.Signer, net.Conn) {
+	var signers []ssh.Signer
+
+	var agentConn net.Conn
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			logger.log("ssh agent unavailable", map[string]any{"socket": socket, "error": err.Error()})
+		} else if agentSigners, err := agent.NewClient(conn).Signers(); err != nil || len(agentSigners) == 0 {
+			conn.Close()
+		} else {
+			signers = append(signers, agentSigners...)
+			agentConn = conn
+		}
+	}
+
+	if sshDir == "" {
+		return signers, agentConn
+	}
+
+	for _, name := range defaultIdentityFiles {
+		path := filepath.Join(sshDir, name)
+		keyFile, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		signer, err := ssh.ParsePrivateKey(keyFile)
+		var passphraseErr *ssh.PassphraseMissingError
+		if errors.As(err, &passphraseErr) {
+			// Only the agent can use encrypted keys, there is nobody to ask for the passphrase.
+			logger.log("ssh identity skipped", map[string]any{"path": path, "reason": "encrypted"})
+			continue
+		}
+		if err != nil {
+			logger.log("ssh identity skipped", map[string]any{"path": path, "error": err.Error()})
+			continue
+		}
+
+		signers = append(signers, signer)
+	}
+
+	return signers, agentConn
+}
+
+// defaultSSHDir returns the .ssh directory of the local user, empty when there is no home.
+func defaultSSHDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".ssh")
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestDiscoverSigners(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	sshDir := t.TempDir()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, block := range map[string]*pem.Block{"id_ed25519": plain, "id_ecdsa": encrypted} {
+		if err := os.WriteFile(filepath.Join(sshDir, name), pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(sshDir, "id_rsa"), []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	signers, agentConn := discoverSigners(sshDir, handshakeLogger{ctx: context.Background()})
+	if agentConn != nil {
+		t.Error("expected no agent connection without SSH_AUTH_SOCK")
+	}
+	if len(signers) != 1 || signers[0].PublicKey().Type() != ssh.KeyAlgoED25519 {
+		t.Fatalf("expected only the unencrypted ed25519 key, got %d keys", len(signers))
+	}
+
+	if signers, _ := discoverSigners("", handshakeLogger{ctx: context.Background()}); len(signers) != 0 {
+		t.Fatalf("expected no key without a .ssh directory, got %d", len(signers))
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"strings"
+)
+
+// IdentityCommand prints the identifiers of a host as key=value lines: the machine ID of
+// systemd or D-Bus, and the product UUID and serial numbers of the DMI tables on Linux or of
+// the platform expert on macOS. The DMI files are only readable by root.
+const IdentityCommand = `rh_id() { [ -r "$2" ] && echo "$1=$(tr -d '\n' < "$2" 2>/dev/null)"; }; ` +
+	`rh_id machine_id /etc/machine-id || rh_id machine_id /var/lib/dbus/machine-id; ` +
+	`rh_id product_uuid /sys/class/dmi/id/product_uuid; ` +
+	`rh_id product_serial /sys/class/dmi/id/product_serial; ` +
+	`rh_id board_serial /sys/class/dmi/id/board_serial; ` +
+	`if command -v ioreg >/dev/null 2>&1; then ioreg -rd1 -c IOPlatformExpertDevice | ` +
+	`sed -n 's/.*"IOPlatformUUID" = "\(.*\)".*/product_uuid=\1/p; s/.*"IOPlatformSerialNumber" = "\(.*\)".*/product_serial=\1/p'; fi; ` +
+	`true`
+
+// placeholderIdentifiers are the values firmware vendors leave in DMI fields they do not fill.
+var placeholderIdentifiers = []string{
+	"", "0", "none", "n/a", "default string", "not specified", "not applicable", "to be filled by o.e.m.",
+	"system serial number", "0123456789", "00000000-0000-0000-0000-000000000000",
+	"ffffffff-ffff-ffff-ffff-ffffffffffff", "03000200-0400-0500-0006-000700080009",
+}
+
+// HostIdentity holds the identifiers of a host, empty when unknown.
+type HostIdentity struct {
+	MachineID     string
+	ProductUUID   string
+	ProductSerial string
+	BoardSerial   string
+}
+
+// Identifier returns the most specific identifier of the host: the product UUID, which
+// survives reinstalls, then the machine ID, then the board serial number.
+func (identity HostIdentity) Identifier() string {
+	for _, value := range []string{identity.ProductUUID, identity.MachineID, identity.BoardSerial} {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// ParseHostIdentity parses the output of IdentityCommand, ignoring unrelated lines and the
+// placeholder values of firmware vendors. UUIDs are lower cased, as DMI tables differ.
+func ParseHostIdentity(output string) HostIdentity {
+	var identity HostIdentity
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		lower := strings.ToLower(value)
+		placeholder := false
+		for _, known := range placeholderIdentifiers {
+			placeholder = placeholder || lower == known
+		}
+		if placeholder {
+			continue
+		}
+
+		switch key {
+		case "machine_id":
+			identity.MachineID = lower
+		case "product_uuid":
+			identity.ProductUUID = lower
+		case "product_serial":
+			identity.ProductSerial = value
+		case "board_serial":
+			identity.BoardSerial = value
+		}
+	}
+
+	return identity
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestParseHostIdentity(t *testing.T) {
+	output := "Welcome!\r\nmachine_id=0F1E2D3C4B5A69788796A5B4C3D2E1F0\r\nproduct_uuid=4C4C4544-0042-3510-8051-B7C04F4A3732\r\n" +
+		"product_serial=7B5QJ32\r\nboard_serial=To Be Filled By O.E.M.\r\n"
+
+	identity := ParseHostIdentity(output)
+	want := HostIdentity{
+		MachineID:     "0f1e2d3c4b5a69788796a5b4c3d2e1f0",
+		ProductUUID:   "4c4c4544-0042-3510-8051-b7c04f4a3732",
+		ProductSerial: "7B5QJ32",
+	}
+	if identity != want {
+		t.Errorf("expected %+v, got %+v", want, identity)
+	}
+	if identity.Identifier() != want.ProductUUID {
+		t.Errorf("expected the product UUID as identifier, got %s", identity.Identifier())
+	}
+
+	identity = ParseHostIdentity("machine_id=abc\nproduct_uuid=03000200-0400-0500-0006-000700080009\n")
+	if identity.ProductUUID != "" || identity.Identifier() != "abc" {
+		t.Errorf("expected the machine ID as identifier, got %+v", identity)
+	}
+
+	if identifier := ParseHostIdentity("").Identifier(); identifier != "" {
+		t.Errorf("expected no identifier, got %s", identifier)
+	}
+}
+
+func TestIdentityCommand(t *testing.T) {
+	if err := exec.Command("sh", "-c", IdentityCommand).Run(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// KnownHostsLine returns the known_hosts line trusting publicKey, in authorized_keys format,
+// for host on port. Ports other than 22 use the "[host]:port" form. With hashed, the host
+// is hashed with a random salt as ssh-keygen -H does, so the file does not reveal it.
+func KnownHostsLine(host string, port int64, publicKey string, hashed bool) (string, error) {
+	if host == "" || strings.ContainsAny(host, " ,\t\n") {
+		return "", fmt.Errorf("invalid host %q", host)
+	}
+	if port < 1 || port > 65535 {
+		return "", fmt.Errorf("invalid port %d", port)
+	}
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return "", fmt.Errorf("parsing the public key: %w", err)
+	}
+
+	entry := knownhosts.Normalize(net.JoinHostPort(host, strconv.FormatInt(port, 10)))
+	if hashed {
+		entry = knownhosts.HashHostname(entry)
+	}
+
+	return entry + " " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))), nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestKnownHostsLine(t *testing.T) {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+
+	line, err := KnownHostsLine("server.example.com", 22, authorizedKey+" root@server", false)
+	if err != nil || line != "server.example.com "+authorizedKey {
+		t.Fatalf("unexpected line %q, %v", line, err)
+	}
+
+	line, err = KnownHostsLine("10.0.0.5", 2222, authorizedKey, false)
+	if err != nil || line != "[10.0.0.5]:2222 "+authorizedKey {
+		t.Fatalf("unexpected line %q, %v", line, err)
+	}
+
+	// Hashed lines are salted, check that ssh accepts them instead.
+	line, err = KnownHostsLine("10.0.0.5", 2222, authorizedKey, true)
+	if err != nil || !strings.HasPrefix(line, "|1|") || strings.Contains(line, "10.0.0.5") {
+		t.Fatalf("unexpected hashed line %q, %v", line, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(path, []byte(line+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := callback("10.0.0.5:2222", &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 2222}, key); err != nil {
+		t.Fatalf("hashed line rejected: %v", err)
+	}
+
+	for _, invalid := range []struct {
+		host string
+		port int64
+		key  string
+	}{
+		{"", 22, authorizedKey},
+		{"a host", 22, authorizedKey},
+		{"host", 0, authorizedKey},
+		{"host", 22, "ssh-ed25519 not-base64"},
+	} {
+		if line, err := KnownHostsLine(invalid.host, invalid.port, invalid.key, false); err == nil {
+			t.Errorf("%+v: expected an error, got %q", invalid, line)
+		}
+	}
+}
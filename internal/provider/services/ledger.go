@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"remote-provider/internal/provider/servers"
+)
+
+// The resource operations counted by Activity.
+const (
+	OperationRead   = "read"
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+// operation is a resource operation marked by WithOperation, its address identifies it.
+type operation struct {
+	kind string
+}
+
+type operationContextKey struct{}
+
+// WithOperation marks ctx as a resource operation of kind, the hosts its commands reach are
+// counted by Activity.
+func WithOperation(ctx context.Context, kind string) context.Context {
+	return context.WithValue(ctx, operationContextKey{}, &operation{kind: kind})
+}
+
+// HostActivity counts the resource operations that ran commands on a host during the provider
+// run. Resources working on several hosts are counted on each of them.
+type HostActivity struct {
+	Resources int
+	Reads     int
+	Creates   int
+	Updates   int
+	Deletes   int
+}
+
+// Changed reports whether a resource created, updated or deleted something on the host.
+func (activity HostActivity) Changed() bool {
+	return activity.Creates+activity.Updates+activity.Deletes > 0
+}
+
+// recordOperation counts the operation of ctx, if any, on server.
+func (service *SSHService) recordOperation(ctx context.Context, server *servers.Server) {
+	op, ok := ctx.Value(operationContextKey{}).(*operation)
+	if !ok {
+		return
+	}
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	if service.operations == nil {
+		service.operations = map[string]map[*operation]bool{}
+	}
+	if service.operations[server.Name] == nil {
+		service.operations[server.Name] = map[*operation]bool{}
+	}
+	service.operations[server.Name][op] = true
+}
+
+// Activity returns the resource operations counted so far keyed by host.
+func (service *SSHService) Activity() map[string]HostActivity {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	activities := make(map[string]HostActivity, len(service.operations))
+	for host, operations := range service.operations {
+		activity := HostActivity{Resources: len(operations)}
+		for op := range operations {
+			switch op.kind {
+			case OperationRead:
+				activity.Reads++
+			case OperationCreate:
+				activity.Creates++
+			case OperationUpdate:
+				activity.Updates++
+			case OperationDelete:
+				activity.Deletes++
+			}
+		}
+		activities[host] = activity
+	}
+
+	return activities
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"remote-provider/internal/provider/servers"
+	"testing"
+)
+
+func TestActivity(t *testing.T) {
+	service := &SSHService{Fake: &FakeTransport{}}
+	web := &servers.Server{Name: "web", User: "deploy"}
+	db := &servers.Server{Name: "db", User: "deploy"}
+
+	read := WithOperation(context.Background(), OperationRead)
+	create := WithOperation(context.Background(), OperationCreate)
+
+	for _, run := range []struct {
+		ctx    context.Context
+		server *servers.Server
+	}{
+		{read, web},
+		{read, web},
+		{WithOperation(context.Background(), OperationRead), web},
+		{create, web},
+		{create, db},
+		{context.Background(), db},
+	} {
+		if err := service.OpenConnection(run.ctx, run.server); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := service.ExecuteCommand(run.ctx, "true", run.server); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	activity := service.Activity()
+	if got, want := activity["web"], (HostActivity{Resources: 3, Reads: 2, Creates: 1}); got != want || !got.Changed() {
+		t.Errorf("web: got %+v, want %+v", got, want)
+	}
+	if got, want := activity["db"], (HostActivity{Resources: 1, Creates: 1}); got != want {
+		t.Errorf("db: got %+v, want %+v", got, want)
+	}
+	if (HostActivity{Resources: 1, Reads: 1}).Changed() {
+		t.Error("expected reads not to change the host")
+	}
+}
@@ -0,0 +1,55 @@
+package services
+
+import "sync"
+
+// hostLimiter bounds how many distinct hosts have work in flight at the same time.
+// Work for a host that is already active never waits for a slot.
+type hostLimiter struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	active map[string]int
+}
+
+func (limiter *hostLimiter) acquire(name string, limit int) {
+	if limit <= 0 {
+		return
+	}
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if limiter.cond == nil {
+		limiter.cond = sync.NewCond(&limiter.mutex)
+		limiter.active = map[string]int{}
+	}
+
+	for limiter.active[name] == 0 && len(limiter.active) >= limit {
+		limiter.cond.Wait()
+	}
+
+	limiter.active[name]++
+}
+
+func (limiter *hostLimiter) release(name string, limit int) {
+	if limit <= 0 {
+		return
+	}
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	limiter.active[name]--
+	if limiter.active[name] == 0 {
+		delete(limiter.active, name)
+		limiter.cond.Broadcast()
+	}
+}
+
+// newSessionSlots returns a semaphore for the sessions of one connection, nil when unlimited.
+func newSessionSlots(limit int) chan struct{} {
+	if limit <= 0 {
+		return nil
+	}
+
+	return make(chan struct{}, limit)
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterBoundsActiveHosts(t *testing.T) {
+	var limiter hostLimiter
+	var wg sync.WaitGroup
+
+	peak := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			name := fmt.Sprintf("host-%d", i%5)
+			limiter.acquire(name, 2)
+			defer limiter.release(name, 2)
+
+			limiter.mutex.Lock()
+			if len(limiter.active) > peak {
+				peak = len(limiter.active)
+			}
+			limiter.mutex.Unlock()
+
+			time.Sleep(time.Millisecond)
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Fatalf("expected at most 2 active hosts, got %d", peak)
+	}
+}
+
+func TestHostLimiterUnlimited(t *testing.T) {
+	var limiter hostLimiter
+
+	limiter.acquire("host", 0)
+	limiter.release("host", 0)
+
+	if limiter.active != nil {
+		t.Fatalf("expected unlimited limiter to stay untouched")
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"remote-provider/internal/provider/servers"
+	"strings"
+	"time"
+)
+
+// localShell runs the commands of local hosts.
+const localShell = "/bin/sh"
+
+// localUser returns the name of the user running Terraform, the user local hosts default to.
+func localUser() (string, error) {
+	current, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("looking up the local user: %w", err)
+	}
+
+	return current.Username, nil
+}
+
+// openLocal registers a connection to host whose commands run on the machine running Terraform.
+func (service *SSHService) openLocal(host *servers.Server) error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	key := connectionKey(host)
+	if service.connectionByKey(key) == nil {
+		service.connections = append(service.connections, &SSHConnection{host: host, key: key, local: true})
+	}
+	return nil
+}
+
+// executeLocalCommand runs command with the local shell. The sudo password of server is its
+// standard input when command holds a privileged command, for the sudo -S of PrivilegedCommand.
+func (service *SSHService) executeLocalCommand(ctx context.Context, server *servers.Server, command string) (*servers.ServerCommand, error) {
+	var stdin io.Reader
+	if !runsAsRoot(server) && server.SudoPassword != "" && strings.Contains(command, localSudoPrefix) {
+		stdin = strings.NewReader(server.SudoPassword + "\n")
+	}
+
+	return service.runLocal(ctx, server, command, stdin)
+}
+
+// runLocal runs command with the local shell, reading stdin when set. Uploads call it directly
+// so their content reaches the command untouched.
+func (service *SSHService) runLocal(ctx context.Context, server *servers.Server, command string, stdin io.Reader) (*servers.ServerCommand, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, localShell, "-c", service.Wrapper.Wrap(containerCommand(server, service.withUmask(command))))
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Canceled commands are interrupted first so they can clean up, then killed.
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = cancelGracePeriod
+
+	start := time.Now()
+	err := cmd.Run()
+	finish := time.Now()
+	service.Measure(ctx, "command", server, start, stdout.Len()+stderr.Len(), err)
+
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("command timed out: %w", ctx.Err())
+	}
+
+	var exitCode int8
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = int8(exitErr.ExitCode())
+		err = fmt.Errorf("local command exited with status %d", exitCode)
+	} else if err != nil {
+		return nil, fmt.Errorf("running a local command for %s: %w", server.Name, err)
+	}
+
+	serverCommand := &servers.ServerCommand{
+		Command:    command,
+		Stdout:     stdout.String(),
+		Stderr:     stderr.String(),
+		ExitCode:   exitCode,
+		StartedAt:  start,
+		FinishedAt: finish,
+	}
+	service.recordHistory(server, serverCommand)
+
+	if privilegeErr := privilegeError(server, serverCommand); privilegeErr != nil {
+		return serverCommand, privilegeErr
+	}
+
+	return serverCommand, err
+}
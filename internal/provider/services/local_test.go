@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"remote-provider/internal/provider/servers"
+	"strings"
+	"testing"
+)
+
+func TestLocalTransport(t *testing.T) {
+	server := &servers.Server{Name: "localhost", Address: "localhost", Port: 22, Local: true}
+
+	service := &SSHService{}
+	ctx := context.Background()
+
+	if err := service.OpenConnection(ctx, server); err != nil {
+		t.Fatal(err)
+	}
+	if connection := service.findConnection(server); connection == nil || !connection.local {
+		t.Fatal("expected a local connection")
+	}
+	if server.User == "" {
+		t.Error("expected the user to default to the local user")
+	}
+
+	result, err := service.ExecuteCommand(ctx, "echo 'hello world'; echo oops >&2; exit 3", server)
+	if err == nil || result.ExitCode != 3 || result.Stdout != "hello world\n" || result.Stderr != "oops\n" {
+		t.Fatalf("unexpected result %+v, %v", result, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "uploaded file")
+	if _, err := service.Upload(ctx, server, UploadCommand(path, false), strings.NewReader("content\n")); err != nil {
+		t.Fatal(err)
+	}
+	if written, err := os.ReadFile(path); err != nil || string(written) != "content\n" {
+		t.Fatalf("unexpected uploaded content %q, %v", written, err)
+	}
+
+	workspace, err := service.Workspace(ctx, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(workspace); !os.IsNotExist(err) {
+		t.Errorf("expected the workspace %s to be removed, got %v", workspace, err)
+	}
+}
+
+func TestLocalPrivilegedCommand(t *testing.T) {
+	server := &servers.Server{User: "deploy", SudoPassword: "secret", Local: true}
+	if got := PrivilegedCommand(server, "id -u"); got != "sudo -S -k -p '' sh -c 'id -u'" {
+		t.Errorf("unexpected local privileged command %q", got)
+	}
+
+	server.Local = false
+	if got := PrivilegedCommand(server, "id -u"); got != "sudo sh -c 'id -u'" {
+		t.Errorf("unexpected privileged command %q", got)
+	}
+}
+
+func TestLocalUploadWithSudoPassword(t *testing.T) {
+	// The user only decides whether commands escalate, local commands run as the current user.
+	server := &servers.Server{Name: "localhost", Address: "localhost", Port: 22, Local: true, User: "deploy", SudoPassword: "hunter2"}
+
+	service := &SSHService{}
+	ctx := context.Background()
+
+	if err := service.OpenConnection(ctx, server); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "uploaded file")
+	if _, err := service.Upload(ctx, server, UploadCommand(path, false), strings.NewReader("payload")); err != nil {
+		t.Fatal(err)
+	}
+	if written, err := os.ReadFile(path); err != nil || string(written) != "payload" {
+		t.Fatalf("unexpected uploaded content %q, %v", written, err)
+	}
+
+	// Commands that do not escalate do not get the password either.
+	result, err := service.ExecuteCommand(ctx, "cat", server)
+	if err != nil || result.Stdout != "" {
+		t.Fatalf("unexpected standard input %q, %v", result.Stdout, err)
+	}
+}
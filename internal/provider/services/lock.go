@@ -0,0 +1,19 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+// LockConflictExitCode is the exit code of a LockedCommand that could not acquire its lock in time.
+const LockConflictExitCode = 75
+
+// LockedCommand returns a command running command while holding an exclusive flock(1) advisory
+// lock on lockFile, waiting up to timeout for other holders such as another Terraform run or a
+// configuration management agent using the same lock file. The lock file is created if missing.
+func LockedCommand(lockFile string, timeout time.Duration, command string) string {
+	return fmt.Sprintf(
+		"flock -x -w %d -E %d %s sh -c %s",
+		int(timeout.Seconds()), LockConflictExitCode, ShellQuote(lockFile), ShellQuote(command),
+	)
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockedCommand(t *testing.T) {
+	want := `flock -x -w 30 -E 75 '/run/lock/nginx.lock' sh -c 'echo ok > /etc/nginx/nginx.conf'`
+	if got := LockedCommand("/run/lock/nginx.lock", 30*time.Second, "echo ok > /etc/nginx/nginx.conf"); got != want {
+		t.Fatalf("LockedCommand() = %s, want %s", got, want)
+	}
+
+	if _, err := exec.LookPath("flock"); err != nil {
+		t.Skip("flock is not installed")
+	}
+
+	lockFile := filepath.Join(t.TempDir(), "file.lock")
+	holder := exec.Command("sh", "-c", LockedCommand(lockFile, time.Second, "sleep 1"))
+	if err := holder.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = holder.Wait() }()
+
+	// Leave the holder time to acquire the lock.
+	time.Sleep(200 * time.Millisecond)
+
+	err := exec.Command("sh", "-c", LockedCommand(lockFile, 0, "true")).Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != LockConflictExitCode {
+		t.Fatalf("expected the conflict exit code, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// loginsMarker prefixes the lines of LoginsCommand announcing the output of each tool.
+const loginsMarker = "remote-host-logins"
+
+// lastlogTimeLayout is the layout of the times printed by lastlog, once its fields are joined
+// with single spaces.
+const lastlogTimeLayout = "Mon Jan 2 15:04:05 -0700 2006"
+
+// Login is a login session of a user, or a failed attempt when read from btmp.
+type Login struct {
+	User     string
+	Terminal string
+	// Host is the remote host the user logged in from, empty for local logins.
+	Host string
+	Time time.Time
+}
+
+// LoginRecords are the login records of a host.
+type LoginRecords struct {
+	// Recent are the most recent logins recorded in wtmp, most recent first.
+	Recent []Login
+	// Last is the last login of every user that ever logged in, as recorded in lastlog.
+	Last []Login
+	// Failed are the failed login attempts recorded in btmp, most recent first.
+	Failed []Login
+}
+
+// LoginsCommand returns a command printing the limit most recent logins with last, the last
+// login of every user with lastlog and the failed attempts with lastb. Tools that are missing or
+// not permitted, lastb needs root, print nothing.
+func LoginsCommand(limit int64) string {
+	return strings.Join([]string{
+		fmt.Sprintf("echo '%s last'", loginsMarker),
+		fmt.Sprintf("last -w --time-format iso -n %d 2>/dev/null", limit),
+		fmt.Sprintf("echo '%s lastlog'", loginsMarker),
+		"lastlog 2>/dev/null",
+		fmt.Sprintf("echo '%s lastb'", loginsMarker),
+		"lastb -w --time-format iso 2>/dev/null",
+		"true",
+	}, "; ")
+}
+
+// parseLastLine parses a session printed by last or lastb with ISO times, e.g.
+// "alice pts/0 10.0.0.1 2024-05-01T10:00:00+00:00 - 2024-05-01T11:00:00+00:00 (01:00)". Reboots,
+// shutdowns and the trailing "wtmp begins" line are not logins.
+func parseLastLine(line string) (Login, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[1] == "begins" {
+		return Login{}, false
+	}
+	switch fields[0] {
+	case "reboot", "shutdown", "runlevel":
+		return Login{}, false
+	}
+
+	// The host column is blank for local logins, so the first time locates the columns.
+	for i := 1; i < len(fields) && i <= 3; i++ {
+		start, err := time.Parse(time.RFC3339, fields[i])
+		if err != nil {
+			continue
+		}
+
+		login := Login{User: fields[0], Time: start}
+		if i > 1 {
+			login.Terminal = fields[1]
+		}
+		if i > 2 {
+			login.Host = fields[2]
+		}
+
+		return login, true
+	}
+
+	return Login{}, false
+}
+
+// parseLastlogLine parses a line of lastlog, e.g.
+// "alice pts/0 10.0.0.1 Wed May 1 10:00:00 +0000 2024". The header and the users that never
+// logged in are skipped.
+func parseLastlogLine(line string) (Login, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 7 || fields[0] == "Username" {
+		return Login{}, false
+	}
+
+	timeStart := len(fields) - 6
+	at, err := time.Parse(lastlogTimeLayout, strings.Join(fields[timeStart:], " "))
+	if err != nil {
+		return Login{}, false
+	}
+
+	login := Login{User: fields[0], Time: at}
+	if timeStart > 1 {
+		login.Terminal = fields[1]
+	}
+	if timeStart > 2 {
+		login.Host = fields[2]
+	}
+
+	return login, true
+}
+
+// ParseLogins parses the output of LoginsCommand. Lines printed before the first marker, e.g. a
+// login banner, and lines that are not records are ignored.
+func ParseLogins(output string) LoginRecords {
+	var records LoginRecords
+
+	tool := ""
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		if name, found := strings.CutPrefix(strings.TrimSpace(line), loginsMarker+" "); found {
+			tool = name
+			continue
+		}
+
+		switch tool {
+		case "last":
+			if login, ok := parseLastLine(line); ok {
+				records.Recent = append(records.Recent, login)
+			}
+		case "lastlog":
+			if login, ok := parseLastlogLine(line); ok {
+				records.Last = append(records.Last, login)
+			}
+		case "lastb":
+			if login, ok := parseLastLine(line); ok {
+				records.Failed = append(records.Failed, login)
+			}
+		}
+	}
+
+	return records
+}
+
+// FailedCounts counts the failed attempts of records made at or after since, by user and by
+// remote host. Local attempts are counted under an empty host.
+func (records LoginRecords) FailedCounts(since time.Time) (byUser map[string]int64, byHost map[string]int64) {
+	byUser = map[string]int64{}
+	byHost = map[string]int64{}
+	for _, login := range records.Failed {
+		if login.Time.Before(since) {
+			continue
+		}
+		byUser[login.User]++
+		byHost[login.Host]++
+	}
+
+	return byUser, byHost
+}
+
+// SortLogins orders logins from the most recent to the oldest, by user for the same time.
+func SortLogins(logins []Login) {
+	sort.SliceStable(logins, func(i, j int) bool {
+		if !logins[i].Time.Equal(logins[j].Time) {
+			return logins[i].Time.After(logins[j].Time)
+		}
+		return logins[i].User < logins[j].User
+	})
+}
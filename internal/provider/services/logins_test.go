@@ -0,0 +1,83 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLogins(t *testing.T) {
+	output := "Welcome!\n" +
+		loginsMarker + " last\n" +
+		"alice    pts/0        10.0.0.1         2024-05-02T10:00:00+00:00   still logged in\n" +
+		"bob      tty1                          2024-05-01T09:00:00+00:00 - 2024-05-01T09:30:00+00:00  (00:30)\n" +
+		"reboot   system boot  6.1.0-18-amd64   2024-05-01T08:00:00+00:00   still running\n" +
+		"\n" +
+		"wtmp begins 2024-04-01T00:00:00+00:00\n" +
+		loginsMarker + " lastlog\n" +
+		"Username         Port     From             Latest\n" +
+		"root                                       **Never logged in**\n" +
+		"alice            pts/0    10.0.0.1         Thu May  2 10:00:00 +0000 2024\n" +
+		"bob              tty1                      Wed May  1 09:00:00 +0000 2024\n" +
+		loginsMarker + " lastb\n" +
+		"root     ssh:notty    203.0.113.7      2024-05-02T03:00:00+00:00 - 2024-05-02T03:00:00+00:00  (00:00)\n" +
+		"root     ssh:notty    203.0.113.7      2024-05-02T02:00:00+00:00 - 2024-05-02T02:00:00+00:00  (00:00)\n" +
+		"admin    ssh:notty    198.51.100.2     2024-04-20T02:00:00+00:00 - 2024-04-20T02:00:00+00:00  (00:00)\n" +
+		"\n" +
+		"btmp begins 2024-04-01T00:00:00+00:00\n"
+
+	records := ParseLogins(output)
+
+	expectedRecent := []Login{
+		{User: "alice", Terminal: "pts/0", Host: "10.0.0.1", Time: time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)},
+		{User: "bob", Terminal: "tty1", Time: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)},
+	}
+	if len(records.Recent) != len(expectedRecent) {
+		t.Fatalf("expected %d recent logins, got %+v", len(expectedRecent), records.Recent)
+	}
+	for i, expected := range expectedRecent {
+		actual := records.Recent[i]
+		if actual.User != expected.User || actual.Terminal != expected.Terminal || actual.Host != expected.Host || !actual.Time.Equal(expected.Time) {
+			t.Errorf("recent %d: expected %+v, got %+v", i, expected, actual)
+		}
+	}
+
+	if len(records.Last) != 2 {
+		t.Fatalf("expected 2 last logins, got %+v", records.Last)
+	}
+	if records.Last[0].User != "alice" || records.Last[0].Host != "10.0.0.1" || !records.Last[0].Time.Equal(expectedRecent[0].Time) {
+		t.Errorf("unexpected last login %+v", records.Last[0])
+	}
+	if records.Last[1].User != "bob" || records.Last[1].Terminal != "tty1" || records.Last[1].Host != "" {
+		t.Errorf("unexpected last login %+v", records.Last[1])
+	}
+
+	if len(records.Failed) != 3 {
+		t.Fatalf("expected 3 failed logins, got %+v", records.Failed)
+	}
+
+	byUser, byHost := records.FailedCounts(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	if len(byUser) != 1 || byUser["root"] != 2 {
+		t.Errorf("unexpected failed counts by user %v", byUser)
+	}
+	if len(byHost) != 1 || byHost["203.0.113.7"] != 2 {
+		t.Errorf("unexpected failed counts by host %v", byHost)
+	}
+}
+
+func TestParseLoginsWithoutTools(t *testing.T) {
+	records := ParseLogins(loginsMarker + " last\n" + loginsMarker + " lastlog\n" + loginsMarker + " lastb\n")
+	if len(records.Recent) != 0 || len(records.Last) != 0 || len(records.Failed) != 0 {
+		t.Errorf("expected no records, got %+v", records)
+	}
+}
+
+func TestSortLogins(t *testing.T) {
+	at := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	logins := []Login{{User: "carol", Time: at}, {User: "bob", Time: at.Add(time.Hour)}, {User: "alice", Time: at}}
+
+	SortLogins(logins)
+
+	if logins[0].User != "bob" || logins[1].User != "alice" || logins[2].User != "carol" {
+		t.Errorf("unexpected order %+v", logins)
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"strings"
+)
+
+// MACStatusCommand prints the state of the mandatory access control systems of a Linux host as
+// key=value lines. The loaded AppArmor profiles are only readable by root.
+const MACStatusCommand = `if [ -e /sys/fs/selinux/enforce ]; then ` +
+	`case $(cat /sys/fs/selinux/enforce) in 1) echo selinux_mode=enforcing;; *) echo selinux_mode=permissive;; esac; ` +
+	`elif command -v getenforce >/dev/null 2>&1; then echo "selinux_mode=$(getenforce | tr A-Z a-z)"; fi; ` +
+	`[ -r /etc/selinux/config ] && sed -n 's/^SELINUXTYPE=/selinux_policy=/p' /etc/selinux/config; ` +
+	`[ "$(cat /sys/module/apparmor/parameters/enabled 2>/dev/null)" = Y ] && echo apparmor_enabled=Y; ` +
+	`sed 's/^/apparmor_profile=/' /sys/kernel/security/apparmor/profiles 2>/dev/null; ` +
+	`true`
+
+// MACStatus is the state of SELinux and AppArmor on a host.
+type MACStatus struct {
+	// SELinuxMode is enforcing, permissive or disabled, and empty when SELinux is not installed.
+	SELinuxMode   string
+	SELinuxPolicy string
+
+	AppArmorEnabled bool
+	// AppArmorProfiles maps the loaded profiles to their mode, e.g. enforce or complain.
+	AppArmorProfiles map[string]string
+}
+
+// Active returns the access control system in effect: selinux, apparmor or none.
+func (status MACStatus) Active() string {
+	switch {
+	case status.SELinuxMode == "enforcing" || status.SELinuxMode == "permissive":
+		return "selinux"
+	case status.AppArmorEnabled:
+		return "apparmor"
+	}
+
+	return "none"
+}
+
+// ParseMACStatus parses the output of MACStatusCommand, ignoring unrelated lines.
+func ParseMACStatus(output string) MACStatus {
+	status := MACStatus{AppArmorProfiles: map[string]string{}}
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "selinux_mode":
+			status.SELinuxMode = value
+		case "selinux_policy":
+			status.SELinuxPolicy = strings.Trim(value, `"`)
+		case "apparmor_enabled":
+			status.AppArmorEnabled = true
+		case "apparmor_profile":
+			// Profiles are listed as "name (mode)", names may contain spaces.
+			separator := strings.LastIndex(value, " (")
+			if separator < 0 {
+				continue
+			}
+			status.AppArmorProfiles[value[:separator]] = strings.TrimSuffix(value[separator+2:], ")")
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseMACStatus(t *testing.T) {
+	status := ParseMACStatus("selinux_mode=enforcing\r\nselinux_policy=targeted\r\n")
+	if status.Active() != "selinux" || status.SELinuxPolicy != "targeted" || len(status.AppArmorProfiles) != 0 {
+		t.Errorf("unexpected SELinux status %+v", status)
+	}
+
+	status = ParseMACStatus("Welcome\napparmor_enabled=Y\n" +
+		"apparmor_profile=/usr/sbin/cupsd (enforce)\n" +
+		"apparmor_profile=/snap/bin/my app (complain)\n")
+	want := map[string]string{"/usr/sbin/cupsd": "enforce", "/snap/bin/my app": "complain"}
+	if status.Active() != "apparmor" || !maps.Equal(status.AppArmorProfiles, want) {
+		t.Errorf("unexpected AppArmor status %+v", status)
+	}
+
+	if status := ParseMACStatus("selinux_mode=disabled\n"); status.Active() != "none" {
+		t.Errorf("unexpected disabled status %+v", status)
+	}
+}
@@ -0,0 +1,24 @@
+package services
+
+import (
+	"context"
+	"remote-provider/internal/provider/servers"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Measure logs the duration and size of the SSH operation on server that started at start,
+// one of "dial", "session", "command" or "transfer".
+func (service *SSHService) Measure(ctx context.Context, operation string, server *servers.Server, start time.Time, bytes int, err error) {
+	fields := map[string]any{
+		"host":        server.Name,
+		"duration_ms": time.Since(start).Milliseconds(),
+		"bytes":       bytes,
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+
+	tflog.Debug(ctx, "ssh "+operation, fields)
+}
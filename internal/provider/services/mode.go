@@ -0,0 +1,108 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// modeClasses are the classes of a symbolic mode with the shift of their permission bits.
+var modeClasses = []struct {
+	class byte
+	shift uint
+	// special is the setuid, setgid or sticky bit written as s or t for the class.
+	special uint64
+}{
+	{class: 'u', shift: 6, special: 0o4000},
+	{class: 'g', shift: 3, special: 0o2000},
+	{class: 'o', shift: 0, special: 0o1000},
+}
+
+// SymbolicToOctal converts an absolute symbolic mode such as "u=rw,g=r,o=" to a four digit
+// octal mode such as "0640". Classes may be combined ("ug=rw") or be "a" for all of them,
+// "s" sets the setuid or setgid bit and "t" the sticky bit. Classes not listed get no permissions.
+func SymbolicToOctal(symbolic string) (string, error) {
+	var mode uint64
+	seen := map[byte]bool{}
+
+	for _, clause := range strings.Split(symbolic, ",") {
+		classes, permissions, ok := strings.Cut(clause, "=")
+		if !ok || classes == "" {
+			return "", fmt.Errorf("invalid clause %q in symbolic mode %q, expected a form such as u=rw", clause, symbolic)
+		}
+		if classes == "a" {
+			classes = "ugo"
+		}
+
+		for i := range len(classes) {
+			index := strings.IndexByte("ugo", classes[i])
+			if index < 0 {
+				return "", fmt.Errorf("invalid class %q in symbolic mode %q, expected u, g, o or a", classes[i], symbolic)
+			}
+			if seen[classes[i]] {
+				return "", fmt.Errorf("class %q is set twice in symbolic mode %q", classes[i], symbolic)
+			}
+			seen[classes[i]] = true
+
+			class := modeClasses[index]
+			for j := range len(permissions) {
+				switch permissions[j] {
+				case 'r':
+					mode |= 4 << class.shift
+				case 'w':
+					mode |= 2 << class.shift
+				case 'x':
+					mode |= 1 << class.shift
+				case 's':
+					if class.class == 'o' {
+						return "", fmt.Errorf("s is only valid for u and g in symbolic mode %q", symbolic)
+					}
+					mode |= class.special
+				case 't':
+					if class.class != 'o' && classes != "ugo" {
+						return "", fmt.Errorf("t is only valid for o in symbolic mode %q", symbolic)
+					}
+					mode |= 0o1000
+				default:
+					return "", fmt.Errorf("invalid permission %q in symbolic mode %q, expected r, w, x, s or t", permissions[j], symbolic)
+				}
+			}
+		}
+	}
+
+	return fmt.Sprintf("%04o", mode), nil
+}
+
+// OctalToSymbolic converts an octal mode such as "640" or "0640" to its symbolic form "u=rw,g=r,o=".
+func OctalToSymbolic(octal string) (string, error) {
+	mode, err := strconv.ParseUint(octal, 8, 64)
+	if err != nil || len(octal) < 3 || len(octal) > 4 {
+		return "", fmt.Errorf("invalid octal mode %q, expected a form such as 0644", octal)
+	}
+
+	clauses := make([]string, 0, len(modeClasses))
+	for _, class := range modeClasses {
+		bits := mode >> class.shift & 7
+		permissions := ""
+		if bits&4 != 0 {
+			permissions += "r"
+		}
+		if bits&2 != 0 {
+			permissions += "w"
+		}
+		if bits&1 != 0 {
+			permissions += "x"
+		}
+		if mode&class.special != 0 {
+			if class.class == 'o' {
+				permissions += "t"
+			} else {
+				permissions += "s"
+			}
+		}
+
+		clauses = append(clauses, string(class.class)+"="+permissions)
+	}
+
+	return strings.Join(clauses, ","), nil
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSymbolicToOctal(t *testing.T) {
+	valid := map[string]string{
+		"u=rw,g=r,o=":       "0640",
+		"a=r":               "0444",
+		"ug=rwx,o=rx":       "0775",
+		"u=rwxs,g=rx,o=":    "4750",
+		"u=rwx,g=rxs,o=rxt": "3755",
+		"u=,g=,o=":          "0000",
+		"u=rw":              "0600",
+	}
+	for symbolic, want := range valid {
+		got, err := SymbolicToOctal(symbolic)
+		if err != nil || got != want {
+			t.Errorf("%q: got %q, %v, want %q", symbolic, got, err, want)
+		}
+	}
+
+	for _, symbolic := range []string{"", "u+rw", "u=rw,u=r", "x=r", "u=rwz", "o=s", "u=t", "u=rw,a=r"} {
+		if got, err := SymbolicToOctal(symbolic); err == nil {
+			t.Errorf("%q: expected an error, got %q", symbolic, got)
+		}
+	}
+}
+
+func TestOctalToSymbolic(t *testing.T) {
+	valid := map[string]string{
+		"0640": "u=rw,g=r,o=",
+		"755":  "u=rwx,g=rx,o=rx",
+		"4750": "u=rwxs,g=rx,o=",
+		"1777": "u=rwx,g=rwx,o=rwxt",
+		"0000": "u=,g=,o=",
+	}
+	for octal, want := range valid {
+		got, err := OctalToSymbolic(octal)
+		if err != nil || got != want {
+			t.Errorf("%q: got %q, %v, want %q", octal, got, err, want)
+		}
+
+		// Converting back yields the four digit form.
+		back, err := SymbolicToOctal(got)
+		if err != nil || strings.TrimLeft(back, "0") != strings.TrimLeft(octal, "0") {
+			t.Errorf("%q: round trip gave %q, %v", octal, back, err)
+		}
+	}
+
+	for _, octal := range []string{"", "64", "0800", "10644", "rw"} {
+		if got, err := OctalToSymbolic(octal); err == nil {
+			t.Errorf("%q: expected an error, got %q", octal, got)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FileOwnership is the octal mode, owner and group of a file. Empty fields are left unchanged.
+type FileOwnership struct {
+	Mode  string
+	Owner string
+	Group string
+}
+
+// ParseOwnership reads the output of Platform.OwnershipCommand. The mode is returned with four
+// octal digits such as "0644". Lines printed before the ownership, e.g. a login banner, are ignored.
+func ParseOwnership(output string) (FileOwnership, error) {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(output, "\r\n", "\n")), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) != 3 {
+		return FileOwnership{}, fmt.Errorf("unexpected ownership output %q", output)
+	}
+
+	mode, err := NormalizeMode(fields[0])
+	if err != nil {
+		// BusyBox prints ls -l permissions such as "-rw-r-----".
+		mode, err = permissionsToOctal(fields[0])
+		if err != nil {
+			return FileOwnership{}, fmt.Errorf("unexpected mode in ownership output %q", output)
+		}
+	}
+
+	return FileOwnership{Mode: mode, Owner: fields[1], Group: fields[2]}, nil
+}
+
+// NormalizeMode returns the octal mode with four digits, so "644" and "0644" compare equal.
+func NormalizeMode(mode string) (string, error) {
+	value, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || value > 07777 {
+		return "", fmt.Errorf("invalid octal mode %q", mode)
+	}
+
+	return fmt.Sprintf("%04o", value), nil
+}
+
+// ChangeOwnershipCommand returns a command applying the non-empty fields of ownership to path,
+// or an empty string when there is nothing to change. Without follow the owner and group of a
+// symlink are the ones of the link itself and its mode, which cannot be changed, is left alone.
+func ChangeOwnershipCommand(path string, ownership FileOwnership, follow bool) string {
+	quotedPath := ShellQuote(path)
+
+	var commands []string
+	if ownership.Owner != "" || ownership.Group != "" {
+		owner := ownership.Owner
+		if ownership.Group != "" {
+			owner += ":" + ownership.Group
+		}
+
+		chown := "chown"
+		if !follow {
+			chown += " -h"
+		}
+		commands = append(commands, fmt.Sprintf("%s %s -- %s", chown, ShellQuote(owner), quotedPath))
+	}
+
+	if ownership.Mode != "" {
+		chmod := fmt.Sprintf("chmod %s -- %s", ownership.Mode, quotedPath)
+		if !follow {
+			chmod = fmt.Sprintf("if [ ! -L %s ]; then %s; fi", quotedPath, chmod)
+		}
+		commands = append(commands, chmod)
+	}
+
+	return strings.Join(commands, " && ")
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseOwnership(t *testing.T) {
+	cases := map[string]FileOwnership{
+		"644 root root\n":                    {Mode: "0644", Owner: "root", Group: "root"},
+		"Welcome!\r\n4755 alice staff\r\n":   {Mode: "4755", Owner: "alice", Group: "staff"},
+		"-rw-r----- root adm\n":              {Mode: "0640", Owner: "root", Group: "adm"},
+		"Welcome!\n-rwsr-xr-x alice users\n": {Mode: "4755", Owner: "alice", Group: "users"},
+		"0600 www-data www-data":             {Mode: "0600", Owner: "www-data", Group: "www-data"},
+	}
+
+	for output, expected := range cases {
+		actual, err := ParseOwnership(output)
+		if err != nil {
+			t.Fatalf("%q: %s", output, err)
+		}
+
+		if actual != expected {
+			t.Errorf("%q: expected %+v, got %+v", output, expected, actual)
+		}
+	}
+
+	for _, output := range []string{"", "644 root", "rw root root"} {
+		if _, err := ParseOwnership(output); err == nil {
+			t.Errorf("%q: expected an error", output)
+		}
+	}
+}
+
+func TestParseOwnershipOfStat(t *testing.T) {
+	if _, err := exec.LookPath("stat"); err != nil {
+		t.Skip("stat is not installed")
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(file, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := exec.Command("sh", "-c", Platform{OS: "linux"}.OwnershipCommand(ShellQuote(file), true)).Output()
+	if err != nil {
+		t.Skipf("stat does not support formats: %s", err)
+	}
+
+	ownership, err := ParseOwnership(string(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ownership.Mode != "0640" || ownership.Owner == "" || ownership.Group == "" {
+		t.Errorf("unexpected ownership %+v", ownership)
+	}
+}
+
+func TestChangeOwnershipCommand(t *testing.T) {
+	cases := map[string]struct {
+		ownership FileOwnership
+		follow    bool
+		expected  string
+	}{
+		"nothing": {FileOwnership{}, true, ""},
+		"all": {
+			FileOwnership{Mode: "0640", Owner: "root", Group: "adm"}, true,
+			"chown 'root:adm' -- '/etc/app.conf' && chmod 0640 -- '/etc/app.conf'",
+		},
+		"group only": {FileOwnership{Group: "adm"}, true, "chown ':adm' -- '/etc/app.conf'"},
+		"no follow": {
+			FileOwnership{Mode: "0600", Owner: "app"}, false,
+			"chown -h 'app' -- '/etc/app.conf' && if [ ! -L '/etc/app.conf' ]; then chmod 0600 -- '/etc/app.conf'; fi",
+		},
+	}
+
+	for name, c := range cases {
+		actual := ChangeOwnershipCommand("/etc/app.conf", c.ownership, c.follow)
+		if actual != c.expected {
+			t.Errorf("%s: expected %s, got %s", name, c.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	fallbackFileMode      = "0644"
+	fallbackDirectoryMode = "0755"
+)
+
+// FileMode returns mode, or the provider default file mode when mode is empty.
+func (service *SSHService) FileMode(mode string) string {
+	if mode != "" {
+		return mode
+	}
+
+	if service.DefaultFileMode != "" {
+		return service.DefaultFileMode
+	}
+
+	return fallbackFileMode
+}
+
+// DirectoryMode returns mode, or the provider default directory mode when mode is empty.
+func (service *SSHService) DirectoryMode(mode string) string {
+	if mode != "" {
+		return mode
+	}
+
+	if service.DefaultDirectoryMode != "" {
+		return service.DefaultDirectoryMode
+	}
+
+	return fallbackDirectoryMode
+}
+
+// withUmask prefixes command with the provider umask so every file it creates honors the policy.
+func (service *SSHService) withUmask(command string) string {
+	if service.Umask == "" {
+		return command
+	}
+
+	return "umask " + service.Umask + "; " + command
+}
+
+// PermissionsCommand returns a command printing the ls -l permissions of path, such as
+// "-rw-r-----". Symlinks are followed, the permissions are the ones of their target.
+func PermissionsCommand(path string) string {
+	return fmt.Sprintf("ls -ldL -- %s | awk '{print $1}'", ShellQuote(path))
+}
+
+// ReadableBeyondOwner lists who besides the owner of a file can read it given its ls -l
+// permissions and its named ACL entries as returned by ParseGetfacl: "group", "others" and the
+// readable entries such as "user:alice". With an ACL the group bits are its mask, which caps
+// the named entries.
+func ReadableBeyondOwner(permissions string, acl []string) ([]string, error) {
+	permissions = strings.TrimSpace(permissions)
+	if len(permissions) < 10 {
+		return nil, fmt.Errorf("unexpected permissions %q", permissions)
+	}
+
+	var readers []string
+	groupReadable := permissions[4] == 'r'
+	if groupReadable {
+		readers = append(readers, "group")
+	}
+	if permissions[7] == 'r' {
+		readers = append(readers, "others")
+	}
+
+	for _, entry := range acl {
+		if strings.HasPrefix(entry, "default:") || !groupReadable {
+			continue
+		}
+
+		principal := entry[:strings.LastIndex(entry, ":")]
+		if strings.HasPrefix(entry[len(principal)+1:], "r") {
+			readers = append(readers, principal)
+		}
+	}
+
+	return readers, nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadableBeyondOwner(t *testing.T) {
+	for _, test := range []struct {
+		permissions string
+		acl         []string
+		want        []string
+	}{
+		{"-rw-------", nil, nil},
+		{"-r--------.", nil, nil},
+		{"-rw-r-----", nil, []string{"group"}},
+		{"-rw-r--r--", nil, []string{"group", "others"}},
+		{"-rw----r--", nil, []string{"others"}},
+		{"-rw-r-----+", []string{"group:ops:r--", "user:alice:rw-", "user:bob:-w-"}, []string{"group", "group:ops", "user:alice"}},
+		// A mask without read caps the named entries.
+		{"-rw--w----+", []string{"user:alice:rw-"}, nil},
+		{"-rw-------+", []string{"default:user:alice:r--"}, nil},
+	} {
+		got, err := ReadableBeyondOwner(test.permissions, test.acl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("ReadableBeyondOwner(%q, %q) = %q, want %q", test.permissions, test.acl, got, test.want)
+		}
+	}
+
+	if _, err := ReadableBeyondOwner("", nil); err == nil {
+		t.Error("expected an error for empty permissions")
+	}
+}
+
+func TestPermissionsCommand(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "db's.key")
+	if err := os.WriteFile(secret, []byte("s3cret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(secret, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(secret, link); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := exec.Command("sh", "-c", PermissionsCommand(link)).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.TrimSpace(string(output)); !strings.HasPrefix(got, "-rw-r-----") {
+		t.Errorf("expected the permissions of the link target, got %q", got)
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"remote-provider/internal/provider/servers"
+	"strings"
+)
+
+// Platform describes the userland of a host, which decides the flags the provider can use.
+type Platform struct {
+	// OS is the lower case kernel name printed by uname -s, e.g. "linux" or "darwin".
+	OS string
+	// BusyBox is set when the core utilities are BusyBox applets, as on Alpine and most
+	// embedded systems, which lack several GNU options such as stat -c.
+	BusyBox bool
+}
+
+// detectPlatformCommand prints the kernel name and the resolved path of stat.
+const detectPlatformCommand = `uname -s; readlink -f "$(command -v stat)" 2>/dev/null || command -v stat || echo none`
+
+// DetectPlatform returns the platform of server, detected once per connection and provider run.
+func (service *SSHService) DetectPlatform(ctx context.Context, server *servers.Server) (Platform, error) {
+	key := connectionKey(server)
+
+	service.mutex.Lock()
+	platform, ok := service.platforms[key]
+	service.mutex.Unlock()
+
+	if ok {
+		return platform, nil
+	}
+
+	err := service.OpenConnection(ctx, server)
+	if err != nil {
+		return Platform{}, err
+	}
+
+	result, err := service.ExecuteCommand(ctx, detectPlatformCommand, server)
+	if err != nil {
+		return Platform{}, fmt.Errorf("detecting the platform of %s: %w", server.Name, err)
+	}
+
+	platform, err = parsePlatform(result.Stdout)
+	if err != nil {
+		return Platform{}, fmt.Errorf("detecting the platform of %s: %w", server.Name, err)
+	}
+
+	service.mutex.Lock()
+	if service.platforms == nil {
+		service.platforms = map[string]Platform{}
+	}
+	service.platforms[key] = platform
+	service.mutex.Unlock()
+
+	return platform, nil
+}
+
+func parsePlatform(output string) (Platform, error) {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) < 2 {
+		return Platform{}, fmt.Errorf("unexpected output %q", output)
+	}
+
+	// Banners may precede the output when framing is disabled, so read it from the end.
+	return Platform{
+		OS:      strings.ToLower(lines[len(lines)-2]),
+		BusyBox: path.Base(lines[len(lines)-1]) == "busybox",
+	}, nil
+}
+
+// InodeCommand returns a command printing the inode number of the quoted path,
+// dereferencing symlinks when follow is set.
+func (p Platform) InodeCommand(quotedPath string, follow bool) string {
+	if p.BusyBox {
+		// BusyBox stat is often built without format support, ls -i is always available.
+		flags := "-di"
+		if follow {
+			flags += "L"
+		}
+
+		return fmt.Sprintf("ls %s -- %s | awk '{print $1}'", flags, quotedPath)
+	}
+
+	flags := ""
+	if follow {
+		flags = "-L "
+	}
+
+	if p.OS == "darwin" {
+		return fmt.Sprintf("stat %s-f '%%i' -- %s", flags, quotedPath)
+	}
+
+	return fmt.Sprintf("stat %s-c '%%i' -- %s", flags, quotedPath)
+}
+
+// MtimeCommand returns a command printing the modification time of the quoted path as a Unix
+// timestamp, dereferencing symlinks when follow is set.
+func (p Platform) MtimeCommand(quotedPath string, follow bool) string {
+	if p.BusyBox {
+		// BusyBox date always dereferences symlinks.
+		return fmt.Sprintf("date -r %s +%%s", quotedPath)
+	}
+
+	flags := ""
+	if follow {
+		flags = "-L "
+	}
+
+	if p.OS == "darwin" {
+		return fmt.Sprintf("stat %s-f '%%m' -- %s", flags, quotedPath)
+	}
+
+	return fmt.Sprintf("stat %s-c '%%Y' -- %s", flags, quotedPath)
+}
+
+// OwnershipCommand returns a command printing the mode, owner and group of the quoted path on
+// one line, read by ParseOwnership, dereferencing symlinks when follow is set.
+func (p Platform) OwnershipCommand(quotedPath string, follow bool) string {
+	if p.BusyBox {
+		// The ls -l permissions are converted to an octal mode by ParseOwnership.
+		flags := "-ld"
+		if follow {
+			flags += "L"
+		}
+
+		return fmt.Sprintf("ls %s -- %s | awk '{print $1, $3, $4}'", flags, quotedPath)
+	}
+
+	flags := ""
+	if follow {
+		flags = "-L "
+	}
+
+	if p.OS == "darwin" {
+		return fmt.Sprintf("stat %s-f '%%Lp %%Su %%Sg' -- %s", flags, quotedPath)
+	}
+
+	return fmt.Sprintf("stat %s-c '%%a %%U %%G' -- %s", flags, quotedPath)
+}
+
+// InodeTools returns the tools needed by InodeCommand, MtimeCommand and OwnershipCommand.
+func (p Platform) InodeTools() []string {
+	if p.BusyBox {
+		return []string{"ls", "awk", "date"}
+	}
+
+	return []string{"stat"}
+}
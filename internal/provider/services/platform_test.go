@@ -0,0 +1,126 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePlatform(t *testing.T) {
+	cases := map[string]Platform{
+		"Linux\n/usr/bin/stat\n":                      {OS: "linux"},
+		"Welcome to Alpine!\r\nLinux\r\n/bin/busybox": {OS: "linux", BusyBox: true},
+	}
+
+	for output, expected := range cases {
+		actual, err := parsePlatform(output)
+		if err != nil {
+			t.Fatalf("%q: %s", output, err)
+		}
+
+		if actual != expected {
+			t.Errorf("%q: expected %+v, got %+v", output, expected, actual)
+		}
+	}
+
+	if _, err := parsePlatform("Linux"); err == nil {
+		t.Fatalf("expected an error for truncated output")
+	}
+}
+
+func TestInodeCommand(t *testing.T) {
+	cases := map[string]struct {
+		platform Platform
+		follow   bool
+		expected string
+	}{
+		"gnu":              {Platform{OS: "linux"}, true, "stat -L -c '%i' -- '/etc/hosts'"},
+		"darwin":           {Platform{OS: "darwin"}, true, "stat -L -f '%i' -- '/etc/hosts'"},
+		"gnu no follow":    {Platform{OS: "linux"}, false, "stat -c '%i' -- '/etc/hosts'"},
+		"busybox":          {Platform{OS: "linux", BusyBox: true}, true, "ls -diL -- '/etc/hosts' | awk '{print $1}'"},
+		"busybox nofollow": {Platform{OS: "linux", BusyBox: true}, false, "ls -di -- '/etc/hosts' | awk '{print $1}'"},
+	}
+
+	for name, c := range cases {
+		actual := c.platform.InodeCommand(ShellQuote("/etc/hosts"), c.follow)
+		if actual != c.expected {
+			t.Errorf("%s: expected %s, got %s", name, c.expected, actual)
+		}
+	}
+}
+
+func TestMtimeCommand(t *testing.T) {
+	cases := map[string]struct {
+		platform Platform
+		follow   bool
+		expected string
+	}{
+		"gnu":     {Platform{OS: "linux"}, true, "stat -L -c '%Y' -- '/etc/hosts'"},
+		"darwin":  {Platform{OS: "darwin"}, false, "stat -f '%m' -- '/etc/hosts'"},
+		"busybox": {Platform{OS: "linux", BusyBox: true}, false, "date -r '/etc/hosts' +%s"},
+	}
+
+	for name, c := range cases {
+		actual := c.platform.MtimeCommand(ShellQuote("/etc/hosts"), c.follow)
+		if actual != c.expected {
+			t.Errorf("%s: expected %s, got %s", name, c.expected, actual)
+		}
+	}
+}
+
+func TestDaemonCommands(t *testing.T) {
+	daemon := Daemon{Name: "node_exporter", User: "node_exporter", Command: []string{"/usr/local/bin/node_exporter"}}
+
+	linux := Platform{OS: "linux"}
+	if path := linux.DaemonDefinitionPath(daemon); path != "/etc/systemd/system/node_exporter.service" {
+		t.Errorf("unexpected unit path %s", path)
+	}
+
+	darwin := Platform{OS: "darwin"}
+	if path := darwin.DaemonDefinitionPath(daemon); path != "/Library/LaunchDaemons/com.remote-host.node_exporter.plist" {
+		t.Errorf("unexpected property list path %s", path)
+	}
+
+	withArguments := daemon
+	withArguments.Description = "Prometheus Node Exporter"
+	withArguments.Command = []string{"/usr/local/bin/node_exporter", "--collector.textfile.directory=/var/lib/node exporter", `--web.config="100%"`, "$HOME\\x"}
+	unit, err := linux.DaemonDefinition(withArguments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedStart := `ExecStart="/usr/local/bin/node_exporter" "--collector.textfile.directory=/var/lib/node exporter" "--web.config=\"100%%\"" "$$HOME\\x"` + "\n"
+	if !strings.Contains(unit, expectedStart) {
+		t.Errorf("expected the unit to contain %q, got %s", expectedStart, unit)
+	}
+
+	injected := daemon
+	injected.User = "node_exporter\nExecStartPre=/bin/sh -c 'id'"
+	if _, err := linux.DaemonDefinition(injected); err == nil {
+		t.Error("expected a multi-line user to be rejected")
+	}
+
+	expected := "launchctl bootout system/com.remote-host.node_exporter 2>/dev/null; " +
+		"launchctl bootstrap system '/Library/LaunchDaemons/com.remote-host.node_exporter.plist' && " +
+		"launchctl enable system/com.remote-host.node_exporter"
+	if command := darwin.StartDaemonCommand(daemon); command != expected {
+		t.Errorf("expected %s, got %s", expected, command)
+	}
+}
+
+func TestOwnershipCommand(t *testing.T) {
+	cases := map[string]struct {
+		platform Platform
+		follow   bool
+		expected string
+	}{
+		"gnu":     {Platform{OS: "linux"}, true, "stat -L -c '%a %U %G' -- '/etc/hosts'"},
+		"darwin":  {Platform{OS: "darwin"}, false, "stat -f '%Lp %Su %Sg' -- '/etc/hosts'"},
+		"busybox": {Platform{OS: "linux", BusyBox: true}, true, "ls -ldL -- '/etc/hosts' | awk '{print $1, $3, $4}'"},
+	}
+
+	for name, c := range cases {
+		actual := c.platform.OwnershipCommand(ShellQuote("/etc/hosts"), c.follow)
+		if actual != c.expected {
+			t.Errorf("%s: expected %s, got %s", name, c.expected, actual)
+		}
+	}
+}
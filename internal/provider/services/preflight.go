@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/servers"
+	"slices"
+	"strings"
+)
+
+// MissingToolsError lists the tools a host lacks to manage a resource.
+type MissingToolsError struct {
+	Host  string
+	Tools []string
+}
+
+func (e *MissingToolsError) Error() string {
+	return fmt.Sprintf("host %s is missing required tools: %s", e.Host, strings.Join(e.Tools, ", "))
+}
+
+// Preflight verifies that server provides every tool in tools before a resource starts
+// changing it. Alternatives are separated by "|", e.g. "curl|wget" is satisfied by either.
+// Lookups are cached per connection, see connectionKey, so each tool is only checked once per
+// provider run and the tools of a container are not mistaken for the ones of its host.
+func (service *SSHService) Preflight(ctx context.Context, server *servers.Server, tools []string) error {
+	tools = privilegeRequirements(server, tools)
+	key := connectionKey(server)
+
+	var unknown []string
+	for _, requirement := range tools {
+		for _, tool := range strings.Split(requirement, "|") {
+			if _, ok := service.cachedTool(key, tool); !ok && !slices.Contains(unknown, tool) {
+				unknown = append(unknown, tool)
+			}
+		}
+	}
+
+	if len(unknown) > 0 {
+		err := service.OpenConnection(ctx, server)
+		if err != nil {
+			return err
+		}
+
+		result, err := service.ExecuteCommand(ctx, lookupToolsCommand(unknown), server)
+		if err != nil {
+			return fmt.Errorf("checking the tools available on %s: %w", server.Name, err)
+		}
+
+		missing := parseMissingTools(result.Stdout)
+
+		service.mutex.Lock()
+		if service.tools == nil {
+			service.tools = map[string]map[string]bool{}
+		}
+		if service.tools[key] == nil {
+			service.tools[key] = map[string]bool{}
+		}
+		for _, tool := range unknown {
+			service.tools[key][tool] = !slices.Contains(missing, tool)
+		}
+		service.mutex.Unlock()
+	}
+
+	var missing []string
+	for _, requirement := range tools {
+		found := slices.ContainsFunc(strings.Split(requirement, "|"), func(tool string) bool {
+			present, _ := service.cachedTool(key, tool)
+			return present
+		})
+
+		name := strings.ReplaceAll(requirement, "|", " or ")
+		if !found && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return &MissingToolsError{Host: server.Name, Tools: missing}
+	}
+
+	return nil
+}
+
+func (service *SSHService) cachedTool(key string, tool string) (present bool, ok bool) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	present, ok = service.tools[key][tool]
+
+	return present, ok
+}
+
+func lookupToolsCommand(tools []string) string {
+	quoted := make([]string, len(tools))
+	for i, tool := range tools {
+		quoted[i] = ShellQuote(tool)
+	}
+
+	return fmt.Sprintf(`for tool in %s; do command -v "$tool" >/dev/null 2>&1 || echo "missing:$tool"; done; true`, strings.Join(quoted, " "))
+}
+
+func parseMissingTools(output string) []string {
+	var missing []string
+	for _, line := range strings.Split(output, "\n") {
+		tool, found := strings.CutPrefix(strings.TrimSpace(line), "missing:")
+		if found && tool != "" {
+			missing = append(missing, tool)
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"remote-provider/internal/provider/servers"
+	"slices"
+	"testing"
+)
+
+func TestParseMissingTools(t *testing.T) {
+	output := "Welcome\r\nmissing:getfacl\r\nmissing:wget\r\n"
+
+	expected := []string{"getfacl", "wget"}
+	actual := parseMissingTools(output)
+	if !slices.Equal(actual, expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestPreflightCached(t *testing.T) {
+	server := &servers.Server{Name: "host"}
+	service := &SSHService{tools: map[string]map[string]bool{
+		connectionKey(server): {"stat": true, "curl": false, "wget": true, "systemctl": false, "apt-get": false, "dnf": false},
+	}}
+
+	err := service.Preflight(t.Context(), server, []string{"stat", "curl|wget"})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	err = service.Preflight(t.Context(), server, []string{"stat", "systemctl", "apt-get|dnf"})
+
+	var missing *MissingToolsError
+	if !errors.As(err, &missing) {
+		t.Fatalf("expected a MissingToolsError, got %v", err)
+	}
+
+	expected := []string{"systemctl", "apt-get or dnf"}
+	if !slices.Equal(missing.Tools, expected) {
+		t.Fatalf("expected %v, got %v", expected, missing.Tools)
+	}
+}
+
+func TestPreflightCachedPerConnection(t *testing.T) {
+	host := &servers.Server{Name: "host", User: "root"}
+	container := &servers.Server{Name: "host", User: "root", Container: "web"}
+	service := &SSHService{
+		Fake: &FakeTransport{Responses: []FakeResponse{
+			{Pattern: regexp.MustCompile(`command -v`), Stdout: "missing:curl\n"},
+		}},
+		tools: map[string]map[string]bool{connectionKey(host): {"curl": true}},
+	}
+
+	if err := service.Preflight(t.Context(), host, []string{"curl"}); err != nil {
+		t.Fatalf("expected the tools of the host to come from the cache, got %v", err)
+	}
+
+	var missing *MissingToolsError
+	if err := service.Preflight(t.Context(), container, []string{"curl"}); !errors.As(err, &missing) {
+		t.Fatalf("expected the container to be checked on its own, got %v", err)
+	}
+}
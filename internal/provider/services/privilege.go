@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"remote-provider/internal/provider/servers"
+)
+
+// PrivilegeTool stands for the privilege escalation tool in the requirements given to Preflight.
+const PrivilegeTool = "sudo"
+
+// BecomeMethods lists the supported privilege escalation tools, the first one is the default.
+// su asks for the password of root rather than the one of the user.
+var BecomeMethods = []string{"sudo", "doas", "su"}
+
+// becomeMethod returns the privilege escalation tool of server.
+func becomeMethod(server *servers.Server) string {
+	if server.BecomeMethod == "" {
+		return BecomeMethods[0]
+	}
+
+	return server.BecomeMethod
+}
+
+// runsAsRoot reports whether the commands of server already run as root, in which case no
+// privilege escalation, PTY or sudo password is needed. Commands run inside a container always
+// run as root in it: docker and podman exec them with -u root, lxc-attach, nsenter and chroot are
+// run as root through the become method, whose refusals privilegeError reports.
+func runsAsRoot(server *servers.Server) bool {
+	return server.User == "root" || server.Container != ""
+}
+
+// PrivilegedCommand wraps command so the whole shell snippet runs as root on server through
+// its become method. Root sessions run it as is, so minimal images without sudo can be managed
+// as root.
+func PrivilegedCommand(server *servers.Server, command string) string {
+	if runsAsRoot(server) {
+		return command
+	}
+
+	return becomePrefix(server) + ShellQuote(command)
+}
+
+// localSudoPrefix starts the privileged commands of local hosts with a sudo password. They have
+// no PTY for sudo to prompt on, the password is read from the first line of their standard input
+// instead. -k makes sudo read it even when credentials are cached.
+const localSudoPrefix = "sudo -S -k -p '' sh -c "
+
+// becomePrefix returns what PrivilegedCommand prepends to the quoted command on server. su
+// runs its single command argument with the shell of root.
+func becomePrefix(server *servers.Server) string {
+	if server.Local && server.SudoPassword != "" && becomeMethod(server) == "sudo" {
+		return localSudoPrefix
+	}
+	if becomeMethod(server) == "su" {
+		return "su root -c "
+	}
+
+	return becomeMethod(server) + " sh -c "
+}
+
+// privilegeRequirements replaces the PrivilegeTool requirement of tools by the become method
+// of server, or drops it for root sessions.
+func privilegeRequirements(server *servers.Server, tools []string) []string {
+	var required []string
+	for _, tool := range tools {
+		switch {
+		case tool != PrivilegeTool:
+			required = append(required, tool)
+		case !runsAsRoot(server):
+			required = append(required, becomeMethod(server))
+		}
+	}
+
+	return required
+}
+
+// privilegeFailure is the reason a host refused to run a privileged command.
+type privilegeFailure int
+
+const (
+	privilegeToolMissing privilegeFailure = iota
+	privilegePasswordRejected
+	privilegeNotPermitted
+)
+
+// privilegeFailures matches the messages sudo, doas and su print when they refuse to run a command,
+// in the order they are checked. The PTY merges them with the output of the command.
+var privilegeFailures = []struct {
+	pattern *regexp.Regexp
+	reason  privilegeFailure
+}{
+	{regexp.MustCompile(`\b(sudo|doas|su): (command )?not found|\b(sudo|doas|su): No such file or directory`), privilegeToolMissing},
+	{regexp.MustCompile(`is not in the sudoers file|is not allowed to (execute|run sudo)|doas: Operation not permitted|su: Permission denied`), privilegeNotPermitted},
+	{regexp.MustCompile(`sudo: (a password is required|no password was provided|a terminal is required|\d+ incorrect password attempts?|no tty present)|doas: (Authentication failed|a password is required)|su: (Authentication failure|Sorry|must be run from a terminal)`), privilegePasswordRejected},
+}
+
+// becomeInvocationRegexp matches the become methods run as commands of a shell snippet, as
+// opposed to appearing in their arguments.
+var becomeInvocationRegexp = regexp.MustCompile(`(?m)(?:^|[;&|(])\s*(sudo|doas|su)\s`)
+
+// invokesBecomeMethod reports whether command runs method.
+func invokesBecomeMethod(command, method string) bool {
+	for _, match := range becomeInvocationRegexp.FindAllStringSubmatch(command, -1) {
+		if match[1] == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PrivilegeError is returned instead of the output of a privileged command when sudo or doas
+// refused to run it, so the user gets told how to fix the host connection rather than the
+// prompts and PTY noise of the failed escalation.
+type PrivilegeError struct {
+	Host   string
+	Method string
+	User   string
+	reason privilegeFailure
+	// passwordSet tells whether a sudo password was sent to the prompt.
+	passwordSet bool
+	// container is set when the method was refused entering the container of the host, which
+	// never prompts for a password.
+	container string
+}
+
+func (e *PrivilegeError) Error() string {
+	var hint string
+	switch {
+	case e.container != "" && e.reason == privilegePasswordRejected:
+		hint = fmt.Sprintf("%s asked for a password to enter container %s, allow %s without a password for user %s or connect as root", e.Method, e.container, e.Method, e.User)
+	case e.reason == privilegeToolMissing:
+		hint = fmt.Sprintf("%s is not installed, install it, connect as root or disable `privileged`", e.Method)
+	case e.reason == privilegeNotPermitted:
+		hint = fmt.Sprintf("user %s may not run commands as root through %s, allow it in the %s configuration, connect as root or disable `privileged`", e.User, e.Method, e.Method)
+	case e.reason == privilegePasswordRejected:
+		if e.passwordSet {
+			hint = fmt.Sprintf("%s rejected the password, check `sudo_password` of the host connection or disable `privileged`", e.Method)
+		} else {
+			hint = fmt.Sprintf("%s asked for a password, set `sudo_password` of the host connection, allow %s without a password for user %s or disable `privileged`", e.Method, e.Method, e.User)
+		}
+	}
+
+	return fmt.Sprintf("privilege escalation failed on %s: %s", e.Host, hint)
+}
+
+// privilegeError returns a *PrivilegeError when the failed command ran through the become
+// method of server, or entered its container through it, and its output shows the escalation
+// itself was refused, nil otherwise.
+func privilegeError(server *servers.Server, result *servers.ServerCommand) error {
+	if result == nil || result.ExitCode == 0 {
+		return nil
+	}
+
+	method := becomeMethod(server)
+	var container string
+	switch {
+	case containerBecomes(server):
+		container = server.Container
+	case runsAsRoot(server) || !invokesBecomeMethod(result.Command, method):
+		return nil
+	}
+
+	output := result.Stdout + "\n" + result.Stderr
+	for _, failure := range privilegeFailures {
+		if failure.pattern.MatchString(output) {
+			return &PrivilegeError{
+				Host:        server.Name,
+				Method:      method,
+				User:        server.User,
+				reason:      failure.reason,
+				passwordSet: server.SudoPassword != "",
+				container:   container,
+			}
+		}
+	}
+
+	return nil
+}
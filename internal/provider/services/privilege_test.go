@@ -0,0 +1,139 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"remote-provider/internal/provider/servers"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPrivilegedCommand(t *testing.T) {
+	root := &servers.Server{User: "root"}
+	deploy := &servers.Server{User: "deploy"}
+
+	if got := PrivilegedCommand(root, "id -u"); got != "id -u" {
+		t.Errorf("expected root commands to run as is, got %q", got)
+	}
+
+	if got := PrivilegedCommand(deploy, "id -u"); got != "sudo sh -c 'id -u'" {
+		t.Errorf("unexpected privileged command %q", got)
+	}
+
+	if got := privilegeRequirements(root, []string{PrivilegeTool, "at"}); !slices.Equal(got, []string{"at"}) {
+		t.Errorf("expected sudo not to be required as root, got %q", got)
+	}
+
+	if got := privilegeRequirements(deploy, []string{PrivilegeTool, "at"}); !slices.Equal(got, []string{PrivilegeTool, "at"}) {
+		t.Errorf("expected sudo to be required, got %q", got)
+	}
+
+	alpine := &servers.Server{User: "alpine", BecomeMethod: "doas"}
+
+	if got := PrivilegedCommand(alpine, "id -u"); got != "doas sh -c 'id -u'" {
+		t.Errorf("unexpected doas command %q", got)
+	}
+
+	if got := privilegeRequirements(alpine, []string{PrivilegeTool, "at"}); !slices.Equal(got, []string{"doas", "at"}) {
+		t.Errorf("expected doas to be required, got %q", got)
+	}
+
+	legacy := &servers.Server{User: "admin", BecomeMethod: "su"}
+
+	if got := PrivilegedCommand(legacy, "id -u"); got != "su root -c 'id -u'" {
+		t.Errorf("unexpected su command %q", got)
+	}
+}
+
+func TestExtractSudoPasswordFromOutput(t *testing.T) {
+	for _, test := range []struct {
+		server *servers.Server
+		output string
+		want   string
+	}{
+		{&servers.Server{User: "deploy", SudoPassword: "s3cret"}, "s3cret\n[sudo] password for deploy: \n0", "0"},
+		{&servers.Server{User: "admin", BecomeMethod: "su", SudoPassword: "r00t"}, "r00t\nPassword: \n0", "0"},
+		{&servers.Server{User: "admin", BecomeMethod: "su", SudoPassword: "r00t"}, "r00t\nPassword: 0\nuid=0", "0\nuid=0"},
+		{&servers.Server{User: "deploy"}, "Password: hunter2", "Password: hunter2"},
+	} {
+		stdout := bytes.NewBufferString(test.output)
+		extractSudoPasswordFromOutput(stdout, test.server)
+		if got := stdout.String(); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.output, got, test.want)
+		}
+	}
+}
+
+func TestPrivilegeError(t *testing.T) {
+	deploy := &servers.Server{Name: "web-1", User: "deploy"}
+	withPassword := &servers.Server{Name: "web-1", User: "deploy", SudoPassword: "s3cret"}
+	alpine := &servers.Server{Name: "web-1", User: "alpine", BecomeMethod: "doas"}
+	root := &servers.Server{Name: "web-1", User: "root"}
+	legacy := &servers.Server{Name: "web-1", User: "admin", BecomeMethod: "su"}
+	guest := &servers.Server{Name: "web-1", User: "deploy", Container: "guest", ContainerTool: "lxc"}
+	docker := &servers.Server{Name: "web-1", User: "deploy", Container: "web"}
+
+	for _, test := range []struct {
+		server  *servers.Server
+		command string
+		output  string
+		want    string
+	}{
+		{deploy, "sudo sh -c 'id -u'", "[sudo] password for deploy: \r\nsudo: a password is required\r\n", "set `sudo_password`"},
+		{deploy, "sudo sh -c 'id -u'", "sudo: a terminal is required to read the password", "set `sudo_password`"},
+		{withPassword, "sudo sh -c 'id -u'", "[sudo] password for deploy: \r\nsudo: 3 incorrect password attempts\r\n", "check `sudo_password`"},
+		{deploy, "sudo sh -c 'id -u'", "deploy is not in the sudoers file.  This incident will be reported.", "user deploy may not run commands as root through sudo"},
+		{deploy, "sudo sh -c 'id -u'", "sh: 1: sudo: not found", "sudo is not installed"},
+		{alpine, "doas sh -c 'id -u'", "doas (alpine@web-1) password: \r\ndoas: Authentication failed\r\n", "doas asked for a password"},
+		{alpine, "doas sh -c 'id -u'", "doas: Operation not permitted", "through doas"},
+		{legacy, "su root -c 'id -u'", "Password: \r\nsu: Authentication failure\r\n", "su asked for a password"},
+		{legacy, "su root -c 'id -u'", "su: Permission denied", "through su"},
+		{deploy, "sudo sh -c 'false'", "", ""},
+		{deploy, "grep sudo /var/log/auth.log", "sudo: a password is required", ""},
+		{root, "sudo sh -c 'id -u'", "sudo: a password is required", ""},
+		{guest, "id -u", "sudo: a password is required", "allow sudo without a password for user deploy"},
+		{guest, "id -u", "deploy is not in the sudoers file.  This incident will be reported.", "user deploy may not run commands as root through sudo"},
+		{docker, "id -u", "sudo: a password is required", ""},
+	} {
+		err := privilegeError(test.server, &servers.ServerCommand{Command: test.command, Stdout: test.output, ExitCode: 1})
+
+		var privilegeErr *PrivilegeError
+		if test.want == "" {
+			if err != nil {
+				t.Errorf("%q: expected no privilege error, got %v", test.output, err)
+			}
+			continue
+		}
+		if !errors.As(err, &privilegeErr) || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: expected an error containing %q, got %v", test.output, test.want, err)
+		}
+	}
+
+	if err := privilegeError(deploy, &servers.ServerCommand{Command: "sudo sh -c 'id -u'", Stdout: "sudo: a password is required"}); err != nil {
+		t.Errorf("expected successful commands to be left alone, got %v", err)
+	}
+}
+
+func TestExecuteCommandPrivilegeError(t *testing.T) {
+	service := &SSHService{Fake: &FakeTransport{Responses: []FakeResponse{
+		{Pattern: regexp.MustCompile(`^sudo `), Stdout: "[sudo] password for deploy: \r\nsudo: a password is required\r\n", ExitCode: 1},
+	}}}
+
+	server := &servers.Server{Name: "web-1", Address: "192.0.2.10", Port: 22, User: "deploy"}
+	ctx := context.Background()
+
+	if err := service.OpenConnection(ctx, server); err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+
+	_, err := service.ExecuteCommand(ctx, PrivilegedCommand(server, "systemctl restart nginx"), server)
+
+	var privilegeErr *PrivilegeError
+	if !errors.As(err, &privilegeErr) || privilegeErr.Host != "web-1" || privilegeErr.Method != "sudo" {
+		t.Errorf("expected a privilege error, got %v", err)
+	}
+}
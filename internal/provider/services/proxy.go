@@ -0,0 +1,282 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getenv returns the value of the upper or lower case variant of an environment variable.
+func getenv(name string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return os.Getenv(strings.ToLower(name))
+}
+
+// proxyFromEnvironment returns the proxy address must be dialed through according to the
+// ALL_PROXY and NO_PROXY variables, or nil to dial it directly.
+func proxyFromEnvironment(getenv func(string) string, address string) (*url.URL, error) {
+	proxy := getenv("ALL_PROXY")
+	if proxy == "" || bypassProxy(getenv("NO_PROXY"), address) {
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ALL_PROXY: %w", err)
+	}
+
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return nil, fmt.Errorf("unsupported ALL_PROXY scheme %q, expected socks5, socks5h or http", proxyURL.Scheme)
+	}
+
+	return proxyURL, nil
+}
+
+// bypassProxy reports whether address matches one of the NO_PROXY entries: "*", IP
+// addresses, CIDR ranges and domains, which also match their subdomains.
+func bypassProxy(noProxy string, address string) bool {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		if entryHost, entryPort, err := net.SplitHostPort(entry); err == nil {
+			if entryPort != port {
+				continue
+			}
+			entry = entryHost
+		}
+
+		if entryIP := net.ParseIP(entry); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		domain := strings.TrimPrefix(entry, ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dialContext connects to address, through the proxy configured in the environment if any.
+func dialContext(ctx context.Context, dialer *net.Dialer, address string) (net.Conn, error) {
+	proxyURL, err := proxyFromEnvironment(getenv, address)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL == nil {
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+
+	proxyAddress := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "1080"
+		if proxyURL.Scheme == "http" {
+			port = "80"
+		}
+		proxyAddress = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddress)
+	if err != nil {
+		return nil, fmt.Errorf("dialing proxy %s: %w", proxyAddress, err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if proxyURL.Scheme == "http" {
+		conn, err = httpConnect(conn, proxyURL, address)
+	} else {
+		err = socks5Connect(conn, proxyURL, address)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("connecting to %s through proxy %s: %w", address, proxyAddress, err)
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
+
+// socks5Connect asks the SOCKS5 server at the other end of conn to connect to address,
+// authenticating with the proxy URL credentials when present.
+func socks5Connect(conn net.Conn, proxyURL *url.URL, address string) error {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portString)
+	}
+	if len(host) > 255 {
+		return fmt.Errorf("host name %q is too long", host)
+	}
+
+	methods := []byte{0x00}
+	if proxyURL.User != nil {
+		methods = append(methods, 0x02)
+	}
+	if _, err := conn.Write(append([]byte{0x05, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != 0x05 {
+		return fmt.Errorf("unexpected SOCKS version %d", reply[0])
+	}
+
+	switch reply[1] {
+	case 0x00:
+	case 0x02:
+		if proxyURL.User == nil {
+			return errors.New("proxy requires a user name and password")
+		}
+		user := proxyURL.User.Username()
+		password, _ := proxyURL.User.Password()
+		if len(user) > 255 || len(password) > 255 {
+			return errors.New("proxy user name or password is too long")
+		}
+
+		request := []byte{0x01, byte(len(user))}
+		request = append(request, user...)
+		request = append(request, byte(len(password)))
+		request = append(request, password...)
+		if _, err := conn.Write(request); err != nil {
+			return err
+		}
+
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != 0x00 {
+			return errors.New("proxy authentication failed")
+		}
+	default:
+		return errors.New("no acceptable proxy authentication method")
+	}
+
+	request := []byte{0x05, 0x01, 0x00}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+		request = append(append(request, 0x01), ip.To4()...)
+	} else if ip != nil {
+		request = append(append(request, 0x04), ip.To16()...)
+	} else {
+		request = append(append(request, 0x03, byte(len(host))), host...)
+	}
+	request = binary.BigEndian.AppendUint16(request, uint16(port))
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != 0x00 {
+		return fmt.Errorf("proxy refused the connection with code %d", header[1])
+	}
+
+	var length int
+	switch header[3] {
+	case 0x01:
+		length = net.IPv4len
+	case 0x04:
+		length = net.IPv6len
+	case 0x03:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return err
+		}
+		length = int(size[0])
+	default:
+		return fmt.Errorf("unexpected SOCKS address type %d", header[3])
+	}
+
+	// Discard the bound address and port.
+	_, err = io.ReadFull(conn, make([]byte, length+2))
+	return err
+}
+
+// bufferedConn reads through the buffer used to parse the proxy response, which may already
+// hold the first bytes sent by the SSH server.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// httpConnect opens a tunnel to address with an HTTP CONNECT request.
+func httpConnect(conn net.Conn, proxyURL *url.URL, address string) (net.Conn, error) {
+	request := fmt.Sprintf("CONNECT %[1]s HTTP/1.1\r\nHost: %[1]s\r\n", address)
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		request += "Proxy-Authorization: Basic " + credentials + "\r\n"
+	}
+
+	if _, err := io.WriteString(conn, request+"\r\n"); err != nil {
+		return conn, err
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return conn, err
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return conn, fmt.Errorf("proxy answered %s", response.Status)
+	}
+
+	return &bufferedConn{Conn: conn, reader: reader}, nil
+}
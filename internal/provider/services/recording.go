@@ -0,0 +1,173 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"remote-provider/internal/provider/servers"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redactedText replaces the secrets found in recordings.
+const redactedText = "[REDACTED]"
+
+// contentText replaces the file content carried by the commands recorded or logged.
+const contentText = "[CONTENT]"
+
+// contentPayload matches the base64 file content piped to `base64 -d` by WriteFileCommand and
+// alike, followed by its closing quotes, which are nested when the command is wrapped again.
+var contentPayload = regexp.MustCompile(`[A-Za-z0-9+/]+={0,2}(['"]+ \| base64 -d)`)
+
+// SessionRecording stores the PTY output of privileged commands as asciinema v2 files so
+// applies can be reviewed and replayed with `asciinema play`.
+type SessionRecording struct {
+	// Directory receives one .cast file per recorded command.
+	Directory string
+	// Always records every privileged command, otherwise only the commands of resources
+	// asking for it are recorded.
+	Always bool
+	// Redact lists patterns replaced in the recordings in addition to the credentials of the host.
+	Redact []*regexp.Regexp
+}
+
+type recordSessionKey struct{}
+
+type fileContentKey struct{}
+
+// WithSessionRecording overrides the provider setting for the privileged commands run with ctx.
+func WithSessionRecording(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, recordSessionKey{}, enabled)
+}
+
+// WithFileContent marks the commands run with ctx as printing file content, which may hold
+// secrets, so they are never recorded.
+func WithFileContent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fileContentKey{}, true)
+}
+
+// recordsSession reports whether command must be recorded.
+func (service *SSHService) recordsSession(ctx context.Context, server *servers.Server, command string) bool {
+	if service.Recording == nil || !isPrivilegedCommand(server, command) {
+		return false
+	}
+
+	if content, _ := ctx.Value(fileContentKey{}).(bool); content {
+		return false
+	}
+
+	if enabled, ok := ctx.Value(recordSessionKey{}).(bool); ok {
+		return enabled
+	}
+
+	return service.Recording.Always
+}
+
+// isPrivilegedCommand reports whether command was built by PrivilegedCommand for server.
+func isPrivilegedCommand(server *servers.Server, command string) bool {
+	return runsAsRoot(server) || strings.HasPrefix(command, becomePrefix(server))
+}
+
+// sessionRecorder collects the output of a command line by line, so secrets are never split
+// across two events when redacted.
+type sessionRecorder struct {
+	mutex   sync.Mutex
+	start   time.Time
+	partial bytes.Buffer
+	events  [][3]any
+}
+
+func newSessionRecorder() *sessionRecorder {
+	return &sessionRecorder{start: time.Now()}
+}
+
+func (recorder *sessionRecorder) Write(p []byte) (int, error) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.partial.Write(p)
+	for {
+		line, err := recorder.partial.ReadString('\n')
+		if err != nil {
+			// Keep the incomplete line for the next write.
+			recorder.partial.Reset()
+			recorder.partial.WriteString(line)
+			break
+		}
+
+		recorder.event(line)
+	}
+
+	return len(p), nil
+}
+
+func (recorder *sessionRecorder) event(data string) {
+	recorder.events = append(recorder.events, [3]any{time.Since(recorder.start).Seconds(), "o", data})
+}
+
+// redact replaces the credentials of server and the matches of patterns in text.
+func redact(text string, server *servers.Server, patterns []*regexp.Regexp) string {
+	for _, secret := range []string{server.Password, server.SudoPassword, server.TOTPSecret} {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, redactedText)
+		}
+	}
+
+	for _, pattern := range patterns {
+		text = pattern.ReplaceAllString(text, redactedText)
+	}
+
+	return text
+}
+
+// RedactFileContent replaces the file content written by command with a placeholder, so it can
+// be logged or recorded.
+func RedactFileContent(command string) string {
+	return contentPayload.ReplaceAllString(command, contentText+"$1")
+}
+
+// save writes the recording of command on server to the recording directory.
+func (recorder *sessionRecorder) save(recording *SessionRecording, server *servers.Server, command string) (string, error) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if recorder.partial.Len() > 0 {
+		recorder.event(recorder.partial.String())
+		recorder.partial.Reset()
+	}
+
+	var cast bytes.Buffer
+	encoder := json.NewEncoder(&cast)
+	err := encoder.Encode(map[string]any{
+		"version":   2,
+		"width":     80,
+		"height":    40,
+		"timestamp": recorder.start.Unix(),
+		"command":   redact(RedactFileContent(command), server, recording.Redact),
+		"title":     server.User + "@" + server.Name,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, event := range recorder.events {
+		data, _ := event[2].(string)
+		event[2] = redact(data, server, recording.Redact)
+		if err := encoder.Encode(event); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.MkdirAll(recording.Directory, 0o700); err != nil {
+		return "", err
+	}
+
+	name := filepath.Join(recording.Directory, fmt.Sprintf("%s-%d.cast", server.Name, recorder.start.UnixNano()))
+
+	return name, os.WriteFile(name, cast.Bytes(), 0o600)
+}
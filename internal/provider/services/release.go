@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// GitHubAPIURL is the API of github.com, GitHub Enterprise servers serve theirs under /api/v3.
+const GitHubAPIURL = "https://api.github.com"
+
+// GitHubRepositoryRegexp matches an <owner>/<repository> name.
+var GitHubRepositoryRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// GitHubRelease is a published release of a GitHub repository.
+type GitHubRelease struct {
+	TagName    string               `json:"tag_name"`
+	Draft      bool                 `json:"draft"`
+	Prerelease bool                 `json:"prerelease"`
+	Assets     []GitHubReleaseAsset `json:"assets"`
+}
+
+// GitHubReleaseAsset is a file attached to a release.
+type GitHubReleaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Version returns the tag of the release without its v prefix.
+func (release *GitHubRelease) Version() string {
+	return strings.TrimPrefix(release.TagName, "v")
+}
+
+// ListGitHubReleases returns the latest 100 releases of repository from the API at apiURL,
+// authenticated with token when it is set.
+func ListGitHubReleases(ctx context.Context, client *http.Client, apiURL string, repository string, token string) ([]GitHubRelease, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(apiURL, "/")+"/repos/"+repository+"/releases?per_page=100", nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/vnd.github+json")
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return nil, fmt.Errorf("listing the releases of %s: %s: %s", repository, response.Status, strings.TrimSpace(string(body)))
+	}
+
+	var releases []GitHubRelease
+	err = json.NewDecoder(response.Body).Decode(&releases)
+	if err != nil {
+		return nil, fmt.Errorf("listing the releases of %s: %w", repository, err)
+	}
+
+	return releases, nil
+}
+
+// SelectGitHubRelease returns the highest release matching constraint, e.g. "~> 3.14" or
+// ">= 0.38, < 1.0", or the highest one when constraint is "latest". Drafts, prereleases and
+// tags that are not versions are skipped.
+func SelectGitHubRelease(releases []GitHubRelease, constraint string) (*GitHubRelease, error) {
+	var constraints version.Constraints
+	if constraint != "latest" {
+		var err error
+		constraints, err = version.NewConstraint(constraint)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var selected *GitHubRelease
+	var highest *version.Version
+	for i := range releases {
+		release := &releases[i]
+		if release.Draft || release.Prerelease {
+			continue
+		}
+
+		v, err := version.NewVersion(release.TagName)
+		if err != nil || v.Prerelease() != "" || !constraints.Check(v) {
+			continue
+		}
+
+		if highest == nil || v.GreaterThan(highest) {
+			selected, highest = release, v
+		}
+	}
+
+	if selected == nil {
+		return nil, fmt.Errorf("no release matches %s", constraint)
+	}
+
+	return selected, nil
+}
+
+// Asset returns the asset of the release matching pattern, a glob in which {version} stands for
+// the version of the release, e.g. "helm-v{version}-linux-amd64.tar.gz".
+func (release *GitHubRelease) Asset(pattern string) (*GitHubReleaseAsset, error) {
+	pattern = strings.ReplaceAll(pattern, "{version}", release.Version())
+
+	var matches []*GitHubReleaseAsset
+	for i := range release.Assets {
+		matched, err := path.Match(pattern, release.Assets[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			matches = append(matches, &release.Assets[i])
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no asset of release %s matches %s", release.TagName, pattern)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, 0, len(matches))
+		for _, asset := range matches {
+			names = append(names, asset.Name)
+		}
+		return nil, fmt.Errorf("several assets of release %s match %s: %s", release.TagName, pattern, strings.Join(names, ", "))
+	}
+}
+
+// BinaryReleaseInstallCommand returns a command downloading asset with curl or wget, verifying
+// it against sha256 or the sha256sum listing at checksumsURL when they are set, extracting
+// binary from it when it is an archive and installing it at target with mode.
+func BinaryReleaseInstallCommand(asset *GitHubReleaseAsset, sha256 string, checksumsURL string, binary string, target string, mode string) string {
+	script := []string{
+		`tmp=$(mktemp -d) || exit 1`,
+		`trap 'rm -rf "$tmp"' EXIT`,
+		`cd "$tmp" || exit 1`,
+		`if command -v curl >/dev/null 2>&1; then fetch() { curl -fsSL -o "$2" "$1"; }; else fetch() { wget -q -O "$2" "$1"; }; fi`,
+		fmt.Sprintf(`fetch %s asset || exit 1`, ShellQuote(asset.URL)),
+	}
+
+	switch {
+	case sha256 != "":
+		script = append(script, "expected="+ShellQuote(strings.ToLower(sha256)))
+	case checksumsURL != "":
+		script = append(script,
+			fmt.Sprintf(`fetch %s checksums || exit 1`, ShellQuote(checksumsURL)),
+			fmt.Sprintf(`expected=$(awk -v asset=%s '{ name = $2; sub(/^\*/, "", name); if (name == asset) { print $1; exit } }' checksums)`, ShellQuote(asset.Name)),
+			fmt.Sprintf(`[ -n "$expected" ] || { echo %s >&2; exit 1; }`, ShellQuote(asset.Name+" is not listed in the checksums")),
+		)
+	}
+	if sha256 != "" || checksumsURL != "" {
+		script = append(script,
+			`actual=$(sha256sum asset 2>/dev/null || shasum -a 256 asset) || exit 1`,
+			`[ "${actual%% *}" = "$expected" ] || { echo "checksum mismatch: expected $expected, got ${actual%% *}" >&2; exit 1; }`,
+		)
+	}
+
+	script = append(script,
+		fmt.Sprintf(`case %s in`, ShellQuote(asset.Name)),
+		`*.tar.gz|*.tgz) mkdir x && tar -xzf asset -C x ;;`,
+		`*.tar.xz) mkdir x && tar -xJf asset -C x ;;`,
+		`*.tar.bz2) mkdir x && tar -xjf asset -C x ;;`,
+		`*.zip) mkdir x && unzip -q asset -d x ;;`,
+		`*) mkdir x && mv asset x/`+ShellQuote(binary)+` ;;`,
+		`esac || exit 1`,
+		fmt.Sprintf(`bin=$(find x -type f -name %s | head -n 1)`, ShellQuote(binary)),
+		fmt.Sprintf(`[ -n "$bin" ] || { echo %s >&2; exit 1; }`, ShellQuote(binary+" is not in "+asset.Name)),
+		fmt.Sprintf(`mkdir -p %s && chmod %s "$bin" && mv -f "$bin" %s`, ShellQuote(path.Dir(target)), mode, ShellQuote(target)),
+	)
+
+	return strings.Join(script, "\n")
+}
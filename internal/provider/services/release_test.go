@@ -0,0 +1,155 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestSelectGitHubRelease(t *testing.T) {
+	releases := []GitHubRelease{
+		{TagName: "v3.15.0-rc.1", Prerelease: true},
+		{TagName: "v3.14.4"},
+		{TagName: "v3.13.3"},
+		{TagName: "nightly"},
+		{TagName: "v4.0.0", Draft: true},
+		{TagName: "v3.14.10"},
+	}
+
+	tests := []struct {
+		constraint string
+		want       string
+	}{
+		{constraint: "latest", want: "v3.14.10"},
+		{constraint: "~> 3.13.0", want: "v3.13.3"},
+		{constraint: ">= 3.14, < 3.14.5", want: "v3.14.4"},
+	}
+
+	for _, tt := range tests {
+		release, err := SelectGitHubRelease(releases, tt.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if release.TagName != tt.want {
+			t.Errorf("SelectGitHubRelease(%s) = %s, want %s", tt.constraint, release.TagName, tt.want)
+		}
+	}
+
+	if _, err := SelectGitHubRelease(releases, ">= 4.0"); err == nil {
+		t.Error("expected an error when no release matches")
+	}
+}
+
+func TestReleaseAsset(t *testing.T) {
+	release := GitHubRelease{TagName: "v3.14.4", Assets: []GitHubReleaseAsset{
+		{Name: "helm-v3.14.4-darwin-arm64.tar.gz"},
+		{Name: "helm-v3.14.4-linux-amd64.tar.gz"},
+		{Name: "helm-v3.14.4-linux-amd64.tar.gz.sha256sum"},
+	}}
+
+	asset, err := release.Asset("helm-v{version}-linux-amd64.tar.gz")
+	if err != nil || asset.Name != "helm-v3.14.4-linux-amd64.tar.gz" {
+		t.Errorf("expected the linux archive, got %+v, %v", asset, err)
+	}
+	if _, err := release.Asset("helm-*-linux-amd64.tar.gz*"); err == nil {
+		t.Error("expected an error when several assets match")
+	}
+}
+
+// releaseArchive returns a tar.gz archive holding content as dir/name.
+func releaseArchive(t *testing.T, name string, content []byte) []byte {
+	var archive bytes.Buffer
+	compressed := gzip.NewWriter(&archive)
+	writer := tar.NewWriter(compressed)
+	if err := writer.WriteHeader(&tar.Header{Name: "linux-amd64/" + name, Mode: 0644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := compressed.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return archive.Bytes()
+}
+
+func TestBinaryReleaseInstall(t *testing.T) {
+	archive := releaseArchive(t, "helm", []byte("#!/bin/sh\necho helm\n"))
+	digest := sha256.Sum256(archive)
+	checksum := hex.EncodeToString(digest[:])
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/helm/helm/releases":
+			_ = json.NewEncoder(w).Encode([]GitHubRelease{{TagName: "v3.14.4", Assets: []GitHubReleaseAsset{
+				{Name: "helm-v3.14.4-linux-amd64.tar.gz", URL: server.URL + "/helm.tar.gz"},
+			}}})
+		case "/helm.tar.gz":
+			_, _ = w.Write(archive)
+		case "/sha256sums":
+			_, _ = w.Write([]byte("0000  helm-v3.14.4-darwin-arm64.tar.gz\n" + checksum + " *helm-v3.14.4-linux-amd64.tar.gz\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	releases, err := ListGitHubReleases(context.Background(), server.Client(), server.URL, "helm/helm", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, err := SelectGitHubRelease(releases, "~> 3.14")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset, err := release.Asset("helm-v{version}-linux-amd64.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(t.TempDir(), "bin", "helm")
+	tests := []struct {
+		sha256       string
+		checksumsURL string
+		fails        bool
+	}{
+		{sha256: checksum},
+		{checksumsURL: server.URL + "/sha256sums"},
+		{sha256: "0000", fails: true},
+	}
+
+	for _, tt := range tests {
+		_ = os.Remove(target)
+		command := BinaryReleaseInstallCommand(asset, tt.sha256, tt.checksumsURL, "helm", target, "0755")
+		output, err := exec.Command("sh", "-c", command).CombinedOutput()
+		if tt.fails {
+			if err == nil {
+				t.Errorf("expected the checksum %s to be rejected", tt.sha256)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %s", err, output)
+		}
+
+		output, err = exec.Command(target).CombinedOutput()
+		if err != nil || string(output) != "helm\n" {
+			t.Errorf("expected the installed binary to run, got %q, %v", output, err)
+		}
+	}
+}
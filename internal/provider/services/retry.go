@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"time"
+)
+
+// RetryPolicy retries commands whose output matches Pattern, e.g. a package manager lock
+// held by unattended upgrades, up to MaxRetries times waiting Delay between attempts.
+type RetryPolicy struct {
+	Pattern    *regexp.Regexp
+	MaxRetries int
+	Delay      time.Duration
+}
+
+// matchRetryPolicy returns the index of the first policy matching output that still has
+// retries left according to attempts, or -1.
+func matchRetryPolicy(policies []RetryPolicy, output string, attempts []int) int {
+	for i, policy := range policies {
+		if attempts[i] < policy.MaxRetries && policy.Pattern.MatchString(output) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// SleepContext waits for delay unless ctx is done first.
+func SleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
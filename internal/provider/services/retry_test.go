@@ -0,0 +1,35 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestMatchRetryPolicy(t *testing.T) {
+	policies := []RetryPolicy{
+		{Pattern: regexp.MustCompile(`Could not get lock /var/lib/dpkg/lock`), MaxRetries: 2},
+		{Pattern: regexp.MustCompile(`(?i)temporary failure`), MaxRetries: 1},
+	}
+	attempts := make([]int, len(policies))
+
+	output := "E: Could not get lock /var/lib/dpkg/lock-frontend. It is held by process 1234"
+	for range 2 {
+		i := matchRetryPolicy(policies, output, attempts)
+		if i != 0 {
+			t.Fatalf("expected the dpkg policy, got %d", i)
+		}
+		attempts[i]++
+	}
+
+	if i := matchRetryPolicy(policies, output, attempts); i != -1 {
+		t.Fatalf("expected the retries to be exhausted, got %d", i)
+	}
+
+	if i := matchRetryPolicy(policies, "Temporary failure resolving archive.ubuntu.com", attempts); i != 1 {
+		t.Fatalf("expected the second policy, got %d", i)
+	}
+
+	if i := matchRetryPolicy(policies, "permission denied", attempts); i != -1 {
+		t.Fatalf("expected no policy, got %d", i)
+	}
+}
@@ -1,15 +1,54 @@
 package services
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"golang.org/x/crypto/ssh"
 	"remote-provider/internal/provider/servers"
+	"strconv"
+	"strings"
 )
 
 type Service interface {
-	ExecuteCommand(command string, server *servers.Server) (*servers.ServerCommand, error)
+	ExecuteCommand(ctx context.Context, command string, server *servers.Server) (*servers.ServerCommand, error)
 }
 
 type SSHConnection struct {
-	host   *servers.Server
-	client *ssh.Client
+	host *servers.Server
+	// key is the connectionKey of host.
+	key      string
+	client   *ssh.Client
+	sessions chan struct{}
+	// console replaces client when the host was only reachable through its serial console.
+	console *serialConsole
+	// local connections have no client, their commands run on the machine running Terraform.
+	local bool
+}
+
+// connectionKey identifies the address, credentials, privilege escalation and transport of
+// host without keeping its secrets, so resources reaching the same host with another user,
+// key, sudo password or transport never share a connection.
+// Connections are only shared within the provider process: Terraform runs the plan and the
+// apply of an operation in separate processes, so no connection outlives its phase.
+func connectionKey(host *servers.Server) string {
+	digest := sha256.Sum256([]byte(strings.Join([]string{
+		host.Name,
+		host.Address,
+		strconv.Itoa(int(host.Port)),
+		host.User,
+		host.Password,
+		host.PrivateKeyPath,
+		host.TOTPSecret,
+		host.BecomeMethod,
+		host.SudoPassword,
+		host.ProxyCommand,
+		host.WebSocketURL,
+		host.ConsoleCommand,
+		strconv.FormatBool(host.Local),
+		host.Container,
+		host.ContainerTool,
+	}, "\x00")))
+
+	return hex.EncodeToString(digest[:])
 }
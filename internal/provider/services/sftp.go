@@ -0,0 +1,461 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"remote-provider/internal/provider/servers"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Packet types of version 3 of the SFTP protocol, the version OpenSSH implements.
+const (
+	sftpPacketInit    = 1
+	sftpPacketVersion = 2
+	sftpPacketOpen    = 3
+	sftpPacketClose   = 4
+	sftpPacketRead    = 5
+	sftpPacketWrite   = 6
+	sftpPacketStatus  = 101
+	sftpPacketHandle  = 102
+	sftpPacketData    = 103
+)
+
+// Flags of the open requests and of their attributes, status codes and limits of the protocol.
+const (
+	sftpOpenRead      = 0x01
+	sftpOpenWrite     = 0x02
+	sftpOpenCreate    = 0x08
+	sftpOpenTruncate  = 0x10
+	sftpAttrPerms     = 0x04
+	sftpStatusOK      = 0
+	sftpStatusEOF     = 1
+	sftpStatusNoFile  = 2
+	sftpStatusDenied  = 3
+	sftpProtocolV3    = 3
+	sftpChunkSize     = 32 * 1024
+	sftpMaxPacketSize = 256 * 1024
+)
+
+// ErrSFTPUnavailable is returned by the SFTP transfers of hosts without the SFTP subsystem, or
+// whose files cannot be reached with it, e.g. local hosts, containers or privileged files. The
+// callers fall back to commands.
+var ErrSFTPUnavailable = errors.New("sftp is not available")
+
+// sftpStatusError is a failure reported by the SFTP server.
+type sftpStatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (err *sftpStatusError) Error() string {
+	return fmt.Sprintf("sftp: %s (status %d)", err.Message, err.Code)
+}
+
+// Is lets errors.Is match the missing files and denied accesses of the server.
+func (err *sftpStatusError) Is(target error) bool {
+	return (target == fs.ErrNotExist && err.Code == sftpStatusNoFile) ||
+		(target == fs.ErrPermission && err.Code == sftpStatusDenied)
+}
+
+// sftpClient speaks version 3 of the SFTP protocol over the standard input and output of a
+// subsystem. Requests are sent one at a time, which is enough for the files resources manage.
+type sftpClient struct {
+	reader io.Reader
+	writer io.WriteCloser
+	nextID uint32
+}
+
+// newSFTPClient negotiates the protocol version with the server at the other end of reader and
+// writer.
+func newSFTPClient(reader io.Reader, writer io.WriteCloser) (*sftpClient, error) {
+	client := &sftpClient{reader: reader, writer: writer}
+
+	err := client.send(sftpPacketInit, binary.BigEndian.AppendUint32(nil, sftpProtocolV3))
+	if err != nil {
+		return nil, err
+	}
+
+	packetType, payload, err := client.receive()
+	if err != nil {
+		return nil, err
+	}
+	if packetType != sftpPacketVersion || len(payload) < 4 {
+		return nil, fmt.Errorf("sftp: unexpected packet %d instead of the version", packetType)
+	}
+	if version := binary.BigEndian.Uint32(payload); version != sftpProtocolV3 {
+		return nil, fmt.Errorf("sftp: unsupported protocol version %d", version)
+	}
+
+	return client, nil
+}
+
+func (client *sftpClient) send(packetType byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	packet = append(packet, packetType)
+	packet = append(packet, payload...)
+
+	_, err := client.writer.Write(packet)
+
+	return err
+}
+
+func (client *sftpClient) receive() (byte, []byte, error) {
+	header := make([]byte, 5)
+	_, err := io.ReadFull(client.reader, header)
+	if err != nil {
+		return 0, nil, fmt.Errorf("sftp: reading a packet: %w", err)
+	}
+
+	length := binary.BigEndian.Uint32(header)
+	if length < 1 || length > sftpMaxPacketSize {
+		return 0, nil, fmt.Errorf("sftp: invalid packet length %d", length)
+	}
+
+	payload := make([]byte, length-1)
+	_, err = io.ReadFull(client.reader, payload)
+	if err != nil {
+		return 0, nil, fmt.Errorf("sftp: reading a packet: %w", err)
+	}
+
+	return header[4], payload, nil
+}
+
+// request sends a request of packetType with payload, then returns the type and the payload of
+// the response, without its request id. Status responses other than OK are returned as errors.
+func (client *sftpClient) request(packetType byte, payload []byte) (byte, []byte, error) {
+	client.nextID++
+	id := client.nextID
+
+	err := client.send(packetType, append(binary.BigEndian.AppendUint32(nil, id), payload...))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	responseType, response, err := client.receive()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(response) < 4 || binary.BigEndian.Uint32(response) != id {
+		return 0, nil, fmt.Errorf("sftp: unexpected response to request %d", id)
+	}
+	response = response[4:]
+
+	if responseType == sftpPacketStatus {
+		if len(response) < 4 {
+			return 0, nil, errors.New("sftp: truncated status")
+		}
+		code := binary.BigEndian.Uint32(response)
+		if code == sftpStatusOK {
+			return responseType, nil, nil
+		}
+
+		message, _, _ := sftpString(response[4:])
+		return 0, nil, &sftpStatusError{Code: code, Message: string(message)}
+	}
+
+	return responseType, response, nil
+}
+
+// sftpString splits the length prefixed string at the start of data from the rest of data.
+func sftpString(data []byte) ([]byte, []byte, bool) {
+	if len(data) < 4 {
+		return nil, nil, false
+	}
+
+	length := binary.BigEndian.Uint32(data)
+	if uint64(len(data)-4) < uint64(length) {
+		return nil, nil, false
+	}
+
+	return data[4 : 4+length], data[4+length:], true
+}
+
+// appendSFTPString appends value to payload with its length prefix.
+func appendSFTPString(payload []byte, value string) []byte {
+	payload = binary.BigEndian.AppendUint32(payload, uint32(len(value)))
+
+	return append(payload, value...)
+}
+
+// open opens path with flags, creating it with perm when it does not exist. It returns the
+// handle of the file.
+func (client *sftpClient) open(path string, flags uint32, perm fs.FileMode) (string, error) {
+	payload := appendSFTPString(nil, path)
+	payload = binary.BigEndian.AppendUint32(payload, flags)
+	payload = binary.BigEndian.AppendUint32(payload, sftpAttrPerms)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(perm.Perm()))
+
+	responseType, response, err := client.request(sftpPacketOpen, payload)
+	if err != nil {
+		return "", fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	handle, _, ok := sftpString(response)
+	if responseType != sftpPacketHandle || !ok {
+		return "", fmt.Errorf("opening %s: sftp: unexpected packet %d instead of a handle", path, responseType)
+	}
+
+	return string(handle), nil
+}
+
+func (client *sftpClient) close(handle string) error {
+	_, _, err := client.request(sftpPacketClose, appendSFTPString(nil, handle))
+
+	return err
+}
+
+// ReadFile returns the content of path.
+func (client *sftpClient) ReadFile(path string) ([]byte, error) {
+	handle, err := client.open(path, sftpOpenRead, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var content []byte
+	for {
+		payload := appendSFTPString(nil, handle)
+		payload = binary.BigEndian.AppendUint64(payload, uint64(len(content)))
+		payload = binary.BigEndian.AppendUint32(payload, sftpChunkSize)
+
+		responseType, response, err := client.request(sftpPacketRead, payload)
+		var status *sftpStatusError
+		if errors.As(err, &status) && status.Code == sftpStatusEOF {
+			break
+		}
+		if err != nil {
+			_ = client.close(handle)
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		data, _, ok := sftpString(response)
+		if responseType != sftpPacketData || !ok {
+			_ = client.close(handle)
+			return nil, fmt.Errorf("reading %s: sftp: unexpected packet %d instead of data", path, responseType)
+		}
+		// An empty read would otherwise be asked again forever.
+		if len(data) == 0 {
+			break
+		}
+		content = append(content, data...)
+	}
+
+	return content, client.close(handle)
+}
+
+// WriteFile replaces the content of path with content, creating it with perm when it does not
+// exist. It returns the number of bytes written.
+func (client *sftpClient) WriteFile(path string, content io.Reader, perm fs.FileMode) (int64, error) {
+	handle, err := client.open(path, sftpOpenWrite|sftpOpenCreate|sftpOpenTruncate, perm)
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+	chunk := make([]byte, sftpChunkSize)
+	for {
+		n, readErr := io.ReadFull(content, chunk)
+		if n > 0 {
+			payload := appendSFTPString(nil, handle)
+			payload = binary.BigEndian.AppendUint64(payload, uint64(written))
+			payload = appendSFTPString(payload, string(chunk[:n]))
+
+			_, _, err = client.request(sftpPacketWrite, payload)
+			if err != nil {
+				_ = client.close(handle)
+				return written, fmt.Errorf("writing %s: %w", path, err)
+			}
+			written += int64(n)
+		}
+
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if readErr != nil {
+			_ = client.close(handle)
+			return written, readErr
+		}
+	}
+
+	return written, client.close(handle)
+}
+
+// Close ends the session of the client.
+func (client *sftpClient) Close() error {
+	return client.writer.Close()
+}
+
+// sftpAvailability remembers which connections, by connectionKey, reach the SFTP subsystem, so
+// hosts without it are only probed once per run.
+type sftpAvailability struct {
+	mutex     sync.Mutex
+	available map[string]bool
+}
+
+func (availability *sftpAvailability) get(key string) (bool, bool) {
+	availability.mutex.Lock()
+	defer availability.mutex.Unlock()
+
+	available, known := availability.available[key]
+
+	return available, known
+}
+
+func (availability *sftpAvailability) set(key string, available bool) {
+	availability.mutex.Lock()
+	defer availability.mutex.Unlock()
+
+	if availability.available == nil {
+		availability.available = map[string]bool{}
+	}
+	availability.available[key] = available
+}
+
+// openSFTP starts the SFTP subsystem in a new session of the connection of server. The returned
+// function releases the session.
+func (service *SSHService) openSFTP(ctx context.Context, server *servers.Server) (*sftpClient, func(), error) {
+	connection := service.findConnection(server)
+	if connection == nil {
+		return nil, nil, fmt.Errorf("no connection found for server %s", server.Name)
+	}
+
+	// Commands of local hosts, containers and fakes do not go through an SSH session.
+	if service.Fake != nil || connection.local || connection.console != nil || connection.client == nil || server.Container != "" {
+		return nil, nil, ErrSFTPUnavailable
+	}
+	if available, known := service.sftp.get(connection.key); known && !available {
+		return nil, nil, ErrSFTPUnavailable
+	}
+
+	service.hosts.acquire(server.Name, service.MaxParallelHosts)
+	if connection.sessions != nil {
+		select {
+		case connection.sessions <- struct{}{}:
+		case <-ctx.Done():
+			service.hosts.release(server.Name, service.MaxParallelHosts)
+			return nil, nil, fmt.Errorf("waiting for a session on %s: %w", server.Name, ctx.Err())
+		}
+	}
+	release := func() {
+		if connection.sessions != nil {
+			<-connection.sessions
+		}
+		service.hosts.release(server.Name, service.MaxParallelHosts)
+	}
+
+	session, err := service.spawnSession(ctx, connection)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	client, err := startSFTP(session)
+	if err != nil {
+		_ = session.Close()
+		release()
+		service.sftp.set(connection.key, false)
+		return nil, nil, fmt.Errorf("%w: %s", ErrSFTPUnavailable, err)
+	}
+	service.sftp.set(connection.key, true)
+
+	// Closing the session interrupts a transfer whose context is done.
+	stop := context.AfterFunc(ctx, func() { _ = session.Close() })
+
+	return client, func() {
+		stop()
+		_ = client.Close()
+		_ = session.Close()
+		release()
+	}, nil
+}
+
+// startSFTP requests the SFTP subsystem on session.
+func startSFTP(session *ssh.Session) (*sftpClient, error) {
+	writer, err := session.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	reader, err := session.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	err = session.RequestSubsystem("sftp")
+	if err != nil {
+		return nil, err
+	}
+
+	return newSFTPClient(reader, writer)
+}
+
+// ReadFileSFTP returns the content of path on server, read over SFTP so any content comes back
+// unchanged. Files read as root are only reachable when the connection user is root, other
+// privileged reads return ErrSFTPUnavailable.
+func (service *SSHService) ReadFileSFTP(ctx context.Context, server *servers.Server, path string, privileged bool) ([]byte, error) {
+	if privileged && !runsAsRoot(server) {
+		return nil, ErrSFTPUnavailable
+	}
+	service.recordOperation(ctx, server)
+
+	client, release, err := service.openSFTP(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	start := time.Now()
+	content, err := client.ReadFile(path)
+	service.Measure(ctx, "transfer", server, start, len(content), err)
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("reading %s timed out: %w", path, ctx.Err())
+	}
+
+	return content, err
+}
+
+// UploadSFTP writes content to path on server over SFTP, creating it with mode 0600. It returns
+// ErrSFTPUnavailable before reading content when the host cannot be reached with SFTP, so the
+// caller can fall back to Upload.
+func (service *SSHService) UploadSFTP(ctx context.Context, server *servers.Server, path string, content io.Reader) error {
+	service.recordOperation(ctx, server)
+	if err := service.beginApply(ctx, server); err != nil {
+		return err
+	}
+
+	client, release, err := service.openSFTP(ctx, server)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	start := time.Now()
+	written, err := client.WriteFile(path, content, 0o600)
+	service.Measure(ctx, "transfer", server, start, int(written), err)
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("writing %s timed out: %w", path, ctx.Err())
+	}
+
+	return err
+}
+
+// SFTPAvailable reports whether the files of server can be transferred over SFTP, probing the
+// subsystem the first time.
+func (service *SSHService) SFTPAvailable(ctx context.Context, server *servers.Server) bool {
+	if available, known := service.sftp.get(connectionKey(server)); known {
+		return available
+	}
+
+	_, release, err := service.openSFTP(ctx, server)
+	if err != nil {
+		return false
+	}
+	release()
+
+	return true
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"remote-provider/internal/provider/servers"
+	"testing"
+)
+
+// serveTestSFTP answers the requests of an sftpClient with the local files, as the sftp-server
+// of OpenSSH would.
+func serveTestSFTP(t *testing.T, requests io.Reader, responses io.WriteCloser) {
+	defer responses.Close()
+
+	client := &sftpClient{reader: requests, writer: responses}
+	files := map[string]*os.File{}
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+
+	packetType, payload, err := client.receive()
+	if err != nil || packetType != sftpPacketInit {
+		return
+	}
+	_ = client.send(sftpPacketVersion, binary.BigEndian.AppendUint32(nil, sftpProtocolV3))
+
+	status := func(id []byte, err error) {
+		code := uint32(sftpStatusOK)
+		switch {
+		case errors.Is(err, io.EOF):
+			code = sftpStatusEOF
+		case errors.Is(err, fs.ErrNotExist):
+			code = sftpStatusNoFile
+		case err != nil:
+			code = 4
+		}
+		message := ""
+		if err != nil {
+			message = err.Error()
+		}
+		_ = client.send(sftpPacketStatus, appendSFTPString(binary.BigEndian.AppendUint32(id, code), message))
+	}
+
+	for {
+		packetType, payload, err = client.receive()
+		if err != nil {
+			return
+		}
+		id, payload := payload[:4:4], payload[4:]
+
+		switch packetType {
+		case sftpPacketOpen:
+			path, rest, _ := sftpString(payload)
+			flags := binary.BigEndian.Uint32(rest)
+			perm := fs.FileMode(binary.BigEndian.Uint32(rest[8:]))
+
+			mode := os.O_RDONLY
+			if flags&sftpOpenWrite != 0 {
+				mode = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			}
+			file, err := os.OpenFile(string(path), mode, perm)
+			if err != nil {
+				status(id, err)
+				continue
+			}
+			handle := string(path)
+			files[handle] = file
+			_ = client.send(sftpPacketHandle, appendSFTPString(id, handle))
+		case sftpPacketRead:
+			handle, rest, _ := sftpString(payload)
+			offset := binary.BigEndian.Uint64(rest)
+			data := make([]byte, binary.BigEndian.Uint32(rest[8:]))
+
+			n, err := files[string(handle)].ReadAt(data, int64(offset))
+			if n == 0 {
+				status(id, err)
+				continue
+			}
+			_ = client.send(sftpPacketData, appendSFTPString(id, string(data[:n])))
+		case sftpPacketWrite:
+			handle, rest, _ := sftpString(payload)
+			offset := binary.BigEndian.Uint64(rest)
+			data, _, _ := sftpString(rest[8:])
+
+			_, err := files[string(handle)].WriteAt(data, int64(offset))
+			status(id, err)
+		case sftpPacketClose:
+			handle, _, _ := sftpString(payload)
+			err := files[string(handle)].Close()
+			delete(files, string(handle))
+			status(id, err)
+		default:
+			t.Errorf("unexpected packet %d", packetType)
+			return
+		}
+	}
+}
+
+// newTestSFTPClient returns a client connected to serveTestSFTP.
+func newTestSFTPClient(t *testing.T) *sftpClient {
+	t.Helper()
+
+	requestReader, requestWriter := io.Pipe()
+	responseReader, responseWriter := io.Pipe()
+	go serveTestSFTP(t, requestReader, responseWriter)
+
+	client, err := newSFTPClient(responseReader, requestWriter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+func TestSFTPClientRoundTrip(t *testing.T) {
+	client := newTestSFTPClient(t)
+
+	// Binary content with line endings, NUL bytes and lines looking like the stat output the
+	// file commands parse.
+	content := []byte("\x00\x01binary\r\n1234567\n1700000000\nsymlink\n\xff\xfe")
+	content = append(content, bytes.Repeat([]byte{0, 0x0a, 0x0d}, sftpChunkSize)...)
+
+	path := filepath.Join(t.TempDir(), "keystore.p12")
+	written, err := client.WriteFile(path, bytes.NewReader(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != int64(len(content)) {
+		t.Errorf("expected %d bytes written, got %d", len(content), written)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected mode 0600, got %o", info.Mode().Perm())
+	}
+
+	read, err := client.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(read, content) {
+		t.Errorf("content changed in the round trip, got %d bytes", len(read))
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	if _, err := client.WriteFile(empty, bytes.NewReader(nil), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if read, err := client.ReadFile(empty); err != nil || len(read) != 0 {
+		t.Errorf("expected an empty file, got %q, %v", read, err)
+	}
+}
+
+func TestSFTPClientMissingFile(t *testing.T) {
+	client := newTestSFTPClient(t)
+
+	_, err := client.ReadFile(filepath.Join(t.TempDir(), "missing"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a missing file error, got %v", err)
+	}
+}
+
+func TestSFTPUnavailableForLocalHosts(t *testing.T) {
+	server := &servers.Server{Name: "localhost", Address: "localhost", Port: 22, Local: true}
+	service := &SSHService{}
+	ctx := context.Background()
+
+	if err := service.OpenConnection(ctx, server); err != nil {
+		t.Fatal(err)
+	}
+
+	if service.SFTPAvailable(ctx, server) {
+		t.Error("expected SFTP to be unavailable for local hosts")
+	}
+	if _, err := service.ReadFileSFTP(ctx, server, "/etc/hostname", false); !errors.Is(err, ErrSFTPUnavailable) {
+		t.Errorf("expected ErrSFTPUnavailable, got %v", err)
+	}
+	if err := service.UploadSFTP(ctx, server, filepath.Join(t.TempDir(), "file"), bytes.NewReader(nil)); !errors.Is(err, ErrSFTPUnavailable) {
+		t.Errorf("expected ErrSFTPUnavailable, got %v", err)
+	}
+
+	remote := &servers.Server{Name: "remote", User: "alice"}
+	if _, err := service.ReadFileSFTP(ctx, remote, "/etc/shadow", true); !errors.Is(err, ErrSFTPUnavailable) {
+		t.Errorf("expected ErrSFTPUnavailable for privileged reads, got %v", err)
+	}
+}
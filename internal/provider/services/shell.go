@@ -0,0 +1,8 @@
+package services
+
+import "strings"
+
+// ShellQuote wraps value in single quotes so a POSIX shell passes it through verbatim.
+func ShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// SnapNameRegexp matches the names of the snap store.
+	SnapNameRegexp = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	// SnapChannelRegexp matches a [<track>/]<risk>[/<branch>] channel.
+	SnapChannelRegexp = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*/)?(stable|candidate|beta|edge)(/[A-Za-z0-9._-]+)?$|^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// snapRisks are the risk levels of a channel, from the most to the least stable.
+var snapRisks = []string{"stable", "candidate", "beta", "edge"}
+
+// Snap is a snap installed on a host.
+type Snap struct {
+	Name     string
+	Version  string
+	Revision string
+	// Channel is the channel followed by the snap, empty for a snap installed from a file.
+	Channel string
+	Classic bool
+}
+
+// NormalizeSnapChannel returns channel the way snapd reports it: a lone risk follows the latest
+// track and a lone track follows its stable risk.
+func NormalizeSnapChannel(channel string) string {
+	if channel == "" || strings.Contains(channel, "/") {
+		return channel
+	}
+
+	for _, risk := range snapRisks {
+		if channel == risk {
+			return "latest/" + risk
+		}
+	}
+
+	return channel + "/stable"
+}
+
+// SnapListCommand prints the snap list line of name, or missing when it is not installed.
+func SnapListCommand(name string) string {
+	return fmt.Sprintf(`if list=$(snap list %s 2>/dev/null); then printf '%%s\n' "$list"; else echo missing; fi`, ShellQuote(name))
+}
+
+// ParseSnapList parses the output of SnapListCommand. It returns nil when name is not installed.
+func ParseSnapList(output string, name string) (*Snap, error) {
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 1 && fields[0] == "missing" {
+			return nil, nil
+		}
+		// Name Version Rev Tracking Publisher Notes
+		if len(fields) < 6 || fields[0] != name {
+			continue
+		}
+
+		snap := &Snap{Name: name, Version: fields[1], Revision: fields[2]}
+		if fields[3] != "-" {
+			snap.Channel = fields[3]
+		}
+		for _, note := range strings.Split(fields[5], ",") {
+			if note == "classic" {
+				snap.Classic = true
+			}
+		}
+
+		return snap, nil
+	}
+
+	return nil, fmt.Errorf("unexpected snap list output: %s", output)
+}
+
+// SnapInstallCommand returns a command installing name from channel, or switching it to channel
+// when it is already installed. classic lifts the confinement of the snap.
+func SnapInstallCommand(name string, channel string, classic bool) string {
+	options := ""
+	if channel != "" {
+		options += " --channel=" + ShellQuote(channel)
+	}
+	if classic {
+		options += " --classic"
+	}
+
+	return fmt.Sprintf(
+		"if snap list %[1]s >/dev/null 2>&1; then snap refresh %[1]s%[2]s; else snap install %[1]s%[2]s; fi",
+		ShellQuote(name), options,
+	)
+}
+
+// SnapRemoveCommand returns a command removing name along with its data snapshots.
+func SnapRemoveCommand(name string) string {
+	return fmt.Sprintf("snap remove --purge %s", ShellQuote(name))
+}
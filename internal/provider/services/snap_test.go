@@ -0,0 +1,66 @@
+package services
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestParseSnapList(t *testing.T) {
+	output := "Welcome!\r\nName  Version  Rev    Tracking       Publisher   Notes\r\nlxd   5.21.1   28460  5.21/stable    canonical✓  -\r\n"
+	output += "aws-cli  2.15.40  1094  latest/stable  aws✓  disabled,classic\n"
+
+	tests := []struct {
+		name string
+		want *Snap
+	}{
+		{name: "lxd", want: &Snap{Name: "lxd", Version: "5.21.1", Revision: "28460", Channel: "5.21/stable"}},
+		{name: "aws-cli", want: &Snap{Name: "aws-cli", Version: "2.15.40", Revision: "1094", Channel: "latest/stable", Classic: true}},
+	}
+
+	for _, tt := range tests {
+		snap, err := ParseSnapList(output, tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *snap != *tt.want {
+			t.Errorf("expected %+v, got %+v", tt.want, snap)
+		}
+	}
+
+	if snap, err := ParseSnapList("missing\n", "lxd"); err != nil || snap != nil {
+		t.Errorf("expected a missing snap, got %+v, %v", snap, err)
+	}
+	if _, err := ParseSnapList("error: cannot communicate with server\n", "lxd"); err == nil {
+		t.Error("expected an error for an unexpected output")
+	}
+}
+
+func TestNormalizeSnapChannel(t *testing.T) {
+	for channel, want := range map[string]string{
+		"":                  "",
+		"edge":              "latest/edge",
+		"5.21":              "5.21/stable",
+		"1.30/candidate":    "1.30/candidate",
+		"latest/stable/fix": "latest/stable/fix",
+	} {
+		if got := NormalizeSnapChannel(channel); got != want {
+			t.Errorf("NormalizeSnapChannel(%q) = %q, want %q", channel, got, want)
+		}
+		if channel != "" && !SnapChannelRegexp.MatchString(channel) {
+			t.Errorf("%q should be a valid channel", channel)
+		}
+	}
+}
+
+func TestSnapCommands(t *testing.T) {
+	for _, command := range []string{
+		SnapListCommand("lxd"),
+		SnapInstallCommand("aws-cli", "v2/stable", true),
+		SnapInstallCommand("lxd", "", false),
+		SnapRemoveCommand("lxd"),
+	} {
+		if output, err := exec.Command("sh", "-n", "-c", command).CombinedOutput(); err != nil {
+			t.Errorf("invalid command %s: %s", command, output)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ListeningSocketsCommand lists the listening TCP and UDP sockets of a host with their process.
+const ListeningSocketsCommand = "ss -H -lntup"
+
+// ListeningSocket is a socket accepting connections or datagrams on a host.
+type ListeningSocket struct {
+	Protocol string
+	Address  string
+	Port     int64
+	Process  string
+	PID      int64
+}
+
+// ssProcessRegexp matches the first process of the users column of ss, e.g. users:(("sshd",pid=812,fd=3)).
+var ssProcessRegexp = regexp.MustCompile(`\("([^"]*)",pid=(\d+)`)
+
+// ParseListeningSockets parses the output of ListeningSocketsCommand. The process is only
+// known for sockets of processes the user can inspect, the command must run as root to see
+// all of them.
+func ParseListeningSockets(output string) ([]ListeningSocket, error) {
+	sockets := []ListeningSocket{}
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || (fields[0] != "tcp" && fields[0] != "udp") {
+			continue
+		}
+
+		address, port, err := splitSocketAddress(fields[4])
+		if err != nil {
+			return nil, err
+		}
+
+		socket := ListeningSocket{Protocol: fields[0], Address: address, Port: port}
+		if match := ssProcessRegexp.FindStringSubmatch(line); match != nil {
+			socket.Process = match[1]
+			socket.PID, _ = strconv.ParseInt(match[2], 10, 64)
+		}
+
+		sockets = append(sockets, socket)
+	}
+
+	return sockets, nil
+}
+
+// splitSocketAddress splits a local address of ss such as "[::]:443", "0.0.0.0:22" or
+// "127.0.0.53%lo:53" into its address, without interface, and port. A "*" port is 0.
+func splitSocketAddress(local string) (string, int64, error) {
+	separator := strings.LastIndex(local, ":")
+	if separator < 0 {
+		return "", 0, fmt.Errorf("unexpected socket address %q", local)
+	}
+
+	address := strings.Trim(local[:separator], "[]")
+	if zone := strings.Index(address, "%"); zone >= 0 {
+		address = address[:zone]
+	}
+
+	if local[separator+1:] == "*" {
+		return address, 0, nil
+	}
+
+	port, err := strconv.ParseInt(local[separator+1:], 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("unexpected socket port in %q", local)
+	}
+
+	return address, port, nil
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseListeningSockets(t *testing.T) {
+	output := "Welcome to host\n" +
+		`tcp   LISTEN 0      4096         0.0.0.0:22        0.0.0.0:*    users:(("sshd",pid=812,fd=3))` + "\n" +
+		`udp   UNCONN 0      0      127.0.0.53%lo:53        0.0.0.0:*    users:(("systemd-resolve",pid=600,fd=13))` + "\n" +
+		`tcp   LISTEN 0      511             [::]:443          [::]:*` + "\n"
+
+	sockets, err := ParseListeningSockets(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ListeningSocket{
+		{Protocol: "tcp", Address: "0.0.0.0", Port: 22, Process: "sshd", PID: 812},
+		{Protocol: "udp", Address: "127.0.0.53", Port: 53, Process: "systemd-resolve", PID: 600},
+		{Protocol: "tcp", Address: "::", Port: 443},
+	}
+	if !slices.Equal(sockets, want) {
+		t.Fatalf("unexpected sockets %+v", sockets)
+	}
+
+	if _, err := ParseListeningSockets("tcp LISTEN 0 128 0.0.0.0:ssh 0.0.0.0:*"); err == nil {
+		t.Fatal("expected an error for a named port")
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/crypto/ssh"
+)
+
+// handshakeLogger logs the steps of an SSH handshake so authentication failures can be
+// diagnosed from the Terraform logs. It does nothing unless enabled.
+type handshakeLogger struct {
+	ctx     context.Context
+	host    string
+	enabled bool
+}
+
+func (logger handshakeLogger) log(message string, fields map[string]any) {
+	if !logger.enabled {
+		return
+	}
+
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	fields["host"] = logger.host
+
+	tflog.Debug(logger.ctx, message, fields)
+}
+
+// offered logs the algorithms the client offers to the server during the key exchange.
+func (logger handshakeLogger) offered(conf *ssh.ClientConfig) {
+	algorithms := ssh.SupportedAlgorithms()
+	if len(conf.KeyExchanges) > 0 {
+		algorithms.KeyExchanges = conf.KeyExchanges
+	}
+	if len(conf.Ciphers) > 0 {
+		algorithms.Ciphers = conf.Ciphers
+	}
+	if len(conf.MACs) > 0 {
+		algorithms.MACs = conf.MACs
+	}
+
+	logger.log("ssh handshake started", map[string]any{
+		"user":           conf.User,
+		"client_version": conf.ClientVersion,
+		"kex":            strings.Join(algorithms.KeyExchanges, ","),
+		"ciphers":        strings.Join(algorithms.Ciphers, ","),
+		"macs":           strings.Join(algorithms.MACs, ","),
+	})
+}
+
+// hostKeyCallback wraps callback to log the negotiated host key and the server version.
+func (logger handshakeLogger) hostKeyCallback(callback ssh.HostKeyCallback) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+
+		fields := map[string]any{
+			"remote":          remote.String(),
+			"host_key_type":   key.Type(),
+			"host_key_sha256": ssh.FingerprintSHA256(key),
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+		logger.log("ssh host key received", fields)
+
+		return err
+	}
+}
+
+// bannerCallback logs the pre-authentication banner sent by the server.
+func (logger handshakeLogger) bannerCallback() ssh.BannerCallback {
+	return func(message string) error {
+		logger.log("ssh server banner", map[string]any{"banner": message})
+		return nil
+	}
+}
+
+// passwordAuth returns a password auth method logging each attempt.
+func (logger handshakeLogger) passwordAuth(password string) ssh.AuthMethod {
+	return ssh.PasswordCallback(func() (string, error) {
+		logger.log("ssh authentication attempt", map[string]any{"method": "password"})
+		return password, nil
+	})
+}
+
+// publicKeyAuth returns a public key auth method logging each offered key. The SSH client only
+// tries one public key method, so every key must be given to the same method.
+func (logger handshakeLogger) publicKeyAuth(signers ...ssh.Signer) ssh.AuthMethod {
+	return ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		for _, signer := range signers {
+			logger.log("ssh authentication attempt", map[string]any{
+				"method":     "publickey",
+				"key_type":   signer.PublicKey().Type(),
+				"key_sha256": ssh.FingerprintSHA256(signer.PublicKey()),
+			})
+		}
+		return signers, nil
+	})
+}
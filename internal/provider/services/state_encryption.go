@@ -0,0 +1,93 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// stateCipherPrefix marks the content encrypted by a StateCipher, so encrypted values are told
+// apart from plain ones and the format can evolve.
+const stateCipherPrefix = "remote-host:aes256gcm:"
+
+// StateCipher encrypts the content resources store in the Terraform state with AES-256-GCM,
+// for users whose state backend does not encrypt it at rest. The key never leaves the machine
+// running Terraform.
+type StateCipher struct {
+	aead cipher.AEAD
+}
+
+// NewStateCipher returns a cipher using key, the base64 encoding of 32 random bytes such as the
+// output of `openssl rand -base64 32`.
+func NewStateCipher(key string) (*StateCipher, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("the state encryption key is not valid base64: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("the state encryption key must be 32 bytes long, got %d", len(raw))
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StateCipher{aead: aead}, nil
+}
+
+// Seal encrypts plaintext under a random nonce.
+func (c *StateCipher) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), []byte(stateCipherPrefix))
+
+	return stateCipherPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value returned by Seal.
+func (c *StateCipher) Open(value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, stateCipherPrefix)
+	if !ok {
+		return "", errors.New("the value is not encrypted")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("the encrypted value is not valid base64: %w", err)
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("the encrypted value is truncated")
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, []byte(stateCipherPrefix))
+	if err != nil {
+		return "", errors.New("unable to decrypt the value, it was encrypted with another key or altered")
+	}
+
+	return string(plaintext), nil
+}
+
+// SealStable encrypts plaintext, returning previous as is when it already decrypts to
+// plaintext: a new nonce on every refresh would rewrite the state of unchanged content.
+// Values encrypted with another key are sealed again with the current one.
+func (c *StateCipher) SealStable(previous string, plaintext string) (string, error) {
+	if opened, err := c.Open(previous); err == nil && opened == plaintext {
+		return previous, nil
+	}
+
+	return c.Seal(plaintext)
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+const testStateKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+func TestStateCipher(t *testing.T) {
+	stateCipher, err := NewStateCipher(testStateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := stateCipher.Seal("password=s3cret\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, stateCipherPrefix) || strings.Contains(sealed, "s3cret") {
+		t.Fatalf("unexpected sealed value %q", sealed)
+	}
+
+	opened, err := stateCipher.Open(sealed)
+	if err != nil || opened != "password=s3cret\n" {
+		t.Errorf("expected the content back, got %q, %v", opened, err)
+	}
+
+	// Unchanged content keeps its ciphertext, changed content gets a new one.
+	if stable, err := stateCipher.SealStable(sealed, "password=s3cret\n"); err != nil || stable != sealed {
+		t.Errorf("expected the previous value to be kept, got %q, %v", stable, err)
+	}
+	if changed, err := stateCipher.SealStable(sealed, "password=other\n"); err != nil || changed == sealed {
+		t.Errorf("expected a new value for changed content, got %q, %v", changed, err)
+	}
+	if fresh, err := stateCipher.SealStable("password=s3cret\n", "password=s3cret\n"); err != nil || !strings.HasPrefix(fresh, stateCipherPrefix) {
+		t.Errorf("expected plain previous content to be encrypted, got %q, %v", fresh, err)
+	}
+
+	other, err := NewStateCipher("ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Open(sealed); err == nil {
+		t.Error("expected values encrypted with another key to be rejected")
+	}
+
+	tampered := sealed[:len(sealed)-4] + "AAA="
+	if _, err := stateCipher.Open(tampered); err == nil {
+		t.Error("expected altered values to be rejected")
+	}
+}
+
+func TestNewStateCipherInvalidKey(t *testing.T) {
+	for _, key := range []string{"", "not base64!", "c2hvcnQ="} {
+		if _, err := NewStateCipher(key); err == nil {
+			t.Errorf("expected key %q to be rejected", key)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// StructuredFormats lists the formats structured documents are written in.
+var StructuredFormats = []string{"json", "yaml"}
+
+// Structured documents are made of nil, bool, string, json.Number, []any and map[string]any
+// values, whatever the format they are read from.
+
+// RenderStructured serializes value deterministically in format: keys are sorted and the
+// document is indented with two spaces and ends with a newline.
+func RenderStructured(format string, value any) (string, error) {
+	switch format {
+	case "json":
+		var buffer bytes.Buffer
+		encoder := json.NewEncoder(&buffer)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(value); err != nil {
+			return "", err
+		}
+
+		return buffer.String(), nil
+	case "yaml":
+		return RenderYAML(value)
+	default:
+		return "", fmt.Errorf("unsupported format %q, expected one of %s", format, strings.Join(StructuredFormats, ", "))
+	}
+}
+
+// ParseStructured parses a document written in format.
+func ParseStructured(format string, content string) (any, error) {
+	switch format {
+	case "json":
+		decoder := json.NewDecoder(strings.NewReader(content))
+		decoder.UseNumber()
+
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+			return nil, errors.New("unexpected content after the JSON document")
+		}
+
+		return value, nil
+	case "yaml":
+		return ParseYAML(content)
+	default:
+		return nil, fmt.Errorf("unsupported format %q, expected one of %s", format, strings.Join(StructuredFormats, ", "))
+	}
+}
+
+// StructuredEqual reports whether two documents hold the same data, ignoring the order of the
+// keys and the spelling of the numbers, e.g. 1e3 and 1000.
+func StructuredEqual(a any, b any) bool {
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		b, ok := b.(bool)
+		return ok && a == b
+	case string:
+		b, ok := b.(string)
+		return ok && a == b
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+
+		x, okA := new(big.Rat).SetString(a.String())
+		y, okB := new(big.Rat).SetString(b.String())
+
+		return okA && okB && x.Cmp(y) == 0
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !StructuredEqual(a[i], b[i]) {
+				return false
+			}
+		}
+
+		return true
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, ok := b[key]
+			if !ok || !StructuredEqual(value, other) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return false
+	}
+}
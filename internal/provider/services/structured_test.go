@@ -0,0 +1,187 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// testDocument exercises every kind of value and the strings needing quotes.
+var testDocument = map[string]any{
+	"name":    "app",
+	"port":    json.Number("8080"),
+	"ratio":   json.Number("0.25"),
+	"enabled": true,
+	"backup":  nil,
+	"tags":    []any{"web", "yes", "1.0", "true", "null", ""},
+	"servers": []any{
+		map[string]any{"host": "10.0.0.1", "weight": json.Number("1")},
+		map[string]any{"host": "web-2.example.com", "weight": json.Number("2"), "labels": map[string]any{}},
+	},
+	"matrix":         []any{[]any{json.Number("1"), json.Number("2")}, []any{}},
+	"message":        "line 1\nline 2: \"quoted\" # not a comment",
+	"with space key": "<b>&</b>",
+	"nested":         map[string]any{"deep": map[string]any{"value": "é ünïcode 🙂"}},
+}
+
+func TestRenderStructured(t *testing.T) {
+	rendered, err := RenderStructured("yaml", testDocument)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `backup: null
+enabled: true
+matrix:
+  - - 1
+    - 2
+  - []
+message: "line 1\nline 2: \"quoted\" # not a comment"
+name: app
+nested:
+  deep:
+    value: "é ünïcode 🙂"
+port: 8080
+ratio: 0.25
+servers:
+  - host: "10.0.0.1"
+    weight: 1
+  - host: web-2.example.com
+    labels: {}
+    weight: 2
+tags:
+  - web
+  - "yes"
+  - "1.0"
+  - "true"
+  - "null"
+  - ""
+"with space key": "<b>&</b>"
+`
+	if rendered != want {
+		t.Errorf("unexpected YAML document:\n%s", rendered)
+	}
+
+	rendered, err = RenderStructured("json", map[string]any{"b": "<b>", "a": []any{json.Number("1")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"a\": [\n    1\n  ],\n  \"b\": \"<b>\"\n}\n"; rendered != want {
+		t.Errorf("unexpected JSON document:\n%s", rendered)
+	}
+}
+
+func TestParseStructuredRoundTrip(t *testing.T) {
+	for _, format := range StructuredFormats {
+		rendered, err := RenderStructured(format, testDocument)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := ParseStructured(format, rendered)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !StructuredEqual(testDocument, parsed) {
+			t.Errorf("%s: expected the document to read back, got %#v", format, parsed)
+		}
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	// The same document as testDocument, written by hand with another layout.
+	document := `---
+# Application settings
+name: 'app'   # quoted
+port: 0x1F90
+ratio: .25
+enabled: True
+backup: ~
+with space key: <b>&</b>
+tags: [web, "yes", '1.0', "true", "null", ""]
+servers:
+- {host: "10.0.0.1", weight: 1.0}
+- host: web-2.example.com
+  weight: 2e0
+  labels: {}
+matrix:
+  -
+    - 1
+    - 2
+  - []
+message: "line 1\nline 2: \"quoted\" # not a comment"
+nested: {deep: {value: "\u00e9 \u00fcn\u00efcode \ud83d\ude42"}}
+...
+`
+	parsed, err := ParseYAML(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !StructuredEqual(testDocument, parsed) {
+		t.Errorf("expected the hand written document to match, got %#v", parsed)
+	}
+
+	blocks := `literal: |
+  first
+    indented
+
+  last
+folded: >-
+  joined
+  lines
+
+  paragraph
+kept: |+
+  text
+
+stripped: |-
+  text
+`
+	parsed, err = ParseYAML(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"literal":  "first\n  indented\n\nlast\n",
+		"folded":   "joined lines\nparagraph",
+		"kept":     "text\n\n",
+		"stripped": "text",
+	}
+	if !StructuredEqual(want, parsed) {
+		t.Errorf("unexpected block scalars %#v", parsed)
+	}
+
+	for _, invalid := range []string{
+		"a: &anchor 1\nb: *anchor\n",
+		"a: 1\n---\nb: 2\n",
+		"a: 1\na: 2\n",
+		"a:\n\t- 1\n",
+		"a: [1,\n  2]\n",
+		"a: 1\n  b: 2\n",
+	} {
+		if _, err := ParseYAML(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestStructuredEqual(t *testing.T) {
+	for _, test := range []struct {
+		a, b any
+		want bool
+	}{
+		{json.Number("1000"), json.Number("1e3"), true},
+		{json.Number("0.1"), json.Number("0.10"), true},
+		{json.Number("1"), "1", false},
+		{map[string]any{"a": nil}, map[string]any{}, false},
+		{[]any{"a", "b"}, []any{"b", "a"}, false},
+		{map[string]any{"a": []any{true}}, map[string]any{"a": []any{true}}, true},
+	} {
+		if got := StructuredEqual(test.a, test.b); got != test.want {
+			t.Errorf("StructuredEqual(%#v, %#v) = %t, want %t", test.a, test.b, got, test.want)
+		}
+	}
+
+	if _, err := ParseStructured("json", `{"a": 1} {"b": 2}`); err == nil {
+		t.Error("expected an error for content after the JSON document")
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	totpPeriod = 30
+	totpDigits = 6
+)
+
+// TOTP returns the RFC 6238 code of the base32 encoded secret at time now, as generated by
+// authenticator apps: HMAC-SHA1, 30 second steps and 6 digits.
+func TOTP(secret string, now time.Time) (string, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(secret), " ", ""))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret, expected base32: %w", err)
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(now.Unix()/totpPeriod))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, code%1000000), nil
+}
+
+// isOneTimePasswordPrompt reports whether the lowercased prompt asks for a one time password,
+// e.g. "Verification code:" of google-authenticator or "One-time password (OATH) for `alice':"
+// of pam_oath. It is checked before the password prompts, which these often mention.
+func isOneTimePasswordPrompt(prompt string) bool {
+	for _, word := range []string{"verification", "code", "otp", "token", "one-time", "oath"} {
+		if strings.Contains(prompt, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// challengeAnswer returns the answer to the keyboard-interactive question asked at now.
+func challengeAnswer(question string, password string, totpSecret string, now time.Time) (string, error) {
+	prompt := strings.ToLower(question)
+
+	switch {
+	case isOneTimePasswordPrompt(prompt):
+		return TOTP(totpSecret, now)
+	case strings.Contains(prompt, "password"):
+		return password, nil
+	default:
+		return "", fmt.Errorf("unable to answer the keyboard-interactive prompt %q", question)
+	}
+}
+
+// keyboardInteractive answers PAM challenges: one time password prompts get the current
+// TOTP code and password prompts the password.
+func keyboardInteractive(password string, totpSecret string, logger handshakeLogger) ssh.AuthMethod {
+	return ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		for i, question := range questions {
+			logger.log("ssh keyboard-interactive challenge", map[string]any{"prompt": question})
+
+			answer, err := challengeAnswer(question, password, totpSecret, time.Now())
+			if err != nil {
+				return nil, err
+			}
+			answers[i] = answer
+		}
+
+		return answers, nil
+	})
+}
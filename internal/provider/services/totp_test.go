@@ -0,0 +1,70 @@
+package services
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 appendix B test vectors for SHA1, truncated to 6 digits.
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{20000000000, "353130"},
+	}
+
+	for _, test := range tests {
+		got, err := TOTP(secret, time.Unix(test.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("TOTP at %d = %s, want %s", test.unix, got, test.want)
+		}
+	}
+
+	// Authenticator apps show secrets in lower case groups without padding.
+	if got, _ := TOTP("gezd gnbv gy3t qojq gezd gnbv gy3t qojq", time.Unix(59, 0)); got != "287082" {
+		t.Errorf("unexpected code %s for a formatted secret", got)
+	}
+
+	if _, err := TOTP("not base32!", time.Now()); err == nil {
+		t.Error("expected an error for an invalid secret")
+	}
+}
+
+func TestChallengeAnswer(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(59, 0)
+
+	tests := []struct {
+		question string
+		want     string
+	}{
+		{"Password: ", "hunter2"},
+		{"alice@db-1's password: ", "hunter2"},
+		{"Verification code: ", "287082"},
+		{"One-time password (OATH) for `alice': ", "287082"},
+		{"OATH password: ", "287082"},
+		{"Enter OTP: ", "287082"},
+		{"Duo token: ", "287082"},
+	}
+
+	for _, test := range tests {
+		got, err := challengeAnswer(test.question, "hunter2", secret, now)
+		if err != nil || got != test.want {
+			t.Errorf("challengeAnswer(%q) = %q, %v, want %q", test.question, got, err, test.want)
+		}
+	}
+
+	if _, err := challengeAnswer("Favorite color? ", "hunter2", secret, now); err == nil {
+		t.Error("expected an error for an unknown prompt")
+	}
+}
@@ -0,0 +1,445 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"remote-provider/internal/provider/servers"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to the handshake key to compute the accept header, see RFC 6455.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxProxyStderr bounds the standard error of a proxy command kept for error messages.
+const maxProxyStderr = 4096
+
+// dialHost opens the stream the SSH connection to host runs over: the standard IO of its
+// proxy command, a WebSocket tunnel, or a TCP connection to its address.
+func dialHost(ctx context.Context, dialer *net.Dialer, host *servers.Server) (net.Conn, error) {
+	switch {
+	case host.ProxyCommand != "":
+		return dialProxyCommand(expandHostTokens(host.ProxyCommand, host, ShellQuote))
+	case host.WebSocketURL != "":
+		return dialWebSocket(ctx, dialer, expandHostTokens(host.WebSocketURL, host, url.QueryEscape))
+	}
+
+	return dialContext(ctx, dialer, host.GetFullAddress())
+}
+
+// expandHostTokens replaces the OpenSSH style %h, %p and %r tokens of template by the address,
+// port and user of host, escaped with escape, and %% by a percent sign.
+func expandHostTokens(template string, host *servers.Server, escape func(string) string) string {
+	var expanded strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] != '%' || i+1 == len(template) {
+			expanded.WriteByte(template[i])
+			continue
+		}
+
+		i++
+		switch template[i] {
+		case 'h':
+			expanded.WriteString(escape(host.Address))
+		case 'p':
+			expanded.WriteString(escape(strconv.Itoa(int(host.Port))))
+		case 'r':
+			expanded.WriteString(escape(host.User))
+		case '%':
+			expanded.WriteByte('%')
+		default:
+			expanded.WriteByte('%')
+			expanded.WriteByte(template[i])
+		}
+	}
+
+	return expanded.String()
+}
+
+// proxyAddr is the address of both ends of a connection without network address.
+type proxyAddr string
+
+func (a proxyAddr) Network() string { return "proxy" }
+func (a proxyAddr) String() string  { return string(a) }
+
+// limitedBuffer keeps the first bytes written to it and discards the rest.
+type limitedBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if room := maxProxyStderr - b.buffer.Len(); room > 0 {
+		b.buffer.Write(p[:min(room, len(p))])
+	}
+
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return strings.TrimSpace(b.buffer.String())
+}
+
+// proxyCommandConn is a connection over the standard input and output of a local command,
+// like the ProxyCommand option of OpenSSH.
+type proxyCommandConn struct {
+	command *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  io.ReadCloser
+	stderr  *limitedBuffer
+	// stderrDone is closed once the error output of the command has been read.
+	stderrDone chan struct{}
+
+	mutex sync.Mutex
+	timer *time.Timer
+}
+
+// dialProxyCommand starts command with sh and returns a connection over its standard IO.
+func dialProxyCommand(command string) (net.Conn, error) {
+	conn := &proxyCommandConn{command: exec.Command("sh", "-c", command), stderr: &limitedBuffer{}, stderrDone: make(chan struct{})}
+
+	var err error
+	if conn.stdin, err = conn.command.StdinPipe(); err != nil {
+		return nil, err
+	}
+	if conn.stdout, err = conn.command.StdoutPipe(); err != nil {
+		return nil, err
+	}
+	stderr, err := conn.command.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.command.Start(); err != nil {
+		return nil, fmt.Errorf("starting proxy command: %w", err)
+	}
+
+	go func() {
+		defer close(conn.stderrDone)
+		_, _ = io.Copy(conn.stderr, stderr)
+	}()
+
+	return conn, nil
+}
+
+func (c *proxyCommandConn) Read(b []byte) (int, error) {
+	n, err := c.stdout.Read(b)
+	if errors.Is(err, io.EOF) {
+		// The error output may still be in flight when the command exits.
+		select {
+		case <-c.stderrDone:
+		case <-time.After(time.Second):
+		}
+
+		if stderr := c.stderr.String(); stderr != "" {
+			return n, fmt.Errorf("%w: proxy command: %s", io.EOF, stderr)
+		}
+	}
+
+	return n, err
+}
+
+func (c *proxyCommandConn) Write(b []byte) (int, error) {
+	return c.stdin.Write(b)
+}
+
+// Close ends the command, which is killed unless it exits on its own once its input is closed.
+func (c *proxyCommandConn) Close() error {
+	_ = c.SetDeadline(time.Time{})
+	_ = c.stdin.Close()
+
+	done := make(chan struct{})
+	go func() {
+		_ = c.command.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		_ = c.command.Process.Kill()
+		<-done
+	}
+
+	return nil
+}
+
+func (c *proxyCommandConn) LocalAddr() net.Addr  { return proxyAddr("proxy-command") }
+func (c *proxyCommandConn) RemoteAddr() net.Addr { return proxyAddr("proxy-command") }
+
+// SetDeadline kills the command when t passes, pipes have no deadline of their own.
+func (c *proxyCommandConn) SetDeadline(t time.Time) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if !t.IsZero() {
+		c.timer = time.AfterFunc(time.Until(t), func() { _ = c.command.Process.Kill() })
+	}
+
+	return nil
+}
+
+func (c *proxyCommandConn) SetReadDeadline(t time.Time) error  { return c.SetDeadline(t) }
+func (c *proxyCommandConn) SetWriteDeadline(t time.Time) error { return c.SetDeadline(t) }
+
+// websocketConn carries a byte stream in the binary messages of a WebSocket connection.
+type websocketConn struct {
+	net.Conn
+	reader *bufio.Reader
+
+	writeMutex sync.Mutex
+	// remaining is the number of payload bytes left in the frame being read.
+	remaining uint64
+	mask      []byte
+	offset    int
+}
+
+// dialWebSocket opens a WebSocket connection to rawURL, a ws:// or wss:// URL, through the
+// proxy configured in the environment if any. Credentials in the URL are sent with basic auth.
+func dialWebSocket(ctx context.Context, dialer *net.Dialer, rawURL string) (net.Conn, error) {
+	endpoint, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebSocket URL: %w", err)
+	}
+
+	port := endpoint.Port()
+	switch {
+	case endpoint.Scheme != "ws" && endpoint.Scheme != "wss":
+		return nil, fmt.Errorf("unsupported WebSocket URL scheme %q, expected ws or wss", endpoint.Scheme)
+	case port == "" && endpoint.Scheme == "wss":
+		port = "443"
+	case port == "":
+		port = "80"
+	}
+
+	conn, err := dialContext(ctx, dialer, net.JoinHostPort(endpoint.Hostname(), port))
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if endpoint.Scheme == "wss" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: endpoint.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake with %s: %w", endpoint.Host, err)
+		}
+		conn = tlsConn
+	}
+
+	wsConn, err := websocketHandshake(conn, endpoint)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("WebSocket handshake with %s: %w", endpoint.Host, err)
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+
+	return wsConn, nil
+}
+
+// websocketHandshake upgrades conn to a WebSocket connection to endpoint.
+func websocketHandshake(conn net.Conn, endpoint *url.URL) (*websocketConn, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	request := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Path: endpoint.Path, RawPath: endpoint.RawPath, RawQuery: endpoint.RawQuery},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Host:       endpoint.Host,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-WebSocket-Key":     {key},
+			"Sec-WebSocket-Version": {"13"},
+		},
+	}
+	if request.URL.Path == "" {
+		request.URL.Path = "/"
+	}
+	if endpoint.User != nil {
+		password, _ := endpoint.User.Password()
+		request.SetBasicAuth(endpoint.User.Username(), password)
+	}
+
+	if err := request.Write(conn); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("unexpected response %s", response.Status)
+	}
+	if response.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		return nil, errors.New("invalid Sec-WebSocket-Accept header")
+	}
+
+	return &websocketConn{Conn: conn, reader: reader}, nil
+}
+
+// websocketAccept returns the Sec-WebSocket-Accept header expected for key.
+func websocketAccept(key string) string {
+	digest := sha1.Sum([]byte(key + websocketGUID))
+
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+// writeFrame sends payload in a single masked frame of type opcode, as clients must.
+func (c *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	frame := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = binary.BigEndian.AppendUint16(append(frame, 0x80|126), uint16(len(payload)))
+	default:
+		frame = binary.BigEndian.AppendUint64(append(frame, 0x80|127), uint64(len(payload)))
+	}
+
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	_, err := c.Conn.Write(frame)
+	return err
+}
+
+func (c *websocketConn) Write(b []byte) (int, error) {
+	if err := c.writeFrame(0x2, b); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+func (c *websocketConn) Read(b []byte) (int, error) {
+	for c.remaining == 0 {
+		if err := c.nextFrame(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := c.reader.Read(b[:min(uint64(len(b)), c.remaining)])
+	for i := range n {
+		if c.mask != nil {
+			b[i] ^= c.mask[c.offset%4]
+		}
+		c.offset++
+	}
+	c.remaining -= uint64(n)
+
+	return n, err
+}
+
+// nextFrame reads the header of the next frame, answering the control frames on the way.
+func (c *websocketConn) nextFrame() error {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return err
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(c.reader, extended); err != nil {
+			return err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err := io.ReadFull(c.reader, extended); err != nil {
+			return err
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+
+	c.mask, c.offset = nil, 0
+	if header[1]&0x80 != 0 {
+		c.mask = make([]byte, 4)
+		if _, err := io.ReadFull(c.reader, c.mask); err != nil {
+			return err
+		}
+	}
+
+	switch opcode := header[0] & 0x0F; opcode {
+	case 0x0, 0x1, 0x2:
+		c.remaining = length
+		return nil
+	case 0x8:
+		return io.EOF
+	case 0x9, 0xA:
+		if length > 125 {
+			return errors.New("oversized WebSocket control frame")
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			return err
+		}
+		for i := range payload {
+			if c.mask != nil {
+				payload[i] ^= c.mask[i%4]
+			}
+		}
+		if opcode == 0x9 {
+			return c.writeFrame(0xA, payload)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unexpected WebSocket opcode %d", opcode)
+	}
+}
+
+func (c *websocketConn) Close() error {
+	_ = c.writeFrame(0x8, []byte{0x03, 0xE8})
+
+	return c.Conn.Close()
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"remote-provider/internal/provider/servers"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandHostTokens(t *testing.T) {
+	host := &servers.Server{Address: "10.0.0.1", Port: 22, User: "o'brien"}
+
+	tests := []struct {
+		template string
+		escape   func(string) string
+		want     string
+	}{
+		{template: "ssh -W %h:%p bastion", escape: ShellQuote, want: `ssh -W '10.0.0.1':'22' bastion`},
+		{template: "connect -u %r 100%% %x", escape: ShellQuote, want: `connect -u 'o'"'"'brien' 100% %x`},
+		{template: "wss://gateway/ssh?target=%h&user=%r", escape: url.QueryEscape, want: "wss://gateway/ssh?target=10.0.0.1&user=o%27brien"},
+		{template: "trailing %", escape: ShellQuote, want: "trailing %"},
+	}
+
+	for _, tt := range tests {
+		if got := expandHostTokens(tt.template, host, tt.escape); got != tt.want {
+			t.Errorf("expandHostTokens(%q) = %s, want %s", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestProxyCommandConn(t *testing.T) {
+	conn, err := dialProxyCommand("cat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Write([]byte("SSH-2.0-test\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "SSH-2.0-test\r\n" {
+		t.Errorf("unexpected echo %q: %v", line, err)
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err = dialProxyCommand("echo 'bastion: connection refused' >&2; exit 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, err = conn.Read(make([]byte, 1))
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("expected the proxy command error, got %v", err)
+	}
+}
+
+// echoWebSocket answers the WebSocket handshake and echoes the binary frames it receives,
+// after sending a ping the client must answer.
+func echoWebSocket(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("response writer cannot be hijacked")
+			return
+		}
+		conn, buffer, err := hijacker.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		_, _ = buffer.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + websocketAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		_, _ = buffer.Write([]byte{0x89, 0x02, 'h', 'i'})
+		_ = buffer.Flush()
+
+		for {
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(buffer, header); err != nil {
+				return
+			}
+
+			length := int(header[1] & 0x7F)
+			if length == 126 {
+				extended := make([]byte, 2)
+				if _, err := io.ReadFull(buffer, extended); err != nil {
+					return
+				}
+				length = int(binary.BigEndian.Uint16(extended))
+			}
+
+			mask := make([]byte, 4)
+			payload := make([]byte, length)
+			if _, err := io.ReadFull(buffer, mask); err != nil {
+				return
+			}
+			if _, err := io.ReadFull(buffer, payload); err != nil {
+				return
+			}
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+
+			switch header[0] & 0x0F {
+			case 0x2:
+				frame := append([]byte{0x82, 126}, binary.BigEndian.AppendUint16(nil, uint16(length))...)
+				_, _ = buffer.Write(append(frame, payload...))
+				_ = buffer.Flush()
+			case 0xA:
+				if string(payload) != "hi" {
+					t.Errorf("unexpected pong %q", payload)
+				}
+			case 0x8:
+				return
+			}
+		}
+	}
+}
+
+func TestWebSocketConn(t *testing.T) {
+	server := httptest.NewServer(echoWebSocket(t))
+	defer server.Close()
+
+	endpoint := "ws://admin:secret@" + strings.TrimPrefix(server.URL, "http://") + "/ssh?target=10.0.0.1"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := dialWebSocket(ctx, &net.Dialer{}, endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	message := strings.Repeat("SSH-2.0-test ", 100)
+	if _, err := conn.Write([]byte(message)); err != nil {
+		t.Fatal(err)
+	}
+
+	echoed := make([]byte, len(message))
+	if _, err := io.ReadFull(conn, echoed); err != nil {
+		t.Fatal(err)
+	}
+	if string(echoed) != message {
+		t.Errorf("unexpected echo %q", echoed)
+	}
+
+	_, err = dialWebSocket(ctx, &net.Dialer{}, strings.Replace(endpoint, "secret", "wrong", 1))
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected the handshake to fail, got %v", err)
+	}
+}
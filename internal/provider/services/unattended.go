@@ -0,0 +1,274 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// AptUnattendedUpgradesPath sorts after the 50unattended-upgrades and 20auto-upgrades files
+	// of the Debian package, so its settings take precedence.
+	AptUnattendedUpgradesPath = "/etc/apt/apt.conf.d/52remote-host-unattended-upgrades"
+	// DnfAutomaticPath is the configuration of dnf-automatic, which has no drop-in directory.
+	DnfAutomaticPath = "/etc/dnf/automatic.conf"
+	// DnfAutomaticTimer is the systemd timer running dnf-automatic.
+	DnfAutomaticTimer = "dnf-automatic.timer"
+)
+
+// UnattendedUpgradesManagerCommand prints the automatic upgrades tool of the host: apt for
+// unattended-upgrades, dnf for dnf-automatic or none.
+const UnattendedUpgradesManagerCommand = `if command -v apt-get >/dev/null 2>&1; then echo apt; ` +
+	`elif command -v dnf >/dev/null 2>&1; then echo dnf; else echo none; fi`
+
+// managedHeader starts the files rendered by the provider.
+const managedHeader = "Managed by Terraform, local changes are overwritten."
+
+// RebootTimeRegexp matches the HH:MM reboot times understood by both tools.
+var RebootTimeRegexp = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+// UnattendedUpgrades is the automatic upgrades configuration of a host.
+type UnattendedUpgrades struct {
+	// SecurityOnly restricts the upgrades to security fixes.
+	SecurityOnly bool
+	// Origins are the unattended-upgrades origin patterns, replacing the defaults derived from
+	// SecurityOnly when set. dnf-automatic has no equivalent.
+	Origins []string
+	// Blacklist lists the packages never upgraded automatically.
+	Blacklist []string
+	// AutomaticReboot reboots the host when an upgrade requires it, at RebootTime when set.
+	AutomaticReboot bool
+	RebootTime      string
+	// Mail receives the upgrade reports when set.
+	Mail string
+}
+
+// DefaultOrigins returns the origin patterns of the distribution security updates, and of its
+// regular updates unless securityOnly is set.
+func DefaultOrigins(securityOnly bool) []string {
+	origins := []string{"origin=${distro_id},codename=${distro_codename}-security"}
+	if !securityOnly {
+		origins = append(origins,
+			"origin=${distro_id},codename=${distro_codename}",
+			"origin=${distro_id},codename=${distro_codename}-updates",
+		)
+	}
+
+	return origins
+}
+
+// check rejects the values that cannot be written to the configuration files.
+func (c UnattendedUpgrades) check() error {
+	values := append(append([]string{c.RebootTime, c.Mail}, c.Origins...), c.Blacklist...)
+	for _, value := range values {
+		if strings.ContainsAny(value, "\"\n\r") {
+			return fmt.Errorf("%q cannot contain quotes or line breaks", value)
+		}
+	}
+	for _, name := range c.Blacklist {
+		if strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("package name %q cannot contain spaces", name)
+		}
+	}
+	if c.RebootTime != "" && !RebootTimeRegexp.MatchString(c.RebootTime) {
+		return fmt.Errorf("reboot time %q is not an HH:MM time", c.RebootTime)
+	}
+
+	return nil
+}
+
+// RenderApt renders the configuration as an apt.conf file for unattended-upgrades. The lists
+// are cleared first, so they replace the ones of the Debian package instead of extending them.
+func (c UnattendedUpgrades) RenderApt() (string, error) {
+	if err := c.check(); err != nil {
+		return "", err
+	}
+
+	origins := c.Origins
+	if len(origins) == 0 {
+		origins = DefaultOrigins(c.SecurityOnly)
+	}
+
+	var content strings.Builder
+	content.WriteString("// " + managedHeader + "\n")
+	content.WriteString("APT::Periodic::Update-Package-Lists \"1\";\n")
+	content.WriteString("APT::Periodic::Unattended-Upgrade \"1\";\n")
+
+	content.WriteString("#clear Unattended-Upgrade::Allowed-Origins;\n")
+	content.WriteString("#clear Unattended-Upgrade::Origins-Pattern;\n")
+	content.WriteString("Unattended-Upgrade::Origins-Pattern {\n")
+	for _, origin := range origins {
+		content.WriteString("\t\"" + origin + "\";\n")
+	}
+	content.WriteString("};\n")
+
+	content.WriteString("#clear Unattended-Upgrade::Package-Blacklist;\n")
+	content.WriteString("Unattended-Upgrade::Package-Blacklist {\n")
+	for _, name := range c.Blacklist {
+		content.WriteString("\t\"" + name + "\";\n")
+	}
+	content.WriteString("};\n")
+
+	fmt.Fprintf(&content, "Unattended-Upgrade::Automatic-Reboot \"%t\";\n", c.AutomaticReboot)
+	if c.RebootTime != "" {
+		content.WriteString("Unattended-Upgrade::Automatic-Reboot-Time \"" + c.RebootTime + "\";\n")
+	}
+	if c.Mail != "" {
+		content.WriteString("Unattended-Upgrade::Mail \"" + c.Mail + "\";\n")
+	}
+
+	return content.String(), nil
+}
+
+// RenderDnf renders the configuration as the automatic.conf file of dnf-automatic.
+func (c UnattendedUpgrades) RenderDnf() (string, error) {
+	if err := c.check(); err != nil {
+		return "", err
+	}
+
+	upgradeType := "default"
+	if c.SecurityOnly {
+		upgradeType = "security"
+	}
+
+	reboot := "never"
+	if c.AutomaticReboot {
+		reboot = "when-needed"
+	}
+
+	var content strings.Builder
+	content.WriteString("# " + managedHeader + "\n")
+	content.WriteString("[commands]\n")
+	content.WriteString("upgrade_type = " + upgradeType + "\n")
+	content.WriteString("download_updates = yes\n")
+	content.WriteString("apply_updates = yes\n")
+	content.WriteString("reboot = " + reboot + "\n")
+	if c.RebootTime != "" {
+		content.WriteString("reboot_command = \"shutdown -r " + c.RebootTime + " 'Rebooting after applying package updates'\"\n")
+	}
+
+	content.WriteString("\n[emitters]\n")
+	if c.Mail != "" {
+		content.WriteString("emit_via = email\n\n[email]\nemail_from = root\nemail_to = " + c.Mail + "\nemail_host = localhost\n")
+	} else {
+		content.WriteString("emit_via = stdio\n")
+	}
+
+	if len(c.Blacklist) > 0 {
+		content.WriteString("\n[base]\nexcludepkgs = " + strings.Join(c.Blacklist, " ") + "\n")
+	}
+
+	return content.String(), nil
+}
+
+// aptValueRegexp matches a quoted apt.conf value.
+var aptValueRegexp = regexp.MustCompile(`"([^"]*)"`)
+
+// ParseAptUnattendedUpgrades reads back a file rendered by RenderApt. Origins matching the
+// defaults of a security setting are reported as unset along with that setting.
+func ParseAptUnattendedUpgrades(content string) UnattendedUpgrades {
+	var config UnattendedUpgrades
+	var list *[]string
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		value := ""
+		if match := aptValueRegexp.FindStringSubmatch(line); match != nil {
+			value = match[1]
+		}
+
+		switch {
+		case strings.HasPrefix(line, "Unattended-Upgrade::Origins-Pattern {"):
+			list = &config.Origins
+		case strings.HasPrefix(line, "Unattended-Upgrade::Package-Blacklist {"):
+			list = &config.Blacklist
+		case strings.HasPrefix(line, "};"):
+			list = nil
+		case list != nil && value != "":
+			*list = append(*list, value)
+		case strings.HasPrefix(line, "Unattended-Upgrade::Automatic-Reboot "):
+			config.AutomaticReboot = value == "true"
+		case strings.HasPrefix(line, "Unattended-Upgrade::Automatic-Reboot-Time "):
+			config.RebootTime = value
+		case strings.HasPrefix(line, "Unattended-Upgrade::Mail "):
+			config.Mail = value
+		}
+	}
+
+	// SecurityOnly is only known from the default origins, custom ones take its place.
+	for _, securityOnly := range []bool{true, false} {
+		if slices.Equal(config.Origins, DefaultOrigins(securityOnly)) {
+			config.SecurityOnly = securityOnly
+			config.Origins = nil
+		}
+	}
+
+	return config
+}
+
+// ParseDnfAutomatic reads back the settings of an automatic.conf file.
+func ParseDnfAutomatic(content string) UnattendedUpgrades {
+	var config UnattendedUpgrades
+	section := ""
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.Trim(line, "[]")
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch section + "." + key {
+		case "commands.upgrade_type":
+			config.SecurityOnly = value == "security"
+		case "commands.reboot":
+			config.AutomaticReboot = value == "when-needed" || value == "when-changed"
+		case "commands.reboot_command":
+			if fields := strings.Fields(strings.Trim(value, `"`)); len(fields) >= 3 && fields[0] == "shutdown" && RebootTimeRegexp.MatchString(fields[2]) {
+				config.RebootTime = fields[2]
+			}
+		case "email.email_to":
+			config.Mail = value
+		case "base.excludepkgs", "base.exclude":
+			config.Blacklist = strings.Fields(strings.ReplaceAll(value, ",", " "))
+		}
+	}
+
+	return config
+}
+
+// Effective returns the settings used by the tool of manager: dnf-automatic has no origins, and
+// custom origins replace the security setting of unattended-upgrades.
+func (c UnattendedUpgrades) Effective(manager string) UnattendedUpgrades {
+	if manager == "dnf" {
+		c.Origins = nil
+	} else if len(c.Origins) > 0 {
+		c.SecurityOnly = false
+	}
+
+	return c
+}
+
+// Equal reports whether both configurations have the same settings.
+func (c UnattendedUpgrades) Equal(other UnattendedUpgrades) bool {
+	return c.SecurityOnly == other.SecurityOnly && slices.Equal(c.Origins, other.Origins) &&
+		slices.Equal(c.Blacklist, other.Blacklist) && c.AutomaticReboot == other.AutomaticReboot &&
+		c.RebootTime == other.RebootTime && c.Mail == other.Mail
+}
+
+// ValidateConfigCommand returns a command failing when the configuration file at path cannot be
+// parsed by the tool of manager.
+func ValidateConfigCommand(manager string, path string) string {
+	if manager == "apt" {
+		return fmt.Sprintf("apt-config -c %s dump >/dev/null", ShellQuote(path))
+	}
+
+	return fmt.Sprintf(`python3 -c 'import configparser, sys; configparser.ConfigParser().read_file(open(sys.argv[1]))' %s`, ShellQuote(path))
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestUnattendedUpgradesRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		manager string
+		config  UnattendedUpgrades
+	}{
+		{name: "apt defaults", manager: "apt", config: UnattendedUpgrades{SecurityOnly: true}},
+		{name: "apt full", manager: "apt", config: UnattendedUpgrades{
+			Blacklist: []string{"docker-ce", "linux-image-.*"}, AutomaticReboot: true, RebootTime: "02:30", Mail: "ops@example.com",
+		}},
+		{name: "apt origins", manager: "apt", config: UnattendedUpgrades{
+			SecurityOnly: true, Origins: []string{"origin=Docker,label=Docker CE", "site=apt.example.com"},
+		}},
+		{name: "dnf security", manager: "dnf", config: UnattendedUpgrades{
+			SecurityOnly: true, Origins: []string{"ignored"}, Blacklist: []string{"kernel*"}, AutomaticReboot: true, RebootTime: "04:00",
+		}},
+		{name: "dnf mail", manager: "dnf", config: UnattendedUpgrades{Mail: "ops@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			render, parse := tt.config.RenderApt, ParseAptUnattendedUpgrades
+			if tt.manager == "dnf" {
+				render, parse = tt.config.RenderDnf, ParseDnfAutomatic
+			}
+
+			content, err := render()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if parsed := parse(content); !parsed.Equal(tt.config.Effective(tt.manager)) {
+				t.Errorf("expected %+v, got %+v from\n%s", tt.config.Effective(tt.manager), parsed, content)
+			}
+		})
+	}
+}
+
+func TestUnattendedUpgradesRejectsInvalidValues(t *testing.T) {
+	for _, config := range []UnattendedUpgrades{
+		{Blacklist: []string{"bad\"name"}},
+		{Blacklist: []string{"two names"}},
+		{Origins: []string{"origin=x\nUnattended-Upgrade::Mail \"evil\";"}},
+		{RebootTime: "25:00"},
+	} {
+		if _, err := config.RenderApt(); err == nil {
+			t.Errorf("expected %+v to be rejected", config)
+		}
+	}
+}
+
+func TestRenderAptIsValid(t *testing.T) {
+	if _, err := exec.LookPath("apt-config"); err != nil {
+		t.Skip("apt-config is not installed")
+	}
+
+	content, err := UnattendedUpgrades{Blacklist: []string{"docker-ce"}, AutomaticReboot: true, RebootTime: "02:00"}.RenderApt()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := t.TempDir() + "/52remote-host-unattended-upgrades"
+	command := exec.Command("sh", "-c", "cat > "+ShellQuote(path)+" && "+ValidateConfigCommand("apt", path))
+	command.Stdin = strings.NewReader(content)
+	if output, err := command.CombinedOutput(); err != nil {
+		t.Errorf("apt-config rejected the configuration: %s", output)
+	}
+}
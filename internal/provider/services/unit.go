@@ -0,0 +1,94 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// UnitSection is a section of a systemd unit file, such as [Service].
+type UnitSection struct {
+	Name    string
+	Entries []UnitEntry
+}
+
+// UnitEntry is a setting of a unit file section. Settings accepting several values are
+// repeated with the same key.
+type UnitEntry struct {
+	Key   string
+	Value string
+}
+
+var (
+	unitSectionRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 ._-]*$`)
+	unitKeyRegexp     = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+)
+
+// sectionRank orders [Unit] first and [Install] last, as systemd documents them.
+func sectionRank(name string) int {
+	switch name {
+	case "Unit":
+		return 0
+	case "Install":
+		return 2
+	}
+
+	return 1
+}
+
+// RenderUnitFile renders sections as a systemd unit file. [Unit] comes first, [Install] last
+// and the other sections are sorted by name in between. Multi-line values are written as
+// continuation lines, which systemd joins with spaces; "%" specifiers are kept as is.
+func RenderUnitFile(sections []UnitSection) (string, error) {
+	sections = slices.Clone(sections)
+	slices.SortStableFunc(sections, func(a, b UnitSection) int {
+		if rank := sectionRank(a.Name) - sectionRank(b.Name); rank != 0 {
+			return rank
+		}
+
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	var builder strings.Builder
+	for i, section := range sections {
+		if !unitSectionRegexp.MatchString(section.Name) {
+			return "", fmt.Errorf("invalid unit section name %q", section.Name)
+		}
+
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		fmt.Fprintf(&builder, "[%s]\n", section.Name)
+
+		for _, entry := range section.Entries {
+			if !unitKeyRegexp.MatchString(entry.Key) {
+				return "", fmt.Errorf("invalid key %q in section [%s]", entry.Key, section.Name)
+			}
+
+			value, err := unitValue(entry.Value)
+			if err != nil {
+				return "", fmt.Errorf("invalid value of %s in section [%s]: %w", entry.Key, section.Name, err)
+			}
+
+			fmt.Fprintf(&builder, "%s=%s\n", entry.Key, value)
+		}
+	}
+
+	return builder.String(), nil
+}
+
+// unitValue escapes the line breaks of value as continuation lines. A trailing backslash
+// would join the next setting, so it is rejected.
+func unitValue(value string) (string, error) {
+	lines := strings.Split(strings.ReplaceAll(value, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if strings.HasSuffix(line, `\`) {
+			return "", fmt.Errorf("line %q ends with a backslash", line)
+		}
+		lines[i] = line
+	}
+
+	return strings.Join(lines, " \\\n"), nil
+}
@@ -0,0 +1,45 @@
+package services
+
+import "testing"
+
+func TestRenderUnitFile(t *testing.T) {
+	unit, err := RenderUnitFile([]UnitSection{
+		{Name: "Install", Entries: []UnitEntry{{Key: "WantedBy", Value: "multi-user.target"}}},
+		{Name: "Service", Entries: []UnitEntry{
+			{Key: "Environment", Value: "A=1"},
+			{Key: "Environment", Value: "B=%i"},
+			{Key: "ExecStart", Value: "/usr/bin/app \\\n--flag\n--other  "},
+		}},
+		{Name: "Unit", Entries: []UnitEntry{{Key: "Description", Value: "App"}}},
+	})
+	if err == nil {
+		t.Fatalf("expected an error for a backslash continuation, got %q", unit)
+	}
+
+	unit, err = RenderUnitFile([]UnitSection{
+		{Name: "Install", Entries: []UnitEntry{{Key: "WantedBy", Value: "multi-user.target"}}},
+		{Name: "Service", Entries: []UnitEntry{
+			{Key: "Environment", Value: "A=1"},
+			{Key: "Environment", Value: "B=%i"},
+			{Key: "ExecStart", Value: "/usr/bin/app\n--flag\n--other  "},
+		}},
+		{Name: "Unit", Entries: []UnitEntry{{Key: "Description", Value: "App"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[Unit]\nDescription=App\n\n" +
+		"[Service]\nEnvironment=A=1\nEnvironment=B=%i\nExecStart=/usr/bin/app \\\n--flag \\\n--other\n\n" +
+		"[Install]\nWantedBy=multi-user.target\n"
+	if unit != want {
+		t.Fatalf("unexpected unit file:\n%s\nwant:\n%s", unit, want)
+	}
+
+	if _, err := RenderUnitFile([]UnitSection{{Name: "Service]", Entries: nil}}); err == nil {
+		t.Fatal("expected an error for an invalid section name")
+	}
+	if _, err := RenderUnitFile([]UnitSection{{Name: "Service", Entries: []UnitEntry{{Key: "Exec Start", Value: "x"}}}}); err == nil {
+		t.Fatal("expected an error for an invalid key")
+	}
+}
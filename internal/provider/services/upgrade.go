@@ -0,0 +1,76 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UpgradeScopes are the sets of updates an upgrade applies: only security fixes, or every update.
+var UpgradeScopes = []string{"security", "full"}
+
+// InstalledPackagesCommand prints a "<name> <version>" line for every installed package, with
+// dpkg on Debian hosts and rpm on Red Hat hosts.
+const InstalledPackagesCommand = `if command -v dpkg-query >/dev/null 2>&1; then ` +
+	`dpkg-query -W -f='${Package} ${Version}\n'; ` +
+	`else rpm -qa --qf '%{NAME} %{VERSION}-%{RELEASE}\n'; fi`
+
+// UpgradeCommand returns a command applying the updates of scope with apt, dnf or yum, holding
+// back the packages of exclude. Configuration files changed locally are kept, as unattended
+// upgrades do.
+func UpgradeCommand(scope string, exclude []string) string {
+	aptFilter := "grep /"
+	dnfScope := ""
+	if scope == "security" {
+		// Security updates come from the <codename>-security suites on Debian and Ubuntu.
+		aptFilter = "grep -- -security"
+		dnfScope = " --security"
+	}
+
+	aptExclude := ""
+	dnfExclude := ""
+	for _, name := range exclude {
+		aptExclude += " -e " + ShellQuote(name)
+		dnfExclude += " --exclude=" + ShellQuote(name)
+	}
+	if aptExclude != "" {
+		aptExclude = " | grep -vxF" + aptExclude
+	}
+
+	return fmt.Sprintf(
+		`if command -v apt-get >/dev/null 2>&1; then `+
+			`export DEBIAN_FRONTEND=noninteractive; apt-get update -q >/dev/null && `+
+			`pkgs=$(apt list --upgradable 2>/dev/null | %s | cut -d / -f 1%s); `+
+			`[ -z "$pkgs" ] || apt-get install -y -q --only-upgrade -o Dpkg::Options::=--force-confdef -o Dpkg::Options::=--force-confold $pkgs; `+
+			`elif command -v dnf >/dev/null 2>&1; then dnf -y -q upgrade%[3]s%[4]s; `+
+			`elif command -v yum >/dev/null 2>&1; then yum -y -q update%[3]s%[4]s; `+
+			`else echo 'no supported package manager, expected apt, dnf or yum' >&2; exit 1; fi`,
+		aptFilter, aptExclude, dnfScope, dnfExclude,
+	)
+}
+
+// ParseInstalledPackages parses the output of InstalledPackagesCommand into the version of
+// every package keyed by name. Lines of another shape, e.g. a login banner, are ignored.
+func ParseInstalledPackages(output string) map[string]string {
+	packages := map[string]string{}
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			packages[fields[0]] = fields[1]
+		}
+	}
+
+	return packages
+}
+
+// ChangedPackages returns the packages of after that are new or whose version differs from
+// before, keyed by name.
+func ChangedPackages(before map[string]string, after map[string]string) map[string]string {
+	changed := map[string]string{}
+	for name, version := range after {
+		if before[name] != version {
+			changed[name] = version
+		}
+	}
+
+	return changed
+}
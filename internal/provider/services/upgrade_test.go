@@ -0,0 +1,45 @@
+package services
+
+import (
+	"maps"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestUpgradeCommand(t *testing.T) {
+	tests := []struct {
+		scope   string
+		exclude []string
+		want    []string
+	}{
+		{scope: "security", want: []string{"grep -- -security | cut -d / -f 1);", "dnf -y -q upgrade --security;"}},
+		{scope: "full", exclude: []string{"linux-image-generic", "docker-ce"}, want: []string{
+			"grep / | cut -d / -f 1 | grep -vxF -e 'linux-image-generic' -e 'docker-ce');",
+			"yum -y -q update --exclude='linux-image-generic' --exclude='docker-ce';",
+		}},
+	}
+
+	for _, tt := range tests {
+		command := UpgradeCommand(tt.scope, tt.exclude)
+		for _, want := range tt.want {
+			if !strings.Contains(command, want) {
+				t.Errorf("UpgradeCommand(%s, %q) = %s, missing %s", tt.scope, tt.exclude, command, want)
+			}
+		}
+
+		if output, err := exec.Command("sh", "-n", "-c", command).CombinedOutput(); err != nil {
+			t.Errorf("invalid command %s: %s", command, output)
+		}
+	}
+}
+
+func TestChangedPackages(t *testing.T) {
+	before := ParseInstalledPackages("Welcome!\r\nopenssl 3.0.2-0ubuntu1.15\r\nlibc6 2.35-0ubuntu3.6\r\ncurl 7.81.0-1ubuntu1.15\r\n")
+	after := ParseInstalledPackages("openssl 3.0.2-0ubuntu1.18\nlibc6 2.35-0ubuntu3.6\ncurl 7.81.0-1ubuntu1.15\nlinux-image-6.8.0-45-generic 6.8.0-45.45\n")
+
+	want := map[string]string{"openssl": "3.0.2-0ubuntu1.18", "linux-image-6.8.0-45-generic": "6.8.0-45.45"}
+	if changed := ChangedPackages(before, after); !maps.Equal(changed, want) {
+		t.Errorf("expected %v, got %v", want, changed)
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"remote-provider/internal/provider/servers"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// UploadCommand returns a command writing its standard input to path. With sparse, blocks of
+// zeros are left as holes when the host dd supports conv=sparse, so VM images keep their
+// allocated size. Other hosts get a plain copy.
+func UploadCommand(path string, sparse bool) string {
+	quotedPath := ShellQuote(path)
+	if !sparse {
+		return fmt.Sprintf("cat > %s", quotedPath)
+	}
+
+	return fmt.Sprintf(
+		"if dd if=/dev/null of=%s conv=sparse 2>/dev/null; then dd of=%s bs=65536 conv=sparse 2>/dev/null; else cat > %s; fi",
+		quotedPath, quotedPath, quotedPath,
+	)
+}
+
+// countingReader counts the bytes read from reader.
+type countingReader struct {
+	reader io.Reader
+	count  int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += n
+
+	return n, err
+}
+
+// Upload runs command on server with content as its standard input. Unlike ExecuteCommand no
+// PTY is requested, so binary content reaches the command untouched, and no sudo password can
+// be answered: privileged steps must run in a separate command.
+func (service *SSHService) Upload(ctx context.Context, server *servers.Server, command string, content io.Reader) (*servers.ServerCommand, error) {
+	service.recordOperation(ctx, server)
+	if err := service.beginApply(ctx, server); err != nil {
+		return nil, err
+	}
+
+	return service.upload(ctx, server, command, content)
+}
+
+// upload is Upload without the apply hooks, for the agent which also serves reads.
+func (service *SSHService) upload(ctx context.Context, server *servers.Server, command string, content io.Reader) (*servers.ServerCommand, error) {
+	connection := service.findConnection(server)
+	if connection == nil {
+		return nil, fmt.Errorf("no connection found for server %s", server.Name)
+	}
+
+	if service.Fake != nil {
+		serverCommand, err := service.Fake.upload(command, content)
+		if serverCommand != nil {
+			service.recordHistory(server, serverCommand)
+		}
+		return serverCommand, err
+	}
+
+	if connection.local {
+		return service.runLocal(ctx, server, command, content)
+	}
+
+	if connection.console != nil {
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return nil, err
+		}
+
+		return service.executeConsoleCommand(ctx, connection, server, command, data)
+	}
+
+	service.hosts.acquire(server.Name, service.MaxParallelHosts)
+	defer service.hosts.release(server.Name, service.MaxParallelHosts)
+
+	if connection.sessions != nil {
+		select {
+		case connection.sessions <- struct{}{}:
+			defer func() { <-connection.sessions }()
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for a session on %s: %w", server.Name, ctx.Err())
+		}
+	}
+
+	session, err := service.spawnSession(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(session *ssh.Session) {
+		_ = session.Close()
+	}(session)
+
+	var stdout, stderr bytes.Buffer
+	reader := &countingReader{reader: content}
+	session.Stdin = reader
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	mark, err := newCommandMark()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	err = runSession(ctx, session, service.Wrapper.Wrap(mark.wrap(containerCommand(server, service.withUmask(command)))), service.interrupter(connection, mark))
+	finish := time.Now()
+	service.Measure(ctx, "transfer", server, start, reader.count, err)
+
+	serverCommand := &servers.ServerCommand{
+		Command:    command,
+		Stdout:     stdout.String(),
+		Stderr:     stderr.String(),
+		ExitCode:   extractExitCode(err),
+		StartedAt:  start,
+		FinishedAt: finish,
+	}
+	service.recordHistory(server, serverCommand)
+
+	return serverCommand, err
+}
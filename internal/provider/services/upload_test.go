@@ -0,0 +1,31 @@
+package services
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestUploadCommand(t *testing.T) {
+	content := append(make([]byte, 1<<20), []byte("tail")...)
+
+	for _, sparse := range []bool{true, false} {
+		path := filepath.Join(t.TempDir(), "disk image.img")
+
+		command := exec.Command("sh", "-c", UploadCommand(path, sparse))
+		command.Stdin = bytes.NewReader(content)
+		if output, err := command.CombinedOutput(); err != nil {
+			t.Fatalf("sparse=%v: %v: %s", sparse, err, output)
+		}
+
+		written, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(written, content) {
+			t.Fatalf("sparse=%v: uploaded content differs, got %d bytes", sparse, len(written))
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// UptimeCommand prints the uptime, load averages and pending reboot indicators of a host as
+// key=value lines. Linux hosts are read from /proc, macOS hosts through sysctl.
+const UptimeCommand = `if [ -r /proc/uptime ]; then ` +
+	`echo "uptime=$(cut -d ' ' -f 1 /proc/uptime)"; echo "load=$(cut -d ' ' -f 1-3 /proc/loadavg)"; ` +
+	`else ` +
+	`echo "uptime=$(( $(date +%s) - $(sysctl -n kern.boottime | sed 's/^{ sec = \([0-9]*\).*/\1/') ))"; echo "load=$(sysctl -n vm.loadavg | tr -d '{}')"; ` +
+	`fi; ` +
+	`if [ -e /var/run/reboot-required ]; then echo reboot_required=Y; sed 's/^/reboot_package=/' /var/run/reboot-required.pkgs 2>/dev/null; fi; ` +
+	`if command -v needs-restarting >/dev/null 2>&1; then needs-restarting -r >/dev/null 2>&1; [ $? -eq 1 ] && echo reboot_required=Y; fi; ` +
+	`true`
+
+// Uptime is how long a host has been running, its load and whether it waits for a reboot.
+type Uptime struct {
+	Seconds int64
+	Load    [3]float64
+	// RebootRequired is set by /var/run/reboot-required on Debian hosts and needs-restarting on
+	// Red Hat hosts. RebootPackages lists the Debian packages asking for it.
+	RebootRequired bool
+	RebootPackages []string
+}
+
+// ParseUptime parses the output of UptimeCommand, ignoring unrelated lines.
+func ParseUptime(output string) (Uptime, error) {
+	uptime := Uptime{RebootPackages: []string{}}
+	found := false
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "uptime":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return uptime, fmt.Errorf("unexpected uptime %q", value)
+			}
+			uptime.Seconds = int64(seconds)
+			found = true
+		case "load":
+			fields := strings.Fields(value)
+			if len(fields) != 3 {
+				return uptime, fmt.Errorf("unexpected load averages %q", value)
+			}
+			for i, field := range fields {
+				load, err := strconv.ParseFloat(strings.ReplaceAll(field, ",", "."), 64)
+				if err != nil {
+					return uptime, fmt.Errorf("unexpected load averages %q", value)
+				}
+				uptime.Load[i] = load
+			}
+		case "reboot_required":
+			uptime.RebootRequired = true
+		case "reboot_package":
+			if value != "" && !slices.Contains(uptime.RebootPackages, value) {
+				uptime.RebootPackages = append(uptime.RebootPackages, value)
+			}
+		}
+	}
+
+	if !found {
+		return uptime, fmt.Errorf("no uptime in output")
+	}
+
+	return uptime, nil
+}
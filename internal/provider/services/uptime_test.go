@@ -0,0 +1,33 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseUptime(t *testing.T) {
+	output := "uptime=86523.41\r\nload=0.52 0.58 0.59\r\nreboot_required=Y\r\n" +
+		"reboot_package=linux-image-6.8.0-45-generic\r\nreboot_package=linux-base\r\nreboot_package=linux-base\r\n"
+
+	uptime, err := ParseUptime(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uptime.Seconds != 86523 || uptime.Load != [3]float64{0.52, 0.58, 0.59} || !uptime.RebootRequired {
+		t.Errorf("unexpected uptime %+v", uptime)
+	}
+	if !slices.Equal(uptime.RebootPackages, []string{"linux-image-6.8.0-45-generic", "linux-base"}) {
+		t.Errorf("unexpected reboot packages %q", uptime.RebootPackages)
+	}
+
+	// macOS prints the load averages with the locale decimal separator.
+	uptime, err = ParseUptime("uptime=3600\nload= 1,20 1,10 0,90 \n")
+	if err != nil || uptime.Load != [3]float64{1.2, 1.1, 0.9} || uptime.RebootRequired {
+		t.Errorf("unexpected uptime %+v, %v", uptime, err)
+	}
+
+	if _, err := ParseUptime("load=0.1 0.1 0.1\n"); err == nil {
+		t.Fatal("expected an error without uptime")
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import "fmt"
+
+// CreateSystemUserCommand returns a command creating the system account user unless it exists.
+func (p Platform) CreateSystemUserCommand(user string) string {
+	quoted := ShellQuote(user)
+
+	if p.OS == "darwin" {
+		record := ShellQuote("/Users/" + user)
+		// dscl needs an explicit id, take the next free one below the 500 range of regular users.
+		return fmt.Sprintf(`id -u %[1]s >/dev/null 2>&1 || { `+
+			`uid=$(( $(dscl . -list /Users UniqueID | awk '$2 < 500 { print $2 }' | sort -n | tail -1) + 1 )) && `+
+			`dscl . -create %[2]s && `+
+			`dscl . -create %[2]s UniqueID "$uid" && `+
+			`dscl . -create %[2]s PrimaryGroupID -2 && `+
+			`dscl . -create %[2]s UserShell /usr/bin/false && `+
+			`dscl . -create %[2]s NFSHomeDirectory /var/empty; }`, quoted, record)
+	}
+
+	// BusyBox systems only ship adduser.
+	return fmt.Sprintf(`id -u %[1]s >/dev/null 2>&1 || useradd --system --no-create-home --shell /usr/sbin/nologin %[1]s 2>/dev/null || adduser -S -D -H -s /sbin/nologin %[1]s`, quoted)
+}
+
+// DeleteUserCommand returns a command removing the account user, ignoring missing ones.
+func (p Platform) DeleteUserCommand(user string) string {
+	if p.OS == "darwin" {
+		return fmt.Sprintf("dscl . -delete %s || true", ShellQuote("/Users/"+user))
+	}
+
+	return fmt.Sprintf("userdel %[1]s || deluser %[1]s || true", ShellQuote(user))
+}
+
+// CreateLoginUserCommand returns a command creating the regular account user with a home
+// directory unless it exists.
+func (p Platform) CreateLoginUserCommand(user string) string {
+	quoted := ShellQuote(user)
+
+	switch {
+	case p.OS == "darwin":
+		return fmt.Sprintf(`id -u %[1]s >/dev/null 2>&1 || { sysadminctl -addUser %[1]s -home %[2]s && createhomedir -c -u %[1]s >/dev/null; }`, quoted, ShellQuote("/Users/"+user))
+	case p.BusyBox:
+		return fmt.Sprintf(`id -u %[1]s >/dev/null 2>&1 || adduser -D %[1]s`, quoted)
+	}
+
+	return fmt.Sprintf(`id -u %[1]s >/dev/null 2>&1 || useradd --create-home --shell /bin/sh %[1]s`, quoted)
+}
+
+// HomeDirectoryCommand returns a command printing the home directory of user.
+func (p Platform) HomeDirectoryCommand(user string) string {
+	if p.OS == "darwin" {
+		return fmt.Sprintf("dscl . -read %s NFSHomeDirectory | awk '{print $2}'", ShellQuote("/Users/"+user))
+	}
+
+	return fmt.Sprintf("{ getent passwd %[1]s 2>/dev/null || grep %[2]s /etc/passwd; } | cut -d: -f6", ShellQuote(user), ShellQuote("^"+user+":"))
+}
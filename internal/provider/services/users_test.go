@@ -0,0 +1,15 @@
+package services
+
+import "testing"
+
+func TestHomeDirectoryCommand(t *testing.T) {
+	expected := `{ getent passwd 'deploy' 2>/dev/null || grep '^deploy:' /etc/passwd; } | cut -d: -f6`
+	if actual := (Platform{OS: "linux"}).HomeDirectoryCommand("deploy"); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+
+	expected = `dscl . -read '/Users/deploy' NFSHomeDirectory | awk '{print $2}'`
+	if actual := (Platform{OS: "darwin"}).HomeDirectoryCommand("deploy"); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"remote-provider/internal/provider/servers"
+	"slices"
+	"strings"
+)
+
+// AllHostsGroup is the host group whose variables apply to every host, whether it lists them or not.
+const AllHostsGroup = "all"
+
+// hostVarRegexp matches the references to host variables in commands, e.g. "{{ var.version }}".
+var hostVarRegexp = regexp.MustCompile(`\{\{\s*var\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// HostVars returns the variables of server. The variables of AllHostsGroup come first, then the
+// ones of the groups listing the host in name order, then the Args of server, later values
+// overriding earlier ones.
+func (service *SSHService) HostVars(server *servers.Server) map[string]string {
+	groups := slices.Clone(service.HostGroups)
+	slices.SortStableFunc(groups, func(a, b *servers.ServerGroup) int {
+		switch {
+		case a.Name == b.Name:
+			return 0
+		case a.Name == AllHostsGroup:
+			return -1
+		case b.Name == AllHostsGroup:
+			return 1
+		default:
+			return strings.Compare(a.Name, b.Name)
+		}
+	})
+
+	vars := map[string]any{}
+	for _, group := range groups {
+		if group.Name == AllHostsGroup || slices.ContainsFunc(group.Servers, func(member *servers.Server) bool {
+			return member.Name == server.Name
+		}) {
+			maps.Copy(vars, group.Args)
+		}
+	}
+	maps.Copy(vars, server.Args)
+
+	values := make(map[string]string, len(vars))
+	for name, value := range vars {
+		values[name] = fmt.Sprint(value)
+	}
+
+	return values
+}
+
+// ExpandHostVars replaces the references to host variables in command by their value on server.
+// Values are inserted as is, quote them in the command where the shell needs it.
+func (service *SSHService) ExpandHostVars(command string, server *servers.Server) (string, error) {
+	if !hostVarRegexp.MatchString(command) {
+		return command, nil
+	}
+
+	vars := service.HostVars(server)
+
+	var undefined []string
+	expanded := hostVarRegexp.ReplaceAllStringFunc(command, func(reference string) string {
+		name := hostVarRegexp.FindStringSubmatch(reference)[1]
+		value, ok := vars[name]
+		if !ok && !slices.Contains(undefined, name) {
+			undefined = append(undefined, name)
+		}
+
+		return value
+	})
+
+	if len(undefined) > 0 {
+		return "", fmt.Errorf("undefined host variables for %s: %s", server.Name, strings.Join(undefined, ", "))
+	}
+
+	return expanded, nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"remote-provider/internal/provider/servers"
+	"strings"
+	"testing"
+)
+
+func TestHostVars(t *testing.T) {
+	web := &servers.Server{Name: "10.0.0.1"}
+	service := &SSHService{HostGroups: []*servers.ServerGroup{
+		{Name: "web", Servers: []*servers.Server{web}, Args: map[string]any{"port": "8080", "role": "web"}},
+		{Name: AllHostsGroup, Args: map[string]any{"env": "prod", "port": "80"}},
+		{Name: "canary", Servers: []*servers.Server{web}, Args: map[string]any{"version": "2.0"}},
+	}}
+
+	vars := service.HostVars(web)
+	want := map[string]string{"env": "prod", "port": "8080", "role": "web", "version": "2.0"}
+	if len(vars) != len(want) {
+		t.Fatalf("unexpected variables %v", vars)
+	}
+	for name, value := range want {
+		if vars[name] != value {
+			t.Errorf("expected %s=%s, got %q", name, value, vars[name])
+		}
+	}
+
+	other := &servers.Server{Name: "10.0.0.2", Args: map[string]any{"env": "staging"}}
+	if vars := service.HostVars(other); len(vars) != 2 || vars["env"] != "staging" || vars["port"] != "80" {
+		t.Errorf("expected the variables of the all group overridden by the host, got %v", vars)
+	}
+
+	command, err := service.ExpandHostVars("deploy --env={{ var.env }} --port={{var.port}} && docker inspect -f '{{.State}}' app", web)
+	if err != nil || command != "deploy --env=prod --port=8080 && docker inspect -f '{{.State}}' app" {
+		t.Errorf("unexpected command %q, %v", command, err)
+	}
+
+	if _, err := service.ExpandHostVars("echo {{ var.missing }} {{ var.missing }}", web); err == nil || !strings.HasSuffix(err.Error(), ": missing") {
+		t.Errorf("expected an undefined variable error, got %v", err)
+	}
+}
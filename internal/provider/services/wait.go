@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"remote-provider/internal/provider/servers"
+	"strings"
+	"time"
+)
+
+// FileStateCommand prints `present` when path exists on the host, `absent` otherwise.
+func FileStateCommand(path string) string {
+	return fmt.Sprintf("if [ -e %s ]; then echo present; else echo absent; fi", ShellQuote(path))
+}
+
+// WaitForFile polls server every interval until path exists, or no longer exists when present
+// is false, and returns how long it waited. The wait ends with an error when ctx is done first.
+func (service *SSHService) WaitForFile(ctx context.Context, server *servers.Server, path string, present bool, privileged bool, interval time.Duration) (time.Duration, error) {
+	command := FileStateCommand(path)
+	if privileged {
+		command = PrivilegedCommand(server, command)
+	}
+
+	want := "absent"
+	if present {
+		want = "present"
+	}
+
+	start := time.Now()
+	for {
+		result, err := service.ExecuteCommand(ctx, command, server)
+		if err != nil {
+			return time.Since(start), fmt.Errorf("checking %s: %w", path, err)
+		}
+
+		// The PTY turns line feeds into CRLF, so the output is compared trimmed.
+		if strings.TrimSpace(result.Stdout) == want {
+			return time.Since(start), nil
+		}
+
+		if err := SleepContext(ctx, interval); err != nil {
+			return time.Since(start), fmt.Errorf("%s still not %s after %s: %w", path, want, time.Since(start).Round(time.Second), err)
+		}
+	}
+}
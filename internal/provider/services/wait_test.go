@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"remote-provider/internal/provider/servers"
+	"testing"
+	"time"
+)
+
+func TestWaitForFile(t *testing.T) {
+	service := &SSHService{Fake: &FakeTransport{Responses: []FakeResponse{
+		{Pattern: regexp.MustCompile(`/var/lib/cloud/instance/boot-finished`), Stdout: "present\r\n"},
+		{Pattern: regexp.MustCompile(`^if \[ -e `), Stdout: "absent\r\n"},
+	}}}
+	defer service.Close()
+
+	server := &servers.Server{Name: "web", Address: "10.0.0.1", Port: 22, User: "admin"}
+	if err := service.OpenConnection(context.Background(), server); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		present bool
+		timeout bool
+	}{
+		{name: "appeared", path: "/var/lib/cloud/instance/boot-finished", present: true},
+		{name: "gone", path: "/run/setup.lock", present: false},
+		{name: "never appears", path: "/etc/license.key", present: true, timeout: true},
+		{name: "never goes", path: "/var/lib/cloud/instance/boot-finished", present: false, timeout: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			_, err := service.WaitForFile(ctx, server, tt.path, tt.present, false, 10*time.Millisecond)
+			if tt.timeout != errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
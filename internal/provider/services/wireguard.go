@@ -0,0 +1,236 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// WireGuardDir holds the wg-quick configurations and the private keys of the interfaces.
+const WireGuardDir = "/etc/wireguard"
+
+var (
+	// WireGuardInterfaceRegexp matches the names wg-quick accepts for an interface.
+	WireGuardInterfaceRegexp = regexp.MustCompile(`^[A-Za-z0-9_=+.-]{1,15}$`)
+	// WireGuardKeyRegexp matches a base64 encoded Curve25519 key.
+	WireGuardKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9+/]{42}[AEIMQUYcgkosw048]=$`)
+)
+
+// WireGuardPeer is a [Peer] section of an interface configuration.
+type WireGuardPeer struct {
+	PublicKey           string
+	AllowedIPs          []string
+	Endpoint            string
+	PersistentKeepalive int64
+}
+
+// WireGuardConfig is the configuration of an interface, without its private key.
+type WireGuardConfig struct {
+	Addresses  []string
+	ListenPort int64
+	Peers      []WireGuardPeer
+}
+
+// wireGuardPaths returns the configuration and private key files of iface.
+func wireGuardPaths(iface string) (string, string) {
+	return WireGuardDir + "/" + iface + ".conf", WireGuardDir + "/" + iface + ".key"
+}
+
+// check rejects the values that would break out of their line in the configuration.
+func (c WireGuardConfig) check() error {
+	values := append([]string{}, c.Addresses...)
+	for _, peer := range c.Peers {
+		values = append(append(values, peer.PublicKey, peer.Endpoint), peer.AllowedIPs...)
+	}
+	for _, value := range values {
+		if strings.ContainsAny(value, "\n\r#") {
+			return fmt.Errorf("%q cannot contain line breaks or comments", value)
+		}
+	}
+
+	return nil
+}
+
+// Render returns the configuration following the PrivateKey line of the [Interface] section.
+func (c WireGuardConfig) Render() (string, error) {
+	if err := c.check(); err != nil {
+		return "", err
+	}
+
+	var content strings.Builder
+	if len(c.Addresses) > 0 {
+		content.WriteString("Address = " + strings.Join(c.Addresses, ", ") + "\n")
+	}
+	if c.ListenPort != 0 {
+		fmt.Fprintf(&content, "ListenPort = %d\n", c.ListenPort)
+	}
+
+	for _, peer := range c.Peers {
+		content.WriteString("\n[Peer]\n")
+		content.WriteString("PublicKey = " + peer.PublicKey + "\n")
+		content.WriteString("AllowedIPs = " + strings.Join(peer.AllowedIPs, ", ") + "\n")
+		if peer.Endpoint != "" {
+			content.WriteString("Endpoint = " + peer.Endpoint + "\n")
+		}
+		if peer.PersistentKeepalive != 0 {
+			fmt.Fprintf(&content, "PersistentKeepalive = %d\n", peer.PersistentKeepalive)
+		}
+	}
+
+	return content.String(), nil
+}
+
+// splitList splits a comma separated configuration value.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// ParseWireGuardConfig reads back the settings of a wg-quick configuration. The private key and
+// the settings the provider does not manage are ignored.
+func ParseWireGuardConfig(content string) (WireGuardConfig, error) {
+	var config WireGuardConfig
+	var peer *WireGuardPeer
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "[Peer]" {
+			config.Peers = append(config.Peers, WireGuardPeer{})
+			peer = &config.Peers[len(config.Peers)-1]
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		var err error
+		switch {
+		case peer == nil && key == "Address":
+			config.Addresses = append(config.Addresses, splitList(value)...)
+		case peer == nil && key == "ListenPort":
+			config.ListenPort, err = strconv.ParseInt(value, 10, 64)
+		case peer != nil && key == "PublicKey":
+			peer.PublicKey = value
+		case peer != nil && key == "AllowedIPs":
+			peer.AllowedIPs = append(peer.AllowedIPs, splitList(value)...)
+		case peer != nil && key == "Endpoint":
+			peer.Endpoint = value
+		case peer != nil && key == "PersistentKeepalive":
+			if value != "off" {
+				peer.PersistentKeepalive, err = strconv.ParseInt(value, 10, 64)
+			}
+		}
+		if err != nil {
+			return config, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+
+	return config, nil
+}
+
+// WireGuardApplyCommand returns a command configuring iface with body, the output of Render,
+// and applying it with `wg syncconf` when the interface is up or starting it with wg-quick
+// otherwise. The private key is moved from keyPath when it is set, generated when rotate is
+// set or the interface has no key yet, and kept otherwise. The command prints the public key.
+func WireGuardApplyCommand(iface string, body string, keyPath string, rotate bool) string {
+	configPath, privateKeyPath := wireGuardPaths(iface)
+	config, key := ShellQuote(configPath), ShellQuote(privateKeyPath)
+
+	script := []string{
+		"set -e",
+		"umask 077",
+		"mkdir -p " + ShellQuote(WireGuardDir),
+	}
+	switch {
+	case keyPath != "":
+		script = append(script, fmt.Sprintf("mv -f %s %s && chown 0:0 %s", ShellQuote(keyPath), key, key))
+	case rotate:
+		script = append(script, fmt.Sprintf("wg genkey > %s", key))
+	default:
+		script = append(script, fmt.Sprintf("[ -s %[1]s ] || wg genkey > %[1]s", key))
+	}
+
+	script = append(script,
+		fmt.Sprintf(`{ printf '[Interface]\nPrivateKey = %%s\n' "$(cat %s)"; printf '%%s' %s | base64 -d; } > %s.new`,
+			key, ShellQuote(base64.StdEncoding.EncodeToString([]byte(body))), config),
+		fmt.Sprintf("mv -f %[1]s.new %[1]s", config),
+		fmt.Sprintf(`if ip link show %[1]s >/dev/null 2>&1; then wg-quick strip %[1]s > %[2]s.strip && wg syncconf %[1]s %[2]s.strip && rm -f %[2]s.strip; `+
+			`else systemctl start wg-quick@%[1]s; fi`,
+			ShellQuote(iface), config),
+		fmt.Sprintf("systemctl enable wg-quick@%s 2>/dev/null", ShellQuote(iface)),
+		fmt.Sprintf(`echo "public_key=$(wg pubkey < %s)"`, key),
+	)
+
+	return strings.Join(script, "\n")
+}
+
+// WireGuardStatusCommand prints the public key and the base64 encoded configuration of iface,
+// or missing when it is not configured.
+func WireGuardStatusCommand(iface string) string {
+	configPath, privateKeyPath := wireGuardPaths(iface)
+	config, key := ShellQuote(configPath), ShellQuote(privateKeyPath)
+
+	return fmt.Sprintf(`if [ -f %[1]s ] && [ -f %[2]s ]; then echo "public_key=$(wg pubkey < %[2]s)"; echo "config=$(base64 < %[1]s | tr -d '\n')"; else echo missing; fi`,
+		config, key)
+}
+
+// WireGuardStatus is the state of an interface read back from a host.
+type WireGuardStatus struct {
+	PublicKey string
+	Config    WireGuardConfig
+}
+
+// ParseWireGuardStatus parses the output of WireGuardStatusCommand or of
+// WireGuardApplyCommand, returning nil when the interface is not configured.
+func ParseWireGuardStatus(output string) (*WireGuardStatus, error) {
+	status := &WireGuardStatus{}
+
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		switch key {
+		case "missing":
+			return nil, nil
+		case "public_key":
+			if !WireGuardKeyRegexp.MatchString(value) {
+				return nil, fmt.Errorf("invalid public key %q", value)
+			}
+			status.PublicKey = value
+		case "config":
+			content, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return nil, fmt.Errorf("decoding the configuration: %w", err)
+			}
+			status.Config, err = ParseWireGuardConfig(string(content))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if status.PublicKey == "" {
+		return nil, errors.New("unexpected output: " + output)
+	}
+
+	return status, nil
+}
+
+// WireGuardRemoveCommand returns a command stopping iface and removing its configuration and
+// private key.
+func WireGuardRemoveCommand(iface string) string {
+	configPath, privateKeyPath := wireGuardPaths(iface)
+
+	return fmt.Sprintf("systemctl disable --now wg-quick@%s >/dev/null 2>&1; rm -f %s %s",
+		ShellQuote(iface), ShellQuote(configPath), ShellQuote(privateKeyPath))
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"encoding/base64"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestWireGuardConfigRoundTrip(t *testing.T) {
+	config := WireGuardConfig{
+		Addresses:  []string{"10.8.0.1/24", "fd00:8::1/64"},
+		ListenPort: 51820,
+		Peers: []WireGuardPeer{
+			{PublicKey: "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=", AllowedIPs: []string{"10.8.0.2/32"}, Endpoint: "vpn.example.com:51820", PersistentKeepalive: 25},
+			{PublicKey: "TrMvSoP4jYQlY6RIzBgbssQqY3vxI2Pi+y71lOWWXX0=", AllowedIPs: []string{"10.8.0.3/32", "192.168.10.0/24"}},
+		},
+	}
+
+	body, err := config.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := "public_key=HIgo9xNzJMWLKASShiTqIybxZ0U3wGLiUeJ1PKf8ykw=\nconfig=" +
+		base64.StdEncoding.EncodeToString([]byte("[Interface]\nPrivateKey = yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk=\n"+body)) + "\n"
+
+	status, err := ParseWireGuardStatus(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.PublicKey != "HIgo9xNzJMWLKASShiTqIybxZ0U3wGLiUeJ1PKf8ykw=" {
+		t.Errorf("unexpected public key %s", status.PublicKey)
+	}
+	if !reflect.DeepEqual(status.Config, config) {
+		t.Errorf("expected %+v, got %+v", config, status.Config)
+	}
+
+	if status, err := ParseWireGuardStatus("missing\n"); status != nil || err != nil {
+		t.Errorf("expected a missing interface, got %+v, %v", status, err)
+	}
+	if _, err := (WireGuardConfig{Addresses: []string{"10.8.0.1/24\nPostUp = reboot"}}).Render(); err == nil {
+		t.Error("expected a line break to be rejected")
+	}
+}
+
+func TestWireGuardCommands(t *testing.T) {
+	for _, command := range []string{
+		WireGuardApplyCommand("wg0", "ListenPort = 51820\n", "", false),
+		WireGuardApplyCommand("wg0", "ListenPort = 51820\n", "/tmp/workspace/wireguard-key", false),
+		WireGuardApplyCommand("wg0", "ListenPort = 51820\n", "", true),
+		WireGuardStatusCommand("wg0"),
+		WireGuardRemoveCommand("wg0"),
+	} {
+		if output, err := exec.Command("sh", "-n", "-c", command).CombinedOutput(); err != nil {
+			t.Errorf("invalid command %s: %s", command, output)
+		}
+	}
+}
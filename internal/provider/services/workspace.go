@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"remote-provider/internal/provider/servers"
+	"slices"
+	"strings"
+)
+
+// workspaceStaleMinutes is the age after which workspaces left behind by killed runs are removed.
+const workspaceStaleMinutes = 24 * 60
+
+// Workspace returns a private scratch directory on server for uploads, scripts and validators.
+// The directory is created once per provider run with an unpredictable name and removed by Close.
+func (service *SSHService) Workspace(ctx context.Context, server *servers.Server) (string, error) {
+	key := connectionKey(server)
+
+	service.mutex.Lock()
+	workspace, ok := service.workspaces[key]
+	service.mutex.Unlock()
+
+	if ok {
+		return workspace, nil
+	}
+
+	err := service.OpenConnection(ctx, server)
+	if err != nil {
+		return "", err
+	}
+
+	command := strings.Join([]string{
+		fmt.Sprintf(`find "${TMPDIR:-/tmp}" -maxdepth 1 -type d -name 'remote-host.*' -user "$(id -u)" -mmin +%d -exec rm -rf {} + 2>/dev/null`, workspaceStaleMinutes),
+		`umask 077 && mktemp -d "${TMPDIR:-/tmp}/remote-host.XXXXXXXXXX"`,
+	}, "; ")
+
+	result, err := service.ExecuteCommand(ctx, command, server)
+	if err != nil {
+		return "", fmt.Errorf("creating workspace on %s: %w", server.Name, err)
+	}
+
+	workspace = strings.TrimSpace(result.Stdout)
+	if workspace == "" {
+		return "", fmt.Errorf("creating workspace on %s: mktemp returned no path", server.Name)
+	}
+
+	service.mutex.Lock()
+	existing, ok := service.workspaces[key]
+	if !ok {
+		if service.workspaces == nil {
+			service.workspaces = map[string]string{}
+		}
+		service.workspaces[key] = workspace
+	}
+	service.mutex.Unlock()
+
+	// Another resource created one concurrently, keep the first and drop ours.
+	if ok {
+		_, err = service.ExecuteCommand(ctx, "rm -rf "+ShellQuote(workspace), server)
+		return existing, err
+	}
+
+	return workspace, nil
+}
+
+// Close runs the post-apply hooks, removes the workspaces created during the run and closes
+// every connection. Workspaces are removed with the commands of their connection, so the ones
+// created inside a container are removed from it with the same privileges.
+func (service *SSHService) Close() error {
+	var errs []error
+	if err := service.endApply(); err != nil {
+		errs = append(errs, err)
+	}
+
+	service.mutex.Lock()
+	workspaces := service.workspaces
+	service.workspaces = nil
+	connections := slices.Clone(service.connections)
+	service.mutex.Unlock()
+
+	for _, connection := range connections {
+		workspace, ok := workspaces[connection.key]
+		if !ok {
+			continue
+		}
+
+		_, err := service.executeCommand(context.Background(), "rm -rf "+ShellQuote(workspace), connection.host)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("removing workspace on %s: %w", connection.host.Name, err))
+		}
+	}
+
+	service.mutex.Lock()
+	connections = service.connections
+	service.connections = nil
+	service.mutex.Unlock()
+
+	for _, connection := range connections {
+		err := service.CloseConnection(connection)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,25 @@
+package services
+
+// CommandWrapper rewrites every command sent to the hosts, for accounts whose login shell is
+// restricted (rbash) or whose sshd ForceCommand only runs commands handed to a wrapper.
+type CommandWrapper struct {
+	// Prefix and Suffix surround the command, e.g. "sh -c " for an rbash account.
+	Prefix string
+	Suffix string
+	// Quote passes the command as a single shell word, so the wrapper receives it whole
+	// including the newlines, redirections and variables a restricted shell would reject.
+	Quote bool
+}
+
+// Wrap returns command as it must be sent to the host.
+func (wrapper *CommandWrapper) Wrap(command string) string {
+	if wrapper == nil {
+		return command
+	}
+
+	if wrapper.Quote {
+		command = ShellQuote(command)
+	}
+
+	return wrapper.Prefix + command + wrapper.Suffix
+}
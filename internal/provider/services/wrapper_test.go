@@ -0,0 +1,25 @@
+package services
+
+import "testing"
+
+func TestCommandWrapper(t *testing.T) {
+	tests := []struct {
+		name    string
+		wrapper *CommandWrapper
+		command string
+		want    string
+	}{
+		{"unset", nil, "uname -s", "uname -s"},
+		{"prefix", &CommandWrapper{Prefix: "appliance-run "}, "uname -s", "appliance-run uname -s"},
+		{"quoted", &CommandWrapper{Prefix: "sh -c ", Quote: true}, "echo 'a' > /tmp/x", `sh -c 'echo '"'"'a'"'"' > /tmp/x'`},
+		{"suffix", &CommandWrapper{Prefix: "exec ", Suffix: " </dev/null", Quote: true}, "id", "exec 'id' </dev/null"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.wrapper.Wrap(test.command); got != test.want {
+				t.Errorf("Wrap(%q) = %q, want %q", test.command, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,729 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// YAML documents are written in block style and read back with a parser for the subset of
+// YAML found in configuration files: block and flow collections, plain, quoted and block
+// scalars, and comments. Anchors, aliases, tags and multi-line flow or plain scalars are
+// rejected, a document using them is reported as unreadable rather than misread.
+
+// plainYAMLRegexp matches the strings written without quotes, as long as they do not read back
+// as another type.
+var plainYAMLRegexp = regexp.MustCompile(`^[A-Za-z_/][A-Za-z0-9_./@+-]*$`)
+
+// yaml11Words are read as booleans by YAML 1.1 parsers, still common, so they are quoted.
+var yaml11Words = map[string]bool{"y": true, "n": true, "yes": true, "no": true, "on": true, "off": true}
+
+var (
+	yamlIntRegexp   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloatRegexp = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+	yamlRadixRegexp = regexp.MustCompile(`^0(x[0-9a-fA-F]+|o[0-7]+)$`)
+)
+
+// RenderYAML serializes value as a block style YAML document with sorted keys.
+func RenderYAML(value any) (string, error) {
+	var builder strings.Builder
+	if err := writeYAML(&builder, value, 0); err != nil {
+		return "", err
+	}
+
+	return builder.String(), nil
+}
+
+// writeYAML writes value as a node whose lines are indented by indent spaces.
+func writeYAML(builder *strings.Builder, value any, indent int) error {
+	prefix := strings.Repeat(" ", indent)
+
+	switch value := value.(type) {
+	case map[string]any:
+		if len(value) == 0 {
+			break
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(value)) {
+			builder.WriteString(prefix + yamlString(key) + ":")
+			if !yamlCollection(value[key]) {
+				scalar, err := yamlScalar(value[key])
+				if err != nil {
+					return fmt.Errorf("%s: %w", key, err)
+				}
+				builder.WriteString(" " + scalar + "\n")
+				continue
+			}
+
+			builder.WriteString("\n")
+			if err := writeYAML(builder, value[key], indent+2); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+
+		return nil
+	case []any:
+		if len(value) == 0 {
+			break
+		}
+
+		for i, item := range value {
+			if !yamlCollection(item) {
+				scalar, err := yamlScalar(item)
+				if err != nil {
+					return fmt.Errorf("item %d: %w", i, err)
+				}
+				builder.WriteString(prefix + "- " + scalar + "\n")
+				continue
+			}
+
+			// Nested collections start on the line of their dash.
+			var nested strings.Builder
+			if err := writeYAML(&nested, item, indent+2); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+			builder.WriteString(prefix + "- " + nested.String()[indent+2:])
+		}
+
+		return nil
+	}
+
+	scalar, err := yamlScalar(value)
+	if err != nil {
+		return err
+	}
+	builder.WriteString(prefix + scalar + "\n")
+
+	return nil
+}
+
+// yamlCollection reports whether value is written as a block collection.
+func yamlCollection(value any) bool {
+	switch value := value.(type) {
+	case map[string]any:
+		return len(value) > 0
+	case []any:
+		return len(value) > 0
+	}
+
+	return false
+}
+
+// yamlScalar returns value written on a single line.
+func yamlScalar(value any) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case json.Number:
+		return value.String(), nil
+	case string:
+		return yamlString(value), nil
+	case map[string]any:
+		return "{}", nil
+	case []any:
+		return "[]", nil
+	}
+
+	return "", fmt.Errorf("unsupported value of type %T", value)
+}
+
+// yamlString returns s plain when it reads back as the same string, double quoted otherwise.
+func yamlString(s string) string {
+	if plainYAMLRegexp.MatchString(s) && !yaml11Words[strings.ToLower(s)] && resolvePlainYAML(s) == s {
+		return s
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
+
+// yamlLine is a line of a document with its indentation removed.
+type yamlLine struct {
+	indent int
+	text   string
+	number int
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// ParseYAML parses a single YAML document made of the supported subset.
+func ParseYAML(content string) (any, error) {
+	content = strings.TrimPrefix(strings.ReplaceAll(content, "\r\n", "\n"), "\ufeff")
+
+	parser := &yamlParser{}
+	for i, raw := range strings.Split(content, "\n") {
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") && strings.TrimSpace(text) != "" {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", i+1)
+		}
+		parser.lines = append(parser.lines, yamlLine{indent: len(raw) - len(text), text: strings.TrimRight(text, " \t"), number: i + 1})
+	}
+
+	parser.skip()
+	if parser.more() && yamlMarker(parser.current().text, "---") {
+		if rest := stripYAMLComment(parser.current().text[3:]); rest != "" {
+			return nil, fmt.Errorf("line %d: content on the --- line is not supported", parser.current().number)
+		}
+		parser.pos++
+		parser.skip()
+	}
+
+	var value any
+	if parser.more() && !yamlMarker(parser.current().text, "...") {
+		var err error
+		value, err = parser.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		parser.skip()
+	}
+
+	if parser.more() {
+		line := parser.current()
+		switch {
+		case yamlMarker(line.text, "---"):
+			return nil, fmt.Errorf("line %d: documents with several YAML documents are not supported", line.number)
+		case !yamlMarker(line.text, "..."):
+			return nil, fmt.Errorf("line %d: unexpected content %q", line.number, line.text)
+		}
+	}
+
+	return value, nil
+}
+
+// yamlMarker reports whether text is the document marker.
+func yamlMarker(text string, marker string) bool {
+	return text == marker || strings.HasPrefix(text, marker+" ")
+}
+
+func (p *yamlParser) more() bool {
+	return p.pos < len(p.lines)
+}
+
+func (p *yamlParser) current() yamlLine {
+	return p.lines[p.pos]
+}
+
+// documentEnd reports whether the current line ends the document.
+func (p *yamlParser) documentEnd() bool {
+	line := p.current()
+
+	return line.indent == 0 && (yamlMarker(line.text, "---") || yamlMarker(line.text, "..."))
+}
+
+// skip moves past the blank and comment lines.
+func (p *yamlParser) skip() {
+	for p.more() && (p.current().text == "" || strings.HasPrefix(p.current().text, "#")) {
+		p.pos++
+	}
+}
+
+// parseNode parses the node starting on the current line.
+func (p *yamlParser) parseNode() (any, error) {
+	line := p.current()
+	if yamlSequenceEntry(line.text) {
+		return p.parseSequence(line.indent)
+	}
+
+	_, _, ok, err := splitYAMLKey(line.text)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", line.number, err)
+	}
+	if ok {
+		return p.parseMapping(line.indent)
+	}
+
+	p.pos++
+
+	return p.parseInline(line.text, line.indent-1, line.number)
+}
+
+// yamlSequenceEntry reports whether text is an entry of a block sequence.
+func yamlSequenceEntry(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// parseSequence parses the block sequence whose dashes are indented by indent spaces.
+func (p *yamlParser) parseSequence(indent int) ([]any, error) {
+	items := []any{}
+	for {
+		p.skip()
+		if !p.more() || p.current().indent < indent || p.documentEnd() {
+			break
+		}
+
+		line := p.current()
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.number)
+		}
+		if !yamlSequenceEntry(line.text) {
+			break
+		}
+
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if rest == "" || strings.HasPrefix(rest, "#") {
+			p.pos++
+			p.skip()
+
+			var item any
+			if p.more() && p.current().indent > indent {
+				var err error
+				item, err = p.parseNode()
+				if err != nil {
+					return nil, err
+				}
+			}
+			items = append(items, item)
+			continue
+		}
+
+		// The content of the entry starts on the line of its dash, it is parsed as a node
+		// indented at its column so "- key: value" continues on the following lines.
+		p.lines[p.pos] = yamlLine{indent: indent + len(line.text) - len(rest), text: rest, number: line.number}
+		item, err := p.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// parseMapping parses the block mapping whose keys are indented by indent spaces.
+func (p *yamlParser) parseMapping(indent int) (map[string]any, error) {
+	mapping := map[string]any{}
+	for {
+		p.skip()
+		if !p.more() || p.current().indent < indent || p.documentEnd() {
+			break
+		}
+
+		line := p.current()
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.number)
+		}
+
+		key, rest, ok, err := splitYAMLKey(line.text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.number, err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a key, got %q", line.number, line.text)
+		}
+		if _, duplicated := mapping[key]; duplicated {
+			return nil, fmt.Errorf("line %d: duplicated key %q", line.number, key)
+		}
+		p.pos++
+
+		var value any
+		if rest != "" && !strings.HasPrefix(rest, "#") {
+			value, err = p.parseInline(rest, indent, line.number)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			// Sequences may be indented at the level of their key.
+			p.skip()
+			if p.more() && (p.current().indent > indent || p.current().indent == indent && yamlSequenceEntry(p.current().text)) {
+				value, err = p.parseNode()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		mapping[key] = value
+	}
+
+	return mapping, nil
+}
+
+// splitYAMLKey splits a mapping entry into its key and the rest of the line, and reports
+// whether text is a mapping entry.
+func splitYAMLKey(text string) (string, string, bool, error) {
+	if text == "" || yamlSequenceEntry(text) || strings.ContainsRune("[{#|>%@`", rune(text[0])) {
+		return "", "", false, nil
+	}
+	if strings.ContainsRune("&*!?", rune(text[0])) {
+		return "", "", false, errors.New("anchors, aliases, tags and complex keys are not supported")
+	}
+
+	if text[0] == '"' || text[0] == '\'' {
+		key, rest, err := parseYAMLQuoted(text)
+		if err != nil {
+			return "", "", false, err
+		}
+		if !strings.HasPrefix(rest, ":") || len(rest) > 1 && rest[1] != ' ' {
+			return "", "", false, nil
+		}
+
+		return key, strings.TrimLeft(rest[1:], " "), true, nil
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] == '#' && i > 0 && text[i-1] == ' ' {
+			break
+		}
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return strings.TrimRight(text[:i], " "), strings.TrimLeft(text[i+1:], " "), true, nil
+		}
+	}
+
+	return "", "", false, nil
+}
+
+// parseInline parses the value starting on a line already consumed, text being the part of
+// the line holding it. Block scalars read their content from the following lines, which are
+// indented by more than parentIndent spaces.
+func (p *yamlParser) parseInline(text string, parentIndent int, number int) (any, error) {
+	switch text[0] {
+	case '|', '>':
+		value, err := p.parseBlockScalar(text, parentIndent)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+
+		return value, nil
+	case '&', '*', '!':
+		return nil, fmt.Errorf("line %d: anchors, aliases and tags are not supported", number)
+	case '[', '{', '"', '\'':
+		value, rest, err := parseYAMLFlow(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+		if rest = strings.TrimLeft(rest, " "); rest != "" && !strings.HasPrefix(rest, "#") {
+			return nil, fmt.Errorf("line %d: unexpected content %q", number, rest)
+		}
+
+		return value, nil
+	}
+
+	return resolvePlainYAML(stripYAMLComment(text)), nil
+}
+
+// stripYAMLComment removes the comment ending text and the spaces around it.
+func stripYAMLComment(text string) string {
+	if strings.HasPrefix(text, "#") {
+		return ""
+	}
+	if i := strings.Index(text, " #"); i >= 0 {
+		text = text[:i]
+	}
+
+	return strings.TrimSpace(text)
+}
+
+// parseBlockScalar parses a literal (|) or folded (>) block scalar whose header is header.
+func (p *yamlParser) parseBlockScalar(header string, parentIndent int) (string, error) {
+	header = stripYAMLComment(header)
+	style := header[0]
+
+	var chomping byte
+	indent := 0
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomping = byte(c)
+		case c >= '1' && c <= '9':
+			indent = max(parentIndent, 0) + int(c-'0')
+		default:
+			return "", fmt.Errorf("invalid block scalar header %q", header)
+		}
+	}
+
+	var lines []string
+	for p.more() {
+		line := p.current()
+		if line.text == "" {
+			lines = append(lines, "")
+			p.pos++
+			continue
+		}
+		if indent == 0 {
+			if line.indent <= parentIndent {
+				break
+			}
+			indent = line.indent
+		}
+		if line.indent < indent {
+			break
+		}
+
+		lines = append(lines, strings.Repeat(" ", line.indent-indent)+line.text)
+		p.pos++
+	}
+
+	// Trailing blank lines only matter to the chomping.
+	end := len(lines)
+	for end > 0 && lines[end-1] == "" {
+		end--
+	}
+	trailing := len(lines) - end
+
+	var text string
+	if style == '|' {
+		text = strings.Join(lines[:end], "\n")
+	} else {
+		text = foldYAML(lines[:end])
+	}
+
+	switch {
+	case chomping == '-':
+		return text, nil
+	case chomping == '+' && end == 0:
+		return strings.Repeat("\n", trailing), nil
+	case chomping == '+':
+		return text + strings.Repeat("\n", trailing+1), nil
+	case end == 0:
+		return "", nil
+	default:
+		return text + "\n", nil
+	}
+}
+
+// foldYAML joins the lines of a folded block scalar: lines are joined with spaces, blank lines
+// become line breaks and more indented lines keep theirs.
+func foldYAML(lines []string) string {
+	var builder strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			previous := lines[i-1]
+			switch {
+			case line == "":
+				builder.WriteString("\n")
+			case previous == "":
+			case strings.HasPrefix(line, " ") || strings.HasPrefix(previous, " "):
+				builder.WriteString("\n")
+			default:
+				builder.WriteString(" ")
+			}
+		}
+		builder.WriteString(line)
+	}
+
+	return builder.String()
+}
+
+// parseYAMLFlow parses the flow node at the start of text and returns the text following it.
+func parseYAMLFlow(text string) (any, string, error) {
+	text = strings.TrimLeft(text, " ")
+	if text == "" {
+		return nil, "", errors.New("unexpected end of a flow collection, multi-line flow collections are not supported")
+	}
+
+	switch text[0] {
+	case '[':
+		items := []any{}
+		text = strings.TrimLeft(text[1:], " ")
+		for !strings.HasPrefix(text, "]") {
+			item, rest, err := parseYAMLFlow(text)
+			if err != nil {
+				return nil, "", err
+			}
+			items = append(items, item)
+
+			text = strings.TrimLeft(rest, " ")
+			if strings.HasPrefix(text, ",") {
+				text = strings.TrimLeft(text[1:], " ")
+			} else if !strings.HasPrefix(text, "]") {
+				return nil, "", errors.New("expected , or ] in a flow sequence")
+			}
+		}
+
+		return items, text[1:], nil
+	case '{':
+		mapping := map[string]any{}
+		text = strings.TrimLeft(text[1:], " ")
+		for !strings.HasPrefix(text, "}") {
+			key, rest, err := parseYAMLFlowKey(text)
+			if err != nil {
+				return nil, "", err
+			}
+
+			var value any
+			text = strings.TrimLeft(rest, " ")
+			if strings.HasPrefix(text, ":") {
+				text = strings.TrimLeft(text[1:], " ")
+				if !strings.HasPrefix(text, ",") && !strings.HasPrefix(text, "}") {
+					value, text, err = parseYAMLFlow(text)
+					if err != nil {
+						return nil, "", err
+					}
+				}
+			}
+			mapping[key] = value
+
+			text = strings.TrimLeft(text, " ")
+			if strings.HasPrefix(text, ",") {
+				text = strings.TrimLeft(text[1:], " ")
+			} else if !strings.HasPrefix(text, "}") {
+				return nil, "", errors.New("expected , or } in a flow mapping")
+			}
+		}
+
+		return mapping, text[1:], nil
+	case '"', '\'':
+		return parseYAMLQuoted(text)
+	case '&', '*', '!':
+		return nil, "", errors.New("anchors, aliases and tags are not supported")
+	}
+
+	end := len(text)
+	for i := 0; i < len(text); i++ {
+		if strings.IndexByte(",]}", text[i]) >= 0 || text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') || text[i] == '#' && i > 0 && text[i-1] == ' ' {
+			end = i
+			break
+		}
+	}
+
+	return resolvePlainYAML(strings.TrimSpace(text[:end])), text[end:], nil
+}
+
+// parseYAMLFlowKey parses the key of a flow mapping entry and returns the text following it.
+func parseYAMLFlowKey(text string) (string, string, error) {
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") {
+		return parseYAMLQuoted(text)
+	}
+
+	for i := 0; i < len(text); i++ {
+		if strings.IndexByte(",}", text[i]) >= 0 || text[i] == ':' && (i+1 == len(text) || strings.IndexByte(" ,}", text[i+1]) >= 0) {
+			return strings.TrimSpace(text[:i]), text[i:], nil
+		}
+	}
+
+	return "", "", errors.New("unexpected end of a flow mapping, multi-line flow collections are not supported")
+}
+
+// parseYAMLQuoted parses the single or double quoted scalar at the start of text and returns
+// the text following it.
+func parseYAMLQuoted(text string) (string, string, error) {
+	var builder strings.Builder
+
+	if text[0] == '\'' {
+		for i := 1; i < len(text); i++ {
+			if text[i] != '\'' {
+				builder.WriteByte(text[i])
+				continue
+			}
+			if i+1 < len(text) && text[i+1] == '\'' {
+				builder.WriteByte('\'')
+				i++
+				continue
+			}
+
+			return builder.String(), text[i+1:], nil
+		}
+
+		return "", "", errors.New("unterminated single quoted string, multi-line strings are not supported")
+	}
+
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '"':
+			return builder.String(), text[i+1:], nil
+		case '\\':
+			if i+1 == len(text) {
+				return "", "", errors.New("unterminated escape sequence")
+			}
+			i++
+
+			if replacement, ok := yamlEscapes[text[i]]; ok {
+				builder.WriteString(replacement)
+				continue
+			}
+
+			size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[text[i]]
+			if size == 0 || i+size >= len(text) {
+				return "", "", fmt.Errorf("invalid escape sequence \\%c", text[i])
+			}
+			code, err := strconv.ParseUint(text[i+1:i+1+size], 16, 32)
+			if err != nil {
+				return "", "", fmt.Errorf("invalid escape sequence \\%c%s", text[i], text[i+1:i+1+size])
+			}
+			i += size
+
+			// JSON writes the characters outside of the basic plane as UTF-16 surrogate pairs.
+			r := rune(code)
+			if utf16.IsSurrogate(r) && strings.HasPrefix(text[i+1:], "\\u") && i+6 < len(text) {
+				if low, err := strconv.ParseUint(text[i+3:i+7], 16, 32); err == nil {
+					r = utf16.DecodeRune(r, rune(low))
+					i += 6
+				}
+			}
+			builder.WriteRune(r)
+		default:
+			builder.WriteByte(text[i])
+		}
+	}
+
+	return "", "", errors.New("unterminated double quoted string, multi-line strings are not supported")
+}
+
+// yamlEscapes maps the single character escape sequences of double quoted scalars.
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f",
+	'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\",
+	'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+// resolvePlainYAML returns the value of a plain scalar following the YAML 1.2 core schema.
+func resolvePlainYAML(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+
+	if yamlIntRegexp.MatchString(s) {
+		return json.Number(strings.TrimPrefix(s, "+"))
+	}
+	if match := yamlRadixRegexp.FindStringSubmatch(s); match != nil {
+		base := map[byte]int{'x': 16, 'o': 8}[match[1][0]]
+		if value, ok := new(big.Int).SetString(match[1][1:], base); ok {
+			return json.Number(value.String())
+		}
+	}
+	if yamlFloatRegexp.MatchString(s) {
+		// Spell the number the way JSON does, e.g. ".5" as "0.5" and "1." as "1.0".
+		number := strings.TrimPrefix(s, "+")
+		sign := ""
+		if strings.HasPrefix(number, "-") {
+			sign, number = "-", number[1:]
+		}
+		mantissa, exponent, _ := strings.Cut(strings.ToLower(number), "e")
+		if strings.HasPrefix(mantissa, ".") {
+			mantissa = "0" + mantissa
+		}
+		if strings.HasSuffix(mantissa, ".") {
+			mantissa += "0"
+		}
+		if exponent != "" {
+			mantissa += "e" + exponent
+		}
+
+		return json.Number(sign + mantissa)
+	}
+
+	return s
+}
@@ -0,0 +1,103 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"remote-provider/internal/provider/services"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const (
+	defaultCreateTimeout = 20 * time.Minute
+	defaultReadTimeout   = 5 * time.Minute
+	defaultUpdateTimeout = 20 * time.Minute
+	defaultDeleteTimeout = 20 * time.Minute
+)
+
+// TimeoutsModel describes the timeouts block shared by every resource.
+type TimeoutsModel struct {
+	Create Duration `tfsdk:"create"`
+	Read   Duration `tfsdk:"read"`
+	Update Duration `tfsdk:"update"`
+	Delete Duration `tfsdk:"delete"`
+}
+
+// timeoutsSchema returns the timeouts attribute. The deadline of an operation bounds every
+// connection, command and transfer it makes, a command still running when it expires is killed.
+func timeoutsSchema() schema.SingleNestedAttribute {
+	timeout := func(operation string, fallback time.Duration) schema.StringAttribute {
+		return schema.StringAttribute{
+			CustomType:          DurationType{},
+			Optional:            true,
+			MarkdownDescription: "Maximum duration of the " + operation + " operation, e.g. `30s` or `10m`. Defaults to `" + fallback.String() + "`",
+		}
+	}
+
+	return schema.SingleNestedAttribute{
+		Optional:            true,
+		MarkdownDescription: "Operation timeouts",
+		Attributes: map[string]schema.Attribute{
+			"create": timeout("create", defaultCreateTimeout),
+			"read":   timeout("read", defaultReadTimeout),
+			"update": timeout("update", defaultUpdateTimeout),
+			"delete": timeout("delete", defaultDeleteTimeout),
+		},
+	}
+}
+
+// create, update and delete mark their context as changing the host, so its commands run after
+// the pre-apply hook of the provider. Every operation is counted in the activity of its hosts.
+func (m *TimeoutsModel) create(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = services.WithOperation(services.WithApply(ctx), services.OperationCreate)
+	if m == nil {
+		return context.WithTimeout(ctx, defaultCreateTimeout)
+	}
+
+	return withTimeout(ctx, m.Create.StringValue, defaultCreateTimeout)
+}
+
+func (m *TimeoutsModel) read(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = services.WithOperation(ctx, services.OperationRead)
+	if m == nil {
+		return context.WithTimeout(ctx, defaultReadTimeout)
+	}
+
+	return withTimeout(ctx, m.Read.StringValue, defaultReadTimeout)
+}
+
+func (m *TimeoutsModel) update(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = services.WithOperation(services.WithApply(ctx), services.OperationUpdate)
+	if m == nil {
+		return context.WithTimeout(ctx, defaultUpdateTimeout)
+	}
+
+	return withTimeout(ctx, m.Update.StringValue, defaultUpdateTimeout)
+}
+
+func (m *TimeoutsModel) delete(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = services.WithOperation(services.WithApply(ctx), services.OperationDelete)
+	if m == nil {
+		return context.WithTimeout(ctx, defaultDeleteTimeout)
+	}
+
+	return withTimeout(ctx, m.Delete.StringValue, defaultDeleteTimeout)
+}
+
+// withTimeout derives a context bounded by value, or by fallback when it is not set.
+// Invalid durations are rejected at validation.
+func withTimeout(ctx context.Context, value types.String, fallback time.Duration) (context.Context, context.CancelFunc) {
+	timeout := fallback
+	if !value.IsNull() && !value.IsUnknown() {
+		duration, err := time.ParseDuration(value.ValueString())
+		if err == nil {
+			timeout = duration
+		}
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
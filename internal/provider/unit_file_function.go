@@ -0,0 +1,155 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"remote-provider/internal/provider/services"
+	"slices"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ function.Function = UnitFileFunction{}
+)
+
+func NewUnitFileFunction() function.Function {
+	return UnitFileFunction{}
+}
+
+// UnitFileFunction renders a systemd unit file from an object of sections.
+type UnitFileFunction struct{}
+
+func (r UnitFileFunction) Metadata(_ context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "unit_file"
+}
+
+func (r UnitFileFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Renders a systemd unit file",
+		MarkdownDescription: "Renders the sections of `unit` as a systemd unit file, e.g. " +
+			"`{ Unit = { Description = \"App\" }, Service = { ExecStart = \"/usr/bin/app\", Environment = [\"A=1\", \"B=2\"] } }`. " +
+			"`[Unit]` comes first, `[Install]` last and keys are sorted by name. Lists repeat their key, booleans " +
+			"become `yes` or `no` and multi-line strings are written as continuation lines. `%` specifiers are kept as is.",
+		Parameters: []function.Parameter{
+			function.DynamicParameter{
+				Name:                "unit",
+				MarkdownDescription: "Object of sections, each an object of settings whose values are strings, numbers, booleans or lists of them",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (r UnitFileFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var unit types.Dynamic
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &unit))
+
+	if resp.Error != nil {
+		return
+	}
+
+	sections, err := unitSections(unit.UnderlyingValue())
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	rendered, err := services.RenderUnitFile(sections)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, rendered))
+}
+
+// objectElements returns the elements of an object or a map value.
+func objectElements(value attr.Value) (map[string]attr.Value, bool) {
+	switch value := value.(type) {
+	case types.Object:
+		return value.Attributes(), true
+	case types.Map:
+		return value.Elements(), true
+	}
+
+	return nil, false
+}
+
+// unitSections converts an object of sections to the unit file sections, keys sorted by name.
+func unitSections(unit attr.Value) ([]services.UnitSection, error) {
+	sections, ok := objectElements(unit)
+	if !ok {
+		return nil, fmt.Errorf("unit must be an object of sections")
+	}
+
+	var result []services.UnitSection
+	for _, name := range slices.Sorted(maps.Keys(sections)) {
+		settings, ok := objectElements(sections[name])
+		if !ok {
+			return nil, fmt.Errorf("section %s must be an object of settings", name)
+		}
+
+		section := services.UnitSection{Name: name}
+		for _, key := range slices.Sorted(maps.Keys(settings)) {
+			values, err := unitValues(settings[key])
+			if err != nil {
+				return nil, fmt.Errorf("setting %s of section %s: %w", key, name, err)
+			}
+
+			for _, value := range values {
+				section.Entries = append(section.Entries, services.UnitEntry{Key: key, Value: value})
+			}
+		}
+
+		result = append(result, section)
+	}
+
+	return result, nil
+}
+
+// unitValues converts a setting value to the values of its repeated key.
+func unitValues(value attr.Value) ([]string, error) {
+	var elements []attr.Value
+	switch value := value.(type) {
+	case types.Tuple:
+		elements = value.Elements()
+	case types.List:
+		elements = value.Elements()
+	case types.Set:
+		elements = value.Elements()
+	default:
+		elements = []attr.Value{value}
+	}
+
+	values := make([]string, 0, len(elements))
+	for _, element := range elements {
+		if element.IsNull() || element.IsUnknown() {
+			return nil, fmt.Errorf("values must be known and not null")
+		}
+
+		switch element := element.(type) {
+		case types.String:
+			values = append(values, element.ValueString())
+		case types.Number:
+			values = append(values, element.ValueBigFloat().Text('f', -1))
+		case types.Bool:
+			if element.ValueBool() {
+				values = append(values, "yes")
+			} else {
+				values = append(values, "no")
+			}
+		default:
+			return nil, fmt.Errorf("values must be strings, numbers or booleans")
+		}
+	}
+
+	return values, nil
+}
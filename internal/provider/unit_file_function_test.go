@@ -0,0 +1,62 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestUnitFileFunction_Known(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				output "test" {
+					value = provider::scaffolding::unit_file({
+						Install = { WantedBy = "multi-user.target" }
+						Service = { ExecStart = "/usr/bin/app", Environment = ["A=1", "B=2"], RestartSec = 5, NoNewPrivileges = true }
+						Unit    = { Description = "App" }
+					})
+				}
+				`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.StringExact(
+						"[Unit]\nDescription=App\n\n"+
+							"[Service]\nEnvironment=A=1\nEnvironment=B=2\nExecStart=/usr/bin/app\nNoNewPrivileges=yes\nRestartSec=5\n\n"+
+							"[Install]\nWantedBy=multi-user.target\n",
+					)),
+				},
+			},
+		},
+	})
+}
+
+func TestUnitFileFunction_Invalid(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				output "test" {
+					value = provider::scaffolding::unit_file({ Service = "ExecStart=/usr/bin/app" })
+				}
+				`,
+				ExpectError: regexp.MustCompile(`section Service must be an object of settings`),
+			},
+		},
+	})
+}
@@ -0,0 +1,176 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"remote-provider/internal/provider/services"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ validator.String = stringOneOfValidator{}
+
+// stringOneOfValidator checks that a string attribute is one of a fixed set of values.
+type stringOneOfValidator struct {
+	values []string
+}
+
+func stringOneOf(values ...string) validator.String {
+	return stringOneOfValidator{values: values}
+}
+
+func (v stringOneOfValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("value must be one of: %s", strings.Join(v.values, ", "))
+}
+
+func (v stringOneOfValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v stringOneOfValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	if !slices.Contains(v.values, req.ConfigValue.ValueString()) {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Attribute Value",
+			fmt.Sprintf("Attribute %s %s, got: %s", req.Path, v.Description(ctx), req.ConfigValue.ValueString()),
+		)
+	}
+}
+
+var _ validator.Set = aclEntriesValidator{}
+
+// aclEntriesValidator checks that every element of a set is a POSIX ACL entry managed by the provider.
+type aclEntriesValidator struct{}
+
+func (v aclEntriesValidator) Description(ctx context.Context) string {
+	return "entries must be named user or group ACL entries such as user:alice:rw-"
+}
+
+func (v aclEntriesValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v aclEntriesValidator) ValidateSet(ctx context.Context, req validator.SetRequest, resp *validator.SetResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	for _, element := range req.ConfigValue.Elements() {
+		entry, ok := element.(types.String)
+		if !ok || entry.IsUnknown() {
+			continue
+		}
+
+		_, err := services.NormalizeACLEntry(entry.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(req.Path, "Invalid ACL Entry", err.Error())
+		}
+	}
+}
+
+var _ validator.String = durationValidator{}
+
+// durationValidator checks that a string attribute is a positive Go duration such as "10m".
+type durationValidator struct{}
+
+func (v durationValidator) Description(ctx context.Context) string {
+	return "value must be a positive duration such as 30s or 10m"
+}
+
+func (v durationValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v durationValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	duration, err := time.ParseDuration(req.ConfigValue.ValueString())
+	if err != nil || duration <= 0 {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Attribute Value",
+			fmt.Sprintf("Attribute %s %s, got: %s", req.Path, v.Description(ctx), req.ConfigValue.ValueString()),
+		)
+	}
+}
+
+var _ validator.String = stringRegexpValidator{}
+
+// stringRegexpValidator checks that a string attribute matches a regular expression.
+type stringRegexpValidator struct {
+	regexp      *regexp.Regexp
+	description string
+}
+
+func stringMatches(expression *regexp.Regexp, description string) validator.String {
+	return stringRegexpValidator{regexp: expression, description: description}
+}
+
+func (v stringRegexpValidator) Description(ctx context.Context) string {
+	return v.description
+}
+
+func (v stringRegexpValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v stringRegexpValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	if !v.regexp.MatchString(req.ConfigValue.ValueString()) {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Attribute Value",
+			fmt.Sprintf("Attribute %s %s, got: %s", req.Path, v.Description(ctx), req.ConfigValue.ValueString()),
+		)
+	}
+}
+
+var _ validator.Int64 = int64AtLeastValidator{}
+
+// int64AtLeastValidator checks that an integer attribute is at least a minimum.
+type int64AtLeastValidator struct {
+	min int64
+}
+
+func int64AtLeast(min int64) validator.Int64 {
+	return int64AtLeastValidator{min: min}
+}
+
+func (v int64AtLeastValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("value must be at least %d", v.min)
+}
+
+func (v int64AtLeastValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v int64AtLeastValidator) ValidateInt64(ctx context.Context, req validator.Int64Request, resp *validator.Int64Response) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	if req.ConfigValue.ValueInt64() < v.min {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Attribute Value",
+			fmt.Sprintf("Attribute %s %s, got: %d", req.Path, v.Description(ctx), req.ConfigValue.ValueInt64()),
+		)
+	}
+}
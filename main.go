@@ -7,7 +7,9 @@ import (
 	"context"
 	"flag"
 	"log"
+	"os"
 	"remote-provider/internal/provider"
+	"remote-provider/internal/provider/agent"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 )
@@ -23,10 +25,19 @@ var (
 
 func main() {
 	var debug bool
+	var runAgent bool
 
 	flag.BoolVar(&debug, "debug", false, "set to true to run the provider with support for debuggers like delve")
+	flag.BoolVar(&runAgent, agent.Flag, false, "run as the helper agent uploaded to the hosts, reading requests from stdin")
 	flag.Parse()
 
+	if runAgent {
+		if err := agent.Serve(os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err.Error())
+		}
+		return
+	}
+
 	opts := providerserver.ServeOpts{
 		Address: "registry.terraform.io/binario-cloud/remote-host",
 		Debug:   debug,
@@ -34,6 +45,11 @@ func main() {
 
 	err := providerserver.Serve(context.Background(), provider.New(version), opts)
 
+	// Best effort: Terraform only waits briefly for the provider to exit.
+	if shutdownErr := provider.Shutdown(); shutdownErr != nil {
+		log.Println(shutdownErr.Error())
+	}
+
 	if err != nil {
 		log.Fatal(err.Error())
 	}